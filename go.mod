@@ -4,5 +4,6 @@ go 1.24.1
 
 require (
 	github.com/google/uuid v1.6.0
+	golang.org/x/net v0.47.0
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,2778 @@
+// Package app 组装配置、服务与 HTTP 路由，供 cmd/server 和进程内集成测试共用。
+package app
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/mcp"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/services"
+	"WideMindsMCP/internal/storage"
+	"WideMindsMCP/internal/utils"
+)
+
+// 结构体
+type Config struct {
+	Port                   int                 `yaml:"port" json:"port"`
+	MCPPort                int                 `yaml:"mcp_port" json:"mcp_port"`
+	LLMAPIKey              string              `yaml:"llm_api_key" json:"llm_api_key"`
+	LLMBaseURL             string              `yaml:"llm_base_url" json:"llm_base_url"`
+	LLMModel               string              `yaml:"llm_model" json:"llm_model"`
+	LLMProvider            string              `yaml:"llm_provider" json:"llm_provider"`
+	LLMAPIType             string              `yaml:"llm_api_type" json:"llm_api_type"`
+	LLMAzureDeployment     string              `yaml:"llm_azure_deployment" json:"llm_azure_deployment"`
+	LLMAzureAPIVersion     string              `yaml:"llm_azure_api_version" json:"llm_azure_api_version"`
+	LLMJSONMode            string              `yaml:"llm_json_mode" json:"llm_json_mode"`
+	OllamaNumCtx           int                 `yaml:"ollama_num_ctx" json:"ollama_num_ctx"`
+	OllamaKeepAlive        string              `yaml:"ollama_keep_alive" json:"ollama_keep_alive"`
+	ContextBudgetTokens    int                 `yaml:"context_budget_tokens" json:"context_budget_tokens"`
+	ModelContextWindows    map[string]int      `yaml:"model_context_windows" json:"model_context_windows"`
+	DataDir                string              `yaml:"data_dir" json:"data_dir"`
+	WebDir                 string              `yaml:"web_dir" json:"web_dir"`
+	UseFileStore           bool                `yaml:"use_file_store" json:"use_file_store"`
+	APIToken               string              `yaml:"api_token" json:"api_token"`
+	HTTPRateLimitPerMinute int                 `yaml:"http_rate_limit_per_minute" json:"http_rate_limit_per_minute"`
+	MCPRateLimitPerMinute  int                 `yaml:"mcp_rate_limit_per_minute" json:"mcp_rate_limit_per_minute"`
+	MaxThoughtDepth        int                 `yaml:"max_thought_depth" json:"max_thought_depth"`
+	LLMTokenBudgetPerUser  int                 `yaml:"llm_token_budget_per_user" json:"llm_token_budget_per_user"`
+	ForceResponseLanguage  string              `yaml:"force_response_language" json:"force_response_language"`
+	LLMCircuitThreshold    int                 `yaml:"llm_circuit_failure_threshold" json:"llm_circuit_failure_threshold"`
+	LLMCircuitRecoverySecs int                 `yaml:"llm_circuit_recovery_seconds" json:"llm_circuit_recovery_seconds"`
+	LLMHealthCheckInterval int                 `yaml:"llm_health_check_interval" json:"llm_health_check_interval"`
+	ExpansionConcurrency   int                 `yaml:"expansion_concurrency" json:"expansion_concurrency"`
+	DefaultThinkingStyle   string              `yaml:"default_thinking_style" json:"default_thinking_style"`
+	ToolPermissions        map[string][]string `yaml:"tool_permissions" json:"tool_permissions"`
+	PluginDirs             []string            `yaml:"plugin_dirs" json:"plugin_dirs"`
+	SupportedLocales       []string            `yaml:"supported_locales" json:"supported_locales"`
+	LocalesDir             string              `yaml:"locales_dir" json:"locales_dir"`
+	PromptsDir             string              `yaml:"prompts_dir" json:"prompts_dir"`
+	PromptExampleLimit     int                 `yaml:"prompt_example_limit" json:"prompt_example_limit"`
+	LLM                    LLMConfig           `yaml:"llm" json:"llm"`
+	// Pricing 按模型名配置每 1K 输入/输出令牌的价格，用于统计会话费用。
+	Pricing map[string]services.ModelPrice `yaml:"pricing" json:"pricing"`
+	// SimilarityAlertThreshold 为探索方向时的相似度告警阈值（余弦距离），0 表示关闭。
+	SimilarityAlertThreshold float64 `yaml:"similarity_alert_threshold" json:"similarity_alert_threshold"`
+	// DirectionDedupThreshold 为方向去重的 Jaccard 重合度阈值（0-1），0 表示关闭去重。
+	DirectionDedupThreshold float64 `yaml:"direction_dedup_threshold" json:"direction_dedup_threshold"`
+	// SanitizationMode 控制用户输入中 HTML 标签的净化：strict 移除全部标签，permissive 保留 <b>、<i>、<em>。
+	SanitizationMode string `yaml:"sanitization_mode" json:"sanitization_mode"`
+	// ThoughtDedupThreshold 为探索生成的思维与目标子树已有节点的相似度阈值（0-1），0 表示关闭查重。
+	ThoughtDedupThreshold float64 `yaml:"thought_dedup_threshold" json:"thought_dedup_threshold"`
+	// ThoughtDedupPolicy 为近似重复时的处理方式：annotate 插入并标注，skip 不插入并返回已有节点。
+	ThoughtDedupPolicy string `yaml:"thought_dedup_policy" json:"thought_dedup_policy"`
+	// PathHintLimit 为探索上下文附带的已有思维路径数（0-20），0 表示不附带。
+	PathHintLimit int `yaml:"path_hint_limit" json:"path_hint_limit"`
+	// PathHintStrategy 为历史路径的选择策略：deepest、most_recent 或 same_branch。
+	PathHintStrategy string `yaml:"path_hint_strategy" json:"path_hint_strategy"`
+	// AuthMode 为 token（比对 api_token）或 jwt（以 jwt_secret 校验 HS256 签名）。
+	AuthMode  string `yaml:"auth_mode" json:"auth_mode"`
+	JWTSecret string `yaml:"jwt_secret" json:"jwt_secret"`
+	// RateLimitByUserID 在 jwt 模式下按令牌中的 sub 限流，而不是按令牌字符串。
+	RateLimitByUserID bool `yaml:"rate_limit_by_user_id" json:"rate_limit_by_user_id"`
+	// AdminToken 为管理接口所需的独立令牌，经 X-Admin-Token 请求头传递；为空时管理接口一律拒绝。
+	AdminToken string `yaml:"admin_token" json:"admin_token"`
+	// ExpansionNodeBudget 限制单个会话的思维节点总数（含扩展预览），0 表示不限。
+	ExpansionNodeBudget int `yaml:"expansion_node_budget" json:"expansion_node_budget"`
+	// SessionArchiveDir 非空时每小时将 30 天未使用的文件会话（含命名快照）压缩归档到该目录并删除原文件。
+	SessionArchiveDir string `yaml:"session_archive_dir" json:"session_archive_dir"`
+}
+
+// LLMConfig 是 LLM 调用参数；MaxRetries 为失败后的重试次数（0 表示不重试），CacheSize 为 0 时关闭响应缓存。
+type LLMConfig struct {
+	TimeoutSeconds     int     `yaml:"timeout_seconds" json:"timeout_seconds"`
+	MaxTokens          int     `yaml:"max_tokens" json:"max_tokens"`
+	DefaultTemperature float64 `yaml:"default_temperature" json:"default_temperature"`
+	MaxRetries         int     `yaml:"max_retries" json:"max_retries"`
+	CacheSize          int     `yaml:"cache_size" json:"cache_size"`
+	CacheTTLSeconds    int     `yaml:"cache_ttl_seconds" json:"cache_ttl_seconds"`
+	EmbeddingModel     string  `yaml:"embedding_model" json:"embedding_model"`
+	HealthCacheSeconds int     `yaml:"health_cache_seconds" json:"health_cache_seconds"`
+	// DebugLog 开启后将完整的提示词与回复（经 LogRedactions 脱敏）写入 data_dir/llm-logs。
+	DebugLog      bool     `yaml:"debug_log" json:"debug_log"`
+	LogRedactions []string `yaml:"log_redactions" json:"log_redactions"`
+	// RepairDirections 开启后，方向解析失败时请求模型修正 JSON 并重试一次。
+	RepairDirections bool `yaml:"repair_directions" json:"repair_directions"`
+	// SchemaValidationEnabled 开启后，方向输出需通过结构校验，失败时以纠正提示重试一次。
+	SchemaValidationEnabled bool `yaml:"schema_validation_enabled" json:"schema_validation_enabled"`
+	// UseToolCalling 开启后，方向生成通过强制函数调用获取结构化结果；需网关支持 tools/tool_choice。
+	UseToolCalling bool `yaml:"use_tool_calling" json:"use_tool_calling"`
+	// SystemPromptPrefix 固定拼接在系统消息之前，请求中的 system_prompt 覆盖无法移除。
+	SystemPromptPrefix string `yaml:"system_prompt_prefix" json:"system_prompt_prefix"`
+	// ProxyURL、CACertFile、ClientCertFile/ClientKeyFile 用于经代理、私有 CA 或 mTLS 访问 LLM 网关。
+	ProxyURL           string `yaml:"proxy_url" json:"proxy_url"`
+	CACertFile         string `yaml:"ca_cert_file" json:"ca_cert_file"`
+	ClientCertFile     string `yaml:"client_cert_file" json:"client_cert_file"`
+	ClientKeyFile      string `yaml:"client_key_file" json:"client_key_file"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify" json:"insecure_skip_verify"`
+	// MaxIdleConnsPerHost 为 0 时使用 services.DefaultLLMMaxIdleConnsPerHost。
+	MaxIdleConnsPerHost int `yaml:"max_idle_conns_per_host" json:"max_idle_conns_per_host"`
+	// Fallbacks 为主模型出现上游故障或熔断时按顺序尝试的备用模型。
+	Fallbacks []services.LLMFallback `yaml:"fallbacks" json:"fallbacks"`
+	// RateLimitRPM 限制每分钟发往供应商的请求数，0 表示不限；RateLimitBurst 为 0 时等于 RateLimitRPM。
+	RateLimitRPM   int `yaml:"rate_limit_rpm" json:"rate_limit_rpm"`
+	RateLimitBurst int `yaml:"rate_limit_burst" json:"rate_limit_burst"`
+	// MaxConcurrent 限制同时发往供应商的请求数，0 表示不限。
+	MaxConcurrent int `yaml:"max_concurrent" json:"max_concurrent"`
+}
+
+const (
+	maxRequestBodyBytes int64 = 64 * 1024
+	maxImportFileBytes  int64 = 5 << 20
+	maxImportFilename         = 255
+
+	defaultLLMTimeoutSeconds = 60
+	maxLLMTimeoutSeconds     = 600
+	maxLLMMaxTokens          = 1 << 20
+	maxLLMRetries            = 10
+	maxLLMCacheSize          = 100000
+	maxLLMHealthCacheSeconds = 3600
+	maxLLMRateLimitRPM       = 100000
+	maxLLMMaxConcurrent      = 1024
+	maxExpansionNodeBudget   = 100000
+
+	defaultRecentLLMCallsLimit = 50
+
+	defaultLLMHealthCheckInterval = 60
+	maxLLMHealthCheckInterval     = 24 * 60 * 60
+)
+
+// 函数
+// Run 读取命令行与配置文件，启动 MCP 与 Web 服务，并阻塞到收到退出信号。
+func Run() error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	thoughtExpander, sessionManager, llm, err := initializeServices(cfg, newSessionStore(cfg))
+	if err != nil {
+		return fmt.Errorf("initialize services: %w", err)
+	}
+
+	mcpServer := setupMCPServer(cfg, thoughtExpander, sessionManager, llm)
+	if err := mcpServer.Start(cfg.MCPPort); err != nil {
+		return fmt.Errorf("start MCP server: %w", err)
+	}
+
+	webMux := setupWebServer(cfg, sessionManager, thoughtExpander, llm)
+	webServer := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Port),
+		Handler:           webMux,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      10 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+
+	go func() {
+		utils.Info("web server listening", utils.KV("addr", webServer.Addr))
+		if err := webServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			utils.Error("web server error", utils.KV("error", err))
+		}
+	}()
+
+	go reloadPromptsOnSignal(llm)
+	gracefulShutdown(mcpServer, webServer)
+	return nil
+}
+
+// NewHandler 使用给定的会话存储组装服务与 Web 路由，不监听端口，供进程内测试挂载到 httptest。
+func NewHandler(cfg *Config, store storage.SessionStore) (http.Handler, error) {
+	if err := validateConfig(cfg); err != nil {
+		return nil, err
+	}
+	expander, sessionManager, llm, err := initializeServices(cfg, store)
+	if err != nil {
+		return nil, err
+	}
+	return setupWebServer(cfg, sessionManager, expander, llm), nil
+}
+
+// DefaultConfig 返回未读取配置文件和环境变量时的默认配置。
+func DefaultConfig() *Config {
+	return &Config{
+		Port:                   8080,
+		MCPPort:                9090,
+		LLMModel:               services.DefaultLLMModel,
+		LLMProvider:            services.ProviderOpenAI,
+		LLMAPIType:             services.APITypeOpenAI,
+		WebDir:                 "web",
+		UseFileStore:           false,
+		HTTPRateLimitPerMinute: 120,
+		MCPRateLimitPerMinute:  60,
+		MaxThoughtDepth:        services.DefaultMaxThoughtDepth,
+		LLMCircuitThreshold:    utils.DefaultCircuitFailureThreshold,
+		LLMCircuitRecoverySecs: int(utils.DefaultCircuitRecoveryTimeout / time.Second),
+		LLMHealthCheckInterval: defaultLLMHealthCheckInterval,
+		ExpansionConcurrency:   services.DefaultExpansionConcurrency,
+		SupportedLocales:       []string{utils.DefaultLocale, "zh-Hans"},
+		LocalesDir:             "configs/locales",
+		PromptExampleLimit:     services.DefaultPromptExampleLimit,
+		LLM: LLMConfig{
+			TimeoutSeconds:     defaultLLMTimeoutSeconds,
+			MaxTokens:          services.DefaultLLMMaxTokens,
+			DefaultTemperature: services.DefaultLLMTemperature,
+			MaxRetries:         services.DefaultLLMMaxAttempts - 1,
+			CacheSize:          services.DefaultResponseCacheSize,
+			CacheTTLSeconds:    int(services.DefaultResponseCacheTTL / time.Second),
+			EmbeddingModel:     services.DefaultEmbeddingModel,
+			HealthCacheSeconds: int(services.DefaultHealthCheckCacheTTL / time.Second),
+			RepairDirections:   true,
+			MaxConcurrent:      services.DefaultLLMMaxConcurrent,
+		},
+		SimilarityAlertThreshold: services.DefaultSimilarityAlertThreshold,
+		DirectionDedupThreshold:  services.DefaultDirectionDedupThreshold,
+		SanitizationMode:         string(utils.SanitizeStrict),
+		ThoughtDedupThreshold:    services.DefaultThoughtDedupThreshold,
+		ThoughtDedupPolicy:       string(services.ThoughtDedupAnnotate),
+		PathHintLimit:            services.DefaultPathHintLimit,
+		PathHintStrategy:         string(services.PathHintDeepest),
+		AuthMode:                 string(utils.AuthModeToken),
+		RateLimitByUserID:        true,
+	}
+}
+
+func loadConfig() (*Config, error) {
+	cfg := DefaultConfig()
+
+	configPath := flag.String("config", "configs/config.yaml", "Path to configuration file")
+	envPath := flag.String("env", "configs/example.env", "Path to env file")
+	flag.Parse()
+
+	if info, err := os.Stat(*envPath); err == nil {
+		if info.IsDir() {
+			return nil, fmt.Errorf("env path %s is a directory", *envPath)
+		}
+		if _, err := utils.LoadEnvFile(*envPath); err != nil {
+			return nil, fmt.Errorf("load env file %s: %w", *envPath, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("stat env file %s: %w", *envPath, err)
+	}
+
+	resolvedPath, err := utils.ResolveConfigPath(*configPath)
+	if err == nil {
+		if _, statErr := os.Stat(resolvedPath); statErr == nil {
+			if err := utils.LoadYAML(resolvedPath, cfg); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	applyEnvOverrides(cfg)
+
+	if err := validateConfig(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func applyEnvOverrides(cfg *Config) {
+	if portStr := os.Getenv("PORT"); portStr != "" {
+		if port, err := strconv.Atoi(portStr); err == nil {
+			cfg.Port = port
+		}
+	}
+	if mcpPortStr := os.Getenv("MCP_PORT"); mcpPortStr != "" {
+		if port, err := strconv.Atoi(mcpPortStr); err == nil {
+			cfg.MCPPort = port
+		}
+	}
+	if val := os.Getenv("LLM_API_KEY"); val != "" {
+		cfg.LLMAPIKey = val
+	}
+	if val := os.Getenv("LLM_BASE_URL"); val != "" {
+		cfg.LLMBaseURL = val
+	}
+	if val := os.Getenv("LLM_MODEL"); val != "" {
+		cfg.LLMModel = val
+	}
+	if val := os.Getenv("LLM_PROVIDER"); val != "" {
+		cfg.LLMProvider = val
+	}
+	if val := os.Getenv("LLM_API_TYPE"); val != "" {
+		cfg.LLMAPIType = val
+	}
+	if val := os.Getenv("LLM_AZURE_DEPLOYMENT"); val != "" {
+		cfg.LLMAzureDeployment = val
+	}
+	if val := os.Getenv("LLM_AZURE_API_VERSION"); val != "" {
+		cfg.LLMAzureAPIVersion = val
+	}
+	if val := os.Getenv("LLM_JSON_MODE"); val != "" {
+		cfg.LLMJSONMode = val
+	}
+	if val := os.Getenv("OLLAMA_NUM_CTX"); val != "" {
+		if numCtx, err := strconv.Atoi(val); err == nil {
+			cfg.OllamaNumCtx = numCtx
+		}
+	}
+	if val := os.Getenv("OLLAMA_KEEP_ALIVE"); val != "" {
+		cfg.OllamaKeepAlive = val
+	}
+	if val := os.Getenv("CONTEXT_BUDGET_TOKENS"); val != "" {
+		if budget, err := strconv.Atoi(val); err == nil {
+			cfg.ContextBudgetTokens = budget
+		}
+	}
+	// MODEL_CONTEXT_WINDOWS 格式：model=tokens,model=tokens
+	if val := os.Getenv("MODEL_CONTEXT_WINDOWS"); val != "" {
+		windows := make(map[string]int)
+		for _, pair := range strings.Split(val, ",") {
+			model, tokens, ok := strings.Cut(pair, "=")
+			if !ok {
+				continue
+			}
+			if window, err := strconv.Atoi(strings.TrimSpace(tokens)); err == nil {
+				windows[strings.TrimSpace(model)] = window
+			}
+		}
+		cfg.ModelContextWindows = windows
+	}
+	if val := os.Getenv("DATA_DIR"); val != "" {
+		cfg.DataDir = val
+	}
+	if val := os.Getenv("SESSION_ARCHIVE_DIR"); val != "" {
+		cfg.SessionArchiveDir = val
+	}
+	if val := os.Getenv("PROMPTS_DIR"); val != "" {
+		cfg.PromptsDir = val
+	}
+	if val := os.Getenv("PROMPT_EXAMPLE_LIMIT"); val != "" {
+		if limit, err := strconv.Atoi(val); err == nil {
+			cfg.PromptExampleLimit = limit
+		}
+	}
+	if val := os.Getenv("SIMILARITY_ALERT_THRESHOLD"); val != "" {
+		if threshold, err := strconv.ParseFloat(val, 64); err == nil {
+			cfg.SimilarityAlertThreshold = threshold
+		}
+	}
+	if val := os.Getenv("DIRECTION_DEDUP_THRESHOLD"); val != "" {
+		if threshold, err := strconv.ParseFloat(val, 64); err == nil {
+			cfg.DirectionDedupThreshold = threshold
+		}
+	}
+	if val := os.Getenv("SANITIZATION_MODE"); val != "" {
+		cfg.SanitizationMode = val
+	}
+	if val := os.Getenv("THOUGHT_DEDUP_THRESHOLD"); val != "" {
+		if threshold, err := strconv.ParseFloat(val, 64); err == nil {
+			cfg.ThoughtDedupThreshold = threshold
+		}
+	}
+	if val := os.Getenv("THOUGHT_DEDUP_POLICY"); val != "" {
+		cfg.ThoughtDedupPolicy = val
+	}
+	if val := os.Getenv("PATH_HINT_LIMIT"); val != "" {
+		if limit, err := strconv.Atoi(val); err == nil {
+			cfg.PathHintLimit = limit
+		}
+	}
+	if val := os.Getenv("PATH_HINT_STRATEGY"); val != "" {
+		cfg.PathHintStrategy = val
+	}
+	if val := os.Getenv("WEB_DIR"); val != "" {
+		cfg.WebDir = val
+	}
+	if val := os.Getenv("USE_FILE_STORE"); val != "" {
+		cfg.UseFileStore = strings.ToLower(val) == "true"
+	}
+	if val := os.Getenv("API_TOKEN"); val != "" {
+		cfg.APIToken = val
+	}
+	if val := os.Getenv("AUTH_MODE"); val != "" {
+		cfg.AuthMode = val
+	}
+	if val := os.Getenv("JWT_SECRET"); val != "" {
+		cfg.JWTSecret = val
+	}
+	if val := os.Getenv("ADMIN_TOKEN"); val != "" {
+		cfg.AdminToken = val
+	}
+	if val := os.Getenv("RATE_LIMIT_BY_USER_ID"); val != "" {
+		cfg.RateLimitByUserID = strings.ToLower(val) == "true"
+	}
+	if val := os.Getenv("HTTP_RATE_LIMIT_PER_MINUTE"); val != "" {
+		if limit, err := strconv.Atoi(val); err == nil {
+			cfg.HTTPRateLimitPerMinute = limit
+		}
+	}
+	if val := os.Getenv("MCP_RATE_LIMIT_PER_MINUTE"); val != "" {
+		if limit, err := strconv.Atoi(val); err == nil {
+			cfg.MCPRateLimitPerMinute = limit
+		}
+	}
+	if val := os.Getenv("LLM_TOKEN_BUDGET_PER_USER"); val != "" {
+		if budget, err := strconv.Atoi(val); err == nil {
+			cfg.LLMTokenBudgetPerUser = budget
+		}
+	}
+	if val := os.Getenv("LLM_TIMEOUT_SECONDS"); val != "" {
+		if seconds, err := strconv.Atoi(val); err == nil {
+			cfg.LLM.TimeoutSeconds = seconds
+		}
+	}
+	if val := os.Getenv("LLM_MAX_TOKENS"); val != "" {
+		if maxTokens, err := strconv.Atoi(val); err == nil {
+			cfg.LLM.MaxTokens = maxTokens
+		}
+	}
+	if val := os.Getenv("LLM_DEFAULT_TEMPERATURE"); val != "" {
+		if temperature, err := strconv.ParseFloat(val, 64); err == nil {
+			cfg.LLM.DefaultTemperature = temperature
+		}
+	}
+	if val := os.Getenv("LLM_MAX_RETRIES"); val != "" {
+		if retries, err := strconv.Atoi(val); err == nil {
+			cfg.LLM.MaxRetries = retries
+		}
+	}
+	if val := os.Getenv("LLM_CACHE_SIZE"); val != "" {
+		if size, err := strconv.Atoi(val); err == nil {
+			cfg.LLM.CacheSize = size
+		}
+	}
+	if val := os.Getenv("LLM_CACHE_TTL_SECONDS"); val != "" {
+		if seconds, err := strconv.Atoi(val); err == nil {
+			cfg.LLM.CacheTTLSeconds = seconds
+		}
+	}
+	if val := os.Getenv("LLM_EMBEDDING_MODEL"); val != "" {
+		cfg.LLM.EmbeddingModel = val
+	}
+	if val := os.Getenv("LLM_HEALTH_CACHE_SECONDS"); val != "" {
+		if seconds, err := strconv.Atoi(val); err == nil {
+			cfg.LLM.HealthCacheSeconds = seconds
+		}
+	}
+	if val := os.Getenv("LLM_DEBUG_LOG"); val != "" {
+		cfg.LLM.DebugLog = strings.ToLower(val) == "true"
+	}
+	if val := os.Getenv("LLM_REPAIR_DIRECTIONS"); val != "" {
+		cfg.LLM.RepairDirections = strings.ToLower(val) == "true"
+	}
+	if val := os.Getenv("LLM_SCHEMA_VALIDATION"); val != "" {
+		cfg.LLM.SchemaValidationEnabled = strings.ToLower(val) == "true"
+	}
+	if val := os.Getenv("LLM_USE_TOOL_CALLING"); val != "" {
+		cfg.LLM.UseToolCalling = strings.ToLower(val) == "true"
+	}
+	if val := os.Getenv("LLM_SYSTEM_PROMPT_PREFIX"); val != "" {
+		cfg.LLM.SystemPromptPrefix = val
+	}
+	if val := os.Getenv("LLM_PROXY_URL"); val != "" {
+		cfg.LLM.ProxyURL = val
+	}
+	if val := os.Getenv("LLM_CA_CERT_FILE"); val != "" {
+		cfg.LLM.CACertFile = val
+	}
+	if val := os.Getenv("LLM_CLIENT_CERT_FILE"); val != "" {
+		cfg.LLM.ClientCertFile = val
+	}
+	if val := os.Getenv("LLM_CLIENT_KEY_FILE"); val != "" {
+		cfg.LLM.ClientKeyFile = val
+	}
+	if val := os.Getenv("LLM_INSECURE_SKIP_VERIFY"); val != "" {
+		cfg.LLM.InsecureSkipVerify = strings.ToLower(val) == "true"
+	}
+	if val := os.Getenv("LLM_MAX_IDLE_CONNS_PER_HOST"); val != "" {
+		if conns, err := strconv.Atoi(val); err == nil {
+			cfg.LLM.MaxIdleConnsPerHost = conns
+		}
+	}
+	if val := os.Getenv("LLM_FALLBACK_MODELS"); val != "" {
+		// 逗号分隔的模型名，沿用主模型的网关与 API key
+		cfg.LLM.Fallbacks = nil
+		for _, model := range strings.Split(val, ",") {
+			if model = strings.TrimSpace(model); model != "" {
+				cfg.LLM.Fallbacks = append(cfg.LLM.Fallbacks, services.LLMFallback{Model: model})
+			}
+		}
+	}
+	if val := os.Getenv("LLM_RATE_LIMIT_RPM"); val != "" {
+		if rpm, err := strconv.Atoi(val); err == nil {
+			cfg.LLM.RateLimitRPM = rpm
+		}
+	}
+	if val := os.Getenv("LLM_RATE_LIMIT_BURST"); val != "" {
+		if burst, err := strconv.Atoi(val); err == nil {
+			cfg.LLM.RateLimitBurst = burst
+		}
+	}
+	if val := os.Getenv("LLM_MAX_CONCURRENT"); val != "" {
+		if limit, err := strconv.Atoi(val); err == nil {
+			cfg.LLM.MaxConcurrent = limit
+		}
+	}
+	if val := os.Getenv("LLM_HEALTH_CHECK_INTERVAL"); val != "" {
+		if seconds, err := strconv.Atoi(val); err == nil {
+			cfg.LLMHealthCheckInterval = seconds
+		}
+	}
+	if val := os.Getenv("DEFAULT_THINKING_STYLE"); val != "" {
+		cfg.DefaultThinkingStyle = val
+	}
+	if val := os.Getenv("EXPANSION_CONCURRENCY"); val != "" {
+		if limit, err := strconv.Atoi(val); err == nil {
+			cfg.ExpansionConcurrency = limit
+		}
+	}
+	if val := os.Getenv("EXPANSION_NODE_BUDGET"); val != "" {
+		if budget, err := strconv.Atoi(val); err == nil {
+			cfg.ExpansionNodeBudget = budget
+		}
+	}
+	if val := os.Getenv("LLM_CIRCUIT_FAILURE_THRESHOLD"); val != "" {
+		if threshold, err := strconv.Atoi(val); err == nil {
+			cfg.LLMCircuitThreshold = threshold
+		}
+	}
+	if val := os.Getenv("LLM_CIRCUIT_RECOVERY_SECONDS"); val != "" {
+		if seconds, err := strconv.Atoi(val); err == nil {
+			cfg.LLMCircuitRecoverySecs = seconds
+		}
+	}
+	if val := os.Getenv("FORCE_RESPONSE_LANGUAGE"); val != "" {
+		cfg.ForceResponseLanguage = val
+	}
+	// LLM_OUTPUT_LANGUAGE 是 FORCE_RESPONSE_LANGUAGE 的别名，同时设置时以它为准
+	if val := os.Getenv("LLM_OUTPUT_LANGUAGE"); val != "" {
+		cfg.ForceResponseLanguage = val
+	}
+	if val := os.Getenv("PLUGIN_DIRS"); val != "" {
+		cfg.PluginDirs = nil
+		for _, dir := range strings.Split(val, ",") {
+			if dir = strings.TrimSpace(dir); dir != "" {
+				cfg.PluginDirs = append(cfg.PluginDirs, dir)
+			}
+		}
+	}
+	if val := os.Getenv("SUPPORTED_LOCALES"); val != "" {
+		cfg.SupportedLocales = nil
+		for _, locale := range strings.Split(val, ",") {
+			if locale = strings.TrimSpace(locale); locale != "" {
+				cfg.SupportedLocales = append(cfg.SupportedLocales, locale)
+			}
+		}
+	}
+	if val := os.Getenv("LOCALES_DIR"); val != "" {
+		cfg.LocalesDir = val
+	}
+	if val := os.Getenv("MAX_THOUGHT_DEPTH"); val != "" {
+		if depth, err := strconv.Atoi(val); err == nil {
+			cfg.MaxThoughtDepth = depth
+		}
+	}
+}
+
+func validateConfig(cfg *Config) error {
+	if cfg == nil {
+		return errors.New("config is nil")
+	}
+	if cfg.Port <= 0 || cfg.Port > 65535 {
+		return fmt.Errorf("invalid port: %d", cfg.Port)
+	}
+	if cfg.MCPPort <= 0 || cfg.MCPPort > 65535 {
+		return fmt.Errorf("invalid mcp_port: %d", cfg.MCPPort)
+	}
+	if cfg.HTTPRateLimitPerMinute < 0 {
+		return fmt.Errorf("invalid http_rate_limit_per_minute: %d", cfg.HTTPRateLimitPerMinute)
+	}
+	if cfg.MCPRateLimitPerMinute < 0 {
+		return fmt.Errorf("invalid mcp_rate_limit_per_minute: %d", cfg.MCPRateLimitPerMinute)
+	}
+	authMode, err := utils.ParseAuthMode(cfg.AuthMode)
+	if err != nil {
+		return fmt.Errorf("invalid auth_mode: %w", err)
+	}
+	if authMode == utils.AuthModeJWT && len(cfg.JWTSecret) < utils.MinJWTSecretLength {
+		return fmt.Errorf("jwt_secret must be at least %d bytes when auth_mode is jwt", utils.MinJWTSecretLength)
+	}
+	if cfg.AdminToken != "" && cfg.AdminToken == cfg.APIToken {
+		return errors.New("admin_token must differ from api_token")
+	}
+	if cfg.LLMTokenBudgetPerUser < 0 {
+		return fmt.Errorf("invalid llm_token_budget_per_user: %d", cfg.LLMTokenBudgetPerUser)
+	}
+	if cfg.MaxThoughtDepth <= 0 {
+		return fmt.Errorf("invalid max_thought_depth: %d", cfg.MaxThoughtDepth)
+	}
+	if cfg.LLMCircuitThreshold <= 0 {
+		return fmt.Errorf("invalid llm_circuit_failure_threshold: %d", cfg.LLMCircuitThreshold)
+	}
+	if cfg.LLMCircuitRecoverySecs <= 0 {
+		return fmt.Errorf("invalid llm_circuit_recovery_seconds: %d", cfg.LLMCircuitRecoverySecs)
+	}
+	if lang := strings.TrimSpace(cfg.ForceResponseLanguage); lang != "" {
+		if _, ok := services.NormalizeLanguageTag(lang); !ok {
+			return fmt.Errorf("invalid force_response_language: %q", cfg.ForceResponseLanguage)
+		}
+	}
+	provider := strings.ToLower(strings.TrimSpace(cfg.LLMProvider))
+	if provider != "" && provider != services.ProviderOpenAI && provider != services.ProviderOllama {
+		return fmt.Errorf("invalid llm_provider: %q", cfg.LLMProvider)
+	}
+	switch apiType := strings.ToLower(strings.TrimSpace(cfg.LLMAPIType)); apiType {
+	case "", services.APITypeOpenAI:
+	case services.APITypeAzure:
+		if provider == services.ProviderOllama {
+			return errors.New("llm_api_type azure cannot be combined with llm_provider ollama")
+		}
+		if strings.TrimSpace(cfg.LLMAzureDeployment) == "" {
+			return errors.New("llm_azure_deployment is required when llm_api_type is azure")
+		}
+		if strings.TrimSpace(cfg.LLMBaseURL) == "" {
+			return errors.New("llm_base_url is required when llm_api_type is azure")
+		}
+	default:
+		return fmt.Errorf("invalid llm_api_type: %q", cfg.LLMAPIType)
+	}
+	if _, err := services.NormalizeJSONMode(cfg.LLMJSONMode); err != nil {
+		return fmt.Errorf("invalid llm_json_mode: %q", cfg.LLMJSONMode)
+	}
+	if cfg.OllamaNumCtx < 0 {
+		return fmt.Errorf("invalid ollama_num_ctx: %d", cfg.OllamaNumCtx)
+	}
+	if cfg.LLMHealthCheckInterval < 0 || cfg.LLMHealthCheckInterval > maxLLMHealthCheckInterval {
+		return fmt.Errorf("invalid llm_health_check_interval: %d (must be 0-%d seconds)", cfg.LLMHealthCheckInterval, maxLLMHealthCheckInterval)
+	}
+	if cfg.ExpansionConcurrency <= 0 || cfg.ExpansionConcurrency > services.MaxExpansionConcurrency {
+		return fmt.Errorf("invalid expansion_concurrency: %d (must be 1-%d)", cfg.ExpansionConcurrency, services.MaxExpansionConcurrency)
+	}
+	if cfg.ExpansionNodeBudget < 0 || cfg.ExpansionNodeBudget > maxExpansionNodeBudget {
+		return fmt.Errorf("invalid expansion_node_budget: %d (must be 0-%d)", cfg.ExpansionNodeBudget, maxExpansionNodeBudget)
+	}
+	if cfg.PromptExampleLimit < 0 || cfg.PromptExampleLimit > services.MaxPromptExampleLimit {
+		return fmt.Errorf("invalid prompt_example_limit: %d (must be 0-%d)", cfg.PromptExampleLimit, services.MaxPromptExampleLimit)
+	}
+	if cfg.SimilarityAlertThreshold < 0 || cfg.SimilarityAlertThreshold > 2 {
+		return fmt.Errorf("invalid similarity_alert_threshold: %.2f (must be 0-2)", cfg.SimilarityAlertThreshold)
+	}
+	if cfg.DirectionDedupThreshold < 0 || cfg.DirectionDedupThreshold > 1 {
+		return fmt.Errorf("invalid direction_dedup_threshold: %.2f (must be 0-1)", cfg.DirectionDedupThreshold)
+	}
+	if _, err := utils.ParseSanitizationMode(cfg.SanitizationMode); err != nil {
+		return fmt.Errorf("invalid sanitization_mode: %w", err)
+	}
+	if cfg.ThoughtDedupThreshold < 0 || cfg.ThoughtDedupThreshold > 1 {
+		return fmt.Errorf("invalid thought_dedup_threshold: %.2f (must be 0-1)", cfg.ThoughtDedupThreshold)
+	}
+	if _, err := services.ParseThoughtDedupPolicy(cfg.ThoughtDedupPolicy); err != nil {
+		return fmt.Errorf("invalid thought_dedup_policy: %w", err)
+	}
+	if cfg.PathHintLimit < 0 || cfg.PathHintLimit > services.MaxPathHintLimit {
+		return fmt.Errorf("invalid path_hint_limit: %d (must be 0-%d)", cfg.PathHintLimit, services.MaxPathHintLimit)
+	}
+	if _, err := services.ParsePathHintStrategy(cfg.PathHintStrategy); err != nil {
+		return fmt.Errorf("invalid path_hint_strategy: %w", err)
+	}
+	for _, locale := range cfg.SupportedLocales {
+		if err := utils.ValidateLocale(locale); err != nil {
+			return fmt.Errorf("invalid supported_locales: %w", err)
+		}
+	}
+	if _, err := utils.ParseThinkingStyle(cfg.DefaultThinkingStyle); err != nil {
+		return fmt.Errorf("invalid default_thinking_style: %q", cfg.DefaultThinkingStyle)
+	}
+	if cfg.LLM.TimeoutSeconds <= 0 || cfg.LLM.TimeoutSeconds > maxLLMTimeoutSeconds {
+		return fmt.Errorf("invalid llm.timeout_seconds: %d (must be 1-%d)", cfg.LLM.TimeoutSeconds, maxLLMTimeoutSeconds)
+	}
+	if cfg.LLM.MaxTokens <= 0 || cfg.LLM.MaxTokens > maxLLMMaxTokens {
+		return fmt.Errorf("invalid llm.max_tokens: %d (must be 1-%d)", cfg.LLM.MaxTokens, maxLLMMaxTokens)
+	}
+	if cfg.LLM.DefaultTemperature < 0 || cfg.LLM.DefaultTemperature > 2 {
+		return fmt.Errorf("invalid llm.default_temperature: %g (must be in [0, 2])", cfg.LLM.DefaultTemperature)
+	}
+	if cfg.LLM.MaxRetries < 0 || cfg.LLM.MaxRetries > maxLLMRetries {
+		return fmt.Errorf("invalid llm.max_retries: %d (must be 0-%d)", cfg.LLM.MaxRetries, maxLLMRetries)
+	}
+	if cfg.LLM.CacheSize < 0 || cfg.LLM.CacheSize > maxLLMCacheSize {
+		return fmt.Errorf("invalid llm.cache_size: %d (must be 0-%d)", cfg.LLM.CacheSize, maxLLMCacheSize)
+	}
+	if cfg.LLM.HealthCacheSeconds < 0 || cfg.LLM.HealthCacheSeconds > maxLLMHealthCacheSeconds {
+		return fmt.Errorf("invalid llm.health_cache_seconds: %d (must be 0-%d)", cfg.LLM.HealthCacheSeconds, maxLLMHealthCacheSeconds)
+	}
+	if _, err := services.CompileRedactions(cfg.LLM.LogRedactions); err != nil {
+		return fmt.Errorf("invalid llm.log_redactions: %w", err)
+	}
+	if utf8.RuneCountInString(cfg.LLM.SystemPromptPrefix) > utils.MaxSystemPromptLength {
+		return fmt.Errorf("invalid llm.system_prompt_prefix: longer than %d characters", utils.MaxSystemPromptLength)
+	}
+	if cfg.LLM.MaxIdleConnsPerHost < 0 || cfg.LLM.MaxIdleConnsPerHost > services.MaxLLMMaxIdleConnsPerHost {
+		return fmt.Errorf("invalid llm.max_idle_conns_per_host: %d (must be 0-%d)", cfg.LLM.MaxIdleConnsPerHost, services.MaxLLMMaxIdleConnsPerHost)
+	}
+	if _, err := services.NewLLMTransport(llmTransportOptions(cfg)); err != nil {
+		return fmt.Errorf("invalid llm transport settings: %w", err)
+	}
+	if err := services.ValidateLLMFallbacks(cfg.LLM.Fallbacks); err != nil {
+		return fmt.Errorf("invalid llm.fallbacks: %w", err)
+	}
+	if cfg.LLM.RateLimitRPM < 0 || cfg.LLM.RateLimitRPM > maxLLMRateLimitRPM {
+		return fmt.Errorf("invalid llm.rate_limit_rpm: %d (must be 0-%d)", cfg.LLM.RateLimitRPM, maxLLMRateLimitRPM)
+	}
+	if cfg.LLM.RateLimitBurst < 0 || cfg.LLM.RateLimitBurst > maxLLMRateLimitRPM {
+		return fmt.Errorf("invalid llm.rate_limit_burst: %d (must be 0-%d)", cfg.LLM.RateLimitBurst, maxLLMRateLimitRPM)
+	}
+	if cfg.LLM.MaxConcurrent < 0 || cfg.LLM.MaxConcurrent > maxLLMMaxConcurrent {
+		return fmt.Errorf("invalid llm.max_concurrent: %d (must be 0-%d)", cfg.LLM.MaxConcurrent, maxLLMMaxConcurrent)
+	}
+	if cfg.LLM.CacheSize > 0 && cfg.LLM.CacheTTLSeconds <= 0 {
+		return fmt.Errorf("invalid llm.cache_ttl_seconds: %d (must be positive when the cache is enabled)", cfg.LLM.CacheTTLSeconds)
+	}
+	if cfg.ContextBudgetTokens < 0 {
+		return fmt.Errorf("invalid context_budget_tokens: %d", cfg.ContextBudgetTokens)
+	}
+	for model, price := range cfg.Pricing {
+		if strings.TrimSpace(model) == "" || price.InputPer1K < 0 || price.OutputPer1K < 0 {
+			return fmt.Errorf("invalid pricing entry %q: prices must be non-negative", model)
+		}
+	}
+	for model, window := range cfg.ModelContextWindows {
+		if strings.TrimSpace(model) == "" || window <= 0 {
+			return fmt.Errorf("invalid model_context_windows entry %q: %d", model, window)
+		}
+	}
+	// Ollama 在本地运行，不需要 API key
+	if provider != services.ProviderOllama && strings.TrimSpace(cfg.LLMBaseURL) != "" && strings.TrimSpace(cfg.LLMAPIKey) == "" {
+		return errors.New("llm_api_key is required when llm_base_url is set; ensure the env file or config provides this value")
+	}
+	return nil
+}
+
+// llmLogDir 返回 LLM 调试日志目录；未配置 data_dir 时与文件存储一样落在 data 下。
+func llmLogDir(config *Config) string {
+	dataDir := config.DataDir
+	if dataDir == "" {
+		dataDir = "data"
+	}
+	return filepath.Join(dataDir, "llm-logs")
+}
+
+// authConfig 返回 HTTP 与 MCP 服务共用的鉴权设置；auth_mode 已在加载配置时校验。
+func authConfig(cfg *Config) utils.AuthConfig {
+	mode, err := utils.ParseAuthMode(cfg.AuthMode)
+	if err != nil {
+		mode = utils.AuthModeToken
+	}
+	return utils.AuthConfig{Mode: mode, Token: cfg.APIToken, JWTSecret: cfg.JWTSecret, AdminToken: cfg.AdminToken}
+}
+
+func llmTransportOptions(cfg *Config) services.LLMTransportOptions {
+	return services.LLMTransportOptions{
+		ProxyURL:            cfg.LLM.ProxyURL,
+		CACertFile:          cfg.LLM.CACertFile,
+		ClientCertFile:      cfg.LLM.ClientCertFile,
+		ClientKeyFile:       cfg.LLM.ClientKeyFile,
+		InsecureSkipVerify:  cfg.LLM.InsecureSkipVerify,
+		MaxIdleConnsPerHost: cfg.LLM.MaxIdleConnsPerHost,
+	}
+}
+
+// newSessionStore 按配置选择文件存储或内存存储。
+func newSessionStore(config *Config) storage.SessionStore {
+	if config.UseFileStore || config.DataDir != "" {
+		return storage.NewFileSessionStore(config.DataDir)
+	}
+	return storage.NewInMemorySessionStore()
+}
+
+func initializeServices(config *Config, sessionStore storage.SessionStore) (*services.ThoughtExpander, *services.SessionManager, *services.LLMOrchestrator, error) {
+	sanitizationMode, err := utils.ParseSanitizationMode(config.SanitizationMode)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	utils.SetSanitizationMode(sanitizationMode)
+	if auth := authConfig(config); auth.Mode == utils.AuthModeJWT && config.RateLimitByUserID {
+		utils.SetRateLimitByUserID(auth.JWTSecret)
+	} else {
+		utils.SetRateLimitByUserID("")
+	}
+
+	sessionManager := services.NewSessionManager(sessionStore)
+	sessionManager.SetMaxThoughtDepth(config.MaxThoughtDepth)
+	llm := services.NewLLMOrchestratorWithOptions(services.LLMOptions{
+		APIKey:             config.LLMAPIKey,
+		BaseURL:            config.LLMBaseURL,
+		Model:              config.LLMModel,
+		Timeout:            time.Duration(config.LLM.TimeoutSeconds) * time.Second,
+		MaxTokens:          config.LLM.MaxTokens,
+		DefaultTemperature: &config.LLM.DefaultTemperature,
+		MaxRetries:         config.LLM.MaxRetries,
+		Transport:          llmTransportOptions(config),
+	})
+	if err := llm.SetProvider(config.LLMProvider); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := llm.SetAPIType(config.LLMAPIType, config.LLMAzureDeployment, config.LLMAzureAPIVersion); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := llm.SetJSONMode(config.LLMJSONMode); err != nil {
+		return nil, nil, nil, err
+	}
+	llm.SetOllamaOptions(config.OllamaNumCtx, config.OllamaKeepAlive)
+	llm.SetContextBudget(config.ContextBudgetTokens)
+	llm.SetModelContextWindows(config.ModelContextWindows)
+	llm.SetResponseCache(config.LLM.CacheSize, time.Duration(config.LLM.CacheTTLSeconds)*time.Second)
+	llm.SetEmbeddingModel(config.LLM.EmbeddingModel)
+	llm.SetHealthCheckCacheTTL(time.Duration(config.LLM.HealthCacheSeconds) * time.Second)
+	llm.SetPricing(config.Pricing)
+	llm.SetPromptExampleLimit(config.PromptExampleLimit)
+	llm.SetSystemPromptPrefix(config.LLM.SystemPromptPrefix)
+	llm.SetDirectionRepair(config.LLM.RepairDirections)
+	llm.SetSchemaValidation(config.LLM.SchemaValidationEnabled)
+	llm.SetToolCalling(config.LLM.UseToolCalling)
+	if err := llm.SetFallbacks(config.LLM.Fallbacks); err != nil {
+		return nil, nil, nil, err
+	}
+	if config.PromptsDir != "" {
+		if err := llm.LoadPromptTemplates(config.PromptsDir); err != nil {
+			utils.Warn("failed to load prompt templates; using built-in templates", utils.KV("dir", config.PromptsDir), utils.KV("error", err))
+		}
+	}
+	if config.LLM.DebugLog {
+		if err := llm.EnableDebugLog(llmLogDir(config), config.LLM.LogRedactions); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	llm.SetBudgetStore(storage.NewInMemoryBudgetStore(), config.LLMTokenBudgetPerUser)
+	llm.SetRateLimit(config.LLM.RateLimitRPM, config.LLM.RateLimitBurst)
+	llm.SetMaxConcurrent(config.LLM.MaxConcurrent)
+	llm.SetCircuitBreaker(utils.NewCircuitBreaker(config.LLMCircuitThreshold, time.Duration(config.LLMCircuitRecoverySecs)*time.Second))
+	if err := llm.SetForceResponseLanguage(config.ForceResponseLanguage); err != nil {
+		return nil, nil, nil, err
+	}
+	sessionManager.SetEmbedder(llm)
+	sessionManager.SetSimilarityAlertThreshold(config.SimilarityAlertThreshold)
+	if config.SessionArchiveDir != "" {
+		if _, err := sessionManager.StartCompactor(context.Background(), config.SessionArchiveDir, services.DefaultCompactionInterval, services.DefaultCompactionMaxIdle, llm.MetricsRegistry()); err != nil {
+			utils.Warn("session compaction disabled", utils.KV("archive_dir", config.SessionArchiveDir), utils.KV("error", err))
+		}
+	}
+	// 后台探测并缓存结果，/readyz 只读取缓存，避免就绪检查受 LLM 延迟影响
+	llm.StartHealthProbe(context.Background(), time.Duration(config.LLMHealthCheckInterval)*time.Second)
+	expander := services.NewThoughtExpander(llm, sessionManager)
+	expander.SetExpansionConcurrency(config.ExpansionConcurrency)
+	expander.SetNodeBudget(config.ExpansionNodeBudget)
+	expander.SetDirectionDedupThreshold(config.DirectionDedupThreshold)
+	dedupPolicy, err := services.ParseThoughtDedupPolicy(config.ThoughtDedupPolicy)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	expander.SetThoughtDedup(config.ThoughtDedupThreshold, dedupPolicy)
+	pathHintStrategy, err := services.ParsePathHintStrategy(config.PathHintStrategy)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	expander.SetPathHints(config.PathHintLimit, pathHintStrategy)
+	thinkingStyle, err := utils.ParseThinkingStyle(config.DefaultThinkingStyle)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := expander.SetDefaultThinkingStyle(thinkingStyle); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return expander, sessionManager, llm, nil
+}
+
+func setupMCPServer(cfg *Config, te *services.ThoughtExpander, sm *services.SessionManager, llm *services.LLMOrchestrator) *mcp.MCPServer {
+	server := mcp.NewMCPServer(te, sm, cfg.APIToken, cfg.MCPRateLimitPerMinute)
+	server.SetAuth(authConfig(cfg))
+	server.SetToolPermissions(cfg.ToolPermissions)
+	server.RegisterTool("expand_thought", mcp.NewExpandThoughtTool(te))
+	server.RegisterTool("explore_direction", mcp.NewExploreDirectionTool(te))
+	server.RegisterTool("auto_explore", mcp.NewAutoExploreTool(te))
+	server.RegisterTool("suggest_next_actions", mcp.NewNextActionsTool(te))
+	server.RegisterTool("reflect_on_session", mcp.NewReflectOnSessionTool(te))
+	server.RegisterTool("auto_structure", mcp.NewAutoStructureTool(te))
+	server.RegisterTool("auto_tag", mcp.NewAutoTagTool(te))
+	server.RegisterTool("complete_thought", mcp.NewCompleteThoughtTool(te))
+	server.RegisterTool("assess_session_readiness", mcp.NewAssessSessionReadinessTool(sm))
+	server.RegisterTool("get_breadcrumb", mcp.NewGetBreadcrumbTool(sm))
+	server.RegisterTool("direction_to_session", mcp.NewDirectionToSessionTool(te))
+	server.RegisterTool("generate_title", mcp.NewGenerateTitleTool(te))
+	server.RegisterTool("recommend_direction", mcp.NewRecommendDirectionTool(te, sm))
+	server.RegisterTool("create_session", mcp.NewCreateSessionTool(sm))
+	server.RegisterTool("get_session", mcp.NewGetSessionTool(sm))
+	server.RegisterTool("list_sessions", mcp.NewListSessionsTool(sm))
+	server.RegisterTool("search_sessions_by_concept", mcp.NewSearchSessionsByConceptTool(sm))
+	server.RegisterTool("delete_session", mcp.NewDeleteSessionTool(sm))
+	server.RegisterTool("update_thought", mcp.NewUpdateThoughtTool(sm))
+	server.RegisterTool("delete_thought", mcp.NewDeleteThoughtTool(sm))
+	server.RegisterTool("add_reaction", mcp.NewAddReactionTool(sm))
+	server.RegisterTool("remove_reaction", mcp.NewRemoveReactionTool(sm))
+	server.RegisterTool("bulk_add_thoughts", mcp.NewBulkAddThoughtsTool(sm))
+	server.RegisterTool("remove_context_entry", mcp.NewRemoveContextEntryTool(sm))
+	server.RegisterTool("update_context_entry", mcp.NewUpdateContextEntryTool(sm))
+	server.RegisterTool("split_thought", mcp.NewSplitThoughtTool(sm))
+	server.RegisterTool("merge_thoughts", mcp.NewMergeThoughtsTool(sm))
+	server.RegisterTool("set_session_root", mcp.NewSetSessionRootTool(sm))
+	server.RegisterTool("compact_session", mcp.NewCompactSessionTool(sm))
+	server.RegisterTool("get_session_activity", mcp.NewGetSessionActivityTool(sm))
+	server.RegisterTool("find_similar_sessions", mcp.NewFindSimilarSessionsTool(sm))
+	server.RegisterTool("get_word_cloud", mcp.NewGetWordCloudTool(sm))
+	server.RegisterTool("measure_distance", mcp.NewMeasureDistanceTool(sm))
+	server.RegisterTool("diff_sessions", mcp.NewDiffSessionsTool(sm))
+	server.RegisterTool("find_thought_by_external_id", mcp.NewFindThoughtByExternalIDTool(sm))
+	server.RegisterTool("export_session", mcp.NewExportSessionTool(sm))
+	server.RegisterTool("import_session", mcp.NewImportSessionTool(sm))
+	server.RegisterTool("export_session_csv", mcp.NewExportSessionCSVTool(sm))
+	server.RegisterTool("export_session_svg", mcp.NewExportSessionSVGTool(sm))
+	server.RegisterTool("get_token_budget", mcp.NewGetTokenBudgetTool(llm))
+	server.RegisterTool("estimate_tokens", mcp.NewEstimateTokensTool(llm))
+	server.RegisterTool("undo_action", mcp.NewUndoActionTool(sm))
+	server.RegisterTool("redo_action", mcp.NewRedoActionTool(sm))
+	server.RegisterTool("create_snapshot", mcp.NewCreateSnapshotTool(sm))
+	server.RegisterTool("list_snapshots", mcp.NewListSnapshotsTool(sm))
+	server.RegisterTool("restore_snapshot", mcp.NewRestoreSnapshotTool(sm))
+	server.RegisterTool("delete_snapshot", mcp.NewDeleteSnapshotTool(sm))
+
+	plugins := mcp.NewPluginManager(server)
+	for _, dir := range cfg.PluginDirs {
+		if _, err := plugins.LoadDir(dir); err != nil {
+			utils.Warn("failed to load MCP tool plugins", utils.KV("dir", dir), utils.KV("error", err))
+		}
+	}
+	return server
+}
+
+func setupWebServer(cfg *Config, sessionManager *services.SessionManager, expander *services.ThoughtExpander, llm *services.LLMOrchestrator) *http.ServeMux {
+	webDir := cfg.WebDir
+	if webDir == "" {
+		webDir = "web"
+	}
+
+	mux := http.NewServeMux()
+	staticDir := filepath.Join(webDir, "static")
+	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir(staticDir))))
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		templatePath := filepath.Join(webDir, "templates", "mindmap.html")
+		http.ServeFile(w, r, templatePath)
+	})
+
+	livenessResponder := func(w http.ResponseWriter, status string) {
+		w.Header().Set("Content-Type", "application/json")
+		response := map[string]interface{}{
+			"status":    status,
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}
+
+	mux.HandleFunc("/livez", func(w http.ResponseWriter, r *http.Request) {
+		livenessResponder(w, "ok")
+	})
+
+	readinessHandler := func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+
+		statusCode := http.StatusOK
+		dependencies := map[string]string{}
+
+		if sessionManager == nil {
+			statusCode = http.StatusServiceUnavailable
+			dependencies["session_store"] = "missing session manager"
+		} else if err := sessionManager.HealthCheck(ctx); err != nil {
+			statusCode = http.StatusServiceUnavailable
+			dependencies["session_store"] = err.Error()
+		} else {
+			dependencies["session_store"] = "ok"
+		}
+
+		if generator := expander.Generator(); generator == nil {
+			statusCode = http.StatusServiceUnavailable
+			dependencies["llm_orchestrator"] = "missing orchestrator"
+		} else if health := llm.HealthStatus(); !health.Monitored {
+			dependencies["llm_orchestrator"] = "ok (not monitored)"
+		} else if !health.Checked {
+			statusCode = http.StatusServiceUnavailable
+			dependencies["llm_orchestrator"] = "waiting for first health check"
+		} else if !health.Healthy {
+			statusCode = http.StatusServiceUnavailable
+			dependencies["llm_orchestrator"] = health.Error
+		} else {
+			dependencies["llm_orchestrator"] = "ok"
+		}
+
+		statusLabel := "ok"
+		if statusCode != http.StatusOK {
+			statusLabel = "unavailable"
+		}
+
+		payload := map[string]interface{}{
+			"status":       statusLabel,
+			"dependencies": dependencies,
+			"timestamp":    time.Now().UTC().Format(time.RFC3339),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		_ = json.NewEncoder(w).Encode(payload)
+	}
+
+	mux.HandleFunc("/healthz", readinessHandler)
+	mux.HandleFunc("/readyz", readinessHandler)
+
+	rateLimiter := utils.NewRateLimiter(cfg.HTTPRateLimitPerMinute, time.Minute)
+	locales := loadLocaleBundle(cfg)
+	auth := authConfig(cfg)
+
+	wrap := func(handler http.HandlerFunc, secure bool, limited bool) http.Handler {
+		h := withLocale(handler, locales)
+		if limited && rateLimiter != nil {
+			next := h
+			h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				token := utils.ResolveRequestToken(r)
+				key := utils.ClientKey(r, token)
+				if !rateLimiter.Allow(key) {
+					http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		}
+		if secure && auth.Enabled() {
+			next := h
+			h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				token := utils.ResolveRequestToken(r)
+				if !auth.Authorize(token) {
+					http.Error(w, "unauthorized", http.StatusUnauthorized)
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		}
+		return h
+	}
+	// admin 要求请求额外携带 admin_token，在 wrap 的鉴权与限流之后执行
+	admin := func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !auth.AuthorizeAdmin(r.Header.Get(utils.AdminTokenHeader)) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			handler(w, r)
+		}
+	}
+
+	mux.Handle("/api/sessions", wrap(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			userID := strings.TrimSpace(r.URL.Query().Get("user_id"))
+			if userID == "" {
+				respondError(w, utils.ValidationError("user_id is required"))
+				return
+			}
+			if err := utils.ValidateUserID(userID); err != nil {
+				respondError(w, err)
+				return
+			}
+			query := r.URL.Query()
+			sessions, err := sessionManager.SearchSessions(userID, query.Get("concept"), query.Get("tag"))
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, sessions)
+		case http.MethodPost:
+			var payload struct {
+				UserID  string `json:"user_id"`
+				Concept string `json:"concept"`
+			}
+			if err := decodeJSONBody(w, r, &payload); err != nil {
+				respondError(w, err)
+				return
+			}
+			payload.UserID = strings.TrimSpace(payload.UserID)
+			payload.Concept = strings.TrimSpace(payload.Concept)
+
+			if err := utils.ValidateUserID(payload.UserID); err != nil {
+				respondError(w, err)
+				return
+			}
+			if err := utils.ValidateConcept(payload.Concept); err != nil {
+				respondError(w, err)
+				return
+			}
+
+			session, err := sessionManager.CreateSession(payload.UserID, payload.Concept)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, session)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}, true, true))
+
+	mux.Handle("/api/sessions/import", wrap(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handleSessionImport(w, r, sessionManager)
+	}, true, true))
+
+	mux.Handle("/api/sessions/import/file", wrap(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handleSessionImportFile(w, r, sessionManager)
+	}, true, true))
+
+	mux.Handle("/api/sessions/", wrap(func(w http.ResponseWriter, r *http.Request) {
+		trimmed := strings.TrimSpace(strings.TrimPrefix(r.URL.Path, "/api/sessions/"))
+		if trimmed == "" {
+			http.Error(w, "session id is required", http.StatusBadRequest)
+			return
+		}
+
+		parts := make([]string, 0)
+		for _, segment := range strings.Split(trimmed, "/") {
+			if segment = strings.TrimSpace(segment); segment != "" {
+				parts = append(parts, segment)
+			}
+		}
+		if len(parts) == 0 {
+			http.Error(w, "session id is required", http.StatusBadRequest)
+			return
+		}
+
+		sessionID := parts[0]
+		if err := utils.ValidateSessionID(sessionID); err != nil {
+			respondError(w, err)
+			return
+		}
+
+		if len(parts) >= 2 && parts[1] == "export" {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			handleSessionExport(w, r, sessionManager, sessionID)
+			return
+		}
+
+		if len(parts) >= 2 && parts[1] == "timeline" {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			bucket, err := timelineBucketFromQuery(r.URL.Query())
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			session, err := sessionManager.GetSession(sessionID)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, session.Timeline(bucket))
+			return
+		}
+
+		if len(parts) >= 2 && parts[1] == "activity" {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			activity, err := sessionManager.SessionActivity(sessionID)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, activity)
+			return
+		}
+
+		if len(parts) >= 2 && parts[1] == "usage" {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			session, err := sessionManager.GetSession(sessionID)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, session.Usage)
+			return
+		}
+
+		if len(parts) >= 2 && parts[1] == "similar" {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			top := services.DefaultSimilarSessions
+			if raw := strings.TrimSpace(r.URL.Query().Get("top")); raw != "" {
+				parsed, err := strconv.Atoi(raw)
+				if err != nil || parsed <= 0 {
+					respondError(w, utils.ValidationError("top must be a positive integer"))
+					return
+				}
+				top = parsed
+			}
+			similar, err := sessionManager.FindSimilarSessions(sessionID, top)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, similar)
+			return
+		}
+
+		if len(parts) >= 2 && parts[1] == "word-cloud" {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			top := services.DefaultWordCloudSize
+			if raw := strings.TrimSpace(r.URL.Query().Get("top")); raw != "" {
+				parsed, err := strconv.Atoi(raw)
+				if err != nil || parsed <= 0 {
+					respondError(w, utils.ValidationError("top must be a positive integer"))
+					return
+				}
+				top = parsed
+			}
+			words, err := sessionManager.WordCloud(sessionID, top)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, words)
+			return
+		}
+
+		if len(parts) >= 2 && parts[1] == "mindmap.svg" {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			width, height := models.DefaultSVGWidth, models.DefaultSVGHeight
+			if raw := strings.TrimSpace(r.URL.Query().Get("width")); raw != "" {
+				parsed, err := strconv.Atoi(raw)
+				if err != nil {
+					respondError(w, utils.ValidationError("width and height must be between 1 and 10000"))
+					return
+				}
+				width = parsed
+			}
+			if raw := strings.TrimSpace(r.URL.Query().Get("height")); raw != "" {
+				parsed, err := strconv.Atoi(raw)
+				if err != nil {
+					respondError(w, utils.ValidationError("width and height must be between 1 and 10000"))
+					return
+				}
+				height = parsed
+			}
+			if err := utils.ValidateSVGDimensions(width, height); err != nil {
+				respondError(w, err)
+				return
+			}
+			session, err := sessionManager.GetSession(sessionID)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			data, err := session.ToSVG(width, height)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "image/svg+xml")
+			_, _ = w.Write(data)
+			return
+		}
+
+		if len(parts) >= 2 && parts[1] == "diff" {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			otherID := strings.TrimSpace(r.URL.Query().Get("other"))
+			if err := utils.ValidateSessionID(otherID); err != nil {
+				respondError(w, err)
+				return
+			}
+			diff, err := sessionManager.DiffSessions(sessionID, otherID)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			if strings.EqualFold(r.URL.Query().Get("format"), "text") {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				_, _ = w.Write([]byte(diff.String()))
+				return
+			}
+			respondJSON(w, diff)
+			return
+		}
+
+		if len(parts) >= 2 && parts[1] == "compact" {
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			var payload struct {
+				MinRelevance *float64 `json:"min_relevance"`
+			}
+			if err := decodeJSONBody(w, r, &payload); err != nil {
+				respondError(w, err)
+				return
+			}
+			if payload.MinRelevance == nil {
+				respondError(w, utils.ValidationError("min_relevance is required"))
+				return
+			}
+			removed, err := sessionManager.CompactSession(sessionID, *payload.MinRelevance)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, map[string]interface{}{
+				"session_id": sessionID,
+				"removed":    removed,
+			})
+			return
+		}
+
+		if len(parts) >= 2 && parts[1] == "root" {
+			if r.Method != http.MethodPut {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			var payload struct {
+				Content   string            `json:"content"`
+				Direction *models.Direction `json:"direction"`
+			}
+			if err := decodeJSONBody(w, r, &payload); err != nil {
+				respondError(w, err)
+				return
+			}
+			session, err := sessionManager.SetRootThought(sessionID, payload.Content, payload.Direction)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, session)
+			return
+		}
+
+		if len(parts) >= 2 && parts[1] == "context" {
+			if len(parts) != 3 {
+				http.Error(w, "context index is required", http.StatusBadRequest)
+				return
+			}
+			index, err := strconv.Atoi(parts[2])
+			if err != nil {
+				respondError(w, utils.ValidationError("context index must be an integer"))
+				return
+			}
+			var session *models.Session
+			switch r.Method {
+			case http.MethodDelete:
+				session, err = sessionManager.RemoveContextEntry(sessionID, index)
+			case http.MethodPatch:
+				var payload struct {
+					Kind  string `json:"kind"`
+					Value string `json:"value"`
+				}
+				if err := decodeJSONBody(w, r, &payload); err != nil {
+					respondError(w, err)
+					return
+				}
+				entry := models.ContextEntry{Value: payload.Value}
+				if strings.TrimSpace(payload.Kind) != "" {
+					kind, ok := models.ParseContextKind(payload.Kind)
+					if !ok {
+						respondError(w, utils.ValidationError("context entry has an unknown kind"))
+						return
+					}
+					entry.Kind = kind
+				}
+				session, err = sessionManager.UpdateContextEntry(sessionID, index, entry)
+			default:
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, session)
+			return
+		}
+
+		if len(parts) >= 2 && parts[1] == "reflect" {
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			report, err := expander.ReflectOnSession(sessionID)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, map[string]interface{}{
+				"session_id": sessionID,
+				"reflection": report,
+			})
+			return
+		}
+
+		if len(parts) >= 2 && parts[1] == "stats" {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			session, err := sessionManager.GetSession(sessionID)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, session.Stats())
+			return
+		}
+
+		if len(parts) >= 2 && parts[1] == "breadcrumb" {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			thoughtID := strings.TrimSpace(r.URL.Query().Get("thought_id"))
+			if thoughtID == "" {
+				respondError(w, utils.ValidationError("thought_id is required"))
+				return
+			}
+			session, err := sessionManager.GetSession(sessionID)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			breadcrumb, err := session.GetBreadcrumb(thoughtID)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, breadcrumb)
+			return
+		}
+
+		if len(parts) >= 2 && parts[1] == "mind-map-data" {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			session, err := sessionManager.GetSession(sessionID)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, session.BuildMindMapData())
+			return
+		}
+
+		if len(parts) >= 2 && parts[1] == "readiness" {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			report, err := sessionManager.AssessReadiness(sessionID)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, report)
+			return
+		}
+
+		if len(parts) >= 2 && parts[1] == "generate-title" {
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			var payload struct {
+				Apply bool `json:"apply"`
+			}
+			if err := decodeJSONBody(w, r, &payload); err != nil {
+				respondError(w, err)
+				return
+			}
+			title, err := expander.GenerateSessionTitle(sessionID, payload.Apply)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, title)
+			return
+		}
+
+		if len(parts) >= 2 && parts[1] == "auto-tag" {
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			session, err := expander.AutoTagSession(sessionID)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, session)
+			return
+		}
+
+		if len(parts) >= 2 && parts[1] == "recommend" {
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			var payload struct {
+				Directions []models.Direction `json:"directions"`
+			}
+			if err := decodeJSONBody(w, r, &payload); err != nil {
+				respondError(w, err)
+				return
+			}
+			session, err := sessionManager.GetSession(sessionID)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			recommended, rationale, err := expander.RecommendDirection(payload.Directions, nil, session)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, map[string]interface{}{
+				"session_id": sessionID,
+				"direction":  recommended,
+				"rationale":  rationale,
+			})
+			return
+		}
+
+		if len(parts) >= 2 && parts[1] == "auto-structure" {
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			var payload struct {
+				Confirm bool `json:"confirm"`
+			}
+			if err := decodeJSONBody(w, r, &payload); err != nil {
+				respondError(w, err)
+				return
+			}
+			if !payload.Confirm {
+				respondError(w, utils.ValidationError("confirm must be true"))
+				return
+			}
+			session, err := expander.AutoStructure(sessionID)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, session)
+			return
+		}
+
+		if len(parts) >= 2 && parts[1] == "snapshots" {
+			switch {
+			case len(parts) == 2 && r.Method == http.MethodPost:
+				var payload struct {
+					Name string `json:"name"`
+				}
+				if err := decodeJSONBody(w, r, &payload); err != nil {
+					respondError(w, err)
+					return
+				}
+				snapshot, err := sessionManager.CreateSnapshot(sessionID, strings.TrimSpace(payload.Name))
+				if err != nil {
+					respondError(w, err)
+					return
+				}
+				respondJSON(w, snapshot)
+			case len(parts) == 2 && r.Method == http.MethodGet:
+				snapshots, err := sessionManager.ListSnapshots(sessionID)
+				if err != nil {
+					respondError(w, err)
+					return
+				}
+				respondJSON(w, snapshots)
+			case len(parts) == 4 && parts[3] == "restore" && r.Method == http.MethodPost:
+				session, err := sessionManager.RestoreSnapshot(sessionID, parts[2])
+				if err != nil {
+					respondError(w, err)
+					return
+				}
+				respondJSON(w, session)
+			case len(parts) == 3 && r.Method == http.MethodDelete:
+				if err := sessionManager.DeleteSnapshot(sessionID, parts[2]); err != nil {
+					respondError(w, err)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			case len(parts) == 2 || len(parts) == 3 || (len(parts) == 4 && parts[3] == "restore"):
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			default:
+				http.NotFound(w, r)
+			}
+			return
+		}
+
+		if len(parts) >= 2 && (parts[1] == "undo" || parts[1] == "redo") {
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			restore := sessionManager.Undo
+			if parts[1] == "redo" {
+				restore = sessionManager.Redo
+			}
+			session, err := restore(sessionID)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, session)
+			return
+		}
+
+		if len(parts) >= 2 && parts[1] == "thoughts" {
+			if len(parts) < 3 {
+				http.Error(w, "thought id is required", http.StatusBadRequest)
+				return
+			}
+			if parts[2] == "distance" && len(parts) == 3 {
+				if r.Method != http.MethodGet {
+					http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				query := r.URL.Query()
+				thoughtA, thoughtB := strings.TrimSpace(query.Get("a")), strings.TrimSpace(query.Get("b"))
+				if thoughtA == "" || thoughtB == "" {
+					respondError(w, utils.ValidationError("query parameters a and b are required"))
+					return
+				}
+				distance, err := sessionManager.MeasureThoughtDistance(sessionID, thoughtA, thoughtB)
+				if err != nil {
+					respondError(w, err)
+					return
+				}
+				respondJSON(w, distance)
+				return
+			}
+			if parts[2] == "batch" && len(parts) == 3 {
+				if r.Method != http.MethodPost {
+					http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				var payload struct {
+					Thoughts []services.BulkThought `json:"thoughts"`
+				}
+				if err := decodeJSONBody(w, r, &payload); err != nil {
+					respondError(w, err)
+					return
+				}
+				result, err := sessionManager.BulkAddThoughts(sessionID, payload.Thoughts)
+				if err != nil {
+					respondError(w, err)
+					return
+				}
+				respondJSON(w, result)
+				return
+			}
+			if parts[2] == "by-external-id" {
+				if r.Method != http.MethodGet {
+					http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				if len(parts) != 4 {
+					http.Error(w, "external id is required", http.StatusBadRequest)
+					return
+				}
+				thought, err := sessionManager.FindThoughtByExternalID(sessionID, parts[3])
+				if err != nil {
+					respondError(w, err)
+					return
+				}
+				respondJSON(w, thought)
+				return
+			}
+			thoughtID := parts[2]
+			if len(parts) >= 4 && parts[3] == "merge" {
+				if r.Method != http.MethodPost {
+					http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				var payload struct {
+					SiblingID string `json:"sibling_id"`
+					Separator string `json:"separator"`
+					Force     bool   `json:"force"`
+				}
+				if err := decodeJSONBody(w, r, &payload); err != nil {
+					respondError(w, err)
+					return
+				}
+				force := payload.Force
+				if raw := strings.TrimSpace(r.URL.Query().Get("force")); raw != "" {
+					parsed, err := strconv.ParseBool(raw)
+					if err != nil {
+						respondError(w, utils.ValidationError("force must be a boolean"))
+						return
+					}
+					force = force || parsed
+				}
+				session, err := sessionManager.MergeThoughts(sessionID, thoughtID, strings.TrimSpace(payload.SiblingID), payload.Separator, force)
+				if err != nil {
+					respondError(w, err)
+					return
+				}
+				respondJSON(w, session)
+				return
+			}
+			if len(parts) >= 4 && parts[3] == "expand" {
+				if r.Method != http.MethodPost {
+					http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				var payload struct {
+					ExpansionType        string `json:"expansion_type"`
+					MaxDirections        int    `json:"max_directions"`
+					MaxTotalThoughts     int    `json:"max_total_thoughts"`
+					ForceIncludeExisting bool   `json:"force_include_existing"`
+					PathHintLimit        int    `json:"path_hint_limit"`
+					PathHintStrategy     string `json:"path_hint_strategy"`
+				}
+				if err := decodeJSONBody(w, r, &payload); err != nil {
+					respondError(w, err)
+					return
+				}
+				if payload.MaxDirections < 0 || payload.MaxTotalThoughts < 0 {
+					respondError(w, utils.ValidationError("max_directions and max_total_thoughts must not be negative"))
+					return
+				}
+				req := &services.ExpansionRequest{
+					SessionID:            sessionID,
+					ThoughtID:            thoughtID,
+					MaxDirections:        payload.MaxDirections,
+					MaxTotalThoughts:     payload.MaxTotalThoughts,
+					ForceIncludeExisting: payload.ForceIncludeExisting,
+					PathHints:            services.PathHintOptions{Limit: payload.PathHintLimit, Strategy: services.PathHintStrategy(payload.PathHintStrategy)},
+				}
+				if trimmed := strings.TrimSpace(payload.ExpansionType); trimmed != "" {
+					dirType, err := utils.ParseDirectionType(trimmed)
+					if err != nil {
+						respondError(w, err)
+						return
+					}
+					req.ExpansionType = dirType
+				}
+				result, err := expander.ExpandContext(r.Context(), req)
+				if err != nil {
+					respondError(w, err)
+					return
+				}
+				respondJSON(w, result)
+				return
+			}
+			if len(parts) >= 4 && parts[3] == "fork-as-session" {
+				if r.Method != http.MethodPost {
+					http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				var payload struct {
+					UserID string `json:"user_id"`
+				}
+				if err := decodeJSONBody(w, r, &payload); err != nil {
+					respondError(w, err)
+					return
+				}
+				userID := strings.TrimSpace(payload.UserID)
+				if userID != "" {
+					if err := utils.ValidateUserID(userID); err != nil {
+						respondError(w, err)
+						return
+					}
+				}
+				session, err := expander.DirectionToSession(sessionID, thoughtID, userID)
+				if err != nil {
+					respondError(w, err)
+					return
+				}
+				respondJSON(w, session)
+				return
+			}
+			if len(parts) >= 4 && parts[3] == "complete" {
+				if r.Method != http.MethodPost {
+					http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				var payload struct {
+					PartialContent string `json:"partial_content"`
+					Apply          bool   `json:"apply"`
+				}
+				if err := decodeJSONBody(w, r, &payload); err != nil {
+					respondError(w, err)
+					return
+				}
+				completion, err := expander.CompleteThought(sessionID, thoughtID, payload.PartialContent, payload.Apply)
+				if err != nil {
+					respondError(w, err)
+					return
+				}
+				respondJSON(w, completion)
+				return
+			}
+			if len(parts) >= 4 && parts[3] == "split" {
+				if r.Method != http.MethodPost {
+					http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				var payload struct {
+					SplitBy string   `json:"split_by"`
+					Parts   []string `json:"parts"`
+				}
+				if err := decodeJSONBody(w, r, &payload); err != nil {
+					respondError(w, err)
+					return
+				}
+				session, err := sessionManager.SplitThought(sessionID, thoughtID, models.SplitMode(strings.ToLower(strings.TrimSpace(payload.SplitBy))), payload.Parts)
+				if err != nil {
+					respondError(w, err)
+					return
+				}
+				respondJSON(w, session)
+				return
+			}
+			if len(parts) >= 4 && parts[3] == "reactions" {
+				var err error
+				switch {
+				case len(parts) == 4 && r.Method == http.MethodGet:
+				case len(parts) == 4 && r.Method == http.MethodPost:
+					var payload struct {
+						Emoji string `json:"emoji"`
+					}
+					if err := decodeJSONBody(w, r, &payload); err != nil {
+						respondError(w, err)
+						return
+					}
+					err = sessionManager.AddReaction(sessionID, thoughtID, payload.Emoji)
+				case len(parts) == 5 && r.Method == http.MethodDelete:
+					err = sessionManager.RemoveReaction(sessionID, thoughtID, parts[4])
+				default:
+					http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				if err != nil {
+					respondError(w, err)
+					return
+				}
+				reactions, err := sessionManager.Reactions(sessionID, thoughtID)
+				if err != nil {
+					respondError(w, err)
+					return
+				}
+				respondJSON(w, reactions)
+				return
+			}
+			switch r.Method {
+			case http.MethodPatch:
+				var payload models.ThoughtUpdate
+				if err := decodeJSONBody(w, r, &payload); err != nil {
+					respondError(w, err)
+					return
+				}
+				if err := utils.ValidateThoughtUpdate(&payload); err != nil {
+					respondError(w, err)
+					return
+				}
+				thought, err := sessionManager.UpdateThought(sessionID, thoughtID, &payload)
+				if err != nil {
+					respondError(w, err)
+					return
+				}
+				respondJSON(w, thought)
+			case http.MethodDelete:
+				session, err := sessionManager.DeleteThought(sessionID, thoughtID)
+				if err != nil {
+					respondError(w, err)
+					return
+				}
+				respondJSON(w, session)
+			default:
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			session, err := sessionManager.GetSession(sessionID)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, session)
+		case http.MethodPost:
+			var payload struct {
+				Direction        models.Direction `json:"direction"`
+				ParentID         string           `json:"parent_id"`
+				PathHintLimit    int              `json:"path_hint_limit"`
+				PathHintStrategy string           `json:"path_hint_strategy"`
+			}
+			if err := decodeJSONBody(w, r, &payload); err != nil {
+				respondError(w, err)
+				return
+			}
+			if err := utils.ValidateDirection(&payload.Direction); err != nil {
+				respondError(w, err)
+				return
+			}
+			hints := services.PathHintOptions{Limit: payload.PathHintLimit, Strategy: services.PathHintStrategy(payload.PathHintStrategy)}
+			thought, err := expander.ExploreDirectionWithHints(payload.Direction, sessionID, strings.TrimSpace(payload.ParentID), hints)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, thought)
+		case http.MethodDelete:
+			if err := sessionManager.DeleteSession(sessionID); err != nil {
+				respondError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}, true, true))
+
+	mux.Handle("/api/users/", wrap(func(w http.ResponseWriter, r *http.Request) {
+		userID, resource, ok := parseUserPath(r.URL.Path, "/api/users/")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := utils.ValidateUserID(userID); err != nil {
+			respondError(w, err)
+			return
+		}
+		switch resource {
+		case "budget":
+			budget, err := llm.GetBudget(userID)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, budget)
+		case "timeline":
+			bucket, err := timelineBucketFromQuery(r.URL.Query())
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			timeline, err := sessionManager.UserTimeline(userID, bucket)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, timeline)
+		case "usage":
+			usage, err := sessionManager.UserUsage(userID)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			respondJSON(w, usage)
+		default:
+			http.NotFound(w, r)
+		}
+	}, true, true))
+
+	mux.Handle("/api/admin/users/", wrap(admin(func(w http.ResponseWriter, r *http.Request) {
+		userID, resource, ok := parseUserPath(r.URL.Path, "/api/admin/users/")
+		if !ok || resource != "budget" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := utils.ValidateUserID(userID); err != nil {
+			respondError(w, err)
+			return
+		}
+		var payload struct {
+			TotalTokensLimit int    `json:"total_tokens_limit"`
+			Period           string `json:"period"`
+		}
+		if err := decodeJSONBody(w, r, &payload); err != nil {
+			respondError(w, err)
+			return
+		}
+		budget, err := llm.SetBudgetLimit(userID, payload.TotalTokensLimit, payload.Period)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		respondJSON(w, budget)
+	}), true, true))
+
+	mux.Handle("/api/admin/prompts", wrap(admin(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		respondJSON(w, llm.PromptTemplates())
+	}), true, true))
+
+	mux.Handle("/api/admin/prompts/self-improve", wrap(admin(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var payload struct {
+			Prompt  string   `json:"prompt"`
+			Concept string   `json:"concept"`
+			Context []string `json:"context"`
+		}
+		if err := decodeJSONBody(w, r, &payload); err != nil {
+			respondError(w, err)
+			return
+		}
+		result, err := llm.WithContext(r.Context()).ImprovePrompt(payload.Prompt, payload.Concept, payload.Context)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		respondJSON(w, result)
+	}), true, true))
+
+	mux.Handle("/api/admin/llm-calls", wrap(admin(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		limit := defaultRecentLLMCallsLimit
+		if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed <= 0 {
+				respondError(w, utils.ValidationError("limit must be a positive integer"))
+				return
+			}
+			limit = parsed
+		}
+		respondJSON(w, llm.GetRecentLLMCalls(limit))
+	}), true, true))
+
+	mux.Handle("/api/admin/llm/stats", wrap(admin(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		respondJSON(w, llm.Metrics())
+	}), true, true))
+
+	mux.Handle("/api/health/detailed", wrap(admin(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		respondJSON(w, services.CheckDetailedHealth(r.Context(), sessionManager, llm))
+	}), true, true))
+
+	mux.Handle("/metrics", wrap(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := llm.MetricsRegistry().WritePrometheus(w); err != nil {
+			utils.Warn("failed to write metrics", utils.KV("error", err))
+		}
+	}, true, true))
+
+	mux.Handle("/api/llm/circuit-status", wrap(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		respondJSON(w, llm.CircuitStatus())
+	}, true, true))
+
+	mux.Handle("/api/llm/stats", wrap(admin(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		respondJSON(w, llm.Stats())
+	}), true, true))
+
+	mux.Handle("/api/expand", wrap(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		req, err := decodeExpansionRequest(w, r)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		if req.UserID, err = resolveRequestUser(auth, r, req.UserID); err != nil {
+			respondError(w, err)
+			return
+		}
+
+		result, err := expander.ExpandContext(r.Context(), req)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		respondJSON(w, result)
+	}, true, true))
+
+	mux.Handle("/api/expand/stream", wrap(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		req, err := decodeExpansionRequest(w, r)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		if req.UserID, err = resolveRequestUser(auth, r, req.UserID); err != nil {
+			respondError(w, err)
+			return
+		}
+
+		rc := http.NewResponseController(w)
+		// 流式响应可能超过服务器的写超时
+		_ = rc.SetWriteDeadline(time.Time{})
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		_ = rc.Flush()
+
+		result, err := expander.ExpandStream(r.Context(), req, func(delta services.ExpansionDelta) {
+			if writeSSE(w, "delta", delta) == nil {
+				_ = rc.Flush()
+			}
+		})
+		if err != nil {
+			if r.Context().Err() != nil {
+				return
+			}
+			_ = writeSSE(w, "error", map[string]interface{}{"status": statusFromError(err), "error": err.Error()})
+			_ = rc.Flush()
+			return
+		}
+		_ = writeSSE(w, "result", result)
+		_ = rc.Flush()
+	}, true, true))
+
+	mux.Handle("/api/auto-explore", wrap(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		req, err := decodeAutoExploreRequest(w, r)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		if req.UserID, err = resolveRequestUser(auth, r, req.UserID); err != nil {
+			respondError(w, err)
+			return
+		}
+
+		session, err := expander.AutoExplore(r.Context(), *req)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		respondJSON(w, session)
+	}, true, true))
+
+	return mux
+}
+
+func handleSessionExport(w http.ResponseWriter, r *http.Request, sessionManager *services.SessionManager, sessionID string) {
+	session, err := sessionManager.GetSession(sessionID)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	format, err := models.ParseExportFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	if format == models.ExportJSON {
+		// 大会话直接流式写出，不在内存中拼装完整 JSON
+		w.Header().Set("Content-Type", "application/json")
+		if err := session.WriteJSON(w); err != nil {
+			utils.Warn("failed to stream session export", utils.KV("session_id", sessionID), utils.KV("error", err))
+		}
+		return
+	}
+
+	opts, err := exportOptionsFromQuery(r.URL.Query())
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	doc, err := session.Export(format, opts)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	respondAttachment(w, doc.ContentType, doc.Filename, doc.Data)
+}
+
+func exportOptionsFromQuery(query url.Values) (models.ExportOptions, error) {
+	opts := models.DefaultExportOptions()
+
+	style, ok := models.ParseMarkdownStyle(query.Get("style"))
+	if !ok {
+		return opts, utils.ValidationError("style must be headings or bullets")
+	}
+	opts.Markdown.Style = style
+
+	if raw := strings.TrimSpace(query.Get("max_depth")); raw != "" {
+		depth, err := strconv.Atoi(raw)
+		if err != nil || depth < 0 {
+			return opts, utils.ValidationError("max_depth must be a non-negative integer")
+		}
+		opts.Markdown.MaxDepth = depth
+	}
+
+	flags := map[string]*bool{
+		"include_descriptions": &opts.Markdown.IncludeDescriptions,
+		"include_keywords":     &opts.Markdown.IncludeKeywords,
+		"include_metadata":     &opts.Markdown.IncludeMetadata,
+	}
+	for key, target := range flags {
+		raw := strings.TrimSpace(query.Get(key))
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return opts, utils.ValidationError(fmt.Sprintf("%s must be a boolean", key))
+		}
+		*target = value
+	}
+
+	return opts, nil
+}
+
+func handleSessionImport(w http.ResponseWriter, r *http.Request, sessionManager *services.SessionManager) {
+	query := r.URL.Query()
+	userID := strings.TrimSpace(query.Get("user_id"))
+	if err := utils.ValidateUserID(userID); err != nil {
+		respondError(w, err)
+		return
+	}
+
+	formatRaw := query.Get("format")
+	if strings.TrimSpace(formatRaw) == "" {
+		formatRaw = string(models.ExportOPML)
+	}
+	format, err := models.ParseExportFormat(formatRaw)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		respondError(w, utils.ValidationError("request body is too large or unreadable"))
+		return
+	}
+
+	imported, err := models.ImportSession(format, data)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	session, err := sessionManager.ImportSession(userID, imported)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, session)
+}
+
+// handleSessionImportFile 处理 multipart/form-data 上传的会话文件（.json、.json.gz、.md），按扩展名选择解析器。
+func handleSessionImportFile(w http.ResponseWriter, r *http.Request, sessionManager *services.SessionManager) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileBytes+64*1024)
+	if err := r.ParseMultipartForm(maxImportFileBytes); err != nil {
+		respondError(w, utils.ValidationError("request must be multipart/form-data no larger than 5 MB"))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	userID := strings.TrimSpace(r.FormValue("user_id"))
+	if err := utils.ValidateUserID(userID); err != nil {
+		respondError(w, err)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		respondError(w, utils.ValidationError("file field is required"))
+		return
+	}
+	defer file.Close()
+
+	filename, err := importFilename(header.Header.Get("Content-Disposition"))
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	if header.Size > maxImportFileBytes {
+		respondError(w, utils.ValidationError("file is larger than 5 MB"))
+		return
+	}
+
+	var reader io.Reader = file
+	lower := strings.ToLower(filename)
+	if strings.HasSuffix(lower, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			respondError(w, utils.ValidationError("file is not valid gzip"))
+			return
+		}
+		defer gz.Close()
+		reader = gz
+		lower = strings.TrimSuffix(lower, ".gz")
+	}
+
+	var format models.ExportFormat
+	switch {
+	case strings.HasSuffix(lower, ".json"):
+		format = models.ExportJSON
+	case strings.HasSuffix(lower, ".md"):
+		format = models.ExportMarkdown
+	default:
+		respondError(w, utils.ValidationError("file must be .json, .json.gz or .md"))
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(reader, maxImportFileBytes+1))
+	if err != nil {
+		respondError(w, utils.ValidationError("file is unreadable"))
+		return
+	}
+	if int64(len(data)) > maxImportFileBytes {
+		respondError(w, utils.ValidationError("decompressed file is larger than 5 MB"))
+		return
+	}
+
+	imported, err := models.ImportSession(format, data)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	session, err := sessionManager.ImportSession(userID, imported)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, session)
+}
+
+// importFilename 从原始 Content-Disposition 中取出文件名，拒绝包含路径、控制字符或隐藏文件等可疑名称。
+func importFilename(disposition string) (string, error) {
+	_, params, err := mime.ParseMediaType(disposition)
+	if err != nil {
+		return "", utils.ValidationError("invalid Content-Disposition header")
+	}
+	filename := params["filename"]
+	switch {
+	case filename == "", len(filename) > maxImportFilename:
+		return "", utils.ValidationError("file name is missing or too long")
+	case strings.ContainsAny(filename, `/\:`), strings.Contains(filename, ".."), strings.HasPrefix(filename, "."):
+		return "", utils.ValidationError("file name must not contain path components")
+	}
+	for _, r := range filename {
+		if r < 0x20 || r == 0x7f {
+			return "", utils.ValidationError("file name contains control characters")
+		}
+	}
+	return filename, nil
+}
+
+// parseUserPath 解析 {prefix}{id}/{resource} 形式的路径，返回用户 ID 与资源名。
+func parseUserPath(path, prefix string) (string, string, bool) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(path, prefix), "/"), "/")
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+func timelineBucketFromQuery(query url.Values) (time.Duration, error) {
+	raw := strings.TrimSpace(query.Get("bucket"))
+	if raw == "" {
+		return models.DefaultTimelineBucket, nil
+	}
+	bucket, err := time.ParseDuration(raw)
+	if err != nil || bucket < time.Minute {
+		return 0, utils.ValidationError("bucket must be a duration of at least 1m")
+	}
+	return bucket, nil
+}
+
+// resolveRequestUser 确定计入令牌预算的用户：jwt 模式下取已校验令牌的 sub，请求中的 user_id 须与之一致；
+// 其他模式下 user_id 必填，避免省略用户标识绕过预算。
+func resolveRequestUser(auth utils.AuthConfig, r *http.Request, userID string) (string, error) {
+	if subject := auth.UserID(utils.ResolveRequestToken(r)); subject != "" {
+		if userID != "" && userID != subject {
+			return "", fmt.Errorf("%w: user_id does not match the authenticated user", appErrors.ErrForbidden)
+		}
+		return subject, nil
+	}
+	if userID == "" {
+		return "", utils.ValidationError("user_id is required")
+	}
+	return userID, nil
+}
+
+// decodeExpansionRequest 解析并校验 /api/expand 与 /api/expand/stream 的请求体。
+func decodeExpansionRequest(w http.ResponseWriter, r *http.Request) (*services.ExpansionRequest, error) {
+	var payload struct {
+		Concept              string                `json:"concept"`
+		Context              []models.ContextEntry `json:"context"`
+		ExpansionType        string                `json:"expansion_type"`
+		UserID               string                `json:"user_id"`
+		ConcurrencyLimit     int                   `json:"concurrency_limit"`
+		MaxTotalThoughts     int                   `json:"max_total_thoughts"`
+		NoCache              bool                  `json:"no_cache"`
+		ThinkingStyle        string                `json:"thinking_style"`
+		SessionID            string                `json:"session_id"`
+		MaxExamples          *int                  `json:"max_examples"`
+		Language             string                `json:"language"`
+		SystemPrompt         string                `json:"system_prompt"`
+		ExtraInstructions    []string              `json:"extra_instructions"`
+		Persist              bool                  `json:"persist"`
+		ParentID             string                `json:"parent_id"`
+		MinRelevance         float64               `json:"min_relevance"`
+		FallbackIfEmpty      bool                  `json:"fallback_if_empty"`
+		IncludeSummary       bool                  `json:"include_summary"`
+		ForceIncludeExisting bool                  `json:"force_include_existing"`
+	}
+	if err := decodeJSONBody(w, r, &payload); err != nil {
+		return nil, err
+	}
+	thinkingStyle, err := utils.ParseThinkingStyle(payload.ThinkingStyle)
+	if err != nil {
+		return nil, err
+	}
+	payload.SessionID = strings.TrimSpace(payload.SessionID)
+	if payload.SessionID != "" {
+		if err := utils.ValidateSessionID(payload.SessionID); err != nil {
+			return nil, err
+		}
+	} else if payload.Persist {
+		return nil, utils.ValidationError("session_id is required to persist an expansion")
+	}
+	if payload.ConcurrencyLimit > services.MaxExpansionConcurrency {
+		return nil, utils.ValidationError("concurrency_limit is too large")
+	}
+	if payload.MaxTotalThoughts < 0 {
+		return nil, utils.ValidationError("max_total_thoughts must not be negative")
+	}
+	if payload.MinRelevance < 0 || payload.MinRelevance > 1 {
+		return nil, utils.ValidationError("min_relevance must be between 0 and 1")
+	}
+	if language := strings.TrimSpace(payload.Language); language != "" {
+		normalized, ok := services.NormalizeLanguageTag(language)
+		if !ok {
+			return nil, utils.ValidationError("language must be a BCP-47 tag such as en or zh-CN")
+		}
+		payload.Language = normalized
+	}
+	payload.SystemPrompt = strings.TrimSpace(payload.SystemPrompt)
+	if err := utils.ValidateSystemPrompt(payload.SystemPrompt); err != nil {
+		return nil, err
+	}
+	extraInstructions, err := utils.NormalizeInstructions(payload.ExtraInstructions)
+	if err != nil {
+		return nil, err
+	}
+
+	payload.Concept = strings.TrimSpace(payload.Concept)
+	if err := utils.ValidateConcept(payload.Concept); err != nil {
+		return nil, err
+	}
+
+	normalizedContext, err := utils.NormalizeContextEntries(payload.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	payload.UserID = strings.TrimSpace(payload.UserID)
+	if payload.UserID != "" {
+		if err := utils.ValidateUserID(payload.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	var expansionType models.DirectionType
+	if trimmed := strings.TrimSpace(payload.ExpansionType); trimmed != "" {
+		dirType, err := utils.ParseDirectionType(trimmed)
+		if err != nil {
+			return nil, err
+		}
+		expansionType = dirType
+	}
+
+	return &services.ExpansionRequest{
+		Concept:              payload.Concept,
+		Context:              normalizedContext,
+		ExpansionType:        expansionType,
+		UserID:               payload.UserID,
+		ConcurrencyLimit:     payload.ConcurrencyLimit,
+		MaxTotalThoughts:     payload.MaxTotalThoughts,
+		NoCache:              payload.NoCache,
+		ThinkingStyle:        thinkingStyle,
+		SessionID:            payload.SessionID,
+		MaxExamples:          payload.MaxExamples,
+		Language:             payload.Language,
+		SystemPrompt:         payload.SystemPrompt,
+		ExtraInstructions:    extraInstructions,
+		Persist:              payload.Persist,
+		ParentThoughtID:      strings.TrimSpace(payload.ParentID),
+		MinRelevance:         payload.MinRelevance,
+		FallbackIfEmpty:      payload.FallbackIfEmpty,
+		IncludeSummary:       payload.IncludeSummary,
+		ForceIncludeExisting: payload.ForceIncludeExisting,
+	}, nil
+}
+
+func decodeAutoExploreRequest(w http.ResponseWriter, r *http.Request) (*services.AutoExploreRequest, error) {
+	var payload struct {
+		UserID           string                `json:"user_id"`
+		Concept          string                `json:"concept"`
+		Context          []models.ContextEntry `json:"context"`
+		Breadth          int                   `json:"breadth"`
+		Depth            int                   `json:"depth"`
+		MaxNodes         int                   `json:"max_nodes"`
+		ConcurrencyLimit int                   `json:"concurrency_limit"`
+	}
+	if err := decodeJSONBody(w, r, &payload); err != nil {
+		return nil, err
+	}
+
+	payload.UserID = strings.TrimSpace(payload.UserID)
+	if err := utils.ValidateUserID(payload.UserID); err != nil {
+		return nil, err
+	}
+	payload.Concept = strings.TrimSpace(payload.Concept)
+	if err := utils.ValidateConcept(payload.Concept); err != nil {
+		return nil, err
+	}
+	normalizedContext, err := utils.NormalizeContextEntries(payload.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return &services.AutoExploreRequest{
+		UserID:           payload.UserID,
+		Concept:          payload.Concept,
+		Context:          normalizedContext,
+		Breadth:          payload.Breadth,
+		Depth:            payload.Depth,
+		MaxNodes:         payload.MaxNodes,
+		ConcurrencyLimit: payload.ConcurrencyLimit,
+	}, nil
+}
+
+func writeSSE(w io.Writer, event string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
+
+// respondAttachment 以附件形式写出 data；文件名按 RFC 2183/2231 转义，无法编码时省略文件名。
+func respondAttachment(w http.ResponseWriter, contentType, filename string, data []byte) {
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", disposition)
+	_, _ = w.Write(data)
+}
+
+// reloadPromptsOnSignal 在收到 SIGHUP 时重新加载提示词模板文件。
+func reloadPromptsOnSignal(llm *services.LLMOrchestrator) {
+	reloadCh := make(chan os.Signal, 1)
+	signal.Notify(reloadCh, syscall.SIGHUP)
+	for range reloadCh {
+		if err := llm.ReloadPromptTemplates(); err != nil {
+			utils.Warn("failed to reload prompt templates", utils.KV("error", err))
+			continue
+		}
+		utils.Info("prompt templates reloaded")
+	}
+}
+
+func gracefulShutdown(mcpServer *mcp.MCPServer, webServer *http.Server) {
+	shutdownCh := make(chan os.Signal, 1)
+	signal.Notify(shutdownCh, os.Interrupt, syscall.SIGTERM)
+
+	<-shutdownCh
+	utils.Warn("shutdown signal received")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := webServer.Shutdown(ctx); err != nil {
+		utils.Error("failed to shutdown web server", utils.KV("error", err))
+	}
+
+	if err := mcpServer.Shutdown(); err != nil {
+		utils.Error("failed to shutdown MCP server", utils.KV("error", err))
+	}
+}
+
+func respondJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func respondError(w http.ResponseWriter, err error) {
+	status := statusFromError(err)
+	message := err.Error()
+	if localized, ok := w.(*localizedResponseWriter); ok {
+		message = localized.bundle.LocalizeError(localized.locale, err)
+	}
+	http.Error(w, message, status)
+}
+
+// localizedResponseWriter 记录请求协商出的语言，供 respondError 翻译错误信息。
+type localizedResponseWriter struct {
+	http.ResponseWriter
+	bundle *utils.LocaleBundle
+	locale string
+}
+
+// Unwrap 使 http.ResponseController 能访问底层连接（如流式响应的 Flush）。
+func (w *localizedResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// withLocale 按 Accept-Language 选择响应语言并写入 Content-Language。
+func withLocale(next http.Handler, bundle *utils.LocaleBundle) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale := bundle.Match(r.Header.Get("Accept-Language"))
+		w.Header().Set("Content-Language", locale)
+		next.ServeHTTP(&localizedResponseWriter{ResponseWriter: w, bundle: bundle, locale: locale}, r)
+	})
+}
+
+// loadLocaleBundle 加载配置的语言文件；失败时记录警告并只保留英文。
+func loadLocaleBundle(cfg *Config) *utils.LocaleBundle {
+	bundle, err := utils.LoadLocaleBundle(cfg.LocalesDir, cfg.SupportedLocales)
+	if err != nil {
+		utils.Warn("failed to load locale files; falling back to English", utils.KV("dir", cfg.LocalesDir), utils.KV("error", err))
+		return utils.NewLocaleBundle(nil)
+	}
+	return bundle
+}
+
+func statusFromError(err error) int {
+	switch {
+	case errors.Is(err, appErrors.ErrInvalidRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, appErrors.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, appErrors.ErrBudgetExceeded), errors.Is(err, appErrors.ErrLLMRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, appErrors.ErrCircuitOpen), errors.Is(err, appErrors.ErrEmbeddingsUnavailable), errors.Is(err, appErrors.ErrLLMBusy):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, appErrors.ErrSessionNotFound), errors.Is(err, appErrors.ErrThoughtNotFound), errors.Is(err, appErrors.ErrSnapshotNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+	if r == nil || r.Body == nil {
+		return utils.ValidationError("request body is empty")
+	}
+
+	limited := http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+	defer limited.Close()
+
+	decoder := json.NewDecoder(limited)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(dst); err != nil {
+		if errors.Is(err, io.EOF) {
+			return utils.ValidationError("request body is empty")
+		}
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return utils.ValidationError(fmt.Sprintf("request body contains badly-formed JSON (at position %d)", syntaxErr.Offset))
+		}
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return utils.ValidationError(fmt.Sprintf("request body has an invalid value for %q (expected %s)", typeErr.Field, typeErr.Type))
+		}
+		return utils.ValidationError("request body is invalid")
+	}
+
+	if err := decoder.Decode(&struct{}{}); err != io.EOF {
+		return utils.ValidationError("request body must contain a single JSON value")
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package app
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"WideMindsMCP/internal/services"
+	"WideMindsMCP/internal/storage"
+	"WideMindsMCP/internal/utils"
+)
+
+const testJWTSecret = "0123456789abcdef0123456789abcdef"
+
+func signHS256(secret, header, payload string) string {
+	input := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." + base64.RawURLEncoding.EncodeToString([]byte(payload))
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newAuthTestMux(cfg *Config) http.Handler {
+	manager := services.NewSessionManager(storage.NewInMemorySessionStore())
+	llm := services.NewLLMOrchestrator("", "", "")
+	llm.SetBudgetStore(storage.NewInMemoryBudgetStore(), 0)
+	return setupWebServer(cfg, manager, services.NewThoughtExpander(llm, manager), llm)
+}
+
+func authStatus(mux http.Handler, token string) int {
+	req := httptest.NewRequest(http.MethodGet, "/api/sessions?user_id=alice", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+	return recorder.Code
+}
+
+func TestAPIAuthModes(t *testing.T) {
+	signed := signHS256(testJWTSecret, `{"alg":"HS256","typ":"JWT"}`, `{"sub":"alice"}`)
+	forged := signHS256("not-the-configured-secret-at-all", `{"alg":"HS256","typ":"JWT"}`, `{"sub":"alice"}`)
+
+	tokenCfg := DefaultConfig()
+	tokenCfg.APIToken = "static-token"
+	tokenMux := newAuthTestMux(tokenCfg)
+	for token, want := range map[string]int{"": http.StatusUnauthorized, "wrong": http.StatusUnauthorized, signed: http.StatusUnauthorized, "static-token": http.StatusOK} {
+		if got := authStatus(tokenMux, token); got != want {
+			t.Fatalf("token mode with %q: expected %d, got %d", token, want, got)
+		}
+	}
+
+	jwtCfg := DefaultConfig()
+	jwtCfg.APIToken = "static-token"
+	jwtCfg.AuthMode = "jwt"
+	jwtCfg.JWTSecret = testJWTSecret
+	if err := validateConfig(jwtCfg); err != nil {
+		t.Fatalf("expected jwt config to be valid: %v", err)
+	}
+	jwtMux := newAuthTestMux(jwtCfg)
+	for token, want := range map[string]int{"": http.StatusUnauthorized, "static-token": http.StatusUnauthorized, forged: http.StatusUnauthorized, signed: http.StatusOK} {
+		if got := authStatus(jwtMux, token); got != want {
+			t.Fatalf("jwt mode with %q: expected %d, got %d", token, want, got)
+		}
+	}
+
+	if got := authStatus(newAuthTestMux(DefaultConfig()), ""); got != http.StatusOK {
+		t.Fatalf("expected open access without auth settings, got %d", got)
+	}
+}
+
+func adminStatus(mux http.Handler, adminToken string) int {
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/users/alice/budget", strings.NewReader(`{"total_tokens_limit":100}`))
+	req.Header.Set("Authorization", "Bearer static-token")
+	if adminToken != "" {
+		req.Header.Set(utils.AdminTokenHeader, adminToken)
+	}
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+	return recorder.Code
+}
+
+func TestAdminRoutesRequireAdminToken(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.APIToken = "static-token"
+	if got := adminStatus(newAuthTestMux(cfg), "static-token"); got != http.StatusForbidden {
+		t.Fatalf("expected admin routes to be disabled without admin_token, got %d", got)
+	}
+
+	cfg.AdminToken = "admin-token"
+	if err := validateConfig(cfg); err != nil {
+		t.Fatalf("expected admin config to be valid: %v", err)
+	}
+	mux := newAuthTestMux(cfg)
+	for token, want := range map[string]int{"": http.StatusForbidden, "static-token": http.StatusForbidden, "admin-token": http.StatusOK} {
+		if got := adminStatus(mux, token); got != want {
+			t.Fatalf("admin token %q: expected %d, got %d", token, want, got)
+		}
+	}
+
+	for _, path := range []string{"/api/admin/prompts", "/api/admin/llm/stats", "/api/llm/stats"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer static-token")
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusForbidden {
+			t.Fatalf("%s: expected 403 without the admin token, got %d", path, recorder.Code)
+		}
+	}
+
+	cfg.AdminToken = cfg.APIToken
+	if err := validateConfig(cfg); err == nil {
+		t.Fatal("expected admin_token equal to api_token to be rejected")
+	}
+}
+
+func expandStatus(mux http.Handler, token, body string) int {
+	req := httptest.NewRequest(http.MethodPost, "/api/expand", strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+	return recorder.Code
+}
+
+func TestExpandRequiresBudgetUser(t *testing.T) {
+	open := newAuthTestMux(DefaultConfig())
+	if got := expandStatus(open, "", `{"concept":"Energy"}`); got != http.StatusBadRequest {
+		t.Fatalf("expected missing user_id to be rejected, got %d", got)
+	}
+	if got := expandStatus(open, "", `{"concept":"Energy","user_id":"alice"}`); got != http.StatusOK {
+		t.Fatalf("expected expansion with user_id to succeed, got %d", got)
+	}
+
+	jwtCfg := DefaultConfig()
+	jwtCfg.AuthMode = "jwt"
+	jwtCfg.JWTSecret = testJWTSecret
+	jwtMux := newAuthTestMux(jwtCfg)
+	signed := signHS256(testJWTSecret, `{"alg":"HS256","typ":"JWT"}`, `{"sub":"alice"}`)
+	if got := expandStatus(jwtMux, signed, `{"concept":"Energy"}`); got != http.StatusOK {
+		t.Fatalf("expected the token's sub to be used as user, got %d", got)
+	}
+	if got := expandStatus(jwtMux, signed, `{"concept":"Energy","user_id":"bob"}`); got != http.StatusForbidden {
+		t.Fatalf("expected a user_id other than the token's sub to be rejected, got %d", got)
+	}
+}
+
+func TestAutoExploreRequiresBudgetUser(t *testing.T) {
+	autoExplore := func(mux http.Handler, token, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/auto-explore", strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	if got := autoExplore(newAuthTestMux(DefaultConfig()), "", `{"concept":"Energy","breadth":1,"depth":1}`); got != http.StatusBadRequest {
+		t.Fatalf("expected missing user_id to be rejected, got %d", got)
+	}
+
+	jwtCfg := DefaultConfig()
+	jwtCfg.AuthMode = "jwt"
+	jwtCfg.JWTSecret = testJWTSecret
+	jwtMux := newAuthTestMux(jwtCfg)
+	signed := signHS256(testJWTSecret, `{"alg":"HS256","typ":"JWT"}`, `{"sub":"alice"}`)
+	if got := autoExplore(jwtMux, signed, `{"concept":"Energy","user_id":"bob","breadth":1,"depth":1}`); got != http.StatusForbidden {
+		t.Fatalf("expected a user_id other than the token's sub to be rejected, got %d", got)
+	}
+	if got := autoExplore(jwtMux, signed, `{"concept":"Energy","breadth":1,"depth":1}`); got != http.StatusOK {
+		t.Fatalf("expected the token's sub to be used as user, got %d", got)
+	}
+}
+
+func TestExpandStopsWhenClientDisconnects(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodPost, "/api/expand", strings.NewReader(`{"concept":"Energy","user_id":"alice"}`)).WithContext(ctx)
+	recorder := httptest.NewRecorder()
+	newAuthTestMux(DefaultConfig()).ServeHTTP(recorder, req)
+	if recorder.Code == http.StatusOK {
+		t.Fatalf("expected a canceled request not to expand, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
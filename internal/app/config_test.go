@@ -0,0 +1,89 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"WideMindsMCP/internal/services"
+	"WideMindsMCP/internal/utils"
+)
+
+func TestLLMConfigEnvOverridesYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := "llm:\n  timeout_seconds: 90\n  max_tokens: 4096\n  default_temperature: 0.4\n  max_retries: 1\n"
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	if err := utils.LoadYAML(path, cfg); err != nil {
+		t.Fatalf("LoadYAML failed: %v", err)
+	}
+	t.Setenv("LLM_TIMEOUT_SECONDS", "120")
+	t.Setenv("LLM_MAX_RETRIES", "4")
+	applyEnvOverrides(cfg)
+
+	if err := validateConfig(cfg); err != nil {
+		t.Fatalf("validateConfig failed: %v", err)
+	}
+	if cfg.LLM.TimeoutSeconds != 120 || cfg.LLM.MaxRetries != 4 {
+		t.Fatalf("expected env overrides to win, got %+v", cfg.LLM)
+	}
+	if cfg.LLM.MaxTokens != 4096 || cfg.LLM.DefaultTemperature != 0.4 {
+		t.Fatalf("expected YAML values without env overrides to be kept, got %+v", cfg.LLM)
+	}
+}
+
+func TestLLMConfigRejectsInvalidRanges(t *testing.T) {
+	cases := map[string]func(cfg *Config){
+		"zero timeout":     func(cfg *Config) { cfg.LLM.TimeoutSeconds = 0 },
+		"huge timeout":     func(cfg *Config) { cfg.LLM.TimeoutSeconds = maxLLMTimeoutSeconds + 1 },
+		"zero max tokens":  func(cfg *Config) { cfg.LLM.MaxTokens = 0 },
+		"huge max tokens":  func(cfg *Config) { cfg.LLM.MaxTokens = maxLLMMaxTokens + 1 },
+		"hot temperature":  func(cfg *Config) { cfg.LLM.DefaultTemperature = 2.5 },
+		"neg temperature":  func(cfg *Config) { cfg.LLM.DefaultTemperature = -0.1 },
+		"negative retries": func(cfg *Config) { cfg.LLM.MaxRetries = -1 },
+		"too many retries": func(cfg *Config) { cfg.LLM.MaxRetries = maxLLMRetries + 1 },
+		"negative cache":   func(cfg *Config) { cfg.LLM.CacheSize = -1 },
+		"zero cache ttl":   func(cfg *Config) { cfg.LLM.CacheTTLSeconds = 0 },
+		"long health ttl":  func(cfg *Config) { cfg.LLM.HealthCacheSeconds = maxLLMHealthCacheSeconds + 1 },
+		"zero concurrency": func(cfg *Config) { cfg.ExpansionConcurrency = 0 },
+		"huge concurrency": func(cfg *Config) { cfg.ExpansionConcurrency = services.MaxExpansionConcurrency + 1 },
+		"neg node budget":  func(cfg *Config) { cfg.ExpansionNodeBudget = -1 },
+		"unknown style":    func(cfg *Config) { cfg.DefaultThinkingStyle = "chaotic" },
+		"bad redaction":    func(cfg *Config) { cfg.LLM.LogRedactions = []string{"[unclosed"} },
+		"many examples":    func(cfg *Config) { cfg.PromptExampleLimit = services.MaxPromptExampleLimit + 1 },
+		"bad similarity":   func(cfg *Config) { cfg.SimilarityAlertThreshold = -0.1 },
+		"bad dedup":        func(cfg *Config) { cfg.DirectionDedupThreshold = 1.5 },
+		"bad sanitization": func(cfg *Config) { cfg.SanitizationMode = "loose" },
+		"bad thought dup":  func(cfg *Config) { cfg.ThoughtDedupThreshold = 1.2 },
+		"bad dedup policy": func(cfg *Config) { cfg.ThoughtDedupPolicy = "merge" },
+		"missing CA file":  func(cfg *Config) { cfg.LLM.CACertFile = "/nonexistent/ca.pem" },
+		"bad proxy":        func(cfg *Config) { cfg.LLM.ProxyURL = "not a url" },
+		"negative rpm":     func(cfg *Config) { cfg.LLM.RateLimitRPM = -1 },
+		"huge burst":       func(cfg *Config) { cfg.LLM.RateLimitBurst = maxLLMRateLimitRPM + 1 },
+		"neg concurrency":  func(cfg *Config) { cfg.LLM.MaxConcurrent = -1 },
+		"unknown auth":     func(cfg *Config) { cfg.AuthMode = "oauth" },
+		"short jwt secret": func(cfg *Config) { cfg.AuthMode, cfg.JWTSecret = "jwt", "short" },
+		"unnamed fallback": func(cfg *Config) { cfg.LLM.Fallbacks = []services.LLMFallback{{BaseURL: "https://example.com"}} },
+		"long prefix":      func(cfg *Config) { cfg.LLM.SystemPromptPrefix = strings.Repeat("x", utils.MaxSystemPromptLength+1) },
+	}
+
+	if err := validateConfig(DefaultConfig()); err != nil {
+		t.Fatalf("expected default config to be valid: %v", err)
+	}
+	greedy := DefaultConfig()
+	greedy.LLM.DefaultTemperature = 0
+	if err := validateConfig(greedy); err != nil {
+		t.Fatalf("expected a zero default temperature to be valid: %v", err)
+	}
+	for name, mutate := range cases {
+		cfg := DefaultConfig()
+		mutate(cfg)
+		if err := validateConfig(cfg); err == nil {
+			t.Fatalf("%s: expected validation error", name)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/services"
+	"WideMindsMCP/internal/storage"
+)
+
+func TestSessionContextEntryEndpoints(t *testing.T) {
+	manager := services.NewSessionManager(storage.NewInMemorySessionStore())
+	llm := services.NewLLMOrchestrator("", "", "")
+	mux := setupWebServer(DefaultConfig(), manager, services.NewThoughtExpander(llm, manager), llm)
+
+	session, err := manager.CreateSession("user-1", "Energy")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	for _, value := range []string{"goal: cut costs", "background: utility sector", "note: check tariffs"} {
+		if _, err := manager.AddContextEntry(session.ID, models.ParseContextEntry(value)); err != nil {
+			t.Fatalf("AddContextEntry failed: %v", err)
+		}
+	}
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		return recorder
+	}
+	base := "/api/sessions/" + session.ID + "/context/"
+
+	for _, index := range []string{"4", "-1", "two"} {
+		if recorder := serve(http.MethodDelete, base+index, ""); recorder.Code != http.StatusBadRequest {
+			t.Fatalf("index %s: expected 400, got %d: %s", index, recorder.Code, recorder.Body.String())
+		}
+	}
+
+	recorder := serve(http.MethodDelete, base+"1", "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var updated models.Session
+	if err := json.Unmarshal(recorder.Body.Bytes(), &updated); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	got := strings.Join(models.ContextStrings(updated.ContextEntries), "|")
+	if got != "Energy|background: utility sector|check tariffs" {
+		t.Fatalf("expected remaining entries in their original order, got %q", got)
+	}
+
+	recorder = serve(http.MethodPatch, base+"2", `{"kind":"goal","value":"compare tariffs"}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	stored, err := manager.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if entry := stored.ContextEntries[2]; entry.Kind != models.ContextGoal || entry.Value != "compare tariffs" {
+		t.Fatalf("expected replaced entry, got %+v", entry)
+	}
+	if recorder := serve(http.MethodPatch, base+"3", `{"value":"out of range"}`); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for out-of-range PATCH, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,111 @@
+package app
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/services"
+	"WideMindsMCP/internal/storage"
+)
+
+func newImportFileRequest(t *testing.T, filename string, content []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("user_id", "user-1"); err != nil {
+		t.Fatalf("write field: %v", err)
+	}
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	if _, err := part.Write(content); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close writer: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/sessions/import/file", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestSessionImportFileAcceptsSupportedFormats(t *testing.T) {
+	source := models.NewSession("someone", "Energy")
+	source.RootThought.AddChild(models.NewThought("Solar", source.ID, models.Direction{Type: models.Broad, Title: "Solar"}))
+	var raw bytes.Buffer
+	if err := source.WriteJSON(&raw); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	_, _ = gz.Write(raw.Bytes())
+	_ = gz.Close()
+
+	files := map[string][]byte{
+		"session.json":    raw.Bytes(),
+		"session.json.gz": gzipped.Bytes(),
+		"session.md":      []byte(source.ToMarkdown(models.DefaultMarkdownOptions())),
+	}
+	for filename, content := range files {
+		manager := services.NewSessionManager(storage.NewInMemorySessionStore())
+		recorder := httptest.NewRecorder()
+		handleSessionImportFile(recorder, newImportFileRequest(t, filename, content), manager)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", filename, recorder.Code, recorder.Body.String())
+		}
+
+		var imported models.Session
+		if err := json.Unmarshal(recorder.Body.Bytes(), &imported); err != nil {
+			t.Fatalf("%s: decode response: %v", filename, err)
+		}
+		if imported.UserID != "user-1" || imported.ID == source.ID || imported.RootThought.Content != "Energy" || len(imported.RootThought.Children) != 1 {
+			t.Fatalf("%s: unexpected imported session %+v", filename, imported)
+		}
+		if _, err := manager.GetSession(imported.ID); err != nil {
+			t.Fatalf("%s: imported session not stored: %v", filename, err)
+		}
+	}
+}
+
+func TestSessionImportFileRejectsBadUploads(t *testing.T) {
+	manager := services.NewSessionManager(storage.NewInMemorySessionStore())
+	cases := map[string][]byte{
+		"../../etc/passwd.json": []byte(`{}`),
+		".hidden.json":          []byte(`{}`),
+		"session.txt":           []byte("hello"),
+		"broken.json.gz":        []byte("not gzip"),
+		"empty.json":            []byte(`{}`),
+	}
+	for filename, content := range cases {
+		recorder := httptest.NewRecorder()
+		handleSessionImportFile(recorder, newImportFileRequest(t, filename, content), manager)
+		if recorder.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d: %s", filename, recorder.Code, recorder.Body.String())
+		}
+	}
+
+	oversized := newImportFileRequest(t, "big.json", bytes.Repeat([]byte("x"), int(maxImportFileBytes)+1))
+	recorder := httptest.NewRecorder()
+	handleSessionImportFile(recorder, oversized, manager)
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected oversized upload to be rejected, got %d", recorder.Code)
+	}
+}
+
+func TestRespondAttachmentQuotesFilename(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	respondAttachment(recorder, "text/plain", `notes; "draft".md`, []byte("x"))
+	_, params, err := mime.ParseMediaType(recorder.Header().Get("Content-Disposition"))
+	if err != nil || params["filename"] != `notes; "draft".md` {
+		t.Fatalf("expected the filename to round-trip, got %q (%v)", recorder.Header().Get("Content-Disposition"), err)
+	}
+}
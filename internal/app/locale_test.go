@@ -0,0 +1,65 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"WideMindsMCP/internal/services"
+	"WideMindsMCP/internal/storage"
+)
+
+func newLocalizedTestMux(t *testing.T) http.Handler {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.LocalesDir = "../../configs/locales"
+	manager := services.NewSessionManager(storage.NewInMemorySessionStore())
+	llm := services.NewLLMOrchestrator("", "", "")
+	return setupWebServer(cfg, manager, services.NewThoughtExpander(llm, manager), llm)
+}
+
+func TestAPIErrorsFollowAcceptLanguage(t *testing.T) {
+	mux := newLocalizedTestMux(t)
+	cases := []struct {
+		acceptLanguage string
+		locale         string
+		message        string
+	}{
+		{"zh-Hans", "zh-Hans", "请求无效: user_id 不能为空"},
+		{"fr;q=0.9, zh-CN;q=0.8", "zh-Hans", "请求无效: user_id 不能为空"},
+		{"fr, de;q=0.5", "en", "invalid request: user_id is required"},
+		{"", "en", "invalid request: user_id is required"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/api/sessions", nil)
+		req.Header.Set("Accept-Language", tc.acceptLanguage)
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Fatalf("%q: expected 400, got %d", tc.acceptLanguage, recorder.Code)
+		}
+		if got := recorder.Header().Get("Content-Language"); got != tc.locale {
+			t.Fatalf("%q: expected Content-Language %q, got %q", tc.acceptLanguage, tc.locale, got)
+		}
+		if got := strings.TrimSpace(recorder.Body.String()); got != tc.message {
+			t.Fatalf("%q: expected %q, got %q", tc.acceptLanguage, tc.message, got)
+		}
+	}
+}
+
+func TestAPISentinelErrorsAreLocalized(t *testing.T) {
+	mux := newLocalizedTestMux(t)
+	req := httptest.NewRequest(http.MethodGet, "/api/sessions/missing-session", nil)
+	req.Header.Set("Accept-Language", "zh-Hans")
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if got := strings.TrimSpace(recorder.Body.String()); !strings.Contains(got, "会话不存在") {
+		t.Fatalf("expected localized sentinel error, got %q", got)
+	}
+}
@@ -9,9 +9,36 @@ var (
 	// ErrThoughtNotFound indicates the requested thought node was not found.
 	ErrThoughtNotFound = errors.New("thought not found")
 
+	// ErrSnapshotNotFound indicates the requested named session snapshot does not exist.
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+
 	// ErrToolNotFound indicates an MCP tool lookup failed.
 	ErrToolNotFound = errors.New("mcp tool not found")
 
 	// ErrInvalidRequest indicates the request payload failed validation.
 	ErrInvalidRequest = errors.New("invalid request")
+
+	// ErrBudgetExceeded indicates the user's LLM token budget does not cover the request.
+	ErrBudgetExceeded = errors.New("llm token budget exceeded")
+
+	// ErrForbidden indicates the caller is not permitted to perform the operation.
+	ErrForbidden = errors.New("forbidden")
+
+	// ErrCircuitOpen indicates the LLM circuit breaker is open and calls are failing fast.
+	ErrCircuitOpen = errors.New("llm circuit breaker is open")
+
+	// ErrEmbeddingsUnavailable indicates no embedding backend is configured.
+	ErrEmbeddingsUnavailable = errors.New("embeddings are not available")
+
+	// ErrLLMUnauthorized indicates the LLM backend rejected the configured credentials.
+	ErrLLMUnauthorized = errors.New("llm authentication failed")
+
+	// ErrLLMUnreachable indicates the LLM backend could not be reached in time.
+	ErrLLMUnreachable = errors.New("llm backend unreachable")
+
+	// ErrLLMRateLimited indicates the client-side LLM rate limit could not grant a slot before the request deadline.
+	ErrLLMRateLimited = errors.New("llm request rate limit reached")
+
+	// ErrLLMBusy indicates the request gave up waiting for a free concurrent LLM call slot.
+	ErrLLMBusy = errors.New("llm busy: too many concurrent requests")
 )
@@ -0,0 +1,152 @@
+//MCP Tool Plugins(MCP工具插件)
+
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"plugin"
+	"sort"
+	"strings"
+	"sync"
+
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+// PluginSymbol 是插件必须导出的构造函数名：func NewMCPTool() mcp.MCPTool
+const (
+	PluginSymbol    = "NewMCPTool"
+	pluginExtension = ".so"
+)
+
+// 结构体
+// PluginManager 从共享库加载外部 MCP 工具并注册到服务器。内置工具不会被插件覆盖。
+type PluginManager struct {
+	server *MCPServer
+	open   func(path string) (MCPTool, error)
+	loaded map[string]string
+	mutex  sync.Mutex
+}
+
+type PluginInfo struct {
+	Path        string `json:"path"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// 函数
+func NewPluginManager(server *MCPServer) *PluginManager {
+	return &PluginManager{
+		server: server,
+		open:   openPluginTool,
+		loaded: make(map[string]string),
+	}
+}
+
+// openPluginTool 打开共享库并调用其导出的 NewMCPTool。
+func openPluginTool(path string) (MCPTool, error) {
+	lib, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open plugin %s: %w", path, err)
+	}
+	symbol, err := lib.Lookup(PluginSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", path, err)
+	}
+	constructor, ok := symbol.(func() MCPTool)
+	if !ok {
+		return nil, fmt.Errorf("plugin %s: %s has type %T, want func() MCPTool", path, PluginSymbol, symbol)
+	}
+	tool := constructor()
+	if tool == nil {
+		return nil, fmt.Errorf("plugin %s: %s returned nil", path, PluginSymbol)
+	}
+	return tool, nil
+}
+
+// 方法
+// LoadPlugin 加载单个插件并注册其工具。
+func (s *MCPServer) LoadPlugin(path string) error {
+	_, err := NewPluginManager(s).Load(path)
+	return err
+}
+
+// Load 打开插件、校验工具名并注册；同名工具已存在时拒绝加载。
+func (pm *PluginManager) Load(path string) (*PluginInfo, error) {
+	if pm == nil || pm.server == nil {
+		return nil, errors.New("plugin manager is not initialized")
+	}
+
+	tool, err := pm.open(path)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(tool.Name())
+	if name == "" {
+		return nil, fmt.Errorf("plugin %s: tool name is empty", path)
+	}
+
+	pm.server.mutex.Lock()
+	if _, exists := pm.server.tools[name]; exists {
+		pm.server.mutex.Unlock()
+		return nil, fmt.Errorf("plugin %s: tool %q is already registered", path, name)
+	}
+	pm.server.tools[name] = tool
+	pm.server.mutex.Unlock()
+
+	pm.mutex.Lock()
+	pm.loaded[path] = name
+	pm.mutex.Unlock()
+
+	info := &PluginInfo{Path: path, Name: name, Description: tool.Description()}
+	utils.Info("loaded MCP tool plugin",
+		utils.KV("name", info.Name),
+		utils.KV("description", info.Description),
+		utils.KV("path", info.Path),
+	)
+	return info, nil
+}
+
+// LoadDir 按文件名顺序加载目录下所有 .so 插件；单个插件失败不影响其余插件，错误合并返回。
+func (pm *PluginManager) LoadDir(dir string) ([]*PluginInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read plugin dir %s: %w", dir, err)
+	}
+
+	paths := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), pluginExtension) {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, entry.Name()))
+	}
+	sort.Strings(paths)
+
+	loaded := make([]*PluginInfo, 0, len(paths))
+	var errs []error
+	for _, path := range paths {
+		info, err := pm.Load(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		loaded = append(loaded, info)
+	}
+	return loaded, errors.Join(errs...)
+}
+
+// Loaded 返回已加载插件的路径到工具名映射。
+func (pm *PluginManager) Loaded() map[string]string {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	cloned := make(map[string]string, len(pm.loaded))
+	for path, name := range pm.loaded {
+		cloned[path] = name
+	}
+	return cloned
+}
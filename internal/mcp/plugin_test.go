@@ -0,0 +1,82 @@
+package mcp
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type pluginTool struct{ name string }
+
+func (t pluginTool) Name() string        { return t.name }
+func (t pluginTool) Description() string { return "plugin " + t.name }
+func (t pluginTool) Execute(params map[string]interface{}) (interface{}, error) {
+	return t.name, nil
+}
+func (t pluginTool) Schema() map[string]interface{} { return map[string]interface{}{} }
+
+func TestPluginManagerLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.so", "a.so", "broken.so", "dup.so", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	server := NewMCPServer(nil, nil, "", 0)
+	server.RegisterTool("builtin", pluginTool{name: "builtin"})
+
+	manager := NewPluginManager(server)
+	var opened []string
+	manager.open = func(path string) (MCPTool, error) {
+		base := filepath.Base(path)
+		opened = append(opened, base)
+		switch base {
+		case "broken.so":
+			return nil, errors.New("bad plugin")
+		case "dup.so":
+			return pluginTool{name: "builtin"}, nil
+		default:
+			return pluginTool{name: strings.TrimSuffix(base, ".so") + "_tool"}, nil
+		}
+	}
+
+	loaded, err := manager.LoadDir(dir)
+	if err == nil || !strings.Contains(err.Error(), "bad plugin") || !strings.Contains(err.Error(), "already registered") {
+		t.Fatalf("expected joined errors for broken and duplicate plugins, got %v", err)
+	}
+	if strings.Join(opened, ",") != "a.so,b.so,broken.so,dup.so" {
+		t.Fatalf("unexpected load order %v", opened)
+	}
+	if len(loaded) != 2 || loaded[0].Name != "a_tool" || loaded[1].Description != "plugin b_tool" {
+		t.Fatalf("unexpected loaded plugins %+v", loaded)
+	}
+
+	server.mutex.RLock()
+	builtin := server.tools["builtin"]
+	_, hasA := server.tools["a_tool"]
+	server.mutex.RUnlock()
+	if builtin.Description() != "plugin builtin" || !hasA {
+		t.Fatalf("expected plugin tools registered without replacing built-ins")
+	}
+	if got := manager.Loaded(); len(got) != 2 || got[filepath.Join(dir, "b.so")] != "b_tool" {
+		t.Fatalf("unexpected loaded map %v", got)
+	}
+}
+
+func TestLoadPluginRejectsInvalidLibrary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invalid.so")
+	if err := os.WriteFile(path, []byte("not a shared object"), 0o644); err != nil {
+		t.Fatalf("write plugin: %v", err)
+	}
+
+	server := NewMCPServer(nil, nil, "", 0)
+	if err := server.LoadPlugin(path); err == nil {
+		t.Fatalf("expected invalid plugin to fail")
+	}
+	if _, err := NewPluginManager(server).LoadDir(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Fatalf("expected missing plugin dir to fail")
+	}
+}
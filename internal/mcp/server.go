@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -23,13 +24,19 @@ type MCPServer struct {
 	tools           map[string]MCPTool
 	server          *http.Server
 	mutex           sync.RWMutex
-	authToken       string
+	auth            utils.AuthConfig
 	rateLimiter     *utils.RateLimiter
+	toolPermissions map[string][]string
 }
 
 type MCPRequest struct {
 	Method string                 `json:"method"`
 	Params map[string]interface{} `json:"params"`
+	UserID string                 `json:"user_id,omitempty"`
+	// Stream 为 true 时以 SSE 返回进度通知与最终响应
+	Stream bool `json:"stream,omitempty"`
+	// authUserID 是 HTTP 入口从已校验 JWT 中取得的用户，不由客户端传入
+	authUserID string
 }
 
 type MCPResponse struct {
@@ -43,19 +50,37 @@ type MCPError struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
+type ProgressNotification struct {
+	Progress  int    `json:"progress"`
+	Stage     string `json:"stage,omitempty"`
+	Direction string `json:"direction,omitempty"`
+	Message   string `json:"message"`
+}
+
+type MCPNotification struct {
+	Method string               `json:"method"`
+	Params ProgressNotification `json:"params"`
+}
+
 type ToolDescriptor struct {
 	Name        string                 `json:"name"`
 	Description string                 `json:"description"`
 	Schema      map[string]interface{} `json:"schema"`
 }
 
+// 常量
+const (
+	toolPermissionWildcard = "*"
+	progressMethod         = "notifications/progress"
+)
+
 // 函数
 func NewMCPServer(te *services.ThoughtExpander, sm *services.SessionManager, authToken string, rateLimitPerMinute int) *MCPServer {
 	return &MCPServer{
 		thoughtExpander: te,
 		sessionManager:  sm,
 		tools:           make(map[string]MCPTool),
-		authToken:       authToken,
+		auth:            utils.AuthConfig{Mode: utils.AuthModeToken, Token: authToken},
 		rateLimiter:     utils.NewRateLimiter(rateLimitPerMinute, time.Minute),
 	}
 }
@@ -69,13 +94,9 @@ func (s *MCPServer) Start(port int) error {
 		return nil
 	}
 
-	mux := http.NewServeMux()
-	mux.Handle("/mcp", s.wrapHandler(http.HandlerFunc(s.handleHTTP)))
-	mux.Handle("/tools", s.wrapHandler(http.HandlerFunc(s.handleTools)))
-
 	s.server = &http.Server{
 		Addr:              fmt.Sprintf(":%d", port),
-		Handler:           mux,
+		Handler:           s.handler(),
 		ReadHeaderTimeout: 5 * time.Second,
 		ReadTimeout:       10 * time.Second,
 		WriteTimeout:      10 * time.Second,
@@ -91,6 +112,20 @@ func (s *MCPServer) Start(port int) error {
 	return nil
 }
 
+// Handler 返回带鉴权与限流的 /mcp 与 /tools 路由，供测试或嵌入其他服务器使用。
+func (s *MCPServer) Handler() http.Handler {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.handler()
+}
+
+func (s *MCPServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", s.wrapHandler(http.HandlerFunc(s.handleHTTP)))
+	mux.Handle("/tools", s.wrapHandler(http.HandlerFunc(s.handleTools)))
+	return mux
+}
+
 func (s *MCPServer) wrapHandler(handler http.Handler) http.Handler {
 	h := handler
 	if s.rateLimiter != nil {
@@ -105,11 +140,11 @@ func (s *MCPServer) wrapHandler(handler http.Handler) http.Handler {
 			next.ServeHTTP(w, r)
 		})
 	}
-	if s.authToken != "" {
+	if s.auth.Enabled() {
 		next := h
 		h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			token := utils.ResolveRequestToken(r)
-			if token != s.authToken {
+			if !s.auth.Authorize(token) {
 				http.Error(w, "unauthorized", http.StatusUnauthorized)
 				return
 			}
@@ -120,16 +155,36 @@ func (s *MCPServer) wrapHandler(handler http.Handler) http.Handler {
 }
 
 func (s *MCPServer) HandleRequest(req *MCPRequest) *MCPResponse {
-	if req == nil {
-		return &MCPResponse{Error: &MCPError{Code: http.StatusBadRequest, Message: appErrors.ErrInvalidRequest.Error()}}
+	tool, errResp := s.resolveTool(req)
+	if errResp != nil {
+		return errResp
 	}
 
-	tool := s.getTool(req.Method)
-	if tool == nil {
-		return &MCPResponse{Error: &MCPError{Code: http.StatusNotFound, Message: appErrors.ErrToolNotFound.Error()}}
+	result, err := tool.Execute(req.Params)
+	if err != nil {
+		return &MCPResponse{Error: &MCPError{Code: statusFromError(err), Message: err.Error()}}
 	}
 
-	result, err := tool.Execute(req.Params)
+	return &MCPResponse{Result: result}
+}
+
+// HandleStreamRequest 执行请求，支持流式的工具会通过 notify 发送进度通知；ctx 取消时中止执行。
+func (s *MCPServer) HandleStreamRequest(ctx context.Context, req *MCPRequest, notify func(MCPNotification)) *MCPResponse {
+	tool, errResp := s.resolveTool(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	streaming, ok := tool.(StreamingTool)
+	if !ok {
+		return s.HandleRequest(req)
+	}
+
+	result, err := streaming.ExecuteStream(ctx, req.Params, func(progress ProgressNotification) {
+		if notify != nil {
+			notify(MCPNotification{Method: progressMethod, Params: progress})
+		}
+	})
 	if err != nil {
 		return &MCPResponse{Error: &MCPError{Code: statusFromError(err), Message: err.Error()}}
 	}
@@ -137,6 +192,51 @@ func (s *MCPServer) HandleRequest(req *MCPRequest) *MCPResponse {
 	return &MCPResponse{Result: result}
 }
 
+func (s *MCPServer) resolveTool(req *MCPRequest) (MCPTool, *MCPResponse) {
+	if req == nil {
+		return nil, &MCPResponse{Error: &MCPError{Code: http.StatusBadRequest, Message: appErrors.ErrInvalidRequest.Error()}}
+	}
+
+	tool := s.getTool(req.Method)
+	if tool == nil {
+		return nil, &MCPResponse{Error: &MCPError{Code: http.StatusNotFound, Message: appErrors.ErrToolNotFound.Error()}}
+	}
+
+	userID, ok := s.requestUser(req)
+	if !ok || !s.Authorize(userID, req.Method) {
+		return nil, &MCPResponse{Error: &MCPError{Code: http.StatusForbidden, Message: appErrors.ErrForbidden.Error()}}
+	}
+	return tool, nil
+}
+
+// requestUser 确定调用用户：jwt 鉴权下取已校验令牌的 sub，客户端传入的 user_id 须与之一致，
+// 并把 params 中的 user_id 改写为该用户；未启用 jwt 鉴权时沿用客户端传入的 user_id。
+func (s *MCPServer) requestUser(req *MCPRequest) (string, bool) {
+	claimed := strings.TrimSpace(req.UserID)
+	param := strings.TrimSpace(getString(req.Params, "user_id"))
+
+	s.mutex.RLock()
+	jwtAuth := s.auth.Mode == utils.AuthModeJWT && s.auth.Enabled()
+	s.mutex.RUnlock()
+	if !jwtAuth {
+		if claimed == "" {
+			claimed = param
+		}
+		return claimed, true
+	}
+
+	subject := req.authUserID
+	if subject == "" || (claimed != "" && claimed != subject) || (param != "" && param != subject) {
+		return "", false
+	}
+	req.UserID = subject
+	if req.Params == nil {
+		req.Params = make(map[string]interface{})
+	}
+	req.Params["user_id"] = subject
+	return subject, true
+}
+
 func (s *MCPServer) RegisterTool(name string, tool MCPTool) {
 	if tool == nil || name == "" {
 		return
@@ -147,6 +247,60 @@ func (s *MCPServer) RegisterTool(name string, tool MCPTool) {
 	s.mutex.Unlock()
 }
 
+// SetAuth 替换构造时传入的静态令牌鉴权设置，需在 Start 之前调用。
+func (s *MCPServer) SetAuth(auth utils.AuthConfig) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.auth = auth
+}
+
+// SetToolPermissions 配置工具级访问控制。键为工具名（"*" 匹配所有未单独配置的工具），
+// 值为允许的用户ID列表（为空或包含 "*" 表示允许所有用户）。
+func (s *MCPServer) SetToolPermissions(permissions map[string][]string) {
+	cloned := make(map[string][]string, len(permissions))
+	for tool, users := range permissions {
+		tool = strings.TrimSpace(tool)
+		if tool == "" {
+			continue
+		}
+		allowed := make([]string, 0, len(users))
+		for _, user := range users {
+			if user = strings.TrimSpace(user); user != "" {
+				allowed = append(allowed, user)
+			}
+		}
+		cloned[tool] = allowed
+	}
+
+	s.mutex.Lock()
+	s.toolPermissions = cloned
+	s.mutex.Unlock()
+}
+
+// Authorize 判断用户是否被允许调用指定工具。
+func (s *MCPServer) Authorize(userID, toolName string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	allowed, ok := s.toolPermissions[toolName]
+	if !ok {
+		allowed, ok = s.toolPermissions[toolPermissionWildcard]
+	}
+	if !ok || len(allowed) == 0 {
+		return true
+	}
+
+	for _, candidate := range allowed {
+		if candidate == toolPermissionWildcard {
+			return true
+		}
+		if userID != "" && candidate == userID {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *MCPServer) GetToolList() []string {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
@@ -203,11 +357,39 @@ func (s *MCPServer) handleHTTP(w http.ResponseWriter, r *http.Request) {
 		respondJSON(w, MCPResponse{Error: &MCPError{Code: http.StatusBadRequest, Message: err.Error()}})
 		return
 	}
+	req.authUserID = s.auth.UserID(utils.ResolveRequestToken(r))
+
+	if req.Stream {
+		s.handleStream(w, r, &req)
+		return
+	}
 
 	resp := s.HandleRequest(&req)
 	respondJSON(w, *resp)
 }
 
+// handleStream 以 SSE 返回进度通知（notification 事件）与最终响应（response 事件）。
+func (s *MCPServer) handleStream(w http.ResponseWriter, r *http.Request, req *MCPRequest) {
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	_ = rc.Flush()
+
+	resp := s.HandleStreamRequest(r.Context(), req, func(notification MCPNotification) {
+		if writeEvent(w, "notification", notification) == nil {
+			_ = rc.Flush()
+		}
+	})
+	if r.Context().Err() != nil {
+		return
+	}
+	_ = writeEvent(w, "response", resp)
+	_ = rc.Flush()
+}
+
 func (s *MCPServer) handleTools(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusMethodNotAllowed)
@@ -227,13 +409,28 @@ func statusFromError(err error) int {
 	switch {
 	case errors.Is(err, appErrors.ErrInvalidRequest):
 		return http.StatusBadRequest
-	case errors.Is(err, appErrors.ErrSessionNotFound), errors.Is(err, appErrors.ErrToolNotFound):
+	case errors.Is(err, appErrors.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, appErrors.ErrBudgetExceeded), errors.Is(err, appErrors.ErrLLMRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, appErrors.ErrCircuitOpen), errors.Is(err, appErrors.ErrEmbeddingsUnavailable), errors.Is(err, appErrors.ErrLLMBusy):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, appErrors.ErrSessionNotFound), errors.Is(err, appErrors.ErrThoughtNotFound), errors.Is(err, appErrors.ErrSnapshotNotFound), errors.Is(err, appErrors.ErrToolNotFound):
 		return http.StatusNotFound
 	default:
 		return http.StatusInternalServerError
 	}
 }
 
+func writeEvent(w http.ResponseWriter, event string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
+
 func respondJSON(w http.ResponseWriter, resp MCPResponse) {
 	w.Header().Set("Content-Type", "application/json")
 	if resp.Error != nil && resp.Error.Code != 0 {
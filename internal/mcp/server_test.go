@@ -0,0 +1,176 @@
+package mcp_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"WideMindsMCP/internal/mcp"
+	"WideMindsMCP/internal/utils"
+)
+
+type echoTool struct{}
+
+func (echoTool) Name() string        { return "echo" }
+func (echoTool) Description() string { return "echo params" }
+func (echoTool) Execute(params map[string]interface{}) (interface{}, error) {
+	return params, nil
+}
+func (echoTool) Schema() map[string]interface{} { return map[string]interface{}{} }
+
+func newPermissionServer(permissions map[string][]string) *mcp.MCPServer {
+	server := mcp.NewMCPServer(nil, nil, "", 0)
+	server.RegisterTool("echo", echoTool{})
+	server.RegisterTool("admin", echoTool{})
+	server.SetToolPermissions(permissions)
+	return server
+}
+
+func TestAuthorizeToolPermissions(t *testing.T) {
+	server := newPermissionServer(map[string][]string{
+		"admin": {"alice"},
+		"echo":  {},
+	})
+
+	cases := []struct {
+		user, tool string
+		want       bool
+	}{
+		{"alice", "admin", true},
+		{"bob", "admin", false},
+		{"", "admin", false},
+		{"bob", "echo", true},
+		{"bob", "unlisted", true},
+	}
+	for _, tc := range cases {
+		if got := server.Authorize(tc.user, tc.tool); got != tc.want {
+			t.Fatalf("Authorize(%q, %q) = %v, want %v", tc.user, tc.tool, got, tc.want)
+		}
+	}
+}
+
+func TestAuthorizeWildcards(t *testing.T) {
+	server := newPermissionServer(map[string][]string{
+		"*":    {"alice"},
+		"echo": {"*"},
+	})
+
+	if !server.Authorize("bob", "echo") {
+		t.Fatalf("expected wildcard user list to allow any user")
+	}
+	if server.Authorize("bob", "admin") {
+		t.Fatalf("expected wildcard tool entry to restrict unlisted tools")
+	}
+	if !server.Authorize("alice", "admin") {
+		t.Fatalf("expected wildcard tool entry to allow listed user")
+	}
+}
+
+func TestHandleRequestEnforcesToolPermissions(t *testing.T) {
+	server := newPermissionServer(map[string][]string{"admin": {"alice"}})
+
+	denied := server.HandleRequest(&mcp.MCPRequest{Method: "admin", Params: map[string]interface{}{"user_id": "bob"}})
+	if denied.Error == nil || denied.Error.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 error, got %+v", denied.Error)
+	}
+
+	allowed := server.HandleRequest(&mcp.MCPRequest{Method: "admin", UserID: "alice"})
+	if allowed.Error != nil {
+		t.Fatalf("expected request to be allowed, got %+v", allowed.Error)
+	}
+
+	fromParams := server.HandleRequest(&mcp.MCPRequest{Method: "admin", Params: map[string]interface{}{"user_id": "alice"}})
+	if fromParams.Error != nil {
+		t.Fatalf("expected user_id param to be honored, got %+v", fromParams.Error)
+	}
+}
+
+const testJWTSecret = "0123456789abcdef0123456789abcdef"
+
+func signTestJWT(subject string) string {
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+	input := encode(`{"alg":"HS256","typ":"JWT"}`) + "." + encode(`{"sub":"`+subject+`"}`)
+	mac := hmac.New(sha256.New, []byte(testJWTSecret))
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func postMCP(handler http.Handler, token, body string) mcp.MCPResponse {
+	req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	var resp mcp.MCPResponse
+	_ = json.Unmarshal(recorder.Body.Bytes(), &resp)
+	return resp
+}
+
+func TestHandleRequestUsesVerifiedJWTUser(t *testing.T) {
+	server := newPermissionServer(map[string][]string{"admin": {"alice"}})
+	server.SetAuth(utils.AuthConfig{Mode: utils.AuthModeJWT, JWTSecret: testJWTSecret})
+	handler := server.Handler()
+
+	for name, body := range map[string]string{
+		"param":    `{"method":"admin","params":{"user_id":"alice"}}`,
+		"envelope": `{"method":"admin","user_id":"alice"}`,
+	} {
+		if resp := postMCP(handler, signTestJWT("bob"), body); resp.Error == nil || resp.Error.Code != http.StatusForbidden {
+			t.Fatalf("%s: expected a user_id other than the token's sub to be rejected, got %+v", name, resp)
+		}
+	}
+	if resp := postMCP(handler, signTestJWT("bob"), `{"method":"echo","params":{"user_id":"alice"}}`); resp.Error == nil || resp.Error.Code != http.StatusForbidden {
+		t.Fatalf("expected a mismatched user_id to be rejected for unrestricted tools too, got %+v", resp)
+	}
+
+	resp := postMCP(handler, signTestJWT("alice"), `{"method":"admin"}`)
+	if resp.Error != nil {
+		t.Fatalf("expected the token's sub to be authorized, got %+v", resp.Error)
+	}
+	if params, ok := resp.Result.(map[string]interface{}); !ok || params["user_id"] != "alice" {
+		t.Fatalf("expected tools to receive the token's sub as user_id, got %+v", resp.Result)
+	}
+
+	if direct := server.HandleRequest(&mcp.MCPRequest{Method: "admin", UserID: "alice"}); direct.Error == nil || direct.Error.Code != http.StatusForbidden {
+		t.Fatalf("expected requests without a verified token to be rejected under jwt auth, got %+v", direct)
+	}
+}
+
+type streamingEchoTool struct{ echoTool }
+
+func (streamingEchoTool) ExecuteStream(ctx context.Context, params map[string]interface{}, notify func(mcp.ProgressNotification)) (interface{}, error) {
+	for i, word := range []string{"a", "b"} {
+		notify(mcp.ProgressNotification{Progress: i + 1, Message: word})
+	}
+	return params, nil
+}
+
+func TestHandleStreamRequestSendsProgress(t *testing.T) {
+	server := mcp.NewMCPServer(nil, nil, "", 0)
+	server.RegisterTool("stream", streamingEchoTool{})
+	server.RegisterTool("echo", echoTool{})
+
+	var notifications []mcp.MCPNotification
+	resp := server.HandleStreamRequest(context.Background(), &mcp.MCPRequest{Method: "stream", Params: map[string]interface{}{"x": 1}}, func(n mcp.MCPNotification) {
+		notifications = append(notifications, n)
+	})
+	if resp.Error != nil {
+		t.Fatalf("unexpected error %+v", resp.Error)
+	}
+	if len(notifications) != 2 || notifications[0].Method != "notifications/progress" || notifications[1].Params.Message != "b" {
+		t.Fatalf("unexpected notifications %+v", notifications)
+	}
+
+	notifications = nil
+	resp = server.HandleStreamRequest(context.Background(), &mcp.MCPRequest{Method: "echo"}, func(n mcp.MCPNotification) {
+		notifications = append(notifications, n)
+	})
+	if resp.Error != nil || len(notifications) != 0 {
+		t.Fatalf("expected non-streaming tool to execute without notifications, got %+v %+v", resp, notifications)
+	}
+}
@@ -3,8 +3,11 @@
 package mcp
 
 import (
+	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"WideMindsMCP/internal/models"
@@ -20,7 +23,25 @@ type MCPTool interface {
 	Schema() map[string]interface{}
 }
 
+// StreamingTool 是可在执行过程中发送进度通知的工具
+type StreamingTool interface {
+	MCPTool
+	ExecuteStream(ctx context.Context, params map[string]interface{}, notify func(ProgressNotification)) (interface{}, error)
+}
+
 // 结构体
+// TextContent 是工具结果中面向用户的文本块
+type TextContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// ExpansionToolResult 是 expand_thought 的结果：扩展结果字段加上可读的文本块
+type ExpansionToolResult struct {
+	*services.ExpansionResult
+	Content []TextContent `json:"content"`
+}
+
 type ExpandThoughtTool struct {
 	expander *services.ThoughtExpander
 }
@@ -29,6 +50,10 @@ type ExploreDirectionTool struct {
 	expander *services.ThoughtExpander
 }
 
+type AutoExploreTool struct {
+	expander *services.ThoughtExpander
+}
+
 type CreateSessionTool struct {
 	manager *services.SessionManager
 }
@@ -41,6 +66,10 @@ type ListSessionsTool struct {
 	manager *services.SessionManager
 }
 
+type SearchSessionsByConceptTool struct {
+	manager *services.SessionManager
+}
+
 type DeleteSessionTool struct {
 	manager *services.SessionManager
 }
@@ -53,6 +82,155 @@ type DeleteThoughtTool struct {
 	manager *services.SessionManager
 }
 
+type AddReactionTool struct {
+	manager *services.SessionManager
+}
+
+type RemoveReactionTool struct {
+	manager *services.SessionManager
+}
+
+type BulkAddThoughtsTool struct {
+	manager *services.SessionManager
+}
+
+type RemoveContextEntryTool struct {
+	manager *services.SessionManager
+}
+
+type UpdateContextEntryTool struct {
+	manager *services.SessionManager
+}
+
+type SplitThoughtTool struct {
+	manager *services.SessionManager
+}
+
+type MergeThoughtsTool struct {
+	manager *services.SessionManager
+}
+
+type SetSessionRootTool struct {
+	manager *services.SessionManager
+}
+
+type CompactSessionTool struct {
+	manager *services.SessionManager
+}
+
+type GetSessionActivityTool struct {
+	manager *services.SessionManager
+}
+
+type FindSimilarSessionsTool struct {
+	manager *services.SessionManager
+}
+
+type GetWordCloudTool struct {
+	manager *services.SessionManager
+}
+
+type MeasureDistanceTool struct {
+	manager *services.SessionManager
+}
+
+type DiffSessionsTool struct {
+	manager *services.SessionManager
+}
+
+type FindThoughtByExternalIDTool struct {
+	manager *services.SessionManager
+}
+
+type NextActionsTool struct {
+	expander *services.ThoughtExpander
+}
+
+type ReflectOnSessionTool struct {
+	expander *services.ThoughtExpander
+}
+
+type AutoStructureTool struct {
+	expander *services.ThoughtExpander
+}
+
+type AutoTagTool struct {
+	expander *services.ThoughtExpander
+}
+
+type CompleteThoughtTool struct {
+	expander *services.ThoughtExpander
+}
+
+type AssessSessionReadinessTool struct {
+	manager *services.SessionManager
+}
+
+type GetBreadcrumbTool struct {
+	manager *services.SessionManager
+}
+
+type DirectionToSessionTool struct {
+	expander *services.ThoughtExpander
+}
+
+type GenerateTitleTool struct {
+	expander *services.ThoughtExpander
+}
+
+type ExportSessionSVGTool struct {
+	manager *services.SessionManager
+}
+
+type RecommendDirectionTool struct {
+	expander *services.ThoughtExpander
+	manager  *services.SessionManager
+}
+
+type ImportSessionTool struct {
+	manager *services.SessionManager
+}
+
+type GetTokenBudgetTool struct {
+	llm *services.LLMOrchestrator
+}
+
+type EstimateTokensTool struct {
+	llm *services.LLMOrchestrator
+}
+
+type ExportSessionTool struct {
+	manager *services.SessionManager
+}
+
+type ExportSessionCSVTool struct {
+	manager *services.SessionManager
+}
+
+type UndoActionTool struct {
+	manager *services.SessionManager
+}
+
+type RedoActionTool struct {
+	manager *services.SessionManager
+}
+
+type CreateSnapshotTool struct {
+	manager *services.SessionManager
+}
+
+type ListSnapshotsTool struct {
+	manager *services.SessionManager
+}
+
+type RestoreSnapshotTool struct {
+	manager *services.SessionManager
+}
+
+type DeleteSnapshotTool struct {
+	manager *services.SessionManager
+}
+
 const (
 	maxGeneratedDirections = 12
 )
@@ -66,106 +244,1988 @@ func NewExploreDirectionTool(expander *services.ThoughtExpander) MCPTool {
 	return &ExploreDirectionTool{expander: expander}
 }
 
+func NewAutoExploreTool(expander *services.ThoughtExpander) MCPTool {
+	return &AutoExploreTool{expander: expander}
+}
+
 func NewCreateSessionTool(manager *services.SessionManager) MCPTool {
 	return &CreateSessionTool{manager: manager}
 }
 
-func NewGetSessionTool(manager *services.SessionManager) MCPTool {
-	return &GetSessionTool{manager: manager}
+func NewGetSessionTool(manager *services.SessionManager) MCPTool {
+	return &GetSessionTool{manager: manager}
+}
+
+func NewListSessionsTool(manager *services.SessionManager) MCPTool {
+	return &ListSessionsTool{manager: manager}
+}
+
+func NewSearchSessionsByConceptTool(manager *services.SessionManager) MCPTool {
+	return &SearchSessionsByConceptTool{manager: manager}
+}
+
+func NewDeleteSessionTool(manager *services.SessionManager) MCPTool {
+	return &DeleteSessionTool{manager: manager}
+}
+
+func NewUpdateThoughtTool(manager *services.SessionManager) MCPTool {
+	return &UpdateThoughtTool{manager: manager}
+}
+
+func NewDeleteThoughtTool(manager *services.SessionManager) MCPTool {
+	return &DeleteThoughtTool{manager: manager}
+}
+
+func NewAddReactionTool(manager *services.SessionManager) MCPTool {
+	return &AddReactionTool{manager: manager}
+}
+
+func NewRemoveReactionTool(manager *services.SessionManager) MCPTool {
+	return &RemoveReactionTool{manager: manager}
+}
+
+func NewBulkAddThoughtsTool(manager *services.SessionManager) MCPTool {
+	return &BulkAddThoughtsTool{manager: manager}
+}
+
+func NewRemoveContextEntryTool(manager *services.SessionManager) MCPTool {
+	return &RemoveContextEntryTool{manager: manager}
+}
+
+func NewUpdateContextEntryTool(manager *services.SessionManager) MCPTool {
+	return &UpdateContextEntryTool{manager: manager}
+}
+
+func NewNextActionsTool(expander *services.ThoughtExpander) MCPTool {
+	return &NextActionsTool{expander: expander}
+}
+
+func NewReflectOnSessionTool(expander *services.ThoughtExpander) MCPTool {
+	return &ReflectOnSessionTool{expander: expander}
+}
+
+func NewAutoStructureTool(expander *services.ThoughtExpander) MCPTool {
+	return &AutoStructureTool{expander: expander}
+}
+
+func NewAutoTagTool(expander *services.ThoughtExpander) MCPTool {
+	return &AutoTagTool{expander: expander}
+}
+
+func NewCompleteThoughtTool(expander *services.ThoughtExpander) MCPTool {
+	return &CompleteThoughtTool{expander: expander}
+}
+
+func NewAssessSessionReadinessTool(manager *services.SessionManager) MCPTool {
+	return &AssessSessionReadinessTool{manager: manager}
+}
+
+func NewGetBreadcrumbTool(manager *services.SessionManager) MCPTool {
+	return &GetBreadcrumbTool{manager: manager}
+}
+
+func NewDirectionToSessionTool(expander *services.ThoughtExpander) MCPTool {
+	return &DirectionToSessionTool{expander: expander}
+}
+
+func NewGenerateTitleTool(expander *services.ThoughtExpander) MCPTool {
+	return &GenerateTitleTool{expander: expander}
+}
+
+func NewExportSessionSVGTool(manager *services.SessionManager) MCPTool {
+	return &ExportSessionSVGTool{manager: manager}
+}
+
+func NewImportSessionTool(manager *services.SessionManager) MCPTool {
+	return &ImportSessionTool{manager: manager}
+}
+
+func NewGetTokenBudgetTool(llm *services.LLMOrchestrator) MCPTool {
+	return &GetTokenBudgetTool{llm: llm}
+}
+
+func NewEstimateTokensTool(llm *services.LLMOrchestrator) MCPTool {
+	return &EstimateTokensTool{llm: llm}
+}
+
+func NewExportSessionTool(manager *services.SessionManager) MCPTool {
+	return &ExportSessionTool{manager: manager}
+}
+
+func NewSplitThoughtTool(manager *services.SessionManager) MCPTool {
+	return &SplitThoughtTool{manager: manager}
+}
+
+func NewMergeThoughtsTool(manager *services.SessionManager) MCPTool {
+	return &MergeThoughtsTool{manager: manager}
+}
+
+func NewSetSessionRootTool(manager *services.SessionManager) MCPTool {
+	return &SetSessionRootTool{manager: manager}
+}
+
+func NewRecommendDirectionTool(expander *services.ThoughtExpander, manager *services.SessionManager) MCPTool {
+	return &RecommendDirectionTool{expander: expander, manager: manager}
+}
+
+func NewCompactSessionTool(manager *services.SessionManager) MCPTool {
+	return &CompactSessionTool{manager: manager}
+}
+
+func NewGetWordCloudTool(manager *services.SessionManager) MCPTool {
+	return &GetWordCloudTool{manager: manager}
+}
+
+func NewMeasureDistanceTool(manager *services.SessionManager) MCPTool {
+	return &MeasureDistanceTool{manager: manager}
+}
+
+func NewDiffSessionsTool(manager *services.SessionManager) MCPTool {
+	return &DiffSessionsTool{manager: manager}
+}
+
+func NewFindThoughtByExternalIDTool(manager *services.SessionManager) MCPTool {
+	return &FindThoughtByExternalIDTool{manager: manager}
+}
+
+func NewExportSessionCSVTool(manager *services.SessionManager) MCPTool {
+	return &ExportSessionCSVTool{manager: manager}
+}
+
+func NewGetSessionActivityTool(manager *services.SessionManager) MCPTool {
+	return &GetSessionActivityTool{manager: manager}
+}
+
+func NewFindSimilarSessionsTool(manager *services.SessionManager) MCPTool {
+	return &FindSimilarSessionsTool{manager: manager}
+}
+
+func NewUndoActionTool(manager *services.SessionManager) MCPTool {
+	return &UndoActionTool{manager: manager}
+}
+
+func NewRedoActionTool(manager *services.SessionManager) MCPTool {
+	return &RedoActionTool{manager: manager}
+}
+
+func NewCreateSnapshotTool(manager *services.SessionManager) MCPTool {
+	return &CreateSnapshotTool{manager: manager}
+}
+
+func NewListSnapshotsTool(manager *services.SessionManager) MCPTool {
+	return &ListSnapshotsTool{manager: manager}
+}
+
+func NewRestoreSnapshotTool(manager *services.SessionManager) MCPTool {
+	return &RestoreSnapshotTool{manager: manager}
+}
+
+func NewDeleteSnapshotTool(manager *services.SessionManager) MCPTool {
+	return &DeleteSnapshotTool{manager: manager}
+}
+
+// ExpandThoughtTool方法
+func (t *ExpandThoughtTool) Name() string {
+	return "expand_thought"
+}
+
+func (t *ExpandThoughtTool) Description() string {
+	return "Generate multiple directions of thought for a given concept"
+}
+
+func (t *ExpandThoughtTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.expander == nil {
+		return nil, errors.New("thought expander not available")
+	}
+
+	req, err := expansionRequestFromParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := t.expander.Expand(req)
+	if err != nil {
+		return nil, err
+	}
+	return newExpansionToolResult(result), nil
+}
+
+// ExecuteStream 与 Execute 相同，但将模型输出的增量作为进度通知发送。
+func (t *ExpandThoughtTool) ExecuteStream(ctx context.Context, params map[string]interface{}, notify func(ProgressNotification)) (interface{}, error) {
+	if t.expander == nil {
+		return nil, errors.New("thought expander not available")
+	}
+
+	req, err := expansionRequestFromParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := 0
+	result, err := t.expander.ExpandStream(ctx, req, func(delta services.ExpansionDelta) {
+		progress++
+		notify(ProgressNotification{
+			Progress:  progress,
+			Stage:     delta.Stage,
+			Direction: delta.Direction,
+			Message:   delta.Delta,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newExpansionToolResult(result), nil
+}
+
+func expansionRequestFromParams(params map[string]interface{}) (*services.ExpansionRequest, error) {
+	// 指定 thought_id 时扩散会话中的已有节点，概念取自节点内容
+	thoughtID := strings.TrimSpace(getString(params, "thought_id"))
+	concept := strings.TrimSpace(getString(params, "concept"))
+	if thoughtID == "" {
+		if err := utils.ValidateConcept(concept); err != nil {
+			return nil, err
+		}
+	}
+
+	contextEntries, err := getContextEntries(params, "context")
+	if err != nil {
+		return nil, err
+	}
+	normalizedContext, err := utils.NormalizeContextEntries(contextEntries)
+	if err != nil {
+		return nil, err
+	}
+
+	expansionTypeRaw := strings.TrimSpace(getString(params, "expansion_type"))
+	var expansionType models.DirectionType
+	if expansionTypeRaw != "" {
+		parsed, err := utils.ParseDirectionType(expansionTypeRaw)
+		if err != nil {
+			return nil, err
+		}
+		expansionType = parsed
+	}
+
+	// 扩散已有节点时默认计入会话所属用户的预算，其余情况必须指明用户
+	userID := strings.TrimSpace(getString(params, "user_id"))
+	if userID == "" && thoughtID == "" {
+		return nil, utils.ValidationError("user_id is required")
+	}
+	if userID != "" {
+		if err := utils.ValidateUserID(userID); err != nil {
+			return nil, err
+		}
+	}
+
+	maxDirections := getInt(params, "max_directions", 4)
+	if maxDirections <= 0 {
+		maxDirections = 4
+	}
+	if maxDirections > maxGeneratedDirections {
+		return nil, utils.ValidationError("max_directions is too large")
+	}
+
+	concurrencyLimit := getInt(params, "concurrency_limit", 0)
+	if concurrencyLimit > services.MaxExpansionConcurrency {
+		return nil, utils.ValidationError("concurrency_limit is too large")
+	}
+
+	maxTotalThoughts := getInt(params, "max_total_thoughts", 0)
+	if maxTotalThoughts < 0 {
+		return nil, utils.ValidationError("max_total_thoughts must not be negative")
+	}
+
+	minRelevance := getFloat(params, "min_relevance", 0)
+	if minRelevance < 0 || minRelevance > 1 {
+		return nil, utils.ValidationError("min_relevance must be between 0 and 1")
+	}
+
+	thinkingStyle, err := utils.ParseThinkingStyle(getString(params, "thinking_style"))
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if sessionID != "" {
+		if err := utils.ValidateSessionID(sessionID); err != nil {
+			return nil, err
+		}
+	}
+	persist := getBool(params, "persist", false)
+	if persist && sessionID == "" {
+		return nil, utils.ValidationError("session_id is required to persist an expansion")
+	}
+	if thoughtID != "" && sessionID == "" {
+		return nil, utils.ValidationError("session_id is required when thought_id is set")
+	}
+
+	language := strings.TrimSpace(getString(params, "language"))
+	if language != "" {
+		normalized, ok := services.NormalizeLanguageTag(language)
+		if !ok {
+			return nil, utils.ValidationError("language must be a BCP-47 tag such as en or zh-CN")
+		}
+		language = normalized
+	}
+
+	var maxExamples *int
+	if _, ok := params["max_examples"]; ok {
+		limit := getInt(params, "max_examples", -1)
+		maxExamples = &limit
+	}
+
+	systemPrompt := strings.TrimSpace(getString(params, "system_prompt"))
+	if err := utils.ValidateSystemPrompt(systemPrompt); err != nil {
+		return nil, err
+	}
+	extraInstructions, err := utils.NormalizeInstructions(getStringSlice(params, "extra_instructions"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &services.ExpansionRequest{
+		Concept:              concept,
+		Context:              normalizedContext,
+		ExpansionType:        expansionType,
+		MaxDirections:        maxDirections,
+		UserID:               userID,
+		ConcurrencyLimit:     concurrencyLimit,
+		MaxTotalThoughts:     maxTotalThoughts,
+		NoCache:              getBool(params, "no_cache", false),
+		ThinkingStyle:        thinkingStyle,
+		SessionID:            sessionID,
+		MaxExamples:          maxExamples,
+		Language:             language,
+		SystemPrompt:         systemPrompt,
+		ExtraInstructions:    extraInstructions,
+		Persist:              persist,
+		ParentThoughtID:      strings.TrimSpace(getString(params, "parent_id")),
+		ThoughtID:            thoughtID,
+		MinRelevance:         minRelevance,
+		FallbackIfEmpty:      getBool(params, "fallback_if_empty", false),
+		IncludeSummary:       getBool(params, "include_summary", false),
+		ForceIncludeExisting: getBool(params, "force_include_existing", false),
+		PathHints:            pathHintOptionsFromParams(params),
+	}, nil
+}
+
+// pathHintOptionsFromParams 读取历史路径提示的请求覆盖，取值由扩展器校验。
+func pathHintOptionsFromParams(params map[string]interface{}) services.PathHintOptions {
+	return services.PathHintOptions{
+		Limit:    getInt(params, "path_hint_limit", 0),
+		Strategy: services.PathHintStrategy(strings.TrimSpace(getString(params, "path_hint_strategy"))),
+	}
+}
+
+// newExpansionToolResult 在扩展结果外附加以 Summary 为内容的文本块，供客户端直接展示。
+func newExpansionToolResult(result *services.ExpansionResult) *ExpansionToolResult {
+	return &ExpansionToolResult{
+		ExpansionResult: result,
+		Content:         []TextContent{{Type: "text", Text: result.Summary}},
+	}
+}
+
+func (t *ExpandThoughtTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"concept":                "string",
+		"context":                "array[string|{kind,value}]",
+		"expansion_type":         "enum[broad,deep,lateral,critical]",
+		"max_directions":         "number",
+		"user_id":                "string",
+		"concurrency_limit":      "number",
+		"max_total_thoughts":     "number",
+		"no_cache":               "boolean",
+		"thinking_style":         "enum[focused,balanced,creative]",
+		"session_id":             "string",
+		"max_examples":           "number",
+		"language":               "string",
+		"system_prompt":          "string",
+		"extra_instructions":     "array[string]",
+		"persist":                "boolean",
+		"parent_id":              "string",
+		"thought_id":             "string",
+		"min_relevance":          "number",
+		"fallback_if_empty":      "boolean",
+		"include_summary":        "boolean",
+		"force_include_existing": "boolean",
+		"path_hint_limit":        "number",
+		"path_hint_strategy":     "enum[deepest,most_recent,same_branch]",
+	}
+}
+
+// ExploreDirectionTool方法
+func (t *ExploreDirectionTool) Name() string {
+	return "explore_direction"
+}
+
+func (t *ExploreDirectionTool) Description() string {
+	return "Deeply explore a selected direction within an existing session"
+}
+
+func (t *ExploreDirectionTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.expander == nil {
+		return nil, errors.New("thought expander not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+
+	directionMap, ok := params["direction"].(map[string]interface{})
+	if !ok {
+		return nil, utils.ValidationError("direction payload is required")
+	}
+
+	direction, err := buildDirection(directionMap)
+	if err != nil {
+		return nil, err
+	}
+
+	parentID := strings.TrimSpace(getString(params, "parent_id"))
+	thought, err := t.expander.ExploreDirectionWithHints(*direction, sessionID, parentID, pathHintOptionsFromParams(params))
+	if err != nil {
+		return nil, err
+	}
+	return thought, nil
+}
+
+func (t *ExploreDirectionTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id":         "string",
+		"parent_id":          "string",
+		"path_hint_limit":    "number",
+		"path_hint_strategy": "enum[deepest,most_recent,same_branch]",
+		"direction": map[string]interface{}{
+			"type":        "string",
+			"title":       "string",
+			"description": "string",
+			"keywords":    "array[string]",
+			"relevance":   "number",
+		},
+	}
+}
+
+// AutoExploreTool方法
+func (t *AutoExploreTool) Name() string {
+	return "auto_explore"
+}
+
+func (t *AutoExploreTool) Description() string {
+	return "Create a session and automatically explore the top directions of a concept several levels deep"
+}
+
+func (t *AutoExploreTool) Execute(params map[string]interface{}) (interface{}, error) {
+	return t.ExecuteStream(context.Background(), params, func(ProgressNotification) {})
+}
+
+// ExecuteStream 与 Execute 相同，每个节点扩散并保存后发送一条进度通知。
+func (t *AutoExploreTool) ExecuteStream(ctx context.Context, params map[string]interface{}, notify func(ProgressNotification)) (interface{}, error) {
+	if t.expander == nil {
+		return nil, errors.New("thought expander not available")
+	}
+
+	req, err := autoExploreRequestFromParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := 0
+	session, err := t.expander.AutoExploreStream(ctx, *req, func(update services.AutoExploreProgress) {
+		progress++
+		notify(ProgressNotification{
+			Progress: progress,
+			Stage:    fmt.Sprintf("level_%d", update.Level),
+			Message:  fmt.Sprintf("added %d thoughts under %s (%d total in session %s)", len(update.ThoughtIDs), update.ParentThoughtID, update.TotalThoughts, update.SessionID),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func autoExploreRequestFromParams(params map[string]interface{}) (*services.AutoExploreRequest, error) {
+	userID := strings.TrimSpace(getString(params, "user_id"))
+	if userID == "" {
+		return nil, utils.ValidationError("user_id is required")
+	}
+	if err := utils.ValidateUserID(userID); err != nil {
+		return nil, err
+	}
+	concept := strings.TrimSpace(getString(params, "concept"))
+	if err := utils.ValidateConcept(concept); err != nil {
+		return nil, err
+	}
+
+	contextEntries, err := getContextEntries(params, "context")
+	if err != nil {
+		return nil, err
+	}
+	normalizedContext, err := utils.NormalizeContextEntries(contextEntries)
+	if err != nil {
+		return nil, err
+	}
+
+	return &services.AutoExploreRequest{
+		UserID:           userID,
+		Concept:          concept,
+		Context:          normalizedContext,
+		Breadth:          getInt(params, "breadth", services.DefaultAutoExploreBreadth),
+		Depth:            getInt(params, "depth", services.DefaultAutoExploreDepth),
+		MaxNodes:         getInt(params, "max_nodes", 0),
+		ConcurrencyLimit: getInt(params, "concurrency_limit", 0),
+	}, nil
+}
+
+func (t *AutoExploreTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"user_id":           "string",
+		"concept":           "string",
+		"context":           "array[string|{kind,value}]",
+		"breadth":           "number",
+		"depth":             "number",
+		"max_nodes":         "number",
+		"concurrency_limit": "number",
+	}
+}
+
+// CreateSessionTool方法
+func (t *CreateSessionTool) Name() string {
+	return "create_session"
+}
+
+func (t *CreateSessionTool) Description() string {
+	return "Create a new thought session for a user"
+}
+
+func (t *CreateSessionTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	userID := strings.TrimSpace(getString(params, "user_id"))
+	concept := strings.TrimSpace(getString(params, "concept"))
+	if err := utils.ValidateUserID(userID); err != nil {
+		return nil, err
+	}
+	if err := utils.ValidateConcept(concept); err != nil {
+		return nil, err
+	}
+
+	session, err := t.manager.CreateSession(userID, concept)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (t *CreateSessionTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"user_id": "string",
+		"concept": "string",
+	}
+}
+
+// GetSessionTool方法
+func (t *GetSessionTool) Name() string {
+	return "get_session"
+}
+
+func (t *GetSessionTool) Description() string {
+	return "Retrieve an existing session by ID"
+}
+
+func (t *GetSessionTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+
+	session, err := t.manager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (t *GetSessionTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+	}
+}
+
+func (t *ListSessionsTool) Name() string {
+	return "list_sessions"
+}
+
+func (t *ListSessionsTool) Description() string {
+	return "List sessions for a user"
+}
+
+func (t *ListSessionsTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	userID := strings.TrimSpace(getString(params, "user_id"))
+	if userID == "" {
+		return nil, utils.ValidationError("user_id is required")
+	}
+	if err := utils.ValidateUserID(userID); err != nil {
+		return nil, err
+	}
+
+	return t.manager.ListSessions(userID)
+}
+
+func (t *ListSessionsTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"user_id": "string",
+	}
+}
+
+func (t *SearchSessionsByConceptTool) Name() string {
+	return "search_sessions_by_concept"
+}
+
+func (t *SearchSessionsByConceptTool) Description() string {
+	return "Find a user's sessions whose concept contains the given text, optionally limited to a tag"
+}
+
+func (t *SearchSessionsByConceptTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	userID := strings.TrimSpace(getString(params, "user_id"))
+	if userID == "" {
+		return nil, utils.ValidationError("user_id is required")
+	}
+	if err := utils.ValidateUserID(userID); err != nil {
+		return nil, err
+	}
+	concept := strings.TrimSpace(getString(params, "concept"))
+	if concept == "" {
+		return nil, utils.ValidationError("concept is required")
+	}
+
+	return t.manager.SearchSessions(userID, concept, getString(params, "tag"))
+}
+
+func (t *SearchSessionsByConceptTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"user_id": "string",
+		"concept": "string",
+		"tag":     "string",
+	}
+}
+
+func (t *DeleteSessionTool) Name() string {
+	return "delete_session"
+}
+
+func (t *DeleteSessionTool) Description() string {
+	return "Delete a session by ID"
+}
+
+func (t *DeleteSessionTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+
+	if err := t.manager.DeleteSession(sessionID); err != nil {
+		return nil, err
+	}
+
+	return map[string]string{"status": "deleted", "session_id": sessionID}, nil
+}
+
+func (t *DeleteSessionTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+	}
+}
+
+func (t *UpdateThoughtTool) Name() string {
+	return "update_thought"
+}
+
+func (t *UpdateThoughtTool) Description() string {
+	return "Update the content or direction metadata of a thought node"
+}
+
+func (t *UpdateThoughtTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+
+	thoughtID := strings.TrimSpace(getString(params, "thought_id"))
+	if thoughtID == "" {
+		return nil, utils.ValidationError("thought_id is required")
+	}
+
+	update := &models.ThoughtUpdate{}
+	if _, ok := params["content"]; ok {
+		content := getString(params, "content")
+		update.Content = &content
+	}
+	if rawDir, ok := params["direction"]; ok {
+		dirMap, valid := rawDir.(map[string]interface{})
+		if !valid {
+			return nil, utils.ValidationError("direction must be an object")
+		}
+		direction, err := buildDirection(dirMap)
+		if err != nil {
+			return nil, err
+		}
+		update.Direction = direction
+	}
+	if _, ok := params["tags"]; ok {
+		tags := getStringSlice(params, "tags")
+		update.Tags = &tags
+	}
+	if _, ok := params["confidence"]; ok {
+		confidence := getFloat(params, "confidence", 0)
+		update.Confidence = &confidence
+	}
+	if _, ok := params["external_id"]; ok {
+		externalID := getString(params, "external_id")
+		update.ExternalID = &externalID
+	}
+	if _, ok := params["external_system_url"]; ok {
+		externalURL := getString(params, "external_system_url")
+		update.ExternalSystemURL = &externalURL
+	}
+
+	if err := utils.ValidateThoughtUpdate(update); err != nil {
+		return nil, err
+	}
+
+	return t.manager.UpdateThought(sessionID, thoughtID, update)
+}
+
+func (t *UpdateThoughtTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+		"thought_id": "string",
+		"content":    "string",
+		"direction": map[string]interface{}{
+			"type":        "string",
+			"title":       "string",
+			"description": "string",
+			"keywords":    "array[string]",
+			"relevance":   "number",
+		},
+		"tags":                "array[string]",
+		"confidence":          "number",
+		"external_id":         "string",
+		"external_system_url": "string",
+	}
+}
+
+func (t *DeleteThoughtTool) Name() string {
+	return "delete_thought"
+}
+
+func (t *DeleteThoughtTool) Description() string {
+	return "Delete a thought node from a session"
+}
+
+func (t *DeleteThoughtTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+
+	thoughtID := strings.TrimSpace(getString(params, "thought_id"))
+	if thoughtID == "" {
+		return nil, utils.ValidationError("thought_id is required")
+	}
+
+	return t.manager.DeleteThought(sessionID, thoughtID)
+}
+
+func (t *DeleteThoughtTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+		"thought_id": "string",
+	}
+}
+
+// AddReactionTool方法
+func (t *AddReactionTool) Name() string {
+	return "add_reaction"
+}
+
+func (t *AddReactionTool) Description() string {
+	return "React to a thought with an emoji such as 👍 👎 💡 ❓ without editing its content"
+}
+
+func (t *AddReactionTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	sessionID, thoughtID, emoji, err := reactionParams(params)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.manager.AddReaction(sessionID, thoughtID, emoji); err != nil {
+		return nil, err
+	}
+	return t.manager.Reactions(sessionID, thoughtID)
+}
+
+func (t *AddReactionTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+		"thought_id": "string",
+		"emoji":      "string",
+	}
+}
+
+// RemoveReactionTool方法
+func (t *RemoveReactionTool) Name() string {
+	return "remove_reaction"
+}
+
+func (t *RemoveReactionTool) Description() string {
+	return "Withdraw one emoji reaction from a thought"
+}
+
+func (t *RemoveReactionTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	sessionID, thoughtID, emoji, err := reactionParams(params)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.manager.RemoveReaction(sessionID, thoughtID, emoji); err != nil {
+		return nil, err
+	}
+	return t.manager.Reactions(sessionID, thoughtID)
+}
+
+func (t *RemoveReactionTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+		"thought_id": "string",
+		"emoji":      "string",
+	}
+}
+
+func reactionParams(params map[string]interface{}) (string, string, string, error) {
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return "", "", "", err
+	}
+	thoughtID := strings.TrimSpace(getString(params, "thought_id"))
+	if thoughtID == "" {
+		return "", "", "", utils.ValidationError("thought_id is required")
+	}
+	emoji := strings.TrimSpace(getString(params, "emoji"))
+	if err := utils.ValidateEmoji(emoji); err != nil {
+		return "", "", "", err
+	}
+	return sessionID, thoughtID, emoji, nil
+}
+
+// BulkAddThoughtsTool方法
+func (t *BulkAddThoughtsTool) Name() string {
+	return "bulk_add_thoughts"
+}
+
+func (t *BulkAddThoughtsTool) Description() string {
+	return "Add up to 50 thoughts in order; parent_id may reference existing thoughts or the id of an earlier item in the batch"
+}
+
+func (t *BulkAddThoughtsTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+
+	rawItems, ok := params["thoughts"].([]interface{})
+	if !ok {
+		return nil, utils.ValidationError("thoughts must be an array")
+	}
+	items := make([]services.BulkThought, 0, len(rawItems))
+	for _, raw := range rawItems {
+		itemMap, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, utils.ValidationError("thoughts must be objects")
+		}
+		item := services.BulkThought{
+			ID:       getString(itemMap, "id"),
+			Content:  getString(itemMap, "content"),
+			ParentID: getString(itemMap, "parent_id"),
+		}
+		if dirMap, ok := itemMap["direction"].(map[string]interface{}); ok {
+			// 方向在服务层逐项校验，错误计入该项而不是整个批次
+			item.Direction = &models.Direction{
+				Type:        models.DirectionType(getString(dirMap, "type")),
+				Title:       getString(dirMap, "title"),
+				Description: getString(dirMap, "description"),
+				Keywords:    getStringSlice(dirMap, "keywords"),
+				Relevance:   getFloat(dirMap, "relevance", 0.5),
+			}
+		}
+		items = append(items, item)
+	}
+
+	return t.manager.BulkAddThoughts(sessionID, items)
+}
+
+func (t *BulkAddThoughtsTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+		"thoughts":   "array[{id,content,parent_id,direction}]",
+	}
+}
+
+// RemoveContextEntryTool方法
+func (t *RemoveContextEntryTool) Name() string {
+	return "remove_context_entry"
+}
+
+func (t *RemoveContextEntryTool) Description() string {
+	return "Remove the context entry at the given index from a session"
+}
+
+func (t *RemoveContextEntryTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+	if _, ok := params["index"]; !ok {
+		return nil, utils.ValidationError("index is required")
+	}
+
+	return t.manager.RemoveContextEntry(sessionID, getInt(params, "index", -1))
+}
+
+func (t *RemoveContextEntryTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+		"index":      "number",
+	}
+}
+
+// UpdateContextEntryTool方法
+func (t *UpdateContextEntryTool) Name() string {
+	return "update_context_entry"
+}
+
+func (t *UpdateContextEntryTool) Description() string {
+	return "Replace the context entry at the given index; kind defaults to the existing entry's kind"
+}
+
+func (t *UpdateContextEntryTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+	if _, ok := params["index"]; !ok {
+		return nil, utils.ValidationError("index is required")
+	}
+
+	entry := models.ContextEntry{Value: getString(params, "value")}
+	if rawKind := strings.TrimSpace(getString(params, "kind")); rawKind != "" {
+		kind, ok := models.ParseContextKind(rawKind)
+		if !ok {
+			return nil, utils.ValidationError("context entry has an unknown kind")
+		}
+		entry.Kind = kind
+	}
+
+	return t.manager.UpdateContextEntry(sessionID, getInt(params, "index", -1), entry)
+}
+
+func (t *UpdateContextEntryTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+		"index":      "number",
+		"kind":       "string",
+		"value":      "string",
+	}
+}
+
+// SplitThoughtTool方法
+func (t *SplitThoughtTool) Name() string {
+	return "split_thought"
+}
+
+func (t *SplitThoughtTool) Description() string {
+	return "Split a multi-idea thought into the original plus new child thoughts"
+}
+
+func (t *SplitThoughtTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+
+	thoughtID := strings.TrimSpace(getString(params, "thought_id"))
+	if thoughtID == "" {
+		return nil, utils.ValidationError("thought_id is required")
+	}
+
+	mode := models.SplitMode(strings.ToLower(strings.TrimSpace(getString(params, "split_by"))))
+	session, err := t.manager.SplitThought(sessionID, thoughtID, mode, getStringSlice(params, "parts"))
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (t *SplitThoughtTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+		"thought_id": "string",
+		"split_by":   "enum[sentence,paragraph,manual]",
+		"parts":      "array[string]",
+	}
+}
+
+// MergeThoughtsTool方法
+func (t *MergeThoughtsTool) Name() string {
+	return "merge_thoughts"
+}
+
+func (t *MergeThoughtsTool) Description() string {
+	return "Merge a sibling thought into another thought and remove the sibling"
+}
+
+func (t *MergeThoughtsTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+
+	thoughtID := strings.TrimSpace(getString(params, "thought_id"))
+	siblingID := strings.TrimSpace(getString(params, "sibling_id"))
+	if thoughtID == "" || siblingID == "" {
+		return nil, utils.ValidationError("thought_id and sibling_id are required")
+	}
+
+	session, err := t.manager.MergeThoughts(sessionID, thoughtID, siblingID, getString(params, "separator"), getBool(params, "force", false))
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (t *MergeThoughtsTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+		"thought_id": "string",
+		"sibling_id": "string",
+		"separator":  "string",
+		"force":      "boolean",
+	}
+}
+
+// FindThoughtByExternalIDTool方法
+func (t *FindThoughtByExternalIDTool) Name() string {
+	return "find_thought_by_external_id"
+}
+
+func (t *FindThoughtByExternalIDTool) Description() string {
+	return "Find the thought linked to an external system ID such as a Jira or Notion ticket"
+}
+
+func (t *FindThoughtByExternalIDTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+
+	return t.manager.FindThoughtByExternalID(sessionID, getString(params, "external_id"))
+}
+
+func (t *FindThoughtByExternalIDTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id":  "string",
+		"external_id": "string",
+	}
+}
+
+// SetSessionRootTool方法
+func (t *SetSessionRootTool) Name() string {
+	return "set_session_root"
+}
+
+func (t *SetSessionRootTool) Description() string {
+	return "Replace the root thought of a session; existing top-level thoughts move under the new root"
+}
+
+func (t *SetSessionRootTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+
+	var direction *models.Direction
+	if directionMap, ok := params["direction"].(map[string]interface{}); ok {
+		parsed, err := buildDirection(directionMap)
+		if err != nil {
+			return nil, err
+		}
+		direction = parsed
+	}
+
+	return t.manager.SetRootThought(sessionID, getString(params, "content"), direction)
+}
+
+func (t *SetSessionRootTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+		"content":    "string",
+		"direction": map[string]interface{}{
+			"type":        "string",
+			"title":       "string",
+			"description": "string",
+			"keywords":    "array[string]",
+			"relevance":   "number",
+		},
+	}
+}
+
+// CompactSessionTool方法
+func (t *CompactSessionTool) Name() string {
+	return "compact_session"
+}
+
+func (t *CompactSessionTool) Description() string {
+	return "Remove leaf thoughts whose direction relevance is below min_relevance (undoable)"
+}
+
+func (t *CompactSessionTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+	if _, ok := params["min_relevance"]; !ok {
+		return nil, utils.ValidationError("min_relevance is required")
+	}
+
+	removed, err := t.manager.CompactSession(sessionID, getFloat(params, "min_relevance", 0))
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"session_id": sessionID,
+		"removed":    removed,
+	}, nil
+}
+
+func (t *CompactSessionTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id":    "string",
+		"min_relevance": "number",
+	}
+}
+
+// GetSessionActivityTool方法
+func (t *GetSessionActivityTool) Name() string {
+	return "get_session_activity"
+}
+
+func (t *GetSessionActivityTool) Description() string {
+	return "List recent changes made to a session, newest first"
+}
+
+func (t *GetSessionActivityTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+
+	activity, err := t.manager.SessionActivity(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if limit := getInt(params, "limit", 0); limit > 0 && limit < len(activity) {
+		activity = activity[:limit]
+	}
+	return map[string]interface{}{
+		"session_id": sessionID,
+		"activity":   activity,
+	}, nil
+}
+
+func (t *GetSessionActivityTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+		"limit":      "number",
+	}
+}
+
+// FindSimilarSessionsTool方法
+func (t *FindSimilarSessionsTool) Name() string {
+	return "find_similar_sessions"
+}
+
+func (t *FindSimilarSessionsTool) Description() string {
+	return "Find the user's past sessions most similar to a session, ranked by embedding similarity"
+}
+
+func (t *FindSimilarSessionsTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+
+	top := getInt(params, "top", services.DefaultSimilarSessions)
+	if top > services.MaxSimilarSessions {
+		return nil, utils.ValidationError("top is too large")
+	}
+
+	similar, err := t.manager.FindSimilarSessions(sessionID, top)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"session_id": sessionID,
+		"sessions":   similar,
+	}, nil
+}
+
+func (t *FindSimilarSessionsTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+		"top":        "number",
+	}
+}
+
+// GetWordCloudTool方法
+func (t *GetWordCloudTool) Name() string {
+	return "get_word_cloud"
+}
+
+func (t *GetWordCloudTool) Description() string {
+	return "List the most frequent words across a session's thoughts and direction keywords"
+}
+
+func (t *GetWordCloudTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+
+	top := getInt(params, "top", services.DefaultWordCloudSize)
+	if top > services.MaxWordCloudSize {
+		return nil, utils.ValidationError("top is too large")
+	}
+
+	words, err := t.manager.WordCloud(sessionID, top)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"session_id": sessionID,
+		"words":      words,
+	}, nil
+}
+
+func (t *GetWordCloudTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+		"top":        "number",
+	}
+}
+
+// MeasureDistanceTool方法
+func (t *MeasureDistanceTool) Name() string {
+	return "measure_distance"
+}
+
+func (t *MeasureDistanceTool) Description() string {
+	return "Measure how semantically related two thoughts in a session are using embedding cosine distance"
+}
+
+func (t *MeasureDistanceTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+	thoughtA := strings.TrimSpace(getString(params, "thought_id_a"))
+	thoughtB := strings.TrimSpace(getString(params, "thought_id_b"))
+	if thoughtA == "" || thoughtB == "" {
+		return nil, utils.ValidationError("thought_id_a and thought_id_b are required")
+	}
+
+	distance, err := t.manager.MeasureThoughtDistance(sessionID, thoughtA, thoughtB)
+	if err != nil {
+		return nil, err
+	}
+	return distance, nil
+}
+
+func (t *MeasureDistanceTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id":   "string",
+		"thought_id_a": "string",
+		"thought_id_b": "string",
+	}
+}
+
+// DiffSessionsTool方法
+func (t *DiffSessionsTool) Name() string {
+	return "diff_sessions"
+}
+
+func (t *DiffSessionsTool) Description() string {
+	return "Compare the thought trees of two sessions and report added, removed, modified and moved thoughts"
+}
+
+func (t *DiffSessionsTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+	otherID := strings.TrimSpace(getString(params, "other_session_id"))
+	if err := utils.ValidateSessionID(otherID); err != nil {
+		return nil, err
+	}
+
+	diff, err := t.manager.DiffSessions(sessionID, otherID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"diff":    diff,
+		"summary": diff.String(),
+	}, nil
+}
+
+func (t *DiffSessionsTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id":       "string",
+		"other_session_id": "string",
+	}
+}
+
+func (t *ExportSessionCSVTool) Name() string {
+	return "export_session_csv"
+}
+
+func (t *ExportSessionCSVTool) Description() string {
+	return "Export all thoughts of a session as a flat CSV document"
+}
+
+func (t *ExportSessionCSVTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+
+	session, err := t.manager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := session.ToCSV()
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"session_id": sessionID,
+		"filename":   fmt.Sprintf("session-%s.csv", sessionID),
+		"mime_type":  "text/csv",
+		"content":    string(data),
+	}, nil
+}
+
+func (t *ExportSessionCSVTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+	}
+}
+
+// ExportSessionSVGTool方法
+func (t *ExportSessionSVGTool) Name() string {
+	return "export_session_svg"
+}
+
+func (t *ExportSessionSVGTool) Description() string {
+	return "Render the thought tree of a session as an SVG mind map, returned base64-encoded"
+}
+
+func (t *ExportSessionSVGTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+	width := getInt(params, "width", models.DefaultSVGWidth)
+	height := getInt(params, "height", models.DefaultSVGHeight)
+	if err := utils.ValidateSVGDimensions(width, height); err != nil {
+		return nil, err
+	}
+
+	session, err := t.manager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := session.ToSVG(width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"session_id": sessionID,
+		"filename":   fmt.Sprintf("session-%s.svg", sessionID),
+		"mime_type":  "image/svg+xml",
+		"encoding":   "base64",
+		"content":    base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
+
+func (t *ExportSessionSVGTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+		"width":      "number",
+		"height":     "number",
+	}
+}
+
+// NextActionsTool方法
+func (t *NextActionsTool) Name() string {
+	return "suggest_next_actions"
+}
+
+func (t *NextActionsTool) Description() string {
+	return "Suggest what to explore next based on the current state of a session"
+}
+
+func (t *NextActionsTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.expander == nil {
+		return nil, errors.New("thought expander not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+
+	maxSuggestions := getInt(params, "max_suggestions", services.DefaultNextActions)
+	if maxSuggestions <= 0 {
+		maxSuggestions = services.DefaultNextActions
+	}
+	if maxSuggestions > services.MaxNextActions {
+		return nil, utils.ValidationError("max_suggestions is too large")
+	}
+
+	actions, err := t.expander.SuggestNextActions(sessionID, maxSuggestions)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"session_id": sessionID,
+		"actions":    actions,
+	}, nil
+}
+
+func (t *NextActionsTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id":      "string",
+		"max_suggestions": "number",
+	}
+}
+
+// ReflectOnSessionTool方法
+func (t *ReflectOnSessionTool) Name() string {
+	return "reflect_on_session"
+}
+
+func (t *ReflectOnSessionTool) Description() string {
+	return "Assess a session's strengths, gaps, unanswered questions, and suggested next steps"
+}
+
+func (t *ReflectOnSessionTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.expander == nil {
+		return nil, errors.New("thought expander not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+
+	report, err := t.expander.ReflectOnSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"session_id": sessionID,
+		"reflection": report,
+	}, nil
+}
+
+func (t *ReflectOnSessionTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+	}
+}
+
+// RecommendDirectionTool方法
+func (t *RecommendDirectionTool) Name() string {
+	return "recommend_direction"
+}
+
+func (t *RecommendDirectionTool) Description() string {
+	return "Recommend which of the given directions to explore first based on the user's goals and session history"
+}
+
+func (t *RecommendDirectionTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.expander == nil || t.manager == nil {
+		return nil, errors.New("thought expander not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+
+	rawDirections, ok := params["directions"].([]interface{})
+	if !ok || len(rawDirections) == 0 {
+		return nil, utils.ValidationError("directions array is required")
+	}
+	directions := make([]models.Direction, 0, len(rawDirections))
+	for _, raw := range rawDirections {
+		directionMap, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, utils.ValidationError("directions must be objects")
+		}
+		direction, err := buildDirection(directionMap)
+		if err != nil {
+			return nil, err
+		}
+		directions = append(directions, *direction)
+	}
+
+	session, err := t.manager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	recommended, rationale, err := t.expander.RecommendDirection(directions, nil, session)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"session_id": sessionID,
+		"direction":  recommended,
+		"rationale":  rationale,
+	}, nil
+}
+
+func (t *RecommendDirectionTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+		"directions": "array",
+	}
+}
+
+// AutoStructureTool方法
+func (t *AutoStructureTool) Name() string {
+	return "auto_structure"
+}
+
+func (t *AutoStructureTool) Description() string {
+	return "Reorganize a session's thoughts into a hierarchy suggested by the LLM (requires confirm=true; undoable)"
+}
+
+func (t *AutoStructureTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.expander == nil {
+		return nil, errors.New("thought expander not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+	if !getBool(params, "confirm", false) {
+		return nil, utils.ValidationError("confirm must be true")
+	}
+
+	return t.expander.AutoStructure(sessionID)
+}
+
+func (t *AutoStructureTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+		"confirm":    "boolean",
+	}
+}
+
+// AutoTagTool方法
+func (t *AutoTagTool) Name() string {
+	return "auto_tag"
+}
+
+func (t *AutoTagTool) Description() string {
+	return "Generate 3-7 concise tags for a session from its concept, directions and key thoughts, replacing existing tags"
+}
+
+func (t *AutoTagTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.expander == nil {
+		return nil, errors.New("thought expander not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+
+	return t.expander.AutoTagSession(sessionID)
+}
+
+func (t *AutoTagTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+	}
+}
+
+// CompleteThoughtTool方法
+func (t *CompleteThoughtTool) Name() string {
+	return "complete_thought"
+}
+
+func (t *CompleteThoughtTool) Description() string {
+	return "Complete a partially written thought using its path as context; set apply to save the result to the thought"
+}
+
+func (t *CompleteThoughtTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.expander == nil {
+		return nil, errors.New("thought expander not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+	thoughtID := strings.TrimSpace(getString(params, "thought_id"))
+	if thoughtID == "" {
+		return nil, utils.ValidationError("thought_id is required")
+	}
+
+	return t.expander.CompleteThought(sessionID, thoughtID, getString(params, "partial_content"), getBool(params, "apply", false))
+}
+
+func (t *CompleteThoughtTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id":      "string",
+		"thought_id":      "string",
+		"partial_content": "string",
+		"apply":           "boolean",
+	}
+}
+
+// AssessSessionReadinessTool方法
+func (t *AssessSessionReadinessTool) Name() string {
+	return "assess_session_readiness"
+}
+
+func (t *AssessSessionReadinessTool) Description() string {
+	return "Score whether a session has been explored enough to summarize, with per-criterion results and a recommendation"
+}
+
+func (t *AssessSessionReadinessTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+
+	return t.manager.AssessReadiness(sessionID)
+}
+
+func (t *AssessSessionReadinessTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+	}
+}
+
+// GetBreadcrumbTool方法
+func (t *GetBreadcrumbTool) Name() string {
+	return "get_breadcrumb"
+}
+
+func (t *GetBreadcrumbTool) Description() string {
+	return "Return the navigation trail from the session root to a thought"
+}
+
+func (t *GetBreadcrumbTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+
+	thoughtID := strings.TrimSpace(getString(params, "thought_id"))
+	if thoughtID == "" {
+		return nil, utils.ValidationError("thought_id is required")
+	}
+
+	session, err := t.manager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return session.GetBreadcrumb(thoughtID)
+}
+
+func (t *GetBreadcrumbTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+		"thought_id": "string",
+	}
+}
+
+// DirectionToSessionTool方法
+func (t *DirectionToSessionTool) Name() string {
+	return "direction_to_session"
+}
+
+func (t *DirectionToSessionTool) Description() string {
+	return "Start a new session from a thought in another session, using its content as the concept and its keywords as context"
+}
+
+func (t *DirectionToSessionTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.expander == nil {
+		return nil, errors.New("thought expander not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+	thoughtID := strings.TrimSpace(getString(params, "thought_id"))
+	if thoughtID == "" {
+		return nil, utils.ValidationError("thought_id is required")
+	}
+	userID := strings.TrimSpace(getString(params, "user_id"))
+	if userID != "" {
+		if err := utils.ValidateUserID(userID); err != nil {
+			return nil, err
+		}
+	}
+
+	return t.expander.DirectionToSession(sessionID, thoughtID, userID)
+}
+
+func (t *DirectionToSessionTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+		"thought_id": "string",
+		"user_id":    "string",
+	}
 }
 
-func NewListSessionsTool(manager *services.SessionManager) MCPTool {
-	return &ListSessionsTool{manager: manager}
+// GenerateTitleTool方法
+func (t *GenerateTitleTool) Name() string {
+	return "generate_title"
 }
 
-func NewDeleteSessionTool(manager *services.SessionManager) MCPTool {
-	return &DeleteSessionTool{manager: manager}
+func (t *GenerateTitleTool) Description() string {
+	return "Suggest a concise, engaging title for a session; set apply to rename the session"
 }
 
-func NewUpdateThoughtTool(manager *services.SessionManager) MCPTool {
-	return &UpdateThoughtTool{manager: manager}
+func (t *GenerateTitleTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.expander == nil {
+		return nil, errors.New("thought expander not available")
+	}
+
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+
+	return t.expander.GenerateSessionTitle(sessionID, getBool(params, "apply", false))
 }
 
-func NewDeleteThoughtTool(manager *services.SessionManager) MCPTool {
-	return &DeleteThoughtTool{manager: manager}
+func (t *GenerateTitleTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
+		"apply":      "boolean",
+	}
 }
 
-// ExpandThoughtTool方法
-func (t *ExpandThoughtTool) Name() string {
-	return "expand_thought"
+// GetTokenBudgetTool方法
+func (t *GetTokenBudgetTool) Name() string {
+	return "get_token_budget"
 }
 
-func (t *ExpandThoughtTool) Description() string {
-	return "Generate multiple directions of thought for a given concept"
+func (t *GetTokenBudgetTool) Description() string {
+	return "Show a user's LLM token budget, usage, and next reset time"
 }
 
-func (t *ExpandThoughtTool) Execute(params map[string]interface{}) (interface{}, error) {
-	if t.expander == nil {
-		return nil, errors.New("thought expander not available")
+func (t *GetTokenBudgetTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.llm == nil {
+		return nil, errors.New("llm orchestrator not available")
 	}
 
-	concept := strings.TrimSpace(getString(params, "concept"))
-	if err := utils.ValidateConcept(concept); err != nil {
+	userID := strings.TrimSpace(getString(params, "user_id"))
+	if err := utils.ValidateUserID(userID); err != nil {
 		return nil, err
 	}
 
-	contextSlice := getStringSlice(params, "context")
-	normalizedContext, err := utils.NormalizeContext(contextSlice)
+	budget, err := t.llm.GetBudget(userID)
 	if err != nil {
 		return nil, err
 	}
+	return map[string]interface{}{
+		"budget":    budget,
+		"remaining": budget.Remaining(),
+		"unlimited": budget.Unlimited(),
+	}, nil
+}
 
-	expansionTypeRaw := strings.TrimSpace(getString(params, "expansion_type"))
-	var expansionType models.DirectionType
-	if expansionTypeRaw != "" {
-		parsed, err := utils.ParseDirectionType(expansionTypeRaw)
-		if err != nil {
-			return nil, err
-		}
-		expansionType = parsed
+func (t *GetTokenBudgetTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"user_id": "string",
 	}
+}
 
-	maxDirections := getInt(params, "max_directions", 4)
-	if maxDirections <= 0 {
-		maxDirections = 4
-	}
-	if maxDirections > maxGeneratedDirections {
-		return nil, utils.ValidationError("max_directions is too large")
+// EstimateTokensTool方法
+func (t *EstimateTokensTool) Name() string {
+	return "estimate_tokens"
+}
+
+func (t *EstimateTokensTool) Description() string {
+	return "Estimate the token count of a prompt and whether it fits the input budget left after the completion tokens"
+}
+
+func (t *EstimateTokensTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.llm == nil {
+		return nil, errors.New("llm orchestrator not available")
 	}
 
-	result, err := t.expander.Expand(&services.ExpansionRequest{
-		Concept:       concept,
-		Context:       normalizedContext,
-		ExpansionType: expansionType,
-		MaxDirections: maxDirections,
-	})
-	if err != nil {
-		return nil, err
+	text := getString(params, "text")
+	if strings.TrimSpace(text) == "" {
+		return nil, utils.ValidationError("text is required")
 	}
-	return result, nil
+	maxTokens := getInt(params, "max_tokens", 0)
+	if maxTokens < 0 {
+		return nil, utils.ValidationError("max_tokens must not be negative")
+	}
+
+	tokens := t.llm.EstimateTokens(text)
+	budget := t.llm.PromptTokenBudget(maxTokens)
+	return map[string]interface{}{
+		"tokens":         tokens,
+		"input_budget":   budget,
+		"exceeds_budget": budget > 0 && tokens > budget,
+	}, nil
 }
 
-func (t *ExpandThoughtTool) Schema() map[string]interface{} {
+func (t *EstimateTokensTool) Schema() map[string]interface{} {
 	return map[string]interface{}{
-		"concept":        "string",
-		"context":        "array[string]",
-		"expansion_type": "enum[broad,deep,lateral,critical]",
-		"max_directions": "number",
+		"text":       "string",
+		"max_tokens": "number",
 	}
 }
 
-// ExploreDirectionTool方法
-func (t *ExploreDirectionTool) Name() string {
-	return "explore_direction"
+// ExportSessionTool方法
+func (t *ExportSessionTool) Name() string {
+	return "export_session"
 }
 
-func (t *ExploreDirectionTool) Description() string {
-	return "Deeply explore a selected direction within an existing session"
+func (t *ExportSessionTool) Description() string {
+	return "Export a session as JSON, CSV, Mermaid, Graphviz DOT, OPML, Markdown or JSON Canvas"
 }
 
-func (t *ExploreDirectionTool) Execute(params map[string]interface{}) (interface{}, error) {
-	if t.expander == nil {
-		return nil, errors.New("thought expander not available")
+func (t *ExportSessionTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
 	}
 
 	sessionID := strings.TrimSpace(getString(params, "session_id"))
@@ -173,83 +2233,118 @@ func (t *ExploreDirectionTool) Execute(params map[string]interface{}) (interface
 		return nil, err
 	}
 
-	directionMap, ok := params["direction"].(map[string]interface{})
-	if !ok {
-		return nil, utils.ValidationError("direction payload is required")
+	format, err := models.ParseExportFormat(getString(params, "format"))
+	if err != nil {
+		return nil, err
 	}
 
-	direction, err := buildDirection(directionMap)
+	opts := models.DefaultExportOptions()
+	if format == models.ExportMarkdown {
+		style, ok := models.ParseMarkdownStyle(getString(params, "style"))
+		if !ok {
+			return nil, utils.ValidationError("style must be headings or bullets")
+		}
+		opts.Markdown.Style = style
+		opts.Markdown.MaxDepth = getInt(params, "max_depth", 0)
+		opts.Markdown.IncludeDescriptions = getBool(params, "include_descriptions", true)
+		opts.Markdown.IncludeKeywords = getBool(params, "include_keywords", true)
+		opts.Markdown.IncludeMetadata = getBool(params, "include_metadata", true)
+	}
+
+	session, err := t.manager.GetSession(sessionID)
 	if err != nil {
 		return nil, err
 	}
 
-	thought, err := t.expander.ExploreDirection(*direction, sessionID)
+	doc, err := session.Export(format, opts)
 	if err != nil {
 		return nil, err
 	}
-	return thought, nil
+
+	return map[string]string{
+		"session_id": sessionID,
+		"format":     string(doc.Format),
+		"filename":   doc.Filename,
+		"mime_type":  doc.ContentType,
+		"content":    string(doc.Data),
+	}, nil
 }
 
-func (t *ExploreDirectionTool) Schema() map[string]interface{} {
+func (t *ExportSessionTool) Schema() map[string]interface{} {
 	return map[string]interface{}{
-		"session_id": "string",
-		"direction": map[string]interface{}{
-			"type":        "string",
-			"title":       "string",
-			"description": "string",
-			"keywords":    "array[string]",
-			"relevance":   "number",
-		},
+		"session_id":           "string",
+		"format":               "enum[json,csv,mermaid,dot,opml,markdown,canvas]",
+		"style":                "enum[headings,bullets]",
+		"max_depth":            "number",
+		"include_descriptions": "boolean",
+		"include_keywords":     "boolean",
+		"include_metadata":     "boolean",
 	}
 }
 
-// CreateSessionTool方法
-func (t *CreateSessionTool) Name() string {
-	return "create_session"
+// ImportSessionTool方法
+func (t *ImportSessionTool) Name() string {
+	return "import_session"
 }
 
-func (t *CreateSessionTool) Description() string {
-	return "Create a new thought session for a user"
+func (t *ImportSessionTool) Description() string {
+	return "Create a session from an OPML outline"
 }
 
-func (t *CreateSessionTool) Execute(params map[string]interface{}) (interface{}, error) {
+func (t *ImportSessionTool) Execute(params map[string]interface{}) (interface{}, error) {
 	if t.manager == nil {
 		return nil, errors.New("session manager not available")
 	}
 
 	userID := strings.TrimSpace(getString(params, "user_id"))
-	concept := strings.TrimSpace(getString(params, "concept"))
 	if err := utils.ValidateUserID(userID); err != nil {
 		return nil, err
 	}
-	if err := utils.ValidateConcept(concept); err != nil {
+
+	formatRaw := getString(params, "format")
+	if strings.TrimSpace(formatRaw) == "" {
+		formatRaw = string(models.ExportOPML)
+	}
+	format, err := models.ParseExportFormat(formatRaw)
+	if err != nil {
 		return nil, err
 	}
 
-	session, err := t.manager.CreateSession(userID, concept)
+	content := getString(params, "content")
+	if strings.TrimSpace(content) == "" {
+		return nil, utils.ValidationError("content is required")
+	}
+
+	imported, err := models.ImportSession(format, []byte(content))
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := t.manager.ImportSession(userID, imported)
 	if err != nil {
 		return nil, err
 	}
 	return session, nil
 }
 
-func (t *CreateSessionTool) Schema() map[string]interface{} {
+func (t *ImportSessionTool) Schema() map[string]interface{} {
 	return map[string]interface{}{
 		"user_id": "string",
-		"concept": "string",
+		"format":  "enum[opml]",
+		"content": "string",
 	}
 }
 
-// GetSessionTool方法
-func (t *GetSessionTool) Name() string {
-	return "get_session"
+// UndoActionTool方法
+func (t *UndoActionTool) Name() string {
+	return "undo_action"
 }
 
-func (t *GetSessionTool) Description() string {
-	return "Retrieve an existing session by ID"
+func (t *UndoActionTool) Description() string {
+	return "Revert the most recent change made to a session"
 }
 
-func (t *GetSessionTool) Execute(params map[string]interface{}) (interface{}, error) {
+func (t *UndoActionTool) Execute(params map[string]interface{}) (interface{}, error) {
 	if t.manager == nil {
 		return nil, errors.New("session manager not available")
 	}
@@ -259,58 +2354,61 @@ func (t *GetSessionTool) Execute(params map[string]interface{}) (interface{}, er
 		return nil, err
 	}
 
-	session, err := t.manager.GetSession(sessionID)
+	session, err := t.manager.Undo(sessionID)
 	if err != nil {
 		return nil, err
 	}
 	return session, nil
 }
 
-func (t *GetSessionTool) Schema() map[string]interface{} {
+func (t *UndoActionTool) Schema() map[string]interface{} {
 	return map[string]interface{}{
 		"session_id": "string",
 	}
 }
 
-func (t *ListSessionsTool) Name() string {
-	return "list_sessions"
+// RedoActionTool方法
+func (t *RedoActionTool) Name() string {
+	return "redo_action"
 }
 
-func (t *ListSessionsTool) Description() string {
-	return "List sessions for a user"
+func (t *RedoActionTool) Description() string {
+	return "Re-apply the most recently undone change on a session"
 }
 
-func (t *ListSessionsTool) Execute(params map[string]interface{}) (interface{}, error) {
+func (t *RedoActionTool) Execute(params map[string]interface{}) (interface{}, error) {
 	if t.manager == nil {
 		return nil, errors.New("session manager not available")
 	}
 
-	userID := strings.TrimSpace(getString(params, "user_id"))
-	if userID == "" {
-		return nil, utils.ValidationError("user_id is required")
-	}
-	if err := utils.ValidateUserID(userID); err != nil {
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
 		return nil, err
 	}
 
-	return t.manager.ListSessions(userID)
+	session, err := t.manager.Redo(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
 }
 
-func (t *ListSessionsTool) Schema() map[string]interface{} {
+func (t *RedoActionTool) Schema() map[string]interface{} {
 	return map[string]interface{}{
-		"user_id": "string",
+		"session_id": "string",
 	}
 }
 
-func (t *DeleteSessionTool) Name() string {
-	return "delete_session"
+// CreateSnapshotTool方法
+func (t *CreateSnapshotTool) Name() string {
+	return "create_snapshot"
 }
 
-func (t *DeleteSessionTool) Description() string {
-	return "Delete a session by ID"
+func (t *CreateSnapshotTool) Description() string {
+	return "Save the current state of a session as a named snapshot"
 }
 
-func (t *DeleteSessionTool) Execute(params map[string]interface{}) (interface{}, error) {
+func (t *CreateSnapshotTool) Execute(params map[string]interface{}) (interface{}, error) {
 	if t.manager == nil {
 		return nil, errors.New("session manager not available")
 	}
@@ -320,28 +2418,30 @@ func (t *DeleteSessionTool) Execute(params map[string]interface{}) (interface{},
 		return nil, err
 	}
 
-	if err := t.manager.DeleteSession(sessionID); err != nil {
+	snapshot, err := t.manager.CreateSnapshot(sessionID, strings.TrimSpace(getString(params, "name")))
+	if err != nil {
 		return nil, err
 	}
-
-	return map[string]string{"status": "deleted", "session_id": sessionID}, nil
+	return snapshot, nil
 }
 
-func (t *DeleteSessionTool) Schema() map[string]interface{} {
+func (t *CreateSnapshotTool) Schema() map[string]interface{} {
 	return map[string]interface{}{
 		"session_id": "string",
+		"name":       "string",
 	}
 }
 
-func (t *UpdateThoughtTool) Name() string {
-	return "update_thought"
+// ListSnapshotsTool方法
+func (t *ListSnapshotsTool) Name() string {
+	return "list_snapshots"
 }
 
-func (t *UpdateThoughtTool) Description() string {
-	return "Update the content or direction metadata of a thought node"
+func (t *ListSnapshotsTool) Description() string {
+	return "List the named snapshots saved for a session"
 }
 
-func (t *UpdateThoughtTool) Execute(params map[string]interface{}) (interface{}, error) {
+func (t *ListSnapshotsTool) Execute(params map[string]interface{}) (interface{}, error) {
 	if t.manager == nil {
 		return nil, errors.New("session manager not available")
 	}
@@ -351,59 +2451,62 @@ func (t *UpdateThoughtTool) Execute(params map[string]interface{}) (interface{},
 		return nil, err
 	}
 
-	thoughtID := strings.TrimSpace(getString(params, "thought_id"))
-	if thoughtID == "" {
-		return nil, utils.ValidationError("thought_id is required")
+	snapshots, err := t.manager.ListSnapshots(sessionID)
+	if err != nil {
+		return nil, err
 	}
+	return snapshots, nil
+}
 
-	update := &models.ThoughtUpdate{}
-	if _, ok := params["content"]; ok {
-		content := getString(params, "content")
-		update.Content = &content
+func (t *ListSnapshotsTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": "string",
 	}
-	if rawDir, ok := params["direction"]; ok {
-		dirMap, valid := rawDir.(map[string]interface{})
-		if !valid {
-			return nil, utils.ValidationError("direction must be an object")
-		}
-		direction, err := buildDirection(dirMap)
-		if err != nil {
-			return nil, err
-		}
-		update.Direction = direction
+}
+
+// RestoreSnapshotTool方法
+func (t *RestoreSnapshotTool) Name() string {
+	return "restore_snapshot"
+}
+
+func (t *RestoreSnapshotTool) Description() string {
+	return "Restore a session to a named snapshot; the previous state can be recovered with undo_action"
+}
+
+func (t *RestoreSnapshotTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.manager == nil {
+		return nil, errors.New("session manager not available")
 	}
 
-	if err := utils.ValidateThoughtUpdate(update); err != nil {
+	sessionID := strings.TrimSpace(getString(params, "session_id"))
+	if err := utils.ValidateSessionID(sessionID); err != nil {
 		return nil, err
 	}
 
-	return t.manager.UpdateThought(sessionID, thoughtID, update)
+	session, err := t.manager.RestoreSnapshot(sessionID, strings.TrimSpace(getString(params, "name")))
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
 }
 
-func (t *UpdateThoughtTool) Schema() map[string]interface{} {
+func (t *RestoreSnapshotTool) Schema() map[string]interface{} {
 	return map[string]interface{}{
 		"session_id": "string",
-		"thought_id": "string",
-		"content":    "string",
-		"direction": map[string]interface{}{
-			"type":        "string",
-			"title":       "string",
-			"description": "string",
-			"keywords":    "array[string]",
-			"relevance":   "number",
-		},
+		"name":       "string",
 	}
 }
 
-func (t *DeleteThoughtTool) Name() string {
-	return "delete_thought"
+// DeleteSnapshotTool方法
+func (t *DeleteSnapshotTool) Name() string {
+	return "delete_snapshot"
 }
 
-func (t *DeleteThoughtTool) Description() string {
-	return "Delete a thought node from a session"
+func (t *DeleteSnapshotTool) Description() string {
+	return "Delete a named snapshot from a session"
 }
 
-func (t *DeleteThoughtTool) Execute(params map[string]interface{}) (interface{}, error) {
+func (t *DeleteSnapshotTool) Execute(params map[string]interface{}) (interface{}, error) {
 	if t.manager == nil {
 		return nil, errors.New("session manager not available")
 	}
@@ -413,18 +2516,17 @@ func (t *DeleteThoughtTool) Execute(params map[string]interface{}) (interface{},
 		return nil, err
 	}
 
-	thoughtID := strings.TrimSpace(getString(params, "thought_id"))
-	if thoughtID == "" {
-		return nil, utils.ValidationError("thought_id is required")
+	name := strings.TrimSpace(getString(params, "name"))
+	if err := t.manager.DeleteSnapshot(sessionID, name); err != nil {
+		return nil, err
 	}
-
-	return t.manager.DeleteThought(sessionID, thoughtID)
+	return map[string]string{"status": "deleted", "name": name}, nil
 }
 
-func (t *DeleteThoughtTool) Schema() map[string]interface{} {
+func (t *DeleteSnapshotTool) Schema() map[string]interface{} {
 	return map[string]interface{}{
 		"session_id": "string",
-		"thought_id": "string",
+		"name":       "string",
 	}
 }
 
@@ -466,6 +2568,49 @@ func getStringSlice(params map[string]interface{}, key string) []string {
 	return result
 }
 
+func getContextEntries(params map[string]interface{}, key string) ([]models.ContextEntry, error) {
+	if params == nil {
+		return nil, nil
+	}
+	value, ok := params[key]
+	if !ok || value == nil {
+		return nil, nil
+	}
+
+	var items []interface{}
+	switch v := value.(type) {
+	case []string:
+		return models.ParseContextEntries(v), nil
+	case []models.ContextEntry:
+		return append([]models.ContextEntry(nil), v...), nil
+	case []interface{}:
+		items = v
+	default:
+		return nil, utils.ValidationError("context must be an array")
+	}
+
+	result := make([]models.ContextEntry, 0, len(items))
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			result = append(result, models.ParseContextEntry(v))
+		case map[string]interface{}:
+			kind := models.ContextNote
+			if rawKind := strings.TrimSpace(getString(v, "kind")); rawKind != "" {
+				parsed, ok := models.ParseContextKind(rawKind)
+				if !ok {
+					return nil, utils.ValidationError("context entry has an unknown kind")
+				}
+				kind = parsed
+			}
+			result = append(result, models.NewContextEntry(kind, getString(v, "value")))
+		default:
+			return nil, utils.ValidationError("context entries must be strings or {kind, value} objects")
+		}
+	}
+	return result, nil
+}
+
 func getInt(params map[string]interface{}, key string, fallback int) int {
 	if params == nil {
 		return fallback
@@ -485,6 +2630,25 @@ func getInt(params map[string]interface{}, key string, fallback int) int {
 	return fallback
 }
 
+func getBool(params map[string]interface{}, key string, fallback bool) bool {
+	if params == nil {
+		return fallback
+	}
+	value, ok := params[key]
+	if !ok {
+		return fallback
+	}
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		if parsed, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
 func getFloat(params map[string]interface{}, key string, fallback float64) float64 {
 	if params == nil {
 		return fallback
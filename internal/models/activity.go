@@ -0,0 +1,66 @@
+//Session Activity Log(会话活动日志)
+
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 枚举类型
+type ActivityAction string
+
+const (
+	ActivityThoughtAdded      ActivityAction = "thought_added"
+	ActivityThoughtDeleted    ActivityAction = "thought_deleted"
+	ActivityDirectionExplored ActivityAction = "direction_explored"
+	ActivityContextUpdated    ActivityAction = "context_updated"
+)
+
+// 常量
+const (
+	MaxActivityEntries     = 100
+	maxActivityDetailRunes = 200
+)
+
+// 结构体
+type ActivityEntry struct {
+	Timestamp time.Time      `json:"timestamp"`
+	Action    ActivityAction `json:"action"`
+	Detail    string         `json:"detail,omitempty"`
+}
+
+// 方法
+// RecordActivity 追加一条活动记录，超过 MaxActivityEntries 时丢弃最旧的记录。
+func (s *Session) RecordActivity(action ActivityAction, detail string) {
+	if s == nil || action == "" {
+		return
+	}
+
+	detail = strings.Join(strings.Fields(detail), " ")
+	if utf8.RuneCountInString(detail) > maxActivityDetailRunes {
+		detail = string([]rune(detail)[:maxActivityDetailRunes-1]) + "…"
+	}
+	s.ActivityLog = append(s.ActivityLog, ActivityEntry{
+		Timestamp: time.Now().UTC(),
+		Action:    action,
+		Detail:    detail,
+	})
+	if len(s.ActivityLog) > MaxActivityEntries {
+		s.ActivityLog = append([]ActivityEntry(nil), s.ActivityLog[len(s.ActivityLog)-MaxActivityEntries:]...)
+	}
+}
+
+// RecentActivity 返回按时间从新到旧排列的活动记录副本。
+func (s *Session) RecentActivity() []ActivityEntry {
+	if s == nil {
+		return []ActivityEntry{}
+	}
+
+	entries := make([]ActivityEntry, 0, len(s.ActivityLog))
+	for i := len(s.ActivityLog) - 1; i >= 0; i-- {
+		entries = append(entries, s.ActivityLog[i])
+	}
+	return entries
+}
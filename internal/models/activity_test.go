@@ -0,0 +1,33 @@
+package models_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"WideMindsMCP/internal/models"
+)
+
+func TestSessionRecordActivityDropsOldest(t *testing.T) {
+	session := models.NewSession("user", "root")
+	for i := 0; i < models.MaxActivityEntries+5; i++ {
+		session.RecordActivity(models.ActivityThoughtAdded, fmt.Sprintf("thought %d", i))
+	}
+
+	if len(session.ActivityLog) != models.MaxActivityEntries {
+		t.Fatalf("expected log capped at %d, got %d", models.MaxActivityEntries, len(session.ActivityLog))
+	}
+	if session.ActivityLog[0].Detail != "thought 5" {
+		t.Fatalf("expected oldest entries to be dropped, first is %q", session.ActivityLog[0].Detail)
+	}
+
+	recent := session.RecentActivity()
+	if recent[0].Detail != fmt.Sprintf("thought %d", models.MaxActivityEntries+4) || recent[len(recent)-1].Detail != "thought 5" {
+		t.Fatalf("expected newest-first order, got %q ... %q", recent[0].Detail, recent[len(recent)-1].Detail)
+	}
+
+	session.RecordActivity(models.ActivityContextUpdated, strings.Repeat("x", 500))
+	if detail := session.ActivityLog[len(session.ActivityLog)-1].Detail; len([]rune(detail)) >= 500 || !strings.HasSuffix(detail, "…") {
+		t.Fatalf("expected long detail to be truncated, got %d runes", len([]rune(detail)))
+	}
+}
@@ -0,0 +1,59 @@
+//Breadcrumb Navigation(面包屑导航)
+
+package models
+
+import (
+	"fmt"
+
+	appErrors "WideMindsMCP/internal/errors"
+)
+
+// 结构体
+// BreadcrumbItem 是面包屑中的一级，Label 为截断后的思维内容。
+type BreadcrumbItem struct {
+	ID            string        `json:"id"`
+	Label         string        `json:"label"`
+	DirectionType DirectionType `json:"direction_type"`
+}
+
+// 方法
+// GetAncestors 按从根到父节点的顺序返回思维的祖先；根节点没有祖先。
+func (s *Session) GetAncestors(thoughtID string) ([]*Thought, error) {
+	tree := s.GetThoughtTree()
+	thought, ok := tree[thoughtID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", appErrors.ErrThoughtNotFound, thoughtID)
+	}
+
+	var ancestors []*Thought
+	for thought.ParentID != nil && len(ancestors) < len(tree) {
+		parent, ok := tree[*thought.ParentID]
+		if !ok {
+			break
+		}
+		ancestors = append(ancestors, parent)
+		thought = parent
+	}
+	for i, j := 0, len(ancestors)-1; i < j; i, j = i+1, j-1 {
+		ancestors[i], ancestors[j] = ancestors[j], ancestors[i]
+	}
+	return ancestors, nil
+}
+
+// GetBreadcrumb 返回从根到指定思维（含）的导航路径。
+func (s *Session) GetBreadcrumb(thoughtID string) ([]BreadcrumbItem, error) {
+	ancestors, err := s.GetAncestors(thoughtID)
+	if err != nil {
+		return nil, err
+	}
+	trail := append(ancestors, s.GetThoughtTree()[thoughtID])
+	items := make([]BreadcrumbItem, 0, len(trail))
+	for _, thought := range trail {
+		items = append(items, BreadcrumbItem{
+			ID:            thought.ID,
+			Label:         diagramLabel(thought.Content),
+			DirectionType: thought.Direction.Type,
+		})
+	}
+	return items, nil
+}
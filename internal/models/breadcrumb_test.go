@@ -0,0 +1,54 @@
+package models_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+)
+
+func TestGetBreadcrumbFollowsDeepTree(t *testing.T) {
+	session := models.NewSession("user", "Energy storage")
+	parent := session.RootThought
+	var chain []*models.Thought
+	for i := 1; i <= 6; i++ {
+		child := models.NewThought(fmt.Sprintf("Level %d", i), session.ID, models.Direction{Type: models.Deep, Title: "dive"})
+		parent.AddChild(child)
+		// 兄弟节点不应出现在路径中
+		parent.AddChild(models.NewThought(fmt.Sprintf("Sibling %d", i), session.ID, models.Direction{Type: models.Lateral, Title: "aside"}))
+		chain = append(chain, child)
+		parent = child
+	}
+
+	items, err := session.GetBreadcrumb(chain[5].ID)
+	if err != nil {
+		t.Fatalf("GetBreadcrumb failed: %v", err)
+	}
+	if len(items) != 7 || items[0].ID != session.RootThought.ID {
+		t.Fatalf("expected root plus six levels, got %+v", items)
+	}
+	for i, thought := range chain {
+		if item := items[i+1]; item.ID != thought.ID || item.Label != thought.Content || item.DirectionType != models.Deep {
+			t.Fatalf("level %d: unexpected item %+v", i+1, item)
+		}
+	}
+
+	ancestors, err := session.GetAncestors(chain[2].ID)
+	if err != nil || len(ancestors) != 3 || ancestors[2].ID != chain[1].ID {
+		t.Fatalf("unexpected ancestors %v (%v)", ancestors, err)
+	}
+}
+
+func TestGetBreadcrumbForRootAndMissingThought(t *testing.T) {
+	session := models.NewSession("user", "Energy storage")
+
+	items, err := session.GetBreadcrumb(session.RootThought.ID)
+	if err != nil || len(items) != 1 || items[0].ID != session.RootThought.ID || items[0].Label != "Energy storage" {
+		t.Fatalf("expected the root as the sole item, got %+v (%v)", items, err)
+	}
+	if _, err := session.GetBreadcrumb("missing"); !errors.Is(err, appErrors.ErrThoughtNotFound) {
+		t.Fatalf("expected ErrThoughtNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+//LLM Token Budget(LLM令牌预算)
+
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// 常量
+const (
+	BudgetPeriodDaily   = "daily"
+	BudgetPeriodWeekly  = "weekly"
+	BudgetPeriodMonthly = "monthly"
+)
+
+// 结构体
+type LLMBudget struct {
+	UserID           string    `json:"userId"`
+	TotalTokensLimit int       `json:"totalTokensLimit"`
+	UsedTokens       int       `json:"usedTokens"`
+	Period           string    `json:"period"`
+	ResetsAt         time.Time `json:"resetsAt"`
+}
+
+// 函数
+func NewLLMBudget(userID string, limit int, period string, now time.Time) *LLMBudget {
+	period = NormalizeBudgetPeriod(period)
+	return &LLMBudget{
+		UserID:           userID,
+		TotalTokensLimit: limit,
+		Period:           period,
+		ResetsAt:         NextBudgetReset(period, now),
+	}
+}
+
+// NormalizeBudgetPeriod 返回受支持的周期，未知值按 monthly 处理。
+func NormalizeBudgetPeriod(period string) string {
+	switch strings.ToLower(strings.TrimSpace(period)) {
+	case BudgetPeriodDaily:
+		return BudgetPeriodDaily
+	case BudgetPeriodWeekly:
+		return BudgetPeriodWeekly
+	default:
+		return BudgetPeriodMonthly
+	}
+}
+
+// NextBudgetReset 计算 from 之后的下一个周期起点（UTC）。
+func NextBudgetReset(period string, from time.Time) time.Time {
+	from = from.UTC()
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	switch NormalizeBudgetPeriod(period) {
+	case BudgetPeriodDaily:
+		return day.AddDate(0, 0, 1)
+	case BudgetPeriodWeekly:
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, 7-offset)
+	default:
+		return time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+	}
+}
+
+// 方法
+// Unlimited 表示未设置上限。
+func (b *LLMBudget) Unlimited() bool {
+	return b == nil || b.TotalTokensLimit <= 0
+}
+
+func (b *LLMBudget) Remaining() int {
+	if b.Unlimited() {
+		return -1
+	}
+	if remaining := b.TotalTokensLimit - b.UsedTokens; remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// ResetIfDue 在周期结束后清零用量并推进重置时间。
+func (b *LLMBudget) ResetIfDue(now time.Time) bool {
+	if b == nil || b.ResetsAt.IsZero() || now.Before(b.ResetsAt) {
+		return false
+	}
+	b.UsedTokens = 0
+	b.ResetsAt = NextBudgetReset(b.Period, now)
+	return true
+}
@@ -0,0 +1,98 @@
+//JSON Canvas Export(JSON Canvas导出)
+
+package models
+
+import (
+	"encoding/json"
+)
+
+// 常量
+const (
+	canvasNodeWidth  = 260
+	canvasNodeHeight = 120
+	canvasGapX       = 100
+	canvasGapY       = 40
+)
+
+// Obsidian 预设颜色："1" 红、"3" 黄、"4" 绿、"5" 青
+var canvasDirectionColors = map[DirectionType]string{
+	Broad:    "5",
+	Deep:     "4",
+	Lateral:  "3",
+	Critical: "1",
+}
+
+// 结构体
+type CanvasDocument struct {
+	Nodes []CanvasNode `json:"nodes"`
+	Edges []CanvasEdge `json:"edges"`
+}
+
+type CanvasNode struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Color  string `json:"color,omitempty"`
+}
+
+type CanvasEdge struct {
+	ID       string `json:"id"`
+	FromNode string `json:"fromNode"`
+	FromSide string `json:"fromSide"`
+	ToNode   string `json:"toNode"`
+	ToSide   string `json:"toSide"`
+}
+
+// 方法
+// BuildJSONCanvas 使用分层布局生成画布：x 由深度决定，y 由同层序号决定。
+// 思维模型目前没有交叉链接，因此只生成父子边。
+func (s *Session) BuildJSONCanvas() *CanvasDocument {
+	doc := &CanvasDocument{Nodes: []CanvasNode{}, Edges: []CanvasEdge{}}
+	rows := map[int]int{}
+
+	var walk func(thought, parent *Thought, depth int)
+	walk = func(thought, parent *Thought, depth int) {
+		id := diagramNodeID(thought.ID)
+		doc.Nodes = append(doc.Nodes, CanvasNode{
+			ID:     id,
+			Type:   "text",
+			Text:   thought.Content,
+			X:      depth * (canvasNodeWidth + canvasGapX),
+			Y:      rows[depth] * (canvasNodeHeight + canvasGapY),
+			Width:  canvasNodeWidth,
+			Height: canvasNodeHeight,
+			Color:  canvasDirectionColors[thought.Direction.Type],
+		})
+		rows[depth]++
+
+		if parent != nil {
+			parentID := diagramNodeID(parent.ID)
+			doc.Edges = append(doc.Edges, CanvasEdge{
+				ID:       "e_" + parentID + "_" + id,
+				FromNode: parentID,
+				FromSide: "right",
+				ToNode:   id,
+				ToSide:   "left",
+			})
+		}
+
+		for _, child := range thought.Children {
+			if child != nil {
+				walk(child, thought, depth+1)
+			}
+		}
+	}
+	if s != nil && s.RootThought != nil {
+		walk(s.RootThought, nil, 0)
+	}
+	return doc
+}
+
+// ToJSONCanvas 导出 Obsidian .canvas 文档。
+func (s *Session) ToJSONCanvas() ([]byte, error) {
+	return json.MarshalIndent(s.BuildJSONCanvas(), "", "  ")
+}
@@ -0,0 +1,76 @@
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"WideMindsMCP/internal/models"
+)
+
+func TestSessionToJSONCanvasReferencesExistingNodes(t *testing.T) {
+	session := buildDiagramFixture()
+
+	data, err := session.ToJSONCanvas()
+	if err != nil {
+		t.Fatalf("ToJSONCanvas failed: %v", err)
+	}
+
+	var doc struct {
+		Nodes []map[string]interface{} `json:"nodes"`
+		Edges []map[string]interface{} `json:"edges"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("canvas is not valid JSON: %v", err)
+	}
+
+	total := session.GetMetadata().TotalThoughts
+	if len(doc.Nodes) != total || len(doc.Edges) != total-1 {
+		t.Fatalf("expected %d nodes and %d edges, got %d and %d", total, total-1, len(doc.Nodes), len(doc.Edges))
+	}
+
+	nodes := map[string]bool{}
+	positions := map[[2]float64]bool{}
+	for _, node := range doc.Nodes {
+		id, _ := node["id"].(string)
+		if id == "" || nodes[id] {
+			t.Fatalf("node ids must be unique and non-empty, got %q", id)
+		}
+		nodes[id] = true
+		for _, key := range []string{"type", "text", "x", "y", "width", "height"} {
+			if _, ok := node[key]; !ok {
+				t.Fatalf("node %s is missing %s", id, key)
+			}
+		}
+		pos := [2]float64{node["x"].(float64), node["y"].(float64)}
+		if positions[pos] {
+			t.Fatalf("node %s overlaps another node at %v", id, pos)
+		}
+		positions[pos] = true
+	}
+
+	edges := map[string]bool{}
+	for _, edge := range doc.Edges {
+		id, _ := edge["id"].(string)
+		if id == "" || edges[id] || nodes[id] {
+			t.Fatalf("edge ids must be unique across nodes and edges, got %q", id)
+		}
+		edges[id] = true
+		from, _ := edge["fromNode"].(string)
+		to, _ := edge["toNode"].(string)
+		if !nodes[from] || !nodes[to] {
+			t.Fatalf("edge %s references missing node (%s -> %s)", id, from, to)
+		}
+	}
+
+	if doc.Nodes[0]["color"] != "5" {
+		t.Fatalf("expected broad root to use color 5, got %v", doc.Nodes[0]["color"])
+	}
+	if again, _ := session.ToJSONCanvas(); string(again) != string(data) {
+		t.Fatalf("expected canvas export to be stable across runs")
+	}
+
+	exported, err := session.Export(models.ExportCanvas, models.DefaultExportOptions())
+	if err != nil || exported.Filename != "session-fixture.canvas" {
+		t.Fatalf("expected canvas export document, got %+v (%v)", exported, err)
+	}
+}
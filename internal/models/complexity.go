@@ -0,0 +1,68 @@
+//Session Complexity(会话复杂度)
+
+package models
+
+// 常量
+const (
+	MaxComplexity = 1000
+
+	complexityThoughtWeight    = 1
+	complexityDepthWeight      = 3
+	complexityDirectionWeight  = 5
+	complexityLinkedWeight     = 2
+	complexityAnnotationWeight = 1
+
+	ComplexitySimple   = "simple"
+	ComplexityModerate = "moderate"
+	ComplexityComplex  = "complex"
+	ComplexityExpert   = "expert"
+)
+
+// 结构体
+// ComplexityBadge 是复杂度分数及其等级。
+type ComplexityBadge struct {
+	Complexity int    `json:"complexity"`
+	Label      string `json:"label"`
+}
+
+// SessionStats 是会话统计接口的响应：会话元数据与复杂度徽章。
+type SessionStats struct {
+	*SessionMetadata
+	Badge ComplexityBadge `json:"badge"`
+}
+
+// 方法
+// EstimateComplexity 按 思维数×1 + 最大深度×3 + 不同方向数×5 + 关联外部条目的思维数×2 + 注释数×1 计算复杂度，上限为 MaxComplexity。
+func (s *Session) EstimateComplexity() int {
+	return s.GetMetadata().Complexity
+}
+
+// Stats 返回会话元数据与复杂度徽章。
+func (s *Session) Stats() *SessionStats {
+	meta := s.GetMetadata()
+	return &SessionStats{SessionMetadata: meta, Badge: NewComplexityBadge(meta.Complexity)}
+}
+
+// 函数
+// NewComplexityBadge 按阈值划分等级：<20 simple，<60 moderate，<150 complex，其余 expert。
+func NewComplexityBadge(complexity int) ComplexityBadge {
+	label := ComplexityExpert
+	switch {
+	case complexity < 20:
+		label = ComplexitySimple
+	case complexity < 60:
+		label = ComplexityModerate
+	case complexity < 150:
+		label = ComplexityComplex
+	}
+	return ComplexityBadge{Complexity: complexity, Label: label}
+}
+
+func complexityScore(thoughts, maxDepth, directions, linked, annotations int) int {
+	score := thoughts*complexityThoughtWeight +
+		maxDepth*complexityDepthWeight +
+		directions*complexityDirectionWeight +
+		linked*complexityLinkedWeight +
+		annotations*complexityAnnotationWeight
+	return min(score, MaxComplexity)
+}
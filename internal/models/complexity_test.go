@@ -0,0 +1,54 @@
+package models_test
+
+import (
+	"fmt"
+	"testing"
+
+	"WideMindsMCP/internal/models"
+)
+
+func TestEstimateComplexityUsesWeightedFormula(t *testing.T) {
+	session := models.NewSession("user", "Energy storage")
+	chemistry := models.NewThought("Cell chemistry", session.ID, models.Direction{Type: models.Deep, Title: "Chemistry"})
+	chemistry.SetAnnotation("source", "paper")
+	chemistry.SetAnnotation("status", "draft")
+	session.RootThought.AddChild(chemistry)
+
+	recycling := models.NewThought("Recycling economics", session.ID, models.Direction{Type: models.Lateral, Title: "Recycling"})
+	recycling.ExternalID = "JIRA-42"
+	session.RootThought.AddChild(recycling)
+
+	safety := models.NewThought("Thermal runaway", session.ID, models.Direction{Type: models.Critical, Title: "Safety"})
+	safety.ExternalSystemURL = "https://notion.example.com/safety"
+	safety.SetAnnotation("reviewed", "yes")
+	chemistry.AddChild(safety)
+
+	// 4 thoughts*1 + depth 2*3 + 4 directions (Root, Chemistry, Recycling, Safety)*5 + 2 linked*2 + 3 annotations*1
+	const want = 4 + 6 + 20 + 4 + 3
+	if got := session.EstimateComplexity(); got != want {
+		t.Fatalf("expected complexity %d, got %d", want, got)
+	}
+
+	stats := session.Stats()
+	if stats.Complexity != want || stats.Badge != (models.ComplexityBadge{Complexity: want, Label: models.ComplexityModerate}) {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestEstimateComplexityIsCapped(t *testing.T) {
+	session := models.NewSession("user", "Everything")
+	for i := 0; i < 200; i++ {
+		session.RootThought.AddChild(models.NewThought("idea", session.ID, models.Direction{Type: models.Broad, Title: fmt.Sprintf("Direction %d", i)}))
+	}
+	if got := session.EstimateComplexity(); got != models.MaxComplexity {
+		t.Fatalf("expected complexity to be capped at %d, got %d", models.MaxComplexity, got)
+	}
+}
+
+func TestComplexityBadgeLabels(t *testing.T) {
+	for score, label := range map[int]string{0: "simple", 19: "simple", 20: "moderate", 59: "moderate", 60: "complex", 149: "complex", 150: "expert"} {
+		if got := models.NewComplexityBadge(score).Label; got != label {
+			t.Fatalf("score %d: expected %s, got %s", score, label, got)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+//Context Entry(上下文条目)
+
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// 枚举类型
+type ContextKind string
+
+const (
+	ContextBackground ContextKind = "background" // 背景信息
+	ContextHistory    ContextKind = "history"    // 历史路径
+	ContextPreference ContextKind = "preference" // 用户偏好
+	ContextGoal       ContextKind = "goal"       // 明确目标
+	ContextNote       ContextKind = "note"       // 其他备注
+)
+
+// 结构体
+type ContextEntry struct {
+	Kind  ContextKind `json:"kind"`
+	Value string      `json:"value"`
+}
+
+// 旧版 "前缀: 内容" 字符串的前缀映射
+var contextKindAliases = map[string]ContextKind{
+	"background":  ContextBackground,
+	"context":     ContextBackground,
+	"domain":      ContextBackground,
+	"history":     ContextHistory,
+	"path":        ContextHistory,
+	"trajectory":  ContextHistory,
+	"preference":  ContextPreference,
+	"preferences": ContextPreference,
+	"style":       ContextPreference,
+	"tone":        ContextPreference,
+	"goal":        ContextGoal,
+	"goals":       ContextGoal,
+	"objective":   ContextGoal,
+	"intent":      ContextGoal,
+	"note":        ContextNote,
+	"notes":       ContextNote,
+}
+
+// 方法
+func NewContextEntry(kind ContextKind, value string) ContextEntry {
+	return ContextEntry{Kind: kind, Value: strings.TrimSpace(value)}
+}
+
+// ParseContextKind 将字符串解析为上下文类型，支持旧版前缀别名。
+func ParseContextKind(value string) (ContextKind, bool) {
+	kind, ok := contextKindAliases[strings.ToLower(strings.TrimSpace(value))]
+	return kind, ok
+}
+
+// ParseContextEntry 解析旧版 "background: ..." 形式的上下文字符串。
+// 未识别的前缀会整体保留为 note。
+func ParseContextEntry(raw string) ContextEntry {
+	entry := strings.TrimSpace(raw)
+	if idx := strings.Index(entry, ":"); idx >= 0 {
+		if kind, ok := ParseContextKind(entry[:idx]); ok {
+			return NewContextEntry(kind, entry[idx+1:])
+		}
+		key := strings.ToLower(strings.TrimSpace(entry[:idx]))
+		value := strings.TrimSpace(entry[idx+1:])
+		if key != "" && value != "" {
+			return NewContextEntry(ContextNote, fmt.Sprintf("%s: %s", key, value))
+		}
+		if value != "" {
+			return NewContextEntry(ContextNote, value)
+		}
+	}
+	return NewContextEntry(ContextNote, entry)
+}
+
+// ParseContextEntries 批量解析旧版上下文字符串并忽略空值。
+func ParseContextEntries(items []string) []ContextEntry {
+	entries := make([]ContextEntry, 0, len(items))
+	for _, item := range items {
+		entry := ParseContextEntry(item)
+		if entry.Value == "" {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// ContextStrings 将结构化上下文转换回旧版字符串形式。
+func ContextStrings(entries []ContextEntry) []string {
+	result := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if text := entry.String(); text != "" {
+			result = append(result, text)
+		}
+	}
+	return result
+}
+
+func (e ContextEntry) String() string {
+	value := strings.TrimSpace(e.Value)
+	if value == "" {
+		return ""
+	}
+	if e.Kind == "" || e.Kind == ContextNote {
+		return value
+	}
+	return fmt.Sprintf("%s: %s", e.Kind, value)
+}
+
+// UnmarshalJSON 同时接受旧版字符串与 {kind, value} 对象。
+func (e *ContextEntry) UnmarshalJSON(data []byte) error {
+	var legacy string
+	if err := json.Unmarshal(data, &legacy); err == nil {
+		*e = ParseContextEntry(legacy)
+		return nil
+	}
+
+	var raw struct {
+		Kind  string `json:"kind"`
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("context entry must be a string or an object with kind and value: %w", err)
+	}
+
+	kind := ContextNote
+	if strings.TrimSpace(raw.Kind) != "" {
+		parsed, ok := ParseContextKind(raw.Kind)
+		if !ok {
+			return fmt.Errorf("unknown context kind %q", raw.Kind)
+		}
+		kind = parsed
+	}
+	*e = NewContextEntry(kind, raw.Value)
+	return nil
+}
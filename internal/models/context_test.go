@@ -0,0 +1,39 @@
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"WideMindsMCP/internal/models"
+)
+
+func TestContextEntriesUnmarshalMixedInput(t *testing.T) {
+	payload := []byte(`["background: robotics", {"kind": "goal", "value": "reduce cost"}, {"value": "plain note"}, "free text"]`)
+
+	var entries []models.ContextEntry
+	if err := json.Unmarshal(payload, &entries); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+
+	expected := []models.ContextEntry{
+		{Kind: models.ContextBackground, Value: "robotics"},
+		{Kind: models.ContextGoal, Value: "reduce cost"},
+		{Kind: models.ContextNote, Value: "plain note"},
+		{Kind: models.ContextNote, Value: "free text"},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %+v", len(expected), entries)
+	}
+	for i := range expected {
+		if entries[i] != expected[i] {
+			t.Fatalf("entry %d: expected %+v, got %+v", i, expected[i], entries[i])
+		}
+	}
+}
+
+func TestContextEntryUnmarshalRejectsUnknownKind(t *testing.T) {
+	var entry models.ContextEntry
+	if err := json.Unmarshal([]byte(`{"kind": "mood", "value": "happy"}`), &entry); err == nil {
+		t.Fatalf("expected error for unknown context kind")
+	}
+}
@@ -0,0 +1,123 @@
+//Diagram Export(图表导出)
+
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 常量
+const DiagramLabelMaxRunes = 60
+
+var dotDirectionColors = map[DirectionType]string{
+	Broad:    "#dbeafe",
+	Deep:     "#dcfce7",
+	Lateral:  "#fef9c3",
+	Critical: "#fee2e2",
+}
+
+const dotDefaultColor = "#f3f4f6"
+
+var mermaidEscaper = strings.NewReplacer(
+	"#", "#35;",
+	"\"", "#quot;",
+	"[", "#91;",
+	"]", "#93;",
+	"(", "#40;",
+	")", "#41;",
+	"{", "#123;",
+	"}", "#125;",
+	"<", "#lt;",
+	">", "#gt;",
+	"|", "#124;",
+)
+
+var dotEscaper = strings.NewReplacer(
+	"\\", "\\\\",
+	"\"", "\\\"",
+)
+
+// ToMermaid 将思维树导出为 Mermaid "graph TD" 图。节点ID由思维ID派生，保证重复导出结果稳定。
+func (s *Session) ToMermaid() string {
+	var b strings.Builder
+	b.WriteString("graph TD\n")
+
+	walkThoughtTree(s, func(thought, parent *Thought) {
+		id := diagramNodeID(thought.ID)
+		fmt.Fprintf(&b, "    %s[\"%s\"]\n", id, mermaidEscaper.Replace(diagramLabel(thought.Content)))
+		if parent != nil {
+			fmt.Fprintf(&b, "    %s --> %s\n", diagramNodeID(parent.ID), id)
+		}
+	})
+
+	return b.String()
+}
+
+// ToDOT 将思维树导出为 Graphviz DOT，节点颜色按方向类型区分；设置了外部链接的节点带 URL 属性。
+func (s *Session) ToDOT() string {
+	var b strings.Builder
+	name := "session"
+	if s != nil && s.ID != "" {
+		name = "session_" + s.ID
+	}
+	fmt.Fprintf(&b, "digraph \"%s\" {\n", dotEscaper.Replace(name))
+	b.WriteString("    rankdir=LR;\n")
+	b.WriteString("    node [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\"];\n")
+
+	walkThoughtTree(s, func(thought, parent *Thought) {
+		id := diagramNodeID(thought.ID)
+		color := DirectionTypeColor(thought.Direction.Type)
+		link := ""
+		if thought.ExternalSystemURL != "" {
+			link = fmt.Sprintf(", URL=\"%s\"", dotEscaper.Replace(thought.ExternalSystemURL))
+		}
+		fmt.Fprintf(&b, "    \"%s\" [label=\"%s\", fillcolor=\"%s\"%s];\n", id, dotEscaper.Replace(diagramLabel(thought.Content)), color, link)
+		if parent != nil {
+			fmt.Fprintf(&b, "    \"%s\" -> \"%s\";\n", diagramNodeID(parent.ID), id)
+		}
+	})
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// walkThoughtTree 按子节点顺序深度优先遍历思维树。
+func walkThoughtTree(s *Session, visit func(thought, parent *Thought)) {
+	if s == nil || s.RootThought == nil {
+		return
+	}
+
+	var walk func(thought, parent *Thought)
+	walk = func(thought, parent *Thought) {
+		visit(thought, parent)
+		for _, child := range thought.Children {
+			if child != nil {
+				walk(child, thought)
+			}
+		}
+	}
+	walk(s.RootThought, nil)
+}
+
+func diagramNodeID(thoughtID string) string {
+	var b strings.Builder
+	b.WriteString("n_")
+	for _, r := range thoughtID {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+func diagramLabel(content string) string {
+	label := strings.Join(strings.Fields(content), " ")
+	runes := []rune(label)
+	if len(runes) > DiagramLabelMaxRunes {
+		label = string(runes[:DiagramLabelMaxRunes-1]) + "…"
+	}
+	return label
+}
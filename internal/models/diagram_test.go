@@ -0,0 +1,70 @@
+package models_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"WideMindsMCP/internal/models"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func buildDiagramFixture() *models.Session {
+	session := models.NewSession("user", "Energy \"storage\" [2030]")
+	session.ID = "fixture"
+	session.RootThought.ID = "root-0001"
+
+	add := func(parent *models.Thought, id, content string, dirType models.DirectionType) *models.Thought {
+		child := models.NewThought(content, session.ID, models.Direction{Type: dirType, Title: content})
+		child.ID = id
+		parent.AddChild(child)
+		return child
+	}
+
+	batteries := add(session.RootThought, "a-0001", "Batteries {solid-state} <next>", models.Broad)
+	add(batteries, "a-0002", "Supply chain risk: cobalt | nickel\nsecond line", models.Critical)
+	add(batteries, "a-0003", "A very long thought that keeps going well beyond the label limit so it must be truncated", models.Deep)
+	add(session.RootThought, "b-0001", `Pumped hydro \ gravity #storage`, models.Lateral)
+	return session
+}
+
+func assertGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("update golden %s: %v", path, err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden %s: %v", path, err)
+	}
+	if string(want) != got {
+		t.Fatalf("%s mismatch\n--- want ---\n%s\n--- got ---\n%s", name, want, got)
+	}
+}
+
+func TestSessionToMermaidGolden(t *testing.T) {
+	assertGolden(t, "session.mermaid.golden", buildDiagramFixture().ToMermaid())
+}
+
+func TestSessionToDOTGolden(t *testing.T) {
+	assertGolden(t, "session.dot.golden", buildDiagramFixture().ToDOT())
+}
+
+func TestSessionToDOTLinksExternalSystem(t *testing.T) {
+	session := buildDiagramFixture()
+	session.RootThought.ExternalSystemURL = "https://tracker.example.com/browse/PROJ-1"
+
+	dot := session.ToDOT()
+	if !strings.Contains(dot, `fillcolor="#dbeafe", URL="https://tracker.example.com/browse/PROJ-1"];`) {
+		t.Fatalf("expected root node to carry the external URL:\n%s", dot)
+	}
+	if strings.Count(dot, "URL=") != 1 {
+		t.Fatalf("expected only linked nodes to carry a URL:\n%s", dot)
+	}
+}
@@ -0,0 +1,232 @@
+//Session Diff(会话差异)
+
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 枚举类型
+type ThoughtChangeKind string
+
+const (
+	ChangeAdded    ThoughtChangeKind = "added"
+	ChangeRemoved  ThoughtChangeKind = "removed"
+	ChangeModified ThoughtChangeKind = "modified"
+	ChangeMoved    ThoughtChangeKind = "moved"
+)
+
+// 匹配方式
+const (
+	MatchedByID      = "id"
+	MatchedByContent = "content"
+)
+
+// 结构体
+type ThoughtChange struct {
+	Kind      ThoughtChangeKind `json:"kind"`
+	ID        string            `json:"id,omitempty"`      // 左侧（a）中的思维ID
+	OtherID   string            `json:"otherId,omitempty"` // 右侧（b）中的思维ID
+	MatchedBy string            `json:"matchedBy,omitempty"`
+	Content   string            `json:"content"`
+	Before    string            `json:"before,omitempty"`
+	After     string            `json:"after,omitempty"`
+	Fields    []string          `json:"fields,omitempty"`
+	FromPath  string            `json:"fromPath,omitempty"`
+	ToPath    string            `json:"toPath,omitempty"`
+}
+
+type SessionDiff struct {
+	SessionID      string          `json:"sessionId"`
+	OtherSessionID string          `json:"otherSessionId"`
+	Added          []ThoughtChange `json:"added"`
+	Removed        []ThoughtChange `json:"removed"`
+	Modified       []ThoughtChange `json:"modified"`
+	Moved          []ThoughtChange `json:"moved"`
+}
+
+// 函数
+// DiffSessions 比较两个会话（或同一会话的两个版本）的思维树。
+// 思维优先按ID匹配，剩余未匹配的按内容匹配；结果按 a、b 的深度优先顺序排列。
+func DiffSessions(a, b *Session) *SessionDiff {
+	diff := &SessionDiff{
+		Added:    []ThoughtChange{},
+		Removed:  []ThoughtChange{},
+		Modified: []ThoughtChange{},
+		Moved:    []ThoughtChange{},
+	}
+	if a != nil {
+		diff.SessionID = a.ID
+	}
+	if b != nil {
+		diff.OtherSessionID = b.ID
+	}
+
+	left, leftParents, leftPaths := diffIndex(a)
+	right, rightParents, rightPaths := diffIndex(b)
+
+	rightByID := make(map[string]*Thought, len(right))
+	for _, thought := range right {
+		rightByID[thought.ID] = thought
+	}
+
+	matches := make(map[string]*Thought, len(left)) // a.ID -> b thought
+	matchedBy := make(map[string]string, len(left)) // a.ID -> 匹配方式
+	claimed := make(map[string]bool, len(right))    // 已匹配的 b.ID
+	for _, thought := range left {
+		if other, ok := rightByID[thought.ID]; ok {
+			matches[thought.ID] = other
+			matchedBy[thought.ID] = MatchedByID
+			claimed[other.ID] = true
+		}
+	}
+
+	unclaimedByContent := map[string][]*Thought{}
+	for _, thought := range right {
+		if !claimed[thought.ID] {
+			key := diffContentKey(thought.Content)
+			unclaimedByContent[key] = append(unclaimedByContent[key], thought)
+		}
+	}
+	for _, thought := range left {
+		if _, ok := matches[thought.ID]; ok {
+			continue
+		}
+		key := diffContentKey(thought.Content)
+		if candidates := unclaimedByContent[key]; len(candidates) > 0 {
+			other := candidates[0]
+			unclaimedByContent[key] = candidates[1:]
+			matches[thought.ID] = other
+			matchedBy[thought.ID] = MatchedByContent
+			claimed[other.ID] = true
+		}
+	}
+
+	for _, thought := range left {
+		other, ok := matches[thought.ID]
+		if !ok {
+			diff.Removed = append(diff.Removed, ThoughtChange{
+				Kind:     ChangeRemoved,
+				ID:       thought.ID,
+				Content:  thought.Content,
+				FromPath: leftPaths[thought.ID],
+			})
+			continue
+		}
+
+		if fields := diffFields(thought, other); len(fields) > 0 {
+			change := ThoughtChange{
+				Kind:      ChangeModified,
+				ID:        thought.ID,
+				OtherID:   other.ID,
+				MatchedBy: matchedBy[thought.ID],
+				Content:   other.Content,
+				Fields:    fields,
+			}
+			if thought.Content != other.Content {
+				change.Before = thought.Content
+				change.After = other.Content
+			}
+			diff.Modified = append(diff.Modified, change)
+		}
+
+		if diffParentChanged(leftParents[thought.ID], rightParents[other.ID], matches) {
+			diff.Moved = append(diff.Moved, ThoughtChange{
+				Kind:      ChangeMoved,
+				ID:        thought.ID,
+				OtherID:   other.ID,
+				MatchedBy: matchedBy[thought.ID],
+				Content:   other.Content,
+				FromPath:  leftPaths[thought.ID],
+				ToPath:    rightPaths[other.ID],
+			})
+		}
+	}
+
+	for _, thought := range right {
+		if claimed[thought.ID] {
+			continue
+		}
+		diff.Added = append(diff.Added, ThoughtChange{
+			Kind:    ChangeAdded,
+			OtherID: thought.ID,
+			Content: thought.Content,
+			ToPath:  rightPaths[thought.ID],
+		})
+	}
+
+	return diff
+}
+
+// 方法
+func (d *SessionDiff) Empty() bool {
+	return d == nil || len(d.Added)+len(d.Removed)+len(d.Modified)+len(d.Moved) == 0
+}
+
+// String 返回便于阅读的差异摘要。
+func (d *SessionDiff) String() string {
+	if d.Empty() {
+		return "No differences.\n"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d added, %d removed, %d modified, %d moved\n", len(d.Added), len(d.Removed), len(d.Modified), len(d.Moved))
+	for _, change := range d.Added {
+		fmt.Fprintf(&b, "+ %s\n", change.ToPath)
+	}
+	for _, change := range d.Removed {
+		fmt.Fprintf(&b, "- %s\n", change.FromPath)
+	}
+	for _, change := range d.Modified {
+		if change.Before != "" || change.After != "" {
+			fmt.Fprintf(&b, "~ %q -> %q (%s)\n", change.Before, change.After, strings.Join(change.Fields, ", "))
+		} else {
+			fmt.Fprintf(&b, "~ %q (%s)\n", change.Content, strings.Join(change.Fields, ", "))
+		}
+	}
+	for _, change := range d.Moved {
+		fmt.Fprintf(&b, "> %s => %s\n", change.FromPath, change.ToPath)
+	}
+	return b.String()
+}
+
+// diffIndex 按遍历顺序收集思维，并根据实际树结构计算父节点与路径。
+func diffIndex(s *Session) ([]*Thought, map[string]*Thought, map[string]string) {
+	thoughts := []*Thought{}
+	parents := map[string]*Thought{}
+	paths := map[string]string{}
+	walkThoughtTree(s, func(thought, parent *Thought) {
+		thoughts = append(thoughts, thought)
+		parents[thought.ID] = parent
+		if parent != nil {
+			paths[thought.ID] = paths[parent.ID] + ThoughtPathSeparator + thought.Content
+		} else {
+			paths[thought.ID] = thought.Content
+		}
+	})
+	return thoughts, parents, paths
+}
+
+func diffContentKey(content string) string {
+	return strings.ToLower(strings.Join(strings.Fields(content), " "))
+}
+
+func diffFields(a, b *Thought) []string {
+	fields := []string{}
+	if a.Content != b.Content {
+		fields = append(fields, "content")
+	}
+	if a.Direction.Type != b.Direction.Type || a.Direction.Title != b.Direction.Title || a.Direction.Description != b.Direction.Description {
+		fields = append(fields, "direction")
+	}
+	return fields
+}
+
+func diffParentChanged(leftParent, rightParent *Thought, matches map[string]*Thought) bool {
+	if leftParent == nil || rightParent == nil {
+		return leftParent != rightParent
+	}
+	counterpart, ok := matches[leftParent.ID]
+	return !ok || counterpart.ID != rightParent.ID
+}
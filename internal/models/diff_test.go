@@ -0,0 +1,91 @@
+package models_test
+
+import (
+	"strings"
+	"testing"
+
+	"WideMindsMCP/internal/models"
+)
+
+func TestDiffSessionsIdentical(t *testing.T) {
+	session := buildFlattenFixture()
+	diff := models.DiffSessions(session, session.Clone())
+	if !diff.Empty() {
+		t.Fatalf("expected no differences, got %+v", diff)
+	}
+	if diff.String() != "No differences.\n" {
+		t.Fatalf("unexpected rendering %q", diff.String())
+	}
+}
+
+func TestDiffSessionsReportsEachCategory(t *testing.T) {
+	before := buildFlattenFixture()
+	after := before.Clone()
+
+	a := after.RootThought.Children[0]
+	b := after.RootThought.Children[1]
+	a1, a2, b1 := a.Children[0], a.Children[1], b.Children[0]
+
+	a1.Content = "A1 revised"
+	b.Direction.Type = models.Critical
+	b.RemoveChildByID(b1.ID)
+	a.RemoveChildByID(a2.ID)
+	b.AddChild(a2)
+	added := models.NewThought("C", after.ID, models.Direction{Type: models.Broad, Title: "C"})
+	after.RootThought.AddChild(added)
+	after.NormalizeTree()
+
+	diff := models.DiffSessions(before, after)
+
+	if len(diff.Added) != 1 || diff.Added[0].OtherID != added.ID || diff.Added[0].ToPath != "R > C" {
+		t.Fatalf("unexpected added changes %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].ID != b1.ID || diff.Removed[0].FromPath != "R > B > B1" {
+		t.Fatalf("unexpected removed changes %+v", diff.Removed)
+	}
+	if len(diff.Modified) != 2 {
+		t.Fatalf("expected 2 modified thoughts, got %+v", diff.Modified)
+	}
+	if got := diff.Modified[1]; got.ID != b.ID || strings.Join(got.Fields, ",") != "direction" || got.Before != "" {
+		t.Fatalf("unexpected direction change %+v", got)
+	}
+	if got := diff.Modified[0]; got.ID != a1.ID || got.Before != "A1" || got.After != "A1 revised" || got.MatchedBy != models.MatchedByID {
+		t.Fatalf("unexpected content change %+v", got)
+	}
+	if len(diff.Moved) != 1 || diff.Moved[0].ID != a2.ID || diff.Moved[0].FromPath != "R > A > A2" || diff.Moved[0].ToPath != "R > B > A2" {
+		t.Fatalf("unexpected moved changes %+v", diff.Moved)
+	}
+
+	text := diff.String()
+	for _, want := range []string{"1 added, 1 removed, 2 modified, 1 moved", "+ R > C", "- R > B > B1", `~ "A1" -> "A1 revised" (content)`, "> R > A > A2 => R > B > A2"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("rendering missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestDiffSessionsMatchesByContent(t *testing.T) {
+	before := buildFlattenFixture()
+	after := buildFlattenFixture()
+
+	diff := models.DiffSessions(before, after)
+	if !diff.Empty() {
+		t.Fatalf("expected content matching to pair independently built trees, got %+v", diff)
+	}
+
+	after.RootThought.Children[0].Children[0].Content = "  a1 "
+	after.RootThought.Children[1].Content = "B2"
+	diff = models.DiffSessions(before, after)
+	if len(diff.Added) != 1 || diff.Added[0].Content != "B2" {
+		t.Fatalf("expected renamed thought without shared ID to be reported as added, got %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Content != "B" {
+		t.Fatalf("expected original thought to be reported as removed, got %+v", diff.Removed)
+	}
+	if len(diff.Moved) != 1 || diff.Moved[0].Content != "B1" || diff.Moved[0].MatchedBy != models.MatchedByContent {
+		t.Fatalf("expected B1 to move to its new parent, got %+v", diff.Moved)
+	}
+	if len(diff.Modified) != 1 || diff.Modified[0].Before != "A1" || diff.Modified[0].MatchedBy != models.MatchedByContent {
+		t.Fatalf("expected loosely matched thought to report its content change, got %+v", diff.Modified)
+	}
+}
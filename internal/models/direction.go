@@ -19,6 +19,7 @@ type Direction struct {
 	Description string        `json:"description"`
 	Keywords    []string      `json:"keywords"`
 	Relevance   float64       `json:"relevance"`
+	Provenance  *Provenance   `json:"provenance,omitempty"`
 }
 
 // 方法
@@ -59,3 +60,16 @@ func (d *Direction) SetRelevance(score float64) {
 		d.Relevance = score
 	}
 }
+
+// Clone 返回方向的深拷贝。
+func (d Direction) Clone() Direction {
+	clone := d
+	if d.Keywords != nil {
+		clone.Keywords = append([]string(nil), d.Keywords...)
+	}
+	if d.Provenance != nil {
+		provenance := *d.Provenance
+		clone.Provenance = &provenance
+	}
+	return clone
+}
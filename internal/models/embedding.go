@@ -0,0 +1,73 @@
+//Session Embedding(会话向量)
+
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"unicode/utf8"
+)
+
+// 常量
+const maxEmbeddingTextRunes = 8000
+
+// 方法
+// EmbeddingText 返回用于生成会话向量的文本：根概念、上下文与按层级排列的思维内容，超长时截断。
+func (s *Session) EmbeddingText() string {
+	if s == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	write := func(text string) bool {
+		text = strings.Join(strings.Fields(text), " ")
+		if text == "" {
+			return true
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(text)
+		return utf8.RuneCountInString(sb.String()) < maxEmbeddingTextRunes
+	}
+
+	s.EnsureContextEntries()
+	if s.RootThought != nil && !write(s.RootThought.Content) {
+		return truncateRunes(sb.String(), maxEmbeddingTextRunes)
+	}
+	for _, entry := range s.ContextEntries {
+		if !write(entry.String()) {
+			return truncateRunes(sb.String(), maxEmbeddingTextRunes)
+		}
+	}
+	if s.RootThought != nil {
+		queue := append([]*Thought(nil), s.RootThought.Children...)
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			if current == nil {
+				continue
+			}
+			if !write(current.Content) {
+				break
+			}
+			queue = append(queue, current.Children...)
+		}
+	}
+	return truncateRunes(sb.String(), maxEmbeddingTextRunes)
+}
+
+// EmbeddingFingerprint 标识指定模型下的会话内容；内容或模型变化后已缓存的向量随之失效。
+func (s *Session) EmbeddingFingerprint(model string) string {
+	sum := sha256.Sum256([]byte(model + "\x00" + s.EmbeddingText()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// 函数
+func truncateRunes(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	return string([]rune(text)[:limit])
+}
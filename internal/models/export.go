@@ -0,0 +1,276 @@
+//Session Export(会话导出)
+
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	appErrors "WideMindsMCP/internal/errors"
+
+	"github.com/google/uuid"
+)
+
+// 枚举类型
+type ExportFormat string
+
+const (
+	ExportJSON     ExportFormat = "json"
+	ExportCSV      ExportFormat = "csv"
+	ExportMermaid  ExportFormat = "mermaid"
+	ExportDOT      ExportFormat = "dot"
+	ExportOPML     ExportFormat = "opml"
+	ExportMarkdown ExportFormat = "markdown"
+	ExportCanvas   ExportFormat = "canvas"
+)
+
+// 结构体
+type ExportOptions struct {
+	Markdown MarkdownOptions
+}
+
+type ExportDocument struct {
+	Format      ExportFormat
+	ContentType string
+	Filename    string
+	Data        []byte
+}
+
+// storedSession 是会话的存储格式：在 API 格式之外附带向量字段。
+type storedSession struct {
+	Session
+	Embedding    []float64 `json:"embedding,omitempty"`
+	EmbeddingKey string    `json:"embeddingKey,omitempty"`
+}
+
+// 函数
+func DefaultExportOptions() ExportOptions {
+	return ExportOptions{Markdown: DefaultMarkdownOptions()}
+}
+
+// ParseExportFormat 解析导出格式，空值默认为 JSON。
+func ParseExportFormat(value string) (ExportFormat, error) {
+	format := ExportFormat(strings.ToLower(strings.TrimSpace(value)))
+	switch format {
+	case "":
+		return ExportJSON, nil
+	case "md":
+		return ExportMarkdown, nil
+	case ExportJSON, ExportCSV, ExportMermaid, ExportDOT, ExportOPML, ExportMarkdown, ExportCanvas:
+		return format, nil
+	default:
+		return "", fmt.Errorf("%w: unsupported export format %q", appErrors.ErrInvalidRequest, value)
+	}
+}
+
+// 方法
+// Export 按指定格式渲染会话。
+func (s *Session) Export(format ExportFormat, opts ExportOptions) (*ExportDocument, error) {
+	if s == nil {
+		return nil, appErrors.ErrInvalidRequest
+	}
+
+	doc := &ExportDocument{Format: format}
+	switch format {
+	case ExportJSON:
+		data, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		doc.ContentType = "application/json"
+		doc.Data = data
+	case ExportCSV:
+		data, err := s.ToCSV()
+		if err != nil {
+			return nil, err
+		}
+		doc.ContentType = "text/csv; charset=utf-8"
+		doc.Data = data
+	case ExportMermaid:
+		doc.ContentType = "text/vnd.mermaid; charset=utf-8"
+		doc.Data = []byte(s.ToMermaid())
+	case ExportDOT:
+		doc.ContentType = "text/vnd.graphviz; charset=utf-8"
+		doc.Data = []byte(s.ToDOT())
+	case ExportMarkdown:
+		doc.ContentType = "text/markdown; charset=utf-8"
+		doc.Data = []byte(s.ToMarkdown(opts.Markdown))
+	case ExportCanvas:
+		data, err := s.ToJSONCanvas()
+		if err != nil {
+			return nil, err
+		}
+		doc.ContentType = "application/json"
+		doc.Data = data
+	case ExportOPML:
+		data, err := SessionToOPML(s)
+		if err != nil {
+			return nil, err
+		}
+		doc.ContentType = "text/x-opml; charset=utf-8"
+		doc.Data = data
+	default:
+		return nil, fmt.Errorf("%w: unsupported export format %q", appErrors.ErrInvalidRequest, format)
+	}
+
+	doc.Filename = fmt.Sprintf("session-%s.%s", s.ID, exportExtension(format))
+	return doc, nil
+}
+
+// WriteJSON 将会话以紧凑 JSON 流式写入 w：逐个节点编码思维树，内存占用与单个节点而非整棵树成正比。
+// 输出可被 json.Unmarshal 还原为与 json.Marshal 相同的会话。
+func (s *Session) WriteJSON(w io.Writer) error {
+	return s.writeJSON(w, false)
+}
+
+// WriteStoredJSON 与 WriteJSON 相同，另外写出会话向量，供存储层持久化；用 DecodeStoredSession 还原。
+func (s *Session) WriteStoredJSON(w io.Writer) error {
+	return s.writeJSON(w, true)
+}
+
+func (s *Session) writeJSON(w io.Writer, stored bool) error {
+	if s == nil {
+		return appErrors.ErrInvalidRequest
+	}
+
+	bw := bufio.NewWriter(w)
+	var scratch bytes.Buffer
+	enc := json.NewEncoder(&scratch)
+
+	envelope := *s
+	envelope.RootThought = nil
+	var header interface{} = envelope
+	if stored {
+		header = storedSession{Session: envelope, Embedding: s.Embedding, EmbeddingKey: s.EmbeddingKey}
+	}
+	if err := writeJSONObjectOpen(bw, enc, &scratch, header); err != nil {
+		return err
+	}
+	if s.RootThought != nil {
+		if _, err := bw.WriteString(`,"rootThought":`); err != nil {
+			return err
+		}
+		if err := writeThoughtJSON(bw, enc, &scratch, s.RootThought); err != nil {
+			return err
+		}
+	}
+	if err := bw.WriteByte('}'); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// DecodeStoredSession 解析 WriteStoredJSON 写出的会话并还原向量，也接受不含向量的 API 格式。
+func DecodeStoredSession(data []byte) (*Session, error) {
+	var stored storedSession
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
+	session := stored.Session
+	session.Embedding = stored.Embedding
+	session.EmbeddingKey = stored.EmbeddingKey
+	return &session, nil
+}
+
+// writeThoughtJSON 先编码不含子节点的思维，再递归写入 children 数组。
+func writeThoughtJSON(bw *bufio.Writer, enc *json.Encoder, scratch *bytes.Buffer, thought *Thought) error {
+	node := *thought
+	node.Children = nil
+	if err := writeJSONObjectOpen(bw, enc, scratch, node); err != nil {
+		return err
+	}
+
+	if len(thought.Children) > 0 {
+		if _, err := bw.WriteString(`,"children":[`); err != nil {
+			return err
+		}
+		for i, child := range thought.Children {
+			if i > 0 {
+				if err := bw.WriteByte(','); err != nil {
+					return err
+				}
+			}
+			if child == nil {
+				if _, err := bw.WriteString("null"); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := writeThoughtJSON(bw, enc, scratch, child); err != nil {
+				return err
+			}
+		}
+		if err := bw.WriteByte(']'); err != nil {
+			return err
+		}
+	}
+	return bw.WriteByte('}')
+}
+
+// writeJSONObjectOpen 编码 value 并写出去掉结尾 "}" 的对象，以便继续追加字段。
+func writeJSONObjectOpen(bw *bufio.Writer, enc *json.Encoder, scratch *bytes.Buffer, value interface{}) error {
+	scratch.Reset()
+	if err := enc.Encode(value); err != nil {
+		return err
+	}
+	data := bytes.TrimRight(scratch.Bytes(), "\n")
+	if len(data) < 2 || data[len(data)-1] != '}' {
+		return fmt.Errorf("unexpected JSON object encoding")
+	}
+	_, err := bw.Write(data[:len(data)-1])
+	return err
+}
+
+// ImportSession 从指定格式的文档构建新会话，支持 OPML、JSON 与 Markdown。
+func ImportSession(format ExportFormat, data []byte) (*Session, error) {
+	switch format {
+	case ExportOPML:
+		return ParseOPML(data)
+	case ExportJSON:
+		return ParseSessionJSON(data)
+	case ExportMarkdown:
+		return ParseMarkdown(data)
+	default:
+		return nil, fmt.Errorf("%w: unsupported import format %q", appErrors.ErrInvalidRequest, format)
+	}
+}
+
+// ParseSessionJSON 从 JSON 导出构建新会话：分配新的会话ID并重建父子关系，思维ID保持不变。
+func ParseSessionJSON(data []byte) (*Session, error) {
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("%w: invalid session JSON: %v", appErrors.ErrInvalidRequest, err)
+	}
+	if session.RootThought == nil || strings.TrimSpace(session.RootThought.Content) == "" {
+		return nil, fmt.Errorf("%w: session JSON has no root thought", appErrors.ErrInvalidRequest)
+	}
+
+	now := time.Now().UTC()
+	session.ID = uuid.NewString()
+	walkThoughtTree(&session, func(thought, _ *Thought) {
+		thought.SessionID = session.ID
+	})
+	session.NormalizeTree()
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = now
+	}
+	session.UpdatedAt = now
+	session.IsActive = true
+	return &session, nil
+}
+
+func exportExtension(format ExportFormat) string {
+	switch format {
+	case ExportMermaid:
+		return "mmd"
+	case ExportMarkdown:
+		return "md"
+	default:
+		return string(format)
+	}
+}
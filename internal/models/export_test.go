@@ -0,0 +1,86 @@
+package models_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"WideMindsMCP/internal/models"
+)
+
+func buildLargeSession(nodes int) *models.Session {
+	session := models.NewSession("user", "Root")
+	level := []*models.Thought{session.RootThought}
+	for created := 1; created < nodes; {
+		var next []*models.Thought
+		for _, parent := range level {
+			for i := 0; i < 4 && created < nodes; i++ {
+				child := models.NewThought(fmt.Sprintf("Thought %d", created), session.ID, models.Direction{Type: models.Deep, Title: "Deep", Keywords: []string{"k"}})
+				child.Tags = []string{"tag"}
+				parent.AddChild(child)
+				next = append(next, child)
+				created++
+			}
+		}
+		level = next
+	}
+	return session
+}
+
+func TestSessionWriteJSONMatchesMarshal(t *testing.T) {
+	session := buildFlattenFixture()
+	session.RootThought.Children[0].SetAnnotation("note", `<b>"quoted"</b>`)
+
+	var streamed bytes.Buffer
+	if err := session.WriteJSON(&streamed); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	marshaled, err := json.Marshal(session)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var got, want interface{}
+	if err := json.Unmarshal(streamed.Bytes(), &got); err != nil {
+		t.Fatalf("streamed output is not valid JSON: %v\n%s", err, streamed.String())
+	}
+	if err := json.Unmarshal(marshaled, &want); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("streamed JSON differs from json.Marshal:\n%s\n%s", streamed.String(), marshaled)
+	}
+
+	var empty models.Session
+	streamed.Reset()
+	if err := empty.WriteJSON(&streamed); err != nil || !json.Valid(streamed.Bytes()) {
+		t.Fatalf("expected valid JSON for a session without root, got %q (%v)", streamed.String(), err)
+	}
+}
+
+func BenchmarkSessionMarshalIndent(b *testing.B) {
+	session := buildLargeSession(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data, err := json.MarshalIndent(session, "", "  ")
+		if err != nil {
+			b.Fatal(err)
+		}
+		_, _ = io.Discard.Write(data)
+	}
+}
+
+func BenchmarkSessionWriteJSON(b *testing.B) {
+	session := buildLargeSession(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := session.WriteJSON(io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+//Markdown Import/Export(Markdown导入导出)
+
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	appErrors "WideMindsMCP/internal/errors"
+)
+
+// 枚举类型
+type MarkdownStyle string
+
+const (
+	MarkdownHeadings MarkdownStyle = "headings" // 每个思维一个标题，超过 6 级时改用列表
+	MarkdownBullets  MarkdownStyle = "bullets"  // 嵌套列表
+)
+
+const markdownMaxHeadingLevel = 6
+
+// 结构体
+type MarkdownOptions struct {
+	Style               MarkdownStyle
+	IncludeDescriptions bool
+	IncludeKeywords     bool
+	MaxDepth            int // <=0 表示不限制
+	IncludeMetadata     bool
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	"*", `\*`,
+	"_", `\_`,
+	"[", `\[`,
+	"]", `\]`,
+	"#", `\#`,
+	"<", `\<`,
+	">", `\>`,
+	"|", `\|`,
+)
+
+var markdownOrderedMarker = regexp.MustCompile(`^(\d+)\.`)
+
+var (
+	markdownEscapedChar   = regexp.MustCompile("\\\\([\\\\`*_\\[\\]#<>|.+=-])")
+	markdownHeadingLine   = regexp.MustCompile(`^(#{1,6})\s+(.+)$`)
+	markdownBulletLine    = regexp.MustCompile(`^(\s*)[-*+]\s+(.+)$`)
+	markdownBulletLabel   = regexp.MustCompile(`\s+_\((.+)\)_$`)
+	markdownEmphasisLabel = regexp.MustCompile(`^_(.+)_$`)
+)
+
+const markdownDefaultRootTitle = "Imported outline"
+
+// 函数
+func DefaultMarkdownOptions() MarkdownOptions {
+	return MarkdownOptions{
+		Style:               MarkdownHeadings,
+		IncludeDescriptions: true,
+		IncludeKeywords:     true,
+		IncludeMetadata:     true,
+	}
+}
+
+// ParseMarkdownStyle 解析样式名称，空值默认为标题样式。
+func ParseMarkdownStyle(value string) (MarkdownStyle, bool) {
+	switch MarkdownStyle(strings.ToLower(strings.TrimSpace(value))) {
+	case "", MarkdownHeadings:
+		return MarkdownHeadings, true
+	case MarkdownBullets, "bullet", "list":
+		return MarkdownBullets, true
+	default:
+		return "", false
+	}
+}
+
+// 方法
+// ToMarkdown 将思维树渲染为 Markdown 大纲，并按选项附加方向注释与元数据。
+func (s *Session) ToMarkdown(opts MarkdownOptions) string {
+	if s == nil || s.RootThought == nil {
+		return ""
+	}
+	if opts.Style == "" {
+		opts.Style = MarkdownHeadings
+	}
+
+	var b strings.Builder
+	var walk func(thought *Thought, depth int)
+	walk = func(thought *Thought, depth int) {
+		if opts.MaxDepth > 0 && depth > opts.MaxDepth {
+			return
+		}
+		if opts.Style == MarkdownHeadings && depth < markdownMaxHeadingLevel {
+			writeMarkdownHeading(&b, thought, depth+1, opts)
+		} else {
+			level := depth
+			if opts.Style == MarkdownHeadings {
+				level -= markdownMaxHeadingLevel
+			}
+			writeMarkdownBullet(&b, thought, level, opts)
+		}
+		for _, child := range thought.Children {
+			if child != nil {
+				walk(child, depth+1)
+			}
+		}
+	}
+	walk(s.RootThought, 0)
+
+	if opts.IncludeMetadata {
+		meta := s.GetMetadata()
+		out := strings.TrimRight(b.String(), "\n")
+		b.Reset()
+		b.WriteString(out)
+		b.WriteString("\n\n---\n\n")
+		fmt.Fprintf(&b, "%d thoughts (%d generated, %d user), max depth %d\n\n", meta.TotalThoughts, meta.LLMThoughts, meta.UserThoughts, meta.MaxDepth)
+		fmt.Fprintf(&b, "Created %s, updated %s\n", s.CreatedAt.UTC().Format(time.RFC3339), s.UpdatedAt.UTC().Format(time.RFC3339))
+	}
+
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+func writeMarkdownHeading(b *strings.Builder, thought *Thought, level int, opts MarkdownOptions) {
+	if out := b.String(); out != "" && !strings.HasSuffix(out, "\n\n") {
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(b, "%s %s\n\n", strings.Repeat("#", level), escapeMarkdown(thought.Content))
+	if label := markdownDirectionLabel(thought); label != "" {
+		fmt.Fprintf(b, "_%s_\n\n", label)
+	}
+	if opts.IncludeDescriptions {
+		if desc := escapeMarkdown(thought.Direction.Description); desc != "" {
+			fmt.Fprintf(b, "%s\n\n", desc)
+		}
+	}
+	if opts.IncludeKeywords {
+		if keywords := markdownKeywords(thought); keywords != "" {
+			fmt.Fprintf(b, "Keywords: %s\n\n", keywords)
+		}
+	}
+}
+
+func writeMarkdownBullet(b *strings.Builder, thought *Thought, level int, opts MarkdownOptions) {
+	indent := strings.Repeat("  ", level)
+	line := escapeMarkdown(thought.Content)
+	if label := markdownDirectionLabel(thought); label != "" {
+		line += " _(" + label + ")_"
+	}
+	fmt.Fprintf(b, "%s- %s\n", indent, line)
+	if opts.IncludeDescriptions {
+		if desc := escapeMarkdown(thought.Direction.Description); desc != "" {
+			fmt.Fprintf(b, "%s  %s\n", indent, desc)
+		}
+	}
+	if opts.IncludeKeywords {
+		if keywords := markdownKeywords(thought); keywords != "" {
+			fmt.Fprintf(b, "%s  Keywords: %s\n", indent, keywords)
+		}
+	}
+}
+
+func markdownDirectionLabel(thought *Thought) string {
+	parts := make([]string, 0, 2)
+	if thought.Direction.Type != "" {
+		parts = append(parts, string(thought.Direction.Type))
+	}
+	title := strings.TrimSpace(thought.Direction.Title)
+	if title != "" && title != strings.TrimSpace(thought.Content) {
+		parts = append(parts, escapeMarkdown(title))
+	}
+	return strings.Join(parts, " · ")
+}
+
+func markdownKeywords(thought *Thought) string {
+	keywords := make([]string, 0, len(thought.Direction.Keywords))
+	for _, keyword := range thought.Direction.Keywords {
+		if escaped := escapeMarkdown(keyword); escaped != "" {
+			keywords = append(keywords, escaped)
+		}
+	}
+	return strings.Join(keywords, ", ")
+}
+
+// escapeMarkdown 折叠空白并转义会破坏大纲结构的 Markdown 语法字符。
+func escapeMarkdown(text string) string {
+	escaped := markdownEscaper.Replace(strings.Join(strings.Fields(text), " "))
+	if strings.HasPrefix(escaped, "-") || strings.HasPrefix(escaped, "+") || strings.HasPrefix(escaped, "=") {
+		escaped = `\` + escaped
+	}
+	return markdownOrderedMarker.ReplaceAllString(escaped, `$1\.`)
+}
+
+// markdownNode 是解析过程中的一个大纲条目
+type markdownNode struct {
+	thought *Thought
+	depth   int
+}
+
+// ParseMarkdown 从 Markdown 大纲构建新会话，兼容 ToMarkdown 的标题与列表两种样式。
+// 标题层级与列表缩进（两个空格一级）决定父子关系；方向注释、描述与关键词行会还原到方向上，
+// "---" 之后的元数据被忽略。多个顶层条目会挂在合成根节点下。
+func ParseMarkdown(data []byte) (*Session, error) {
+	session := NewSession("", markdownDefaultRootTitle)
+
+	var nodes []*markdownNode
+	var current *markdownNode
+	headingDepth := -1
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "---" && len(nodes) > 0 {
+			break
+		}
+		if trimmed == "" {
+			continue
+		}
+
+		if match := markdownHeadingLine.FindStringSubmatch(line); match != nil {
+			headingDepth = len(match[1]) - 1
+			current = &markdownNode{thought: newMarkdownThought(session.ID, match[2], ""), depth: headingDepth}
+			nodes = append(nodes, current)
+			continue
+		}
+		if match := markdownBulletLine.FindStringSubmatch(line); match != nil {
+			content, label := match[2], ""
+			if labelMatch := markdownBulletLabel.FindStringSubmatchIndex(content); labelMatch != nil {
+				label = content[labelMatch[2]:labelMatch[3]]
+				content = content[:labelMatch[0]]
+			}
+			depth := len(strings.ReplaceAll(match[1], "\t", "  ")) / 2
+			if headingDepth >= 0 {
+				depth += headingDepth + 1
+			}
+			current = &markdownNode{thought: newMarkdownThought(session.ID, content, label), depth: depth}
+			nodes = append(nodes, current)
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(trimmed, "Keywords:"):
+			for _, keyword := range strings.Split(strings.TrimPrefix(trimmed, "Keywords:"), ",") {
+				if keyword = unescapeMarkdown(keyword); keyword != "" {
+					current.thought.Direction.Keywords = append(current.thought.Direction.Keywords, keyword)
+				}
+			}
+		case markdownEmphasisLabel.MatchString(trimmed) && current.thought.Direction.Description == "":
+			applyMarkdownLabel(&current.thought.Direction, markdownEmphasisLabel.FindStringSubmatch(trimmed)[1])
+		default:
+			description := unescapeMarkdown(trimmed)
+			if existing := current.thought.Direction.Description; existing != "" {
+				description = existing + " " + description
+			}
+			current.thought.Direction.Description = description
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: invalid Markdown: %v", appErrors.ErrInvalidRequest, err)
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("%w: Markdown document has no headings or list items", appErrors.ErrInvalidRequest)
+	}
+
+	var roots []*markdownNode
+	stack := make([]*markdownNode, 0, len(nodes))
+	for _, node := range nodes {
+		for len(stack) > 0 && stack[len(stack)-1].depth >= node.depth {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, node)
+		} else {
+			stack[len(stack)-1].thought.AddChild(node.thought)
+		}
+		stack = append(stack, node)
+	}
+
+	if len(roots) == 1 {
+		root := roots[0].thought
+		session.RootThought = root
+		session.Context = []string{root.Content}
+		session.ContextEntries = []ContextEntry{NewContextEntry(ContextNote, root.Content)}
+	} else {
+		for _, root := range roots {
+			session.RootThought.AddChild(root.thought)
+		}
+	}
+	session.NormalizeTree()
+	return session, nil
+}
+
+func newMarkdownThought(sessionID, content, label string) *Thought {
+	content = unescapeMarkdown(content)
+	thought := NewThought(content, sessionID, Direction{Type: Broad, Title: content})
+	if label != "" {
+		applyMarkdownLabel(&thought.Direction, label)
+	}
+	return thought
+}
+
+// applyMarkdownLabel 解析 "type · title" 形式的方向注释
+func applyMarkdownLabel(direction *Direction, label string) {
+	parts := strings.SplitN(label, " · ", 2)
+	switch candidate := DirectionType(strings.ToLower(strings.TrimSpace(parts[0]))); candidate {
+	case Broad, Deep, Lateral, Critical:
+		direction.Type = candidate
+		parts = parts[1:]
+	}
+	if len(parts) > 0 {
+		if title := unescapeMarkdown(parts[0]); title != "" {
+			direction.Title = title
+		}
+	}
+}
+
+// unescapeMarkdown 去除 escapeMarkdown 添加的转义符
+func unescapeMarkdown(text string) string {
+	return strings.TrimSpace(markdownEscapedChar.ReplaceAllString(text, "$1"))
+}
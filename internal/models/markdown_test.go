@@ -0,0 +1,104 @@
+package models_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+)
+
+func buildMarkdownFixture() *models.Session {
+	session := buildDiagramFixture()
+	created := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	session.CreatedAt = created
+	session.UpdatedAt = created.Add(2 * time.Hour)
+
+	batteries := session.RootThought.Children[0]
+	batteries.Direction.Description = "Compare *chemistries* by cost_per_kWh"
+	batteries.Direction.Keywords = []string{"lithium", "#solid-state"}
+
+	deepest := batteries.Children[0]
+	for _, content := range []string{"Level 3", "Level 4", "Level 5", "1. Level 6"} {
+		child := models.NewThought(content, session.ID, models.Direction{Type: models.Deep, Title: "Drill down"})
+		deepest.AddChild(child)
+		deepest = child
+	}
+	return session
+}
+
+func TestSessionToMarkdownHeadingsGolden(t *testing.T) {
+	assertGolden(t, "session.headings.md.golden", buildMarkdownFixture().ToMarkdown(models.DefaultMarkdownOptions()))
+}
+
+func TestSessionToMarkdownBulletsGolden(t *testing.T) {
+	opts := models.MarkdownOptions{Style: models.MarkdownBullets, IncludeDescriptions: true, IncludeKeywords: true, MaxDepth: 3}
+	assertGolden(t, "session.bullets.md.golden", buildMarkdownFixture().ToMarkdown(opts))
+}
+
+func TestParseMarkdownRoundTrip(t *testing.T) {
+	session := buildMarkdownFixture()
+	// Markdown 导出会折叠空白，多行内容无法原样还原
+	for _, thought := range session.GetThoughtTree() {
+		thought.Content = strings.Join(strings.Fields(thought.Content), " ")
+		thought.Direction.Title = strings.Join(strings.Fields(thought.Direction.Title), " ")
+	}
+	for _, style := range []models.MarkdownStyle{models.MarkdownHeadings, models.MarkdownBullets} {
+		opts := models.DefaultMarkdownOptions()
+		opts.Style = style
+
+		parsed, err := models.ParseMarkdown([]byte(session.ToMarkdown(opts)))
+		if err != nil {
+			t.Fatalf("%s: ParseMarkdown failed: %v", style, err)
+		}
+		if want, got := fmt.Sprint(describeTree(session)), fmt.Sprint(describeTree(parsed)); want != got {
+			t.Fatalf("%s: round trip mismatch\nwant %v\ngot  %v", style, want, got)
+		}
+
+		batteries := parsed.RootThought.Children[0]
+		if batteries.Direction.Description != "Compare *chemistries* by cost_per_kWh" {
+			t.Fatalf("%s: unexpected description %q", style, batteries.Direction.Description)
+		}
+		if strings.Join(batteries.Direction.Keywords, ",") != "lithium,#solid-state" {
+			t.Fatalf("%s: unexpected keywords %v", style, batteries.Direction.Keywords)
+		}
+	}
+}
+
+func TestParseMarkdownWrapsMultipleTopLevelItems(t *testing.T) {
+	parsed, err := models.ParseMarkdown([]byte("## Alpha\n\n- one\n  - two\n\n## Beta\n"))
+	if err != nil {
+		t.Fatalf("ParseMarkdown failed: %v", err)
+	}
+	contents := flattenedContents(parsed.FlattenThoughts(models.FlattenOptions{Order: models.TraversalDFS}))
+	if strings.Join(contents, ",") != "Imported outline,Alpha,one,two,Beta" {
+		t.Fatalf("unexpected imported tree: %v", contents)
+	}
+
+	if _, err := models.ParseMarkdown([]byte("just prose\n")); !errors.Is(err, appErrors.ErrInvalidRequest) {
+		t.Fatalf("expected prose without outline to be rejected, got %v", err)
+	}
+}
+
+func TestParseSessionJSONAssignsNewSessionID(t *testing.T) {
+	session := buildMarkdownFixture()
+	var buf bytes.Buffer
+	if err := session.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+
+	parsed, err := models.ImportSession(models.ExportJSON, buf.Bytes())
+	if err != nil {
+		t.Fatalf("ImportSession failed: %v", err)
+	}
+	if parsed.ID == session.ID || parsed.RootThought.Children[0].SessionID != parsed.ID {
+		t.Fatalf("expected imported thoughts to belong to a new session id")
+	}
+	if want, got := fmt.Sprint(describeTree(session)), fmt.Sprint(describeTree(parsed)); want != got {
+		t.Fatalf("round trip mismatch\nwant %v\ngot  %v", want, got)
+	}
+}
@@ -0,0 +1,110 @@
+//Mind Map Data(前端思维导图数据)
+
+package models
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 常量
+// 思维节点可通过注释覆盖颜色（#rgb 或 #rrggbb）并标记收藏（"true"）
+const (
+	AnnotationColor      = "color"
+	AnnotationBookmarked = "bookmarked"
+)
+
+var hexColorPattern = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// 结构体
+// MindMapData 是前端思维导图渲染所需的节点、边、统计与标签，不随内部模型字段变化。
+type MindMapData struct {
+	Nodes  []MindMapNode `json:"nodes"`
+	Edges  []MindMapEdge `json:"edges"`
+	RootID string        `json:"rootId"`
+	Stats  *SessionStats `json:"stats"`
+	Tags   []string      `json:"tags"`
+}
+
+type MindMapNode struct {
+	ID            string        `json:"id"`
+	ParentID      string        `json:"parentId,omitempty"`
+	Label         string        `json:"label"`
+	Content       string        `json:"content"`
+	Tooltip       string        `json:"tooltip,omitempty"`
+	Color         string        `json:"color"`
+	DirectionType DirectionType `json:"directionType"`
+	Depth         int           `json:"depth"`
+	Tags          []string      `json:"tags,omitempty"`
+	IsBookmarked  bool          `json:"isBookmarked"`
+}
+
+type MindMapEdge struct {
+	ID   string `json:"id"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// 方法
+// BuildMindMapData 按深度优先顺序生成节点与父子边；Tags 为会话标签与各思维标签的并集。
+func (s *Session) BuildMindMapData() *MindMapData {
+	data := &MindMapData{Nodes: []MindMapNode{}, Edges: []MindMapEdge{}, Tags: []string{}}
+	if s == nil {
+		return data
+	}
+	if s.RootThought != nil {
+		data.RootID = s.RootThought.ID
+	}
+	data.Stats = s.Stats()
+
+	tags := map[string]struct{}{}
+	for _, tag := range s.Tags {
+		tags[tag] = struct{}{}
+	}
+	walkThoughtTree(s, func(thought, parent *Thought) {
+		node := MindMapNode{
+			ID:            thought.ID,
+			Label:         diagramLabel(thought.Content),
+			Content:       thought.Content,
+			Tooltip:       strings.TrimSpace(thought.Direction.Description),
+			Color:         thoughtColor(thought),
+			DirectionType: thought.Direction.Type,
+			Depth:         thought.Depth,
+			Tags:          append([]string(nil), thought.Tags...),
+			IsBookmarked:  thought.Annotations[AnnotationBookmarked] == "true",
+		}
+		if parent != nil {
+			node.ParentID = parent.ID
+			data.Edges = append(data.Edges, MindMapEdge{ID: parent.ID + "->" + thought.ID, From: parent.ID, To: thought.ID})
+		}
+		data.Nodes = append(data.Nodes, node)
+		for _, tag := range thought.Tags {
+			tags[tag] = struct{}{}
+		}
+	})
+
+	for tag := range tags {
+		if strings.TrimSpace(tag) != "" {
+			data.Tags = append(data.Tags, tag)
+		}
+	}
+	sort.Strings(data.Tags)
+	return data
+}
+
+// 函数
+// DirectionTypeColor 返回方向类型在图表中使用的填充色，未知类型为灰色。
+func DirectionTypeColor(dirType DirectionType) string {
+	if color, ok := dotDirectionColors[dirType]; ok {
+		return color
+	}
+	return dotDefaultColor
+}
+
+func thoughtColor(thought *Thought) string {
+	if color := strings.TrimSpace(thought.Annotations[AnnotationColor]); hexColorPattern.MatchString(color) {
+		return color
+	}
+	return DirectionTypeColor(thought.Direction.Type)
+}
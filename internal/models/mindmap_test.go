@@ -0,0 +1,49 @@
+package models_test
+
+import (
+	"strings"
+	"testing"
+
+	"WideMindsMCP/internal/models"
+)
+
+func TestBuildMindMapData(t *testing.T) {
+	session := models.NewSession("user", "Energy storage")
+	session.Tags = []string{"energy"}
+	chemistry := models.NewThought("Cell chemistry", session.ID, models.Direction{Type: models.Deep, Title: "Chemistry", Description: "Look at electrode materials"})
+	chemistry.Tags = []string{"battery", "energy"}
+	chemistry.SetAnnotation(models.AnnotationBookmarked, "true")
+	session.RootThought.AddChild(chemistry)
+	recycling := models.NewThought(strings.Repeat("Recycling economics ", 10), session.ID, models.Direction{Type: models.Critical, Title: "Recycling"})
+	recycling.SetAnnotation(models.AnnotationColor, "#123abc")
+	chemistry.AddChild(recycling)
+
+	data := session.BuildMindMapData()
+	if data.RootID != session.RootThought.ID || len(data.Nodes) != 3 || len(data.Edges) != 2 {
+		t.Fatalf("unexpected mind map data %+v", data)
+	}
+	node := data.Nodes[1]
+	if node.ID != chemistry.ID || node.ParentID != session.RootThought.ID || node.Tooltip != "Look at electrode materials" ||
+		node.Color != models.DirectionTypeColor(models.Deep) || !node.IsBookmarked || node.Depth != 1 {
+		t.Fatalf("unexpected chemistry node %+v", node)
+	}
+	leaf := data.Nodes[2]
+	if leaf.Color != "#123abc" || leaf.IsBookmarked || len([]rune(leaf.Label)) > models.DiagramLabelMaxRunes || leaf.Content != recycling.Content {
+		t.Fatalf("unexpected recycling node %+v", leaf)
+	}
+	if edge := data.Edges[1]; edge.From != chemistry.ID || edge.To != recycling.ID {
+		t.Fatalf("unexpected edge %+v", edge)
+	}
+	if strings.Join(data.Tags, ",") != "battery,energy" {
+		t.Fatalf("expected merged session and thought tags, got %v", data.Tags)
+	}
+	if data.Stats == nil || data.Stats.TotalThoughts != 3 || data.Stats.Badge.Complexity != data.Stats.Complexity {
+		t.Fatalf("unexpected stats %+v", data.Stats)
+	}
+}
+
+func TestDirectionTypeColorFallsBackForUnknownTypes(t *testing.T) {
+	if models.DirectionTypeColor(models.Broad) == models.DirectionTypeColor("unknown") {
+		t.Fatalf("expected distinct colors for known and unknown direction types")
+	}
+}
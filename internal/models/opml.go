@@ -0,0 +1,167 @@
+//OPML Import/Export(OPML导入导出)
+
+package models
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	appErrors "WideMindsMCP/internal/errors"
+)
+
+// 常量
+// MaxOPMLDepth 限制导入大纲的嵌套层级，防止恶意或损坏的文件造成过深的递归。
+const MaxOPMLDepth = 64
+
+const opmlDefaultRootTitle = "Imported outline"
+
+// 结构体
+type opmlDocument struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    opmlHead `xml:"head"`
+	Body    opmlBody `xml:"body"`
+}
+
+type opmlHead struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type opmlBody struct {
+	Outlines []*opmlOutline `xml:"outline"`
+}
+
+type opmlOutline struct {
+	Text           string         `xml:"text,attr"`
+	Title          string         `xml:"title,attr,omitempty"`
+	DirectionType  string         `xml:"directionType,attr,omitempty"`
+	DirectionTitle string         `xml:"directionTitle,attr,omitempty"`
+	Note           string         `xml:"_note,attr,omitempty"`
+	Outlines       []*opmlOutline `xml:"outline"`
+}
+
+// 函数
+// SessionToOPML 将会话的思维树导出为 OPML 2.0 文档，方向类型与标题写入 outline 属性。
+func SessionToOPML(s *Session) ([]byte, error) {
+	if s == nil || s.RootThought == nil {
+		return nil, appErrors.ErrInvalidRequest
+	}
+
+	doc := opmlDocument{
+		Version: "2.0",
+		Head: opmlHead{
+			Title:       s.RootThought.Content,
+			DateCreated: s.CreatedAt.UTC().Format(time.RFC1123Z),
+		},
+		Body: opmlBody{Outlines: []*opmlOutline{thoughtToOutline(s.RootThought)}},
+	}
+
+	payload, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.Write(payload)
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// ParseOPML 从 OPML 文档构建新的会话，所有思维都会分配新的ID。
+// 多个顶层 outline 会挂在以 head.title 命名的合成根节点下；未知属性会被忽略。
+func ParseOPML(data []byte) (*Session, error) {
+	var doc opmlDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: invalid OPML: %v", appErrors.ErrInvalidRequest, err)
+	}
+
+	outlines := make([]*opmlOutline, 0, len(doc.Body.Outlines))
+	for _, outline := range doc.Body.Outlines {
+		if outline != nil && outline.content() != "" {
+			outlines = append(outlines, outline)
+		}
+	}
+	if len(outlines) == 0 {
+		return nil, fmt.Errorf("%w: OPML document has no outlines", appErrors.ErrInvalidRequest)
+	}
+
+	var root *opmlOutline
+	if len(outlines) == 1 {
+		root = outlines[0]
+	} else {
+		title := strings.TrimSpace(doc.Head.Title)
+		if title == "" {
+			title = opmlDefaultRootTitle
+		}
+		root = &opmlOutline{Text: title, Outlines: outlines}
+	}
+
+	session := NewSession("", root.content())
+	session.RootThought.Direction = root.direction(session.RootThought.Direction)
+	if err := appendOutlineChildren(session, session.RootThought, root, 1); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func thoughtToOutline(thought *Thought) *opmlOutline {
+	outline := &opmlOutline{
+		Text:           thought.Content,
+		DirectionType:  string(thought.Direction.Type),
+		DirectionTitle: thought.Direction.Title,
+		Note:           thought.Direction.Description,
+	}
+	for _, child := range thought.Children {
+		if child != nil {
+			outline.Outlines = append(outline.Outlines, thoughtToOutline(child))
+		}
+	}
+	return outline
+}
+
+func appendOutlineChildren(session *Session, parent *Thought, outline *opmlOutline, depth int) error {
+	for _, child := range outline.Outlines {
+		if child == nil || child.content() == "" {
+			continue
+		}
+		if depth > MaxOPMLDepth {
+			return fmt.Errorf("%w: OPML outline exceeds maximum depth of %d", appErrors.ErrInvalidRequest, MaxOPMLDepth)
+		}
+
+		thought := NewThought(child.content(), session.ID, child.direction(Direction{Type: Broad}))
+		parent.AddChild(thought)
+		if err := appendOutlineChildren(session, thought, child, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *opmlOutline) content() string {
+	if text := strings.TrimSpace(o.Text); text != "" {
+		return text
+	}
+	return strings.TrimSpace(o.Title)
+}
+
+func (o *opmlOutline) direction(fallback Direction) Direction {
+	direction := fallback
+	switch DirectionType(strings.ToLower(strings.TrimSpace(o.DirectionType))) {
+	case Broad, Deep, Lateral, Critical:
+		direction.Type = DirectionType(strings.ToLower(strings.TrimSpace(o.DirectionType)))
+	}
+	if title := strings.TrimSpace(o.DirectionTitle); title != "" {
+		direction.Title = title
+	} else if direction.Title == "" {
+		direction.Title = o.content()
+	}
+	if note := strings.TrimSpace(o.Note); note != "" {
+		direction.Description = note
+	}
+	return direction
+}
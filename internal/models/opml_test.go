@@ -0,0 +1,109 @@
+package models_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+)
+
+type outlineNode struct {
+	Content   string
+	Depth     int
+	Type      models.DirectionType
+	Title     string
+	ParentIdx int
+}
+
+func describeTree(session *models.Session) []outlineNode {
+	views := session.FlattenThoughts(models.FlattenOptions{Order: models.TraversalDFS})
+	index := make(map[string]int, len(views))
+	nodes := make([]outlineNode, 0, len(views))
+	for i, view := range views {
+		index[view.ID] = i
+		parent := -1
+		if view.ParentID != "" {
+			parent = index[view.ParentID]
+		}
+		nodes = append(nodes, outlineNode{view.Content, view.Depth, view.Direction.Type, view.Direction.Title, parent})
+	}
+	return nodes
+}
+
+func TestOPMLRoundTripPreservesStructure(t *testing.T) {
+	session := models.NewSession("user", "可再生能源 & <storage>")
+	add := func(parent *models.Thought, content string, dirType models.DirectionType, title string) *models.Thought {
+		child := models.NewThought(content, session.ID, models.Direction{Type: dirType, Title: title, Description: "desc " + title})
+		parent.AddChild(child)
+		return child
+	}
+	solar := add(session.RootThought, "Solar \"PV\" économie", models.Broad, "太阳能")
+	add(solar, "Perovskite cells — 钙钛矿", models.Deep, "Materials")
+	grid := add(solar, "Grid 'parity' & subsidies", models.Critical, "Policy")
+	add(grid, "Net metering 🔌", models.Lateral, "Tariffs")
+	add(session.RootThought, "Wind offshore", models.Lateral, "Wind")
+
+	data, err := models.SessionToOPML(session)
+	if err != nil {
+		t.Fatalf("SessionToOPML failed: %v", err)
+	}
+
+	parsed, err := models.ParseOPML(data)
+	if err != nil {
+		t.Fatalf("ParseOPML failed: %v\n%s", err, data)
+	}
+	if parsed.ID == session.ID || parsed.RootThought.ID == session.RootThought.ID {
+		t.Fatalf("expected imported session to receive fresh ids")
+	}
+
+	want := describeTree(session)
+	got := describeTree(parsed)
+	if fmt.Sprint(want) != fmt.Sprint(got) {
+		t.Fatalf("round trip mismatch\nwant %v\ngot  %v", want, got)
+	}
+
+	views := parsed.FlattenThoughts(models.FlattenOptions{})
+	if views[1].Direction.Description != "desc 太阳能" {
+		t.Fatalf("expected direction description to survive, got %q", views[1].Direction.Description)
+	}
+}
+
+func TestParseOPMLHandlesForeignOutlines(t *testing.T) {
+	data := []byte(`<?xml version="1.0"?>
+<opml version="1.0">
+  <head><title>Reading list</title></head>
+  <body>
+    <outline text="Books" _status="checked" icon="book">
+      <outline title="Dune"/>
+    </outline>
+    <outline text="Papers" type="link" url="https://example.com"/>
+    <outline text="   "/>
+  </body>
+</opml>`)
+
+	session, err := models.ParseOPML(data)
+	if err != nil {
+		t.Fatalf("ParseOPML failed: %v", err)
+	}
+	contents := flattenedContents(session.FlattenThoughts(models.FlattenOptions{}))
+	if strings.Join(contents, ",") != "Reading list,Books,Dune,Papers" {
+		t.Fatalf("unexpected imported tree: %v", contents)
+	}
+}
+
+func TestParseOPMLRejectsInvalidInput(t *testing.T) {
+	deep := strings.Repeat(`<outline text="x">`, models.MaxOPMLDepth+2) + strings.Repeat(`</outline>`, models.MaxOPMLDepth+2)
+	cases := map[string]string{
+		"malformed": `<opml><body><outline text="a"></body>`,
+		"empty":     `<opml version="2.0"><head/><body/></opml>`,
+		"too deep":  `<opml version="2.0"><body>` + deep + `</body></opml>`,
+	}
+	for name, input := range cases {
+		if _, err := models.ParseOPML([]byte(input)); !errors.Is(err, appErrors.ErrInvalidRequest) {
+			t.Fatalf("%s: expected invalid request error, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+//User Profile(用户画像)
+
+package models
+
+import "strings"
+
+// 枚举类型
+// ThinkingStyle 决定扩展时 LLM 的采样温度。
+type ThinkingStyle string
+
+const (
+	ThinkingFocused  ThinkingStyle = "focused"  // 聚焦分析
+	ThinkingBalanced ThinkingStyle = "balanced" // 平衡
+	ThinkingCreative ThinkingStyle = "creative" // 发散创意
+)
+
+var thinkingStyleTemperatures = map[ThinkingStyle]float64{
+	ThinkingFocused:  0.3,
+	ThinkingBalanced: 0.7,
+	ThinkingCreative: 1.1,
+}
+
+// 结构体
+// UserProfile 汇总用户在会话中表达的目标、偏好与背景，用于个性化推荐。
+type UserProfile struct {
+	UserID      string   `json:"userId"`
+	Goals       []string `json:"goals,omitempty"`
+	Preferences []string `json:"preferences,omitempty"`
+	Background  []string `json:"background,omitempty"`
+	// ThinkingStyle 来自 "thinking style: creative" 形式的偏好条目，多条时以最后一条为准。
+	ThinkingStyle ThinkingStyle `json:"thinkingStyle,omitempty"`
+}
+
+// 方法
+// Temperature 返回思考风格对应的温度；未知风格返回 false。
+func (s ThinkingStyle) Temperature() (float64, bool) {
+	temperature, ok := thinkingStyleTemperatures[s]
+	return temperature, ok
+}
+
+// IsValid 判断是否为受支持的思考风格。
+func (s ThinkingStyle) IsValid() bool {
+	_, ok := thinkingStyleTemperatures[s]
+	return ok
+}
+
+// 函数
+// NewUserProfile 从会话的上下文条目中提取用户画像；session 为 nil 时返回 nil。
+func NewUserProfile(session *Session) *UserProfile {
+	if session == nil {
+		return nil
+	}
+	session.EnsureContextEntries()
+
+	profile := &UserProfile{UserID: session.UserID}
+	for _, entry := range session.ContextEntries {
+		switch entry.Kind {
+		case ContextGoal:
+			profile.Goals = append(profile.Goals, entry.Value)
+		case ContextPreference:
+			profile.Preferences = append(profile.Preferences, entry.Value)
+			if style, ok := thinkingStyleFromPreference(entry.Value); ok {
+				profile.ThinkingStyle = style
+			}
+		case ContextBackground:
+			profile.Background = append(profile.Background, entry.Value)
+		}
+	}
+	return profile
+}
+
+// thinkingStyleFromPreference 解析 "thinking style: creative" 或 "thinking_style: creative" 形式的偏好。
+func thinkingStyleFromPreference(value string) (ThinkingStyle, bool) {
+	key, raw, found := strings.Cut(value, ":")
+	if !found {
+		return "", false
+	}
+	key = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(key)), "_", " ")
+	if key != "thinking style" {
+		return "", false
+	}
+	style := ThinkingStyle(strings.ToLower(strings.TrimSpace(raw)))
+	return style, style.IsValid()
+}
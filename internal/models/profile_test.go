@@ -0,0 +1,46 @@
+package models_test
+
+import (
+	"testing"
+
+	"WideMindsMCP/internal/models"
+)
+
+func TestNewUserProfileReadsThinkingStyle(t *testing.T) {
+	session := models.NewSession("user", "Batteries")
+	session.ContextEntries = []models.ContextEntry{
+		models.NewContextEntry(models.ContextPreference, "thinking style: focused"),
+		models.NewContextEntry(models.ContextPreference, "short answers"),
+		models.NewContextEntry(models.ContextPreference, "Thinking_Style: Creative"),
+		models.NewContextEntry(models.ContextNote, "thinking style: balanced"),
+	}
+
+	profile := models.NewUserProfile(session)
+	if profile.ThinkingStyle != models.ThinkingCreative {
+		t.Fatalf("expected the last preference to win, got %q", profile.ThinkingStyle)
+	}
+	if len(profile.Preferences) != 3 {
+		t.Fatalf("expected style preferences to stay in the profile, got %v", profile.Preferences)
+	}
+
+	session.ContextEntries = []models.ContextEntry{models.NewContextEntry(models.ContextPreference, "thinking style: chaotic")}
+	if style := models.NewUserProfile(session).ThinkingStyle; style != "" {
+		t.Fatalf("expected unknown styles to be ignored, got %q", style)
+	}
+}
+
+func TestThinkingStyleTemperature(t *testing.T) {
+	cases := map[models.ThinkingStyle]float64{
+		models.ThinkingFocused:  0.3,
+		models.ThinkingBalanced: 0.7,
+		models.ThinkingCreative: 1.1,
+	}
+	for style, expected := range cases {
+		if temperature, ok := style.Temperature(); !ok || temperature != expected {
+			t.Fatalf("%s: expected %.1f, got %.1f (%v)", style, expected, temperature, ok)
+		}
+	}
+	if _, ok := models.ThinkingStyle("chaotic").Temperature(); ok {
+		t.Fatalf("expected unknown style to have no temperature")
+	}
+}
@@ -0,0 +1,148 @@
+//Session Readiness(会话总结就绪度)
+
+package models
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// 常量
+const (
+	ReadinessTargetThoughts      = 10
+	ReadinessTargetAverageDepth  = 2.0
+	ReadinessConfidenceThreshold = 0.7
+
+	CriterionThoughtCount       = "thought_count"
+	CriterionDirectionDiversity = "direction_diversity"
+	CriterionAverageDepth       = "average_depth"
+	CriterionHighConfidence     = "high_confidence"
+)
+
+var readinessDirectionTypes = []DirectionType{Broad, Deep, Lateral, Critical}
+
+// 结构体
+// CriterionResult 是单项就绪标准的评估结果，Score 取值 0-1。
+type CriterionResult struct {
+	Name   string  `json:"name"`
+	Met    bool    `json:"met"`
+	Score  float64 `json:"score"`
+	Detail string  `json:"detail"`
+}
+
+// ReadinessReport 评估会话是否已探索充分、可以整理成文；Score 为各项标准得分的平均值。
+type ReadinessReport struct {
+	Score          float64           `json:"score"`
+	Criteria       []CriterionResult `json:"criteria"`
+	Recommendation string            `json:"recommendation"`
+}
+
+// 方法
+// ReadinessScore 仅根据会话数据计算就绪度：思维数量、方向类型覆盖、平均深度与是否存在高置信度思维，均不计根节点。
+func (s *Session) ReadinessScore() ReadinessReport {
+	var (
+		count      int
+		depthTotal int
+		confident  int
+		types      = map[DirectionType]struct{}{}
+	)
+	walkThoughtTree(s, func(thought, parent *Thought) {
+		if parent == nil {
+			return
+		}
+		count++
+		depthTotal += thought.Depth
+		if thought.Confidence > ReadinessConfidenceThreshold {
+			confident++
+		}
+		if thought.Direction.Type != "" {
+			types[thought.Direction.Type] = struct{}{}
+		}
+	})
+
+	var missing []DirectionType
+	for _, dirType := range readinessDirectionTypes {
+		if _, ok := types[dirType]; !ok {
+			missing = append(missing, dirType)
+		}
+	}
+	averageDepth := 0.0
+	if count > 0 {
+		averageDepth = float64(depthTotal) / float64(count)
+	}
+
+	criteria := []CriterionResult{
+		{
+			Name:   CriterionThoughtCount,
+			Met:    count >= ReadinessTargetThoughts,
+			Score:  math.Min(1, float64(count)/ReadinessTargetThoughts),
+			Detail: fmt.Sprintf("%d of %d thoughts", count, ReadinessTargetThoughts),
+		},
+		{
+			Name:   CriterionDirectionDiversity,
+			Met:    len(missing) == 0,
+			Score:  float64(len(readinessDirectionTypes)-len(missing)) / float64(len(readinessDirectionTypes)),
+			Detail: fmt.Sprintf("%d of %d direction types explored", len(readinessDirectionTypes)-len(missing), len(readinessDirectionTypes)),
+		},
+		{
+			Name:   CriterionAverageDepth,
+			Met:    averageDepth >= ReadinessTargetAverageDepth,
+			Score:  math.Min(1, averageDepth/ReadinessTargetAverageDepth),
+			Detail: fmt.Sprintf("average depth %.1f (target %.0f)", averageDepth, ReadinessTargetAverageDepth),
+		},
+		{
+			Name:   CriterionHighConfidence,
+			Met:    confident > 0,
+			Score:  math.Min(1, float64(confident)),
+			Detail: fmt.Sprintf("%d thoughts with confidence above %.1f", confident, ReadinessConfidenceThreshold),
+		},
+	}
+
+	total := 0.0
+	for _, criterion := range criteria {
+		total += criterion.Score
+	}
+	return ReadinessReport{
+		Score:          math.Round(total/float64(len(criteria))*100) / 100,
+		Criteria:       criteria,
+		Recommendation: readinessRecommendation(criteria, missing, count),
+	}
+}
+
+// 函数
+// readinessRecommendation 针对第一个未满足的标准给出建议，全部满足时提示可以开始总结。
+func readinessRecommendation(criteria []CriterionResult, missing []DirectionType, count int) string {
+	for _, criterion := range criteria {
+		if criterion.Met {
+			continue
+		}
+		switch criterion.Name {
+		case CriterionThoughtCount:
+			return fmt.Sprintf("Add about %d more thoughts before summarizing.", ReadinessTargetThoughts-count)
+		case CriterionDirectionDiversity:
+			names := make([]string, len(missing))
+			for i, dirType := range missing {
+				names[i] = string(dirType)
+			}
+			return fmt.Sprintf("Consider exploring %s perspectives before summarizing.", JoinWithAnd(names))
+		case CriterionAverageDepth:
+			return "Deepen a few branches so the ideas are developed beyond first impressions."
+		case CriterionHighConfidence:
+			return "Mark the thoughts you are confident about (confidence above 0.7) to anchor the summary."
+		}
+	}
+	return "The session is well explored and ready to summarize."
+}
+
+// JoinWithAnd 以英文列举形式连接 items，如 "a, b and c"。
+func JoinWithAnd(items []string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	default:
+		return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+	}
+}
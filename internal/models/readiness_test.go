@@ -0,0 +1,61 @@
+package models_test
+
+import (
+	"testing"
+
+	"WideMindsMCP/internal/models"
+)
+
+func TestReadinessScoreForEmptySession(t *testing.T) {
+	session := models.NewSession("user", "Batteries")
+
+	report := session.ReadinessScore()
+	if report.Score != 0 || len(report.Criteria) != 4 {
+		t.Fatalf("expected zero score across four criteria, got %+v", report)
+	}
+	for _, criterion := range report.Criteria {
+		if criterion.Met {
+			t.Fatalf("expected no criterion to be met, got %+v", criterion)
+		}
+	}
+	if report.Recommendation != "Add about 10 more thoughts before summarizing." {
+		t.Fatalf("unexpected recommendation %q", report.Recommendation)
+	}
+}
+
+func TestReadinessScoreRecommendsMissingPerspectives(t *testing.T) {
+	session := models.NewSession("user", "Batteries")
+	types := []models.DirectionType{models.Broad, models.Deep, models.Lateral}
+	for i := 0; i < 5; i++ {
+		parent := models.NewThought("branch", session.ID, models.Direction{Type: types[i%len(types)]})
+		session.RootThought.AddChild(parent)
+		child := models.NewThought("detail", session.ID, models.Direction{Type: models.Deep})
+		parent.AddChild(child)
+		grandchild := models.NewThought("insight", session.ID, models.Direction{Type: models.Deep})
+		child.AddChild(grandchild)
+		if i == 0 {
+			grandchild.Confidence = 0.9
+		}
+	}
+
+	report := session.ReadinessScore()
+	met := map[string]bool{}
+	for _, criterion := range report.Criteria {
+		met[criterion.Name] = criterion.Met
+	}
+	if !met[models.CriterionThoughtCount] || met[models.CriterionDirectionDiversity] || !met[models.CriterionAverageDepth] || !met[models.CriterionHighConfidence] {
+		t.Fatalf("unexpected criteria %+v", report.Criteria)
+	}
+	if report.Score != 0.94 {
+		t.Fatalf("expected score 0.94, got %v", report.Score)
+	}
+	if report.Recommendation != "Consider exploring critical perspectives before summarizing." {
+		t.Fatalf("unexpected recommendation %q", report.Recommendation)
+	}
+
+	deepest := session.RootThought.Children[0].Children[0].Children[0]
+	deepest.AddChild(models.NewThought("risks", session.ID, models.Direction{Type: models.Critical}))
+	if report := session.ReadinessScore(); report.Score != 1 || report.Recommendation != "The session is well explored and ready to summarize." {
+		t.Fatalf("expected a ready session, got %+v", report)
+	}
+}
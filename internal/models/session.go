@@ -3,10 +3,16 @@
 package models
 
 import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	appErrors "WideMindsMCP/internal/errors"
 
@@ -15,13 +21,25 @@ import (
 
 // 结构体
 type Session struct {
-	ID          string    `json:"id"`
-	UserID      string    `json:"userId"`
-	RootThought *Thought  `json:"rootThought,omitempty"`
-	Context     []string  `json:"context,omitempty"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
-	IsActive    bool      `json:"isActive"`
+	ID          string   `json:"id"`
+	UserID      string   `json:"userId"`
+	RootThought *Thought `json:"rootThought,omitempty"`
+	Context     []string `json:"context,omitempty"`
+	// ContextEntries 是 Context 的结构化形式；Context 仅为兼容旧数据保留。
+	ContextEntries []ContextEntry `json:"contextEntries,omitempty"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	UpdatedAt      time.Time      `json:"updatedAt"`
+	IsActive       bool           `json:"isActive"`
+	// Tags 是会话级标签，可手动设置或由 LLM 自动生成。
+	Tags []string `json:"tags,omitempty"`
+	// ActivityLog 记录会话的近期变更（最多 MaxActivityEntries 条），随会话一起持久化与导出。
+	ActivityLog []ActivityEntry `json:"activityLog,omitempty"`
+	// Embedding 是会话内容的向量表示，首次用于相似度检索时生成；EmbeddingKey 标识生成它的模型与内容。
+	// 两者不出现在 API 响应与导出中，仅由存储层经 WriteStoredJSON 持久化。
+	Embedding    []float64 `json:"-"`
+	EmbeddingKey string    `json:"-"`
+	// Usage 累计与该会话相关的 LLM 调用用量与费用，撤销操作不会回退。
+	Usage SessionUsage `json:"usage"`
 }
 
 func (s *Session) FindThought(thoughtID string) (*Thought, *Thought) {
@@ -106,6 +124,18 @@ func (s *Session) ApplyThoughtUpdate(thoughtID string, update *ThoughtUpdate) (*
 	if update.Direction != nil {
 		target.Direction = *update.Direction
 	}
+	if update.Tags != nil {
+		target.Tags = append([]string(nil), (*update.Tags)...)
+	}
+	if update.Confidence != nil {
+		target.Confidence = *update.Confidence
+	}
+	if update.ExternalID != nil {
+		target.ExternalID = strings.TrimSpace(*update.ExternalID)
+	}
+	if update.ExternalSystemURL != nil {
+		target.ExternalSystemURL = strings.TrimSpace(*update.ExternalSystemURL)
+	}
 
 	s.NormalizeTree()
 	s.UpdatedAt = time.Now().UTC()
@@ -113,6 +143,22 @@ func (s *Session) ApplyThoughtUpdate(thoughtID string, update *ThoughtUpdate) (*
 	return target, nil
 }
 
+// FindThoughtByExternalID 按外部系统ID查找思维（深度优先，返回第一个匹配）。
+func (s *Session) FindThoughtByExternalID(externalID string) *Thought {
+	externalID = strings.TrimSpace(externalID)
+	if externalID == "" {
+		return nil
+	}
+
+	var found *Thought
+	walkThoughtTree(s, func(thought, _ *Thought) {
+		if found == nil && thought.ExternalID == externalID {
+			found = thought
+		}
+	})
+	return found
+}
+
 func (s *Session) RemoveThought(thoughtID string) error {
 	if s == nil || strings.TrimSpace(thoughtID) == "" {
 		return appErrors.ErrInvalidRequest
@@ -142,32 +188,199 @@ func (s *Session) RemoveThought(thoughtID string) error {
 	return nil
 }
 
+// SetRootThought 用新思维替换根思维：原根的子思维全部改挂到新根下，原根本身被丢弃。
+func (s *Session) SetRootThought(newRoot *Thought) error {
+	if s == nil || newRoot == nil {
+		return appErrors.ErrInvalidRequest
+	}
+	if _, err := uuid.Parse(newRoot.ID); err != nil {
+		return fmt.Errorf("%w: root thought id must be a valid UUID", appErrors.ErrInvalidRequest)
+	}
+	if newRoot.ParentID != nil {
+		return fmt.Errorf("%w: root thought must not have a parent", appErrors.ErrInvalidRequest)
+	}
+	if existing, _ := s.FindThought(newRoot.ID); existing != nil {
+		return fmt.Errorf("%w: thought %s is already part of the session", appErrors.ErrInvalidRequest, newRoot.ID)
+	}
+
+	if s.RootThought != nil {
+		for _, child := range s.RootThought.Children {
+			newRoot.AddChild(child)
+		}
+		s.RootThought.Children = nil
+	}
+	newRoot.SessionID = s.ID
+
+	s.RootThought = newRoot
+	s.NormalizeTree()
+	s.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// Compact 自底向上删除方向相关度低于 minRelevance 的叶子思维（子节点被删后成为叶子的也会删除），
+// 根思维始终保留。返回删除的思维数量。
+func (s *Session) Compact(minRelevance float64) int {
+	if s == nil || s.RootThought == nil {
+		return 0
+	}
+
+	var prune func(thought *Thought) int
+	prune = func(thought *Thought) int {
+		removed := 0
+		kept := thought.Children[:0]
+		for _, child := range thought.Children {
+			if child == nil {
+				continue
+			}
+			removed += prune(child)
+			if len(child.Children) == 0 && child.Direction.Relevance < minRelevance {
+				removed++
+				continue
+			}
+			kept = append(kept, child)
+		}
+		for i := len(kept); i < len(thought.Children); i++ {
+			thought.Children[i] = nil
+		}
+		thought.Children = kept
+		return removed
+	}
+
+	removed := prune(s.RootThought)
+	if removed > 0 {
+		s.NormalizeTree()
+		s.UpdatedAt = time.Now().UTC()
+	}
+	return removed
+}
+
+// SplitThought 将思维内容替换为 parts[0]，其余片段作为继承相同方向的子思维追加。
+func (s *Session) SplitThought(thoughtID string, parts []string) (*Thought, error) {
+	if s == nil || strings.TrimSpace(thoughtID) == "" || len(parts) < 2 {
+		return nil, appErrors.ErrInvalidRequest
+	}
+
+	target, _ := s.FindThought(thoughtID)
+	if target == nil {
+		return nil, fmt.Errorf("%w: %s", appErrors.ErrThoughtNotFound, thoughtID)
+	}
+
+	target.Content = strings.TrimSpace(parts[0])
+	for _, part := range parts[1:] {
+		child := NewThought(strings.TrimSpace(part), s.ID, target.Direction.Clone())
+		target.AddChild(child)
+	}
+
+	s.NormalizeTree()
+	s.UpdatedAt = time.Now().UTC()
+	return target, nil
+}
+
+// DefaultMergeSeparator 为合并思维内容时的默认分隔符。
+const DefaultMergeSeparator = " | "
+
+// MergeThoughts 将 siblingID 的内容并入 targetID 并移除该兄弟节点。两者必须拥有相同父节点；
+// 任一节点有子节点时需 force，此时兄弟节点的子节点会挂到目标节点下。
+func (s *Session) MergeThoughts(targetID, siblingID, separator string, force bool) (*Thought, error) {
+	if s == nil || strings.TrimSpace(targetID) == "" || strings.TrimSpace(siblingID) == "" {
+		return nil, appErrors.ErrInvalidRequest
+	}
+	if targetID == siblingID {
+		return nil, fmt.Errorf("%w: cannot merge a thought with itself", appErrors.ErrInvalidRequest)
+	}
+
+	target, targetParent := s.FindThought(targetID)
+	if target == nil {
+		return nil, fmt.Errorf("%w: %s", appErrors.ErrThoughtNotFound, targetID)
+	}
+	sibling, siblingParent := s.FindThought(siblingID)
+	if sibling == nil {
+		return nil, fmt.Errorf("%w: %s", appErrors.ErrThoughtNotFound, siblingID)
+	}
+	if targetParent == nil || siblingParent == nil || targetParent.ID != siblingParent.ID {
+		return nil, fmt.Errorf("%w: thoughts must share the same parent", appErrors.ErrInvalidRequest)
+	}
+	if !force && (len(target.Children) > 0 || len(sibling.Children) > 0) {
+		return nil, fmt.Errorf("%w: thoughts with children can only be merged with force", appErrors.ErrInvalidRequest)
+	}
+
+	if separator == "" {
+		separator = DefaultMergeSeparator
+	}
+	target.Content = target.Content + separator + sibling.Content
+	target.Children = append(target.Children, sibling.Children...)
+	targetParent.RemoveChildByID(sibling.ID)
+
+	s.NormalizeTree()
+	s.UpdatedAt = time.Now().UTC()
+	return target, nil
+}
+
+type TraversalOrder string
+
+const (
+	TraversalDFS TraversalOrder = "dfs" // 深度优先（先序）
+	TraversalBFS TraversalOrder = "bfs" // 广度优先
+)
+
+// FlattenOptions 控制思维树的线性化方式。MaxDepth <= 0 表示不限制深度。
+type FlattenOptions struct {
+	Order       TraversalOrder `json:"order,omitempty"`
+	MaxDepth    int            `json:"maxDepth,omitempty"`
+	ExcludeRoot bool           `json:"excludeRoot,omitempty"`
+}
+
+// ThoughtView 是思维节点的扁平只读副本。
+type ThoughtView struct {
+	ID        string    `json:"id"`
+	ParentID  string    `json:"parentId,omitempty"`
+	Depth     int       `json:"depth"`
+	Content   string    `json:"content"`
+	Direction Direction `json:"direction"`
+	Path      string    `json:"path"`
+}
+
+// ThoughtPathSeparator 用于拼接 ThoughtView.Path。
+const ThoughtPathSeparator = " > "
+
 type SessionMetadata struct {
-	TotalThoughts int      `json:"totalThoughts"`
-	MaxDepth      int      `json:"maxDepth"`
-	Directions    []string `json:"directions"`
+	TotalThoughts int          `json:"totalThoughts"`
+	LLMThoughts   int          `json:"llmThoughts"`
+	UserThoughts  int          `json:"userThoughts"`
+	MaxDepth      int          `json:"maxDepth"`
+	Directions    []string     `json:"directions"`
+	Usage         SessionUsage `json:"usage"`
+	// Complexity 见 Session.EstimateComplexity
+	Complexity int `json:"complexity"`
 }
 
-// 方法
-func NewSession(userID, initialConcept string) *Session {
-	sessionID := uuid.NewString()
-	now := time.Now().UTC()
-	direction := Direction{
+// 函数
+// RootDirection 返回根思维使用的默认方向。
+func RootDirection() Direction {
+	return Direction{
 		Type:        Broad,
 		Title:       "Root",
 		Description: "Initial concept",
 	}
+}
 
-	rootThought := NewThought(initialConcept, sessionID, direction)
+// 方法
+func NewSession(userID, initialConcept string) *Session {
+	sessionID := uuid.NewString()
+	now := time.Now().UTC()
+	rootThought := NewThought(initialConcept, sessionID, RootDirection())
 
 	return &Session{
 		ID:          sessionID,
 		UserID:      userID,
 		RootThought: rootThought,
 		Context:     []string{initialConcept},
-		CreatedAt:   now,
-		UpdatedAt:   now,
-		IsActive:    true,
+		ContextEntries: []ContextEntry{
+			NewContextEntry(ContextNote, initialConcept),
+		},
+		CreatedAt: now,
+		UpdatedAt: now,
+		IsActive:  true,
 	}
 }
 
@@ -176,17 +389,89 @@ func (s *Session) AddContext(context string) {
 		return
 	}
 
+	s.EnsureContextEntries()
 	s.Context = append(s.Context, context)
+	s.ContextEntries = append(s.ContextEntries, ParseContextEntry(context))
+	s.UpdatedAt = time.Now().UTC()
+}
+
+func (s *Session) AddContextEntry(entry ContextEntry) {
+	if s == nil || strings.TrimSpace(entry.Value) == "" {
+		return
+	}
+	if entry.Kind == "" {
+		entry.Kind = ContextNote
+	}
+
+	s.EnsureContextEntries()
+	s.ContextEntries = append(s.ContextEntries, entry)
+	s.Context = append(s.Context, entry.String())
 	s.UpdatedAt = time.Now().UTC()
 }
 
+// RemoveContextAt 删除指定位置的上下文条目，其余条目保持原有顺序。
+func (s *Session) RemoveContextAt(index int) error {
+	if err := s.checkContextIndex(index); err != nil {
+		return err
+	}
+
+	s.ContextEntries = append(s.ContextEntries[:index], s.ContextEntries[index+1:]...)
+	s.Context = ContextStrings(s.ContextEntries)
+	s.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// ReplaceContextAt 替换指定位置的上下文条目；entry.Kind 为空时沿用原条目的类型。
+func (s *Session) ReplaceContextAt(index int, entry ContextEntry) error {
+	if err := s.checkContextIndex(index); err != nil {
+		return err
+	}
+	entry.Value = strings.TrimSpace(entry.Value)
+	if entry.Value == "" {
+		return fmt.Errorf("%w: context value is required", appErrors.ErrInvalidRequest)
+	}
+	if entry.Kind == "" {
+		entry.Kind = s.ContextEntries[index].Kind
+	}
+
+	s.ContextEntries[index] = entry
+	s.Context = ContextStrings(s.ContextEntries)
+	s.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+func (s *Session) checkContextIndex(index int) error {
+	if s == nil {
+		return appErrors.ErrInvalidRequest
+	}
+	s.EnsureContextEntries()
+	if index < 0 || index >= len(s.ContextEntries) {
+		return fmt.Errorf("%w: context index %d out of range (0-%d)", appErrors.ErrInvalidRequest, index, len(s.ContextEntries)-1)
+	}
+	return nil
+}
+
+// EnsureContextEntries 将旧版字符串上下文迁移为结构化条目（仅执行一次）。
+func (s *Session) EnsureContextEntries() {
+	if s == nil || len(s.ContextEntries) > 0 || len(s.Context) == 0 {
+		return
+	}
+	s.ContextEntries = ParseContextEntries(s.Context)
+}
+
 func (s *Session) GetMetadata() *SessionMetadata {
-	if s == nil || s.RootThought == nil {
+	if s == nil {
 		return &SessionMetadata{}
 	}
+	if s.RootThought == nil {
+		return &SessionMetadata{Usage: s.Usage}
+	}
 
 	total := 0
+	generated := 0
 	maxDepth := 0
+	linked := 0
+	annotations := 0
 	directionSet := map[string]struct{}{}
 
 	queue := []*Thought{s.RootThought}
@@ -195,9 +480,16 @@ func (s *Session) GetMetadata() *SessionMetadata {
 		queue = queue[1:]
 
 		total++
+		if thought.IsGenerated() {
+			generated++
+		}
 		if thought.Depth > maxDepth {
 			maxDepth = thought.Depth
 		}
+		if thought.ExternalID != "" || thought.ExternalSystemURL != "" {
+			linked++
+		}
+		annotations += len(thought.Annotations)
 		key := thought.Direction.Title
 		if key == "" {
 			key = string(thought.Direction.Type)
@@ -221,11 +513,148 @@ func (s *Session) GetMetadata() *SessionMetadata {
 
 	return &SessionMetadata{
 		TotalThoughts: total,
+		LLMThoughts:   generated,
+		UserThoughts:  total - generated,
 		MaxDepth:      maxDepth,
 		Directions:    directions,
+		Usage:         s.Usage,
+		Complexity:    complexityScore(total, maxDepth, len(directions), linked, annotations),
 	}
 }
 
+// FlattenThoughts 按指定顺序返回思维树的扁平副本，修改结果不会影响原树。
+func (s *Session) FlattenThoughts(opts FlattenOptions) []*ThoughtView {
+	if s == nil || s.RootThought == nil {
+		return []*ThoughtView{}
+	}
+
+	type item struct {
+		thought  *Thought
+		parentID string
+		depth    int
+		path     []string
+	}
+
+	views := make([]*ThoughtView, 0, 16)
+	visit := func(current item) {
+		if opts.ExcludeRoot && current.depth == 0 {
+			return
+		}
+		views = append(views, &ThoughtView{
+			ID:        current.thought.ID,
+			ParentID:  current.parentID,
+			Depth:     current.depth,
+			Content:   current.thought.Content,
+			Direction: current.thought.Direction.Clone(),
+			Path:      strings.Join(current.path, ThoughtPathSeparator),
+		})
+	}
+	childrenOf := func(current item) []item {
+		if opts.MaxDepth > 0 && current.depth >= opts.MaxDepth {
+			return nil
+		}
+		children := make([]item, 0, len(current.thought.Children))
+		for _, child := range current.thought.Children {
+			if child == nil {
+				continue
+			}
+			children = append(children, item{
+				thought:  child,
+				parentID: current.thought.ID,
+				depth:    current.depth + 1,
+				path:     append(append([]string{}, current.path...), child.Content),
+			})
+		}
+		return children
+	}
+
+	root := item{thought: s.RootThought, path: []string{s.RootThought.Content}}
+	if opts.Order == TraversalBFS {
+		queue := []item{root}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			visit(current)
+			queue = append(queue, childrenOf(current)...)
+		}
+		return views
+	}
+
+	stack := []item{root}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		visit(current)
+		children := childrenOf(current)
+		for i := len(children) - 1; i >= 0; i-- {
+			stack = append(stack, children[i])
+		}
+	}
+	return views
+}
+
+// CSVHeader 列出 ToCSV 输出的列。
+var CSVHeader = []string{"id", "content", "depth", "path", "direction_type", "direction_title", "relevance", "created_at", "tags", "confidence"}
+
+// ToCSV 将全部思维节点按深度优先顺序导出为 RFC 4180 CSV。
+func (s *Session) ToCSV() ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(CSVHeader); err != nil {
+		return nil, err
+	}
+
+	if s != nil {
+		tree := s.GetThoughtTree()
+		for _, view := range s.FlattenThoughts(FlattenOptions{Order: TraversalDFS}) {
+			thought := tree[view.ID]
+			if thought == nil {
+				continue
+			}
+			record := []string{
+				view.ID,
+				view.Content,
+				strconv.Itoa(view.Depth),
+				strings.Join(thought.GetPath(), "|"),
+				string(view.Direction.Type),
+				view.Direction.Title,
+				strconv.FormatFloat(view.Direction.Relevance, 'f', -1, 64),
+				thought.CreatedAt.UTC().Format(time.RFC3339),
+				strings.Join(thought.Tags, "|"),
+				strconv.FormatFloat(thought.Confidence, 'f', -1, 64),
+			}
+			if err := writer.Write(record); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Clone 返回会话的深拷贝，思维树的父子关系会重新建立。
+func (s *Session) Clone() *Session {
+	if s == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(s)
+	if err != nil {
+		return nil
+	}
+	var clone Session
+	if err := json.Unmarshal(payload, &clone); err != nil {
+		return nil
+	}
+	clone.NormalizeTree()
+	clone.EnsureContextEntries()
+	return &clone
+}
+
 func (s *Session) Close() {
 	if s == nil {
 		return
@@ -235,6 +664,64 @@ func (s *Session) Close() {
 	s.UpdatedAt = time.Now().UTC()
 }
 
+// WordCount 是词云中的一项。
+type WordCount struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+// WordCloud 统计所有节点内容与方向关键词中各词出现的次数；词统一转为小写，
+// 忽略 stopWords 中的词与单个字符。
+func (s *Session) WordCloud(stopWords []string) map[string]int {
+	counts := map[string]int{}
+	if s == nil || s.RootThought == nil {
+		return counts
+	}
+
+	ignored := make(map[string]struct{}, len(stopWords))
+	for _, word := range stopWords {
+		ignored[strings.ToLower(strings.TrimSpace(word))] = struct{}{}
+	}
+	count := func(text string) {
+		for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if utf8.RuneCountInString(word) < 2 {
+				continue
+			}
+			if _, skip := ignored[word]; skip {
+				continue
+			}
+			counts[word]++
+		}
+	}
+	for _, thought := range s.GetThoughtTree() {
+		count(thought.Content)
+		for _, keyword := range thought.Direction.Keywords {
+			count(keyword)
+		}
+	}
+	return counts
+}
+
+// TopWords 按次数从高到低（次数相同时按字母序）返回前 n 个词；n <= 0 时返回全部。
+func TopWords(counts map[string]int, n int) []WordCount {
+	words := make([]WordCount, 0, len(counts))
+	for word, count := range counts {
+		words = append(words, WordCount{Word: word, Count: count})
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if words[i].Count != words[j].Count {
+			return words[i].Count > words[j].Count
+		}
+		return words[i].Word < words[j].Word
+	})
+	if n > 0 && len(words) > n {
+		words = words[:n]
+	}
+	return words
+}
+
 func (s *Session) GetThoughtTree() map[string]*Thought {
 	if s == nil || s.RootThought == nil {
 		return map[string]*Thought{}
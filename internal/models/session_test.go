@@ -1,8 +1,13 @@
 package models_test
 
 import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"strings"
 	"testing"
 
+	appErrors "WideMindsMCP/internal/errors"
 	"WideMindsMCP/internal/models"
 )
 
@@ -100,3 +105,310 @@ func TestSessionRemoveThought(t *testing.T) {
 		t.Fatalf("expected root thought to be nil after root removal")
 	}
 }
+
+func TestSessionCompactKeepsRelevantParents(t *testing.T) {
+	session := models.NewSession("user", "Root")
+	kept := models.NewThought("Kept", session.ID, models.Direction{Type: models.Deep, Title: "Kept", Relevance: 0.9})
+	weakLeaf := models.NewThought("Weak leaf", session.ID, models.Direction{Type: models.Lateral, Title: "Weak", Relevance: 0.1})
+	weakBranch := models.NewThought("Weak branch", session.ID, models.Direction{Type: models.Broad, Title: "Branch", Relevance: 0.2})
+	weakGrandchild := models.NewThought("Weak grandchild", session.ID, models.Direction{Type: models.Deep, Title: "Grand", Relevance: 0.3})
+	strongGrandchild := models.NewThought("Strong grandchild", session.ID, models.Direction{Type: models.Critical, Title: "Strong", Relevance: 0.8})
+	mixed := models.NewThought("Mixed", session.ID, models.Direction{Type: models.Broad, Title: "Mixed", Relevance: 0.1})
+
+	kept.AddChild(weakLeaf)
+	weakBranch.AddChild(weakGrandchild)
+	mixed.AddChild(strongGrandchild)
+	session.RootThought.AddChild(kept)
+	session.RootThought.AddChild(weakBranch)
+	session.RootThought.AddChild(mixed)
+
+	if removed := session.Compact(0.5); removed != 3 {
+		t.Fatalf("expected 3 thoughts removed, got %d", removed)
+	}
+
+	tree := session.GetThoughtTree()
+	for _, id := range []string{weakLeaf.ID, weakBranch.ID, weakGrandchild.ID} {
+		if _, ok := tree[id]; ok {
+			t.Fatalf("expected thought %s to be removed", id)
+		}
+	}
+	for _, id := range []string{session.RootThought.ID, kept.ID, mixed.ID, strongGrandchild.ID} {
+		if _, ok := tree[id]; !ok {
+			t.Fatalf("expected thought %s to be kept", id)
+		}
+	}
+	if len(kept.Children) != 0 {
+		t.Fatalf("expected kept thought to have no children left, got %d", len(kept.Children))
+	}
+
+	if removed := session.Compact(0.5); removed != 0 {
+		t.Fatalf("expected second compaction to be a no-op, got %d", removed)
+	}
+}
+
+func TestSessionSetRootThought(t *testing.T) {
+	session := buildFlattenFixture()
+	a, b := session.RootThought.Children[0], session.RootThought.Children[1]
+	a1 := a.Children[0]
+
+	newRoot := models.NewThought("Overview", "other-session", models.RootDirection())
+	if err := session.SetRootThought(newRoot); err != nil {
+		t.Fatalf("SetRootThought failed: %v", err)
+	}
+
+	if session.RootThought != newRoot || newRoot.SessionID != session.ID {
+		t.Fatalf("expected new root to be installed for the session")
+	}
+	if len(newRoot.Children) != 2 || newRoot.Children[0] != a || newRoot.Children[1] != b {
+		t.Fatalf("expected root children to be adopted in order")
+	}
+	if a.ParentID == nil || *a.ParentID != newRoot.ID || b.ParentID == nil || *b.ParentID != newRoot.ID {
+		t.Fatalf("expected children to be re-parented to the new root")
+	}
+	if got := strings.Join(a1.Path, " > "); got != "Overview > A > A1" || a1.Depth != 2 {
+		t.Fatalf("expected descendant path to be updated, got %q at depth %d", got, a1.Depth)
+	}
+	if _, ok := session.GetThoughtTree()[newRoot.ID]; !ok || session.GetMetadata().TotalThoughts != 6 {
+		t.Fatalf("expected the old root to be replaced, got %d thoughts", session.GetMetadata().TotalThoughts)
+	}
+}
+
+func TestSessionSetRootThoughtValidation(t *testing.T) {
+	session := buildFlattenFixture()
+	child := session.RootThought.Children[0]
+
+	invalidID := models.NewThought("X", session.ID, models.RootDirection())
+	invalidID.ID = "not-a-uuid"
+	withParent := models.NewThought("Y", session.ID, models.RootDirection())
+	withParent.ParentID = &child.ID
+
+	for name, root := range map[string]*models.Thought{
+		"nil":        nil,
+		"invalid id": invalidID,
+		"has parent": withParent,
+		"existing":   session.RootThought,
+	} {
+		if err := session.SetRootThought(root); !errors.Is(err, appErrors.ErrInvalidRequest) {
+			t.Fatalf("%s: expected invalid request, got %v", name, err)
+		}
+	}
+	if session.RootThought.Content != "R" || len(session.RootThought.Children) != 2 {
+		t.Fatalf("expected tree to be untouched after rejected roots")
+	}
+}
+
+func buildFlattenFixture() *models.Session {
+	session := models.NewSession("user", "R")
+	a := models.NewThought("A", session.ID, models.Direction{Type: models.Broad, Title: "A", Keywords: []string{"k"}})
+	b := models.NewThought("B", session.ID, models.Direction{Type: models.Deep, Title: "B"})
+	session.RootThought.AddChild(a)
+	session.RootThought.AddChild(b)
+	a.AddChild(models.NewThought("A1", session.ID, models.Direction{Type: models.Lateral, Title: "A1"}))
+	a.AddChild(models.NewThought("A2", session.ID, models.Direction{Type: models.Critical, Title: "A2"}))
+	b.AddChild(models.NewThought("B1", session.ID, models.Direction{Type: models.Deep, Title: "B1"}))
+	return session
+}
+
+func flattenedContents(views []*models.ThoughtView) []string {
+	contents := make([]string, 0, len(views))
+	for _, view := range views {
+		contents = append(contents, view.Content)
+	}
+	return contents
+}
+
+func TestSessionFlattenThoughtsOrder(t *testing.T) {
+	session := buildFlattenFixture()
+
+	cases := []struct {
+		name     string
+		opts     models.FlattenOptions
+		expected []string
+	}{
+		{"dfs", models.FlattenOptions{Order: models.TraversalDFS}, []string{"R", "A", "A1", "A2", "B", "B1"}},
+		{"bfs", models.FlattenOptions{Order: models.TraversalBFS}, []string{"R", "A", "B", "A1", "A2", "B1"}},
+		{"depth-limited without root", models.FlattenOptions{Order: models.TraversalBFS, MaxDepth: 1, ExcludeRoot: true}, []string{"A", "B"}},
+	}
+
+	for _, tc := range cases {
+		got := flattenedContents(session.FlattenThoughts(tc.opts))
+		if strings.Join(got, ",") != strings.Join(tc.expected, ",") {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+
+	views := session.FlattenThoughts(models.FlattenOptions{})
+	if views[2].Path != "R > A > A1" || views[2].Depth != 2 || views[2].ParentID != views[1].ID {
+		t.Fatalf("unexpected view for A1: %+v", views[2])
+	}
+}
+
+func TestSessionFlattenThoughtsReturnsCopies(t *testing.T) {
+	session := buildFlattenFixture()
+
+	views := session.FlattenThoughts(models.FlattenOptions{})
+	views[1].Content = "mutated"
+	views[1].Direction.Keywords[0] = "mutated"
+
+	child := session.RootThought.Children[0]
+	if child.Content != "A" || child.Direction.Keywords[0] != "k" {
+		t.Fatalf("expected tree to be unaffected by view mutation, got %q / %v", child.Content, child.Direction.Keywords)
+	}
+}
+
+func TestSessionToCSVEscapesSpecialCharacters(t *testing.T) {
+	session := models.NewSession("user", "Root, \"quoted\"")
+	child := models.NewThought("line one\nline two", session.ID, models.Direction{Type: models.Deep, Title: "Deep, dive", Relevance: 0.8})
+	child.Tags = []string{"a", "b"}
+	child.Confidence = 0.75
+	session.RootThought.AddChild(child)
+
+	data, err := session.ToCSV()
+	if err != nil {
+		t.Fatalf("ToCSV returned error: %v", err)
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse produced CSV: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected header plus 2 rows, got %d", len(records))
+	}
+	if strings.Join(records[0], ",") != strings.Join(models.CSVHeader, ",") {
+		t.Fatalf("unexpected header %v", records[0])
+	}
+
+	row := records[2]
+	if row[1] != "line one\nline two" {
+		t.Fatalf("expected multi-line content to round-trip, got %q", row[1])
+	}
+	if row[3] != "Root, \"quoted\"|line one\nline two" {
+		t.Fatalf("unexpected path column %q", row[3])
+	}
+	if row[5] != "Deep, dive" || row[6] != "0.8" || row[8] != "a|b" || row[9] != "0.75" {
+		t.Fatalf("unexpected row %v", row)
+	}
+}
+
+func TestSessionMergeThoughts(t *testing.T) {
+	session := buildFlattenFixture()
+	a := session.RootThought.Children[0]
+	b := session.RootThought.Children[1]
+	a1, a2 := a.Children[0], a.Children[1]
+
+	merged, err := session.MergeThoughts(a1.ID, a2.ID, "", false)
+	if err != nil {
+		t.Fatalf("merge leaf siblings failed: %v", err)
+	}
+	if merged.Content != "A1 | A2" || len(a.Children) != 1 {
+		t.Fatalf("unexpected merge result %q with %d siblings", merged.Content, len(a.Children))
+	}
+
+	if _, err := session.MergeThoughts(a1.ID, b.ID, " / ", false); !errors.Is(err, appErrors.ErrInvalidRequest) {
+		t.Fatalf("expected merge across parents to fail, got %v", err)
+	}
+	if _, err := session.MergeThoughts(a.ID, b.ID, " / ", false); !errors.Is(err, appErrors.ErrInvalidRequest) {
+		t.Fatalf("expected merge of thoughts with children to require force, got %v", err)
+	}
+
+	b1 := b.Children[0]
+	if _, err := session.MergeThoughts(a.ID, b.ID, " / ", true); err != nil {
+		t.Fatalf("forced merge failed: %v", err)
+	}
+	if a.Content != "A / B" || len(session.RootThought.Children) != 1 {
+		t.Fatalf("unexpected forced merge result %q", a.Content)
+	}
+	if b1.ParentID == nil || *b1.ParentID != a.ID || b1.Depth != 2 {
+		t.Fatalf("expected B1 to be re-parented under A, got parent %v depth %d", b1.ParentID, b1.Depth)
+	}
+}
+
+func TestSessionExternalReferences(t *testing.T) {
+	session := buildFlattenFixture()
+	b1 := session.RootThought.Children[1].Children[0]
+
+	externalID := " PROJ-7 "
+	externalURL := "https://tracker.example.com/browse/PROJ-7"
+	if _, err := session.ApplyThoughtUpdate(b1.ID, &models.ThoughtUpdate{ExternalID: &externalID, ExternalSystemURL: &externalURL}); err != nil {
+		t.Fatalf("apply external reference failed: %v", err)
+	}
+	if found := session.FindThoughtByExternalID("PROJ-7"); found != b1 || found.ExternalSystemURL != externalURL {
+		t.Fatalf("expected B1 to be found by external id, got %+v", found)
+	}
+
+	cleared := ""
+	if _, err := session.ApplyThoughtUpdate(b1.ID, &models.ThoughtUpdate{ExternalID: &cleared}); err != nil {
+		t.Fatalf("clear external id failed: %v", err)
+	}
+	if found := session.FindThoughtByExternalID("PROJ-7"); found != nil {
+		t.Fatalf("expected cleared external id not to match, got %+v", found)
+	}
+}
+
+func TestSessionRemoveAndReplaceContextAt(t *testing.T) {
+	session := models.NewSession("user-1", "Energy")
+	session.AddContextEntry(models.NewContextEntry(models.ContextGoal, "cut costs"))
+	session.AddContextEntry(models.NewContextEntry(models.ContextBackground, "utility sector"))
+	session.AddContextEntry(models.NewContextEntry(models.ContextPreference, "concise answers"))
+
+	if err := session.RemoveContextAt(1); err != nil {
+		t.Fatalf("RemoveContextAt failed: %v", err)
+	}
+	values := make([]string, 0, len(session.ContextEntries))
+	for _, entry := range session.ContextEntries {
+		values = append(values, entry.Value)
+	}
+	if strings.Join(values, "|") != "Energy|utility sector|concise answers" {
+		t.Fatalf("expected remaining entries to keep their order, got %v", values)
+	}
+	if len(session.Context) != 3 || session.Context[1] != "background: utility sector" {
+		t.Fatalf("expected legacy context to follow the entries, got %v", session.Context)
+	}
+
+	if err := session.ReplaceContextAt(2, models.ContextEntry{Value: "detailed answers"}); err != nil {
+		t.Fatalf("ReplaceContextAt failed: %v", err)
+	}
+	if got := session.ContextEntries[2]; got.Kind != models.ContextPreference || got.Value != "detailed answers" {
+		t.Fatalf("expected replacement to keep the existing kind, got %+v", got)
+	}
+
+	for _, index := range []int{-1, 3} {
+		if err := session.RemoveContextAt(index); !errors.Is(err, appErrors.ErrInvalidRequest) {
+			t.Fatalf("index %d: expected ErrInvalidRequest, got %v", index, err)
+		}
+	}
+	if err := session.ReplaceContextAt(0, models.ContextEntry{Value: "  "}); !errors.Is(err, appErrors.ErrInvalidRequest) {
+		t.Fatalf("expected empty replacement to be rejected, got %v", err)
+	}
+}
+
+func TestSessionWordCloudCountsContentAndKeywords(t *testing.T) {
+	session := models.NewSession("user-1", "Solar energy storage")
+	child := models.NewThought("The storage of solar power at night", session.ID, models.Direction{
+		Type:     models.Deep,
+		Title:    "Storage",
+		Keywords: []string{"battery storage", "Solar"},
+	})
+	session.RootThought.AddChild(child)
+	child.AddChild(models.NewThought("Battery chemistry and a grid", session.ID, models.Direction{Type: models.Broad, Title: "Chemistry"}))
+
+	counts := session.WordCloud([]string{"the", "of", "at", "and"})
+	expected := map[string]int{
+		"solar": 3, "storage": 3, "energy": 1, "power": 1, "night": 1,
+		"battery": 2, "chemistry": 1, "grid": 1,
+	}
+	if len(counts) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, counts)
+	}
+	for word, count := range expected {
+		if counts[word] != count {
+			t.Fatalf("expected %q to appear %d times, got %d (%v)", word, count, counts[word], counts)
+		}
+	}
+
+	top := models.TopWords(counts, 3)
+	if len(top) != 3 || top[0] != (models.WordCount{Word: "solar", Count: 3}) || top[1].Word != "storage" || top[2] != (models.WordCount{Word: "battery", Count: 2}) {
+		t.Fatalf("unexpected top words %+v", top)
+	}
+}
@@ -0,0 +1,13 @@
+//Named Session Snapshots(会话命名快照)
+
+package models
+
+import "time"
+
+// 结构体
+// SessionSnapshot 是用户手动保存的命名会话版本，用于在大幅调整前设置检查点。
+type SessionSnapshot struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	Data      *Session  `json:"data"`
+}
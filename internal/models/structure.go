@@ -0,0 +1,131 @@
+//Session Restructuring(会话重组)
+
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	appErrors "WideMindsMCP/internal/errors"
+)
+
+// 常量
+const MaxStructureGroupLength = 200
+
+// 结构体
+// StructureNode 描述重组后树中的一个节点：ID 引用已有思维，Group 则新建一个分组思维。
+type StructureNode struct {
+	ID       string          `json:"id,omitempty"`
+	Group    string          `json:"group,omitempty"`
+	Children []StructureNode `json:"children,omitempty"`
+}
+
+// StructureSpec 描述重组后的整棵树。RootID 引用已有思维作为根；否则以 Root 为内容新建根思维。
+type StructureSpec struct {
+	RootID   string          `json:"root_id,omitempty"`
+	Root     string          `json:"root,omitempty"`
+	Children []StructureNode `json:"children"`
+}
+
+// 方法
+// Restructure 按规格重建思维树。已有思维保留ID与内容；规格中未出现的思维仍挂在原父节点下
+// （原根挂到新根下），重复或未知的ID被忽略，没有子节点的分组被丢弃。
+func (s *Session) Restructure(spec *StructureSpec) error {
+	if s == nil || s.RootThought == nil || spec == nil {
+		return appErrors.ErrInvalidRequest
+	}
+	if err := validateStructureGroup(spec.Root); err != nil {
+		return err
+	}
+	if err := validateStructureNodes(spec.Children); err != nil {
+		return err
+	}
+
+	ordered := make([]*Thought, 0)
+	index := map[string]*Thought{}
+	parents := map[string]*Thought{}
+	walkThoughtTree(s, func(thought, parent *Thought) {
+		ordered = append(ordered, thought)
+		index[thought.ID] = thought
+		parents[thought.ID] = parent
+	})
+	for _, thought := range ordered {
+		thought.Children = nil
+	}
+
+	used := map[string]bool{}
+	root, ok := index[strings.TrimSpace(spec.RootID)]
+	if !ok {
+		content := strings.TrimSpace(spec.Root)
+		if content == "" {
+			content = s.RootThought.Content
+		}
+		root = NewThought(content, s.ID, Direction{Type: Broad, Title: "Root", Description: "Auto-structured root"})
+	}
+	used[root.ID] = true
+
+	var build func(nodes []StructureNode, parent *Thought)
+	build = func(nodes []StructureNode, parent *Thought) {
+		for _, node := range nodes {
+			id := strings.TrimSpace(node.ID)
+			if thought, ok := index[id]; ok && !used[id] {
+				used[id] = true
+				parent.AddChild(thought)
+				build(node.Children, thought)
+				continue
+			}
+
+			group := strings.TrimSpace(node.Group)
+			if group == "" {
+				// 无法识别的节点：将其子节点提升到当前父节点
+				build(node.Children, parent)
+				continue
+			}
+			groupThought := NewThought(group, s.ID, Direction{Type: Broad, Title: group})
+			build(node.Children, groupThought)
+			if len(groupThought.Children) > 0 {
+				parent.AddChild(groupThought)
+			}
+		}
+	}
+	build(spec.Children, root)
+
+	// 先序遍历保证原父节点先于子节点被放置
+	for _, thought := range ordered {
+		if used[thought.ID] {
+			continue
+		}
+		used[thought.ID] = true
+		if parent := parents[thought.ID]; parent != nil && used[parent.ID] {
+			parent.AddChild(thought)
+		} else {
+			root.AddChild(thought)
+		}
+	}
+
+	s.RootThought = root
+	s.NormalizeTree()
+	s.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+func validateStructureNodes(nodes []StructureNode) error {
+	for _, node := range nodes {
+		if err := validateStructureGroup(node.Group); err != nil {
+			return err
+		}
+		if err := validateStructureNodes(node.Children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateStructureGroup(content string) error {
+	if utf8.RuneCountInString(strings.TrimSpace(content)) > MaxStructureGroupLength {
+		return fmt.Errorf("%w: group title is too long", appErrors.ErrInvalidRequest)
+	}
+	return nil
+}
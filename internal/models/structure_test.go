@@ -0,0 +1,96 @@
+package models_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+)
+
+func TestSessionRestructureGroupsThoughts(t *testing.T) {
+	session := buildFlattenFixture()
+	before := session.GetThoughtTree()
+	byContent := map[string]*models.Thought{}
+	for _, thought := range before {
+		byContent[thought.Content] = thought
+	}
+	oldRoot := session.RootThought
+
+	spec := &models.StructureSpec{
+		Root: "Overview",
+		Children: []models.StructureNode{
+			{Group: "Leaves", Children: []models.StructureNode{
+				{ID: byContent["A1"].ID},
+				{ID: byContent["B1"].ID},
+				{ID: byContent["A1"].ID},
+				{ID: "unknown"},
+			}},
+			{Group: "Empty"},
+			{ID: byContent["A"].ID, Children: []models.StructureNode{{ID: byContent["A2"].ID}}},
+		},
+	}
+	if err := session.Restructure(spec); err != nil {
+		t.Fatalf("restructure failed: %v", err)
+	}
+
+	if session.RootThought.Content != "Overview" || session.RootThought.ParentID != nil {
+		t.Fatalf("unexpected root %+v", session.RootThought)
+	}
+	after := session.GetThoughtTree()
+	for id, thought := range before {
+		moved, ok := after[id]
+		if !ok {
+			t.Fatalf("thought %q was lost", thought.Content)
+		}
+		if moved.Content != thought.Content {
+			t.Fatalf("content of %s changed to %q", id, moved.Content)
+		}
+	}
+
+	got := flattenedContents(session.FlattenThoughts(models.FlattenOptions{Order: models.TraversalDFS}))
+	want := "Overview,Leaves,A1,B1,A,A2,R,B"
+	if strings.Join(got, ",") != want {
+		t.Fatalf("unexpected order %v, want %s", got, want)
+	}
+	if oldRoot.ParentID == nil || *oldRoot.ParentID != session.RootThought.ID {
+		t.Fatalf("expected leftover old root to be attached to the new root")
+	}
+	if len(after) != len(before)+2 {
+		t.Fatalf("expected root and one group to be added, got %d thoughts", len(after))
+	}
+}
+
+func TestSessionRestructureKeepsExistingRoot(t *testing.T) {
+	session := buildFlattenFixture()
+	root := session.RootThought
+	b := root.Children[1]
+
+	if err := session.Restructure(&models.StructureSpec{RootID: root.ID, Children: []models.StructureNode{{ID: b.ID}}}); err != nil {
+		t.Fatalf("restructure failed: %v", err)
+	}
+	if session.RootThought != root {
+		t.Fatalf("expected the existing root to be kept")
+	}
+	if len(root.Children) != 2 || root.Children[0] != b {
+		t.Fatalf("expected B first and A appended, got %d children", len(root.Children))
+	}
+	if len(b.Children) != 1 || len(root.Children[1].Children) != 2 {
+		t.Fatalf("expected unlisted thoughts to stay under their original parents")
+	}
+}
+
+func TestSessionRestructureRejectsLongGroup(t *testing.T) {
+	session := buildFlattenFixture()
+	spec := &models.StructureSpec{Children: []models.StructureNode{
+		{Group: "ok", Children: []models.StructureNode{{Group: strings.Repeat("x", models.MaxStructureGroupLength+1)}}},
+	}}
+
+	if err := session.Restructure(spec); !errors.Is(err, appErrors.ErrInvalidRequest) {
+		t.Fatalf("expected invalid request, got %v", err)
+	}
+	if len(session.RootThought.Children) != 2 || session.RootThought.Content != "R" {
+		t.Fatalf("expected tree to be untouched after a rejected spec")
+	}
+}
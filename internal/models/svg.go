@@ -0,0 +1,148 @@
+//SVG Mind Map Export(SVG 思维导图导出)
+
+package models
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// 常量
+const (
+	DefaultSVGWidth  = 1200
+	DefaultSVGHeight = 800
+	MaxSVGDimension  = 10000
+	SVGLabelMaxRunes = 30
+
+	svgNodeWidth  = 200
+	svgNodeHeight = 40
+	svgGapX       = 24
+	svgGapY       = 60
+	svgMargin     = 20
+)
+
+// 结构体
+type svgNode struct {
+	thought *Thought
+	parent  int
+	slot    float64
+	depth   int
+}
+
+// 方法
+// ToSVG 将思维树渲染为自上而下的 SVG 思维导图：叶子节点按顺序占据横向槽位，父节点居中于子节点之上。
+// 图像按 viewBox 缩放到给定宽高内；节点颜色与 DOT 导出一致，按方向类型区分。
+func (s *Session) ToSVG(width, height int) ([]byte, error) {
+	if width <= 0 || height <= 0 || width > MaxSVGDimension || height > MaxSVGDimension {
+		return nil, fmt.Errorf("svg dimensions must be between 1 and %d, got %dx%d", MaxSVGDimension, width, height)
+	}
+
+	nodes, leaves, maxDepth := layoutSVGTree(s)
+	contentWidth := svgMargin*2 + leaves*(svgNodeWidth+svgGapX) - svgGapX
+	contentHeight := svgMargin*2 + (maxDepth+1)*(svgNodeHeight+svgGapY) - svgGapY
+	if leaves == 0 {
+		contentWidth, contentHeight = width, height
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" preserveAspectRatio="xMidYMid meet" font-family="sans-serif" font-size="13">`+"\n",
+		width, height, contentWidth, contentHeight)
+
+	b.WriteString(`  <g stroke="#9ca3af" stroke-width="1.5">` + "\n")
+	for _, node := range nodes {
+		if node.parent < 0 {
+			continue
+		}
+		parent := nodes[node.parent]
+		px, py := svgNodeOrigin(parent)
+		cx, cy := svgNodeOrigin(node)
+		fmt.Fprintf(&b, `    <line x1="%d" y1="%d" x2="%d" y2="%d"/>`+"\n",
+			px+svgNodeWidth/2, py+svgNodeHeight, cx+svgNodeWidth/2, cy)
+	}
+	b.WriteString("  </g>\n")
+
+	for _, node := range nodes {
+		x, y := svgNodeOrigin(node)
+		color := DirectionTypeColor(node.thought.Direction.Type)
+		fmt.Fprintf(&b, `  <g id="%s">`+"\n", diagramNodeID(node.thought.ID))
+		b.WriteString("    <title>")
+		if err := xml.EscapeText(&b, []byte(node.thought.Content)); err != nil {
+			return nil, err
+		}
+		b.WriteString("</title>\n")
+		fmt.Fprintf(&b, `    <rect x="%d" y="%d" width="%d" height="%d" rx="6" fill="%s" stroke="#6b7280"/>`+"\n",
+			x, y, svgNodeWidth, svgNodeHeight, color)
+		fmt.Fprintf(&b, `    <text x="%d" y="%d" text-anchor="middle" dominant-baseline="middle">`,
+			x+svgNodeWidth/2, y+svgNodeHeight/2)
+		if err := xml.EscapeText(&b, []byte(svgLabel(node.thought.Content))); err != nil {
+			return nil, err
+		}
+		b.WriteString("</text>\n  </g>\n")
+	}
+
+	b.WriteString("</svg>\n")
+	return b.Bytes(), nil
+}
+
+// 函数
+// layoutSVGTree 计算分层布局，返回先序排列的节点、叶子数（即横向槽位数）与最大深度。
+func layoutSVGTree(s *Session) ([]svgNode, int, int) {
+	if s == nil || s.RootThought == nil {
+		return nil, 0, 0
+	}
+
+	var (
+		nodes    []svgNode
+		leaves   int
+		maxDepth int
+	)
+	var place func(thought *Thought, parent, depth int) float64
+	place = func(thought *Thought, parent, depth int) float64 {
+		index := len(nodes)
+		nodes = append(nodes, svgNode{thought: thought, parent: parent, depth: depth})
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+
+		first, last, placed := 0.0, 0.0, false
+		for _, child := range thought.Children {
+			if child == nil {
+				continue
+			}
+			slot := place(child, index, depth+1)
+			if !placed {
+				first, placed = slot, true
+			}
+			last = slot
+		}
+
+		slot := float64(leaves)
+		if placed {
+			slot = (first + last) / 2
+		} else {
+			leaves++
+		}
+		nodes[index].slot = slot
+		return slot
+	}
+	place(s.RootThought, -1, 0)
+	return nodes, leaves, maxDepth
+}
+
+func svgNodeOrigin(node svgNode) (int, int) {
+	x := svgMargin + int(node.slot*float64(svgNodeWidth+svgGapX))
+	y := svgMargin + node.depth*(svgNodeHeight+svgGapY)
+	return x, y
+}
+
+// svgLabel 合并空白并截断到 SVGLabelMaxRunes 个字符（含省略号）。
+func svgLabel(content string) string {
+	label := strings.Join(strings.Fields(content), " ")
+	runes := []rune(label)
+	if len(runes) > SVGLabelMaxRunes {
+		label = string(runes[:SVGLabelMaxRunes-1]) + "…"
+	}
+	return label
+}
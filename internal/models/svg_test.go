@@ -0,0 +1,81 @@
+package models_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"WideMindsMCP/internal/models"
+)
+
+func TestSessionToSVGGolden(t *testing.T) {
+	data, err := buildDiagramFixture().ToSVG(models.DefaultSVGWidth, models.DefaultSVGHeight)
+	if err != nil {
+		t.Fatalf("ToSVG failed: %v", err)
+	}
+	assertGolden(t, "session.svg", string(data))
+}
+
+func TestSessionToSVGIsWellFormed(t *testing.T) {
+	session := buildDiagramFixture()
+	data, err := session.ToSVG(640, 480)
+	if err != nil {
+		t.Fatalf("ToSVG failed: %v", err)
+	}
+
+	counts := map[string]int{}
+	var texts []string
+	inText := false
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("svg is not well-formed XML: %v", err)
+		}
+		switch tok := token.(type) {
+		case xml.StartElement:
+			counts[tok.Name.Local]++
+			inText = tok.Name.Local == "text"
+			if tok.Name.Local == "svg" {
+				for _, attr := range tok.Attr {
+					if (attr.Name.Local == "width" && attr.Value != "640") || (attr.Name.Local == "height" && attr.Value != "480") {
+						t.Fatalf("expected requested size, got %s=%s", attr.Name.Local, attr.Value)
+					}
+				}
+			}
+		case xml.CharData:
+			if inText {
+				texts = append(texts, string(tok))
+			}
+		case xml.EndElement:
+			inText = false
+		}
+	}
+
+	total := session.GetMetadata().TotalThoughts
+	if counts["rect"] != total || counts["text"] != total || counts["line"] != total-1 {
+		t.Fatalf("expected %d rects/texts and %d lines, got %v", total, total-1, counts)
+	}
+	for _, text := range texts {
+		if len([]rune(text)) > models.SVGLabelMaxRunes {
+			t.Fatalf("label %q exceeds %d characters", text, models.SVGLabelMaxRunes)
+		}
+	}
+	if !strings.Contains(string(data), "&lt;next&gt;") {
+		t.Fatalf("expected markup in content to be escaped:\n%s", data)
+	}
+}
+
+func TestSessionToSVGRejectsInvalidSize(t *testing.T) {
+	session := buildDiagramFixture()
+	for _, size := range [][2]int{{0, 800}, {1200, -1}, {models.MaxSVGDimension + 1, 800}} {
+		if _, err := session.ToSVG(size[0], size[1]); err == nil {
+			t.Fatalf("expected %dx%d to be rejected", size[0], size[1])
+		}
+	}
+}
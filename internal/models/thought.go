@@ -3,28 +3,65 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// 枚举类型
+type ThoughtSource string
+
+const (
+	SourceUser ThoughtSource = "user" // 人工录入
+	SourceLLM  ThoughtSource = "llm"  // 模型生成
+)
+
 // 结构体
+type TokenUsage struct {
+	PromptTokens     int `json:"promptTokens"`
+	CompletionTokens int `json:"completionTokens"`
+	TotalTokens      int `json:"totalTokens"`
+}
+
+type Provenance struct {
+	Source      ThoughtSource `json:"source"`
+	Model       string        `json:"model,omitempty"`
+	PromptHash  string        `json:"promptHash,omitempty"`
+	TokenUsage  TokenUsage    `json:"tokenUsage"`
+	GeneratedAt time.Time     `json:"generatedAt"`
+}
+
 type Thought struct {
-	ID        string     `json:"id"`
-	Content   string     `json:"content"`
-	ParentID  *string    `json:"parentId,omitempty"`
-	SessionID string     `json:"sessionId"`
-	Direction Direction  `json:"direction"`
-	Depth     int        `json:"depth"`
-	CreatedAt time.Time  `json:"createdAt"`
-	Children  []*Thought `json:"children,omitempty"`
-	Path      []string   `json:"path,omitempty"`
-	parent    *Thought   `json:"-"`
+	ID          string            `json:"id"`
+	Content     string            `json:"content"`
+	ParentID    *string           `json:"parentId,omitempty"`
+	SessionID   string            `json:"sessionId"`
+	Direction   Direction         `json:"direction"`
+	Depth       int               `json:"depth"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	Children    []*Thought        `json:"children,omitempty"`
+	Path        []string          `json:"path,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Confidence  float64           `json:"confidence,omitempty"`
+	Provenance  *Provenance       `json:"provenance,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Reactions 记录各表情反馈的次数，键为表情
+	Reactions map[string]int `json:"reactions,omitempty"`
+	// 外部系统（如 Jira、Notion）中的关联条目
+	ExternalID        string   `json:"externalId,omitempty"`
+	ExternalSystemURL string   `json:"externalSystemUrl,omitempty"`
+	parent            *Thought `json:"-"`
 }
 
 type ThoughtUpdate struct {
-	Content   *string    `json:"content,omitempty"`
-	Direction *Direction `json:"direction,omitempty"`
+	Content    *string    `json:"content,omitempty"`
+	Direction  *Direction `json:"direction,omitempty"`
+	Tags       *[]string  `json:"tags,omitempty"`
+	Confidence *float64   `json:"confidence,omitempty"`
+	// 传入空字符串表示清除
+	ExternalID        *string `json:"externalId,omitempty"`
+	ExternalSystemURL *string `json:"externalSystemUrl,omitempty"`
 }
 
 // 方法
@@ -39,10 +76,60 @@ func NewThought(content, sessionID string, direction Direction) *Thought {
 		CreatedAt: now,
 		Children:  make([]*Thought, 0),
 		Path:      []string{content},
+		Provenance: &Provenance{
+			Source:      SourceUser,
+			GeneratedAt: now,
+		},
 	}
 	return thought
 }
 
+// SetAnnotation 记录节点的附加说明，空值会删除对应键。
+func (t *Thought) SetAnnotation(key, value string) {
+	if t == nil || key == "" {
+		return
+	}
+	if value == "" {
+		delete(t.Annotations, key)
+		return
+	}
+	if t.Annotations == nil {
+		t.Annotations = make(map[string]string)
+	}
+	t.Annotations[key] = value
+}
+
+// AddReaction 将 emoji 的反馈次数加一。
+func (t *Thought) AddReaction(emoji string) {
+	if t == nil || emoji == "" {
+		return
+	}
+	if t.Reactions == nil {
+		t.Reactions = make(map[string]int)
+	}
+	t.Reactions[emoji]++
+}
+
+// RemoveReaction 将 emoji 的反馈次数减一，归零时删除该键；没有该反馈时返回 false。
+func (t *Thought) RemoveReaction(emoji string) bool {
+	if t == nil || t.Reactions[emoji] <= 0 {
+		return false
+	}
+	t.Reactions[emoji]--
+	if t.Reactions[emoji] == 0 {
+		delete(t.Reactions, emoji)
+	}
+	if len(t.Reactions) == 0 {
+		t.Reactions = nil
+	}
+	return true
+}
+
+// IsGenerated 判断节点是否由模型生成；缺少来源信息的历史数据视为人工录入。
+func (t *Thought) IsGenerated() bool {
+	return t != nil && t.Provenance != nil && t.Provenance.Source == SourceLLM
+}
+
 func (t *Thought) AddChild(child *Thought) {
 	if t == nil || child == nil {
 		return
@@ -107,3 +194,42 @@ func (t *Thought) IsRoot() bool {
 	}
 	return t.ParentID == nil
 }
+
+// 枚举类型
+type SplitMode string
+
+const (
+	SplitBySentence  SplitMode = "sentence"
+	SplitByParagraph SplitMode = "paragraph"
+	SplitManual      SplitMode = "manual"
+)
+
+// SplitThoughtContent 按句子（句末标点）或段落（换行）切分内容，并去除空白片段。
+func SplitThoughtContent(content string, mode SplitMode) []string {
+	var parts []string
+	switch mode {
+	case SplitBySentence:
+		var current strings.Builder
+		for _, r := range content {
+			current.WriteRune(r)
+			switch r {
+			case '.', '!', '?', '。', '！', '？', '\n':
+				parts = append(parts, current.String())
+				current.Reset()
+			}
+		}
+		parts = append(parts, current.String())
+	case SplitByParagraph:
+		parts = strings.Split(content, "\n")
+	default:
+		return nil
+	}
+
+	cleaned := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			cleaned = append(cleaned, trimmed)
+		}
+	}
+	return cleaned
+}
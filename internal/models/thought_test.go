@@ -1,6 +1,7 @@
 package models_test
 
 import (
+	"encoding/json"
 	"testing"
 
 	"WideMindsMCP/internal/models"
@@ -37,3 +38,36 @@ func TestThoughtAddChildUpdatesPathAndDepth(t *testing.T) {
 		t.Fatalf("child CreatedAt should be set")
 	}
 }
+
+func TestThoughtJSONUsesCamelCaseKeys(t *testing.T) {
+	parent := models.NewThought("root", "session-1", models.Direction{Type: models.Broad, Title: "Root"})
+	child := models.NewThought("child", "session-1", models.Direction{Type: models.Deep, Title: "Deep"})
+	parent.AddChild(child)
+
+	data, err := json.Marshal(parent)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	for _, key := range []string{"id", "content", "sessionId", "direction", "depth", "createdAt", "children", "path"} {
+		if _, ok := raw[key]; !ok {
+			t.Fatalf("expected key %q in %s", key, data)
+		}
+	}
+	for _, key := range []string{"ID", "ParentID", "parent"} {
+		if _, ok := raw[key]; ok {
+			t.Fatalf("unexpected key %q in %s", key, data)
+		}
+	}
+
+	children, _ := raw["children"].([]interface{})
+	if len(children) != 1 {
+		t.Fatalf("expected one serialized child, got %v", raw["children"])
+	}
+	if got := children[0].(map[string]interface{})["parentId"]; got != parent.ID {
+		t.Fatalf("expected child parentId %s, got %v", parent.ID, got)
+	}
+}
@@ -0,0 +1,83 @@
+//Session Timeline(会话时间线)
+
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// 常量
+const DefaultTimelineBucket = time.Hour
+
+// 结构体
+type TimelineBucket struct {
+	Start  time.Time             `json:"start"`
+	End    time.Time             `json:"end"`
+	Count  int                   `json:"count"`
+	ByType map[DirectionType]int `json:"byType"`
+}
+
+// 方法
+// Timeline 按创建时间将思维分组到固定宽度的时间桶中，只返回非空的桶。
+// 缺少 CreatedAt 的历史数据回退为会话的 CreatedAt。
+func (s *Session) Timeline(bucket time.Duration) []TimelineBucket {
+	if bucket <= 0 {
+		bucket = DefaultTimelineBucket
+	}
+
+	index := map[int64]*TimelineBucket{}
+	walkThoughtTree(s, func(thought, _ *Thought) {
+		created := thought.CreatedAt
+		if created.IsZero() {
+			created = s.CreatedAt
+		}
+		addTimelineEntry(index, bucket, created, thought.Direction.Type, 1)
+	})
+	return sortedTimeline(index)
+}
+
+// 函数
+// MergeTimelines 合并多个使用相同桶宽度的时间线。
+func MergeTimelines(bucket time.Duration, timelines ...[]TimelineBucket) []TimelineBucket {
+	if bucket <= 0 {
+		bucket = DefaultTimelineBucket
+	}
+
+	index := map[int64]*TimelineBucket{}
+	for _, timeline := range timelines {
+		for _, entry := range timeline {
+			for directionType, count := range entry.ByType {
+				addTimelineEntry(index, bucket, entry.Start, directionType, count)
+			}
+		}
+	}
+	return sortedTimeline(index)
+}
+
+func addTimelineEntry(index map[int64]*TimelineBucket, bucket time.Duration, at time.Time, directionType DirectionType, count int) {
+	start := at.UTC().Truncate(bucket)
+	key := start.UnixNano()
+	entry, ok := index[key]
+	if !ok {
+		entry = &TimelineBucket{
+			Start:  start,
+			End:    start.Add(bucket),
+			ByType: map[DirectionType]int{},
+		}
+		index[key] = entry
+	}
+	entry.Count += count
+	entry.ByType[directionType] += count
+}
+
+func sortedTimeline(index map[int64]*TimelineBucket) []TimelineBucket {
+	timeline := make([]TimelineBucket, 0, len(index))
+	for _, entry := range index {
+		timeline = append(timeline, *entry)
+	}
+	sort.Slice(timeline, func(i, j int) bool {
+		return timeline[i].Start.Before(timeline[j].Start)
+	})
+	return timeline
+}
@@ -0,0 +1,67 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"WideMindsMCP/internal/models"
+)
+
+func TestSessionTimelineBuckets(t *testing.T) {
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	session := models.NewSession("user", "root")
+	session.CreatedAt = base
+	session.RootThought.CreatedAt = base.Add(5 * time.Minute)
+
+	add := func(offset time.Duration, directionType models.DirectionType) *models.Thought {
+		thought := models.NewThought("t", session.ID, models.Direction{Type: directionType})
+		thought.CreatedAt = base.Add(offset)
+		session.RootThought.AddChild(thought)
+		return thought
+	}
+	add(59*time.Minute+59*time.Second, models.Deep)
+	add(time.Hour, models.Lateral)
+	add(2*time.Hour+30*time.Minute, models.Deep)
+	legacy := add(0, models.Critical)
+	legacy.CreatedAt = time.Time{}
+
+	timeline := session.Timeline(time.Hour)
+	if len(timeline) != 3 {
+		t.Fatalf("expected 3 non-empty buckets, got %+v", timeline)
+	}
+
+	first := timeline[0]
+	if !first.Start.Equal(base) || !first.End.Equal(base.Add(time.Hour)) {
+		t.Fatalf("unexpected first bucket boundaries %v - %v", first.Start, first.End)
+	}
+	if first.Count != 3 || first.ByType[models.Broad] != 1 || first.ByType[models.Deep] != 1 || first.ByType[models.Critical] != 1 {
+		t.Fatalf("unexpected first bucket %+v", first)
+	}
+	if second := timeline[1]; !second.Start.Equal(base.Add(time.Hour)) || second.Count != 1 || second.ByType[models.Lateral] != 1 {
+		t.Fatalf("unexpected second bucket %+v", second)
+	}
+	if third := timeline[2]; !third.Start.Equal(base.Add(2*time.Hour)) || third.Count != 1 {
+		t.Fatalf("unexpected third bucket %+v", third)
+	}
+
+	if fallback := session.Timeline(0); len(fallback) != 3 {
+		t.Fatalf("expected non-positive bucket to fall back to hourly, got %d buckets", len(fallback))
+	}
+}
+
+func TestMergeTimelines(t *testing.T) {
+	base := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	left := []models.TimelineBucket{{Start: base, Count: 2, ByType: map[models.DirectionType]int{models.Deep: 2}}}
+	right := []models.TimelineBucket{
+		{Start: base, Count: 1, ByType: map[models.DirectionType]int{models.Broad: 1}},
+		{Start: base.Add(-time.Hour), Count: 1, ByType: map[models.DirectionType]int{models.Deep: 1}},
+	}
+
+	merged := models.MergeTimelines(time.Hour, left, right)
+	if len(merged) != 2 || !merged[0].Start.Equal(base.Add(-time.Hour)) {
+		t.Fatalf("expected merged buckets sorted by start, got %+v", merged)
+	}
+	if merged[1].Count != 3 || merged[1].ByType[models.Deep] != 2 || merged[1].ByType[models.Broad] != 1 {
+		t.Fatalf("unexpected merged bucket %+v", merged[1])
+	}
+}
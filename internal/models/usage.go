@@ -0,0 +1,38 @@
+//Session Usage(会话用量)
+
+package models
+
+// 结构体
+// SessionUsage 累计会话内 LLM 调用的令牌用量与按价格表计算的费用。
+type SessionUsage struct {
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"promptTokens"`
+	CompletionTokens int     `json:"completionTokens"`
+	TotalTokens      int     `json:"totalTokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// 方法
+// Add 记入一次 LLM 调用的用量与费用。
+func (u *SessionUsage) Add(usage TokenUsage, cost float64) {
+	if u == nil {
+		return
+	}
+	u.Calls++
+	u.PromptTokens += usage.PromptTokens
+	u.CompletionTokens += usage.CompletionTokens
+	u.TotalTokens += usage.TotalTokens
+	u.Cost += cost
+}
+
+// Merge 累加另一份用量汇总。
+func (u *SessionUsage) Merge(other SessionUsage) {
+	if u == nil {
+		return
+	}
+	u.Calls += other.Calls
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+	u.Cost += other.Cost
+}
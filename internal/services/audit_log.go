@@ -0,0 +1,205 @@
+//LLM Call Audit Log(LLM 调用审计日志)
+
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const (
+	DefaultRecentLLMCalls = 200
+
+	llmDebugLogFile     = "llm-calls.log"
+	llmDebugLogMaxBytes = 10 << 20
+	llmDebugLogBackups  = 5
+	redactedPlaceholder = "[REDACTED]"
+)
+
+// 结构体
+// LLMCallRecord 是一次远程 LLM 调用的元数据，不包含提示词或回复原文。
+type LLMCallRecord struct {
+	Timestamp     time.Time  `json:"timestamp"`
+	Model         string     `json:"model"`
+	PromptHash    string     `json:"prompt_hash"`
+	PromptBytes   int        `json:"prompt_bytes"`
+	ResponseBytes int        `json:"response_bytes"`
+	LatencyMs     int64      `json:"latency_ms"`
+	Status        string     `json:"status"`
+	Error         string     `json:"error,omitempty"`
+	Usage         TokenUsage `json:"usage"`
+	Attempts      int        `json:"attempts,omitempty"`
+	Stream        bool       `json:"stream,omitempty"`
+}
+
+// llmDebugEntry 是调试日志文件中的一行，在元数据之外附带脱敏后的提示词与回复。
+type llmDebugEntry struct {
+	LLMCallRecord
+	Prompt   string `json:"prompt"`
+	Response string `json:"response,omitempty"`
+}
+
+// llmAuditLog 保存最近的调用记录，并在开启调试时写入完整内容；由编排器副本共享。
+type llmAuditLog struct {
+	mutex      sync.Mutex
+	records    []LLMCallRecord
+	next       int
+	full       bool
+	debug      *utils.RotatingFile
+	redactions []*regexp.Regexp
+}
+
+// 函数
+func newLLMAuditLog(capacity int) *llmAuditLog {
+	if capacity <= 0 {
+		capacity = DefaultRecentLLMCalls
+	}
+	return &llmAuditLog{records: make([]LLMCallRecord, capacity)}
+}
+
+// CompileRedactions 编译脱敏正则表达式列表。
+func CompileRedactions(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// 方法
+// EnableDebugLog 将每次调用的完整提示词与回复（按 redactions 脱敏后）写入 dir 下的轮转日志文件。
+func (llm *LLMOrchestrator) EnableDebugLog(dir string, redactions []string) error {
+	if llm == nil || llm.audit == nil {
+		return nil
+	}
+	compiled, err := CompileRedactions(redactions)
+	if err != nil {
+		return err
+	}
+	file, err := utils.NewRotatingFile(filepath.Join(dir, llmDebugLogFile), llmDebugLogMaxBytes, llmDebugLogBackups)
+	if err != nil {
+		return err
+	}
+
+	llm.audit.mutex.Lock()
+	defer llm.audit.mutex.Unlock()
+	if llm.audit.debug != nil {
+		_ = llm.audit.debug.Close()
+	}
+	llm.audit.debug = file
+	llm.audit.redactions = compiled
+	return nil
+}
+
+// GetRecentLLMCalls 返回最近 n 次远程调用的记录，最新的在前；n <= 0 时返回全部保留的记录。
+func (llm *LLMOrchestrator) GetRecentLLMCalls(n int) []LLMCallRecord {
+	if llm == nil || llm.audit == nil {
+		return []LLMCallRecord{}
+	}
+	return llm.audit.recent(n)
+}
+
+// recordCall 记录一次远程调用：更新调用指标，元数据始终写入结构化日志与最近调用列表，完整内容仅在开启调试日志时写入文件。
+func (llm *LLMOrchestrator) recordCall(call *llmCall, resp *LLMResponse, err error, started time.Time, stream bool) {
+	if llm == nil || call == nil {
+		return
+	}
+	llm.metrics.observe(resp, err, time.Since(started), stream)
+	if llm.audit == nil {
+		return
+	}
+	record := LLMCallRecord{
+		Timestamp:   started.UTC(),
+		Model:       llm.reportedModel(),
+		PromptHash:  hashPrompt(call.prompt),
+		PromptBytes: len(call.prompt),
+		LatencyMs:   time.Since(started).Milliseconds(),
+		Status:      "ok",
+		Stream:      stream,
+	}
+	if err != nil {
+		record.Status = "error"
+		record.Error = err.Error()
+	}
+	if resp != nil {
+		if resp.Model != "" {
+			record.Model = resp.Model
+		}
+		record.ResponseBytes = len(resp.Content)
+		record.Usage = resp.Usage
+		record.Attempts = resp.Attempts
+	}
+
+	utils.Debug("llm call",
+		utils.KV("model", record.Model),
+		utils.KV("prompt_hash", record.PromptHash),
+		utils.KV("prompt_bytes", record.PromptBytes),
+		utils.KV("response_bytes", record.ResponseBytes),
+		utils.KV("latency_ms", record.LatencyMs),
+		utils.KV("status", record.Status),
+		utils.KV("total_tokens", record.Usage.TotalTokens),
+	)
+	llm.audit.add(record, call.prompt, resp)
+}
+
+func (a *llmAuditLog) add(record LLMCallRecord, prompt string, resp *LLMResponse) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.records[a.next] = record
+	a.next = (a.next + 1) % len(a.records)
+	if a.next == 0 {
+		a.full = true
+	}
+
+	if a.debug == nil {
+		return
+	}
+	entry := llmDebugEntry{LLMCallRecord: record, Prompt: a.redact(prompt)}
+	if resp != nil {
+		entry.Response = a.redact(resp.Content)
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if _, err := a.debug.Write(append(line, '\n')); err != nil {
+		utils.Warn("failed to write llm debug log", utils.KV("error", err))
+	}
+}
+
+func (a *llmAuditLog) redact(text string) string {
+	for _, re := range a.redactions {
+		text = re.ReplaceAllString(text, redactedPlaceholder)
+	}
+	return text
+}
+
+func (a *llmAuditLog) recent(n int) []LLMCallRecord {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	count := a.next
+	if a.full {
+		count = len(a.records)
+	}
+	if n <= 0 || n > count {
+		n = count
+	}
+	recent := make([]LLMCallRecord, 0, n)
+	for i := 1; i <= n; i++ {
+		recent = append(recent, a.records[(a.next-i+len(a.records))%len(a.records)])
+	}
+	return recent
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"WideMindsMCP/internal/utils"
+)
+
+func newAuditTestServer(t *testing.T, completion string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"model":   "audit-model",
+			"choices": []map[string]any{{"message": map[string]string{"content": completion}}},
+			"usage":   map[string]int{"prompt_tokens": 7, "completion_tokens": 3, "total_tokens": 10},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDebugLogAppliesRedactions(t *testing.T) {
+	server := newAuditTestServer(t, "reach me at bob@example.com")
+	dir := t.TempDir()
+
+	llm := NewLLMOrchestrator("key", server.URL, "model")
+	if err := llm.EnableDebugLog(dir, []string{`[\w.]+@[\w.]+`, `sk-[A-Za-z0-9]+`}); err != nil {
+		t.Fatalf("EnableDebugLog failed: %v", err)
+	}
+	if _, err := llm.CallLLM(&LLMRequest{Prompt: "my key is sk-abc123 and mail alice@example.com"}); err != nil {
+		t.Fatalf("CallLLM failed: %v", err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(dir, llmDebugLogFile))
+	if err != nil {
+		t.Fatalf("read debug log: %v", err)
+	}
+	var entry llmDebugEntry
+	if err := json.Unmarshal(bytes.TrimSpace(raw), &entry); err != nil {
+		t.Fatalf("decode debug log entry: %v", err)
+	}
+	for _, secret := range []string{"sk-abc123", "alice@example.com", "bob@example.com"} {
+		if strings.Contains(string(raw), secret) {
+			t.Fatalf("expected %q to be redacted:\n%s", secret, raw)
+		}
+	}
+	if !strings.Contains(entry.Prompt, "my key is [REDACTED]") || entry.Response != "reach me at [REDACTED]" {
+		t.Fatalf("unexpected redacted entry %+v", entry)
+	}
+	if entry.Model != "audit-model" || entry.Usage.TotalTokens != 10 || entry.Status != "ok" {
+		t.Fatalf("expected call metadata in debug entry, got %+v", entry.LLMCallRecord)
+	}
+
+	if err := llm.EnableDebugLog(dir, []string{"("}); err == nil {
+		t.Fatalf("expected invalid redaction pattern to be rejected")
+	}
+}
+
+func TestDefaultAuditModeNeverWritesPromptText(t *testing.T) {
+	const secret = "confidential launch plan"
+	server := newAuditTestServer(t, "noted")
+
+	var logs bytes.Buffer
+	previous := utils.Logger()
+	utils.SetLogger(slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { utils.SetLogger(previous) })
+
+	workDir := t.TempDir()
+	t.Chdir(workDir)
+
+	llm := NewLLMOrchestrator("key", server.URL, "model")
+	for i := 0; i < 3; i++ {
+		if _, err := llm.WithoutCache().CallLLM(&LLMRequest{Prompt: secret}); err != nil {
+			t.Fatalf("CallLLM failed: %v", err)
+		}
+	}
+
+	calls := llm.GetRecentLLMCalls(2)
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 recent calls, got %d", len(calls))
+	}
+	if calls[0].PromptHash != hashPrompt(secret) || calls[0].PromptBytes != len(secret) || calls[0].ResponseBytes != len("noted") {
+		t.Fatalf("unexpected call record %+v", calls[0])
+	}
+	if len(llm.GetRecentLLMCalls(0)) != 3 {
+		t.Fatalf("expected all retained calls when n <= 0")
+	}
+
+	encoded, _ := json.Marshal(llm.GetRecentLLMCalls(0))
+	if strings.Contains(string(encoded), secret) {
+		t.Fatalf("expected call records to omit prompt text: %s", encoded)
+	}
+	if !strings.Contains(logs.String(), `"msg":"llm call"`) || strings.Contains(logs.String(), secret) {
+		t.Fatalf("expected metadata-only debug logs, got:\n%s", logs.String())
+	}
+	entries, err := os.ReadDir(workDir)
+	if err != nil {
+		t.Fatalf("read work dir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected no log files in default mode, found %d entries", len(entries))
+	}
+}
+
+func TestRecentLLMCallsWrapAround(t *testing.T) {
+	audit := newLLMAuditLog(2)
+	for _, model := range []string{"a", "b", "c"} {
+		audit.add(LLMCallRecord{Model: model}, "", nil)
+	}
+	recent := audit.recent(5)
+	if len(recent) != 2 || recent[0].Model != "c" || recent[1].Model != "b" {
+		t.Fatalf("expected newest-first wrap-around, got %+v", recent)
+	}
+}
@@ -0,0 +1,173 @@
+//Auto Exploration(自动探索起步图)
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 结构体
+// AutoExploreRequest 描述一次自动探索：以概念新建会话，逐层扩散每个节点的前 Breadth 个方向，共 Depth 层。
+type AutoExploreRequest struct {
+	UserID  string                `json:"userId"`
+	Concept string                `json:"concept"`
+	Context []models.ContextEntry `json:"context,omitempty"`
+	// Breadth 是每个节点继续探索的方向数，<=0 时使用 DefaultAutoExploreBreadth。
+	Breadth int `json:"breadth,omitempty"`
+	// Depth 是根节点以下自动展开的层数，<=0 时使用 DefaultAutoExploreDepth。
+	Depth int `json:"depth,omitempty"`
+	// MaxNodes 限制会话的思维总数（含根节点），<=0 时仅受部署的节点预算约束。
+	MaxNodes int `json:"maxNodes,omitempty"`
+	// ConcurrencyLimit 限制每次扩散同时生成的预览数，<=0 时使用扩展器的默认并发数。
+	ConcurrencyLimit int `json:"concurrencyLimit,omitempty"`
+}
+
+// AutoExploreProgress 在每个节点扩散并保存后报告进度
+type AutoExploreProgress struct {
+	SessionID       string   `json:"sessionId"`
+	Level           int      `json:"level"`
+	ParentThoughtID string   `json:"parentThoughtId"`
+	ThoughtIDs      []string `json:"thoughtIds"`
+	// TotalThoughts 是会话当前的思维总数（含根节点）
+	TotalThoughts int `json:"totalThoughts"`
+}
+
+// 常量
+const (
+	DefaultAutoExploreBreadth = 3
+	DefaultAutoExploreDepth   = 2
+	MaxAutoExploreBreadth     = DefaultMaxDirections
+	MaxAutoExploreDepth       = 4
+)
+
+// 方法
+// AutoExplore 新建会话并自动探索，返回最终会话。
+func (te *ThoughtExpander) AutoExplore(ctx context.Context, req AutoExploreRequest) (*models.Session, error) {
+	return te.AutoExploreStream(ctx, req, nil)
+}
+
+// AutoExploreStream 与 AutoExplore 相同，每个节点扩散并保存后回调 onProgress。
+// 每次扩散后立即保存会话，达到 MaxNodes 或节点预算时停止并返回已生成的部分；
+// 中途失败时已保存的层级保留在会话中，错误信息附带会话 ID。
+func (te *ThoughtExpander) AutoExploreStream(ctx context.Context, req AutoExploreRequest, onProgress func(AutoExploreProgress)) (*models.Session, error) {
+	if te == nil || te.generator == nil || te.sessionManager == nil {
+		return nil, errors.New("thought expander is not initialized")
+	}
+	breadth, depth, err := te.autoExploreShape(req)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := te.sessionManager.CreateSession(req.UserID, req.Concept)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Context) > 0 {
+		for _, entry := range req.Context {
+			session.AddContextEntry(entry)
+		}
+		if err := te.sessionManager.UpdateSession(session); err != nil {
+			return nil, err
+		}
+	}
+
+	sessionID := session.ID
+	frontier := []string{session.RootThought.ID}
+	for level := 1; level <= depth && len(frontier) > 0; level++ {
+		next := make([]string, 0, len(frontier)*breadth)
+		for _, parentID := range frontier {
+			if err := ctx.Err(); err != nil {
+				return nil, autoExploreError(sessionID, err)
+			}
+			if session, err = te.sessionManager.GetSession(sessionID); err != nil {
+				return nil, autoExploreError(sessionID, err)
+			}
+			maxTotal := 0
+			if req.MaxNodes > 0 {
+				maxTotal = req.MaxNodes - len(session.GetThoughtTree())
+				if maxTotal <= 0 {
+					return session, nil
+				}
+			}
+
+			result, err := te.ExpandContext(ctx, &ExpansionRequest{
+				SessionID:        sessionID,
+				ThoughtID:        parentID,
+				UserID:           req.UserID,
+				MaxDirections:    breadth,
+				MaxTotalThoughts: maxTotal,
+				ConcurrencyLimit: req.ConcurrencyLimit,
+			})
+			if err != nil {
+				return nil, autoExploreError(sessionID, err)
+			}
+			if session, err = te.sessionManager.GetSession(sessionID); err != nil {
+				return nil, autoExploreError(sessionID, err)
+			}
+			next = append(next, result.PersistedThoughtIDs...)
+			if onProgress != nil {
+				onProgress(AutoExploreProgress{
+					SessionID:       sessionID,
+					Level:           level,
+					ParentThoughtID: parentID,
+					ThoughtIDs:      result.PersistedThoughtIDs,
+					TotalThoughts:   len(session.GetThoughtTree()),
+				})
+			}
+			if result.Truncated {
+				return session, nil
+			}
+		}
+		frontier = next
+	}
+	return session, nil
+}
+
+// autoExploreShape 校验请求并返回实际使用的宽度与深度；用户必填，每次扩散都计入其令牌预算。
+func (te *ThoughtExpander) autoExploreShape(req AutoExploreRequest) (int, int, error) {
+	if strings.TrimSpace(req.UserID) == "" {
+		return 0, 0, utils.ValidationError("user_id is required")
+	}
+	if err := utils.ValidateConcept(req.Concept); err != nil {
+		return 0, 0, err
+	}
+	breadth := req.Breadth
+	if breadth <= 0 {
+		breadth = DefaultAutoExploreBreadth
+	}
+	if breadth > MaxAutoExploreBreadth {
+		return 0, 0, utils.ValidationError(fmt.Sprintf("breadth must be at most %d", MaxAutoExploreBreadth))
+	}
+	depth := req.Depth
+	if depth <= 0 {
+		depth = DefaultAutoExploreDepth
+	}
+	if depth > MaxAutoExploreDepth {
+		return 0, 0, utils.ValidationError(fmt.Sprintf("depth must be at most %d", MaxAutoExploreDepth))
+	}
+	if depth > te.sessionManager.MaxThoughtDepth() {
+		return 0, 0, utils.ValidationError(maxDepthReachedMessage)
+	}
+	if req.MaxNodes < 0 {
+		return 0, 0, utils.ValidationError("max_nodes must not be negative")
+	}
+	if req.ConcurrencyLimit > MaxExpansionConcurrency {
+		return 0, 0, utils.ValidationError("concurrency_limit is too large")
+	}
+	return breadth, depth, nil
+}
+
+// 函数
+func autoExploreError(sessionID string, err error) error {
+	if errors.Is(err, appErrors.ErrSessionNotFound) {
+		return err
+	}
+	return fmt.Errorf("auto explore stopped; partial results are saved in session %s: %w", sessionID, err)
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+)
+
+// assertConsistentTree 校验每个子节点的父 ID 与深度和其在树中的位置一致。
+func assertConsistentTree(t *testing.T, thought *models.Thought) {
+	t.Helper()
+	for _, child := range thought.Children {
+		if child.ParentID == nil || *child.ParentID != thought.ID || child.Depth != thought.Depth+1 {
+			t.Fatalf("inconsistent child %s under %s (depth %d)", child.ID, thought.ID, child.Depth)
+		}
+		assertConsistentTree(t, child)
+	}
+}
+
+func TestAutoExploreBuildsBreadthByDepthTree(t *testing.T) {
+	expander := NewThoughtExpander(&nodeGenerator{}, NewSessionManager(storage.NewInMemorySessionStore()))
+
+	var progress []AutoExploreProgress
+	session, err := expander.AutoExploreStream(context.Background(), AutoExploreRequest{
+		UserID:  "user",
+		Concept: "Flow batteries",
+		Context: []models.ContextEntry{models.NewContextEntry(models.ContextGoal, "grid storage")},
+		Breadth: 2,
+		Depth:   2,
+	}, func(update AutoExploreProgress) {
+		progress = append(progress, update)
+	})
+	if err != nil {
+		t.Fatalf("AutoExplore failed: %v", err)
+	}
+
+	root := session.RootThought
+	if root.Content != "Flow batteries" || len(root.Children) != 2 {
+		t.Fatalf("expected two first-level directions, got %+v", root.Children)
+	}
+	for _, child := range root.Children {
+		if len(child.Children) != 2 {
+			t.Fatalf("expected two second-level directions under %q, got %d", child.Content, len(child.Children))
+		}
+		for _, grandchild := range child.Children {
+			if len(grandchild.Children) != 0 {
+				t.Fatalf("expected leaves at depth 2, got children under %q", grandchild.Content)
+			}
+		}
+	}
+	assertConsistentTree(t, root)
+	if len(session.GetThoughtTree()) != 7 {
+		t.Fatalf("expected 7 thoughts, got %d", len(session.GetThoughtTree()))
+	}
+	if len(progress) != 3 || progress[0].Level != 1 || progress[2].Level != 2 || progress[2].TotalThoughts != 7 {
+		t.Fatalf("expected one progress event per expanded node, got %+v", progress)
+	}
+	if len(session.ContextEntries) != 2 {
+		t.Fatalf("expected the request context on the session, got %v", session.ContextEntries)
+	}
+}
+
+func TestAutoExploreBudgetCutLeavesConsistentSession(t *testing.T) {
+	store := storage.NewInMemorySessionStore()
+	expander := NewThoughtExpander(&nodeGenerator{}, NewSessionManager(store))
+
+	session, err := expander.AutoExplore(context.Background(), AutoExploreRequest{
+		UserID:   "user",
+		Concept:  "Flow batteries",
+		Breadth:  2,
+		Depth:    2,
+		MaxNodes: 4,
+	})
+	if err != nil {
+		t.Fatalf("AutoExplore failed: %v", err)
+	}
+	if len(session.GetThoughtTree()) != 4 {
+		t.Fatalf("expected the budget to stop at 4 thoughts, got %d", len(session.GetThoughtTree()))
+	}
+
+	// 从新的管理器读取，确认截断前的每一层都已保存
+	stored, err := NewSessionManager(store).GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if len(stored.GetThoughtTree()) != 4 || len(stored.RootThought.Children) != 2 {
+		t.Fatalf("expected the persisted partial tree, got %d thoughts", len(stored.GetThoughtTree()))
+	}
+	assertConsistentTree(t, stored.RootThought)
+}
+
+func TestAutoExploreRejectsOversizedShape(t *testing.T) {
+	expander := NewThoughtExpander(&nodeGenerator{}, NewSessionManager(storage.NewInMemorySessionStore()))
+	if _, err := expander.AutoExplore(context.Background(), AutoExploreRequest{UserID: "user", Concept: "Flow batteries", Depth: MaxAutoExploreDepth + 1}); err == nil {
+		t.Fatal("expected an error for a depth above the limit")
+	}
+	if _, err := expander.AutoExplore(context.Background(), AutoExploreRequest{UserID: "user", Concept: "Flow batteries", MaxNodes: -1}); err == nil {
+		t.Fatal("expected an error for a negative node budget")
+	}
+	if _, err := expander.AutoExplore(context.Background(), AutoExploreRequest{Concept: "Flow batteries"}); err == nil {
+		t.Fatal("expected an error for a missing user")
+	}
+}
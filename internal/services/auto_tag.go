@@ -0,0 +1,168 @@
+//Session Auto Tagging(会话自动标签)
+
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const (
+	maxAutoTags         = 7
+	maxAutoTagThoughts  = 5
+	maxAutoTagTitles    = 20
+	fallbackAutoTagSize = 5
+)
+
+// 方法
+// SuggestSessionTags 请求 LLM 根据根概念、方向标题与主要思维生成 3-7 个标签，
+// 未配置后端或响应无法解析时回退到词云中出现最多的 5 个词。
+func (llm *LLMOrchestrator) SuggestSessionTags(session *models.Session) ([]string, error) {
+	if session == nil || session.RootThought == nil {
+		return nil, errors.New("session has no thoughts")
+	}
+
+	if llm.hasRemoteBackend() {
+		prompt := llm.BuildPrompt(session.RootThought.Content, buildTaggingContext(session), "tagging")
+		resp, err := llm.CallLLM(&LLMRequest{
+			Prompt:      prompt,
+			Temperature: float64Ptr(0.2),
+			MaxTokens:   256,
+		})
+		if errors.Is(err, appErrors.ErrBudgetExceeded) {
+			return nil, err
+		} else if err != nil {
+			utils.Warn("LLM call failed while tagging a session", utils.KV("error", err))
+		} else if resp != nil {
+			if tags, parseErr := parseTagList(resp.Content); parseErr != nil {
+				utils.Warn("failed to parse LLM tagging response", utils.KV("error", parseErr))
+			} else {
+				return tags, nil
+			}
+		}
+	}
+
+	return fallbackSessionTags(session), nil
+}
+
+// AutoTagSession 为会话生成标签并替换原有标签（可撤销）。
+func (te *ThoughtExpander) AutoTagSession(sessionID string) (*models.Session, error) {
+	if te == nil || te.generator == nil {
+		return nil, errors.New("thought expander is not initialized")
+	}
+	if sessionID == "" {
+		return nil, appErrors.ErrInvalidRequest
+	}
+
+	session, err := te.sessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	generator, flushUsage := te.trackUsage(generatorForUser(te.generator, session.UserID), session.ID)
+	tags, err := suggestSessionTags(generator, session)
+	flushUsage()
+	if err != nil {
+		return nil, err
+	}
+	return te.sessionManager.TagSession(sessionID, tags)
+}
+
+// 函数
+// buildTaggingContext 汇总全部方向标题（去重）与子思维最多的 5 个思维内容。
+func buildTaggingContext(session *models.Session) []models.ContextEntry {
+	var (
+		titles   []string
+		thoughts []*models.Thought
+	)
+	seen := map[string]struct{}{}
+	queue := []*models.Thought{session.RootThought}
+	for len(queue) > 0 {
+		thought := queue[0]
+		queue = queue[1:]
+		if thought == nil {
+			continue
+		}
+		if !thought.IsRoot() {
+			thoughts = append(thoughts, thought)
+			title := strings.TrimSpace(thought.Direction.Title)
+			key := strings.ToLower(title)
+			if _, ok := seen[key]; title != "" && !ok && len(titles) < maxAutoTagTitles {
+				seen[key] = struct{}{}
+				titles = append(titles, title)
+			}
+		}
+		queue = append(queue, thought.Children...)
+	}
+
+	sort.SliceStable(thoughts, func(i, j int) bool {
+		return len(thoughts[i].Children) > len(thoughts[j].Children)
+	})
+	if len(thoughts) > maxAutoTagThoughts {
+		thoughts = thoughts[:maxAutoTagThoughts]
+	}
+
+	entries := make([]models.ContextEntry, 0, len(thoughts)+1)
+	if len(titles) > 0 {
+		entries = append(entries, models.NewContextEntry(models.ContextNote, "direction titles: "+strings.Join(titles, "; ")))
+	}
+	for _, thought := range thoughts {
+		entries = append(entries, models.NewContextEntry(models.ContextNote, "key thought: "+truncate(thought.Content, 120)))
+	}
+	return entries
+}
+
+// parseTagList 解析 JSON 字符串数组，丢弃空项、重复项与超长项，最多保留 maxAutoTags 个。
+func parseTagList(content string) ([]string, error) {
+	start := strings.Index(content, "[")
+	end := strings.LastIndex(content, "]")
+	if start < 0 || end <= start {
+		return nil, errors.New("tagging response does not contain a JSON array")
+	}
+
+	var raw []string
+	if err := json.Unmarshal([]byte(content[start:end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("parse llm tags: %w", err)
+	}
+
+	tags := make([]string, 0, len(raw))
+	seen := make(map[string]struct{}, len(raw))
+	for _, tag := range raw {
+		tag = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+		if tag == "" || utf8.RuneCountInString(tag) > utils.MaxTagLength {
+			continue
+		}
+		key := strings.ToLower(tag)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		tags = append(tags, tag)
+		if len(tags) == maxAutoTags {
+			break
+		}
+	}
+	if len(tags) == 0 {
+		return nil, errors.New("tagging response has no valid tags")
+	}
+	return tags, nil
+}
+
+func fallbackSessionTags(session *models.Session) []string {
+	words := models.TopWords(session.WordCloud(utils.DefaultStopWords()), fallbackAutoTagSize)
+	tags := make([]string, 0, len(words))
+	for _, word := range words {
+		if utf8.RuneCountInString(word.Word) <= utils.MaxTagLength {
+			tags = append(tags, word.Word)
+		}
+	}
+	return tags
+}
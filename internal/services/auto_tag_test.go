@@ -0,0 +1,64 @@
+package services
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+)
+
+func newTaggingSession(t *testing.T, manager *SessionManager) *models.Session {
+	t.Helper()
+	session, err := manager.CreateSession("user", "Battery storage")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	for _, content := range []string{"Lithium battery chemistry", "Battery recycling economics"} {
+		thought := models.NewThought(content, session.ID, models.Direction{Type: models.Deep, Title: "Battery " + content})
+		if err := manager.AddThoughtToSession(session.ID, thought); err != nil {
+			t.Fatalf("AddThoughtToSession failed: %v", err)
+		}
+	}
+	return session
+}
+
+func TestAutoTagSessionUsesLLMTags(t *testing.T) {
+	var prompts []string
+	reply := `Here you go: ["energy storage", "#Batteries", "batteries", "", "` + strings.Repeat("x", 40) + `", "recycling"]`
+	server, _ := newScriptedBackend(t, http.StatusOK, []string{reply}, &prompts)
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	session := newTaggingSession(t, manager)
+	expander := NewThoughtExpander(newRepairTestLLM(t, server.URL), manager)
+
+	tagged, err := expander.AutoTagSession(session.ID)
+	if err != nil {
+		t.Fatalf("AutoTagSession failed: %v", err)
+	}
+	if got := strings.Join(tagged.Tags, ","); got != "energy storage,Batteries,recycling" {
+		t.Fatalf("unexpected tags %q", got)
+	}
+	if len(prompts) != 1 || !strings.Contains(prompts[0], "Battery Lithium battery chemistry") || !strings.Contains(prompts[0], "key thought: Battery recycling economics") {
+		t.Fatalf("expected prompt to include direction titles and thoughts, got %q", prompts)
+	}
+
+	undone, err := manager.Undo(session.ID)
+	if err != nil || len(undone.Tags) != 0 {
+		t.Fatalf("expected undo to clear the generated tags, got %v (%v)", undone.Tags, err)
+	}
+}
+
+func TestAutoTagSessionFallsBackToWordCloud(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	session := newTaggingSession(t, manager)
+	expander := NewThoughtExpander(NewLLMOrchestrator("", "", ""), manager)
+
+	tagged, err := expander.AutoTagSession(session.ID)
+	if err != nil {
+		t.Fatalf("AutoTagSession failed: %v", err)
+	}
+	if len(tagged.Tags) == 0 || len(tagged.Tags) > fallbackAutoTagSize || tagged.Tags[0] != "battery" {
+		t.Fatalf("expected word cloud tags led by the most frequent word, got %v", tagged.Tags)
+	}
+}
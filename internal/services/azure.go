@@ -0,0 +1,81 @@
+//Azure OpenAI Endpoint(Azure OpenAI 部署)
+
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// 常量
+const (
+	APITypeOpenAI = "openai"
+	APITypeAzure  = "azure"
+
+	DefaultAzureAPIVersion = "2024-06-01"
+)
+
+// 方法
+// SetAPIType 选择 OpenAI 兼容接口的地址与认证方式。azure 使用
+// {baseURL}/openai/deployments/{deployment}/chat/completions?api-version=... 与 api-key 头，必须提供部署名。
+func (llm *LLMOrchestrator) SetAPIType(apiType, deployment, apiVersion string) error {
+	if llm == nil {
+		return nil
+	}
+	switch strings.ToLower(strings.TrimSpace(apiType)) {
+	case "", APITypeOpenAI:
+		llm.apiType = APITypeOpenAI
+		llm.azureDeployment = ""
+		llm.azureAPIVersion = ""
+	case APITypeAzure:
+		deployment = strings.TrimSpace(deployment)
+		if deployment == "" {
+			return fmt.Errorf("azure api type requires a deployment name")
+		}
+		apiVersion = strings.TrimSpace(apiVersion)
+		if apiVersion == "" {
+			apiVersion = DefaultAzureAPIVersion
+		}
+		llm.apiType = APITypeAzure
+		llm.azureDeployment = deployment
+		llm.azureAPIVersion = apiVersion
+	default:
+		return fmt.Errorf("unsupported llm api type %q", apiType)
+	}
+	return nil
+}
+
+func (llm *LLMOrchestrator) isAzure() bool {
+	return llm != nil && llm.apiType == APITypeAzure && !llm.isOllama()
+}
+
+func (llm *LLMOrchestrator) azureEndpoint() string {
+	base := strings.TrimRight(llm.baseURL, "/")
+	if i := strings.Index(base, "/openai/"); i >= 0 {
+		base = base[:i]
+	}
+	return fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+		base, url.PathEscape(llm.azureDeployment), url.QueryEscape(llm.azureAPIVersion))
+}
+
+// setAuthHeader 按接口类型设置认证头：Azure 使用 api-key，其余使用 Bearer token。
+func (llm *LLMOrchestrator) setAuthHeader(req *http.Request) {
+	if llm.apiKey == "" {
+		return
+	}
+	if llm.isAzure() {
+		req.Header.Set("api-key", llm.apiKey)
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+llm.apiKey)
+}
+
+// reportedModel 返回响应未携带模型名时使用的名称；Azure 以部署名标识模型。
+func (llm *LLMOrchestrator) reportedModel() string {
+	if llm.isAzure() {
+		return llm.azureDeployment
+	}
+	return llm.model
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAzureEndpointAndHeaders(t *testing.T) {
+	var (
+		gotPath   string
+		gotQuery  string
+		gotKey    string
+		gotBearer string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotQuery = r.URL.RawQuery
+		gotKey = r.Header.Get("api-key")
+		gotBearer = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"content": "ok"}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	orchestrator := NewLLMOrchestrator("secret", server.URL+"/", "gpt-4o")
+	if err := orchestrator.SetAPIType("Azure", "prod-gpt4o", "2024-02-15-preview"); err != nil {
+		t.Fatalf("SetAPIType failed: %v", err)
+	}
+
+	want := server.URL + "/openai/deployments/prod-gpt4o/chat/completions?api-version=2024-02-15-preview"
+	if got := orchestrator.endpoint(); got != want {
+		t.Fatalf("unexpected endpoint %q, want %q", got, want)
+	}
+
+	resp, err := orchestrator.CallLLM(&LLMRequest{Prompt: "hello"})
+	if err != nil {
+		t.Fatalf("CallLLM returned error: %v", err)
+	}
+	if gotPath != "/openai/deployments/prod-gpt4o/chat/completions" || gotQuery != "api-version=2024-02-15-preview" {
+		t.Fatalf("unexpected request target %s?%s", gotPath, gotQuery)
+	}
+	if gotKey != "secret" || gotBearer != "" {
+		t.Fatalf("expected api-key header only, got api-key=%q authorization=%q", gotKey, gotBearer)
+	}
+	if resp.Model != "prod-gpt4o" {
+		t.Fatalf("expected deployment name as reported model, got %q", resp.Model)
+	}
+}
+
+func TestSetAPITypeValidation(t *testing.T) {
+	orchestrator := NewLLMOrchestrator("key", "https://example.openai.azure.com", "gpt-4o")
+	if err := orchestrator.SetAPIType(APITypeAzure, " ", ""); err == nil {
+		t.Fatalf("expected missing deployment to be rejected")
+	}
+	if err := orchestrator.SetAPIType("vertex", "", ""); err == nil {
+		t.Fatalf("expected unknown api type to be rejected")
+	}
+	if err := orchestrator.SetAPIType(APITypeAzure, "dep", ""); err != nil {
+		t.Fatalf("SetAPIType failed: %v", err)
+	}
+	if got := orchestrator.endpoint(); got != "https://example.openai.azure.com/openai/deployments/dep/chat/completions?api-version="+DefaultAzureAPIVersion {
+		t.Fatalf("unexpected default api version endpoint %q", got)
+	}
+}
@@ -0,0 +1,123 @@
+//Batch Direction Generation(批量生成方向)
+
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 结构体
+type BatchDirectionRequest struct {
+	Concept string                `json:"concept"`
+	Context []models.ContextEntry `json:"context,omitempty"`
+}
+
+// BatchDirectionResult 与请求按下标一一对应；Cached 表示结果复用了同一批次中概念与上下文相同的请求。
+type BatchDirectionResult struct {
+	Directions []models.Direction `json:"directions"`
+	Cached     bool               `json:"cached"`
+	Error      error              `json:"-"`
+}
+
+// 方法
+// BatchGenerateDirections 以最多 concurrency 个并发为每个请求生成方向，结果保持请求顺序。
+// 概念与上下文相同的请求只调用一次 LLM 并共享结果；单个请求失败记录在其 Error 中，
+// 超出令牌预算或请求被取消时停止启动其余请求并返回该错误。
+func (llm *LLMOrchestrator) BatchGenerateDirections(requests []BatchDirectionRequest, concurrency int) ([]BatchDirectionResult, error) {
+	if llm == nil {
+		return nil, errors.New("llm orchestrator is nil")
+	}
+	if concurrency <= 0 {
+		concurrency = DefaultExpansionConcurrency
+	}
+	if concurrency > MaxExpansionConcurrency {
+		concurrency = MaxExpansionConcurrency
+	}
+
+	// 相同请求只保留首次出现的下标，其余下标共享其结果
+	keys := make([]string, len(requests))
+	first := make(map[string]int, len(requests))
+	unique := make([]int, 0, len(requests))
+	for i, req := range requests {
+		keys[i] = batchDirectionKey(req)
+		if _, ok := first[keys[i]]; !ok {
+			first[keys[i]] = i
+			unique = append(unique, i)
+		}
+	}
+
+	results := make([]BatchDirectionResult, len(requests))
+	var (
+		wg       sync.WaitGroup
+		failOnce sync.Once
+		fatal    error
+		stopped  = make(chan struct{})
+	)
+	semaphore := make(chan struct{}, concurrency)
+	for _, index := range unique {
+		select {
+		case semaphore <- struct{}{}:
+		case <-stopped:
+		}
+		select {
+		case <-stopped:
+			results[index].Error = fatal
+			continue
+		default:
+		}
+
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			req := requests[index]
+			directions, err := llm.GenerateThoughtDirections(req.Concept, req.Context)
+			results[index] = BatchDirectionResult{Directions: directions, Error: err}
+			if errors.Is(err, appErrors.ErrBudgetExceeded) || isCanceled(err) {
+				failOnce.Do(func() {
+					fatal = err
+					close(stopped)
+				})
+			}
+		}(index)
+	}
+	wg.Wait()
+
+	for i := range requests {
+		if source := first[keys[i]]; source != i {
+			results[i] = BatchDirectionResult{
+				Directions: append([]models.Direction(nil), results[source].Directions...),
+				Cached:     true,
+				Error:      results[source].Error,
+			}
+		}
+	}
+
+	utils.Info("batch direction generation finished",
+		utils.KV("requests", len(requests)),
+		utils.KV("llm_calls", len(unique)),
+		utils.KV("calls_saved", len(requests)-len(unique)),
+	)
+	return results, fatal
+}
+
+// 函数
+// batchDirectionKey 以概念与各上下文条目的 SHA-256 识别重复请求。
+func batchDirectionKey(req BatchDirectionRequest) string {
+	hash := sha256.New()
+	hash.Write([]byte(req.Concept))
+	for _, entry := range req.Context {
+		hash.Write([]byte{0})
+		hash.Write([]byte(entry.Kind))
+		hash.Write([]byte{':'})
+		hash.Write([]byte(entry.Value))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
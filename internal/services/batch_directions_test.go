@@ -0,0 +1,51 @@
+package services
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"WideMindsMCP/internal/models"
+)
+
+func TestBatchGenerateDirectionsDeduplicatesRequests(t *testing.T) {
+	content := `{"directions":[{"type":"deep","title":"Chemistry","description":"Cell chemistry"}]}`
+	server, calls := newScriptedBackend(t, http.StatusOK, []string{content}, nil)
+	llm := newRepairTestLLM(t, server.URL)
+	llm.SetResponseCache(0, 0)
+
+	background := []models.ContextEntry{models.NewContextEntry(models.ContextBackground, "grid scale")}
+	results, err := llm.BatchGenerateDirections([]BatchDirectionRequest{
+		{Concept: "Batteries", Context: background},
+		{Concept: "Solar"},
+		{Concept: "Batteries", Context: background},
+		{Concept: "Batteries"},
+	}, 2)
+	if err != nil {
+		t.Fatalf("BatchGenerateDirections failed: %v", err)
+	}
+	if got := atomic.LoadInt32(calls); got != 3 {
+		t.Fatalf("expected one LLM call per distinct request, got %d", got)
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected a result per request, got %d", len(results))
+	}
+	for i, result := range results {
+		if result.Error != nil || len(result.Directions) != 1 || result.Directions[0].Title != "Chemistry" {
+			t.Fatalf("result %d: unexpected %+v", i, result)
+		}
+		if result.Cached != (i == 2) {
+			t.Fatalf("result %d: expected Cached=%v, got %v", i, i == 2, result.Cached)
+		}
+	}
+}
+
+func TestBatchGenerateDirectionsReportsPerRequestErrors(t *testing.T) {
+	results, err := NewLLMOrchestrator("", "", "").BatchGenerateDirections([]BatchDirectionRequest{{Concept: "Batteries"}, {Concept: ""}}, 0)
+	if err != nil {
+		t.Fatalf("expected per-request errors only, got %v", err)
+	}
+	if results[0].Error != nil || len(results[0].Directions) == 0 || results[1].Error == nil {
+		t.Fatalf("expected the empty concept to fail on its own, got %+v", results)
+	}
+}
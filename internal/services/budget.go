@@ -0,0 +1,123 @@
+//LLM Token Budget Enforcement(令牌预算控制)
+
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+)
+
+// 方法
+// SetBudgetStore 启用按用户的令牌预算。defaultLimit 为新用户的默认额度，0 表示不限。
+func (llm *LLMOrchestrator) SetBudgetStore(store storage.BudgetStore, defaultLimit int) {
+	if llm == nil {
+		return
+	}
+	llm.budgets = store
+	llm.defaultBudget = defaultLimit
+}
+
+// ForUser 返回一个将调用计入指定用户预算的编排器副本。
+func (llm *LLMOrchestrator) ForUser(userID string) *LLMOrchestrator {
+	if llm == nil {
+		return nil
+	}
+	clone := *llm
+	clone.userID = strings.TrimSpace(userID)
+	return &clone
+}
+
+// GetBudget 返回用户当前预算，必要时创建默认预算或执行周期重置。
+func (llm *LLMOrchestrator) GetBudget(userID string) (*models.LLMBudget, error) {
+	if llm == nil || llm.budgets == nil {
+		return nil, errors.New("token budgets are not enabled")
+	}
+	if strings.TrimSpace(userID) == "" {
+		return nil, appErrors.ErrInvalidRequest
+	}
+
+	now := time.Now().UTC()
+	budget, err := llm.budgets.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if budget == nil {
+		budget = models.NewLLMBudget(userID, llm.defaultBudget, models.BudgetPeriodMonthly, now)
+		if err := llm.budgets.Save(budget); err != nil {
+			return nil, err
+		}
+		return budget, nil
+	}
+	if budget.ResetIfDue(now) {
+		if err := llm.budgets.Save(budget); err != nil {
+			return nil, err
+		}
+	}
+	return budget, nil
+}
+
+// SetBudgetLimit 更新用户额度与周期，已用量保持不变。
+func (llm *LLMOrchestrator) SetBudgetLimit(userID string, limit int, period string) (*models.LLMBudget, error) {
+	budget, err := llm.GetBudget(userID)
+	if err != nil {
+		return nil, err
+	}
+	if limit < 0 {
+		return nil, fmt.Errorf("%w: total_tokens_limit must not be negative", appErrors.ErrInvalidRequest)
+	}
+
+	budget.TotalTokensLimit = limit
+	if strings.TrimSpace(period) != "" {
+		normalized := models.NormalizeBudgetPeriod(period)
+		if normalized != budget.Period {
+			budget.Period = normalized
+			budget.ResetsAt = models.NextBudgetReset(normalized, time.Now().UTC())
+		}
+	}
+	if err := llm.budgets.Save(budget); err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
+
+// checkBudget 在用户预算不足时拒绝调用；userID 为空的调用只来自健康探测等内部请求，
+// 面向用户的入口（/api/expand、expand_thought）要求提供用户标识。
+func (llm *LLMOrchestrator) checkBudget(userID string, estimatedTokens int) error {
+	if llm.budgets == nil || userID == "" {
+		return nil
+	}
+	budget, err := llm.GetBudget(userID)
+	if err != nil {
+		return err
+	}
+	if budget.Unlimited() {
+		return nil
+	}
+	if budget.UsedTokens+estimatedTokens > budget.TotalTokensLimit {
+		return fmt.Errorf("%w: %d of %d tokens used, request needs about %d", appErrors.ErrBudgetExceeded, budget.UsedTokens, budget.TotalTokensLimit, estimatedTokens)
+	}
+	return nil
+}
+
+func (llm *LLMOrchestrator) recordUsage(userID string, tokens int) error {
+	if llm.budgets == nil || userID == "" || tokens <= 0 {
+		return nil
+	}
+	_, err := llm.budgets.AddUsage(userID, tokens)
+	return err
+}
+
+// estimatePromptTokens 使用配置的估算器（默认约 4 字符/令牌）估算提示与上下文长度。
+func (llm *LLMOrchestrator) estimatePromptTokens(prompt string, context []string) int {
+	tokens := llm.estimateTokens(prompt)
+	for _, entry := range context {
+		tokens += llm.estimateTokens(entry)
+	}
+	return tokens
+}
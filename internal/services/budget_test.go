@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/storage"
+)
+
+func TestCallLLMEnforcesUserBudget(t *testing.T) {
+	server := newChatCompletionServer(t, "ok", TokenUsage{PromptTokens: 60, CompletionTokens: 40, TotalTokens: 100})
+
+	orchestrator := NewLLMOrchestrator("key", server.URL, "")
+	orchestrator.SetBudgetStore(storage.NewInMemoryBudgetStore(), 0)
+	if _, err := orchestrator.SetBudgetLimit("alice", 150, ""); err != nil {
+		t.Fatalf("SetBudgetLimit failed: %v", err)
+	}
+
+	alice := orchestrator.ForUser("alice")
+	if _, err := alice.CallLLM(&LLMRequest{Prompt: "hi"}); err != nil {
+		t.Fatalf("first call should fit the budget: %v", err)
+	}
+	budget, err := orchestrator.GetBudget("alice")
+	if err != nil {
+		t.Fatalf("GetBudget failed: %v", err)
+	}
+	if budget.UsedTokens != 100 {
+		t.Fatalf("expected 100 used tokens, got %d", budget.UsedTokens)
+	}
+
+	if _, err := alice.CallLLM(&LLMRequest{Prompt: "hi"}); err != nil {
+		t.Fatalf("second call starts under the limit and should succeed: %v", err)
+	}
+	if _, err := alice.CallLLM(&LLMRequest{Prompt: "hi"}); !errors.Is(err, appErrors.ErrBudgetExceeded) {
+		t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+	}
+
+	if _, err := orchestrator.CallLLM(&LLMRequest{Prompt: "hi", UserID: "bob"}); err != nil {
+		t.Fatalf("default budget of 0 should be unlimited: %v", err)
+	}
+}
+
+func TestBudgetResetsAfterPeriod(t *testing.T) {
+	store := storage.NewInMemoryBudgetStore()
+	orchestrator := NewLLMOrchestrator("", "", "")
+	orchestrator.SetBudgetStore(store, 500)
+
+	budget, err := orchestrator.GetBudget("carol")
+	if err != nil {
+		t.Fatalf("GetBudget failed: %v", err)
+	}
+	budget.UsedTokens = 400
+	budget.ResetsAt = time.Now().UTC().Add(-time.Minute)
+	if err := store.Save(budget); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	budget, err = orchestrator.GetBudget("carol")
+	if err != nil {
+		t.Fatalf("GetBudget failed: %v", err)
+	}
+	if budget.UsedTokens != 0 || !budget.ResetsAt.After(time.Now()) {
+		t.Fatalf("expected budget to reset, got %+v", budget)
+	}
+}
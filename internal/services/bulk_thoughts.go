@@ -0,0 +1,124 @@
+//Bulk Thought Insertion(批量添加思维节点)
+
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const MaxBulkThoughts = 50
+
+// 结构体
+// BulkThought 是批量添加中的一项。ID 是批内引用键；ParentID 可引用会话中已有的节点，
+// 或批内更早出现的项的 ID，为空时挂到根节点下。Direction 为空时以内容作为 broad 方向标题。
+type BulkThought struct {
+	ID        string            `json:"id,omitempty"`
+	Content   string            `json:"content"`
+	Direction *models.Direction `json:"direction,omitempty"`
+	ParentID  string            `json:"parent_id,omitempty"`
+}
+
+type BulkThoughtError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+type BulkAddResult struct {
+	Added  int                `json:"added"`
+	Errors []BulkThoughtError `json:"errors"`
+}
+
+// 方法
+// BulkAddThoughts 按顺序添加多个节点，单项失败不影响其余项；全部处理完后只持久化一次（整体可撤销）。
+func (sm *SessionManager) BulkAddThoughts(sessionID string, items []BulkThought) (*BulkAddResult, error) {
+	if len(items) == 0 {
+		return nil, utils.ValidationError("thoughts are required")
+	}
+	if len(items) > MaxBulkThoughts {
+		return nil, utils.ValidationError(fmt.Sprintf("at most %d thoughts can be added at once", MaxBulkThoughts))
+	}
+
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := newSessionSnapshot(session, "add_thought")
+	result := &BulkAddResult{Errors: []BulkThoughtError{}}
+	batch := make(map[string]*models.Thought, len(items))
+	for i, item := range items {
+		thought, err := sm.attachBulkThought(session, item, batch)
+		if err != nil {
+			result.Errors = append(result.Errors, BulkThoughtError{Index: i, Error: err.Error()})
+			continue
+		}
+		if ref := strings.TrimSpace(item.ID); ref != "" {
+			batch[ref] = thought
+		}
+		result.Added++
+	}
+	if result.Added == 0 {
+		return result, nil
+	}
+	session.RecordActivity(models.ActivityThoughtAdded, fmt.Sprintf("bulk added %d thoughts", result.Added))
+
+	if err := sm.store.Update(session); err != nil {
+		return nil, err
+	}
+
+	sm.mutex.Lock()
+	sm.cache[session.ID] = session
+	sm.mutex.Unlock()
+	sm.recordSnapshot(snapshot)
+
+	return result, nil
+}
+
+// attachBulkThought 校验单项并挂到解析出的父节点下；batch 保存批内已添加的项。
+func (sm *SessionManager) attachBulkThought(session *models.Session, item BulkThought, batch map[string]*models.Thought) (*models.Thought, error) {
+	content := strings.TrimSpace(utils.SanitizeHTML(item.Content))
+	if err := utils.ValidateConcept(content); err != nil {
+		return nil, err
+	}
+	if ref := strings.TrimSpace(item.ID); ref != "" {
+		if _, exists := batch[ref]; exists {
+			return nil, utils.ValidationError(fmt.Sprintf("duplicate id %q in batch", ref))
+		}
+	}
+
+	direction := models.Direction{Type: models.Broad, Title: truncate(content, utils.MaxDirectionTitleLength)}
+	if item.Direction != nil {
+		direction = *item.Direction
+	}
+	if err := utils.ValidateDirection(&direction); err != nil {
+		return nil, err
+	}
+
+	parent := session.RootThought
+	if parentID := strings.TrimSpace(item.ParentID); parentID != "" {
+		if inBatch, ok := batch[parentID]; ok {
+			parent = inBatch
+		} else if existing, _ := session.FindThought(parentID); existing != nil {
+			parent = existing
+		} else {
+			return nil, fmt.Errorf("%w: parent_id %s", appErrors.ErrThoughtNotFound, parentID)
+		}
+	}
+
+	thought := models.NewThought(content, session.ID, direction)
+	if parent == nil {
+		session.RootThought = thought
+		return thought, nil
+	}
+	if err := sm.checkAttachDepth(parent, thought); err != nil {
+		return nil, err
+	}
+	parent.AddChild(thought)
+	return thought, nil
+}
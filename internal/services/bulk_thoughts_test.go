@@ -0,0 +1,59 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+)
+
+func TestBulkAddThoughtsResolvesBatchReferencesInOrder(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	session, err := manager.CreateSession("user", "Reading notes")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	rootID := session.RootThought.ID
+
+	result, err := manager.BulkAddThoughts(session.ID, []BulkThought{
+		{ID: "book", Content: "Thinking, Fast and Slow"},
+		{ID: "ch1", Content: "System 1 and System 2", ParentID: "book"},
+		{Content: "Anchoring bias", ParentID: "later"},
+		{ID: "later", Content: "Prospect theory", ParentID: rootID, Direction: &models.Direction{Type: models.Deep, Title: "Prospect theory"}},
+		{Content: "Bad direction", Direction: &models.Direction{Type: "sideways", Title: "x"}},
+		{Content: "Loss aversion", ParentID: "later"},
+	})
+	if err != nil {
+		t.Fatalf("BulkAddThoughts failed: %v", err)
+	}
+	if result.Added != 4 || len(result.Errors) != 2 {
+		t.Fatalf("expected 4 added and 2 errors, got %+v", result)
+	}
+	if result.Errors[0].Index != 2 || !strings.Contains(result.Errors[0].Error, "later") || result.Errors[1].Index != 4 {
+		t.Fatalf("expected the forward reference and bad direction to fail, got %+v", result.Errors)
+	}
+
+	stored, err := manager.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	titles := map[string]*models.Thought{}
+	for _, thought := range stored.GetThoughtTree() {
+		titles[thought.Content] = thought
+	}
+	book, chapter, prospect, loss := titles["Thinking, Fast and Slow"], titles["System 1 and System 2"], titles["Prospect theory"], titles["Loss aversion"]
+	if book == nil || chapter == nil || prospect == nil || loss == nil || titles["Anchoring bias"] != nil {
+		t.Fatalf("unexpected thoughts after bulk add: %v", titles)
+	}
+	if *book.ParentID != rootID || *chapter.ParentID != book.ID || *prospect.ParentID != rootID || *loss.ParentID != prospect.ID {
+		t.Fatalf("expected batch references to resolve to earlier items")
+	}
+	if prospect.Direction.Type != models.Deep || book.Direction.Type != models.Broad {
+		t.Fatalf("expected explicit and default directions, got %s and %s", prospect.Direction.Type, book.Direction.Type)
+	}
+
+	if _, err := manager.BulkAddThoughts(session.ID, make([]BulkThought, MaxBulkThoughts+1)); err == nil {
+		t.Fatalf("expected oversized batches to be rejected")
+	}
+}
@@ -0,0 +1,157 @@
+//Conceptual Distance(概念距离)
+
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const (
+	DefaultSimilarityAlertThreshold = 0.2
+
+	// maxSimilarityComparisons 限制探索方向时参与比较的已有节点数（取最新的节点），避免一次探索触发过多向量请求；
+	// 节点向量经 thoughtEmbeddingCache 缓存，内容不变的节点只请求一次。
+	maxSimilarityComparisons = 10
+)
+
+// 结构体
+// ThoughtDistance 是两个节点之间的余弦距离（0 表示语义相同，最大为 2）及其解读。
+type ThoughtDistance struct {
+	Distance       float64 `json:"distance"`
+	Interpretation string  `json:"interpretation"`
+}
+
+// 方法
+// ConceptualDistance 返回两段文本向量的余弦距离（1 - 余弦相似度）。
+func (llm *LLMOrchestrator) ConceptualDistance(textA, textB string) (float64, error) {
+	return conceptualDistance(llm, textA, textB)
+}
+
+// SetSimilarityAlertThreshold 设置探索方向时的相似度告警阈值：与已有节点的距离低于该值时记录告警，0 表示关闭。
+func (sm *SessionManager) SetSimilarityAlertThreshold(threshold float64) {
+	if threshold < 0 {
+		return
+	}
+	sm.mutex.Lock()
+	sm.similarityThreshold = threshold
+	sm.mutex.Unlock()
+}
+
+// MeasureThoughtDistance 计算会话中两个节点内容之间的概念距离。
+func (sm *SessionManager) MeasureThoughtDistance(sessionID, thoughtIDA, thoughtIDB string) (*ThoughtDistance, error) {
+	sm.mutex.RLock()
+	embedder := sm.embedder
+	sm.mutex.RUnlock()
+	if embedder == nil {
+		return nil, appErrors.ErrEmbeddingsUnavailable
+	}
+
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	tree := session.GetThoughtTree()
+	thoughtA, ok := tree[strings.TrimSpace(thoughtIDA)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", appErrors.ErrThoughtNotFound, thoughtIDA)
+	}
+	thoughtB, ok := tree[strings.TrimSpace(thoughtIDB)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", appErrors.ErrThoughtNotFound, thoughtIDB)
+	}
+
+	distance, err := conceptualDistance(embedder, thoughtA.Content, thoughtB.Content)
+	if err != nil {
+		return nil, err
+	}
+	return &ThoughtDistance{Distance: distance, Interpretation: InterpretDistance(distance)}, nil
+}
+
+// annotateSimilarDirection 在新节点与会话中已有节点过于相似时记录告警，并在节点注解中标出最相近的节点。
+// 向量服务不可用或请求失败时仅跳过检查，不影响探索。
+func (sm *SessionManager) annotateSimilarDirection(session *models.Session, direction models.Direction, thought *models.Thought) {
+	sm.mutex.RLock()
+	embedder, threshold := sm.embedder, sm.similarityThreshold
+	sm.mutex.RUnlock()
+	if embedder == nil || threshold <= 0 || session == nil || thought == nil {
+		return
+	}
+
+	existing := make([]*models.Thought, 0)
+	for _, candidate := range session.GetThoughtTree() {
+		if strings.TrimSpace(candidate.Content) != "" {
+			existing = append(existing, candidate)
+		}
+	}
+	if len(existing) == 0 {
+		return
+	}
+
+	match, ok, err := sm.nearestThought(embedder, existing, directionText(direction))
+	if err != nil {
+		utils.Debug("skipped direction similarity check", utils.KV("session_id", session.ID), utils.KV("error", err))
+		return
+	}
+	closest, closestDistance := match.thought, 1-match.similarity
+	if !ok || closestDistance >= threshold {
+		return
+	}
+
+	utils.Warn("explored direction is very similar to an existing thought",
+		utils.KV("session_id", session.ID),
+		utils.KV("direction", direction.Title),
+		utils.KV("similar_thought_id", closest.ID),
+		utils.KV("distance", closestDistance),
+	)
+	thought.SetAnnotation("similar_to", closest.ID)
+	thought.SetAnnotation("similarity_distance", fmt.Sprintf("%.2f", closestDistance))
+}
+
+// 函数
+// InterpretDistance 将概念距离映射为可读的描述。
+func InterpretDistance(distance float64) string {
+	switch {
+	case distance < 0.2:
+		return "very similar"
+	case distance < 0.5:
+		return "related"
+	case distance < 0.8:
+		return "loosely related"
+	default:
+		return "unrelated"
+	}
+}
+
+func conceptualDistance(embedder Embedder, textA, textB string) (float64, error) {
+	if strings.TrimSpace(textA) == "" || strings.TrimSpace(textB) == "" {
+		return 0, utils.ValidationError("both texts are required")
+	}
+	embeddingA, err := embedder.GetEmbedding(textA)
+	if err != nil {
+		return 0, err
+	}
+	embeddingB, err := embedder.GetEmbedding(textB)
+	if err != nil {
+		return 0, err
+	}
+	if len(embeddingA) != len(embeddingB) {
+		return 0, fmt.Errorf("embedding dimensions differ: %d vs %d", len(embeddingA), len(embeddingB))
+	}
+	return math.Max(0, math.Min(1-cosineSimilarity(embeddingA, embeddingB), 2)), nil
+}
+
+// directionText 返回用于比较的方向文本：标题加描述。
+func directionText(direction models.Direction) string {
+	text := strings.TrimSpace(direction.Title)
+	if description := strings.TrimSpace(direction.Description); description != "" {
+		text += ": " + description
+	}
+	return text
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+)
+
+func TestMeasureThoughtDistanceInterpretsCosineDistance(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	session, err := manager.CreateSession("user", "Solar power")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	if _, err := manager.MeasureThoughtDistance(session.ID, session.RootThought.ID, session.RootThought.ID); !errors.Is(err, appErrors.ErrEmbeddingsUnavailable) {
+		t.Fatalf("expected ErrEmbeddingsUnavailable without an embedder, got %v", err)
+	}
+	manager.SetEmbedder(&keywordEmbedder{})
+
+	add := func(content string) *models.Thought {
+		t.Helper()
+		thought := models.NewThought(content, session.ID, models.Direction{Type: models.Broad, Title: content})
+		if err := manager.AddThoughtToSession(session.ID, thought); err != nil {
+			t.Fatalf("AddThoughtToSession failed: %v", err)
+		}
+		return thought
+	}
+	mixed := add("Solar and wind farms")
+	cooking := add("Cooking with leftovers")
+
+	cases := []struct {
+		a, b           string
+		distance       float64
+		interpretation string
+	}{
+		{session.RootThought.ID, session.RootThought.ID, 0, "very similar"},
+		{session.RootThought.ID, mixed.ID, 1 - 1/math.Sqrt2, "related"},
+		{session.RootThought.ID, cooking.ID, 1, "unrelated"},
+	}
+	for _, tc := range cases {
+		result, err := manager.MeasureThoughtDistance(session.ID, tc.a, tc.b)
+		if err != nil {
+			t.Fatalf("MeasureThoughtDistance failed: %v", err)
+		}
+		if math.Abs(result.Distance-tc.distance) > 1e-9 || result.Interpretation != tc.interpretation {
+			t.Fatalf("expected %.3f (%s), got %+v", tc.distance, tc.interpretation, result)
+		}
+	}
+
+	if _, err := manager.MeasureThoughtDistance(session.ID, mixed.ID, "missing"); !errors.Is(err, appErrors.ErrThoughtNotFound) {
+		t.Fatalf("expected ErrThoughtNotFound, got %v", err)
+	}
+	if got := InterpretDistance(0.6); got != "loosely related" {
+		t.Fatalf("expected loosely related, got %q", got)
+	}
+}
+
+func TestExploreDirectionAnnotatesSimilarThought(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	embedder := &keywordEmbedder{}
+	manager.SetEmbedder(embedder)
+	manager.SetSimilarityAlertThreshold(DefaultSimilarityAlertThreshold)
+	llm := NewScriptedLLM().
+		QueueThoughts([]*models.Thought{models.NewThought("Rooftop solar panels", "", models.Direction{})}, nil).
+		QueueThoughts([]*models.Thought{models.NewThought("Wind turbines", "", models.Direction{})}, nil)
+	expander := NewThoughtExpander(llm, manager)
+	expander.SetThoughtDedup(0, "")
+
+	session, err := manager.CreateSession("user", "Solar power")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	similar, err := expander.ExploreDirection(models.Direction{Type: models.Deep, Title: "Solar installations"}, session.ID, "")
+	if err != nil {
+		t.Fatalf("ExploreDirection failed: %v", err)
+	}
+	if similar.Annotations["similar_to"] != session.RootThought.ID || similar.Annotations["similarity_distance"] != "0.00" {
+		t.Fatalf("expected similarity alert annotations, got %+v", similar.Annotations)
+	}
+
+	novel, err := expander.ExploreDirection(models.Direction{Type: models.Lateral, Title: "Wind"}, session.ID, "")
+	if err != nil {
+		t.Fatalf("ExploreDirection failed: %v", err)
+	}
+	if _, ok := novel.Annotations["similar_to"]; ok {
+		t.Fatalf("expected no alert for a novel direction, got %+v", novel.Annotations)
+	}
+	// 两次探索的目标文本各一次，根节点与第一次生成的节点各一次；根节点的向量来自缓存
+	if embedder.calls != 4 {
+		t.Fatalf("expected cached thought embeddings to be reused, got %d embedding calls", embedder.calls)
+	}
+}
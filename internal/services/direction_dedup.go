@@ -0,0 +1,114 @@
+//Direction Deduplication(方向去重与排序)
+
+package services
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const (
+	// DefaultDirectionDedupThreshold 为标题或关键词集合的 Jaccard 重合度阈值，达到即视为重复
+	DefaultDirectionDedupThreshold = 0.6
+)
+
+// 方法
+// SetDirectionDedupThreshold 设置方向去重阈值（0-1），0 表示关闭去重，仅按相关度排序。
+func (te *ThoughtExpander) SetDirectionDedupThreshold(threshold float64) {
+	if te == nil {
+		return
+	}
+	te.dedupThreshold = threshold
+}
+
+// 函数
+// rankDirections 按相关度从高到低稳定排序，并丢弃与更靠前方向重复的项。
+// 重复的方向中保留相关度最高者，相关度相同时保留先出现的一项。
+func rankDirections(directions []models.Direction, threshold float64) []models.Direction {
+	ranked := make([]models.Direction, len(directions))
+	copy(ranked, directions)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Relevance > ranked[j].Relevance
+	})
+	if threshold <= 0 {
+		return ranked
+	}
+
+	kept := make([]models.Direction, 0, len(ranked))
+	keptTitles := make([]map[string]struct{}, 0, len(ranked))
+	keptKeywords := make([]map[string]struct{}, 0, len(ranked))
+	for _, dir := range ranked {
+		title := titleTokens(dir.Title)
+		keywords := keywordSet(dir.Keywords)
+		duplicate := false
+		for i := range kept {
+			if directionOverlap(title, keywords, keptTitles[i], keptKeywords[i]) >= threshold {
+				duplicate = true
+				utils.Debug("dropped duplicate direction",
+					utils.KV("title", dir.Title),
+					utils.KV("duplicate_of", kept[i].Title),
+				)
+				break
+			}
+		}
+		if duplicate {
+			continue
+		}
+		kept = append(kept, dir)
+		keptTitles = append(keptTitles, title)
+		keptKeywords = append(keptKeywords, keywords)
+	}
+	return kept
+}
+
+// directionOverlap 取标题词集合与关键词集合 Jaccard 系数中的较大者；任一方没有关键词时只比较标题。
+func directionOverlap(titleA, keywordsA, titleB, keywordsB map[string]struct{}) float64 {
+	overlap := jaccard(titleA, titleB)
+	if len(keywordsA) > 0 && len(keywordsB) > 0 {
+		if score := jaccard(keywordsA, keywordsB); score > overlap {
+			overlap = score
+		}
+	}
+	return overlap
+}
+
+// titleTokens 将标题转为小写并按标点与空白切分为词集合。
+func titleTokens(title string) map[string]struct{} {
+	fields := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tokens := make(map[string]struct{}, len(fields))
+	for _, field := range fields {
+		tokens[field] = struct{}{}
+	}
+	return tokens
+}
+
+func keywordSet(keywords []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(keywords))
+	for _, keyword := range keywords {
+		normalized := strings.Join(strings.Fields(strings.ToLower(keyword)), " ")
+		if normalized != "" {
+			set[normalized] = struct{}{}
+		}
+	}
+	return set
+}
+
+func jaccard(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	intersection := 0
+	for token := range a {
+		if _, ok := b[token]; ok {
+			intersection++
+		}
+	}
+	return float64(intersection) / float64(len(a)+len(b)-intersection)
+}
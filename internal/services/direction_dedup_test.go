@@ -0,0 +1,58 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+)
+
+func duplicateDirectionSet() []models.Direction {
+	return []models.Direction{
+		{Type: models.Deep, Title: "Deep dive into fundamentals", Keywords: []string{"fundamentals", "principles", "basics"}, Relevance: 0.5},
+		{Type: models.Broad, Title: "Market adoption", Keywords: []string{"markets"}, Relevance: 0.7},
+		{Type: models.Deep, Title: "Explore core principles", Keywords: []string{"Principles", "fundamentals", " basics "}, Relevance: 0.8},
+		{Type: models.Critical, Title: "Market adoption!", Keywords: []string{"adoption"}, Relevance: 0.7},
+		{Type: models.Lateral, Title: "Recycling", Keywords: []string{"recycling"}, Relevance: 0.9},
+	}
+}
+
+func directionTitles(directions []models.Direction) string {
+	titles := make([]string, 0, len(directions))
+	for _, dir := range directions {
+		titles = append(titles, dir.Title)
+	}
+	return strings.Join(titles, ",")
+}
+
+func TestRankDirectionsDropsDuplicatesAndSortsByRelevance(t *testing.T) {
+	ranked := rankDirections(duplicateDirectionSet(), DefaultDirectionDedupThreshold)
+
+	// 关键词重合的两项保留相关度更高者；标题归一化后相同且相关度相同时保留先出现者
+	if got := directionTitles(ranked); got != "Recycling,Explore core principles,Market adoption" {
+		t.Fatalf("unexpected survivors %s", got)
+	}
+	if ranked[2].Type != models.Broad {
+		t.Fatalf("expected first occurrence to win the tie, got %+v", ranked[2])
+	}
+
+	unfiltered := rankDirections(duplicateDirectionSet(), 0)
+	if got := directionTitles(unfiltered); got != "Recycling,Explore core principles,Market adoption,Market adoption!,Deep dive into fundamentals" {
+		t.Fatalf("expected sorting without deduplication, got %s", got)
+	}
+}
+
+func TestExpandRanksBeforeMaxDirectionsCut(t *testing.T) {
+	scripted := NewScriptedLLM().QueueDirections(duplicateDirectionSet(), nil)
+	scripted.QueueThoughts(nil, nil).QueueThoughts(nil, nil)
+	expander := NewThoughtExpander(scripted, NewSessionManager(storage.NewInMemorySessionStore()))
+
+	result, err := expander.Expand(&ExpansionRequest{Concept: "Batteries", MaxDirections: 2, ConcurrencyLimit: 1})
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if got := directionTitles(result.Directions); got != "Recycling,Explore core principles" {
+		t.Fatalf("expected the two most relevant distinct directions, got %s", got)
+	}
+}
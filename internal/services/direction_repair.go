@@ -0,0 +1,56 @@
+//Direction Repair(方向解析修复)
+
+package services
+
+import (
+	"fmt"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const (
+	// maxRepairOutputRunes 限制修复提示中回传的无效输出长度
+	maxRepairOutputRunes = 4000
+	repairMaxTokens      = 1024
+)
+
+// 方法
+// SetDirectionRepair 设置方向解析失败时是否请求模型修正 JSON 后重试一次（默认开启）。
+func (llm *LLMOrchestrator) SetDirectionRepair(enabled bool) {
+	if llm == nil {
+		return
+	}
+	llm.repairDisabled = !enabled
+}
+
+// repairDirections 将无法解析的输出与解析错误发回模型，要求只返回修正后的 JSON，并再次解析。
+// 修复请求不走流式输出，避免向客户端重复推送内容。
+func (llm *LLMOrchestrator) repairDirections(invalid string, parseErr error) ([]models.Direction, *LLMResponse, error) {
+	resp, err := llm.withoutStream().CallLLM(&LLMRequest{
+		Prompt:         buildRepairPrompt(invalid, parseErr),
+		MaxTokens:      repairMaxTokens,
+		ResponseFormat: directionsResponseFormat,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	directions, err := llm.parseDirectionsFromContent(resp.Content)
+	if err != nil {
+		return nil, nil, err
+	}
+	utils.Info("repaired LLM directions response", utils.KV("directions", len(directions)))
+	return directions, resp, nil
+}
+
+// 函数
+func buildRepairPrompt(invalid string, parseErr error) string {
+	return fmt.Sprintf(
+		"Your previous response could not be parsed as JSON (%v).\n"+
+			"Return only the corrected JSON: an object with a %q array whose items have type, title, description, keywords and relevance. "+
+			"Do not add commentary or code fences, and keep the original content.\n\n"+
+			"Previous response:\n%s",
+		parseErr, directionsResponseKey, truncate(invalid, maxRepairOutputRunes),
+	)
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const brokenDirectionsJSON = `{"directions":[{"type":"deep","title":"Chemistry","description":"Cell chemistry",}]}`
+
+// newScriptedBackend 依次返回 contents 中的内容，超出后重复最后一项；status 非 200 时直接返回该状态码。
+func newScriptedBackend(t *testing.T, status int, contents []string, prompts *[]string) (*httptest.Server, *int32) {
+	t.Helper()
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		index := int(atomic.AddInt32(&calls, 1)) - 1
+		if status != http.StatusOK {
+			http.Error(w, "upstream unavailable", status)
+			return
+		}
+		var payload struct {
+			Messages []capturedMessage `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		if prompts != nil && len(payload.Messages) > 0 {
+			*prompts = append(*prompts, payload.Messages[len(payload.Messages)-1].Content)
+		}
+		content := contents[min(index, len(contents)-1)]
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"content": content}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+// newRepairTestLLM 固定响应语言，避免语言检测请求计入调用次数。
+func newRepairTestLLM(t *testing.T, baseURL string) *LLMOrchestrator {
+	t.Helper()
+	llm := NewLLMOrchestrator("key", baseURL, "model")
+	if err := llm.SetForceResponseLanguage("en"); err != nil {
+		t.Fatalf("SetForceResponseLanguage failed: %v", err)
+	}
+	return llm
+}
+
+func TestGenerateThoughtDirectionsRepairsBrokenJSON(t *testing.T) {
+	fixed := `{"directions":[{"type":"deep","title":"Chemistry","description":"Cell chemistry"}]}`
+	var prompts []string
+	server, calls := newScriptedBackend(t, http.StatusOK, []string{brokenDirectionsJSON, fixed}, &prompts)
+	llm := newRepairTestLLM(t, server.URL)
+
+	directions, err := llm.GenerateThoughtDirections("Batteries", nil)
+	if err != nil {
+		t.Fatalf("GenerateThoughtDirections failed: %v", err)
+	}
+	if atomic.LoadInt32(calls) != 2 {
+		t.Fatalf("expected exactly two calls, got %d", *calls)
+	}
+	if len(directions) != 1 || directions[0].Title != "Chemistry" || directions[0].Provenance == nil || directions[0].Provenance.Model == localFallbackModel {
+		t.Fatalf("expected repaired directions, got %+v", directions)
+	}
+	if len(prompts) != 2 || !strings.Contains(prompts[1], brokenDirectionsJSON) || !strings.Contains(prompts[1], "could not be parsed") {
+		t.Fatalf("expected repair prompt to include the invalid output and parse error, got %q", prompts)
+	}
+}
+
+func TestGenerateThoughtDirectionsSkipsRepairWhenDisabledOrTransportFails(t *testing.T) {
+	server, calls := newScriptedBackend(t, http.StatusOK, []string{brokenDirectionsJSON}, nil)
+	llm := newRepairTestLLM(t, server.URL)
+	llm.SetDirectionRepair(false)
+
+	directions, err := llm.GenerateThoughtDirections("Batteries", nil)
+	if err != nil {
+		t.Fatalf("GenerateThoughtDirections failed: %v", err)
+	}
+	if atomic.LoadInt32(calls) != 1 || len(directions) == 0 || directions[0].Provenance.Model != localFallbackModel {
+		t.Fatalf("expected a single call and fallback directions, got %d calls and %+v", *calls, directions)
+	}
+
+	failing, failingCalls := newScriptedBackend(t, http.StatusServiceUnavailable, nil, nil)
+	llm = newRepairTestLLM(t, failing.URL)
+	llm.SetRetryPolicy(1, 0)
+	if _, err := llm.GenerateThoughtDirections("Batteries", nil); err != nil {
+		t.Fatalf("GenerateThoughtDirections failed: %v", err)
+	}
+	if atomic.LoadInt32(failingCalls) != 1 {
+		t.Fatalf("expected no repair after a transport error, got %d calls", *failingCalls)
+	}
+}
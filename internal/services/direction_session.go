@@ -0,0 +1,57 @@
+//Direction To Session(方向转为独立会话)
+
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const sourceSessionContextKey = "source_session"
+
+// 方法
+// DirectionToSession 以源会话中某个思维的内容为概念新建会话：方向关键词作为背景上下文，
+// 并记录 "source_session: <id>" 便于追溯。userID 为空时沿用源会话的用户。
+func (te *ThoughtExpander) DirectionToSession(sourceSessionID, thoughtID, userID string) (*models.Session, error) {
+	if te == nil || te.sessionManager == nil {
+		return nil, errors.New("thought expander is not initialized")
+	}
+	if sourceSessionID == "" || thoughtID == "" {
+		return nil, appErrors.ErrInvalidRequest
+	}
+
+	source, err := te.sessionManager.GetSession(sourceSessionID)
+	if err != nil {
+		return nil, err
+	}
+	thought, _ := source.FindThought(thoughtID)
+	if thought == nil {
+		return nil, fmt.Errorf("%w: %s", appErrors.ErrThoughtNotFound, thoughtID)
+	}
+	if userID == "" {
+		userID = source.UserID
+	}
+	concept := strings.TrimSpace(thought.Content)
+	if err := utils.ValidateConcept(concept); err != nil {
+		return nil, err
+	}
+
+	session, err := te.sessionManager.CreateSession(userID, concept)
+	if err != nil {
+		return nil, err
+	}
+	for _, keyword := range thought.Direction.Keywords {
+		session.AddContextEntry(models.NewContextEntry(models.ContextBackground, keyword))
+	}
+	session.AddContextEntry(models.NewContextEntry(models.ContextNote, fmt.Sprintf("%s: %s", sourceSessionContextKey, source.ID)))
+	if err := te.sessionManager.UpdateSession(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+)
+
+func TestDirectionToSessionSeedsNewSession(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	expander := NewThoughtExpander(NewLLMOrchestrator("", "", ""), manager)
+	source, err := manager.CreateSession("alice", "Energy")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	thought := models.NewThought("Flow batteries for the grid", source.ID, models.Direction{
+		Type: models.Deep, Title: "Flow", Keywords: []string{"vanadium", "electrolyte"},
+	})
+	if err := manager.AddThoughtToSession(source.ID, thought); err != nil {
+		t.Fatalf("AddThoughtToSession failed: %v", err)
+	}
+
+	session, err := expander.DirectionToSession(source.ID, thought.ID, "")
+	if err != nil {
+		t.Fatalf("DirectionToSession failed: %v", err)
+	}
+	if session.ID == source.ID || session.UserID != "alice" || session.RootThought.Content != "Flow batteries for the grid" {
+		t.Fatalf("unexpected new session %+v", session)
+	}
+	want := []models.ContextEntry{
+		models.NewContextEntry(models.ContextNote, "Flow batteries for the grid"),
+		models.NewContextEntry(models.ContextBackground, "vanadium"),
+		models.NewContextEntry(models.ContextBackground, "electrolyte"),
+		models.NewContextEntry(models.ContextNote, "source_session: "+source.ID),
+	}
+	stored, err := manager.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if len(stored.ContextEntries) != len(want) {
+		t.Fatalf("expected context %+v, got %+v", want, stored.ContextEntries)
+	}
+	for i, entry := range want {
+		if stored.ContextEntries[i] != entry {
+			t.Fatalf("context %d: expected %+v, got %+v", i, entry, stored.ContextEntries[i])
+		}
+	}
+
+	other, err := expander.DirectionToSession(source.ID, thought.ID, "bob")
+	if err != nil || other.UserID != "bob" {
+		t.Fatalf("expected a session for bob, got %+v (%v)", other, err)
+	}
+	if _, err := expander.DirectionToSession(source.ID, "missing", ""); !errors.Is(err, appErrors.ErrThoughtNotFound) {
+		t.Fatalf("expected ErrThoughtNotFound, got %v", err)
+	}
+}
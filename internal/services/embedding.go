@@ -0,0 +1,115 @@
+//Embeddings(文本向量)
+
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const DefaultEmbeddingModel = "text-embedding-3-small"
+
+// 接口
+// Embedder 将文本转换为向量，用于会话相似度检索。
+type Embedder interface {
+	GetEmbedding(text string) ([]float64, error)
+	// EmbeddingModel 标识向量所属的模型，不同模型的向量不可比较。
+	EmbeddingModel() string
+}
+
+// 方法
+// SetEmbeddingModel 设置 /v1/embeddings 使用的模型（Azure 下为部署名），空值恢复默认。
+func (llm *LLMOrchestrator) SetEmbeddingModel(model string) {
+	if llm == nil {
+		return
+	}
+	llm.embeddingModel = strings.TrimSpace(model)
+}
+
+func (llm *LLMOrchestrator) EmbeddingModel() string {
+	if llm == nil || llm.embeddingModel == "" {
+		return DefaultEmbeddingModel
+	}
+	return llm.embeddingModel
+}
+
+// GetEmbedding 调用 OpenAI 兼容的 /v1/embeddings 接口返回文本向量；未配置远程后端时返回 ErrEmbeddingsUnavailable。
+func (llm *LLMOrchestrator) GetEmbedding(text string) ([]float64, error) {
+	if !llm.hasRemoteBackend() {
+		return nil, appErrors.ErrEmbeddingsUnavailable
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, utils.ValidationError("embedding input is empty")
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"model": llm.EmbeddingModel(),
+		"input": text,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal embedding payload: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(llm.requestContext(), llm.timeout)
+	defer cancel()
+
+	if err := llm.breaker.Allow(); err != nil {
+		return nil, err
+	}
+	raw, _, err := llm.postWithRetry(ctx, llm.embeddingEndpoint(), body)
+	if err != nil {
+		if isUpstreamFailure(err) {
+			llm.breaker.RecordFailure()
+		} else {
+			llm.breaker.RecordSuccess()
+		}
+		return nil, err
+	}
+	llm.breaker.RecordSuccess()
+
+	var parsed struct {
+		Data []struct {
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+		Usage struct {
+			TotalTokens int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return nil, fmt.Errorf("decode embedding response: %w", err)
+	}
+	if len(parsed.Data) == 0 || len(parsed.Data[0].Embedding) == 0 {
+		return nil, errors.New("embedding response is empty")
+	}
+	if err := llm.recordUsage(llm.userID, parsed.Usage.TotalTokens); err != nil {
+		utils.Warn("failed to record embedding token usage", utils.KV("user_id", llm.userID), utils.KV("error", err))
+	}
+	return parsed.Data[0].Embedding, nil
+}
+
+// embeddingEndpoint 返回向量接口地址：Azure 按部署名路由，Ollama 使用其 OpenAI 兼容接口。
+func (llm *LLMOrchestrator) embeddingEndpoint() string {
+	if llm.isAzure() {
+		base := strings.TrimRight(llm.baseURL, "/")
+		if i := strings.Index(base, "/openai/"); i >= 0 {
+			base = base[:i]
+		}
+		return fmt.Sprintf("%s/openai/deployments/%s/embeddings?api-version=%s",
+			base, url.PathEscape(llm.EmbeddingModel()), url.QueryEscape(llm.azureAPIVersion))
+	}
+	if llm.isOllama() {
+		return llm.ollamaURL("/v1/embeddings")
+	}
+	base := strings.TrimRight(llm.baseURL, "/")
+	base = strings.TrimSuffix(base, "/v1/chat/completions")
+	return base + "/v1/embeddings"
+}
@@ -0,0 +1,66 @@
+//Expansion Node Budget(扩展节点预算)
+
+package services
+
+import (
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const (
+	// DefaultMaxDirections 是请求未指定 MaxDirections 时保留的方向数
+	DefaultMaxDirections = 5
+
+	nodeBudgetExhaustedMessage = "expansion node budget exhausted"
+)
+
+// 方法
+// SetNodeBudget 限制单个会话的思维节点总数（含扩展预览），非正值表示不限。
+// 扩展达到预算时返回截断的部分结果，深入探索只生成剩余预算允许的层数。
+func (te *ThoughtExpander) SetNodeBudget(budget int) {
+	if te == nil {
+		return
+	}
+	te.nodeBudget = max(budget, 0)
+}
+
+// remainingNodes 返回会话在节点预算内还可新增的节点数，-1 表示不限。
+func (te *ThoughtExpander) remainingNodes(session *models.Session) int {
+	if te.nodeBudget <= 0 {
+		return -1
+	}
+	used := 0
+	if session != nil {
+		used = len(session.GetThoughtTree())
+	}
+	return max(te.nodeBudget-used, 0)
+}
+
+// expansionCapacity 返回本次扩展最多生成的预览数：取 MaxTotalThoughts 与节点预算剩余量
+// （指定 SessionID 时扣除会话已有节点）中较小者，-1 表示不限。
+func (te *ThoughtExpander) expansionCapacity(req *ExpansionRequest) (int, error) {
+	if req.MaxTotalThoughts < 0 {
+		return 0, utils.ValidationError("max_total_thoughts must not be negative")
+	}
+	capacity := -1
+	if req.MaxTotalThoughts > 0 {
+		capacity = req.MaxTotalThoughts
+	}
+	if te.nodeBudget <= 0 {
+		return capacity, nil
+	}
+
+	var session *models.Session
+	if req.SessionID != "" && te.sessionManager != nil {
+		var err error
+		if session, err = te.sessionManager.GetSession(req.SessionID); err != nil {
+			return 0, err
+		}
+	}
+	remaining := te.remainingNodes(session)
+	if capacity < 0 || remaining < capacity {
+		capacity = remaining
+	}
+	return capacity, nil
+}
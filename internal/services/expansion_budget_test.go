@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+)
+
+// wideGenerator 返回 12 个互不相似的方向，并为每个方向返回一个预览。
+type wideGenerator struct {
+	previews int32
+}
+
+var wideDirectionTitles = []string{
+	"Anodes", "Cathodes", "Electrolytes", "Recycling", "Mining", "Pricing",
+	"Safety", "Regulation", "Logistics", "Thermals", "Software", "Warranty",
+}
+
+func (g *wideGenerator) GenerateThoughtDirections(concept string, context []models.ContextEntry) ([]models.Direction, error) {
+	directions := make([]models.Direction, 0, len(wideDirectionTitles))
+	for i, title := range wideDirectionTitles {
+		directions = append(directions, models.Direction{Type: models.Broad, Title: title, Description: title + " angle", Relevance: 1 - float64(i)/100})
+	}
+	return directions, nil
+}
+
+func (g *wideGenerator) ExploreDirection(direction models.Direction, depth int, context []models.ContextEntry) ([]*models.Thought, error) {
+	atomic.AddInt32(&g.previews, 1)
+	return []*models.Thought{models.NewThought(direction.Title+" preview", "", direction)}, nil
+}
+
+func (g *wideGenerator) CallLLM(req *LLMRequest) (*LLMResponse, error) {
+	return nil, errors.New("not scripted")
+}
+
+func (g *wideGenerator) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+func TestExpandDefaultsToFiveDirections(t *testing.T) {
+	generator := &wideGenerator{}
+	expander := NewThoughtExpander(generator, NewSessionManager(storage.NewInMemorySessionStore()))
+
+	result, err := expander.Expand(&ExpansionRequest{Concept: "Batteries"})
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if len(result.Directions) != DefaultMaxDirections || len(result.Thoughts) != DefaultMaxDirections || result.Truncated {
+		t.Fatalf("expected %d untruncated directions, got %d directions, %d thoughts, truncated=%v",
+			DefaultMaxDirections, len(result.Directions), len(result.Thoughts), result.Truncated)
+	}
+
+	result, err = expander.Expand(&ExpansionRequest{Concept: "Batteries", MaxDirections: 12})
+	if err != nil || len(result.Thoughts) != 12 || result.Truncated {
+		t.Fatalf("expected an explicit MaxDirections to keep all 12 directions, got %+v (%v)", result, err)
+	}
+}
+
+func TestExpandHonorsMaxTotalThoughts(t *testing.T) {
+	generator := &wideGenerator{}
+	expander := NewThoughtExpander(generator, NewSessionManager(storage.NewInMemorySessionStore()))
+
+	result, err := expander.Expand(&ExpansionRequest{Concept: "Batteries", MaxDirections: 12, MaxTotalThoughts: 3})
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if !result.Truncated || len(result.Directions) != 3 || len(result.Thoughts) != 3 {
+		t.Fatalf("expected a truncated result with 3 thoughts, got %d directions, %d thoughts, truncated=%v",
+			len(result.Directions), len(result.Thoughts), result.Truncated)
+	}
+	if got := atomic.LoadInt32(&generator.previews); got != 3 {
+		t.Fatalf("expected previews only within the cap, got %d", got)
+	}
+	if _, err := expander.Expand(&ExpansionRequest{Concept: "Batteries", MaxTotalThoughts: -1}); !errors.Is(err, appErrors.ErrInvalidRequest) {
+		t.Fatalf("expected a negative cap to be rejected, got %v", err)
+	}
+}
+
+func TestExpandNodeBudgetCountsPersistedThoughts(t *testing.T) {
+	generator := &wideGenerator{}
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	expander := NewThoughtExpander(generator, manager)
+	expander.SetNodeBudget(8)
+
+	session, err := manager.CreateSession("user", "Batteries")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	for _, content := range []string{"Supply", "Demand", "Storage"} {
+		if err := manager.AddThoughtToSession(session.ID, models.NewThought(content, session.ID, models.Direction{Type: models.Broad, Title: content})); err != nil {
+			t.Fatalf("AddThoughtToSession failed: %v", err)
+		}
+	}
+
+	// 预算 8，会话已有 4 个节点（根 + 3），最多还能生成 4 个预览
+	result, err := expander.Expand(&ExpansionRequest{Concept: "Batteries", MaxDirections: 12, SessionID: session.ID})
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if !result.Truncated || len(result.Thoughts) != 4 {
+		t.Fatalf("expected 4 previews within the node budget, got %d (truncated=%v)", len(result.Thoughts), result.Truncated)
+	}
+
+	// 不指定会话时只计算本次预览
+	result, err = expander.Expand(&ExpansionRequest{Concept: "Batteries", MaxDirections: 12, MaxTotalThoughts: 10})
+	if err != nil || !result.Truncated || len(result.Thoughts) != 8 {
+		t.Fatalf("expected the node budget to cap the request limit at 8, got %+v (%v)", result, err)
+	}
+
+	expander.SetNodeBudget(4)
+	before := atomic.LoadInt32(&generator.previews)
+	result, err = expander.Expand(&ExpansionRequest{Concept: "Batteries", SessionID: session.ID})
+	if err != nil || !result.Truncated || len(result.Thoughts) != 0 || len(result.Directions) != 0 {
+		t.Fatalf("expected an empty truncated result once the budget is spent, got %+v (%v)", result, err)
+	}
+	if atomic.LoadInt32(&generator.previews) != before {
+		t.Fatalf("expected no generation once the budget is spent")
+	}
+	if _, err := expander.ExploreDirection(models.Direction{Type: models.Deep, Title: "More"}, session.ID, ""); !errors.Is(err, appErrors.ErrInvalidRequest) {
+		t.Fatalf("expected exploring past the budget to fail, got %v", err)
+	}
+}
+
+func TestDeepDiveStopsAtNodeBudget(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	expander := NewThoughtExpander(NewLLMOrchestrator("", "", ""), manager)
+	expander.SetNodeBudget(3)
+	session, err := manager.CreateSession("user", "Batteries")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	root, err := expander.DeepDive(session.ID, "", models.Direction{Type: models.Deep, Title: "Chemistry"}, 5)
+	if err != nil {
+		t.Fatalf("DeepDive failed: %v", err)
+	}
+	stored, _ := manager.GetSession(session.ID)
+	if len(stored.GetThoughtTree()) != 3 || len(root.Children) != 1 || len(root.Children[0].Children) != 0 {
+		t.Fatalf("expected the chain to stop at the budget, got %d nodes", len(stored.GetThoughtTree()))
+	}
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+)
+
+func TestExpandMinRelevanceDropsWeakDirections(t *testing.T) {
+	expander := NewThoughtExpander(&nodeGenerator{}, NewSessionManager(storage.NewInMemorySessionStore()))
+
+	result, err := expander.Expand(&ExpansionRequest{Concept: "Flow batteries", MinRelevance: 0.85})
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if len(result.Directions) != 1 || result.Directions[0].Title != "Membranes" || len(result.Thoughts) != 1 || result.EmptyReason != "" {
+		t.Fatalf("expected only the strong direction, got %+v", result)
+	}
+
+	if _, err := expander.Expand(&ExpansionRequest{Concept: "Flow batteries", MinRelevance: 1.5}); !errors.Is(err, appErrors.ErrInvalidRequest) {
+		t.Fatalf("expected a validation error for min_relevance above 1, got %v", err)
+	}
+}
+
+func TestExpandFiltersReturnEmptyResultUnlessFallbackRequested(t *testing.T) {
+	generator := &nodeGenerator{}
+	expander := NewThoughtExpander(generator, NewSessionManager(storage.NewInMemorySessionStore()))
+
+	for name, req := range map[string]*ExpansionRequest{
+		"relevance": {Concept: "Flow batteries", MinRelevance: 0.95},
+		"type":      {Concept: "Flow batteries", ExpansionType: models.Critical},
+	} {
+		result, err := expander.Expand(req)
+		if err != nil {
+			t.Fatalf("%s: Expand failed: %v", name, err)
+		}
+		if len(result.Directions) != 0 || len(result.Thoughts) != 0 || result.EmptyReason == "" {
+			t.Fatalf("%s: expected an explained empty result, got %+v", name, result)
+		}
+
+		req.FallbackIfEmpty = true
+		result, err = expander.Expand(req)
+		if err != nil {
+			t.Fatalf("%s: Expand failed: %v", name, err)
+		}
+		if len(result.Directions) != 2 || len(result.Thoughts) != 2 || result.EmptyReason != "" {
+			t.Fatalf("%s: expected the fallback to keep both directions, got %+v", name, result)
+		}
+	}
+
+	result, err := expander.Expand(&ExpansionRequest{Concept: "Flow batteries", MinRelevance: 0.95})
+	if err != nil || !strings.Contains(result.EmptyReason, "0.95") {
+		t.Fatalf("expected the threshold in the reason, got %q (%v)", result.EmptyReason, err)
+	}
+}
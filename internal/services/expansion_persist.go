@@ -0,0 +1,70 @@
+//Expansion Persistence(扩展结果写入会话)
+
+package services
+
+import (
+	"fmt"
+	"time"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const persistSessionRequiredMessage = "session_id is required to persist an expansion"
+
+// 方法
+// persistTarget 返回 Persist 请求要写入的会话与父节点；在调用模型前检查，避免无效请求浪费生成。
+func (te *ThoughtExpander) persistTarget(req *ExpansionRequest) (*models.Session, *models.Thought, error) {
+	if req.SessionID == "" || te.sessionManager == nil {
+		return nil, nil, utils.ValidationError(persistSessionRequiredMessage)
+	}
+	session, err := te.sessionManager.GetSession(req.SessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	parent := session.RootThought
+	if req.ParentThoughtID != "" {
+		parent, _ = session.FindThought(req.ParentThoughtID)
+		if parent == nil {
+			return nil, nil, fmt.Errorf("%w: %s", appErrors.ErrThoughtNotFound, req.ParentThoughtID)
+		}
+	}
+	return session, parent, nil
+}
+
+// persistExpansion 将预览思维挂到目标节点下并一次性保存，写入的 ID 记入 result。
+func (te *ThoughtExpander) persistExpansion(req *ExpansionRequest, result *ExpansionResult) error {
+	session, parent, err := te.persistTarget(req)
+	if err != nil {
+		return err
+	}
+	result.PersistedThoughtIDs = make([]string, 0, len(result.Thoughts))
+	if len(result.Thoughts) == 0 {
+		return nil
+	}
+
+	if parent == nil {
+		return appErrors.ErrInvalidRequest
+	}
+	if parent.Depth+1 > te.sessionManager.MaxThoughtDepth() {
+		return utils.ValidationError(maxDepthReachedMessage)
+	}
+
+	snapshot := newSessionSnapshot(session, "expand")
+	for _, thought := range result.Thoughts {
+		thought.SessionID = session.ID
+		thought.Children = nil
+		parent.AddChild(thought)
+		session.RecordActivity(models.ActivityDirectionExplored, thought.Direction.Title)
+		result.PersistedThoughtIDs = append(result.PersistedThoughtIDs, thought.ID)
+	}
+
+	session.UpdatedAt = time.Now().UTC()
+	if err := te.sessionManager.UpdateSession(session); err != nil {
+		return err
+	}
+	te.sessionManager.recordSnapshot(snapshot)
+	return nil
+}
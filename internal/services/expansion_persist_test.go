@@ -0,0 +1,91 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+)
+
+func TestExpandPersistsPreviewThoughts(t *testing.T) {
+	store := storage.NewInMemorySessionStore()
+	manager := NewSessionManager(store)
+	expander := NewThoughtExpander(&wideGenerator{}, manager)
+	session, err := manager.CreateSession("user", "Batteries")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	anchor := models.NewThought("Grid storage", session.ID, models.Direction{Type: models.Broad, Title: "Grid"})
+	if err := manager.AddThoughtToSession(session.ID, anchor); err != nil {
+		t.Fatalf("AddThoughtToSession failed: %v", err)
+	}
+
+	result, err := expander.Expand(&ExpansionRequest{Concept: "Batteries", MaxDirections: 3, SessionID: session.ID, Persist: true, ParentThoughtID: anchor.ID})
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if len(result.Thoughts) != 3 || len(result.PersistedThoughtIDs) != 3 {
+		t.Fatalf("expected three persisted previews, got %d thoughts and ids %v", len(result.Thoughts), result.PersistedThoughtIDs)
+	}
+
+	stored, err := store.Get(session.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got := len(stored.GetThoughtTree()); got != 2+3 {
+		t.Fatalf("expected root, anchor and three previews, got %d thoughts", got)
+	}
+	for i, id := range result.PersistedThoughtIDs {
+		thought, parent := stored.FindThought(id)
+		if thought == nil || parent == nil || parent.ID != anchor.ID || thought.Content != result.Thoughts[i].Content {
+			t.Fatalf("expected preview %d stored under the anchor, got %+v under %+v", i, thought, parent)
+		}
+	}
+}
+
+func TestExpandWithoutPersistLeavesStoreUntouched(t *testing.T) {
+	store := storage.NewInMemorySessionStore()
+	manager := NewSessionManager(store)
+	expander := NewThoughtExpander(&wideGenerator{}, manager)
+	session, err := manager.CreateSession("user", "Batteries")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	result, err := expander.Expand(&ExpansionRequest{Concept: "Batteries", MaxDirections: 3, SessionID: session.ID})
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if len(result.Thoughts) != 3 || result.PersistedThoughtIDs != nil {
+		t.Fatalf("expected three unpersisted previews, got %+v", result)
+	}
+	stored, err := store.Get(session.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got := len(stored.GetThoughtTree()); got != 1 || !stored.UpdatedAt.Equal(session.UpdatedAt) {
+		t.Fatalf("expected the stored session unchanged, got %d thoughts", got)
+	}
+}
+
+func TestExpandPersistRequiresTarget(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	generator := &wideGenerator{}
+	expander := NewThoughtExpander(generator, manager)
+	session, err := manager.CreateSession("user", "Batteries")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	if _, err := expander.Expand(&ExpansionRequest{Concept: "Batteries", Persist: true}); !errors.Is(err, appErrors.ErrInvalidRequest) {
+		t.Fatalf("expected a validation error without a session, got %v", err)
+	}
+	if _, err := expander.Expand(&ExpansionRequest{Concept: "Batteries", SessionID: session.ID, Persist: true, ParentThoughtID: "missing"}); !errors.Is(err, appErrors.ErrThoughtNotFound) {
+		t.Fatalf("expected ErrThoughtNotFound, got %v", err)
+	}
+	if generator.previews != 0 {
+		t.Fatalf("expected no generation for invalid persist targets, got %d previews", generator.previews)
+	}
+}
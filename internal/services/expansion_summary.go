@@ -0,0 +1,102 @@
+//Expansion Summary(扩展结果摘要)
+
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const (
+	summaryMaxTokens   = 160
+	summaryTemperature = 0.3
+	maxSummaryLength   = 600
+)
+
+// 方法
+// SummarizeExpansion 请求 LLM 用一段话概括为 concept 生成的方向；
+// 未配置后端、调用失败或回复为空时返回 templateExpansionSummary 的结果，超出预算时返回错误。
+func (llm *LLMOrchestrator) SummarizeExpansion(concept string, directions []models.Direction) (string, error) {
+	fallback := templateExpansionSummary(concept, directions)
+	if len(directions) == 0 || !llm.hasRemoteBackend() {
+		return fallback, nil
+	}
+
+	context := make([]models.ContextEntry, 0, len(directions))
+	for _, dir := range directions {
+		note := fmt.Sprintf("%s direction: %s", dir.Type, dir.Title)
+		if desc := strings.TrimSpace(dir.Description); desc != "" {
+			note += " - " + desc
+		}
+		context = append(context, models.NewContextEntry(models.ContextNote, note))
+	}
+	resp, err := llm.CallLLM(&LLMRequest{
+		Prompt:      llm.BuildPrompt(concept, context, "summary"),
+		Temperature: float64Ptr(summaryTemperature),
+		MaxTokens:   summaryMaxTokens,
+	})
+	if errors.Is(err, appErrors.ErrBudgetExceeded) || isCanceled(err) {
+		return "", err
+	} else if err != nil {
+		utils.Warn("LLM call failed while summarizing an expansion", utils.KV("error", err))
+		return fallback, nil
+	}
+
+	text, _ := stripCodeFences(resp.Content)
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return fallback, nil
+	}
+	return truncate(text, maxSummaryLength), nil
+}
+
+// 函数
+// templateExpansionSummary 根据方向标题与类型生成确定性的一段话摘要，不调用 LLM。
+func templateExpansionSummary(concept string, directions []models.Direction) string {
+	if len(directions) == 0 {
+		return fmt.Sprintf("No directions were generated for %q.", concept)
+	}
+
+	titles := make([]string, 0, len(directions))
+	counts := map[models.DirectionType]int{}
+	types := make([]models.DirectionType, 0, 4)
+	for _, dir := range directions {
+		titles = append(titles, fmt.Sprintf("%q", dir.Title))
+		if dir.Type == "" {
+			continue
+		}
+		if counts[dir.Type] == 0 {
+			types = append(types, dir.Type)
+		}
+		counts[dir.Type]++
+	}
+
+	noun := "directions"
+	if len(directions) == 1 {
+		noun = "direction"
+	}
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Generated %d %s for %q: %s.", len(directions), noun, concept, models.JoinWithAnd(titles))
+	if len(types) > 0 {
+		// 按出现次数降序，次数相同时保持方向顺序
+		emphasis := make([]string, 0, len(types))
+		for len(types) > 0 {
+			best := 0
+			for i, dirType := range types {
+				if counts[dirType] > counts[types[best]] {
+					best = i
+				}
+			}
+			emphasis = append(emphasis, string(types[best]))
+			types = append(types[:best], types[best+1:]...)
+		}
+		fmt.Fprintf(&builder, " The expansion emphasizes %s thinking.", models.JoinWithAnd(emphasis))
+	}
+	return builder.String()
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+)
+
+func TestTemplateExpansionSummaryMentionsEveryDirection(t *testing.T) {
+	directions := []models.Direction{
+		{Type: models.Critical, Title: "Risk analysis"},
+		{Type: models.Lateral, Title: "Adjacent domains"},
+		{Type: models.Critical, Title: "Failure modes"},
+	}
+
+	summary := templateExpansionSummary("Flow batteries", directions)
+	for _, dir := range directions {
+		if !strings.Contains(summary, dir.Title) {
+			t.Fatalf("expected %q in the summary, got %q", dir.Title, summary)
+		}
+	}
+	if !strings.Contains(summary, "Generated 3 directions") || !strings.Contains(summary, "critical and lateral") {
+		t.Fatalf("expected the count and emphasis in the summary, got %q", summary)
+	}
+}
+
+func TestExpandUsesTemplateSummaryByDefault(t *testing.T) {
+	if (ExpansionRequest{}).IncludeSummary {
+		t.Fatal("expected include_summary to default to off")
+	}
+	expander := NewThoughtExpander(&nodeGenerator{}, NewSessionManager(storage.NewInMemorySessionStore()))
+
+	result, err := expander.Expand(&ExpansionRequest{Concept: "Flow batteries"})
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if result.Summary != templateExpansionSummary("Flow batteries", result.Directions) {
+		t.Fatalf("expected the template summary, got %q", result.Summary)
+	}
+
+	empty, err := expander.Expand(&ExpansionRequest{Concept: "Flow batteries", MinRelevance: 0.95})
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if !strings.Contains(empty.Summary, empty.EmptyReason) {
+		t.Fatalf("expected the empty reason in the summary, got %q", empty.Summary)
+	}
+}
+
+func TestSummarizeExpansionCallsLLMWhenRequested(t *testing.T) {
+	var prompts []string
+	server, calls := newScriptedBackend(t, http.StatusOK, []string{"Two directions on membranes and shipping."}, &prompts)
+	llm := newRepairTestLLM(t, server.URL)
+
+	directions := []models.Direction{{Type: models.Deep, Title: "Membranes"}, {Type: models.Lateral, Title: "Shipping"}}
+	summary, err := llm.SummarizeExpansion("Flow batteries", directions)
+	if err != nil || summary != "Two directions on membranes and shipping." {
+		t.Fatalf("expected the LLM summary, got %q (%v)", summary, err)
+	}
+	if atomic.LoadInt32(calls) != 1 || !strings.Contains(prompts[0], "Shipping") {
+		t.Fatalf("expected one call listing the directions, got %d calls: %v", *calls, prompts)
+	}
+}
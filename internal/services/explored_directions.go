@@ -0,0 +1,88 @@
+//Explored Direction Filter(已探索方向过滤)
+
+package services
+
+import (
+	"strings"
+	"unicode"
+
+	"WideMindsMCP/internal/models"
+)
+
+// 常量
+// maxExploredHintTitles 限制提示词中列出的已探索方向数，过滤仍使用全部标题
+const maxExploredHintTitles = 20
+
+const exploredHintPrefix = "already explored, do not repeat: "
+
+// 函数
+// exploredDirectionTitles 按广度优先收集 parent 子树（不含 parent）中思维的方向标题，按规范化标题去重。
+func exploredDirectionTitles(parent *models.Thought) []string {
+	if parent == nil {
+		return nil
+	}
+	titles := make([]string, 0, len(parent.Children))
+	seen := map[string]struct{}{}
+	queue := append([]*models.Thought(nil), parent.Children...)
+	for len(queue) > 0 {
+		thought := queue[0]
+		queue = queue[1:]
+		if thought == nil {
+			continue
+		}
+		queue = append(queue, thought.Children...)
+		title := strings.TrimSpace(thought.Direction.Title)
+		key := normalizeDirectionTitle(title)
+		if key == "" {
+			continue
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		titles = append(titles, title)
+	}
+	return titles
+}
+
+// exploredHintContext 在 base 之后追加列出已探索方向的提示，base 不会被修改。
+func exploredHintContext(base []models.ContextEntry, titles []string) []models.ContextEntry {
+	if len(titles) == 0 {
+		return base
+	}
+	if len(titles) > maxExploredHintTitles {
+		titles = titles[:maxExploredHintTitles]
+	}
+	entries := make([]models.ContextEntry, 0, len(base)+1)
+	entries = append(entries, base...)
+	return append(entries, models.NewContextEntry(models.ContextNote, exploredHintPrefix+strings.Join(titles, "; ")))
+}
+
+// skipExploredDirections 丢弃规范化标题与已探索方向相同的方向。
+func skipExploredDirections(directions []models.Direction, titles []string) []models.Direction {
+	if len(titles) == 0 {
+		return directions
+	}
+	explored := make(map[string]struct{}, len(titles))
+	for _, title := range titles {
+		explored[normalizeDirectionTitle(title)] = struct{}{}
+	}
+	fresh := make([]models.Direction, 0, len(directions))
+	for _, dir := range directions {
+		if _, ok := explored[normalizeDirectionTitle(dir.Title)]; !ok {
+			fresh = append(fresh, dir)
+		}
+	}
+	return fresh
+}
+
+// normalizeDirectionTitle 将标题转为小写，去掉标点并合并空白。
+func normalizeDirectionTitle(title string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		if unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			return ' '
+		}
+		return unicode.ToLower(r)
+	}, title)
+	return strings.Join(strings.Fields(cleaned), " ")
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+)
+
+// exploredGenerator 总是返回一个与已有子节点同名的方向和一个新方向。
+type exploredGenerator struct {
+	nodeGenerator
+}
+
+func (g *exploredGenerator) GenerateThoughtDirections(concept string, context []models.ContextEntry) ([]models.Direction, error) {
+	g.concept = concept
+	g.context = context
+	return []models.Direction{
+		{Type: models.Critical, Title: "Risk Analysis!", Description: "Failure modes", Relevance: 0.9},
+		{Type: models.Lateral, Title: "Supply chains", Description: "Sourcing", Relevance: 0.8},
+	}, nil
+}
+
+func TestExpandSkipsDirectionsAlreadyExplored(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	generator := &exploredGenerator{}
+	expander := NewThoughtExpander(generator, manager)
+	session, err := manager.CreateSession("user", "Flow batteries")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	existing := models.NewThought("What could go wrong", session.ID, models.Direction{Type: models.Critical, Title: "Risk analysis"})
+	if err := manager.AddThoughtToSession(session.ID, existing); err != nil {
+		t.Fatalf("AddThoughtToSession failed: %v", err)
+	}
+
+	result, err := expander.Expand(&ExpansionRequest{Concept: "Flow batteries", SessionID: session.ID, Persist: true})
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if len(result.Directions) != 1 || result.Directions[0].Title != "Supply chains" {
+		t.Fatalf("expected the explored direction to be filtered, got %+v", result.Directions)
+	}
+	hints := strings.Join(models.ContextStrings(generator.context), "\n")
+	if !strings.Contains(hints, exploredHintPrefix+"Risk analysis") {
+		t.Fatalf("expected the exclusion hint in the prompt context, got %q", hints)
+	}
+
+	result, err = expander.Expand(&ExpansionRequest{SessionID: session.ID, ThoughtID: session.RootThought.ID, ForceIncludeExisting: true})
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if len(result.Directions) != 2 {
+		t.Fatalf("expected force_include_existing to keep both directions, got %+v", result.Directions)
+	}
+}
+
+func TestNormalizeDirectionTitle(t *testing.T) {
+	if got := normalizeDirectionTitle("  Risk   Analysis!! "); got != "risk analysis" {
+		t.Fatalf("unexpected normalized title %q", got)
+	}
+}
@@ -0,0 +1,96 @@
+//Few-shot Example Selection(示例选择)
+
+package services
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 常量
+const (
+	DefaultPromptExampleLimit = 2
+	MaxPromptExampleLimit     = 10
+)
+
+// 选择示例时忽略的常见英文虚词
+var exampleStopWords = map[string]struct{}{
+	"a": {}, "an": {}, "and": {}, "as": {}, "at": {}, "by": {}, "for": {}, "from": {}, "in": {},
+	"into": {}, "of": {}, "on": {}, "or": {}, "the": {}, "to": {}, "with": {},
+}
+
+// 方法
+// SetPromptExampleLimit 设置提示词默认最多包含的示例数，0 表示不附带示例。
+func (llm *LLMOrchestrator) SetPromptExampleLimit(limit int) {
+	if llm == nil || limit < 0 {
+		return
+	}
+	llm.exampleLimit = min(limit, MaxPromptExampleLimit)
+}
+
+// WithExampleLimit 返回一个编排器副本，其提示词最多包含 limit 个与概念相关的示例；0 表示不附带示例。
+func (llm *LLMOrchestrator) WithExampleLimit(limit int) *LLMOrchestrator {
+	if llm == nil {
+		return nil
+	}
+	clone := *llm
+	clone.SetPromptExampleLimit(limit)
+	return &clone
+}
+
+// 函数
+// selectExamples 按与概念的关键词重合数从高到低选出至多 limit 个示例；没有任何重合的示例不会入选。
+func selectExamples(examples []fewShotExample, concept string, limit int) []fewShotExample {
+	if limit <= 0 || len(examples) == 0 {
+		return nil
+	}
+	conceptTerms := exampleTerms(concept)
+	if len(conceptTerms) == 0 {
+		return nil
+	}
+
+	type scored struct {
+		example fewShotExample
+		score   int
+	}
+	candidates := make([]scored, 0, len(examples))
+	for _, example := range examples {
+		terms := exampleTerms(example.name + " " + example.input + " " + strings.Join(example.keywords, " "))
+		score := 0
+		for term := range conceptTerms {
+			if _, ok := terms[term]; ok {
+				score++
+			}
+		}
+		if score > 0 {
+			candidates = append(candidates, scored{example: example, score: score})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	selected := make([]fewShotExample, 0, min(limit, len(candidates)))
+	for _, candidate := range candidates[:min(limit, len(candidates))] {
+		selected = append(selected, candidate.example)
+	}
+	return selected
+}
+
+// exampleTerms 将文本切分为小写词集合，去掉虚词与单字符英文词。
+func exampleTerms(text string) map[string]struct{} {
+	terms := map[string]struct{}{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) < 2 {
+			continue
+		}
+		if _, ok := exampleStopWords[word]; ok {
+			continue
+		}
+		terms[word] = struct{}{}
+	}
+	return terms
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelectExamplesByKeywordOverlap(t *testing.T) {
+	examples := []fewShotExample{
+		{name: "Solar power", input: "Concept: Solar panels", output: "-"},
+		{name: "Battery storage", input: "Concept: Grid battery storage", keywords: []string{"energy storage", "lithium"}, output: "-"},
+		{name: "Baking", input: "Concept: Sourdough bread", output: "-"},
+	}
+
+	selected := selectExamples(examples, "Lithium battery storage for the grid", 2)
+	if len(selected) != 1 || selected[0].name != "Battery storage" {
+		t.Fatalf("expected only the overlapping example, got %+v", selected)
+	}
+
+	selected = selectExamples(examples, "Solar energy storage", 1)
+	if len(selected) != 1 || selected[0].name != "Battery storage" {
+		t.Fatalf("expected the highest-overlap example within the limit, got %+v", selected)
+	}
+	if selected := selectExamples(examples, "Solar energy storage", 0); len(selected) != 0 {
+		t.Fatalf("expected no examples with a zero limit, got %+v", selected)
+	}
+}
+
+func TestBuildPromptOmitsExamplesWhenNoneApply(t *testing.T) {
+	llm := NewLLMOrchestrator("", "", "")
+
+	if prompt := llm.BuildPrompt("Machine Learning", nil, "directions"); !strings.Contains(prompt, "## Reference examples") {
+		t.Fatalf("expected the machine learning example for a matching concept:\n%s", prompt)
+	}
+	prompt := llm.BuildPrompt("Medieval pottery", nil, "directions")
+	if strings.Contains(prompt, "## Reference examples") || strings.Contains(prompt, "Machine learning concept expansion") {
+		t.Fatalf("expected no examples section for an unrelated concept:\n%s", prompt)
+	}
+	if prompt := llm.WithExampleLimit(0).BuildPrompt("Machine Learning", nil, "directions"); strings.Contains(prompt, "## Reference examples") {
+		t.Fatalf("expected a zero example limit to omit the section:\n%s", prompt)
+	}
+}
@@ -0,0 +1,202 @@
+//LLM Health Probe(LLM健康探测)
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const (
+	healthProbePrompt    = "Health check: reply with the single word OK."
+	healthProbeMaxTokens = 16
+	healthProbeTimeout   = 30 * time.Second
+
+	DefaultHealthCheckCacheTTL = 30 * time.Second
+)
+
+// 结构体
+// LLMHealthStatus 是最近一次 LLM 连通性探测的结果。
+type LLMHealthStatus struct {
+	// Monitored 表示已启动周期性探测；未启动时其余字段无意义。
+	Monitored bool      `json:"monitored"`
+	Checked   bool      `json:"checked"`
+	Healthy   bool      `json:"healthy"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checkedAt,omitempty"`
+}
+
+type healthMonitor struct {
+	mutex  sync.RWMutex
+	status LLMHealthStatus
+
+	// cacheTTL 内复用 lastErr 作为 HealthCheck 的结果
+	cacheTTL  time.Duration
+	lastCheck time.Time
+	lastErr   error
+}
+
+// 方法
+// SetHealthCheckCacheTTL 设置 HealthCheck 结果的复用时间窗口，非正值表示每次都请求上游。
+func (llm *LLMOrchestrator) SetHealthCheckCacheTTL(ttl time.Duration) {
+	if llm == nil || llm.health == nil {
+		return
+	}
+	llm.health.mutex.Lock()
+	defer llm.health.mutex.Unlock()
+	llm.health.cacheTTL = ttl
+	llm.health.lastCheck = time.Time{}
+	llm.health.lastErr = nil
+}
+
+// checkHealth 不经缓存执行一次检查，并将失败归类为认证失败或无法连接。
+func (llm *LLMOrchestrator) checkHealth(ctx context.Context) error {
+	if llm.isOllama() {
+		if err := llm.checkOllamaModel(ctx); err != nil {
+			return classifyHealthError(err)
+		}
+	}
+	return classifyHealthError(llm.probe(ctx))
+}
+
+// probe 发送一个极小的请求并要求返回非空内容；只尝试一次，不读写响应缓存，也不计入任何用户预算。
+func (llm *LLMOrchestrator) probe(ctx context.Context) error {
+	prober := llm.WithContext(ctx).WithoutCache()
+	prober.userID = ""
+	prober.stream = nil
+	prober.usageRecorder = nil
+	prober.maxAttempts = 1
+
+	resp, err := prober.CallLLM(&LLMRequest{Prompt: healthProbePrompt, MaxTokens: healthProbeMaxTokens})
+	if err != nil {
+		return err
+	}
+	if resp == nil || strings.TrimSpace(resp.Content) == "" {
+		return errors.New("llm returned an empty health check response")
+	}
+	return nil
+}
+
+// StartHealthProbe 立即并按 interval 周期探测 LLM 连通性并缓存结果，ctx 取消时停止；interval 非正时不启动。
+func (llm *LLMOrchestrator) StartHealthProbe(ctx context.Context, interval time.Duration) {
+	if llm == nil || llm.health == nil || interval <= 0 {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	llm.health.mutex.Lock()
+	llm.health.status = LLMHealthStatus{Monitored: true}
+	llm.health.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			llm.ProbeHealth(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// ProbeHealth 执行一次探测并更新缓存的状态；由健康转为不健康时记录警告。
+func (llm *LLMOrchestrator) ProbeHealth(ctx context.Context) LLMHealthStatus {
+	if llm == nil || llm.health == nil {
+		return LLMHealthStatus{}
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, healthProbeTimeout)
+	defer cancel()
+	var err error
+	if llm.hasRemoteBackend() {
+		err = llm.checkHealth(probeCtx)
+	}
+
+	llm.health.mutex.Lock()
+	defer llm.health.mutex.Unlock()
+
+	llm.health.lastCheck = time.Now()
+	llm.health.lastErr = err
+
+	previous := llm.health.status
+	status := LLMHealthStatus{Monitored: previous.Monitored, Checked: true, Healthy: err == nil, CheckedAt: time.Now().UTC()}
+	if err != nil {
+		status.Error = err.Error()
+		if !previous.Checked || previous.Healthy {
+			utils.Warn("llm health check failed; marking llm unhealthy", utils.KV("error", err))
+		}
+	} else if previous.Checked && !previous.Healthy {
+		utils.Info("llm health check recovered")
+	}
+	llm.health.status = status
+	return status
+}
+
+// HealthStatus 返回缓存的探测结果，不会触发新的请求。
+func (llm *LLMOrchestrator) HealthStatus() LLMHealthStatus {
+	if llm == nil || llm.health == nil {
+		return LLMHealthStatus{}
+	}
+	llm.health.mutex.RLock()
+	defer llm.health.mutex.RUnlock()
+	return llm.health.status
+}
+
+// cached 返回时间窗口内最近一次检查的结果。
+func (m *healthMonitor) cached() (error, bool) {
+	if m == nil {
+		return nil, false
+	}
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if m.cacheTTL <= 0 || m.lastCheck.IsZero() || time.Since(m.lastCheck) >= m.cacheTTL {
+		return nil, false
+	}
+	return m.lastErr, true
+}
+
+func (m *healthMonitor) remember(err error) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.lastCheck = time.Now()
+	m.lastErr = err
+}
+
+// 函数
+// classifyHealthError 将 401/403 包装为 ErrLLMUnauthorized，将网络错误与超时包装为 ErrLLMUnreachable。
+func classifyHealthError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var httpErr *llmHTTPError
+	if errors.As(err, &httpErr) {
+		if httpErr.status == http.StatusUnauthorized || httpErr.status == http.StatusForbidden {
+			return fmt.Errorf("%w, check the api key: %w", appErrors.ErrLLMUnauthorized, err)
+		}
+		return err
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) {
+		return fmt.Errorf("%w: %w", appErrors.ErrLLMUnreachable, err)
+	}
+	return err
+}
@@ -0,0 +1,116 @@
+//Detailed Health Report(组件级健康报告)
+
+package services
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"time"
+
+	"WideMindsMCP/internal/models"
+)
+
+// 常量
+const (
+	DetailedHealthTimeout = 3 * time.Second
+
+	ComponentOK       = "ok"
+	ComponentError    = "error"
+	ComponentDisabled = "disabled"
+
+	healthProbeUserID = "health-check"
+)
+
+// 结构体
+// DetailedHealth 是各组件的实测延迟与运行状态。
+type DetailedHealth struct {
+	Components HealthComponents `json:"components"`
+}
+
+type HealthComponents struct {
+	LLM     ComponentLatency `json:"llm"`
+	Store   ComponentLatency `json:"store"`
+	Cache   CacheHealth      `json:"cache"`
+	Runtime RuntimeHealth    `json:"runtime"`
+}
+
+// ComponentLatency 是一次探测的耗时（毫秒）与结果；未配置远程 LLM 时状态为 disabled。
+// Cached 表示结果取自健康检查缓存，此时没有发出请求，耗时为 0。
+type ComponentLatency struct {
+	LatencyMS int64  `json:"latency_ms"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	Cached    bool   `json:"cached,omitempty"`
+}
+
+type CacheHealth struct {
+	HitRate float64 `json:"hit_rate"`
+	Size    int     `json:"size"`
+}
+
+type RuntimeHealth struct {
+	Goroutines int `json:"goroutines"`
+}
+
+// 方法
+// ProbeStore 写入、读取并删除一个临时会话，返回整个过程的耗时。
+func (sm *SessionManager) ProbeStore(ctx context.Context) (time.Duration, error) {
+	started := time.Now()
+	if err := sm.HealthCheck(ctx); err != nil {
+		return time.Since(started), err
+	}
+	probe := models.NewSession(healthProbeUserID, "health check")
+	if err := sm.store.Save(probe); err != nil {
+		return time.Since(started), err
+	}
+	_, err := sm.store.Get(probe.ID)
+	if deleteErr := sm.store.Delete(probe.ID); err == nil {
+		err = deleteErr
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	return time.Since(started), err
+}
+
+// 函数
+// CheckDetailedHealth 依次探测存储与 LLM，整体受 DetailedHealthTimeout 约束。
+// LLM 优先复用健康检查缓存（含周期探测的结果），缓存过期时才发送一次极小的探测请求。
+func CheckDetailedHealth(ctx context.Context, sm *SessionManager, llm *LLMOrchestrator) *DetailedHealth {
+	ctx, cancel := context.WithTimeout(ctx, DetailedHealthTimeout)
+	defer cancel()
+
+	report := &DetailedHealth{}
+	report.Components.Store = componentLatency(sm.ProbeStore(ctx))
+
+	switch {
+	case llm == nil:
+		report.Components.LLM = componentLatency(0, errors.New("llm orchestrator is nil"))
+	case !llm.hasRemoteBackend():
+		report.Components.LLM = ComponentLatency{Status: ComponentDisabled}
+	default:
+		if err, ok := llm.health.cached(); ok {
+			report.Components.LLM = componentLatency(0, err)
+			report.Components.LLM.Cached = true
+			break
+		}
+		started := time.Now()
+		err := llm.HealthCheck(ctx)
+		report.Components.LLM = componentLatency(time.Since(started), err)
+	}
+
+	cache := llm.Stats().Cache
+	report.Components.Cache = CacheHealth{HitRate: cache.hitRate(), Size: cache.Entries}
+	report.Components.Runtime = RuntimeHealth{Goroutines: runtime.NumGoroutine()}
+	return report
+}
+
+func componentLatency(latency time.Duration, err error) ComponentLatency {
+	result := ComponentLatency{LatencyMS: latency.Milliseconds(), Status: ComponentOK}
+	if err != nil {
+		result.Status = ComponentError
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"WideMindsMCP/internal/storage"
+)
+
+func TestCheckDetailedHealthReportsComponents(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	server, calls := newScriptedBackend(t, http.StatusOK, []string{"OK"}, nil)
+	llm := newRepairTestLLM(t, server.URL)
+
+	report := CheckDetailedHealth(context.Background(), manager, llm)
+	if report.Components.LLM.Status != ComponentOK || report.Components.LLM.Cached || *calls != 1 {
+		t.Fatalf("expected one successful LLM ping, got %+v after %d calls", report.Components.LLM, *calls)
+	}
+	if cached := CheckDetailedHealth(context.Background(), manager, llm); !cached.Components.LLM.Cached || cached.Components.LLM.Status != ComponentOK || *calls != 1 {
+		t.Fatalf("expected the cached health result without another call, got %+v after %d calls", cached.Components.LLM, *calls)
+	}
+	if report.Components.Store.Status != ComponentOK || report.Components.Store.Error != "" {
+		t.Fatalf("expected healthy store, got %+v", report.Components.Store)
+	}
+	if report.Components.Runtime.Goroutines <= 0 {
+		t.Fatalf("expected goroutine count, got %+v", report.Components.Runtime)
+	}
+	sessions, err := manager.ListSessions(healthProbeUserID)
+	if err != nil || len(sessions) != 0 {
+		t.Fatalf("expected the probe session to be removed, got %d sessions (%v)", len(sessions), err)
+	}
+}
+
+func TestCheckDetailedHealthDisablesLocalLLM(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	report := CheckDetailedHealth(context.Background(), manager, NewLLMOrchestrator("", "", ""))
+	if report.Components.LLM != (ComponentLatency{Status: ComponentDisabled}) {
+		t.Fatalf("expected disabled LLM without a remote backend, got %+v", report.Components.LLM)
+	}
+}
+
+func TestCheckDetailedHealthReportsUpstreamErrors(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	server, _ := newScriptedBackend(t, http.StatusBadGateway, nil, nil)
+	llm := newRepairTestLLM(t, server.URL)
+	llm.SetRetryPolicy(1, 0)
+
+	report := CheckDetailedHealth(context.Background(), manager, llm)
+	if report.Components.LLM.Status != ComponentError || report.Components.LLM.Error == "" {
+		t.Fatalf("expected LLM error, got %+v", report.Components.LLM)
+	}
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	appErrors "WideMindsMCP/internal/errors"
+)
+
+func TestProbeHealthTracksTransitions(t *testing.T) {
+	var failing int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			http.Error(w, "down", http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"OK"}}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	llm := NewLLMOrchestrator("key", server.URL, "model")
+	llm.SetRetryPolicy(1, 0)
+	if status := llm.HealthStatus(); status.Monitored || status.Checked {
+		t.Fatalf("expected no cached status before probing, got %+v", status)
+	}
+
+	if status := llm.ProbeHealth(context.Background()); !status.Healthy || !status.Checked {
+		t.Fatalf("expected healthy probe, got %+v", status)
+	}
+	atomic.StoreInt32(&failing, 1)
+	if status := llm.ProbeHealth(context.Background()); status.Healthy || status.Error == "" {
+		t.Fatalf("expected unhealthy probe with error, got %+v", status)
+	}
+	if cached := llm.ForUser("someone").HealthStatus(); cached.Healthy || cached.Error == "" {
+		t.Fatalf("expected derived orchestrators to share the cached status, got %+v", cached)
+	}
+
+	if err := NewLLMOrchestrator("", "", "").HealthCheck(context.Background()); err != nil {
+		t.Fatalf("expected local fallback to be healthy, got %v", err)
+	}
+}
+
+func TestStartHealthProbeCachesResultInBackground(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"OK"}}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	llm := NewLLMOrchestrator("key", server.URL, "model")
+	llm.SetResponseCache(8, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	llm.StartHealthProbe(ctx, 20*time.Millisecond)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&requests) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := atomic.LoadInt32(&requests); got < 2 {
+		t.Fatalf("expected repeated probes that bypass the response cache, got %d requests", got)
+	}
+	if status := llm.HealthStatus(); !status.Monitored || !status.Checked || !status.Healthy {
+		t.Fatalf("expected cached healthy status, got %+v", status)
+	}
+}
+
+func TestHealthCheckClassifiesFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Authorization") {
+		case "Bearer bad-key":
+			http.Error(w, `{"error":"invalid api key"}`, http.StatusUnauthorized)
+		case "Bearer slow-key":
+			// 读完请求体后服务端才能感知客户端断开
+			_, _ = io.Copy(io.Discard, r.Body)
+			select {
+			case <-r.Context().Done():
+			case <-time.After(2 * time.Second):
+			}
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"OK"}}]}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	newLLM := func(key string) *LLMOrchestrator {
+		llm := NewLLMOrchestrator(key, server.URL, "model")
+		llm.SetCircuitBreaker(nil)
+		return llm
+	}
+
+	if err := newLLM("good-key").HealthCheck(context.Background()); err != nil {
+		t.Fatalf("expected healthy backend, got %v", err)
+	}
+	if err := newLLM("bad-key").HealthCheck(context.Background()); !errors.Is(err, appErrors.ErrLLMUnauthorized) {
+		t.Fatalf("expected auth failure, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	if err := newLLM("slow-key").HealthCheck(ctx); !errors.Is(err, appErrors.ErrLLMUnreachable) {
+		t.Fatalf("expected connectivity failure, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Fatalf("expected health check to honor the context deadline, took %v", elapsed)
+	}
+}
+
+func TestHealthCheckCachesWithinWindow(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"OK"}}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	llm := NewLLMOrchestrator("key", server.URL, "model")
+	llm.SetHealthCheckCacheTTL(50 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		if err := llm.ForUser("someone").HealthCheck(context.Background()); err != nil {
+			t.Fatalf("HealthCheck %d failed: %v", i, err)
+		}
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Fatalf("expected checks within the window to reuse the result, got %d requests", got)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if err := llm.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("HealthCheck after window failed: %v", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Fatalf("expected a new request once the window expired, got %d requests", got)
+	}
+
+	llm.SetHealthCheckCacheTTL(0)
+	_ = llm.HealthCheck(context.Background())
+	_ = llm.HealthCheck(context.Background())
+	if got := atomic.LoadInt32(&requests); got != 4 {
+		t.Fatalf("expected every check to hit the backend without a cache, got %d requests", got)
+	}
+}
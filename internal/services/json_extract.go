@@ -0,0 +1,200 @@
+//JSON Extraction(从 LLM 回复中提取 JSON)
+
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// 常量
+// 提取策略名，用于日志诊断
+const (
+	extractArray           = "array"
+	extractDirectionsField = "directions_object"
+	extractBalancedScan    = "balanced_scan"
+	extractSingleObject    = "single_object"
+)
+
+// 结构体
+// rawDirection 是 LLM 返回的单个方向，兼容多种字段命名。
+type rawDirection struct {
+	Type                string   `json:"type"`
+	Title               string   `json:"title"`
+	Summary             string   `json:"summary"`
+	Description         string   `json:"description"`
+	DirectionRationale  string   `json:"direction_rationale"`
+	KeyQuestions        []string `json:"key_questions"`
+	RecommendedActions  []string `json:"recommended_actions"`
+	Keywords            []string `json:"keywords"`
+	Relevance           float64  `json:"relevance"`
+	Confidence          float64  `json:"confidence"`
+	Importance          float64  `json:"importance"`
+	SuggestedRelevance  float64  `json:"suggested_relevance"`
+	SuggestedConfidence float64  `json:"suggested_confidence"`
+}
+
+// directionExtraction 记录成功的提取策略及内容是否来自 Markdown 代码块。
+type directionExtraction struct {
+	strategy string
+	fenced   bool
+}
+
+// 函数
+// extractRawDirections 依次尝试：去除代码块标记、直接解析数组、解析带 directions 字段的对象、
+// 按括号配对（忽略字符串内的括号）扫描数组，最后将单个对象包装为只含一个元素的数组。
+func extractRawDirections(content string) ([]rawDirection, directionExtraction, error) {
+	text, fenced := stripCodeFences(content)
+	diagnostic := directionExtraction{fenced: fenced}
+	if text == "" {
+		return nil, diagnostic, errors.New("llm response empty")
+	}
+
+	var directions []rawDirection
+	if err := json.Unmarshal([]byte(text), &directions); err == nil && len(directions) > 0 {
+		diagnostic.strategy = extractArray
+		return directions, diagnostic, nil
+	}
+
+	if directions := directionsFromEnvelope([]byte(text)); len(directions) > 0 {
+		diagnostic.strategy = extractDirectionsField
+		return directions, diagnostic, nil
+	}
+
+	for _, segment := range balancedSegments(text, '[', ']') {
+		var candidate []rawDirection
+		if err := json.Unmarshal([]byte(segment), &candidate); err == nil && hasTitledDirection(candidate) {
+			diagnostic.strategy = extractBalancedScan
+			return candidate, diagnostic, nil
+		}
+	}
+
+	for _, segment := range balancedSegments(text, '{', '}') {
+		if directions := directionsFromEnvelope([]byte(segment)); len(directions) > 0 {
+			diagnostic.strategy = extractDirectionsField
+			return directions, diagnostic, nil
+		}
+		var single rawDirection
+		if err := json.Unmarshal([]byte(segment), &single); err == nil && strings.TrimSpace(single.Title) != "" {
+			diagnostic.strategy = extractSingleObject
+			return []rawDirection{single}, diagnostic, nil
+		}
+	}
+
+	return nil, diagnostic, errors.New("no JSON directions found in llm response")
+}
+
+// stripCodeFences 返回第一个以 [ 或 { 开头的 Markdown 代码块内容；没有这样的代码块时去掉所有围栏行后返回全文。
+func stripCodeFences(content string) (string, bool) {
+	trimmed := strings.TrimSpace(content)
+	if !strings.Contains(trimmed, "```") {
+		return trimmed, false
+	}
+
+	var (
+		blocks  []string
+		current []string
+		outside []string
+		inBlock bool
+	)
+	for _, line := range strings.Split(trimmed, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			if inBlock {
+				blocks = append(blocks, strings.TrimSpace(strings.Join(current, "\n")))
+				current = nil
+			}
+			inBlock = !inBlock
+			continue
+		}
+		if inBlock {
+			current = append(current, line)
+		} else {
+			outside = append(outside, line)
+		}
+	}
+	if inBlock {
+		// 未闭合的代码块（回复被截断）按已闭合处理
+		blocks = append(blocks, strings.TrimSpace(strings.Join(current, "\n")))
+	}
+
+	for _, block := range blocks {
+		if strings.HasPrefix(block, "[") || strings.HasPrefix(block, "{") {
+			return block, true
+		}
+	}
+	return strings.TrimSpace(strings.Join(append(outside, blocks...), "\n")), true
+}
+
+// directionsFromEnvelope 解析 {"directions": [...]}，不是该结构时返回 nil。
+func directionsFromEnvelope(data []byte) []rawDirection {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil
+	}
+	field, ok := envelope[directionsResponseKey]
+	if !ok {
+		return nil
+	}
+	var directions []rawDirection
+	if err := json.Unmarshal(field, &directions); err != nil {
+		return nil
+	}
+	return directions
+}
+
+// balancedSegments 按出现顺序返回所有以 open 开始、括号配对闭合的片段；字符串字面量内的括号与转义字符不参与配对。
+func balancedSegments(text string, open, close byte) []string {
+	var segments []string
+	for start := 0; start < len(text); start++ {
+		if text[start] != open {
+			continue
+		}
+		if end := matchingBracket(text, start, open, close); end > start {
+			segments = append(segments, text[start:end+1])
+		}
+	}
+	return segments
+}
+
+// matchingBracket 返回与 text[start] 配对的闭括号下标，未闭合时返回 -1。
+func matchingBracket(text string, start int, open, close byte) int {
+	depth := 0
+	inString := false
+	escaped := false
+	for i := start; i < len(text); i++ {
+		c := text[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func hasTitledDirection(directions []rawDirection) bool {
+	for _, direction := range directions {
+		if strings.TrimSpace(direction.Title) != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"testing"
+)
+
+func TestExtractRawDirectionsHandlesMessyPayloads(t *testing.T) {
+	item := `{"type":"deep","title":"Chemistry","summary":"Cell chemistry","keywords":["anode","cathode"]}`
+	tricky := `{"type":"critical","title":"Risks","description":"Watch for ] and [ in text, plus \"quoted [x]\" parts"}`
+
+	cases := []struct {
+		name     string
+		content  string
+		strategy string
+		fenced   bool
+		titles   []string
+	}{
+		{"plain array", `[` + item + `]`, extractArray, false, []string{"Chemistry"}},
+		{"json fence", "```json\n[" + item + "]\n```", extractArray, true, []string{"Chemistry"}},
+		{"bare fence with prose", "Sure! Here they are:\n```\n[" + item + "," + tricky + "]\n```\nLet me know [if] you need more.", extractArray, true, []string{"Chemistry", "Risks"}},
+		{"directions object", `{"directions":[` + item + `],"next_step_recommendations":["pick one"]}`, extractDirectionsField, false, []string{"Chemistry"}},
+		{"fenced directions object", "```json\n{\"directions\":[" + item + "]}\n```", extractDirectionsField, true, []string{"Chemistry"}},
+		{"trailing commentary with brackets", "[" + item + "]\n\nNote: see [1] and [2] for sources.", extractBalancedScan, false, []string{"Chemistry"}},
+		{"leading commentary with brackets", "Options [draft v2]: [" + item + "] (end)", extractBalancedScan, false, []string{"Chemistry"}},
+		{"brackets inside strings", "Result -> [" + tricky + "] done", extractBalancedScan, false, []string{"Risks"}},
+		{"nested under another key", `{"result":{"items":[` + item + `]}}`, extractBalancedScan, false, []string{"Chemistry"}},
+		{"single object", item, extractSingleObject, false, []string{"Chemistry"}},
+		{"single object in prose", "I only found one: " + tricky + " Hope it helps {really}.", extractSingleObject, false, []string{"Risks"}},
+		{"directions object in prose", "Here you go: {\"directions\":[" + item + "]} -- thanks", extractBalancedScan, false, []string{"Chemistry"}},
+		{"non-json fence first", "```text\nthinking...\n```\n```json\n[" + item + "]\n```", extractArray, true, []string{"Chemistry"}},
+	}
+
+	for _, tc := range cases {
+		directions, diagnostic, err := extractRawDirections(tc.content)
+		if err != nil {
+			t.Fatalf("%s: extraction failed: %v", tc.name, err)
+		}
+		if diagnostic.strategy != tc.strategy || diagnostic.fenced != tc.fenced {
+			t.Fatalf("%s: expected strategy %s (fenced %v), got %+v", tc.name, tc.strategy, tc.fenced, diagnostic)
+		}
+		if len(directions) != len(tc.titles) {
+			t.Fatalf("%s: expected %d directions, got %+v", tc.name, len(tc.titles), directions)
+		}
+		for i, title := range tc.titles {
+			if directions[i].Title != title {
+				t.Fatalf("%s: expected title %q at %d, got %q", tc.name, title, i, directions[i].Title)
+			}
+		}
+	}
+
+	for _, content := range []string{"", "   ", "No JSON here [just text]", "[]", `{"directions":[]}`, `[{"title":"Chemis`} {
+		if _, _, err := extractRawDirections(content); err == nil {
+			t.Fatalf("expected extraction of %q to fail", content)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+//Structured JSON Output(结构化 JSON 输出)
+
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 常量
+const (
+	JSONModeOff        = "off"
+	JSONModeJSONObject = "json_object"
+	JSONModeJSONSchema = "json_schema"
+
+	directionsResponseKey = "directions"
+)
+
+// 结构体
+// ResponseFormat 描述请求期望的 JSON 输出；仅在开启 JSON 模式时发送给提供方。
+type ResponseFormat struct {
+	Name   string
+	Schema map[string]any
+}
+
+// directionsResponseFormat 描述方向生成的输出：{"directions": [...]}
+var directionsResponseFormat = &ResponseFormat{
+	Name: "thought_directions",
+	Schema: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			directionsResponseKey: map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"type":                map[string]any{"type": "string", "enum": []string{"broad", "deep", "lateral", "critical"}},
+						"title":               map[string]any{"type": "string"},
+						"summary":             map[string]any{"type": "string"},
+						"key_questions":       map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+						"recommended_actions": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+						"direction_rationale": map[string]any{"type": "string"},
+						"relevance":           map[string]any{"type": "number"},
+					},
+					"required": []string{"type", "title", "summary"},
+				},
+			},
+		},
+		"required": []string{directionsResponseKey},
+	},
+}
+
+// 函数
+// NormalizeJSONMode 解析 JSON 模式配置；空值与 false 表示关闭，true 等同于 json_object。
+func NormalizeJSONMode(mode string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", JSONModeOff, "false":
+		return JSONModeOff, nil
+	case JSONModeJSONObject, "true", "json":
+		return JSONModeJSONObject, nil
+	case JSONModeJSONSchema, "schema":
+		return JSONModeJSONSchema, nil
+	default:
+		return "", fmt.Errorf("unsupported llm json mode %q", mode)
+	}
+}
+
+// withJSONObjectOutput 将方向模板的输出格式与示例改为 {"directions": [...]} 对象。
+func withJSONObjectOutput(tpl promptTemplate) promptTemplate {
+	tpl.outputFormat = []string{
+		`Return only a JSON object of the form {"directions": [...]}; do not add prose or markdown fences.`,
+		"Each element of directions must include type, title, summary, key_questions, and recommended_actions fields.",
+		"Put any next_step_recommendations or open_questions inside the same JSON object as additional keys.",
+	}
+	examples := make([]fewShotExample, len(tpl.examples))
+	for i, example := range tpl.examples {
+		example.output = `{"directions": ` + strings.TrimSpace(example.output) + `}`
+		examples[i] = example
+	}
+	tpl.examples = examples
+	return tpl
+}
+
+// 方法
+// SetJSONMode 配置是否要求提供方返回结构化 JSON（off、json_object、json_schema）。
+func (llm *LLMOrchestrator) SetJSONMode(mode string) error {
+	if llm == nil {
+		return nil
+	}
+	normalized, err := NormalizeJSONMode(mode)
+	if err != nil {
+		return err
+	}
+	llm.jsonMode = normalized
+	return nil
+}
+
+func (llm *LLMOrchestrator) jsonModeEnabled() bool {
+	return llm != nil && llm.jsonMode != "" && llm.jsonMode != JSONModeOff
+}
+
+// responseFormatPayload 返回 chat completions 的 response_format 字段；未开启或请求未声明格式时返回 nil。
+func (llm *LLMOrchestrator) responseFormatPayload(format *ResponseFormat) any {
+	if format == nil || !llm.jsonModeEnabled() {
+		return nil
+	}
+	if llm.jsonMode == JSONModeJSONSchema && format.Schema != nil {
+		return map[string]any{
+			"type": "json_schema",
+			"json_schema": map[string]any{
+				"name":   format.Name,
+				"schema": format.Schema,
+			},
+		}
+	}
+	return map[string]any{"type": "json_object"}
+}
+
+// ollamaFormatPayload 返回 Ollama 的 format 字段："json" 或 JSON schema。
+func (llm *LLMOrchestrator) ollamaFormatPayload(format *ResponseFormat) any {
+	if format == nil || !llm.jsonModeEnabled() {
+		return nil
+	}
+	if llm.jsonMode == JSONModeJSONSchema && format.Schema != nil {
+		return format.Schema
+	}
+	return "json"
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseDirectionsAcceptsObjectAndArray(t *testing.T) {
+	llm := NewLLMOrchestrator("", "", "")
+	item := `{"type":"deep","title":"Chemistry","summary":"Cell chemistry","key_questions":["Which anode?"]}`
+
+	for name, content := range map[string]string{
+		"object": `{"directions":[` + item + `],"next_step_recommendations":["pick one"]}`,
+		"array":  `[` + item + `]`,
+		"prose":  "Here you go:\n```json\n[" + item + "]\n```\nGood luck!",
+	} {
+		directions, err := llm.parseDirectionsFromContent(content)
+		if err != nil {
+			t.Fatalf("%s: parse failed: %v", name, err)
+		}
+		if len(directions) != 1 || directions[0].Title != "Chemistry" || directions[0].Description != "Cell chemistry" {
+			t.Fatalf("%s: unexpected directions %+v", name, directions)
+		}
+	}
+}
+
+func TestJSONModeRequestPayload(t *testing.T) {
+	call := &llmCall{prompt: "p", userContent: "p", maxTokens: 10, temperature: 0.5, responseFormat: directionsResponseFormat}
+	decode := func(llm *LLMOrchestrator) map[string]any {
+		body, err := llm.buildPayload(call, false)
+		if err != nil {
+			t.Fatalf("buildPayload failed: %v", err)
+		}
+		var payload map[string]any
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatalf("decode payload: %v", err)
+		}
+		return payload
+	}
+
+	llm := NewLLMOrchestrator("key", "https://example.com", "model")
+	if _, ok := decode(llm)["response_format"]; ok {
+		t.Fatalf("expected no response_format when json mode is off")
+	}
+
+	if err := llm.SetJSONMode("json_object"); err != nil {
+		t.Fatalf("SetJSONMode failed: %v", err)
+	}
+	format, _ := decode(llm)["response_format"].(map[string]any)
+	if format["type"] != "json_object" {
+		t.Fatalf("unexpected json_object format %+v", format)
+	}
+
+	if err := llm.SetJSONMode("json_schema"); err != nil {
+		t.Fatalf("SetJSONMode failed: %v", err)
+	}
+	format, _ = decode(llm)["response_format"].(map[string]any)
+	schema, _ := format["json_schema"].(map[string]any)
+	if format["type"] != "json_schema" || schema["name"] != "thought_directions" || schema["schema"] == nil {
+		t.Fatalf("unexpected json_schema format %+v", format)
+	}
+
+	if err := llm.SetJSONMode("xml"); err == nil {
+		t.Fatalf("expected unsupported json mode to be rejected")
+	}
+}
+
+func TestJSONModePromptRequestsObject(t *testing.T) {
+	llm := NewLLMOrchestrator("", "", "")
+	if prompt := llm.BuildPrompt("Batteries", nil, "directions"); strings.Contains(prompt, `{"directions": [`) {
+		t.Fatalf("expected default prompt to request a bare array")
+	}
+	_ = llm.SetJSONMode("json_object")
+	prompt := llm.BuildPrompt("Batteries", nil, "directions")
+	if !strings.Contains(prompt, `Return only a JSON object of the form {"directions": [...]}`) || !strings.Contains(prompt, `{"directions": [`) {
+		t.Fatalf("expected JSON mode prompt to request a directions object:\n%s", prompt)
+	}
+}
+
+func TestJSONModeFallsBackWhenProviderIgnoresFlag(t *testing.T) {
+	var sawFormat bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		_, sawFormat = payload["response_format"]
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{
+				"content": "Sure! ```json\n[{\"type\":\"lateral\",\"title\":\"Analogies\",\"summary\":\"Borrow ideas from biology\"}]\n```",
+			}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	llm := NewLLMOrchestrator("key", server.URL, "model")
+	_ = llm.SetForceResponseLanguage("en")
+	_ = llm.SetJSONMode("json_object")
+
+	directions, err := llm.GenerateThoughtDirections("Batteries", nil)
+	if err != nil {
+		t.Fatalf("GenerateThoughtDirections failed: %v", err)
+	}
+	if !sawFormat {
+		t.Fatalf("expected response_format to be sent")
+	}
+	if len(directions) != 1 || directions[0].Title != "Analogies" || directions[0].Provenance == nil || directions[0].Provenance.Model == localFallbackModel {
+		t.Fatalf("expected directions parsed from free text, got %+v", directions)
+	}
+}
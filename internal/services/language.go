@@ -0,0 +1,244 @@
+//Response Language Detection(响应语言检测)
+
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const (
+	DefaultResponseLanguage = "en"
+	languageCacheTTL        = time.Hour
+	languageSampleRunes     = 500
+	// maxLanguageTagLength 是 RFC 5646 建议支持的最大标签长度
+	maxLanguageTagLength = 35
+)
+
+var bcp47Pattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// 常见主语言子标签对应的英文名称，用于提示词中的语言约束
+var languageNames = map[string]string{
+	"ar": "Arabic",
+	"de": "German",
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"hi": "Hindi",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"pt": "Portuguese",
+	"ru": "Russian",
+	"zh": "Chinese",
+}
+
+// 结构体
+type languageCache struct {
+	mutex   sync.Mutex
+	entries map[string]languageCacheEntry
+	now     func() time.Time
+}
+
+type languageCacheEntry struct {
+	language  string
+	expiresAt time.Time
+}
+
+// 函数
+func newLanguageCache() *languageCache {
+	return &languageCache{
+		entries: make(map[string]languageCacheEntry),
+		now:     time.Now,
+	}
+}
+
+func (c *languageCache) get(key string) (string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	if !c.now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return "", false
+	}
+	return entry.language, true
+}
+
+func (c *languageCache) put(key, language string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+	for k, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = languageCacheEntry{language: language, expiresAt: now.Add(languageCacheTTL)}
+}
+
+// NormalizeLanguageTag 校验并规范化 BCP-47 语言标签，例如 "PT-br" -> "pt-BR"。
+func NormalizeLanguageTag(tag string) (string, bool) {
+	tag = strings.TrimSpace(strings.ReplaceAll(tag, "_", "-"))
+	if len(tag) > maxLanguageTagLength || !bcp47Pattern.MatchString(tag) {
+		return "", false
+	}
+
+	parts := strings.Split(tag, "-")
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		switch len(parts[i]) {
+		case 2:
+			parts[i] = strings.ToUpper(parts[i])
+		case 4:
+			parts[i] = strings.ToUpper(parts[i][:1]) + strings.ToLower(parts[i][1:])
+		default:
+			parts[i] = strings.ToLower(parts[i])
+		}
+	}
+	return strings.Join(parts, "-"), true
+}
+
+// languageName 返回语言标签的英文名称，未知语言返回标签本身。
+func languageName(language string) string {
+	primary, _, _ := strings.Cut(language, "-")
+	if name, ok := languageNames[strings.ToLower(primary)]; ok {
+		return name
+	}
+	return language
+}
+
+func isChinese(language string) bool {
+	primary, _, _ := strings.Cut(language, "-")
+	return strings.EqualFold(primary, "zh")
+}
+
+func isEnglish(language string) bool {
+	return language == "" || language == DefaultResponseLanguage || strings.HasPrefix(language, DefaultResponseLanguage+"-")
+}
+
+// 方法
+// SetForceResponseLanguage 固定响应语言并跳过检测；传入空字符串恢复自动检测。
+func (llm *LLMOrchestrator) SetForceResponseLanguage(language string) error {
+	if llm == nil {
+		return nil
+	}
+	if strings.TrimSpace(language) == "" {
+		llm.forceLanguage = ""
+		return nil
+	}
+	normalized, ok := NormalizeLanguageTag(language)
+	if !ok {
+		return fmt.Errorf("invalid response language %q", language)
+	}
+	llm.forceLanguage = normalized
+	return nil
+}
+
+// WithResponseLanguage 返回一个编排器副本，其生成内容固定使用 language 且跳过检测；无效或空标签时原样返回副本。
+func (llm *LLMOrchestrator) WithResponseLanguage(language string) *LLMOrchestrator {
+	if llm == nil {
+		return nil
+	}
+	clone := *llm
+	if normalized, ok := NormalizeLanguageTag(language); ok {
+		clone.forceLanguage = normalized
+	}
+	return &clone
+}
+
+// DetectLanguage 通过简短的分类提示让模型返回文本的 BCP-47 语言代码，结果按内容哈希缓存一小时。
+func (llm *LLMOrchestrator) DetectLanguage(text string) (string, error) {
+	if llm == nil {
+		return "", errors.New("llm orchestrator is nil")
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", errors.New("text is empty")
+	}
+	if !llm.hasRemoteBackend() {
+		return "", errors.New("language detection requires a remote llm backend")
+	}
+
+	key := hashPrompt(text)
+	if llm.languages != nil {
+		if language, ok := llm.languages.get(key); ok {
+			return language, nil
+		}
+	}
+
+	prompt := "Identify the language of the text between the markers. " +
+		"Respond with only its BCP-47 language code (for example en, ja, zh-CN, pt-BR) and nothing else.\n" +
+		"<text>\n" + truncate(text, languageSampleRunes) + "\n</text>"
+	resp, err := llm.withoutStream().CallLLM(&LLMRequest{
+		Prompt:      prompt,
+		Temperature: float64Ptr(0.1),
+		MaxTokens:   8,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	candidate := strings.Trim(strings.TrimSpace(resp.Content), "\"'`.")
+	language, ok := NormalizeLanguageTag(candidate)
+	if !ok {
+		return "", fmt.Errorf("unrecognized language code %q", truncate(candidate, 32))
+	}
+
+	if llm.languages != nil {
+		llm.languages.put(key, language)
+	}
+	return language, nil
+}
+
+// responseLanguageFor 返回生成内容应使用的语言：优先使用配置的固定语言，检测失败时回退为英语。
+func (llm *LLMOrchestrator) responseLanguageFor(text string) string {
+	if llm == nil {
+		return DefaultResponseLanguage
+	}
+	if llm.forceLanguage != "" {
+		return llm.forceLanguage
+	}
+	if !llm.hasRemoteBackend() {
+		return DefaultResponseLanguage
+	}
+
+	language, err := llm.DetectLanguage(text)
+	if err != nil {
+		utils.Warn("language detection failed; responding in English", utils.KV("error", err))
+		return DefaultResponseLanguage
+	}
+	return language
+}
+
+// withResponseLanguage 将模板中的英语输出约束替换为目标语言约束，并在结尾说明中重申。
+func withResponseLanguage(tpl promptTemplate, language string) promptTemplate {
+	if isEnglish(language) {
+		return tpl
+	}
+
+	constraints := make([]string, 0, len(tpl.constraints)+1)
+	for _, constraint := range tpl.constraints {
+		if strings.HasPrefix(constraint, "All output must be in English") {
+			continue
+		}
+		constraints = append(constraints, constraint)
+	}
+	name := languageName(language)
+	constraints = append(constraints, fmt.Sprintf(
+		"All output must be in %s (BCP-47 code %q); keep JSON field names and direction type values in English.", name, language))
+	tpl.constraints = constraints
+	tpl.closing = strings.TrimSpace(tpl.closing + " " + fmt.Sprintf("Write every natural-language value in %s.", name))
+	return tpl
+}
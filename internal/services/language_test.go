@@ -0,0 +1,143 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type languageTestServer struct {
+	mutex      sync.Mutex
+	detections int
+	prompts    []string
+}
+
+func newLanguageTestServer(t *testing.T, detected string) (*httptest.Server, *languageTestServer) {
+	t.Helper()
+	state := &languageTestServer{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Messages []map[string]string `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		prompt := payload.Messages[len(payload.Messages)-1]["content"]
+
+		content := `[{"type":"deep","title":"核心","summary":"基本"}]`
+		state.mutex.Lock()
+		if strings.HasPrefix(prompt, "Identify the language") {
+			state.detections++
+			content = detected
+		} else {
+			state.prompts = append(state.prompts, prompt)
+		}
+		state.mutex.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{
+				{"index": 0, "message": map[string]string{"role": "assistant", "content": content}},
+			},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, state
+}
+
+func TestGenerateThoughtDirectionsRespondsInDetectedLanguage(t *testing.T) {
+	server, state := newLanguageTestServer(t, "ja")
+	orchestrator := NewLLMOrchestrator("key", server.URL, "model")
+
+	for i := 0; i < 2; i++ {
+		if _, err := orchestrator.GenerateThoughtDirections("機械学習", nil); err != nil {
+			t.Fatalf("GenerateThoughtDirections returned error: %v", err)
+		}
+	}
+
+	if state.detections != 1 {
+		t.Fatalf("expected detection to be cached after the first call, got %d detections", state.detections)
+	}
+	prompt := state.prompts[0]
+	if !strings.Contains(prompt, `BCP-47 code "ja"`) {
+		t.Fatalf("expected prompt to request Japanese output:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "All output must be in English") {
+		t.Fatalf("expected English-only constraint to be replaced:\n%s", prompt)
+	}
+
+	orchestrator.languages.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, err := orchestrator.GenerateThoughtDirections("機械学習", nil); err != nil {
+		t.Fatalf("GenerateThoughtDirections returned error: %v", err)
+	}
+	if state.detections != 2 {
+		t.Fatalf("expected expired cache entry to trigger a new detection, got %d detections", state.detections)
+	}
+}
+
+func TestGenerateThoughtDirectionsFallsBackToEnglish(t *testing.T) {
+	server, state := newLanguageTestServer(t, "I think this is Portuguese")
+	orchestrator := NewLLMOrchestrator("key", server.URL, "model")
+
+	if _, err := orchestrator.GenerateThoughtDirections("aprendizado de máquina", nil); err != nil {
+		t.Fatalf("GenerateThoughtDirections returned error: %v", err)
+	}
+	if !strings.Contains(state.prompts[0], "All output must be in English") {
+		t.Fatalf("expected English constraint after failed detection:\n%s", state.prompts[0])
+	}
+}
+
+func TestForceResponseLanguageSkipsDetection(t *testing.T) {
+	server, state := newLanguageTestServer(t, "en")
+	orchestrator := NewLLMOrchestrator("key", server.URL, "model")
+	if err := orchestrator.SetForceResponseLanguage("pt_br"); err != nil {
+		t.Fatalf("SetForceResponseLanguage returned error: %v", err)
+	}
+
+	if _, err := orchestrator.GenerateThoughtDirections("machine learning", nil); err != nil {
+		t.Fatalf("GenerateThoughtDirections returned error: %v", err)
+	}
+	if state.detections != 0 {
+		t.Fatalf("expected forced language to skip detection, got %d detections", state.detections)
+	}
+	if !strings.Contains(state.prompts[0], `BCP-47 code "pt-BR"`) {
+		t.Fatalf("expected prompt to request Brazilian Portuguese output:\n%s", state.prompts[0])
+	}
+
+	if err := orchestrator.SetForceResponseLanguage("not a language"); err == nil {
+		t.Fatalf("expected invalid forced language to be rejected")
+	}
+}
+
+func TestResponseLanguageConstraintAndLocalizedFallback(t *testing.T) {
+	llm := NewLLMOrchestrator("", "", "")
+
+	prompt := llm.buildPrompt("机器学习", nil, "directions", "zh-CN")
+	if !strings.Contains(prompt, `All output must be in Chinese (BCP-47 code "zh-CN")`) || !strings.Contains(prompt, "Write every natural-language value in Chinese.") {
+		t.Fatalf("expected Chinese constraint and closing instruction:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "All output must be in English") {
+		t.Fatalf("expected English-only constraint to be replaced:\n%s", prompt)
+	}
+
+	english, err := llm.GenerateThoughtDirections("机器学习", nil)
+	if err != nil {
+		t.Fatalf("GenerateThoughtDirections failed: %v", err)
+	}
+	if english[0].Title != "Mapping the 机器学习 landscape" {
+		t.Fatalf("expected English fallback titles by default, got %q", english[0].Title)
+	}
+
+	chinese, err := llm.WithResponseLanguage("zh").GenerateThoughtDirections("机器学习", nil)
+	if err != nil {
+		t.Fatalf("GenerateThoughtDirections failed: %v", err)
+	}
+	if chinese[0].Title != "机器学习全景梳理" || chinese[1].Title != "深入机器学习的核心机制" {
+		t.Fatalf("expected Chinese fallback titles, got %q and %q", chinese[0].Title, chinese[1].Title)
+	}
+	if llm.forceLanguage != "" {
+		t.Fatalf("expected WithResponseLanguage to leave the original orchestrator untouched")
+	}
+}
@@ -0,0 +1,47 @@
+//LLM Concurrency Limit(LLM 并发上限)
+
+package services
+
+import (
+	"context"
+	"fmt"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+// DefaultLLMMaxConcurrent 是同时发往上游的 LLM 请求数默认上限。
+const DefaultLLMMaxConcurrent = 8
+
+// 方法
+// SetMaxConcurrent 限制同时发往上游的请求数；limit 非正时不限制。
+// 编排器的副本（ForUser、WithContext 等）共享同一个信号量。
+func (llm *LLMOrchestrator) SetMaxConcurrent(limit int) {
+	if llm == nil {
+		return
+	}
+	llm.concurrency = utils.NewSemaphore(limit)
+}
+
+// ConcurrencyStatus 返回并发上限、进行中与排队中的请求数。
+func (llm *LLMOrchestrator) ConcurrencyStatus() utils.SemaphoreStatus {
+	if llm == nil {
+		return utils.SemaphoreStatus{}
+	}
+	return llm.concurrency.Status()
+}
+
+// acquireCallSlot 在发送请求前等待空位，等待时间受 ctx 与单次调用超时约束；
+// 等待期间 ctx 结束时返回 ErrLLMBusy，请求不会发出。成功时返回的函数用于归还空位。
+func (llm *LLMOrchestrator) acquireCallSlot(ctx context.Context) (func(), error) {
+	if llm.concurrency == nil {
+		return func() {}, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, llm.timeout)
+	defer cancel()
+	if err := llm.concurrency.Acquire(ctx); err != nil {
+		return nil, fmt.Errorf("%w: %w", appErrors.ErrLLMBusy, err)
+	}
+	return llm.concurrency.Release, nil
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	appErrors "WideMindsMCP/internal/errors"
+)
+
+func TestCallLLMQueuesBeyondConcurrencyLimit(t *testing.T) {
+	var calls int32
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		started <- struct{}{}
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"ok"}}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	llm := NewLLMOrchestrator("key", server.URL, "model")
+	llm.SetMaxConcurrent(1)
+
+	errs := make(chan error, 2)
+	call := func() {
+		_, err := llm.CallLLM(&LLMRequest{Prompt: "slow", NoCache: true})
+		errs <- err
+	}
+	go call()
+	<-started
+	go call()
+	waitFor(t, func() bool { return llm.ConcurrencyStatus().Waiting == 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := llm.WithContext(ctx).CallLLM(&LLMRequest{Prompt: "slow", NoCache: true}); !errors.Is(err, appErrors.ErrLLMBusy) {
+		t.Fatalf("expected ErrLLMBusy for a call whose deadline expires in the queue, got %v", err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("expected only the first call to reach the backend, got %d", got)
+	}
+	if status := llm.ConcurrencyStatus(); status.InFlight != 1 || status.Limit != 1 || status.Rejected != 1 {
+		t.Fatalf("unexpected concurrency status %+v", status)
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("queued call failed: %v", err)
+		}
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("expected the queued call to run after the first finished, got %d calls", got)
+	}
+	if status := llm.Stats().Concurrency; status.InFlight != 0 || status.Waiting != 0 {
+		t.Fatalf("expected all slots to be released, got %+v", status)
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,98 @@
+//LLM Model Fallback(LLM 备用模型链)
+
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"WideMindsMCP/internal/utils"
+)
+
+// 结构体
+// LLMFallback 描述一个备用模型。BaseURL 为空时沿用主模型的网关与 API key；
+// 指定了 BaseURL 时只使用 APIKey，不会把主模型的 key 发往其他网关。
+// 备用模型与主模型共用供应商类型、传输层与重试策略，但不经过主模型的熔断器。
+type LLMFallback struct {
+	Model   string `yaml:"model" json:"model"`
+	BaseURL string `yaml:"base_url" json:"base_url"`
+	APIKey  string `yaml:"api_key" json:"api_key"`
+}
+
+// 方法
+// SetFallbacks 设置主模型失败（429、5xx、网络错误或熔断打开）时按顺序尝试的备用模型。
+func (llm *LLMOrchestrator) SetFallbacks(fallbacks []LLMFallback) error {
+	if llm == nil {
+		return nil
+	}
+	if err := ValidateLLMFallbacks(fallbacks); err != nil {
+		return err
+	}
+	llm.fallbacks = append([]LLMFallback(nil), fallbacks...)
+	return nil
+}
+
+// completeWithFallback 先请求主模型，遇到上游故障时依次尝试备用模型，并返回实际应答的编排器；
+// 请求上下文已取消或超时后不再尝试，返回最后一个错误。
+func (llm *LLMOrchestrator) completeWithFallback(call *llmCall) (*LLMResponse, *LLMOrchestrator, error) {
+	resp, err := llm.completeRemote(call)
+	if err == nil || len(llm.fallbacks) == 0 || !isUpstreamFailure(err) {
+		return resp, llm, err
+	}
+
+	for _, fallback := range llm.fallbacks {
+		if llm.requestContext().Err() != nil {
+			return nil, nil, err
+		}
+		backend := llm.fallbackBackend(fallback)
+		utils.Warn("llm backend failed; trying fallback model",
+			utils.KV("fallback_model", backend.reportedModel()),
+			utils.KV("error", err),
+		)
+		resp, err = backend.completeRemote(call)
+		if err == nil {
+			utils.Info("llm request served by fallback model", utils.KV("model", resp.Model))
+			return resp, backend, nil
+		}
+		if !isUpstreamFailure(err) {
+			return nil, nil, err
+		}
+	}
+	return nil, nil, err
+}
+
+// fallbackBackend 返回替换了模型、网关与 API key 的编排器副本，供单次备用请求使用。
+func (llm *LLMOrchestrator) fallbackBackend(fallback LLMFallback) *LLMOrchestrator {
+	backend := *llm
+	backend.model = fallback.Model
+	if llm.isAzure() {
+		backend.azureDeployment = fallback.Model
+	}
+	if baseURL := strings.TrimSpace(fallback.BaseURL); baseURL != "" {
+		backend.baseURL = baseURL
+		backend.apiKey = fallback.APIKey
+	}
+	backend.breaker = nil
+	backend.fallbacks = nil
+	return &backend
+}
+
+// 函数
+// ValidateLLMFallbacks 校验备用模型配置：模型名必填，BaseURL 须为 http(s) 绝对地址。
+func ValidateLLMFallbacks(fallbacks []LLMFallback) error {
+	for i, fallback := range fallbacks {
+		if strings.TrimSpace(fallback.Model) == "" {
+			return fmt.Errorf("fallback %d: model is required", i+1)
+		}
+		baseURL := strings.TrimSpace(fallback.BaseURL)
+		if baseURL == "" {
+			continue
+		}
+		parsed, err := url.Parse(baseURL)
+		if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return fmt.Errorf("fallback %d: base_url %q must be an absolute http(s) URL", i+1, baseURL)
+		}
+	}
+	return nil
+}
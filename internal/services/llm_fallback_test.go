@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"WideMindsMCP/internal/utils"
+)
+
+// newModelRecordingBackend 返回成功响应，并记录请求中的模型名与 Authorization 头。
+func newModelRecordingBackend(t *testing.T, content string) (*httptest.Server, *[]string, *[]string) {
+	t.Helper()
+	var models, auths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		models = append(models, payload.Model)
+		auths = append(auths, r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"content": content}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, &models, &auths
+}
+
+func newFallbackTestLLM(t *testing.T, primaryURL string, fallbacks ...LLMFallback) *LLMOrchestrator {
+	t.Helper()
+	llm := NewLLMOrchestrator("primary-key", primaryURL, "primary-model")
+	llm.SetRetryPolicy(2, time.Millisecond)
+	if err := llm.SetFallbacks(fallbacks); err != nil {
+		t.Fatalf("SetFallbacks failed: %v", err)
+	}
+	return llm
+}
+
+func TestCallLLMFallsBackOnPrimaryServerErrors(t *testing.T) {
+	primary, primaryCalls := newScriptedBackend(t, http.StatusInternalServerError, nil, nil)
+	secondary, models, auths := newModelRecordingBackend(t, "from secondary")
+	llm := newFallbackTestLLM(t, primary.URL, LLMFallback{Model: "secondary-model", BaseURL: secondary.URL, APIKey: "secondary-key"})
+
+	resp, err := llm.CallLLM(&LLMRequest{Prompt: "hello"})
+	if err != nil {
+		t.Fatalf("CallLLM failed: %v", err)
+	}
+	if resp.Content != "from secondary" || resp.Model != "secondary-model" {
+		t.Fatalf("expected secondary response attributed to secondary-model, got %+v", resp)
+	}
+	if got := atomic.LoadInt32(primaryCalls); got != 2 {
+		t.Fatalf("expected primary to exhaust its retries first, got %d calls", got)
+	}
+	if len(*models) != 1 || (*models)[0] != "secondary-model" || (*auths)[0] != "Bearer secondary-key" {
+		t.Fatalf("expected one secondary call with its own model and key, got %v %v", *models, *auths)
+	}
+	if calls := llm.GetRecentLLMCalls(1); len(calls) != 1 || calls[0].Model != "secondary-model" || calls[0].Status != "ok" {
+		t.Fatalf("expected audit record attributed to the fallback, got %+v", calls)
+	}
+}
+
+func TestCallLLMCachesFallbackResponsesUnderTheFallbackModel(t *testing.T) {
+	primary, primaryCalls := newScriptedBackend(t, http.StatusInternalServerError, nil, nil)
+	secondary, models, _ := newModelRecordingBackend(t, "from secondary")
+	llm := newFallbackTestLLM(t, primary.URL, LLMFallback{Model: "secondary-model", BaseURL: secondary.URL, APIKey: "secondary-key"})
+	llm.SetResponseCache(8, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		resp, err := llm.CallLLM(&LLMRequest{Prompt: "hello"})
+		if err != nil {
+			t.Fatalf("call %d: CallLLM failed: %v", i+1, err)
+		}
+		if resp.Cached {
+			t.Fatalf("call %d: expected the fallback answer not to be served from the primary model's cache entry", i+1)
+		}
+	}
+	if got := atomic.LoadInt32(primaryCalls); got != 4 || len(*models) != 2 {
+		t.Fatalf("expected both calls to try the primary before the fallback, got %d primary and %d fallback calls", got, len(*models))
+	}
+
+	backend := llm.fallbackBackend(llm.fallbacks[0])
+	call, _, err := llm.prepareCall(&LLMRequest{Prompt: "hello"})
+	if err != nil {
+		t.Fatalf("prepareCall failed: %v", err)
+	}
+	if _, ok := llm.cache.get(responseCacheKey(backend.reportedModel(), call)); !ok {
+		t.Fatal("expected the fallback answer to be cached under the fallback model")
+	}
+}
+
+func TestCallLLMFallbackReusesPrimaryGatewayWithoutBaseURL(t *testing.T) {
+	secondary, models, auths := newModelRecordingBackend(t, "ok")
+	llm := newFallbackTestLLM(t, secondary.URL, LLMFallback{Model: "cheap-model"})
+	breaker := utils.NewCircuitBreaker(1, time.Minute)
+	breaker.RecordFailure()
+	llm.SetCircuitBreaker(breaker)
+
+	resp, err := llm.CallLLM(&LLMRequest{Prompt: "hello"})
+	if err != nil {
+		t.Fatalf("expected fallback while the circuit is open, got %v", err)
+	}
+	if resp.Model != "cheap-model" || len(*models) != 1 || (*models)[0] != "cheap-model" || (*auths)[0] != "Bearer primary-key" {
+		t.Fatalf("expected cheap-model on the primary gateway, got %+v %v %v", resp, *models, *auths)
+	}
+}
+
+func TestCallLLMFallbackSkipsNonRetryableFailures(t *testing.T) {
+	secondary, models, _ := newModelRecordingBackend(t, "ok")
+
+	llm := newFallbackTestLLM(t, secondary.URL, LLMFallback{Model: "secondary-model", BaseURL: secondary.URL, APIKey: "key"})
+	if _, err := llm.CallLLM(&LLMRequest{Prompt: "   "}); err == nil {
+		t.Fatal("expected empty prompt to be rejected")
+	}
+
+	badRequest, _ := newScriptedBackend(t, http.StatusBadRequest, nil, nil)
+	llm = newFallbackTestLLM(t, badRequest.URL, LLMFallback{Model: "secondary-model", BaseURL: secondary.URL, APIKey: "key"})
+	var httpErr *llmHTTPError
+	if _, err := llm.CallLLM(&LLMRequest{Prompt: "hello"}); !errors.As(err, &httpErr) || httpErr.status != http.StatusBadRequest {
+		t.Fatalf("expected the primary 400 to be returned, got %v", err)
+	}
+
+	if len(*models) != 0 {
+		t.Fatalf("expected no fallback calls, got %v", *models)
+	}
+}
+
+func TestCallLLMFallbackRespectsCanceledContext(t *testing.T) {
+	primary, _ := newScriptedBackend(t, http.StatusServiceUnavailable, nil, nil)
+	secondary, models, _ := newModelRecordingBackend(t, "ok")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	llm := newFallbackTestLLM(t, primary.URL, LLMFallback{Model: "secondary-model", BaseURL: secondary.URL, APIKey: "key"}).WithContext(ctx)
+
+	if _, err := llm.CallLLM(&LLMRequest{Prompt: "hello"}); err == nil {
+		t.Fatal("expected an error once the context is canceled")
+	}
+	if len(*models) != 0 {
+		t.Fatalf("expected no fallback after cancellation, got %v", *models)
+	}
+}
+
+func TestValidateLLMFallbacks(t *testing.T) {
+	valid := []LLMFallback{{Model: "a"}, {Model: "b", BaseURL: "https://backup.example.com"}}
+	if err := ValidateLLMFallbacks(valid); err != nil {
+		t.Fatalf("expected valid fallbacks, got %v", err)
+	}
+	for _, invalid := range [][]LLMFallback{{{Model: " "}}, {{Model: "a", BaseURL: "ftp://backup"}}, {{Model: "a", BaseURL: "backup"}}} {
+		if err := ValidateLLMFallbacks(invalid); err == nil {
+			t.Fatalf("expected %+v to be rejected", invalid)
+		}
+	}
+}
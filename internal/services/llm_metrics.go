@@ -0,0 +1,194 @@
+//LLM Call Metrics(LLM 调用指标)
+
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+// 错误分类，作为 llm_request_errors_total 的 class 标签。
+const (
+	LLMErrorTimeout     = "timeout"
+	LLMErrorClient      = "4xx"
+	LLMErrorServer      = "5xx"
+	LLMErrorParse       = "parse"
+	LLMErrorCircuitOpen = "circuit_open"
+	LLMErrorCanceled    = "canceled"
+	LLMErrorOther       = "other"
+)
+
+// 结构体
+// LLMMetrics 是 LLM 调用指标的 JSON 快照；Calls 与 Errors 分别按调用方式（sync/stream）与错误分类计数。
+type LLMMetrics struct {
+	Calls        map[string]uint64       `json:"calls"`
+	Errors       map[string]uint64       `json:"errors"`
+	Latency      utils.HistogramSnapshot `json:"latency_seconds"`
+	TokensIn     uint64                  `json:"tokens_in"`
+	TokensOut    uint64                  `json:"tokens_out"`
+	Circuit      utils.CircuitStatus     `json:"circuit"`
+	Cache        ResponseCacheStats      `json:"cache"`
+	CacheHitRate float64                 `json:"cache_hit_rate"`
+	// SchemaValidationFailures 是未通过结构校验的方向回复数（含纠正重试）。
+	SchemaValidationFailures uint64 `json:"schema_validation_failures"`
+}
+
+// llmMetrics 保存实际发往上游的调用指标（不含缓存命中），由编排器副本共享。
+type llmMetrics struct {
+	registry *utils.MetricsRegistry
+	calls    *utils.Counter
+	errors   *utils.Counter
+	latency  *utils.Histogram
+	tokens   *utils.Counter
+	schema   *utils.Counter
+}
+
+// llmParseError 表示上游响应无法解析，仅用于指标分类，错误信息与被包装的错误一致。
+type llmParseError struct {
+	err error
+}
+
+func (e *llmParseError) Error() string {
+	return e.err.Error()
+}
+
+func (e *llmParseError) Unwrap() error {
+	return e.err
+}
+
+// 方法
+// MetricsRegistry 返回 LLM 指标注册表，用于 Prometheus 导出。
+func (llm *LLMOrchestrator) MetricsRegistry() *utils.MetricsRegistry {
+	if llm == nil || llm.metrics == nil {
+		return nil
+	}
+	return llm.metrics.registry
+}
+
+// Metrics 返回调用次数、错误分类、耗时分布、令牌数以及熔断器与缓存状态。
+func (llm *LLMOrchestrator) Metrics() LLMMetrics {
+	stats := llm.Stats()
+	result := LLMMetrics{
+		Calls:        map[string]uint64{},
+		Errors:       map[string]uint64{},
+		Circuit:      stats.Circuit,
+		Cache:        stats.Cache,
+		CacheHitRate: stats.Cache.hitRate(),
+	}
+	if llm == nil || llm.metrics == nil {
+		return result
+	}
+	result.Calls = llm.metrics.calls.Values()
+	result.Errors = llm.metrics.errors.Values()
+	result.Latency = llm.metrics.latency.Snapshot()
+	result.TokensIn = llm.metrics.tokens.Value("in")
+	result.TokensOut = llm.metrics.tokens.Value("out")
+	result.SchemaValidationFailures = llm.metrics.schema.Value("")
+	return result
+}
+
+// observe 记录一次上游调用的结果、耗时与令牌数。
+func (m *llmMetrics) observe(resp *LLMResponse, err error, latency time.Duration, stream bool) {
+	if m == nil {
+		return
+	}
+	mode := "sync"
+	if stream {
+		mode = "stream"
+	}
+	m.calls.Inc(mode)
+	m.latency.Observe(latency.Seconds())
+	if err != nil {
+		m.errors.Inc(classifyLLMError(err))
+		return
+	}
+	if resp != nil {
+		m.tokens.Add("in", uint64(max(resp.Usage.PromptTokens, 0)))
+		m.tokens.Add("out", uint64(max(resp.Usage.CompletionTokens, 0)))
+	}
+}
+
+// observeSchemaFailure 记录一次未通过结构校验的回复。
+func (m *llmMetrics) observeSchemaFailure() {
+	if m == nil {
+		return
+	}
+	m.schema.Inc("")
+}
+
+func (s ResponseCacheStats) hitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// 函数
+// newLLMMetrics 注册 LLM 指标；熔断器状态与缓存命中率在导出时从 llm 读取。
+func newLLMMetrics(llm *LLMOrchestrator) *llmMetrics {
+	registry := utils.NewMetricsRegistry()
+	metrics := &llmMetrics{
+		registry: registry,
+		calls:    registry.NewCounter("llm_requests_total", "LLM requests sent upstream by mode.", "mode"),
+		errors:   registry.NewCounter("llm_request_errors_total", "Failed LLM requests by error class.", "class"),
+		latency:  registry.NewHistogram("llm_request_duration_seconds", "LLM request latency including retries.", utils.DefaultLatencyBuckets),
+		tokens:   registry.NewCounter("llm_tokens_total", "Tokens reported by the LLM backend by direction.", "direction"),
+		schema:   registry.NewCounter("llm_schema_validation_failures_total", "LLM responses that failed schema validation.", ""),
+	}
+	registry.NewGaugeFunc("llm_circuit_state", "LLM circuit breaker state (0 closed, 1 half open, 2 open).", func() float64 {
+		return circuitStateValue(llm.CircuitStatus().State)
+	})
+	registry.NewCounterFunc("circuit_open_total", "Times the LLM circuit breaker has opened.", func() float64 {
+		return float64(llm.CircuitStatus().CircuitOpenTotal)
+	})
+	registry.NewGaugeFunc("llm_in_flight_requests", "LLM requests currently in flight upstream.", func() float64 {
+		return float64(llm.ConcurrencyStatus().InFlight)
+	})
+	registry.NewGaugeFunc("llm_cache_hit_rate", "Share of cacheable LLM requests served from the response cache.", func() float64 {
+		return llm.Stats().Cache.hitRate()
+	})
+	return metrics
+}
+
+// classifyLLMError 将调用错误归入超时、4xx、5xx、解析失败等分类。
+func classifyLLMError(err error) string {
+	var (
+		httpErr  *llmHTTPError
+		parseErr *llmParseError
+		netErr   net.Error
+	)
+	switch {
+	case errors.As(err, &httpErr) && httpErr.status >= http.StatusInternalServerError:
+		return LLMErrorServer
+	case errors.As(err, &httpErr):
+		return LLMErrorClient
+	case errors.As(err, &parseErr):
+		return LLMErrorParse
+	case errors.Is(err, appErrors.ErrCircuitOpen):
+		return LLMErrorCircuitOpen
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return LLMErrorTimeout
+	case isCanceled(err):
+		return LLMErrorCanceled
+	default:
+		return LLMErrorOther
+	}
+}
+
+func circuitStateValue(state utils.CircuitState) float64 {
+	switch state {
+	case utils.CircuitHalfOpen:
+		return 1
+	case utils.CircuitOpen:
+		return 2
+	default:
+		return 0
+	}
+}
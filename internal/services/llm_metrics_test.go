@@ -0,0 +1,107 @@
+package services
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newOutcomeBackend 按调用顺序依次返回 responses 中的状态码与响应体。
+func newOutcomeBackend(t *testing.T, responses []struct {
+	status int
+	body   string
+}) *httptest.Server {
+	t.Helper()
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		index := int(atomic.AddInt32(&calls, 1)) - 1
+		response := responses[min(index, len(responses)-1)]
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(response.status)
+		_, _ = w.Write([]byte(response.body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLLMMetricsCountOutcomes(t *testing.T) {
+	success := `{"choices":[{"message":{"content":"ok"}}],"usage":{"prompt_tokens":12,"completion_tokens":5,"total_tokens":17}}`
+	server := newOutcomeBackend(t, []struct {
+		status int
+		body   string
+	}{
+		{http.StatusOK, success},
+		{http.StatusOK, success},
+		{http.StatusInternalServerError, "upstream down"},
+		{http.StatusBadRequest, "bad request"},
+		{http.StatusOK, "not json"},
+	})
+	llm := NewLLMOrchestrator("key", server.URL, "model")
+	llm.SetRetryPolicy(1, time.Millisecond)
+
+	for i, wantErr := range []bool{false, false, true, true, true} {
+		_, err := llm.CallLLM(&LLMRequest{Prompt: "prompt", NoCache: true})
+		if (err != nil) != wantErr {
+			t.Fatalf("call %d: expected error=%v, got %v", i, wantErr, err)
+		}
+	}
+
+	metrics := llm.Metrics()
+	if metrics.Calls["sync"] != 5 {
+		t.Fatalf("expected 5 sync calls, got %+v", metrics.Calls)
+	}
+	for class, want := range map[string]uint64{LLMErrorServer: 1, LLMErrorClient: 1, LLMErrorParse: 1, LLMErrorTimeout: 0} {
+		if got := metrics.Errors[class]; got != want {
+			t.Fatalf("expected %d %s errors, got %d (%+v)", want, class, got, metrics.Errors)
+		}
+	}
+	if metrics.TokensIn != 24 || metrics.TokensOut != 10 {
+		t.Fatalf("expected 24 tokens in and 10 out, got %d/%d", metrics.TokensIn, metrics.TokensOut)
+	}
+	if metrics.Latency.Count != 5 || metrics.Latency.Buckets[len(metrics.Latency.Buckets)-1].Count != 5 {
+		t.Fatalf("expected 5 latency observations, got %+v", metrics.Latency)
+	}
+
+	var out bytes.Buffer
+	if err := llm.MetricsRegistry().WritePrometheus(&out); err != nil {
+		t.Fatalf("WritePrometheus failed: %v", err)
+	}
+	for _, line := range []string{
+		"# TYPE llm_requests_total counter",
+		`llm_requests_total{mode="sync"} 5`,
+		`llm_request_errors_total{class="5xx"} 1`,
+		`llm_request_duration_seconds_bucket{le="+Inf"} 5`,
+		"llm_request_duration_seconds_count 5",
+		`llm_tokens_total{direction="in"} 24`,
+		"llm_circuit_state 0",
+		"# TYPE circuit_open_total counter",
+		"circuit_open_total 0",
+		"# TYPE llm_cache_hit_rate gauge",
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Fatalf("expected exposition to contain %q, got:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestClassifyLLMErrorTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(200 * time.Millisecond):
+		}
+	}))
+	t.Cleanup(server.Close)
+	llm := NewLLMOrchestratorWithOptions(LLMOptions{BaseURL: server.URL, Model: "model", Timeout: 20 * time.Millisecond})
+
+	if _, err := llm.CallLLM(&LLMRequest{Prompt: "slow"}); err == nil {
+		t.Fatal("expected a timeout error")
+	}
+	if got := llm.Metrics().Errors[LLMErrorTimeout]; got != 1 {
+		t.Fatalf("expected one timeout error, got %+v", llm.Metrics().Errors)
+	}
+}
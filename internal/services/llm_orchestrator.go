@@ -3,18 +3,21 @@
 package services
 
 import (
-	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"math"
 	"net/http"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	appErrors "WideMindsMCP/internal/errors"
 	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
 	"WideMindsMCP/internal/utils"
 )
 
@@ -23,20 +26,108 @@ type LLMOrchestrator struct {
 	apiKey     string
 	baseURL    string
 	model      string
+	provider   string
+	apiType    string
 	maxTokens  int
 	httpClient *http.Client
 	timeout    time.Duration
+
+	defaultTemperature float64
+	styleTemperature   float64
+
+	budgets       storage.BudgetStore
+	defaultBudget int
+	userID        string
+
+	forceLanguage string
+	languages     *languageCache
+	jsonMode      string
+
+	maxAttempts  int
+	retryBackoff time.Duration
+	breaker      *utils.CircuitBreaker
+	rateLimiter  *utils.TokenBucket
+	concurrency  *utils.Semaphore
+
+	stream *streamHook
+	ctx    context.Context
+
+	ollamaNumCtx    int
+	ollamaKeepAlive string
+
+	azureDeployment string
+	azureAPIVersion string
+
+	exampleLimit int
+	// repairDisabled 关闭方向解析失败后的修复重试
+	repairDisabled bool
+	// schemaValidation 开启方向输出的结构校验与纠正重试
+	schemaValidation bool
+	// toolCalling 开启方向生成的强制函数调用
+	toolCalling bool
+
+	systemPrompt       string
+	systemPromptPrefix string
+	extraInstructions  []string
+
+	estimator      TokenEstimator
+	contextBudget  int
+	contextWindows map[string]int
+
+	cache   *responseCache
+	noCache bool
+	// titles 缓存会话标题建议，不受响应缓存配置影响
+	titles *responseCache
+
+	embeddingModel string
+
+	pricing       map[string]ModelPrice
+	usageRecorder UsageRecorder
+
+	health  *healthMonitor
+	audit   *llmAuditLog
+	prompts *promptLibrary
+	metrics *llmMetrics
+
+	fallbacks []LLMFallback
 }
 
 func (llm *LLMOrchestrator) hasRemoteBackend() bool {
 	return llm != nil && llm.baseURL != "" && llm.httpClient != nil
 }
 
+// LLMOptions 是 NewLLMOrchestratorWithOptions 的参数；非正值使用默认值，DefaultTemperature 为 nil 时使用默认值、
+// 可为 0，MaxRetries 为负时使用默认值、为 0 时不重试。
+type LLMOptions struct {
+	APIKey             string
+	BaseURL            string
+	Model              string
+	Timeout            time.Duration
+	MaxTokens          int
+	DefaultTemperature *float64
+	MaxRetries         int
+	// Transport 配置代理、CA 证书、mTLS 与连接池。
+	Transport LLMTransportOptions
+}
+
 type LLMRequest struct {
-	Prompt      string
-	Context     []string
-	Temperature float64
+	Prompt  string
+	Context []string
+	// Temperature 为 nil 时依次使用思考风格温度与默认温度；显式的 0 会原样发送。
+	Temperature *float64
 	MaxTokens   int
+	// UserID 指定计费用户；为空时使用 ForUser 绑定的用户。
+	UserID string
+	// ResponseFormat 声明期望的 JSON 结构，开启 JSON 模式时随请求发送。
+	ResponseFormat *ResponseFormat
+	// Tool 声明强制调用的函数，设置后替代 ResponseFormat 随请求发送。
+	Tool *ToolSpec
+	// NoCache 跳过响应缓存，强制请求上游。
+	NoCache bool
+	// SystemPrompt 替换默认的系统消息；部署配置的固定前缀始终保留。
+	SystemPrompt string
+	// ExtraInstructions 作为附加约束追加在提示词末尾。
+	ExtraInstructions []string
 }
 
 type LLMResponse struct {
@@ -44,13 +135,15 @@ type LLMResponse struct {
 	Usage     TokenUsage
 	Model     string
 	Timestamp time.Time
+	// Attempts 为获得该响应实际发起的 HTTP 请求次数
+	Attempts int
+	// Cached 表示响应来自缓存，未发起请求，也不计入令牌预算。
+	Cached bool
+	// ToolCalls 为模型返回的函数调用，此时 Content 可能为空。
+	ToolCalls []ToolCall
 }
 
-type TokenUsage struct {
-	PromptTokens     int
-	CompletionTokens int
-	TotalTokens      int
-}
+type TokenUsage = models.TokenUsage
 
 type promptTemplate struct {
 	role         string
@@ -60,13 +153,18 @@ type promptTemplate struct {
 	reasoning    []string
 	styleNotes   []string
 	examples     []fewShotExample
+	outputFormat []string
 	closing      string
+	// extraInstructions 来自请求，原样输出，不做模板替换
+	extraInstructions []string
 }
 
 type fewShotExample struct {
 	name   string
 	input  string
 	output string
+	// keywords 参与与当前概念的关键词匹配，name 与 input 中的词同样参与
+	keywords []string
 }
 
 type promptContextSegments struct {
@@ -77,206 +175,598 @@ type promptContextSegments struct {
 	additional  []string
 }
 
+// localFallbackModel labels content produced without a remote backend.
+const localFallbackModel = "local-fallback"
+
+// LLM 调用默认参数
+const (
+	DefaultLLMModel       = "gpt-4.1"
+	DefaultLLMTimeout     = 15 * time.Second
+	DefaultLLMMaxTokens   = 32768
+	DefaultLLMTemperature = 0.7
+)
+
 // Constructors
+// NewLLMOrchestrator 使用默认超时、令牌上限、温度与重试次数创建编排器。
 func NewLLMOrchestrator(apiKey, baseURL, model string) *LLMOrchestrator {
-	if model == "" {
-		model = "gpt-4.1"
-	}
+	return NewLLMOrchestratorWithOptions(LLMOptions{
+		APIKey:     apiKey,
+		BaseURL:    baseURL,
+		Model:      model,
+		MaxRetries: -1,
+	})
+}
 
-	return &LLMOrchestrator{
-		apiKey:     apiKey,
-		baseURL:    strings.TrimRight(baseURL, "/"),
-		model:      model,
-		maxTokens:  32768,
-		httpClient: &http.Client{Timeout: 15 * time.Second},
-		timeout:    15 * time.Second,
-	}
+func NewLLMOrchestratorWithOptions(opts LLMOptions) *LLMOrchestrator {
+	if opts.Model == "" {
+		opts.Model = DefaultLLMModel
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultLLMTimeout
+	}
+	if opts.MaxTokens <= 0 {
+		opts.MaxTokens = DefaultLLMMaxTokens
+	}
+	defaultTemperature := DefaultLLMTemperature
+	if opts.DefaultTemperature != nil {
+		defaultTemperature = math.Max(0, math.Min(*opts.DefaultTemperature, 2))
+	}
+	maxAttempts := DefaultLLMMaxAttempts
+	if opts.MaxRetries >= 0 {
+		maxAttempts = opts.MaxRetries + 1
+	}
+
+	llm := &LLMOrchestrator{
+		apiKey:       opts.APIKey,
+		baseURL:      strings.TrimRight(opts.BaseURL, "/"),
+		model:        opts.Model,
+		provider:     ProviderOpenAI,
+		apiType:      APITypeOpenAI,
+		maxTokens:    opts.MaxTokens,
+		httpClient:   newLLMHTTPClient(opts.Transport, opts.Timeout),
+		timeout:      opts.Timeout,
+		languages:    newLanguageCache(),
+		jsonMode:     JSONModeOff,
+		estimator:    NewHeuristicTokenEstimator(),
+		exampleLimit: DefaultPromptExampleLimit,
+
+		defaultTemperature: defaultTemperature,
+
+		maxAttempts:  maxAttempts,
+		retryBackoff: defaultRetryBackoff,
+		breaker:      utils.NewCircuitBreaker(0, 0),
+		concurrency:  utils.NewSemaphore(DefaultLLMMaxConcurrent),
+		health:       &healthMonitor{cacheTTL: DefaultHealthCheckCacheTTL},
+		audit:        newLLMAuditLog(DefaultRecentLLMCalls),
+		prompts:      &promptLibrary{},
+		titles:       newResponseCache(sessionTitleCacheSize, SessionTitleCacheTTL),
+	}
+	llm.metrics = newLLMMetrics(llm)
+	return llm
 }
 
 // Methods
-func (llm *LLMOrchestrator) GenerateThoughtDirections(concept string, context []string) ([]models.Direction, error) {
+func (llm *LLMOrchestrator) GenerateThoughtDirections(concept string, context []models.ContextEntry) ([]models.Direction, error) {
 	if concept == "" {
 		return nil, errors.New("concept is required")
 	}
 
-	normalizedContext := make([]string, 0, len(context))
-	for _, entry := range context {
-		trimmed := strings.TrimSpace(entry)
-		if trimmed != "" {
-			normalizedContext = append(normalizedContext, trimmed)
+	normalizedContext := normalizeContextEntries(context)
+
+	language := llm.responseLanguageFor(concept)
+	prompt := llm.buildPrompt(concept, normalizedContext, "directions", language)
+	if llm.hasRemoteBackend() {
+		remotePrompt := prompt
+		// 自我改进的提示词按默认语言改写，指定其他响应语言时仍使用模板
+		if improved, ok := llm.improvedPromptFor(selfImprovePromptType, concept); ok && language == DefaultResponseLanguage {
+			remotePrompt = improved
 		}
+		directions, err := llm.remoteDirections(remotePrompt, normalizedContext)
+		if err != nil {
+			return nil, err
+		}
+		if len(directions) > 0 {
+			return directions, nil
+		}
+	}
+
+	directions := llm.generateFallbackDirections(concept, models.ContextStrings(normalizedContext), language)
+	for i := range directions {
+		directions[i].Provenance = llm.fallbackProvenance(prompt)
 	}
+	return directions, nil
+}
 
-	prompt := llm.BuildPrompt(concept, normalizedContext, "directions")
+// remoteDirections 使用给定提示词请求方向并解析，解析失败时尝试修复；
+// 调用或解析失败时记录警告并返回空结果，仅超出预算或被取消时返回错误。
+func (llm *LLMOrchestrator) remoteDirections(prompt string, context []models.ContextEntry) ([]models.Direction, error) {
+	caller := llm
+	req := &LLMRequest{
+		Prompt:         prompt,
+		Context:        models.ContextStrings(context),
+		MaxTokens:      1024,
+		ResponseFormat: directionsResponseFormat,
+	}
+	if llm.toolCallingEnabled() {
+		// 流式输出不解析函数调用的增量参数
+		caller = llm.withoutStream()
+		req.Tool = directionsTool
+	}
+	resp, err := caller.CallLLM(req)
+	if errors.Is(err, appErrors.ErrBudgetExceeded) || isCanceled(err) {
+		return nil, err
+	} else if err != nil {
+		utils.Warn("LLM call failed while generating directions", utils.KV("error", err))
+	} else if resp != nil {
+		directions, parseErr := llm.directionsFromResponse(resp)
+		if parseErr != nil {
+			utils.Warn("failed to parse LLM directions response", utils.KV("error", parseErr))
+			if !llm.repairDisabled {
+				var repairErr error
+				directions, resp, repairErr = llm.repairDirections(resp.directionsPayload(), parseErr)
+				if errors.Is(repairErr, appErrors.ErrBudgetExceeded) || isCanceled(repairErr) {
+					return nil, repairErr
+				} else if repairErr != nil {
+					utils.Warn("failed to repair LLM directions response", utils.KV("error", repairErr))
+				}
+			}
+		}
+		if parseErr == nil && len(directions) > 0 {
+			var schemaErr error
+			directions, resp, schemaErr = llm.validateDirections(directions, resp)
+			if errors.Is(schemaErr, appErrors.ErrBudgetExceeded) || isCanceled(schemaErr) {
+				return nil, schemaErr
+			} else if schemaErr != nil {
+				utils.Warn("failed to correct LLM directions response; keeping parsed directions", utils.KV("error", schemaErr))
+			}
+		}
+		if len(directions) > 0 {
+			for i := range directions {
+				directions[i].Provenance = newProvenance(prompt, resp)
+			}
+			return directions, nil
+		}
+	}
+	return nil, nil
+}
+
+func (llm *LLMOrchestrator) ExploreDirection(direction models.Direction, depth int, context []models.ContextEntry) ([]*models.Thought, error) {
+	if depth <= 0 {
+		depth = 1
+	}
+
+	normalizedContext := normalizeContextEntries(context)
 	if llm.hasRemoteBackend() {
+		return llm.exploreDirectionRemote(direction, depth, normalizedContext)
+	}
+
+	prompt := llm.BuildPrompt(direction.Title, normalizedContext, "exploration")
+	thoughts := make([]*models.Thought, 0, depth)
+	for i := 0; i < depth; i++ {
+		thoughts = appendLevel(thoughts, llm.syntheticExplorationThought(direction, i+1, normalizedContext, prompt))
+	}
+	return thoughts, nil
+}
+
+// exploreDirectionRemote 为每个深度层级请求一次 "exploration" 提示词；
+// 调用或解析失败时该层级回退为合成内容，超出预算、被取消或 ctx 超时时返回错误。
+func (llm *LLMOrchestrator) exploreDirectionRemote(direction models.Direction, depth int, context []models.ContextEntry) ([]*models.Thought, error) {
+	concept := strings.TrimSpace(direction.Title)
+	if concept == "" {
+		concept = "Exploration insight"
+	}
+
+	thoughts := make([]*models.Thought, 0, depth)
+	for i := 0; i < depth; i++ {
+		levelContext := append([]models.ContextEntry{}, context...)
+		if desc := strings.TrimSpace(direction.Description); desc != "" {
+			levelContext = append(levelContext, models.NewContextEntry(models.ContextBackground, desc))
+		}
+		if i > 0 {
+			levelContext = append(levelContext, models.NewContextEntry(models.ContextHistory, "previous level: "+thoughts[i-1].Content))
+		}
+		levelContext = append(levelContext, models.NewContextEntry(models.ContextNote, fmt.Sprintf("depth: level %d of %d", i+1, depth)))
+
+		prompt := llm.BuildPrompt(concept, levelContext, "exploration")
 		resp, err := llm.CallLLM(&LLMRequest{
-			Prompt:      prompt,
-			Context:     normalizedContext,
-			Temperature: 0.7,
-			MaxTokens:   1024,
+			Prompt:    prompt,
+			MaxTokens: 768,
 		})
-		if err != nil {
-			utils.Warn("LLM call failed while generating directions", utils.KV("error", err))
-		} else if resp != nil {
-			if directions, parseErr := llm.parseDirectionsFromContent(resp.Content); parseErr != nil {
-				utils.Warn("failed to parse LLM directions response", utils.KV("error", parseErr))
-			} else if len(directions) > 0 {
-				return directions, nil
-			}
+		if errors.Is(err, appErrors.ErrBudgetExceeded) || isCanceled(err) || (err != nil && llm.requestContext().Err() != nil) {
+			return nil, fmt.Errorf("explore direction: %w", err)
+		} else if err != nil {
+			utils.Warn("LLM call failed while exploring a direction", utils.KV("error", err))
+			thoughts = appendLevel(thoughts, llm.syntheticExplorationThought(direction, i+1, context, prompt))
+			continue
 		}
+
+		body, parseErr := parseThoughtBody(resp.Content)
+		if parseErr != nil {
+			utils.Warn("failed to parse LLM exploration response", utils.KV("error", parseErr))
+			thoughts = appendLevel(thoughts, llm.syntheticExplorationThought(direction, i+1, context, prompt))
+			continue
+		}
+
+		thought := models.NewThought(body.Content, "", direction)
+		thought.Depth = i + 1
+		thought.Provenance = newProvenance(prompt, resp)
+		thought.Tags = explorationTags(body.KeyConcepts)
+		if body.KeyInsight != "" {
+			thought.SetAnnotation("key_insight", body.KeyInsight)
+		}
+		if body.NextStep != "" {
+			thought.SetAnnotation("next_step", body.NextStep)
+		}
+		if len(body.ValidationSteps) > 0 {
+			thought.SetAnnotation("validation_steps", strings.Join(body.ValidationSteps, "; "))
+		}
+		thoughts = appendLevel(thoughts, thought)
 	}
 
-	return llm.generateFallbackDirections(concept, normalizedContext), nil
+	return thoughts, nil
 }
 
-func (llm *LLMOrchestrator) ExploreDirection(direction models.Direction, depth int, context []string) ([]*models.Thought, error) {
-	if depth <= 0 {
-		depth = 1
+// appendLevel 将新层级挂到上一层之下，使逐层生成的思维构成父子链。
+func appendLevel(thoughts []*models.Thought, thought *models.Thought) []*models.Thought {
+	if n := len(thoughts); n > 0 {
+		thoughts[n-1].AddChild(thought)
 	}
+	return append(thoughts, thought)
+}
 
-	normalizedContext := uniqueStrings(context)
+// syntheticExplorationThought 在没有可用 LLM 响应时根据方向与上下文拼出占位思维。
+func (llm *LLMOrchestrator) syntheticExplorationThought(direction models.Direction, level int, context []models.ContextEntry, prompt string) *models.Thought {
 	contextSummary := ""
-	if len(normalizedContext) > 0 {
-		joined := normalizedContext
+	if len(context) > 0 {
+		joined := models.ContextStrings(context)
 		if len(joined) > 3 {
 			joined = joined[:3]
 		}
 		contextSummary = strings.Join(joined, " | ")
 	}
 
-	thoughts := make([]*models.Thought, 0, depth)
-	for i := 0; i < depth; i++ {
-		contentBuilder := strings.Builder{}
-		contentBuilder.Grow(256)
-		contentBuilder.WriteString(strings.TrimSpace(direction.Title))
-		if contentBuilder.Len() == 0 {
-			contentBuilder.WriteString("Exploration insight")
-		}
-		contentBuilder.WriteString(fmt.Sprintf(" • level %d", i+1))
+	contentBuilder := strings.Builder{}
+	contentBuilder.Grow(256)
+	contentBuilder.WriteString(strings.TrimSpace(direction.Title))
+	if contentBuilder.Len() == 0 {
+		contentBuilder.WriteString("Exploration insight")
+	}
+	contentBuilder.WriteString(fmt.Sprintf(" • level %d", level))
 
-		if desc := strings.TrimSpace(direction.Description); desc != "" {
-			contentBuilder.WriteString(" — ")
-			contentBuilder.WriteString(desc)
+	if desc := strings.TrimSpace(direction.Description); desc != "" {
+		contentBuilder.WriteString(" — ")
+		contentBuilder.WriteString(desc)
+	}
+
+	if contextSummary != "" {
+		contentBuilder.WriteString(" (context: ")
+		contentBuilder.WriteString(contextSummary)
+		if len(context) > 3 {
+			contentBuilder.WriteString(" …")
 		}
+		contentBuilder.WriteString(")")
+	}
 
-		if contextSummary != "" {
-			contentBuilder.WriteString(" (context: ")
-			contentBuilder.WriteString(contextSummary)
-			if len(normalizedContext) > 3 {
-				contentBuilder.WriteString(" …")
-			}
-			contentBuilder.WriteString(")")
+	thought := models.NewThought(contentBuilder.String(), "", direction)
+	thought.Depth = level
+	thought.Provenance = llm.fallbackProvenance(prompt)
+	return thought
+}
+
+// thoughtBody 是探索回复：既接受 "thought" 提示词的 content/key_insight/next_step，
+// 也接受 "exploration" 提示词的 hypothesis/key_concepts/validation_steps，两者可同时出现。
+type thoughtBody struct {
+	Content         string   `json:"content"`
+	KeyInsight      string   `json:"key_insight"`
+	NextStep        string   `json:"next_step"`
+	Hypothesis      string   `json:"hypothesis"`
+	KeyConcepts     []string `json:"key_concepts"`
+	ValidationSteps []string `json:"validation_steps"`
+}
+
+// parseThoughtBody 解析探索回复，content 为空时以 hypothesis 作为思维内容。
+func parseThoughtBody(content string) (*thoughtBody, error) {
+	trimmed := strings.TrimSpace(content)
+	start := strings.Index(trimmed, "{")
+	end := strings.LastIndex(trimmed, "}")
+	if start < 0 || end <= start {
+		return nil, errors.New("llm thought response is not a JSON object")
+	}
+
+	var body thoughtBody
+	if err := json.Unmarshal([]byte(trimmed[start:end+1]), &body); err != nil {
+		return nil, fmt.Errorf("parse llm thought: %w", err)
+	}
+	body.Content = strings.TrimSpace(body.Content)
+	body.KeyInsight = strings.TrimSpace(body.KeyInsight)
+	body.NextStep = strings.TrimSpace(body.NextStep)
+	body.Hypothesis = strings.TrimSpace(body.Hypothesis)
+	if body.Content == "" {
+		body.Content = body.Hypothesis
+	}
+	if body.Content == "" {
+		return nil, errors.New("llm thought content is empty")
+	}
+	steps := make([]string, 0, len(body.ValidationSteps))
+	for _, step := range body.ValidationSteps {
+		if step = strings.TrimSpace(step); step != "" {
+			steps = append(steps, step)
 		}
+	}
+	body.ValidationSteps = steps
+	return &body, nil
+}
 
-		thought := models.NewThought(contentBuilder.String(), "", direction)
-		thought.Depth = i + 1
-		thoughts = append(thoughts, thought)
+// explorationTags 将 key_concepts 转为节点标签：去重、跳过过长项并限制数量。
+func explorationTags(concepts []string) []string {
+	seen := make(map[string]struct{}, len(concepts))
+	tags := make([]string, 0, len(concepts))
+	for _, concept := range concepts {
+		tag := strings.TrimSpace(concept)
+		if tag == "" || utf8.RuneCountInString(tag) > utils.MaxTagLength {
+			continue
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		tags = append(tags, tag)
+		if len(tags) == utils.MaxThoughtTags {
+			break
+		}
+	}
+	if len(tags) == 0 {
+		return nil
 	}
+	return tags
+}
 
-	return thoughts, nil
+// llmCall 是已完成校验与预算检查的一次调用参数
+type llmCall struct {
+	prompt          string
+	maxTokens       int
+	temperature     float64
+	userID          string
+	estimatedTokens int
+	userContent     string
+	systemPrompt    string
+	responseFormat  *ResponseFormat
+	tool            *ToolSpec
 }
 
-func (llm *LLMOrchestrator) CallLLM(req *LLMRequest) (*LLMResponse, error) {
+// prepareCall 校验请求并规范化参数；remote 为 false 时表示应使用本地回退响应。
+func (llm *LLMOrchestrator) prepareCall(req *LLMRequest) (call *llmCall, remote bool, err error) {
 	if llm == nil {
-		return nil, errors.New("llm orchestrator is nil")
+		return nil, false, errors.New("llm orchestrator is nil")
 	}
 
 	if req == nil {
-		return nil, errors.New("request is nil")
+		return nil, false, errors.New("request is nil")
 	}
 
 	prompt := strings.TrimSpace(req.Prompt)
 	if prompt == "" {
-		return nil, errors.New("prompt is empty")
-	}
-
-	maxTokens := req.MaxTokens
-	if maxTokens <= 0 {
-		maxTokens = int(math.Min(float64(llm.maxTokens), 2048))
-	} else if maxTokens > llm.maxTokens {
-		maxTokens = llm.maxTokens
+		return nil, false, errors.New("prompt is empty")
+	}
+	if err := utils.ValidateSystemPrompt(req.SystemPrompt); err != nil {
+		return nil, false, err
+	}
+	prompt = appendExtraInstructions(prompt, req.ExtraInstructions)
+
+	maxTokens := llm.completionTokens(req.MaxTokens)
+	if budget := llm.PromptTokenBudget(maxTokens); budget > 0 {
+		if estimated := llm.estimateTokens(prompt); estimated > budget {
+			utils.Warn("prompt exceeds the input token budget; truncating",
+				utils.KV("estimated_tokens", estimated),
+				utils.KV("budget_tokens", budget),
+				utils.KV("completion_tokens", maxTokens),
+			)
+			prompt = llm.truncatePrompt(prompt, budget)
+		}
 	}
 
-	temperature := req.Temperature
-	if temperature <= 0 {
-		temperature = 0.7
+	temperature := llm.defaultTemperature
+	if req.Temperature != nil {
+		temperature = *req.Temperature
+	} else if llm.styleTemperature > 0 {
+		temperature = llm.styleTemperature
 	}
 	temperature = math.Max(0, math.Min(temperature, 2))
 
+	call = &llmCall{
+		prompt:         prompt,
+		maxTokens:      maxTokens,
+		temperature:    temperature,
+		systemPrompt:   llm.systemMessage(req.SystemPrompt),
+		responseFormat: req.ResponseFormat,
+		tool:           req.Tool,
+	}
 	if !llm.hasRemoteBackend() {
-		return llm.localLLMResponse(prompt, maxTokens), nil
+		return call, false, nil
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), llm.timeout)
-	defer cancel()
+	call.userID = strings.TrimSpace(req.UserID)
+	if call.userID == "" {
+		call.userID = llm.userID
+	}
+	requestContext := llm.fitRequestContext(prompt, req.Context)
+	call.estimatedTokens = llm.estimatePromptTokens(prompt, requestContext)
+	if call.maxTokens, err = llm.clampCompletionTokens(call.estimatedTokens, call.maxTokens); err != nil {
+		return nil, true, err
+	}
+	if err := llm.checkBudget(call.userID, call.estimatedTokens); err != nil {
+		return nil, true, err
+	}
 
-	userContent := prompt
-	if len(req.Context) > 0 {
+	call.userContent = prompt
+	if len(requestContext) > 0 {
 		var sb strings.Builder
 		sb.Grow(len(prompt) + 128)
 		sb.WriteString(prompt)
 		sb.WriteString("\n\nContext:\n")
-		for _, entry := range uniqueStrings(req.Context) {
+		for _, entry := range uniqueStrings(requestContext) {
 			sb.WriteString("- ")
 			sb.WriteString(entry)
 			sb.WriteString("\n")
 		}
-		userContent = strings.TrimSpace(sb.String())
+		call.userContent = strings.TrimSpace(sb.String())
+	}
+	return call, true, nil
+}
+
+// buildPayload 按提供方构建请求体（chat completions 或 Ollama /api/chat）
+func (llm *LLMOrchestrator) buildPayload(call *llmCall, stream bool) ([]byte, error) {
+	if llm.isOllama() {
+		return llm.buildOllamaPayload(call)
 	}
 
 	payload := map[string]any{
 		"model": llm.model,
 		"messages": []map[string]string{
-			{"role": "system", "content": "You are an assistant that returns valid JSON matching the user's instructions."},
-			{"role": "user", "content": userContent},
+			{"role": "system", "content": call.systemPrompt},
+			{"role": "user", "content": call.userContent},
 		},
-		"max_tokens":  maxTokens,
-		"temperature": temperature,
+		"max_tokens":  call.maxTokens,
+		"temperature": call.temperature,
+	}
+	if stream {
+		payload["stream"] = true
+		payload["stream_options"] = map[string]bool{"include_usage": true}
+	}
+	if call.tool != nil {
+		payload["tools"], payload["tool_choice"] = toolsPayload(call.tool)
+	} else if format := llm.responseFormatPayload(call.responseFormat); format != nil {
+		payload["response_format"] = format
 	}
 
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("marshal llm payload: %w", err)
 	}
+	return body, nil
+}
 
+func (llm *LLMOrchestrator) endpoint() string {
+	if llm.isOllama() {
+		return llm.ollamaURL(ollamaChatPath)
+	}
+	if llm.isAzure() {
+		return llm.azureEndpoint()
+	}
 	endpoint := llm.baseURL
 	if !strings.HasSuffix(endpoint, "/v1/chat/completions") {
 		endpoint = strings.TrimRight(endpoint, "/") + "/v1/chat/completions"
 	}
+	return endpoint
+}
+
+// chargeUsage 按实际用量（缺失时按估算）记入用户预算
+func (llm *LLMOrchestrator) chargeUsage(call *llmCall, usage TokenUsage) {
+	charged := usage.TotalTokens
+	if charged <= 0 {
+		charged = call.estimatedTokens
+	}
+	if err := llm.recordUsage(call.userID, charged); err != nil {
+		utils.Warn("failed to record llm token usage", utils.KV("user_id", call.userID), utils.KV("error", err))
+	}
+}
 
-	reqHTTP, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+func (llm *LLMOrchestrator) CallLLM(req *LLMRequest) (*LLMResponse, error) {
+	if llm != nil && llm.stream != nil {
+		return llm.CallLLMStream(llm.stream.ctx, req, llm.stream.onDelta)
+	}
+
+	call, remote, err := llm.prepareCall(req)
 	if err != nil {
-		return nil, fmt.Errorf("new http request: %w", err)
+		return nil, err
+	}
+	if !remote {
+		return llm.localLLMResponse(call.prompt, call.maxTokens), nil
 	}
-	reqHTTP.Header.Set("Content-Type", "application/json")
-	if llm.apiKey != "" {
-		reqHTTP.Header.Set("Authorization", "Bearer "+llm.apiKey)
+	cacheKey, cached := llm.cachedResponse(req, call)
+	if cached != nil {
+		return cached, nil
 	}
 
-	resp, err := llm.httpClient.Do(reqHTTP)
+	if err := llm.waitForRateLimit(llm.requestContext()); err != nil {
+		return nil, err
+	}
+	release, err := llm.acquireCallSlot(llm.requestContext())
+	if err != nil {
+		return nil, err
+	}
+	started := time.Now()
+	resp, servedBy, err := llm.completeWithFallback(call)
+	release()
+	llm.recordCall(call, resp, err, started, false)
+	if err != nil {
+		return nil, err
+	}
+
+	llm.chargeUsage(call, resp.Usage)
+	llm.reportUsage(call, resp)
+	llm.storeResponse(cacheKey, servedBy, call, resp)
+	return resp, nil
+}
+
+// completeRemote 经熔断器与重试向上游发起一次非流式请求并解析响应。
+func (llm *LLMOrchestrator) completeRemote(call *llmCall) (*LLMResponse, error) {
+	ctx, cancel := context.WithTimeout(llm.requestContext(), llm.timeout)
+	defer cancel()
+
+	body, err := llm.buildPayload(call, false)
 	if err != nil {
-		return nil, fmt.Errorf("llm request failed: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	endpoint := llm.endpoint()
 
-	raw, err := io.ReadAll(io.LimitReader(resp.Body, 2*1024*1024))
+	if err := llm.breaker.Allow(); err != nil {
+		return nil, err
+	}
+	raw, attempts, err := llm.postWithRetry(ctx, endpoint, body)
 	if err != nil {
-		return nil, fmt.Errorf("read llm response: %w", err)
+		if isUpstreamFailure(err) {
+			llm.breaker.RecordFailure()
+		} else {
+			llm.breaker.RecordSuccess()
+		}
+		return nil, err
 	}
+	llm.breaker.RecordSuccess()
 
-	if resp.StatusCode >= 400 {
-		snippet := truncate(string(raw), 512)
-		return nil, fmt.Errorf("llm http %d: %s", resp.StatusCode, snippet)
+	parse := parseChatCompletion
+	if llm.isOllama() {
+		parse = parseOllamaChat
 	}
+	resp, err := parse(raw)
+	if err != nil {
+		return nil, &llmParseError{err: err}
+	}
+	if resp.Model == "" {
+		resp.Model = llm.reportedModel()
+	}
+	resp.Timestamp = time.Now().UTC()
+	resp.Attempts = attempts
+	return resp, nil
+}
 
+// parseChatCompletion 解析 chat completions 响应
+func parseChatCompletion(raw []byte) (*LLMResponse, error) {
 	var parsed struct {
 		ID      string `json:"id"`
 		Model   string `json:"model"`
 		Choices []struct {
 			Index   int `json:"index"`
 			Message struct {
-				Role    string `json:"role"`
-				Content string `json:"content"`
+				Role      string `json:"role"`
+				Content   string `json:"content"`
+				ToolCalls []struct {
+					ID       string `json:"id"`
+					Function struct {
+						Name      string          `json:"name"`
+						Arguments json.RawMessage `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
 			} `json:"message"`
 			Text string `json:"text"`
 		} `json:"choices"`
@@ -299,26 +789,27 @@ func (llm *LLMOrchestrator) CallLLM(req *LLMRequest) (*LLMResponse, error) {
 	if content == "" {
 		content = strings.TrimSpace(parsed.Choices[0].Text)
 	}
-	if content == "" {
-		return nil, errors.New("llm response empty")
-	}
-
-	usage := TokenUsage{
-		PromptTokens:     parsed.Usage.PromptTokens,
-		CompletionTokens: parsed.Usage.CompletionTokens,
-		TotalTokens:      parsed.Usage.TotalTokens,
+	var toolCalls []ToolCall
+	for _, call := range parsed.Choices[0].Message.ToolCalls {
+		toolCalls = append(toolCalls, ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: toolCallArguments(call.Function.Arguments),
+		})
 	}
-
-	model := parsed.Model
-	if model == "" {
-		model = llm.model
+	if content == "" && len(toolCalls) == 0 {
+		return nil, errors.New("llm response empty")
 	}
 
 	return &LLMResponse{
 		Content:   content,
-		Usage:     usage,
-		Model:     model,
-		Timestamp: time.Now().UTC(),
+		ToolCalls: toolCalls,
+		Usage: TokenUsage{
+			PromptTokens:     parsed.Usage.PromptTokens,
+			CompletionTokens: parsed.Usage.CompletionTokens,
+			TotalTokens:      parsed.Usage.TotalTokens,
+		},
+		Model: parsed.Model,
 	}, nil
 }
 
@@ -339,23 +830,83 @@ func (llm *LLMOrchestrator) localLLMResponse(prompt string, maxTokens int) *LLMR
 	}
 }
 
+func newProvenance(prompt string, resp *LLMResponse) *models.Provenance {
+	provenance := &models.Provenance{
+		Source:      models.SourceLLM,
+		PromptHash:  hashPrompt(prompt),
+		GeneratedAt: time.Now().UTC(),
+	}
+	if resp != nil {
+		provenance.Model = resp.Model
+		provenance.TokenUsage = resp.Usage
+		if !resp.Timestamp.IsZero() {
+			provenance.GeneratedAt = resp.Timestamp
+		}
+	}
+	return provenance
+}
+
+func (llm *LLMOrchestrator) fallbackProvenance(prompt string) *models.Provenance {
+	return &models.Provenance{
+		Source:      models.SourceLLM,
+		Model:       localFallbackModel,
+		PromptHash:  hashPrompt(prompt),
+		GeneratedAt: time.Now().UTC(),
+	}
+}
+
+func hashPrompt(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// HealthCheck 通过一次最小请求确认远程 LLM 可用；Ollama 先确认模型已下载。未配置远程后端时使用本地回退，视为健康。
+// 结果在 SetHealthCheckCacheTTL 设置的时间窗口内复用，避免频繁请求上游。
 func (llm *LLMOrchestrator) HealthCheck(ctx context.Context) error {
 	if llm == nil {
 		return errors.New("llm orchestrator is nil")
 	}
-	_ = ctx
-	return nil
+	if !llm.hasRemoteBackend() {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err, ok := llm.health.cached(); ok {
+		return err
+	}
+	err := llm.checkHealth(ctx)
+	// 调用方自身的取消或超时不代表后端状态，不写入缓存
+	if ctx.Err() == nil {
+		llm.health.remember(err)
+	}
+	return err
+}
+
+func (llm *LLMOrchestrator) BuildPrompt(concept string, context []models.ContextEntry, promptType string) string {
+	return llm.buildPrompt(concept, context, promptType, DefaultResponseLanguage)
 }
 
-func (llm *LLMOrchestrator) BuildPrompt(concept string, context []string, promptType string) string {
-	tpl := llm.promptTemplateFor(promptType)
+// buildPrompt 构建提示词；language 不是英语时追加响应语言约束。
+func (llm *LLMOrchestrator) buildPrompt(concept string, context []models.ContextEntry, promptType, language string) string {
+	tpl := withResponseLanguage(llm.promptTemplateFor(promptType), language)
+	tpl.examples = selectExamples(tpl.examples, concept, llm.exampleLimit)
+	tpl.extraInstructions = llm.extraInstructions
+	if promptType == "directions" && llm.jsonModeEnabled() {
+		tpl = withJSONObjectOutput(tpl)
+	}
 	data := map[string]string{
 		"concept":    concept,
 		"model":      llm.model,
 		"promptType": promptType,
 	}
-	segments := extractContextSegments(context)
+	return llm.fitSegmentsToBudget(segmentContext(context), func(segments promptContextSegments) string {
+		return renderPrompt(tpl, data, segments)
+	})
+}
 
+// renderPrompt 按模板与上下文片段拼接提示词
+func renderPrompt(tpl promptTemplate, data map[string]string, segments promptContextSegments) string {
 	var builder strings.Builder
 	builder.Grow(1024)
 
@@ -402,6 +953,11 @@ func (llm *LLMOrchestrator) BuildPrompt(concept string, context []string, prompt
 		writeBulletedList(&builder, renderTemplateList(tpl.constraints, data))
 	}
 
+	if len(tpl.extraInstructions) > 0 {
+		builder.WriteString(additionalConstraintsHeading)
+		writeBulletedList(&builder, tpl.extraInstructions)
+	}
+
 	if len(tpl.reasoning) > 0 {
 		builder.WriteString("## Reasoning steps\n")
 		writeNumberedList(&builder, renderTemplateList(tpl.reasoning, data))
@@ -426,9 +982,11 @@ func (llm *LLMOrchestrator) BuildPrompt(concept string, context []string, prompt
 	}
 
 	builder.WriteString("## Output format\n")
-	builder.WriteString("- Prefer structured JSON with a concise natural-language summary.\n")
-	builder.WriteString("- Each direction in the JSON array must include type, title, summary, key_questions, and recommended_actions fields.\n")
-	builder.WriteString("- If requirements cannot be met, explicitly state the missing information and suggest a next step.\n\n")
+	outputFormat := tpl.outputFormat
+	if len(outputFormat) == 0 {
+		outputFormat = defaultOutputFormat
+	}
+	writeBulletedList(&builder, renderTemplateList(outputFormat, data))
 
 	if tpl.closing != "" {
 		builder.WriteString(renderTemplate(tpl.closing, data))
@@ -438,8 +996,168 @@ func (llm *LLMOrchestrator) BuildPrompt(concept string, context []string, prompt
 	return strings.TrimSpace(builder.String())
 }
 
-func (llm *LLMOrchestrator) promptTemplateFor(promptType string) promptTemplate {
+var defaultOutputFormat = []string{
+	"Prefer structured JSON with a concise natural-language summary.",
+	"Each direction in the JSON array must include type, title, summary, key_questions, and recommended_actions fields.",
+	"If requirements cannot be met, explicitly state the missing information and suggest a next step.",
+}
+
+// builtinPromptTemplate 返回内置的提示词模板；未知类型使用通用模板。
+func builtinPromptTemplate(promptType string) promptTemplate {
 	switch strings.ToLower(strings.TrimSpace(promptType)) {
+	case "thought":
+		return promptTemplate{
+			role:    "You are a focused research partner who turns a chosen exploration direction into one concrete, well-argued thought.",
+			mission: "Write a single specific thought that advances the direction '{{concept}}', grounded in the provided context rather than generic statements.",
+			deliverables: []string{
+				"content: 2-4 sentences stating a concrete claim, mechanism, or example tied to the direction.",
+				"key_insight: one sentence capturing the most important takeaway.",
+				"next_step: one actionable follow-up the user could take to go deeper.",
+			},
+			constraints: []string{
+				"Avoid restating the direction title; add new information.",
+				"Stay consistent with the background, history, and preferences provided.",
+			},
+			outputFormat: []string{
+				`Return only a JSON object of the form {"content":"...","key_insight":"...","next_step":"..."}.`,
+				"Do not wrap the JSON in markdown fences or add commentary.",
+			},
+		}
+	case "next_actions":
+		return promptTemplate{
+			role:    "You are a thinking coach who reviews an in-progress mind map and helps the user get unstuck.",
+			mission: "Review the current state of the session rooted at '{{concept}}' and suggest the most valuable next actions.",
+			deliverables: []string{
+				"type: one of deepen, broaden, challenge, connect, or prune.",
+				"rationale: one or two sentences explaining why this action helps now, referencing the session state.",
+				"suggested_direction: an object with type (broad/deep/lateral/critical), title, description, and keywords that can be explored directly.",
+			},
+			constraints: []string{
+				"Prioritize the under-explored branches listed in the notes before proposing entirely new areas.",
+				"Do not repeat directions that already exist in the session.",
+			},
+			outputFormat: []string{
+				`Return only a JSON array of the form [{"type":"...","rationale":"...","suggested_direction":{"type":"...","title":"...","description":"...","keywords":["..."]}}].`,
+				"Do not wrap the JSON in markdown fences or add commentary.",
+			},
+		}
+	case "reflection":
+		return promptTemplate{
+			role:    "You are a candid thinking coach who audits a mind map for blind spots.",
+			mission: "Assess how well the session rooted at '{{concept}}' has been explored and where it falls short.",
+			deliverables: []string{
+				"strengths: what the session already covers well, referencing its branches.",
+				"gaps: missing perspectives; name every direction type (broad, deep, lateral, critical) listed as unexplored in the notes.",
+				"unanswered_questions: questions raised by leaf thoughts that have no further elaboration.",
+				"suggested_next_steps: concrete steps that close the most important gaps first.",
+			},
+			constraints: []string{
+				"Ground every item in the session stats, direction distribution, hot spots, and leaf thoughts provided in the notes.",
+				"Explicitly call out direction types not yet explored and leaf thoughts that were never elaborated.",
+				"Keep each item to one sentence.",
+			},
+			outputFormat: []string{
+				`Return only a JSON object of the form {"strengths":["..."],"gaps":["..."],"unanswered_questions":["..."],"suggested_next_steps":["..."]}.`,
+				"Do not wrap the JSON in markdown fences or add commentary.",
+			},
+		}
+	case "tagging":
+		return promptTemplate{
+			role:    "You are a librarian who files mind-mapping sessions so they are easy to find later.",
+			mission: "Suggest concise tags that describe the session rooted at '{{concept}}', using the direction titles and key thoughts listed in the notes.",
+			deliverables: []string{
+				"Between 3 and 7 tags covering the main subject, its key sub-topics, and the perspective taken.",
+			},
+			constraints: []string{
+				"Each tag is one to three words and at most 32 characters.",
+				"Use lowercase unless the tag is a proper noun or acronym; do not repeat tags or add the '#' prefix.",
+			},
+			outputFormat: []string{
+				`Return only a JSON array of strings, for example ["energy storage","batteries","policy"].`,
+				"Do not wrap the JSON in markdown fences or add commentary.",
+			},
+		}
+	case "title":
+		return promptTemplate{
+			role:    "You are an editor who names mind-mapping sessions so they stand out in a long list.",
+			mission: "Suggest a better title for the session whose current concept is '{{concept}}', using the key thoughts listed in the notes.",
+			deliverables: []string{
+				"One concise, engaging title that captures what the session explores.",
+			},
+			constraints: []string{
+				"Use at most 8 words and 60 characters, in the same language as the concept.",
+				"Stay faithful to the concept; do not invent topics the notes do not mention.",
+			},
+			outputFormat: []string{
+				"Return only the title as plain text without quotes, labels, or trailing punctuation.",
+			},
+		}
+	case "summary":
+		return promptTemplate{
+			role:    "You are an editor who recaps brainstorming results for a busy reader.",
+			mission: "Summarize the directions generated for '{{concept}}' that are listed in the notes.",
+			deliverables: []string{
+				"One short paragraph that states how many directions were generated, names each of them, and says which perspectives they emphasize.",
+			},
+			constraints: []string{
+				"Use at most three sentences, in the same language as the concept.",
+				"Mention only the directions listed in the notes; do not add new ideas.",
+			},
+			outputFormat: []string{
+				"Return only the paragraph as plain text without labels, lists, or markdown.",
+			},
+		}
+	case "completion":
+		return promptTemplate{
+			role:    "You are a writing partner who helps the user finish a thought they started in a mind map about '{{concept}}'.",
+			mission: "Continue the partially written thought given in the notes so it reads as one natural, complete idea.",
+			deliverables: []string{
+				"The continuation text only: the words that should follow the partial thought.",
+			},
+			constraints: []string{
+				"Keep the user's voice, language, and line of reasoning; do not repeat or rephrase the partial text.",
+				"Stay consistent with the path from the root listed in the notes.",
+				"Keep the complete thought under 400 characters, usually one or two sentences.",
+			},
+			outputFormat: []string{
+				"Return only the continuation as plain text without quotes, labels, or commentary.",
+			},
+		}
+	case "recommend_direction":
+		return promptTemplate{
+			role:    "You are a learning advisor who helps the user decide where to focus next.",
+			mission: "Compare the candidate directions listed in the notes for the topic '{{concept}}' and pick the single one the user should explore first.",
+			deliverables: []string{
+				"option: the number of the recommended candidate as listed in the notes.",
+				"rationale: two or three sentences tying the choice to the user's goals, preferences, and what the session has already explored.",
+			},
+			constraints: []string{
+				"Prefer directions that advance the explicit goals and avoid repeating explored directions.",
+				"Only choose among the listed options.",
+			},
+			outputFormat: []string{
+				`Return only a JSON object of the form {"option":1,"rationale":"..."}.`,
+				"Do not wrap the JSON in markdown fences or add commentary.",
+			},
+		}
+	case "structure":
+		return promptTemplate{
+			role:    "You are an information architect who turns loose brainstorming notes into a clear hierarchy.",
+			mission: "Reorganize the thoughts of the session about '{{concept}}' (listed in the notes as id=<id> | <content>) into a hierarchical tree.",
+			deliverables: []string{
+				"root: a short title for a new root thought that summarizes the whole session.",
+				"children: nested nodes; a node either references an existing thought with id or introduces a grouping heading with group, and may have children.",
+			},
+			constraints: []string{
+				"Reference every listed id exactly once and never invent ids.",
+				"Use group headings only when they gather at least two related thoughts.",
+				"Do not rewrite the content of existing thoughts.",
+			},
+			outputFormat: []string{
+				`Return only a JSON object of the form {"root":"...","children":[{"group":"...","children":[{"id":"..."}]},{"id":"...","children":[]}]}.`,
+				"Do not wrap the JSON in markdown fences or add commentary.",
+			},
+		}
 	case "directions":
 		return promptTemplate{
 			role:    "You are an experienced learning-path architect and knowledge-graph advisor who excels at breaking abstract themes into complementary exploration directions.",
@@ -465,7 +1183,8 @@ func (llm *LLMOrchestrator) promptTemplateFor(promptType string) promptTemplate
 			},
 			examples: []fewShotExample{
 				{
-					name: "Machine learning concept expansion",
+					name:     "Machine learning concept expansion",
+					keywords: []string{"machine learning", "algorithms", "statistics", "data science", "AI"},
 					input: `Concept: Machine Learning
 Background: strong statistics foundation
 History: completed "Statistical Learning Methods"
@@ -494,14 +1213,18 @@ Preference: project-driven learning`,
 	case "exploration":
 		return promptTemplate{
 			role:    "You are a seasoned research coach who guides users through deep exploration and validation.",
-			mission: "For the concept '{{concept}}' and a chosen direction, deliver an actionable plan covering research outline, core ideas, and validation steps.",
+			mission: "For the direction '{{concept}}', deliver the next step of an actionable plan covering a testable hypothesis, core ideas, and validation steps at the requested depth level.",
 			deliverables: []string{
-				"Return hypothesis, key_concepts, resources, validation_steps, and reflection_questions fields.",
-				"Each field should include at least 2-3 high-quality suggestions with rationale.",
+				"hypothesis: 2-4 sentences stating a concrete, testable claim that goes deeper than the previous level.",
+				"key_concepts: 2-5 short concept names (at most 32 characters each) the hypothesis relies on.",
+				"validation_steps: 2-3 concrete steps to confirm or refute the hypothesis.",
+				"key_insight: one sentence capturing the most important takeaway.",
+				"next_step: one actionable follow-up the user could take to go deeper.",
 			},
 			constraints: []string{
 				"Cite the type or credibility of any referenced resources; note when proof is lacking.",
 				"Keep guidance concrete and actionable, avoiding vague descriptions.",
+				"Avoid restating the direction title; add new information.",
 			},
 			reasoning: []string{
 				"Assess the user's current progress and gaps.",
@@ -510,7 +1233,10 @@ Preference: project-driven learning`,
 			styleNotes: []string{
 				"Use precise language that highlights action priorities.",
 			},
-			closing: "Finish with a 'checkpoints' list to help the user measure interim progress.",
+			outputFormat: []string{
+				`Return only a JSON object of the form {"hypothesis":"...","key_concepts":["..."],"validation_steps":["..."],"key_insight":"...","next_step":"..."}.`,
+				"Do not wrap the JSON in markdown fences or add commentary.",
+			},
 		}
 	default:
 		return promptTemplate{
@@ -527,43 +1253,52 @@ Preference: project-driven learning`,
 	}
 }
 
-func extractContextSegments(entries []string) promptContextSegments {
+func segmentContext(entries []models.ContextEntry) promptContextSegments {
 	segments := promptContextSegments{}
 
-	for _, raw := range entries {
-		entry := strings.TrimSpace(raw)
-		if entry == "" {
+	for _, entry := range entries {
+		value := strings.TrimSpace(entry.Value)
+		if value == "" {
 			continue
 		}
 
-		key := ""
-		value := entry
-		if idx := strings.Index(entry, ":"); idx >= 0 {
-			key = strings.ToLower(strings.TrimSpace(entry[:idx]))
-			value = strings.TrimSpace(entry[idx+1:])
-		}
-
-		switch key {
-		case "background", "context", "domain":
+		switch entry.Kind {
+		case models.ContextBackground:
 			segments.background = append(segments.background, value)
-		case "history", "path", "trajectory":
+		case models.ContextHistory:
 			segments.history = append(segments.history, value)
-		case "preference", "preferences", "style", "tone":
+		case models.ContextPreference:
 			segments.preferences = append(segments.preferences, value)
-		case "goal", "goals", "objective", "intent":
+		case models.ContextGoal:
 			segments.goals = append(segments.goals, value)
 		default:
-			if key != "" && value != "" {
-				segments.additional = append(segments.additional, fmt.Sprintf("%s: %s", key, value))
-			} else {
-				segments.additional = append(segments.additional, value)
-			}
+			segments.additional = append(segments.additional, value)
 		}
 	}
 
 	return segments
 }
 
+func normalizeContextEntries(entries []models.ContextEntry) []models.ContextEntry {
+	seen := map[models.ContextEntry]struct{}{}
+	result := make([]models.ContextEntry, 0, len(entries))
+	for _, entry := range entries {
+		entry.Value = strings.TrimSpace(entry.Value)
+		if entry.Value == "" {
+			continue
+		}
+		if entry.Kind == "" {
+			entry.Kind = models.ContextNote
+		}
+		if _, ok := seen[entry]; ok {
+			continue
+		}
+		seen[entry] = struct{}{}
+		result = append(result, entry)
+	}
+	return result
+}
+
 func renderTemplate(input string, data map[string]string) string {
 	result := input
 	for key, value := range data {
@@ -620,37 +1355,20 @@ func uniqueStrings(values []string) []string {
 }
 
 func (llm *LLMOrchestrator) parseDirectionsFromContent(content string) ([]models.Direction, error) {
-	trimmed := strings.TrimSpace(content)
-	if trimmed == "" {
-		return nil, errors.New("llm response empty")
-	}
-
-	start := strings.Index(trimmed, "[")
-	end := strings.LastIndex(trimmed, "]")
-	if start >= 0 && end > start {
-		trimmed = trimmed[start : end+1]
-	}
-
-	var raw []struct {
-		Type                string   `json:"type"`
-		Title               string   `json:"title"`
-		Summary             string   `json:"summary"`
-		Description         string   `json:"description"`
-		DirectionRationale  string   `json:"direction_rationale"`
-		KeyQuestions        []string `json:"key_questions"`
-		RecommendedActions  []string `json:"recommended_actions"`
-		Keywords            []string `json:"keywords"`
-		Relevance           float64  `json:"relevance"`
-		Confidence          float64  `json:"confidence"`
-		Importance          float64  `json:"importance"`
-		SuggestedRelevance  float64  `json:"suggested_relevance"`
-		SuggestedConfidence float64  `json:"suggested_confidence"`
-	}
-
-	if err := json.Unmarshal([]byte(trimmed), &raw); err != nil {
+	raw, diagnostic, err := extractRawDirections(content)
+	if err != nil {
 		return nil, fmt.Errorf("parse llm directions: %w", err)
 	}
+	utils.Debug("extracted llm directions",
+		utils.KV("strategy", diagnostic.strategy),
+		utils.KV("fenced", diagnostic.fenced),
+		utils.KV("count", len(raw)),
+	)
+	return convertRawDirections(raw)
+}
 
+// convertRawDirections 将原始方向规范化为 models.Direction，跳过缺少标题或描述的条目。
+func convertRawDirections(raw []rawDirection) ([]models.Direction, error) {
 	results := make([]models.Direction, 0, len(raw))
 	for _, item := range raw {
 		title := strings.TrimSpace(item.Title)
@@ -662,20 +1380,7 @@ func (llm *LLMOrchestrator) parseDirectionsFromContent(content string) ([]models
 			continue
 		}
 
-		typeStr := strings.ToLower(strings.TrimSpace(item.Type))
-		var dirType models.DirectionType
-		switch typeStr {
-		case string(models.Broad), "overview", "expansion":
-			dirType = models.Broad
-		case string(models.Deep), "deepen", "analysis":
-			dirType = models.Deep
-		case string(models.Lateral), "adjacent":
-			dirType = models.Lateral
-		case string(models.Critical), "challenge":
-			dirType = models.Critical
-		default:
-			dirType = models.Broad
-		}
+		dirType := normalizeDirectionType(item.Type)
 
 		keywords := uniqueStrings(append(append([]string{}, item.Keywords...), item.KeyQuestions...))
 		if len(keywords) == 0 && item.DirectionRationale != "" {
@@ -718,10 +1423,29 @@ func (llm *LLMOrchestrator) parseDirectionsFromContent(content string) ([]models
 	return results, nil
 }
 
-func (llm *LLMOrchestrator) generateFallbackDirections(concept string, context []string) []models.Direction {
+// normalizeDirectionType 将 LLM 返回的方向类型映射到已知类型，未知值按 broad 处理。
+func normalizeDirectionType(value string) models.DirectionType {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case string(models.Deep), "deepen", "analysis":
+		return models.Deep
+	case string(models.Lateral), "adjacent":
+		return models.Lateral
+	case string(models.Critical), "challenge":
+		return models.Critical
+	default:
+		return models.Broad
+	}
+}
+
+// generateFallbackDirections 在无法使用 LLM 时生成模板方向；language 为中文时使用中文标题与描述，其余语言使用英文。
+func (llm *LLMOrchestrator) generateFallbackDirections(concept string, context []string, language string) []models.Direction {
+	chinese := isChinese(language)
 	concept = strings.TrimSpace(concept)
 	if concept == "" {
 		concept = "the topic"
+		if chinese {
+			concept = "该主题"
+		}
 	}
 
 	keyTopics := uniqueStrings(context)
@@ -762,6 +1486,20 @@ func (llm *LLMOrchestrator) generateFallbackDirections(concept string, context [
 			keys:    append([]string{"risks", "open questions"}, keyTopics...),
 		},
 	}
+	if chinese {
+		plans[0].title = fmt.Sprintf("%s全景梳理", concept)
+		plans[0].desc = fmt.Sprintf("梳理构成%s的主要主题、参与者与发展趋势。", concept)
+		plans[0].keys = append([]string{"概览", concept}, keyTopics...)
+		plans[1].title = fmt.Sprintf("深入%s的核心机制", concept)
+		plans[1].desc = fmt.Sprintf("分析支撑%s的基本原理、框架与边界情况。", concept)
+		plans[1].keys = append([]string{"分析", "核心原理"}, keyTopics...)
+		plans[2].title = fmt.Sprintf("%s的跨领域启发", concept)
+		plans[2].desc = fmt.Sprintf("借鉴相邻领域的类比，重新审视关于%s的假设。", concept)
+		plans[2].keys = append([]string{"类比", "跨领域"}, keyTopics...)
+		plans[3].title = fmt.Sprintf("检验%s的假设", concept)
+		plans[3].desc = fmt.Sprintf("找出风险、局限与未解决的问题，使%s的计划更稳健。", concept)
+		plans[3].keys = append([]string{"风险", "待解问题"}, keyTopics...)
+	}
 
 	results := make([]models.Direction, 0, len(plans))
 	for i, plan := range plans {
@@ -788,3 +1526,8 @@ func truncate(input string, max int) string {
 	runes := []rune(input)
 	return string(runes[:max])
 }
+
+// float64Ptr 返回 value 的指针，用于填写 LLMRequest.Temperature。
+func float64Ptr(value float64) *float64 {
+	return &value
+}
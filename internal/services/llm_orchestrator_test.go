@@ -0,0 +1,248 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"WideMindsMCP/internal/models"
+)
+
+func newChatCompletionServer(t *testing.T, content string, usage TokenUsage) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":    "chatcmpl-test",
+			"model": "test-model",
+			"choices": []map[string]any{
+				{"index": 0, "message": map[string]string{"role": "assistant", "content": content}},
+			},
+			"usage": map[string]int{
+				"prompt_tokens":     usage.PromptTokens,
+				"completion_tokens": usage.CompletionTokens,
+				"total_tokens":      usage.TotalTokens,
+			},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGenerateThoughtDirectionsRecordsProvenance(t *testing.T) {
+	content := `[{"type":"deep","title":"Core mechanics","summary":"Study the fundamentals","key_questions":["why?"]}]`
+	usage := TokenUsage{PromptTokens: 120, CompletionTokens: 45, TotalTokens: 165}
+	server := newChatCompletionServer(t, content, usage)
+
+	orchestrator := NewLLMOrchestrator("key", server.URL, "configured-model")
+	directions, err := orchestrator.GenerateThoughtDirections("Batteries", nil)
+	if err != nil {
+		t.Fatalf("GenerateThoughtDirections returned error: %v", err)
+	}
+	if len(directions) != 1 {
+		t.Fatalf("expected 1 direction, got %d", len(directions))
+	}
+
+	provenance := directions[0].Provenance
+	if provenance == nil {
+		t.Fatalf("expected provenance to be recorded")
+	}
+	if provenance.Source != models.SourceLLM {
+		t.Fatalf("expected llm source, got %q", provenance.Source)
+	}
+	if provenance.Model != "test-model" {
+		t.Fatalf("expected model from response, got %q", provenance.Model)
+	}
+	if provenance.TokenUsage != usage {
+		t.Fatalf("expected usage %+v, got %+v", usage, provenance.TokenUsage)
+	}
+	if provenance.PromptHash == "" {
+		t.Fatalf("expected prompt hash to be set")
+	}
+}
+
+func TestExploreDirectionUsesLLMThoughtBody(t *testing.T) {
+	content := "```json\n" + `{"content":"Solid-state cells trade energy density for manufacturing complexity.","key_insight":"Manufacturing is the bottleneck.","next_step":"Compare pilot line yields."}` + "\n```"
+	usage := TokenUsage{PromptTokens: 80, CompletionTokens: 20, TotalTokens: 100}
+	server := newChatCompletionServer(t, content, usage)
+
+	orchestrator := NewLLMOrchestrator("key", server.URL, "")
+	direction := models.Direction{Type: models.Deep, Title: "Solid-state batteries", Description: "Next-generation chemistry"}
+
+	thoughts, err := orchestrator.ExploreDirection(direction, 1, []models.ContextEntry{models.NewContextEntry(models.ContextBackground, "energy storage")})
+	if err != nil {
+		t.Fatalf("ExploreDirection returned error: %v", err)
+	}
+	if len(thoughts) != 1 {
+		t.Fatalf("expected 1 thought, got %d", len(thoughts))
+	}
+
+	thought := thoughts[0]
+	if thought.Content != "Solid-state cells trade energy density for manufacturing complexity." {
+		t.Fatalf("unexpected content %q", thought.Content)
+	}
+	if thought.Annotations["key_insight"] != "Manufacturing is the bottleneck." {
+		t.Fatalf("unexpected key insight %q", thought.Annotations["key_insight"])
+	}
+	if thought.Annotations["next_step"] != "Compare pilot line yields." {
+		t.Fatalf("unexpected next step %q", thought.Annotations["next_step"])
+	}
+	if thought.Provenance == nil || thought.Provenance.TokenUsage != usage {
+		t.Fatalf("expected usage to flow into provenance, got %+v", thought.Provenance)
+	}
+}
+
+func TestExploreDirectionUsesLLMExploration(t *testing.T) {
+	fixture, err := os.ReadFile(filepath.Join("testdata", "exploration.json"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	usage := TokenUsage{PromptTokens: 80, CompletionTokens: 20, TotalTokens: 100}
+	server := newChatCompletionServer(t, "```json\n"+string(fixture)+"\n```", usage)
+
+	orchestrator := NewLLMOrchestrator("key", server.URL, "")
+	direction := models.Direction{Type: models.Deep, Title: "Solid-state batteries", Description: "Next-generation chemistry"}
+
+	thoughts, err := orchestrator.ExploreDirection(direction, 2, []models.ContextEntry{models.NewContextEntry(models.ContextBackground, "energy storage")})
+	if err != nil {
+		t.Fatalf("ExploreDirection returned error: %v", err)
+	}
+	if len(thoughts) != 2 {
+		t.Fatalf("expected 2 thoughts, got %d", len(thoughts))
+	}
+
+	for i, thought := range thoughts {
+		if thought.Depth != i+1 {
+			t.Fatalf("expected depth %d, got %d", i+1, thought.Depth)
+		}
+		if !strings.HasPrefix(thought.Content, "Solid-state cells trade energy density") {
+			t.Fatalf("unexpected content %q", thought.Content)
+		}
+		if strings.Join(thought.Tags, ",") != "solid electrolyte,pilot line yield" {
+			t.Fatalf("unexpected tags %v", thought.Tags)
+		}
+		if thought.Annotations["validation_steps"] != "Compare published pilot line yields.; Model cost per kWh at three yield levels." {
+			t.Fatalf("unexpected validation steps %q", thought.Annotations["validation_steps"])
+		}
+		if thought.Provenance == nil || thought.Provenance.Model != "test-model" || thought.Provenance.TokenUsage != usage {
+			t.Fatalf("expected usage to flow into provenance, got %+v", thought.Provenance)
+		}
+	}
+}
+
+func TestExploreDirectionFallsBackOnUnparseableResponse(t *testing.T) {
+	server := newChatCompletionServer(t, "I cannot help with that.", TokenUsage{TotalTokens: 5})
+
+	orchestrator := NewLLMOrchestrator("key", server.URL, "")
+	direction := models.Direction{Type: models.Deep, Title: "Solid-state batteries"}
+
+	thoughts, err := orchestrator.ExploreDirection(direction, 1, nil)
+	if err != nil {
+		t.Fatalf("ExploreDirection returned error: %v", err)
+	}
+	if len(thoughts) != 1 || thoughts[0].Content != "Solid-state batteries • level 1" {
+		t.Fatalf("expected synthetic fallback thought, got %+v", thoughts)
+	}
+	if thoughts[0].Provenance == nil || thoughts[0].Provenance.Model != localFallbackModel {
+		t.Fatalf("expected fallback provenance, got %+v", thoughts[0].Provenance)
+	}
+}
+
+func TestSuggestNextActionsParsesLLMResponse(t *testing.T) {
+	content := `[{"type":"deepen","rationale":"Recycling has no follow-up.","suggested_direction":{"type":"deep","title":"Battery recycling economics","description":"Compare hydrometallurgy and direct recycling costs.","keywords":["recycling"]}},{"type":"challenge","rationale":"Assumptions are untested."}]`
+	server := newChatCompletionServer(t, content, TokenUsage{TotalTokens: 42})
+
+	session := models.NewSession("user", "Batteries")
+	session.RootThought.AddChild(models.NewThought("Recycling", session.ID, models.Direction{Type: models.Broad, Title: "Recycling"}))
+
+	orchestrator := NewLLMOrchestrator("key", server.URL, "")
+	actions, err := orchestrator.SuggestNextActions(session, 5)
+	if err != nil {
+		t.Fatalf("SuggestNextActions returned error: %v", err)
+	}
+	if len(actions) != 2 {
+		t.Fatalf("expected 2 actions, got %d", len(actions))
+	}
+
+	direction := actions[0].SuggestedDirection
+	if direction == nil || direction.Type != models.Deep || direction.Title != "Battery recycling economics" {
+		t.Fatalf("unexpected suggested direction: %+v", direction)
+	}
+	if direction.Provenance == nil || direction.Provenance.Model != "test-model" {
+		t.Fatalf("expected provenance from LLM response, got %+v", direction.Provenance)
+	}
+	if actions[1].SuggestedDirection != nil {
+		t.Fatalf("expected action without direction to stay nil")
+	}
+}
+
+func TestSuggestNextActionsFallsBackToColdSpots(t *testing.T) {
+	session := models.NewSession("user", "Batteries")
+	explored := models.NewThought("Chemistry", session.ID, models.Direction{Type: models.Broad, Title: "Chemistry"})
+	session.RootThought.AddChild(explored)
+	explored.AddChild(models.NewThought("Solid-state", session.ID, models.Direction{Type: models.Deep, Title: "Solid-state"}))
+	explored.AddChild(models.NewThought("Sodium-ion", session.ID, models.Direction{Type: models.Deep, Title: "Sodium-ion"}))
+
+	orchestrator := NewLLMOrchestrator("", "", "")
+	actions, err := orchestrator.SuggestNextActions(session, 2)
+	if err != nil {
+		t.Fatalf("SuggestNextActions returned error: %v", err)
+	}
+	if len(actions) != 2 {
+		t.Fatalf("expected 2 actions, got %d", len(actions))
+	}
+	if actions[0].Type != "broaden" {
+		t.Fatalf("expected broaden action for a single-branch root, got %q", actions[0].Type)
+	}
+	if actions[1].Type != "deepen" || actions[1].SuggestedDirection == nil || actions[1].SuggestedDirection.Title != "Solid-state" {
+		t.Fatalf("expected deepen action on the oldest leaf, got %+v", actions[1])
+	}
+}
+
+func TestNewLLMOrchestratorWithOptions(t *testing.T) {
+	temperature := 0.2
+	llm := NewLLMOrchestratorWithOptions(LLMOptions{
+		APIKey:             "key",
+		BaseURL:            "https://example.com/",
+		Timeout:            90 * time.Second,
+		MaxTokens:          4096,
+		DefaultTemperature: &temperature,
+		MaxRetries:         0,
+	})
+	if llm.model != DefaultLLMModel || llm.baseURL != "https://example.com" {
+		t.Fatalf("unexpected model or base URL: %q %q", llm.model, llm.baseURL)
+	}
+	if llm.timeout != 90*time.Second || llm.httpClient.Timeout != 90*time.Second {
+		t.Fatalf("expected configured timeout, got %v / %v", llm.timeout, llm.httpClient.Timeout)
+	}
+	if llm.maxAttempts != 1 {
+		t.Fatalf("expected zero retries to mean a single attempt, got %d", llm.maxAttempts)
+	}
+
+	call, _, err := llm.prepareCall(&LLMRequest{Prompt: "hello", MaxTokens: 10000})
+	if err != nil {
+		t.Fatalf("prepareCall failed: %v", err)
+	}
+	if call.temperature != 0.2 || call.maxTokens != 4096 {
+		t.Fatalf("expected default temperature and max tokens to apply, got %v / %d", call.temperature, call.maxTokens)
+	}
+
+	temperature = 0
+	greedy := NewLLMOrchestratorWithOptions(LLMOptions{DefaultTemperature: &temperature})
+	if call, _, err := greedy.prepareCall(&LLMRequest{Prompt: "hello"}); err != nil || call.temperature != 0 {
+		t.Fatalf("expected a zero default temperature to be kept, got %+v (%v)", call, err)
+	}
+	if call, _, err := llm.WithTemperature(0.9).prepareCall(&LLMRequest{Prompt: "hello", Temperature: float64Ptr(0)}); err != nil || call.temperature != 0 {
+		t.Fatalf("expected an explicit zero request temperature to be kept, got %+v (%v)", call, err)
+	}
+
+	defaults := NewLLMOrchestrator("", "", "")
+	if defaults.timeout != DefaultLLMTimeout || defaults.maxTokens != DefaultLLMMaxTokens || defaults.maxAttempts != DefaultLLMMaxAttempts || defaults.defaultTemperature != DefaultLLMTemperature {
+		t.Fatalf("expected legacy constructor to keep defaults")
+	}
+}
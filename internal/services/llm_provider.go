@@ -0,0 +1,183 @@
+//LLM Provider Interface(LLM 提供方接口)
+
+package services
+
+import (
+	"context"
+
+	"WideMindsMCP/internal/models"
+)
+
+// 接口
+// DirectionGenerator 是 ThoughtExpander 与就绪检查所依赖的 LLM 能力，LLMOrchestrator 为默认实现。
+type DirectionGenerator interface {
+	GenerateThoughtDirections(concept string, context []models.ContextEntry) ([]models.Direction, error)
+	// ExploreDirection 按层级顺序返回 depth 个思维，后一层为前一层的子节点。
+	ExploreDirection(direction models.Direction, depth int, context []models.ContextEntry) ([]*models.Thought, error)
+	CallLLM(req *LLMRequest) (*LLMResponse, error)
+	HealthCheck(ctx context.Context) error
+}
+
+// structureProposer、nextActionSuggester、sessionReflector、sessionTagger、directionRecommender、thoughtCompleter、sessionTitler 与 expansionSummarizer 是可选能力；未实现时使用本地启发式结果。
+type structureProposer interface {
+	ProposeStructure(session *models.Session) (*models.StructureSpec, error)
+}
+
+type nextActionSuggester interface {
+	SuggestNextActions(session *models.Session, maxSuggestions int) ([]NextAction, error)
+}
+
+type sessionReflector interface {
+	ReflectOnSession(session *models.Session) (*ReflectionReport, error)
+}
+
+type sessionTagger interface {
+	SuggestSessionTags(session *models.Session) ([]string, error)
+}
+
+type directionRecommender interface {
+	RecommendDirection(directions []models.Direction, profile *models.UserProfile, session *models.Session) (*models.Direction, string, error)
+}
+
+type thoughtCompleter interface {
+	CompleteThought(path []string, partial string) (string, error)
+}
+
+type sessionTitler interface {
+	SuggestSessionTitle(concept string, thoughts []string) (string, error)
+}
+
+type expansionSummarizer interface {
+	SummarizeExpansion(concept string, directions []models.Direction) (string, error)
+}
+
+var _ DirectionGenerator = (*LLMOrchestrator)(nil)
+
+// 函数
+// generatorForUser 将调用计入 userID 的预算；非默认实现原样返回。
+func generatorForUser(generator DirectionGenerator, userID string) DirectionGenerator {
+	if llm, ok := generator.(*LLMOrchestrator); ok {
+		return llm.ForUser(userID)
+	}
+	return generator
+}
+
+// generatorWithStream 为默认实现开启流式输出；其他实现不支持增量输出，按普通调用执行。
+func generatorWithStream(generator DirectionGenerator, ctx context.Context, onDelta func(string)) DirectionGenerator {
+	if llm, ok := generator.(*LLMOrchestrator); ok {
+		return llm.WithStream(ctx, onDelta)
+	}
+	return generator
+}
+
+// generatorWithContext 使默认实现的上游请求随 ctx 取消；其他实现原样返回。
+func generatorWithContext(generator DirectionGenerator, ctx context.Context) DirectionGenerator {
+	if llm, ok := generator.(*LLMOrchestrator); ok {
+		return llm.WithContext(ctx)
+	}
+	return generator
+}
+
+// generatorWithoutCache 使默认实现绕过响应缓存；其他实现原样返回。
+func generatorWithoutCache(generator DirectionGenerator) DirectionGenerator {
+	if llm, ok := generator.(*LLMOrchestrator); ok {
+		return llm.WithoutCache()
+	}
+	return generator
+}
+
+// generatorWithTemperature 使默认实现未指定温度的调用使用 temperature；其他实现原样返回。
+func generatorWithTemperature(generator DirectionGenerator, temperature float64) DirectionGenerator {
+	if llm, ok := generator.(*LLMOrchestrator); ok {
+		return llm.WithTemperature(temperature)
+	}
+	return generator
+}
+
+// generatorWithLanguage 使默认实现的生成内容固定使用 language；其他实现原样返回。
+func generatorWithLanguage(generator DirectionGenerator, language string) DirectionGenerator {
+	if llm, ok := generator.(*LLMOrchestrator); ok {
+		return llm.WithResponseLanguage(language)
+	}
+	return generator
+}
+
+// generatorWithExampleLimit 使默认实现的提示词最多包含 limit 个示例；其他实现原样返回。
+func generatorWithExampleLimit(generator DirectionGenerator, limit int) DirectionGenerator {
+	if llm, ok := generator.(*LLMOrchestrator); ok {
+		return llm.WithExampleLimit(limit)
+	}
+	return generator
+}
+
+// generatorWithPromptOverrides 使默认实现使用请求提供的系统消息与附加指令；其他实现原样返回。
+func generatorWithPromptOverrides(generator DirectionGenerator, systemPrompt string, extra []string) DirectionGenerator {
+	if llm, ok := generator.(*LLMOrchestrator); ok {
+		return llm.WithPromptOverrides(systemPrompt, extra)
+	}
+	return generator
+}
+
+// generatorWithUsage 使默认实现在每次远程调用后回调 recorder；其他实现原样返回。
+func generatorWithUsage(generator DirectionGenerator, recorder UsageRecorder) DirectionGenerator {
+	if llm, ok := generator.(*LLMOrchestrator); ok {
+		return llm.WithUsageRecorder(recorder)
+	}
+	return generator
+}
+
+func proposeStructure(generator DirectionGenerator, session *models.Session) (*models.StructureSpec, error) {
+	if proposer, ok := generator.(structureProposer); ok {
+		return proposer.ProposeStructure(session)
+	}
+	return NewLLMOrchestrator("", "", "").ProposeStructure(session)
+}
+
+func suggestNextActions(generator DirectionGenerator, session *models.Session, maxSuggestions int) ([]NextAction, error) {
+	if suggester, ok := generator.(nextActionSuggester); ok {
+		return suggester.SuggestNextActions(session, maxSuggestions)
+	}
+	return NewLLMOrchestrator("", "", "").SuggestNextActions(session, maxSuggestions)
+}
+
+func recommendDirection(generator DirectionGenerator, directions []models.Direction, profile *models.UserProfile, session *models.Session) (*models.Direction, string, error) {
+	if recommender, ok := generator.(directionRecommender); ok {
+		return recommender.RecommendDirection(directions, profile, session)
+	}
+	return NewLLMOrchestrator("", "", "").RecommendDirection(directions, profile, session)
+}
+
+func reflectOnSession(generator DirectionGenerator, session *models.Session) (*ReflectionReport, error) {
+	if reflector, ok := generator.(sessionReflector); ok {
+		return reflector.ReflectOnSession(session)
+	}
+	return NewLLMOrchestrator("", "", "").ReflectOnSession(session)
+}
+
+func suggestSessionTags(generator DirectionGenerator, session *models.Session) ([]string, error) {
+	if tagger, ok := generator.(sessionTagger); ok {
+		return tagger.SuggestSessionTags(session)
+	}
+	return NewLLMOrchestrator("", "", "").SuggestSessionTags(session)
+}
+
+func completeThought(generator DirectionGenerator, path []string, partial string) (string, error) {
+	if completer, ok := generator.(thoughtCompleter); ok {
+		return completer.CompleteThought(path, partial)
+	}
+	return NewLLMOrchestrator("", "", "").CompleteThought(path, partial)
+}
+
+func suggestSessionTitle(generator DirectionGenerator, concept string, thoughts []string) (string, error) {
+	if titler, ok := generator.(sessionTitler); ok {
+		return titler.SuggestSessionTitle(concept, thoughts)
+	}
+	return NewLLMOrchestrator("", "", "").SuggestSessionTitle(concept, thoughts)
+}
+
+func summarizeExpansion(generator DirectionGenerator, concept string, directions []models.Direction) (string, error) {
+	if summarizer, ok := generator.(expansionSummarizer); ok {
+		return summarizer.SummarizeExpansion(concept, directions)
+	}
+	return NewLLMOrchestrator("", "", "").SummarizeExpansion(concept, directions)
+}
@@ -0,0 +1,42 @@
+//LLM Client Rate Limit(LLM 客户端限流)
+
+package services
+
+import (
+	"context"
+	"fmt"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/utils"
+)
+
+// 方法
+// SetRateLimit 限制发往 LLM 供应商的请求速率（每分钟 rpm 个，突发 burst 个）；rpm 非正时关闭限流。
+// 编排器的副本（ForUser、WithContext 等）共享同一个令牌桶。
+func (llm *LLMOrchestrator) SetRateLimit(rpm, burst int) {
+	if llm == nil {
+		return
+	}
+	llm.rateLimiter = utils.NewTokenBucket(rpm, burst)
+}
+
+// RateLimitStatus 返回客户端限流的当前饱和度。
+func (llm *LLMOrchestrator) RateLimitStatus() utils.TokenBucketStatus {
+	if llm == nil {
+		return utils.TokenBucketStatus{}
+	}
+	return llm.rateLimiter.Status()
+}
+
+// waitForRateLimit 在发送请求前等待令牌，等待时间受 ctx 与单次调用超时约束。
+func (llm *LLMOrchestrator) waitForRateLimit(ctx context.Context) error {
+	if llm.rateLimiter == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, llm.timeout)
+	defer cancel()
+	if err := llm.rateLimiter.Wait(ctx); err != nil {
+		return fmt.Errorf("%w: %w", appErrors.ErrLLMRateLimited, err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	appErrors "WideMindsMCP/internal/errors"
+)
+
+func TestCallLLMRateLimitRejectsCallsBeyondDeadline(t *testing.T) {
+	server, calls := newScriptedBackend(t, http.StatusOK, []string{"ok"}, nil)
+	llm := NewLLMOrchestrator("key", server.URL, "model")
+	llm.SetRateLimit(2, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	bound := llm.WithContext(ctx)
+	for i := 0; i < 2; i++ {
+		if _, err := bound.CallLLM(&LLMRequest{Prompt: "hello", NoCache: true}); err != nil {
+			t.Fatalf("call %d within burst failed: %v", i+1, err)
+		}
+	}
+
+	started := time.Now()
+	_, err := bound.CallLLM(&LLMRequest{Prompt: "hello", NoCache: true})
+	if !errors.Is(err, appErrors.ErrLLMRateLimited) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected third call to be rate limited by its deadline, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 100*time.Millisecond {
+		t.Fatalf("expected an unreachable slot to fail fast, waited %v", elapsed)
+	}
+	if got := atomic.LoadInt32(calls); got != 2 {
+		t.Fatalf("expected only two upstream requests, got %d", got)
+	}
+	if stats := llm.Stats().RateLimit; !stats.Enabled || stats.RPM != 2 || stats.Burst != 2 || stats.Saturation < 0.99 {
+		t.Fatalf("expected an exhausted bucket in stats, got %+v", stats)
+	}
+}
+
+func TestCallLLMRateLimitDelaysAndReleasesOnCancel(t *testing.T) {
+	server, _ := newScriptedBackend(t, http.StatusOK, []string{"ok"}, nil)
+	llm := NewLLMOrchestrator("key", server.URL, "model")
+	llm.SetRateLimit(1200, 1)
+
+	if _, err := llm.CallLLM(&LLMRequest{Prompt: "hello", NoCache: true}); err != nil {
+		t.Fatalf("first call failed: %v", err)
+	}
+	started := time.Now()
+	if _, err := llm.CallLLM(&LLMRequest{Prompt: "hello", NoCache: true}); err != nil {
+		t.Fatalf("delayed call failed: %v", err)
+	}
+	if elapsed := time.Since(started); elapsed < 30*time.Millisecond {
+		t.Fatalf("expected the second call to wait for a token, took %v", elapsed)
+	}
+	if stats := llm.Stats().RateLimit; stats.ThrottledTotal != 1 {
+		t.Fatalf("expected one throttled call, got %+v", stats)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := llm.WithContext(ctx).CallLLM(&LLMRequest{Prompt: "hello", NoCache: true}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation while waiting, got %v", err)
+	}
+	if stats := llm.Stats().RateLimit; stats.Waiting != 0 {
+		t.Fatalf("expected no waiters after cancellation, got %+v", stats)
+	}
+}
+
+func TestRateLimitDisabledByDefault(t *testing.T) {
+	llm := NewLLMOrchestrator("", "", "")
+	if stats := llm.Stats().RateLimit; stats.Enabled {
+		t.Fatalf("expected rate limit to be off by default, got %+v", stats)
+	}
+}
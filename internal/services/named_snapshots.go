@@ -0,0 +1,123 @@
+//Named Session Snapshots(命名会话快照)
+
+package services
+
+import (
+	"errors"
+	"time"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const MaxNamedSnapshots = 10
+
+// 方法
+// CreateSnapshot 以 name 保存会话当前状态的命名版本；同一会话最多保存 MaxNamedSnapshots 个，名称不可重复。
+func (sm *SessionManager) CreateSnapshot(sessionID, name string) (*models.SessionSnapshot, error) {
+	if err := utils.ValidateSnapshotName(name); err != nil {
+		return nil, err
+	}
+	store, err := sm.snapshotStore()
+	if err != nil {
+		return nil, err
+	}
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := store.ListSnapshots(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshot := range existing {
+		if snapshot.Name == name {
+			return nil, utils.ValidationError("snapshot name already exists")
+		}
+	}
+	if len(existing) >= MaxNamedSnapshots {
+		return nil, utils.ValidationError("too many snapshots")
+	}
+
+	snapshot := &models.SessionSnapshot{
+		Name:      name,
+		CreatedAt: time.Now().UTC(),
+		Data:      session.Clone(),
+	}
+	if err := store.SaveSnapshot(sessionID, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// ListSnapshots 按创建时间返回会话的命名快照。
+func (sm *SessionManager) ListSnapshots(sessionID string) ([]*models.SessionSnapshot, error) {
+	store, err := sm.snapshotStore()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := sm.GetSession(sessionID); err != nil {
+		return nil, err
+	}
+	return store.ListSnapshots(sessionID)
+}
+
+// RestoreSnapshot 将会话恢复为命名快照中的状态。恢复前的状态记入撤销历史，
+// 已发生的 LLM 用量不随快照回退。
+func (sm *SessionManager) RestoreSnapshot(sessionID, name string) (*models.Session, error) {
+	if err := utils.ValidateSnapshotName(name); err != nil {
+		return nil, err
+	}
+	store, err := sm.snapshotStore()
+	if err != nil {
+		return nil, err
+	}
+	current, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := store.GetSnapshot(sessionID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	restored := snapshot.Data.Clone()
+	if restored == nil {
+		return nil, errors.New("session snapshot could not be restored")
+	}
+	restored.ID = current.ID
+	restored.Usage = current.Usage
+
+	undo := newSessionSnapshot(current, "restore_snapshot")
+	if err := sm.UpdateSession(restored); err != nil {
+		return nil, err
+	}
+	sm.recordSnapshot(undo)
+	return restored, nil
+}
+
+// DeleteSnapshot 删除会话的命名快照。
+func (sm *SessionManager) DeleteSnapshot(sessionID, name string) error {
+	if err := utils.ValidateSnapshotName(name); err != nil {
+		return err
+	}
+	store, err := sm.snapshotStore()
+	if err != nil {
+		return err
+	}
+	if _, err := sm.GetSession(sessionID); err != nil {
+		return err
+	}
+	return store.DeleteSnapshot(sessionID, name)
+}
+
+func (sm *SessionManager) snapshotStore() (storage.SnapshotStore, error) {
+	store, ok := sm.store.(storage.SnapshotStore)
+	if !ok {
+		return nil, errors.New("session store does not support snapshots")
+	}
+	return store, nil
+}
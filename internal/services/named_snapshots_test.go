@@ -0,0 +1,83 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+)
+
+func TestNamedSnapshotCreateRestoreAndUndo(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	session, err := manager.CreateSession("user", "Batteries")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	if _, err := manager.CreateSnapshot(session.ID, "before-reorg"); err != nil {
+		t.Fatalf("CreateSnapshot failed: %v", err)
+	}
+	thought := models.NewThought("Chemistry", session.ID, models.Direction{Type: models.Deep, Title: "Chemistry"})
+	if err := manager.AddThoughtToSession(session.ID, thought); err != nil {
+		t.Fatalf("AddThoughtToSession failed: %v", err)
+	}
+
+	restored, err := manager.RestoreSnapshot(session.ID, "before-reorg")
+	if err != nil {
+		t.Fatalf("RestoreSnapshot failed: %v", err)
+	}
+	if restored.ID != session.ID {
+		t.Fatalf("expected restored session to keep id %s, got %s", session.ID, restored.ID)
+	}
+	if _, ok := restored.GetThoughtTree()[thought.ID]; ok {
+		t.Fatalf("expected thought added after the snapshot to be gone")
+	}
+
+	undone, err := manager.Undo(session.ID)
+	if err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if _, ok := undone.GetThoughtTree()[thought.ID]; !ok {
+		t.Fatalf("expected undo to bring back the state before the restore")
+	}
+}
+
+func TestNamedSnapshotValidationAndLimits(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	session, err := manager.CreateSession("user", "Batteries")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	for _, name := range []string{"", "-leading", "has space", "../escape"} {
+		if _, err := manager.CreateSnapshot(session.ID, name); !errors.Is(err, appErrors.ErrInvalidRequest) {
+			t.Fatalf("expected invalid request for name %q, got %v", name, err)
+		}
+	}
+
+	for i := 0; i < MaxNamedSnapshots; i++ {
+		if _, err := manager.CreateSnapshot(session.ID, fmt.Sprintf("v%d", i)); err != nil {
+			t.Fatalf("CreateSnapshot %d failed: %v", i, err)
+		}
+	}
+	if _, err := manager.CreateSnapshot(session.ID, "v0"); !errors.Is(err, appErrors.ErrInvalidRequest) {
+		t.Fatalf("expected duplicate name to be rejected, got %v", err)
+	}
+	if _, err := manager.CreateSnapshot(session.ID, "overflow"); !errors.Is(err, appErrors.ErrInvalidRequest) {
+		t.Fatalf("expected snapshot limit to be enforced, got %v", err)
+	}
+
+	if err := manager.DeleteSnapshot(session.ID, "v0"); err != nil {
+		t.Fatalf("DeleteSnapshot failed: %v", err)
+	}
+	if _, err := manager.RestoreSnapshot(session.ID, "v0"); !errors.Is(err, appErrors.ErrSnapshotNotFound) {
+		t.Fatalf("expected snapshot not found after delete, got %v", err)
+	}
+	snapshots, err := manager.ListSnapshots(session.ID)
+	if err != nil || len(snapshots) != MaxNamedSnapshots-1 || snapshots[0].Name != "v1" {
+		t.Fatalf("expected remaining snapshots in creation order, got %d (%v)", len(snapshots), err)
+	}
+}
@@ -0,0 +1,230 @@
+//Next Action Suggestions(下一步行动建议)
+
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const (
+	DefaultNextActions = 3
+	MaxNextActions     = 10
+	maxColdSpots       = 5
+)
+
+// 结构体
+type NextAction struct {
+	Type               string            `json:"type"`
+	Rationale          string            `json:"rationale"`
+	SuggestedDirection *models.Direction `json:"suggested_direction,omitempty"`
+}
+
+// 方法
+// SuggestNextActions 根据会话当前状态请求 LLM 给出下一步建议，失败时回退到基于冷点的启发式建议。
+func (llm *LLMOrchestrator) SuggestNextActions(session *models.Session, maxSuggestions int) ([]NextAction, error) {
+	if session == nil || session.RootThought == nil {
+		return nil, errors.New("session has no thoughts")
+	}
+	if maxSuggestions <= 0 {
+		maxSuggestions = DefaultNextActions
+	}
+	if maxSuggestions > MaxNextActions {
+		maxSuggestions = MaxNextActions
+	}
+
+	coldSpots := findColdSpots(session, maxColdSpots)
+	prompt := llm.BuildPrompt(session.RootThought.Content, buildNextActionsContext(session, coldSpots, maxSuggestions), "next_actions")
+
+	if llm.hasRemoteBackend() {
+		resp, err := llm.CallLLM(&LLMRequest{
+			Prompt:      prompt,
+			Temperature: float64Ptr(0.6),
+			MaxTokens:   768,
+		})
+		if errors.Is(err, appErrors.ErrBudgetExceeded) {
+			return nil, err
+		} else if err != nil {
+			utils.Warn("LLM call failed while suggesting next actions", utils.KV("error", err))
+		} else if resp != nil {
+			if actions, parseErr := parseNextActions(resp.Content); parseErr != nil {
+				utils.Warn("failed to parse LLM next actions response", utils.KV("error", parseErr))
+			} else if len(actions) > 0 {
+				return limitNextActions(actions, maxSuggestions, newProvenance(prompt, resp)), nil
+			}
+		}
+	}
+
+	return limitNextActions(fallbackNextActions(session, coldSpots), maxSuggestions, llm.fallbackProvenance(prompt)), nil
+}
+
+func buildNextActionsContext(session *models.Session, coldSpots []*models.Thought, maxSuggestions int) []models.ContextEntry {
+	session.EnsureContextEntries()
+	entries := make([]models.ContextEntry, 0, len(session.ContextEntries)+len(coldSpots)+3)
+	for _, entry := range session.ContextEntries {
+		if entry.Kind == models.ContextGoal || entry.Kind == models.ContextPreference || entry.Kind == models.ContextBackground {
+			entries = append(entries, entry)
+		}
+	}
+
+	meta := session.GetMetadata()
+	entries = append(entries, models.NewContextEntry(models.ContextNote, fmt.Sprintf(
+		"session stats: %d thoughts (%d generated, %d user), max depth %d",
+		meta.TotalThoughts, meta.LLMThoughts, meta.UserThoughts, meta.MaxDepth,
+	)))
+	if len(meta.Directions) > 0 {
+		entries = append(entries, models.NewContextEntry(models.ContextHistory, "explored directions: "+strings.Join(meta.Directions, ", ")))
+	}
+	for _, thought := range coldSpots {
+		entries = append(entries, models.NewContextEntry(models.ContextNote, fmt.Sprintf(
+			"under-explored: %s (depth %d, %d children)",
+			truncate(thought.Content, 80), thought.Depth, len(thought.Children),
+		)))
+	}
+	entries = append(entries, models.NewContextEntry(models.ContextGoal, fmt.Sprintf("suggest at most %d next actions", maxSuggestions)))
+	return entries
+}
+
+// findColdSpots 找出探索不足的分支：子节点最少、层级最浅、创建最早的思维优先。
+func findColdSpots(session *models.Session, limit int) []*models.Thought {
+	tree := session.GetThoughtTree()
+	candidates := make([]*models.Thought, 0, len(tree))
+	for _, thought := range tree {
+		if thought == nil || len(thought.Children) > 1 {
+			continue
+		}
+		if thought.IsRoot() && len(tree) > 1 {
+			continue
+		}
+		candidates = append(candidates, thought)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		left, right := candidates[i], candidates[j]
+		if len(left.Children) != len(right.Children) {
+			return len(left.Children) < len(right.Children)
+		}
+		if left.Depth != right.Depth {
+			return left.Depth < right.Depth
+		}
+		if !left.CreatedAt.Equal(right.CreatedAt) {
+			return left.CreatedAt.Before(right.CreatedAt)
+		}
+		return left.ID < right.ID
+	})
+
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates
+}
+
+func fallbackNextActions(session *models.Session, coldSpots []*models.Thought) []NextAction {
+	actions := make([]NextAction, 0, len(coldSpots)+1)
+	root := session.RootThought
+	if len(root.Children) < 2 {
+		actions = append(actions, NextAction{
+			Type:      "broaden",
+			Rationale: "The root concept has few branches; open another angle before going deeper.",
+			SuggestedDirection: &models.Direction{
+				Type:        models.Broad,
+				Title:       truncate("Alternative angles on "+root.Content, 80),
+				Description: fmt.Sprintf("Identify perspectives on %s that the current branches do not cover.", root.Content),
+				Keywords:    []string{root.Content},
+				Relevance:   0.6,
+			},
+		})
+	}
+
+	for _, thought := range coldSpots {
+		if thought.IsRoot() {
+			continue
+		}
+		actions = append(actions, NextAction{
+			Type:      "deepen",
+			Rationale: fmt.Sprintf("This branch stops at depth %d with no follow-up thoughts.", thought.Depth),
+			SuggestedDirection: &models.Direction{
+				Type:        models.Deep,
+				Title:       truncate(thought.Content, 80),
+				Description: fmt.Sprintf("Continue exploring %q with concrete mechanisms, evidence, or examples.", truncate(thought.Content, 120)),
+				Keywords:    append([]string(nil), thought.Direction.Keywords...),
+				Relevance:   0.6,
+			},
+		})
+	}
+	return actions
+}
+
+func parseNextActions(content string) ([]NextAction, error) {
+	trimmed := strings.TrimSpace(content)
+	start := strings.Index(trimmed, "[")
+	end := strings.LastIndex(trimmed, "]")
+	if start < 0 || end <= start {
+		return nil, errors.New("llm next actions response is not a JSON array")
+	}
+
+	var raw []struct {
+		Type               string `json:"type"`
+		Rationale          string `json:"rationale"`
+		SuggestedDirection *struct {
+			Type        string   `json:"type"`
+			Title       string   `json:"title"`
+			Description string   `json:"description"`
+			Keywords    []string `json:"keywords"`
+		} `json:"suggested_direction"`
+	}
+	if err := json.Unmarshal([]byte(trimmed[start:end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("parse llm next actions: %w", err)
+	}
+
+	actions := make([]NextAction, 0, len(raw))
+	for _, item := range raw {
+		action := NextAction{
+			Type:      strings.ToLower(strings.TrimSpace(item.Type)),
+			Rationale: strings.TrimSpace(item.Rationale),
+		}
+		if action.Type == "" || action.Rationale == "" {
+			continue
+		}
+		if dir := item.SuggestedDirection; dir != nil && strings.TrimSpace(dir.Title) != "" {
+			description := strings.TrimSpace(dir.Description)
+			if description == "" {
+				description = action.Rationale
+			}
+			action.SuggestedDirection = &models.Direction{
+				Type:        normalizeDirectionType(dir.Type),
+				Title:       strings.TrimSpace(dir.Title),
+				Description: description,
+				Keywords:    uniqueStrings(dir.Keywords),
+				Relevance:   0.7,
+			}
+		}
+		actions = append(actions, action)
+	}
+
+	if len(actions) == 0 {
+		return nil, errors.New("no valid next actions returned")
+	}
+	return actions, nil
+}
+
+func limitNextActions(actions []NextAction, max int, provenance *models.Provenance) []NextAction {
+	if len(actions) > max {
+		actions = actions[:max]
+	}
+	for i := range actions {
+		if actions[i].SuggestedDirection != nil {
+			p := *provenance
+			actions[i].SuggestedDirection.Provenance = &p
+		}
+	}
+	return actions
+}
@@ -0,0 +1,72 @@
+//Node Re-expansion(已有思维节点再扩散)
+
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+)
+
+// 常量
+// representedTypePenalty 是某方向类型占满已有子节点时相关度的降幅，按占比线性缩放
+const representedTypePenalty = 0.5
+
+// 方法
+// thoughtExpansionRequest 以 req.ThoughtID 节点的内容为概念、路径与会话上下文为背景生成新请求，
+// 结果作为该节点的子节点保存。req 中的其他选项保持不变。
+func (te *ThoughtExpander) thoughtExpansionRequest(req *ExpansionRequest) (*ExpansionRequest, error) {
+	if req.SessionID == "" || te.sessionManager == nil {
+		return nil, appErrors.ErrInvalidRequest
+	}
+	session, err := te.sessionManager.GetSession(req.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	thought, _ := session.FindThought(req.ThoughtID)
+	if thought == nil {
+		return nil, fmt.Errorf("%w: %s", appErrors.ErrThoughtNotFound, req.ThoughtID)
+	}
+
+	hints, err := te.pathHints(req.PathHints, thought)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeReq := *req
+	nodeReq.Concept = strings.TrimSpace(thought.Content)
+	nodeReq.Context = append(buildSessionExplorationContext(session, models.Direction{}, hints), req.Context...)
+	if path := thought.GetPath(); len(path) > 1 {
+		nodeReq.Context = append(nodeReq.Context, models.NewContextEntry(models.ContextHistory, "path: "+strings.Join(path, " -> ")))
+	}
+	if nodeReq.UserID == "" {
+		nodeReq.UserID = session.UserID
+	}
+	nodeReq.Persist = true
+	nodeReq.ParentThoughtID = thought.ID
+	nodeReq.ThoughtID = ""
+	return &nodeReq, nil
+}
+
+// 函数
+// downweightRepresentedTypes 按方向类型在已有子节点中的占比降低相关度，让再次扩散偏向尚未覆盖的类型。
+func downweightRepresentedTypes(directions []models.Direction, children []*models.Thought) []models.Direction {
+	if len(children) == 0 {
+		return directions
+	}
+	counts := make(map[models.DirectionType]int)
+	for _, child := range children {
+		if child != nil {
+			counts[child.Direction.Type]++
+		}
+	}
+	weighted := make([]models.Direction, len(directions))
+	for i, dir := range directions {
+		share := float64(counts[dir.Type]) / float64(len(children))
+		dir.Relevance *= 1 - share*representedTypePenalty
+		weighted[i] = dir
+	}
+	return weighted
+}
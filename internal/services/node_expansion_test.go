@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+)
+
+// nodeGenerator 记录收到的概念与上下文，并返回一个深入方向和一个横向方向。
+type nodeGenerator struct {
+	concept string
+	context []models.ContextEntry
+}
+
+func (g *nodeGenerator) GenerateThoughtDirections(concept string, context []models.ContextEntry) ([]models.Direction, error) {
+	g.concept = concept
+	g.context = context
+	return []models.Direction{
+		{Type: models.Deep, Title: "Membranes", Description: "Membrane chemistry", Relevance: 0.9},
+		{Type: models.Lateral, Title: "Shipping", Description: "Tank logistics", Relevance: 0.8},
+	}, nil
+}
+
+func (g *nodeGenerator) ExploreDirection(direction models.Direction, depth int, context []models.ContextEntry) ([]*models.Thought, error) {
+	return []*models.Thought{models.NewThought(direction.Title+" preview", "", direction)}, nil
+}
+
+func (g *nodeGenerator) CallLLM(req *LLMRequest) (*LLMResponse, error) {
+	return nil, errors.New("not scripted")
+}
+
+func (g *nodeGenerator) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+func TestExpandThoughtAttachesChildrenToNode(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	generator := &nodeGenerator{}
+	expander := NewThoughtExpander(generator, manager)
+	session, err := manager.CreateSession("user", "Energy")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	flow := models.NewThought("Flow batteries", session.ID, models.Direction{Type: models.Broad, Title: "Flow"})
+	if err := manager.AddThoughtToSession(session.ID, flow); err != nil {
+		t.Fatalf("AddThoughtToSession failed: %v", err)
+	}
+	existing := models.NewThought("Vanadium cost", session.ID, models.Direction{Type: models.Deep, Title: "Vanadium"})
+	existing.ParentID = &flow.ID
+	if err := manager.AddThoughtToSession(session.ID, existing); err != nil {
+		t.Fatalf("AddThoughtToSession failed: %v", err)
+	}
+	grandchild := models.NewThought("Price hedging", session.ID, models.Direction{Type: models.Deep, Title: "Hedging"})
+	grandchild.ParentID = &existing.ID
+	if err := manager.AddThoughtToSession(session.ID, grandchild); err != nil {
+		t.Fatalf("AddThoughtToSession failed: %v", err)
+	}
+
+	result, err := expander.Expand(&ExpansionRequest{SessionID: session.ID, ThoughtID: flow.ID, MaxDirections: 1})
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if generator.concept != "Flow batteries" {
+		t.Fatalf("expected the node content as concept, got %q", generator.concept)
+	}
+	if !strings.Contains(strings.Join(models.ContextStrings(generator.context), "\n"), "path: Energy -> Flow batteries") {
+		t.Fatalf("expected the node path in the context, got %v", models.ContextStrings(generator.context))
+	}
+	// Deep 已占满节点的子节点，相关度减半后排在 Lateral 之后
+	if len(result.Directions) != 1 || result.Directions[0].Type != models.Lateral {
+		t.Fatalf("expected the under-represented lateral direction, got %+v", result.Directions)
+	}
+
+	stored, err := manager.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	node, _ := stored.FindThought(flow.ID)
+	if node == nil || len(node.Children) != 2 {
+		t.Fatalf("expected the existing child plus one new child, got %+v", node)
+	}
+	if node.Children[0].ID != existing.ID || len(node.Children[0].Children) != 1 || node.Children[0].Children[0].ID != grandchild.ID {
+		t.Fatalf("expected existing children to stay untouched, got %+v", node.Children[0])
+	}
+	added := node.Children[1]
+	if added.ID != result.PersistedThoughtIDs[0] || added.Depth != 2 || *added.ParentID != flow.ID {
+		t.Fatalf("expected a depth-2 child of the node, got %+v", added)
+	}
+
+	if _, err := expander.Expand(&ExpansionRequest{SessionID: session.ID, ThoughtID: "missing"}); !errors.Is(err, appErrors.ErrThoughtNotFound) {
+		t.Fatalf("expected ErrThoughtNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,201 @@
+//Ollama Provider(Ollama 本地模型)
+
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const (
+	ProviderOpenAI = "openai"
+	ProviderOllama = "ollama"
+
+	DefaultOllamaBaseURL = "http://localhost:11434"
+
+	ollamaChatPath = "/api/chat"
+	ollamaTagsPath = "/api/tags"
+)
+
+// 结构体
+type ollamaChatResponse struct {
+	Model   string `json:"model"`
+	Message struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	Error           string `json:"error"`
+}
+
+type ollamaTagsResponse struct {
+	Models []struct {
+		Name  string `json:"name"`
+		Model string `json:"model"`
+	} `json:"models"`
+}
+
+// 方法
+// SetProvider 选择 LLM 提供方：openai（默认，兼容 chat completions）或 ollama（原生 /api/chat）。
+// 选择 ollama 且未配置 baseURL 时使用本机默认地址。
+func (llm *LLMOrchestrator) SetProvider(provider string) error {
+	if llm == nil {
+		return nil
+	}
+	switch normalized := strings.ToLower(strings.TrimSpace(provider)); normalized {
+	case "", ProviderOpenAI:
+		llm.provider = ProviderOpenAI
+	case ProviderOllama:
+		llm.provider = ProviderOllama
+		if llm.baseURL == "" {
+			llm.baseURL = DefaultOllamaBaseURL
+		}
+	default:
+		return fmt.Errorf("unsupported llm provider %q", provider)
+	}
+	return nil
+}
+
+// SetOllamaOptions 配置 Ollama 的上下文窗口（num_ctx）与模型驻留时间（keep_alive），零值使用服务端默认。
+func (llm *LLMOrchestrator) SetOllamaOptions(numCtx int, keepAlive string) {
+	if llm == nil {
+		return
+	}
+	if numCtx > 0 {
+		llm.ollamaNumCtx = numCtx
+	}
+	llm.ollamaKeepAlive = strings.TrimSpace(keepAlive)
+}
+
+func (llm *LLMOrchestrator) isOllama() bool {
+	return llm != nil && llm.provider == ProviderOllama
+}
+
+func (llm *LLMOrchestrator) ollamaURL(path string) string {
+	base := strings.TrimRight(llm.baseURL, "/")
+	base = strings.TrimSuffix(base, ollamaChatPath)
+	return base + path
+}
+
+// buildOllamaPayload 将调用参数映射为 Ollama /api/chat 请求，始终关闭流式输出。
+func (llm *LLMOrchestrator) buildOllamaPayload(call *llmCall) ([]byte, error) {
+	options := map[string]any{
+		"temperature": call.temperature,
+		"num_predict": call.maxTokens,
+	}
+	if llm.ollamaNumCtx > 0 {
+		options["num_ctx"] = llm.ollamaNumCtx
+	}
+
+	payload := map[string]any{
+		"model": llm.model,
+		"messages": []map[string]string{
+			{"role": "system", "content": call.systemPrompt},
+			{"role": "user", "content": call.userContent},
+		},
+		"stream":  false,
+		"options": options,
+	}
+	if llm.ollamaKeepAlive != "" {
+		payload["keep_alive"] = llm.ollamaKeepAlive
+	}
+	if format := llm.ollamaFormatPayload(call.responseFormat); format != nil {
+		payload["format"] = format
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal ollama payload: %w", err)
+	}
+	return body, nil
+}
+
+// checkOllamaModel 通过 /api/tags 确认配置的模型已下载，缺失时提示 ollama pull。
+func (llm *LLMOrchestrator) checkOllamaModel(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	reqHTTP, err := http.NewRequestWithContext(ctx, http.MethodGet, llm.ollamaURL(ollamaTagsPath), nil)
+	if err != nil {
+		return fmt.Errorf("new http request: %w", err)
+	}
+	resp, err := llm.httpClient.Do(reqHTTP)
+	if err != nil {
+		return fmt.Errorf("ollama unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxLLMResponseBytes))
+	if err != nil {
+		return fmt.Errorf("read ollama tags: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return &llmHTTPError{status: resp.StatusCode, body: truncate(string(raw), 512)}
+	}
+
+	var tags ollamaTagsResponse
+	if err := json.Unmarshal(raw, &tags); err != nil {
+		return fmt.Errorf("decode ollama tags: %w", err)
+	}
+	for _, model := range tags.Models {
+		if ollamaModelMatches(model.Name, llm.model) || ollamaModelMatches(model.Model, llm.model) {
+			return nil
+		}
+	}
+
+	utils.Warn("configured ollama model is not available", utils.KV("model", llm.model), utils.KV("hint", "ollama pull "+llm.model))
+	return fmt.Errorf("ollama model %q not found; run `ollama pull %s`", llm.model, llm.model)
+}
+
+// 函数
+// parseOllamaChat 将 Ollama /api/chat 响应转换为 LLMResponse，token 数取自 prompt_eval_count 与 eval_count。
+func parseOllamaChat(raw []byte) (*LLMResponse, error) {
+	var parsed ollamaChatResponse
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return nil, fmt.Errorf("decode ollama response: %w", err)
+	}
+	if parsed.Error != "" {
+		return nil, fmt.Errorf("ollama error: %s", parsed.Error)
+	}
+
+	content := strings.TrimSpace(parsed.Message.Content)
+	if content == "" {
+		return nil, errors.New("llm response empty")
+	}
+
+	return &LLMResponse{
+		Content: content,
+		Usage: TokenUsage{
+			PromptTokens:     parsed.PromptEvalCount,
+			CompletionTokens: parsed.EvalCount,
+			TotalTokens:      parsed.PromptEvalCount + parsed.EvalCount,
+		},
+		Model:     parsed.Model,
+		Timestamp: time.Now().UTC(),
+	}, nil
+}
+
+// ollamaModelMatches 比较模型名，未写标签的名称视为 :latest。
+func ollamaModelMatches(available, configured string) bool {
+	if available == "" || configured == "" {
+		return false
+	}
+	withTag := func(name string) string {
+		if strings.Contains(name, ":") {
+			return name
+		}
+		return name + ":latest"
+	}
+	return withTag(available) == withTag(configured)
+}
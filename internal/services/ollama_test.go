@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newFakeOllama(t *testing.T, models []string, chat func(payload map[string]any) map[string]any) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case ollamaTagsPath:
+			entries := make([]map[string]string, 0, len(models))
+			for _, name := range models {
+				entries = append(entries, map[string]string{"name": name, "model": name})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"models": entries})
+		case ollamaChatPath:
+			var payload map[string]any
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(chat(payload))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newOllamaOrchestrator(t *testing.T, baseURL, model string) *LLMOrchestrator {
+	t.Helper()
+	orchestrator := NewLLMOrchestrator("", baseURL, model)
+	if err := orchestrator.SetProvider(ProviderOllama); err != nil {
+		t.Fatalf("SetProvider failed: %v", err)
+	}
+	return orchestrator
+}
+
+func TestOllamaCallLLMMapsOptionsAndUsage(t *testing.T) {
+	var received map[string]any
+	server := newFakeOllama(t, nil, func(payload map[string]any) map[string]any {
+		received = payload
+		return map[string]any{
+			"model":             "llama3:latest",
+			"message":           map[string]string{"role": "assistant", "content": " hi "},
+			"done":              true,
+			"prompt_eval_count": 12,
+			"eval_count":        5,
+		}
+	})
+	orchestrator := newOllamaOrchestrator(t, server.URL, "llama3")
+	orchestrator.SetOllamaOptions(8192, "10m")
+
+	resp, err := orchestrator.CallLLM(&LLMRequest{Prompt: "hello", Temperature: float64Ptr(0.2), MaxTokens: 64})
+	if err != nil {
+		t.Fatalf("CallLLM returned error: %v", err)
+	}
+	if resp.Content != "hi" || resp.Model != "llama3:latest" || resp.Attempts != 1 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	if resp.Usage.PromptTokens != 12 || resp.Usage.CompletionTokens != 5 || resp.Usage.TotalTokens != 17 {
+		t.Fatalf("unexpected usage %+v", resp.Usage)
+	}
+
+	if received["model"] != "llama3" || received["stream"] != false || received["keep_alive"] != "10m" {
+		t.Fatalf("unexpected payload %+v", received)
+	}
+	options, _ := received["options"].(map[string]any)
+	if options["temperature"] != 0.2 || options["num_predict"] != float64(64) || options["num_ctx"] != float64(8192) {
+		t.Fatalf("unexpected options %+v", options)
+	}
+}
+
+func TestOllamaStreamFallsBackToSingleDelta(t *testing.T) {
+	server := newFakeOllama(t, nil, func(payload map[string]any) map[string]any {
+		if payload["stream"] != false {
+			t.Errorf("expected streaming to be disabled, got %v", payload["stream"])
+		}
+		return map[string]any{"message": map[string]string{"content": "whole answer"}, "done": true}
+	})
+	orchestrator := newOllamaOrchestrator(t, server.URL, "llama3")
+
+	var deltas []string
+	resp, err := orchestrator.CallLLMStream(context.Background(), &LLMRequest{Prompt: "hello"}, func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	if err != nil {
+		t.Fatalf("CallLLMStream returned error: %v", err)
+	}
+	if len(deltas) != 1 || deltas[0] != "whole answer" || resp.Content != "whole answer" || resp.Model != "llama3" {
+		t.Fatalf("unexpected stream result %v / %+v", deltas, resp)
+	}
+}
+
+func TestOllamaHealthCheckVerifiesModel(t *testing.T) {
+	server := newFakeOllama(t, []string{"llama3:latest", "qwen2:7b"}, func(payload map[string]any) map[string]any {
+		return map[string]any{"message": map[string]string{"content": "OK"}, "done": true}
+	})
+
+	if err := newOllamaOrchestrator(t, server.URL, "llama3").HealthCheck(context.Background()); err != nil {
+		t.Fatalf("expected untagged model to match :latest, got %v", err)
+	}
+	if err := newOllamaOrchestrator(t, server.URL, "qwen2:7b").HealthCheck(context.Background()); err != nil {
+		t.Fatalf("expected tagged model to match, got %v", err)
+	}
+
+	err := newOllamaOrchestrator(t, server.URL, "mistral").HealthCheck(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "ollama pull mistral") {
+		t.Fatalf("expected missing model error with pull hint, got %v", err)
+	}
+}
+
+func TestSetProviderDefaultsAndRejectsUnknown(t *testing.T) {
+	orchestrator := NewLLMOrchestrator("", "", "llama3")
+	if err := orchestrator.SetProvider("Ollama"); err != nil {
+		t.Fatalf("SetProvider failed: %v", err)
+	}
+	if orchestrator.baseURL != DefaultOllamaBaseURL || orchestrator.endpoint() != DefaultOllamaBaseURL+ollamaChatPath {
+		t.Fatalf("unexpected ollama endpoint %q", orchestrator.endpoint())
+	}
+	if err := orchestrator.SetProvider("bard"); err == nil {
+		t.Fatalf("expected unknown provider to be rejected")
+	}
+}
@@ -0,0 +1,173 @@
+//Exploration Path Hints(探索上下文中的历史路径提示)
+
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 枚举类型
+// PathHintStrategy 决定探索上下文中附带哪些已有思维路径作为历史提示。
+type PathHintStrategy string
+
+// 常量
+const (
+	// PathHintDeepest 优先选择最深的节点
+	PathHintDeepest PathHintStrategy = "deepest"
+	// PathHintMostRecent 优先选择最近创建的节点
+	PathHintMostRecent PathHintStrategy = "most_recent"
+	// PathHintSameBranch 只选择目标节点的祖先与子树中的节点，优先最深者
+	PathHintSameBranch PathHintStrategy = "same_branch"
+
+	// DefaultPathHintLimit 是探索上下文中默认附带的历史路径数
+	DefaultPathHintLimit = 4
+	// MaxPathHintLimit 是历史路径数的上限
+	MaxPathHintLimit = 20
+)
+
+// 结构体
+// PathHintOptions 为单次请求覆盖部署配置的历史路径提示设置，零值字段使用扩展器默认值。
+type PathHintOptions struct {
+	Limit    int              `json:"limit,omitempty"`
+	Strategy PathHintStrategy `json:"strategy,omitempty"`
+}
+
+// pathHintSelection 是解析后的历史路径提示设置；Target 为新思维将挂载的节点，供 same_branch 使用
+type pathHintSelection struct {
+	Limit    int
+	Strategy PathHintStrategy
+	Target   *models.Thought
+}
+
+// 函数
+// ParsePathHintStrategy 解析历史路径提示策略，空值视为 deepest。
+func ParsePathHintStrategy(value string) (PathHintStrategy, error) {
+	switch strategy := PathHintStrategy(strings.ToLower(strings.TrimSpace(value))); strategy {
+	case "", PathHintDeepest:
+		return PathHintDeepest, nil
+	case PathHintMostRecent, PathHintSameBranch:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown path hint strategy %q", value)
+	}
+}
+
+// collectThoughtPathHints 按策略选出至多 hints.Limit 个节点，以其从根出发的路径作为历史提示。
+func collectThoughtPathHints(root *models.Thought, hints pathHintSelection) []models.ContextEntry {
+	if root == nil || hints.Limit <= 0 {
+		return nil
+	}
+
+	var nodes []*models.Thought
+	if hints.Strategy == PathHintSameBranch && hints.Target != nil {
+		nodes = branchThoughts(root, hints.Target)
+	} else {
+		nodes = subtreeThoughts(root, nil)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		if hints.Strategy == PathHintMostRecent && !nodes[i].CreatedAt.Equal(nodes[j].CreatedAt) {
+			return nodes[i].CreatedAt.After(nodes[j].CreatedAt)
+		}
+		if nodes[i].Depth == nodes[j].Depth {
+			return strings.Compare(nodes[i].Content, nodes[j].Content) < 0
+		}
+		return nodes[i].Depth > nodes[j].Depth
+	})
+
+	entries := make([]models.ContextEntry, 0, hints.Limit)
+	seen := map[string]struct{}{}
+	for _, node := range nodes {
+		if len(entries) >= hints.Limit {
+			break
+		}
+		path := node.GetPath()
+		if len(path) == 0 {
+			continue
+		}
+		joined := strings.Join(path, " -> ")
+		if _, ok := seen[joined]; ok {
+			continue
+		}
+		seen[joined] = struct{}{}
+		entries = append(entries, models.NewContextEntry(models.ContextHistory, joined))
+	}
+
+	return entries
+}
+
+// subtreeThoughts 按广度优先将 root 子树中的节点追加到 nodes。
+func subtreeThoughts(root *models.Thought, nodes []*models.Thought) []*models.Thought {
+	queue := []*models.Thought{root}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == nil {
+			continue
+		}
+		nodes = append(nodes, current)
+		queue = append(queue, current.Children...)
+	}
+	return nodes
+}
+
+// branchThoughts 返回 target 的祖先（含 target）与其子树中的节点；target 不在树中时返回整棵树。
+func branchThoughts(root, target *models.Thought) []*models.Thought {
+	all := subtreeThoughts(root, nil)
+	byID := make(map[string]*models.Thought, len(all))
+	for _, node := range all {
+		byID[node.ID] = node
+	}
+	if byID[target.ID] == nil {
+		return all
+	}
+
+	nodes := subtreeThoughts(byID[target.ID], nil)
+	for current := byID[target.ID]; current.ParentID != nil; {
+		parent := byID[*current.ParentID]
+		if parent == nil {
+			break
+		}
+		nodes = append(nodes, parent)
+		current = parent
+	}
+	return nodes
+}
+
+// 方法
+// SetPathHints 设置探索上下文默认附带的历史路径数（0 表示不附带，超过上限时取上限）与选择策略，
+// 负数被忽略，空策略视为 deepest。
+func (te *ThoughtExpander) SetPathHints(limit int, strategy PathHintStrategy) {
+	if te == nil || limit < 0 {
+		return
+	}
+	if strategy == "" {
+		strategy = PathHintDeepest
+	}
+	te.pathHintLimit = min(limit, MaxPathHintLimit)
+	te.pathHintStrategy = strategy
+}
+
+// pathHints 合并请求覆盖与扩展器默认值，target 为新思维将挂载的节点。
+func (te *ThoughtExpander) pathHints(opts PathHintOptions, target *models.Thought) (pathHintSelection, error) {
+	if opts.Limit < 0 || opts.Limit > MaxPathHintLimit {
+		return pathHintSelection{}, utils.ValidationError(fmt.Sprintf("path_hint_limit must be between 0 and %d", MaxPathHintLimit))
+	}
+	selection := pathHintSelection{Limit: te.pathHintLimit, Strategy: te.pathHintStrategy, Target: target}
+	if opts.Limit > 0 {
+		selection.Limit = opts.Limit
+	}
+	if opts.Strategy != "" {
+		strategy, err := ParsePathHintStrategy(string(opts.Strategy))
+		if err != nil {
+			return pathHintSelection{}, utils.ValidationError(err.Error())
+		}
+		selection.Strategy = strategy
+	}
+	return selection, nil
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+)
+
+// pathHintTree 构建时间戳已知的树：Wind 分支最新，Solar 分支最深。
+func pathHintTree() (*models.Thought, map[string]*models.Thought) {
+	base := time.Date(2026, time.May, 1, 9, 0, 0, 0, time.UTC)
+	nodes := map[string]*models.Thought{}
+	add := func(parent *models.Thought, content string, minutes int) *models.Thought {
+		thought := models.NewThought(content, "session", models.Direction{Type: models.Deep, Title: content})
+		thought.CreatedAt = base.Add(time.Duration(minutes) * time.Minute)
+		if parent != nil {
+			parent.AddChild(thought)
+		}
+		nodes[content] = thought
+		return thought
+	}
+
+	root := add(nil, "Energy", 0)
+	solar := add(root, "Solar", 1)
+	panels := add(solar, "Panels", 2)
+	add(panels, "Perovskite", 3)
+	wind := add(root, "Wind", 4)
+	add(wind, "Turbines", 5)
+	add(wind, "Offshore", 6)
+	return root, nodes
+}
+
+func TestCollectThoughtPathHintsStrategies(t *testing.T) {
+	root, nodes := pathHintTree()
+	cases := []struct {
+		name  string
+		hints pathHintSelection
+		want  []string
+	}{
+		{"deepest", pathHintSelection{Limit: 2, Strategy: PathHintDeepest}, []string{
+			"history: Energy -> Solar -> Panels -> Perovskite",
+			"history: Energy -> Wind -> Offshore",
+		}},
+		{"most_recent", pathHintSelection{Limit: 2, Strategy: PathHintMostRecent}, []string{
+			"history: Energy -> Wind -> Offshore",
+			"history: Energy -> Wind -> Turbines",
+		}},
+		{"same_branch", pathHintSelection{Limit: 4, Strategy: PathHintSameBranch, Target: nodes["Wind"]}, []string{
+			"history: Energy -> Wind -> Offshore",
+			"history: Energy -> Wind -> Turbines",
+			"history: Energy -> Wind",
+			"history: Energy",
+		}},
+		{"same_branch_leaf", pathHintSelection{Limit: 4, Strategy: PathHintSameBranch, Target: nodes["Panels"]}, []string{
+			"history: Energy -> Solar -> Panels -> Perovskite",
+			"history: Energy -> Solar -> Panels",
+			"history: Energy -> Solar",
+			"history: Energy",
+		}},
+		{"disabled", pathHintSelection{Limit: 0, Strategy: PathHintDeepest}, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := models.ContextStrings(collectThoughtPathHints(root, tc.hints))
+			if len(got) == 0 && len(tc.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPathHintsRequestOverridesDefaults(t *testing.T) {
+	expander := NewThoughtExpander(&nodeGenerator{}, NewSessionManager(storage.NewInMemorySessionStore()))
+	expander.SetPathHints(6, PathHintMostRecent)
+
+	hints, err := expander.pathHints(PathHintOptions{}, nil)
+	if err != nil || hints.Limit != 6 || hints.Strategy != PathHintMostRecent {
+		t.Fatalf("expected the configured defaults, got %+v (%v)", hints, err)
+	}
+	hints, err = expander.pathHints(PathHintOptions{Limit: 2, Strategy: "same_branch"}, nil)
+	if err != nil || hints.Limit != 2 || hints.Strategy != PathHintSameBranch {
+		t.Fatalf("expected the request override, got %+v (%v)", hints, err)
+	}
+	if _, err := expander.pathHints(PathHintOptions{Strategy: "oldest"}, nil); err == nil {
+		t.Fatal("expected an error for an unknown strategy")
+	}
+	if _, err := expander.pathHints(PathHintOptions{Limit: MaxPathHintLimit + 1}, nil); err == nil {
+		t.Fatal("expected an error for a limit above the maximum")
+	}
+}
@@ -0,0 +1,105 @@
+//LLM Pricing(LLM计费)
+
+package services
+
+import (
+	"strings"
+	"sync"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 结构体
+// ModelPrice 是模型每 1K 输入/输出令牌的价格。
+type ModelPrice struct {
+	InputPer1K  float64 `yaml:"input_per_1k" json:"input_per_1k"`
+	OutputPer1K float64 `yaml:"output_per_1k" json:"output_per_1k"`
+}
+
+// UsageRecorder 接收每次实际发出的 LLM 调用的用量与费用；命中缓存的响应不会上报。
+type UsageRecorder func(model string, usage TokenUsage, cost float64)
+
+// usageCollector 汇总一次操作中的 LLM 用量，可被并行调用。
+type usageCollector struct {
+	mutex sync.Mutex
+	usage models.SessionUsage
+}
+
+// 方法
+// SetPricing 设置按模型名计费的价格表，nil 或空表表示不计费。
+func (llm *LLMOrchestrator) SetPricing(pricing map[string]ModelPrice) {
+	if llm == nil {
+		return
+	}
+	table := make(map[string]ModelPrice, len(pricing))
+	for model, price := range pricing {
+		if model = strings.TrimSpace(model); model != "" {
+			table[model] = price
+		}
+	}
+	llm.pricing = table
+}
+
+// WithUsageRecorder 返回一个编排器副本，其每次远程调用的用量都会回调 recorder。
+func (llm *LLMOrchestrator) WithUsageRecorder(recorder UsageRecorder) *LLMOrchestrator {
+	if llm == nil {
+		return nil
+	}
+	clone := *llm
+	clone.usageRecorder = recorder
+	return &clone
+}
+
+// Cost 按价格表计算用量费用；模型不在价格表中时返回 false。
+func (llm *LLMOrchestrator) Cost(model string, usage TokenUsage) (float64, bool) {
+	if llm == nil {
+		return 0, false
+	}
+	price, ok := llm.pricing[model]
+	if !ok {
+		price, ok = llm.pricing[llm.reportedModel()]
+	}
+	if !ok {
+		return 0, false
+	}
+
+	prompt, completion := usage.PromptTokens, usage.CompletionTokens
+	if prompt == 0 && completion == 0 {
+		prompt = usage.TotalTokens
+	}
+	return float64(prompt)/1000*price.InputPer1K + float64(completion)/1000*price.OutputPer1K, true
+}
+
+// reportUsage 将一次远程调用的用量上报给绑定的 UsageRecorder；响应未携带用量时按估算的提示令牌数记。
+func (llm *LLMOrchestrator) reportUsage(call *llmCall, resp *LLMResponse) {
+	if llm.usageRecorder == nil || resp == nil {
+		return
+	}
+
+	usage := resp.Usage
+	if usage.TotalTokens <= 0 {
+		usage = TokenUsage{PromptTokens: call.estimatedTokens, TotalTokens: call.estimatedTokens}
+	}
+	model := resp.Model
+	if model == "" {
+		model = llm.reportedModel()
+	}
+	cost, ok := llm.Cost(model, usage)
+	if !ok {
+		utils.Warn("no pricing configured for llm model; recording zero cost", utils.KV("model", model))
+	}
+	llm.usageRecorder(model, usage, cost)
+}
+
+func (c *usageCollector) record(model string, usage TokenUsage, cost float64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.usage.Add(usage, cost)
+}
+
+func (c *usageCollector) total() models.SessionUsage {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.usage
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+)
+
+func TestExploreDirectionAccumulatesSessionCost(t *testing.T) {
+	content := `{"content":"Solid-state cells trade energy density for manufacturing complexity."}`
+	server := newChatCompletionServer(t, content, TokenUsage{PromptTokens: 1000, CompletionTokens: 500, TotalTokens: 1500})
+
+	llm := NewLLMOrchestrator("key", server.URL, "priced-model")
+	llm.SetPricing(map[string]ModelPrice{"test-model": {InputPer1K: 0.01, OutputPer1K: 0.03}})
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	expander := NewThoughtExpander(llm, manager)
+
+	session, err := manager.CreateSession("user", "Batteries")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	direction := models.Direction{Type: models.Deep, Title: "Solid-state batteries"}
+	for i := 0; i < 2; i++ {
+		if _, err := expander.ExploreDirection(direction, session.ID, ""); err != nil {
+			t.Fatalf("ExploreDirection %d failed: %v", i, err)
+		}
+	}
+
+	stored, err := manager.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	usage := stored.GetMetadata().Usage
+	if usage.Calls != 2 || usage.PromptTokens != 2000 || usage.CompletionTokens != 1000 || usage.TotalTokens != 3000 {
+		t.Fatalf("unexpected cumulative usage %+v", usage)
+	}
+	if math.Abs(usage.Cost-0.05) > 1e-9 {
+		t.Fatalf("expected cumulative cost 0.05, got %v", usage.Cost)
+	}
+
+	if _, err := manager.Undo(session.ID); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if restored, _ := manager.GetSession(session.ID); restored.Usage != usage {
+		t.Fatalf("expected undo to keep usage %+v, got %+v", usage, restored.Usage)
+	}
+	if total, err := manager.UserUsage("user"); err != nil || total != usage {
+		t.Fatalf("expected user usage %+v, got %+v (%v)", usage, total, err)
+	}
+}
+
+func TestUnpricedModelRecordsTokensWithZeroCost(t *testing.T) {
+	server := newChatCompletionServer(t, `{"content":"Insight"}`, TokenUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15})
+
+	llm := NewLLMOrchestrator("key", server.URL, "unknown-model")
+	var recorded models.SessionUsage
+	tracked := llm.WithUsageRecorder(func(model string, usage TokenUsage, cost float64) {
+		recorded.Add(usage, cost)
+	})
+	if _, err := tracked.CallLLM(&LLMRequest{Prompt: "hello"}); err != nil {
+		t.Fatalf("CallLLM failed: %v", err)
+	}
+	if recorded.Calls != 1 || recorded.TotalTokens != 15 || recorded.Cost != 0 {
+		t.Fatalf("expected tokens with zero cost, got %+v", recorded)
+	}
+}
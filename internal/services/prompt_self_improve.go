@@ -0,0 +1,235 @@
+//Prompt Self-Improvement(提示词自我改进)
+
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const (
+	// ImprovedPromptPrefix 是改进后提示词在提示词库中的键前缀。
+	ImprovedPromptPrefix = "improved_"
+
+	selfImprovePromptType = "directions"
+	maxPromptWeaknesses   = 10
+)
+
+// 结构体
+// PromptQuality 是用某个提示词生成方向的质量指标。
+type PromptQuality struct {
+	Count        int     `json:"count"`
+	RelevanceSum float64 `json:"relevance_sum"`
+}
+
+// PromptImprovement 记录一次提示词自我改进：模型指出的不足、改进前后的方向质量以及是否采用。
+type PromptImprovement struct {
+	PromptType     string        `json:"prompt_type"`
+	OriginalPrompt string        `json:"original_prompt"`
+	ImprovedPrompt string        `json:"improved_prompt"`
+	Weaknesses     []string      `json:"weaknesses,omitempty"`
+	Before         PromptQuality `json:"before"`
+	After          PromptQuality `json:"after"`
+	Adopted        bool          `json:"adopted"`
+	CacheKey       string        `json:"cache_key,omitempty"`
+}
+
+// improvedPrompt 是自我改进胜出的提示词；改写针对特定概念，只用于该概念的方向生成。
+type improvedPrompt struct {
+	concept string
+	prompt  string
+}
+
+type selfImproveResponse struct {
+	Weaknesses     []string `json:"weaknesses"`
+	ImprovedPrompt string   `json:"improved_prompt"`
+}
+
+// 方法
+// SelfImprovePrompt 让模型评审并改写方向提示词，返回表现更好的一方（改进版胜出时同时缓存）。
+func (llm *LLMOrchestrator) SelfImprovePrompt(currentPrompt, concept string, context []string) (string, error) {
+	result, err := llm.ImprovePrompt(currentPrompt, concept, context)
+	if err != nil {
+		return "", err
+	}
+	if result.Adopted {
+		return result.ImprovedPrompt, nil
+	}
+	return result.OriginalPrompt, nil
+}
+
+// ImprovePrompt 将当前提示词连同元提示词发给模型，要求指出不足并给出改写版本；
+// 随后分别用两个提示词生成方向，比较数量与相关度之和。改写版更好时以 "improved_directions" 缓存到提示词库，
+// 此后同一概念的方向生成使用该提示词。
+// currentPrompt 为空时使用按 concept 与 context 构建的默认方向提示词。
+func (llm *LLMOrchestrator) ImprovePrompt(currentPrompt, concept string, context []string) (*PromptImprovement, error) {
+	concept = strings.TrimSpace(concept)
+	if concept == "" {
+		return nil, utils.ValidationError("concept is required")
+	}
+	if !llm.hasRemoteBackend() {
+		return nil, errors.New("prompt self-improvement requires a remote llm backend")
+	}
+
+	entries := normalizeContextEntries(models.ParseContextEntries(context))
+	currentPrompt = strings.TrimSpace(currentPrompt)
+	if currentPrompt == "" {
+		currentPrompt = llm.BuildPrompt(concept, entries, selfImprovePromptType)
+	}
+
+	resp, err := llm.CallLLM(&LLMRequest{
+		Prompt:      buildSelfImprovePrompt(currentPrompt, concept),
+		Temperature: float64Ptr(0.3),
+		MaxTokens:   2048,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("self-improve prompt: %w", err)
+	}
+	critique, err := parseSelfImproveResponse(resp.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := llm.measurePrompt(currentPrompt, entries)
+	if err != nil {
+		return nil, err
+	}
+	after, err := llm.measurePrompt(critique.ImprovedPrompt, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PromptImprovement{
+		PromptType:     selfImprovePromptType,
+		OriginalPrompt: currentPrompt,
+		ImprovedPrompt: critique.ImprovedPrompt,
+		Weaknesses:     critique.Weaknesses,
+		Before:         before,
+		After:          after,
+		Adopted:        after.betterThan(before),
+	}
+	if result.Adopted {
+		result.CacheKey = ImprovedPromptPrefix + selfImprovePromptType
+		llm.storeImprovedPrompt(result.CacheKey, concept, result.ImprovedPrompt)
+	}
+
+	utils.Info("prompt self-improvement evaluated",
+		utils.KV("prompt_type", result.PromptType),
+		utils.KV("before_count", before.Count),
+		utils.KV("before_relevance_sum", before.RelevanceSum),
+		utils.KV("after_count", after.Count),
+		utils.KV("after_relevance_sum", after.RelevanceSum),
+		utils.KV("adopted", result.Adopted))
+	return result, nil
+}
+
+// ImprovedPrompt 返回某类型缓存的改进版提示词。
+func (llm *LLMOrchestrator) ImprovedPrompt(promptType string) (string, bool) {
+	improved, ok := llm.improvedPrompt(promptType)
+	return improved.prompt, ok
+}
+
+// improvedPromptFor 返回为 concept 改进的提示词；缓存的改写针对其他概念时返回 false。
+func (llm *LLMOrchestrator) improvedPromptFor(promptType, concept string) (string, bool) {
+	improved, ok := llm.improvedPrompt(promptType)
+	if !ok || !strings.EqualFold(improved.concept, strings.TrimSpace(concept)) {
+		return "", false
+	}
+	return improved.prompt, true
+}
+
+func (llm *LLMOrchestrator) improvedPrompt(promptType string) (improvedPrompt, bool) {
+	if llm == nil || llm.prompts == nil {
+		return improvedPrompt{}, false
+	}
+	llm.prompts.mutex.RLock()
+	defer llm.prompts.mutex.RUnlock()
+	improved, ok := llm.prompts.improved[ImprovedPromptPrefix+strings.ToLower(strings.TrimSpace(promptType))]
+	return improved, ok
+}
+
+func (llm *LLMOrchestrator) storeImprovedPrompt(key, concept, prompt string) {
+	if llm == nil || llm.prompts == nil {
+		return
+	}
+	llm.prompts.mutex.Lock()
+	defer llm.prompts.mutex.Unlock()
+	if llm.prompts.improved == nil {
+		llm.prompts.improved = map[string]improvedPrompt{}
+	}
+	llm.prompts.improved[key] = improvedPrompt{concept: concept, prompt: prompt}
+}
+
+// measurePrompt 用提示词生成方向并统计数量与相关度之和；生成失败记为零分。
+func (llm *LLMOrchestrator) measurePrompt(prompt string, context []models.ContextEntry) (PromptQuality, error) {
+	directions, err := llm.remoteDirections(prompt, context)
+	if err != nil {
+		return PromptQuality{}, err
+	}
+	quality := PromptQuality{Count: len(directions)}
+	for _, direction := range directions {
+		quality.RelevanceSum += direction.Relevance
+	}
+	quality.RelevanceSum = math.Round(quality.RelevanceSum*100) / 100
+	return quality, nil
+}
+
+// betterThan 以相关度之和为主、方向数量为次比较质量。
+func (q PromptQuality) betterThan(other PromptQuality) bool {
+	if q.RelevanceSum != other.RelevanceSum {
+		return q.RelevanceSum > other.RelevanceSum
+	}
+	return q.Count > other.Count
+}
+
+// 函数
+func buildSelfImprovePrompt(currentPrompt, concept string) string {
+	var builder strings.Builder
+	builder.WriteString("System role: You are a prompt engineer reviewing a prompt that asks a model to propose thought exploration directions.\n\n")
+	builder.WriteString("## Mission\n")
+	builder.WriteString("Identify the weaknesses of the prompt below (ambiguity, missing constraints, weak output guidance, wasted tokens) ")
+	builder.WriteString("and rewrite it so the model returns more, and more relevant, directions for the concept \"")
+	builder.WriteString(concept)
+	builder.WriteString("\".\n\n")
+	builder.WriteString("## Rules\n")
+	builder.WriteString("- Keep the output format the prompt requires (JSON directions with title, description, type and relevance).\n")
+	builder.WriteString("- The improved prompt must be complete and usable on its own.\n\n")
+	builder.WriteString("## Output format\n")
+	builder.WriteString("Respond with a single JSON object: {\"weaknesses\": [\"...\"], \"improved_prompt\": \"...\"}\n\n")
+	builder.WriteString("<prompt>\n")
+	builder.WriteString(currentPrompt)
+	builder.WriteString("\n</prompt>")
+	return builder.String()
+}
+
+// parseSelfImproveResponse 解析 {"weaknesses": [...], "improved_prompt": "..."}，允许外层有代码块或说明文字。
+func parseSelfImproveResponse(content string) (*selfImproveResponse, error) {
+	text, _ := stripCodeFences(content)
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start < 0 || end <= start {
+		return nil, errors.New("self-improve response does not contain a JSON object")
+	}
+
+	var parsed selfImproveResponse
+	if err := json.Unmarshal([]byte(text[start:end+1]), &parsed); err != nil {
+		return nil, fmt.Errorf("parse self-improve response: %w", err)
+	}
+	parsed.ImprovedPrompt = strings.TrimSpace(parsed.ImprovedPrompt)
+	if parsed.ImprovedPrompt == "" {
+		return nil, errors.New("self-improve response has no improved prompt")
+	}
+	weaknesses := uniqueStrings(parsed.Weaknesses)
+	if len(weaknesses) > maxPromptWeaknesses {
+		weaknesses = weaknesses[:maxPromptWeaknesses]
+	}
+	parsed.Weaknesses = weaknesses
+	return &parsed, nil
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const (
+	selfImproveCritique  = "```json\n{\"weaknesses\":[\"vague mission\",\"vague mission\"],\"improved_prompt\":\"IMPROVED PROMPT\"}\n```"
+	weakDirectionsJSON   = `{"directions":[{"type":"broad","title":"Overview","description":"General view","relevance":0.4}]}`
+	strongDirectionsJSON = `{"directions":[{"type":"deep","title":"Chemistry","description":"Cell chemistry","relevance":0.9},{"type":"lateral","title":"Recycling","description":"End of life","relevance":0.8}]}`
+)
+
+func TestImprovePromptAdoptsBetterPrompt(t *testing.T) {
+	var prompts []string
+	server, calls := newScriptedBackend(t, http.StatusOK, []string{selfImproveCritique, weakDirectionsJSON, strongDirectionsJSON}, &prompts)
+	llm := newRepairTestLLM(t, server.URL)
+
+	result, err := llm.ImprovePrompt("ORIGINAL PROMPT", "Batteries", []string{"goal: storage"})
+	if err != nil {
+		t.Fatalf("ImprovePrompt failed: %v", err)
+	}
+	if atomic.LoadInt32(calls) != 3 {
+		t.Fatalf("expected critique plus two evaluation calls, got %d", *calls)
+	}
+	if !strings.Contains(prompts[0], "ORIGINAL PROMPT") || !strings.Contains(prompts[0], "improved_prompt") {
+		t.Fatalf("expected meta-prompt to embed the current prompt, got %q", prompts[0])
+	}
+	if !strings.HasPrefix(prompts[1], "ORIGINAL PROMPT") || !strings.HasPrefix(prompts[2], "IMPROVED PROMPT") {
+		t.Fatalf("expected both prompts to be evaluated in order, got %q", prompts[1:])
+	}
+	if result.Before != (PromptQuality{Count: 1, RelevanceSum: 0.4}) || result.After != (PromptQuality{Count: 2, RelevanceSum: 1.7}) {
+		t.Fatalf("unexpected metrics: before %+v after %+v", result.Before, result.After)
+	}
+	if !result.Adopted || result.CacheKey != "improved_directions" || len(result.Weaknesses) != 1 {
+		t.Fatalf("expected improved prompt to be adopted, got %+v", result)
+	}
+	if cached, ok := llm.ImprovedPrompt("directions"); !ok || cached != "IMPROVED PROMPT" {
+		t.Fatalf("expected improved prompt to be cached, got %q (%v)", cached, ok)
+	}
+
+	if _, err := llm.GenerateThoughtDirections("batteries", nil); err != nil {
+		t.Fatalf("GenerateThoughtDirections failed: %v", err)
+	}
+	if _, err := llm.GenerateThoughtDirections("Solar panels", nil); err != nil {
+		t.Fatalf("GenerateThoughtDirections failed: %v", err)
+	}
+	if len(prompts) != 5 || !strings.HasPrefix(prompts[3], "IMPROVED PROMPT") || strings.HasPrefix(prompts[4], "IMPROVED PROMPT") {
+		t.Fatalf("expected the improved prompt to be used for its concept only, got %q", prompts[3:])
+	}
+}
+
+func TestSelfImprovePromptKeepsOriginalWhenNotBetter(t *testing.T) {
+	server, _ := newScriptedBackend(t, http.StatusOK, []string{selfImproveCritique, strongDirectionsJSON, weakDirectionsJSON}, nil)
+	llm := newRepairTestLLM(t, server.URL)
+
+	prompt, err := llm.SelfImprovePrompt("ORIGINAL PROMPT", "Batteries", nil)
+	if err != nil {
+		t.Fatalf("SelfImprovePrompt failed: %v", err)
+	}
+	if prompt != "ORIGINAL PROMPT" {
+		t.Fatalf("expected original prompt to win, got %q", prompt)
+	}
+	if _, ok := llm.ImprovedPrompt("directions"); ok {
+		t.Fatal("expected no improved prompt to be cached")
+	}
+}
+
+func TestImprovePromptRejectsInvalidInput(t *testing.T) {
+	if _, err := NewLLMOrchestrator("", "", "model").ImprovePrompt("prompt", "Batteries", nil); err == nil {
+		t.Fatal("expected an error without a remote backend")
+	}
+
+	server, _ := newScriptedBackend(t, http.StatusOK, []string{`{"weaknesses":["none"]}`}, nil)
+	llm := newRepairTestLLM(t, server.URL)
+	if _, err := llm.ImprovePrompt("prompt", " ", nil); err == nil {
+		t.Fatal("expected an error for an empty concept")
+	}
+	if _, err := llm.ImprovePrompt("prompt", "Batteries", nil); err == nil || !strings.Contains(err.Error(), "no improved prompt") {
+		t.Fatalf("expected a parse error for a response without an improved prompt, got %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+//Prompt Template Files(提示词模板文件)
+
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const (
+	PromptSourceBuiltin = "builtin"
+	PromptSourceFile    = "file"
+)
+
+// PromptTypes 是可从文件覆盖的提示词类型，文件名为 <类型>.yaml。
+var PromptTypes = []string{
+	"completion",
+	"directions",
+	"exploration",
+	"next_actions",
+	"reflection",
+	"recommend_direction",
+	"structure",
+	"summary",
+	"tagging",
+	"thought",
+	"title",
+}
+
+// 结构体
+// promptFile 是提示词模板文件的 YAML 结构，字段与 promptTemplate 一一对应。
+type promptFile struct {
+	Role         string   `yaml:"role"`
+	Mission      string   `yaml:"mission"`
+	Deliverables []string `yaml:"deliverables"`
+	Constraints  []string `yaml:"constraints"`
+	Reasoning    []string `yaml:"reasoning"`
+	StyleNotes   []string `yaml:"style_notes"`
+	Examples     []struct {
+		Name     string   `yaml:"name"`
+		Input    string   `yaml:"input"`
+		Output   string   `yaml:"output"`
+		Keywords []string `yaml:"keywords"`
+	} `yaml:"examples"`
+	OutputFormat []string `yaml:"output_format"`
+	Closing      string   `yaml:"closing"`
+}
+
+// PromptTemplateInfo 描述某类提示词当前使用的模板来源。
+type PromptTemplateInfo struct {
+	Type   string `json:"type"`
+	Source string `json:"source"`
+	Path   string `json:"path,omitempty"`
+}
+
+// promptLibrary 保存从目录加载的模板，由编排器副本共享，可在运行时重新加载。
+// improved 保存自我改进后胜出的完整提示词，键为 "improved_"+类型，重新加载模板时保留。
+type promptLibrary struct {
+	mutex     sync.RWMutex
+	dir       string
+	templates map[string]promptTemplate
+	paths     map[string]string
+	improved  map[string]improvedPrompt
+}
+
+// 方法
+// LoadPromptTemplates 从 dir 加载各类型的模板文件；文件缺失或无效的类型回退到内置模板（无效时记录警告）。
+// 仅当目录本身无法读取时返回错误，此时全部类型使用内置模板。
+func (llm *LLMOrchestrator) LoadPromptTemplates(dir string) error {
+	if llm == nil || llm.prompts == nil {
+		return nil
+	}
+	dir = strings.TrimSpace(dir)
+	templates := map[string]promptTemplate{}
+	paths := map[string]string{}
+
+	var err error
+	if dir != "" {
+		templates, paths, err = loadPromptDir(dir)
+	}
+
+	llm.prompts.mutex.Lock()
+	defer llm.prompts.mutex.Unlock()
+	llm.prompts.dir = dir
+	llm.prompts.templates = templates
+	llm.prompts.paths = paths
+	return err
+}
+
+// ReloadPromptTemplates 重新读取上次加载的目录（用于 SIGHUP）。
+func (llm *LLMOrchestrator) ReloadPromptTemplates() error {
+	if llm == nil || llm.prompts == nil {
+		return nil
+	}
+	llm.prompts.mutex.RLock()
+	dir := llm.prompts.dir
+	llm.prompts.mutex.RUnlock()
+	return llm.LoadPromptTemplates(dir)
+}
+
+// PromptTemplates 列出各类型提示词来自文件还是内置模板。
+func (llm *LLMOrchestrator) PromptTemplates() []PromptTemplateInfo {
+	infos := make([]PromptTemplateInfo, 0, len(PromptTypes))
+	for _, promptType := range PromptTypes {
+		info := PromptTemplateInfo{Type: promptType, Source: PromptSourceBuiltin}
+		if llm != nil && llm.prompts != nil {
+			llm.prompts.mutex.RLock()
+			if path, ok := llm.prompts.paths[promptType]; ok {
+				info.Source = PromptSourceFile
+				info.Path = path
+			}
+			llm.prompts.mutex.RUnlock()
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// promptTemplateFor 优先返回文件模板，否则返回内置模板。
+func (llm *LLMOrchestrator) promptTemplateFor(promptType string) promptTemplate {
+	if llm != nil && llm.prompts != nil {
+		llm.prompts.mutex.RLock()
+		tpl, ok := llm.prompts.templates[strings.ToLower(strings.TrimSpace(promptType))]
+		llm.prompts.mutex.RUnlock()
+		if ok {
+			return tpl
+		}
+	}
+	return builtinPromptTemplate(promptType)
+}
+
+// 函数
+func loadPromptDir(dir string) (map[string]promptTemplate, map[string]string, error) {
+	templates := map[string]promptTemplate{}
+	paths := map[string]string{}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return templates, paths, fmt.Errorf("read prompts dir: %w", err)
+	}
+	if !info.IsDir() {
+		return templates, paths, fmt.Errorf("prompts dir %s is not a directory", dir)
+	}
+
+	for _, promptType := range PromptTypes {
+		path, tpl, err := loadPromptFile(dir, promptType)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			utils.Warn("invalid prompt template file; using built-in template",
+				utils.KV("prompt_type", promptType), utils.KV("path", path), utils.KV("error", err))
+			continue
+		}
+		templates[promptType] = tpl
+		paths[promptType] = path
+	}
+	return templates, paths, nil
+}
+
+// loadPromptFile 读取 <类型>.yaml（或 .yml）并校验必填字段。
+func loadPromptFile(dir, promptType string) (string, promptTemplate, error) {
+	path := filepath.Join(dir, promptType+".yaml")
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		path = filepath.Join(dir, promptType+".yml")
+	}
+
+	var file promptFile
+	if err := utils.LoadYAML(path, &file); err != nil {
+		return path, promptTemplate{}, err
+	}
+	tpl, err := file.template()
+	return path, tpl, err
+}
+
+func (f promptFile) template() (promptTemplate, error) {
+	var missing []string
+	if strings.TrimSpace(f.Role) == "" {
+		missing = append(missing, "role")
+	}
+	if strings.TrimSpace(f.Mission) == "" {
+		missing = append(missing, "mission")
+	}
+	if len(uniqueStrings(f.Deliverables)) == 0 {
+		missing = append(missing, "deliverables")
+	}
+	if len(missing) > 0 {
+		return promptTemplate{}, fmt.Errorf("missing required fields: %s", strings.Join(missing, ", "))
+	}
+
+	tpl := promptTemplate{
+		role:         strings.TrimSpace(f.Role),
+		mission:      strings.TrimSpace(f.Mission),
+		deliverables: f.Deliverables,
+		constraints:  f.Constraints,
+		reasoning:    f.Reasoning,
+		styleNotes:   f.StyleNotes,
+		outputFormat: f.OutputFormat,
+		closing:      strings.TrimSpace(f.Closing),
+	}
+	for i, example := range f.Examples {
+		if strings.TrimSpace(example.Input) == "" || strings.TrimSpace(example.Output) == "" {
+			return promptTemplate{}, fmt.Errorf("example %d requires input and output", i+1)
+		}
+		tpl.examples = append(tpl.examples, fewShotExample{name: example.Name, input: example.Input, output: example.Output, keywords: example.Keywords})
+	}
+	return tpl, nil
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const customDirectionsTemplate = `role: You are a terse brainstorming partner.
+mission: List angles on '{{concept}}' for a workshop.
+deliverables:
+  - One line per angle.
+constraints:
+  - Never exceed five angles.
+examples:
+  - name: Coffee
+    input: "Concept: Coffee"
+    output: "- sourcing\n- roasting"
+closing: Ask one follow-up question.
+`
+
+func writePromptFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+		t.Fatalf("write prompt file: %v", err)
+	}
+}
+
+func TestLoadPromptTemplatesOverridesBuildPrompt(t *testing.T) {
+	dir := t.TempDir()
+	writePromptFile(t, dir, "directions.yaml", customDirectionsTemplate)
+	writePromptFile(t, dir, "exploration.yaml", "role: Missing mission and deliverables.\n")
+
+	llm := NewLLMOrchestrator("", "", "")
+	builtin := llm.BuildPrompt("Tea", nil, "exploration")
+	if err := llm.LoadPromptTemplates(dir); err != nil {
+		t.Fatalf("LoadPromptTemplates failed: %v", err)
+	}
+
+	prompt := llm.BuildPrompt("Tea", nil, "directions")
+	for _, want := range []string{"You are a terse brainstorming partner.", "List angles on 'Tea' for a workshop.", "Never exceed five angles.", "Ask one follow-up question."} {
+		if !strings.Contains(prompt, want) {
+			t.Fatalf("expected prompt to contain %q:\n%s", want, prompt)
+		}
+	}
+	if strings.Contains(prompt, "learning-path architect") {
+		t.Fatalf("expected built-in directions template to be replaced:\n%s", prompt)
+	}
+	if got := llm.BuildPrompt("Tea", nil, "exploration"); got != builtin {
+		t.Fatalf("expected invalid exploration file to fall back to the built-in template")
+	}
+
+	sources := map[string]string{}
+	for _, info := range llm.PromptTemplates() {
+		sources[info.Type] = info.Source
+	}
+	if sources["directions"] != PromptSourceFile || sources["exploration"] != PromptSourceBuiltin || sources["reflection"] != PromptSourceBuiltin {
+		t.Fatalf("unexpected template sources %v", sources)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "directions.yaml")); err != nil {
+		t.Fatalf("remove prompt file: %v", err)
+	}
+	if err := llm.ReloadPromptTemplates(); err != nil {
+		t.Fatalf("ReloadPromptTemplates failed: %v", err)
+	}
+	if prompt := llm.BuildPrompt("Tea", nil, "directions"); !strings.Contains(prompt, "learning-path architect") {
+		t.Fatalf("expected reload to restore the built-in template:\n%s", prompt)
+	}
+}
+
+func TestLoadPromptTemplatesMissingDirFallsBack(t *testing.T) {
+	llm := NewLLMOrchestrator("", "", "")
+	if err := llm.LoadPromptTemplates(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Fatalf("expected missing directory to be reported")
+	}
+	if prompt := llm.BuildPrompt("Tea", nil, "directions"); !strings.Contains(prompt, "learning-path architect") {
+		t.Fatalf("expected built-in template after failed load:\n%s", prompt)
+	}
+}
@@ -0,0 +1,98 @@
+//Thought Reactions(思维表情反馈)
+
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 结构体
+// ThoughtReactions 是思维当前的表情反馈计数
+type ThoughtReactions struct {
+	ThoughtID string         `json:"thought_id"`
+	Reactions map[string]int `json:"reactions"`
+}
+
+// 方法
+// AddReaction 为思维添加一次 emoji 反馈；反馈不计入撤销历史。
+func (sm *SessionManager) AddReaction(sessionID, thoughtID, emoji string) error {
+	emoji = strings.TrimSpace(emoji)
+	if err := utils.ValidateEmoji(emoji); err != nil {
+		return err
+	}
+	return sm.updateReactions(sessionID, thoughtID, func(thought *models.Thought) error {
+		if _, ok := thought.Reactions[emoji]; !ok && len(thought.Reactions) >= utils.MaxReactionTypes {
+			return utils.ValidationError("too many reaction types")
+		}
+		thought.AddReaction(emoji)
+		return nil
+	})
+}
+
+// RemoveReaction 撤回一次 emoji 反馈，次数归零时移除该表情。
+func (sm *SessionManager) RemoveReaction(sessionID, thoughtID, emoji string) error {
+	emoji = strings.TrimSpace(emoji)
+	if err := utils.ValidateEmoji(emoji); err != nil {
+		return err
+	}
+	return sm.updateReactions(sessionID, thoughtID, func(thought *models.Thought) error {
+		if !thought.RemoveReaction(emoji) {
+			return utils.ValidationError("thought has no such reaction")
+		}
+		return nil
+	})
+}
+
+// Reactions 返回思维当前表情反馈计数的副本。
+func (sm *SessionManager) Reactions(sessionID, thoughtID string) (*ThoughtReactions, error) {
+	sm.reactionMutex.Lock()
+	defer sm.reactionMutex.Unlock()
+
+	thought, _, err := sm.findReactionTarget(sessionID, thoughtID)
+	if err != nil {
+		return nil, err
+	}
+	reactions := make(map[string]int, len(thought.Reactions))
+	for emoji, count := range thought.Reactions {
+		reactions[emoji] = count
+	}
+	return &ThoughtReactions{ThoughtID: thought.ID, Reactions: reactions}, nil
+}
+
+// updateReactions 串行化反馈计数的读改写，避免多个用户同时反馈时丢失更新。
+func (sm *SessionManager) updateReactions(sessionID, thoughtID string, apply func(thought *models.Thought) error) error {
+	sm.reactionMutex.Lock()
+	defer sm.reactionMutex.Unlock()
+
+	thought, session, err := sm.findReactionTarget(sessionID, thoughtID)
+	if err != nil {
+		return err
+	}
+	if err := apply(thought); err != nil {
+		return err
+	}
+	if err := utils.ValidateReactions(thought.Reactions); err != nil {
+		return err
+	}
+	return sm.UpdateSession(session)
+}
+
+func (sm *SessionManager) findReactionTarget(sessionID, thoughtID string) (*models.Thought, *models.Session, error) {
+	if thoughtID == "" {
+		return nil, nil, appErrors.ErrInvalidRequest
+	}
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	thought, _ := session.FindThought(thoughtID)
+	if thought == nil {
+		return nil, nil, fmt.Errorf("%w: %s", appErrors.ErrThoughtNotFound, thoughtID)
+	}
+	return thought, session, nil
+}
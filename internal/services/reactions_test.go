@@ -0,0 +1,105 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+	"WideMindsMCP/internal/utils"
+)
+
+func newReactionSession(t *testing.T) (*SessionManager, *models.Session, *models.Thought) {
+	t.Helper()
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	session, err := manager.CreateSession("user", "Energy")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	thought := models.NewThought("Batteries", session.ID, models.Direction{Type: models.Deep, Title: "Storage"})
+	if err := manager.AddThoughtToSession(session.ID, thought); err != nil {
+		t.Fatalf("AddThoughtToSession failed: %v", err)
+	}
+	return manager, session, thought
+}
+
+func TestAddAndRemoveReactions(t *testing.T) {
+	manager, session, thought := newReactionSession(t)
+
+	for _, emoji := range []string{"👍", "👍", "💡", "❓", "👍🏽", "🇯🇵", "1️⃣", "👩‍🔬"} {
+		if err := manager.AddReaction(session.ID, thought.ID, emoji); err != nil {
+			t.Fatalf("AddReaction(%q) failed: %v", emoji, err)
+		}
+	}
+	if err := manager.RemoveReaction(session.ID, thought.ID, "💡"); err != nil {
+		t.Fatalf("RemoveReaction failed: %v", err)
+	}
+
+	reactions, err := manager.Reactions(session.ID, thought.ID)
+	if err != nil {
+		t.Fatalf("Reactions failed: %v", err)
+	}
+	if reactions.Reactions["👍"] != 2 || reactions.Reactions["❓"] != 1 || len(reactions.Reactions) != 6 {
+		t.Fatalf("unexpected reactions %+v", reactions.Reactions)
+	}
+	if _, ok := reactions.Reactions["💡"]; ok {
+		t.Fatalf("expected the withdrawn reaction to be removed, got %+v", reactions.Reactions)
+	}
+
+	if err := manager.RemoveReaction(session.ID, thought.ID, "💡"); !errors.Is(err, appErrors.ErrInvalidRequest) {
+		t.Fatalf("expected an error removing a missing reaction, got %v", err)
+	}
+	if err := manager.AddReaction(session.ID, "missing", "👍"); !errors.Is(err, appErrors.ErrThoughtNotFound) {
+		t.Fatalf("expected ErrThoughtNotFound, got %v", err)
+	}
+}
+
+func TestAddReactionValidatesEmojiAndLimit(t *testing.T) {
+	manager, session, thought := newReactionSession(t)
+
+	for _, emoji := range []string{"", "ok", "👍a", "<script>", "👍👍👍👍👍👍👍👍👍👍👍"} {
+		if err := manager.AddReaction(session.ID, thought.ID, emoji); !errors.Is(err, appErrors.ErrInvalidRequest) {
+			t.Fatalf("expected %q to be rejected, got %v", emoji, err)
+		}
+	}
+
+	emojis := []string{"😀", "😁", "😂", "😃", "😄", "😅", "😆", "😇", "😈", "😉"}
+	for _, emoji := range emojis {
+		if err := manager.AddReaction(session.ID, thought.ID, emoji); err != nil {
+			t.Fatalf("AddReaction(%q) failed: %v", emoji, err)
+		}
+	}
+	if err := manager.AddReaction(session.ID, thought.ID, "😊"); !errors.Is(err, appErrors.ErrInvalidRequest) {
+		t.Fatalf("expected the 11th reaction type to be rejected, got %v", err)
+	}
+	if err := manager.AddReaction(session.ID, thought.ID, emojis[0]); err != nil {
+		t.Fatalf("expected existing reaction types to keep counting, got %v", err)
+	}
+
+	if err := utils.ValidateReactions(map[string]int{"👍": -1}); !errors.Is(err, appErrors.ErrInvalidRequest) {
+		t.Fatalf("expected negative counts to be rejected, got %v", err)
+	}
+}
+
+func TestConcurrentReactionsAreNotLost(t *testing.T) {
+	manager, session, thought := newReactionSession(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := manager.AddReaction(session.ID, thought.ID, "👍"); err != nil {
+				t.Errorf("AddReaction failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	reactions, err := manager.Reactions(session.ID, thought.ID)
+	if err != nil || reactions.Reactions["👍"] != 20 {
+		t.Fatalf("expected 20 reactions, got %+v (%v)", reactions, err)
+	}
+}
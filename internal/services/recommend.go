@@ -0,0 +1,120 @@
+//Direction Recommendation(方向推荐)
+
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const MaxRecommendDirections = 20
+
+// 方法
+// RecommendDirection 请求 LLM 结合用户目标与会话历史从候选方向中选出最值得先探索的一个，
+// 返回所选方向及理由；LLM 不可用或响应无法解析时回退为相关度最高的方向。
+func (llm *LLMOrchestrator) RecommendDirection(directions []models.Direction, profile *models.UserProfile, session *models.Session) (*models.Direction, string, error) {
+	if len(directions) == 0 {
+		return nil, "", utils.ValidationError("directions are required")
+	}
+
+	concept := "the current topic"
+	if session != nil && session.RootThought != nil {
+		concept = session.RootThought.Content
+	}
+	prompt := llm.BuildPrompt(concept, buildRecommendContext(directions, profile, session), "recommend_direction")
+
+	if llm.hasRemoteBackend() {
+		resp, err := llm.CallLLM(&LLMRequest{
+			Prompt:      prompt,
+			Temperature: float64Ptr(0.3),
+			MaxTokens:   512,
+		})
+		if errors.Is(err, appErrors.ErrBudgetExceeded) {
+			return nil, "", err
+		} else if err != nil {
+			utils.Warn("LLM call failed while recommending a direction", utils.KV("error", err))
+		} else if resp != nil {
+			if index, rationale, parseErr := parseRecommendation(resp.Content, len(directions)); parseErr != nil {
+				utils.Warn("failed to parse LLM direction recommendation", utils.KV("error", parseErr))
+			} else {
+				recommended := directions[index]
+				return &recommended, rationale, nil
+			}
+		}
+	}
+
+	index := mostRelevantDirection(directions)
+	recommended := directions[index]
+	return &recommended, fmt.Sprintf("Highest relevance score (%.2f) among %d candidate directions.", recommended.Relevance, len(directions)), nil
+}
+
+// 函数
+func buildRecommendContext(directions []models.Direction, profile *models.UserProfile, session *models.Session) []models.ContextEntry {
+	entries := make([]models.ContextEntry, 0, len(directions)+4)
+	if profile != nil {
+		for _, goal := range profile.Goals {
+			entries = append(entries, models.NewContextEntry(models.ContextGoal, goal))
+		}
+		for _, preference := range profile.Preferences {
+			entries = append(entries, models.NewContextEntry(models.ContextPreference, preference))
+		}
+		for _, background := range profile.Background {
+			entries = append(entries, models.NewContextEntry(models.ContextBackground, background))
+		}
+	}
+	if session != nil {
+		if meta := session.GetMetadata(); len(meta.Directions) > 0 {
+			entries = append(entries, models.NewContextEntry(models.ContextHistory, "explored directions: "+strings.Join(meta.Directions, ", ")))
+		}
+	}
+	for i, direction := range directions {
+		entries = append(entries, models.NewContextEntry(models.ContextNote, fmt.Sprintf(
+			"option %d: [%s] %s — %s (relevance %.2f)",
+			i+1, direction.Type, direction.Title, truncate(direction.Description, 160), direction.Relevance,
+		)))
+	}
+	return entries
+}
+
+// parseRecommendation 解析 {"option":N,"rationale":"..."}，N 从 1 开始；返回从 0 开始的下标。
+func parseRecommendation(content string, count int) (int, string, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end <= start {
+		return 0, "", errors.New("recommendation response does not contain a JSON object")
+	}
+
+	var raw struct {
+		Option    int    `json:"option"`
+		Rationale string `json:"rationale"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &raw); err != nil {
+		return 0, "", fmt.Errorf("parse llm recommendation: %w", err)
+	}
+	if raw.Option < 1 || raw.Option > count {
+		return 0, "", fmt.Errorf("recommended option %d is out of range", raw.Option)
+	}
+	rationale := strings.TrimSpace(raw.Rationale)
+	if rationale == "" {
+		return 0, "", errors.New("recommendation rationale is empty")
+	}
+	return raw.Option - 1, rationale, nil
+}
+
+// mostRelevantDirection 返回相关度最高的方向下标，并列时取靠前者。
+func mostRelevantDirection(directions []models.Direction) int {
+	best := 0
+	for i := 1; i < len(directions); i++ {
+		if directions[i].Relevance > directions[best].Relevance {
+			best = i
+		}
+	}
+	return best
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+)
+
+func recommendCandidates() []models.Direction {
+	return []models.Direction{
+		{Type: models.Broad, Title: "Markets", Relevance: 0.4},
+		{Type: models.Deep, Title: "Chemistry", Relevance: 0.9},
+		{Type: models.Critical, Title: "Risks", Relevance: 0.9},
+	}
+}
+
+func TestRecommendDirectionFallsBackToHighestRelevance(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	expander := NewThoughtExpander(NewScriptedLLM(), manager)
+
+	session, err := manager.CreateSession("user", "Batteries")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	recommended, rationale, err := expander.RecommendDirection(recommendCandidates(), nil, session)
+	if err != nil {
+		t.Fatalf("RecommendDirection failed: %v", err)
+	}
+	if recommended.Title != "Chemistry" || rationale == "" {
+		t.Fatalf("expected first highest-relevance direction, got %q (%q)", recommended.Title, rationale)
+	}
+
+	if _, _, err := expander.RecommendDirection(nil, nil, session); err == nil {
+		t.Fatalf("expected empty directions to be rejected")
+	}
+}
+
+func TestRecommendDirectionUsesLLMChoice(t *testing.T) {
+	var prompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		prompt = payload.Messages[len(payload.Messages)-1].Content
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{
+				"content": `{"option":3,"rationale":"Risks match the goal of a safety review."}`,
+			}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	llm := NewLLMOrchestrator("key", server.URL, "model")
+	session := models.NewSession("user", "Batteries")
+	session.ContextEntries = []models.ContextEntry{models.NewContextEntry(models.ContextGoal, "prepare a safety review")}
+
+	recommended, rationale, err := llm.RecommendDirection(recommendCandidates(), models.NewUserProfile(session), session)
+	if err != nil {
+		t.Fatalf("RecommendDirection failed: %v", err)
+	}
+	if recommended.Title != "Risks" || !strings.Contains(rationale, "safety review") {
+		t.Fatalf("expected LLM choice, got %q (%q)", recommended.Title, rationale)
+	}
+	if !strings.Contains(prompt, "prepare a safety review") || !strings.Contains(prompt, "option 3: [critical] Risks") {
+		t.Fatalf("expected prompt to include goals and numbered options:\n%s", prompt)
+	}
+}
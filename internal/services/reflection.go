@@ -0,0 +1,237 @@
+//Session Reflection(会话反思)
+
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const (
+	maxReflectionHotSpots = 3
+	maxReflectionLeaves   = 5
+	maxReflectionItems    = 8
+)
+
+var reflectionDirectionTypes = []models.DirectionType{models.Broad, models.Deep, models.Lateral, models.Critical}
+
+// 结构体
+// ReflectionReport 是对会话探索情况的自我评估。
+type ReflectionReport struct {
+	Strengths           []string `json:"strengths"`
+	Gaps                []string `json:"gaps"`
+	UnansweredQuestions []string `json:"unanswered_questions"`
+	SuggestedNextSteps  []string `json:"suggested_next_steps"`
+}
+
+// sessionReflection 汇总生成反思所需的会话结构信息。
+type sessionReflection struct {
+	meta       *models.SessionMetadata
+	typeCounts map[models.DirectionType]int
+	unexplored []models.DirectionType
+	hotSpots   []*models.Thought
+	leaves     []*models.Thought
+}
+
+// 方法
+// ReflectOnSession 请求 LLM 结合会话统计、方向分布、热点与叶子思维评估会话缺口，
+// LLM 不可用或响应无法解析时回退到基于结构的启发式报告。
+func (llm *LLMOrchestrator) ReflectOnSession(session *models.Session) (*ReflectionReport, error) {
+	if session == nil || session.RootThought == nil {
+		return nil, errors.New("session has no thoughts")
+	}
+
+	reflection := analyzeSession(session)
+	prompt := llm.BuildPrompt(session.RootThought.Content, buildReflectionContext(session, reflection), "reflection")
+
+	if llm.hasRemoteBackend() {
+		resp, err := llm.CallLLM(&LLMRequest{
+			Prompt:      prompt,
+			Temperature: float64Ptr(0.4),
+			MaxTokens:   768,
+		})
+		if errors.Is(err, appErrors.ErrBudgetExceeded) {
+			return nil, err
+		} else if err != nil {
+			utils.Warn("LLM call failed while reflecting on a session", utils.KV("error", err))
+		} else if resp != nil {
+			if report, parseErr := parseReflectionReport(resp.Content); parseErr != nil {
+				utils.Warn("failed to parse LLM reflection response", utils.KV("error", parseErr))
+			} else {
+				return report, nil
+			}
+		}
+	}
+
+	return fallbackReflection(session, reflection), nil
+}
+
+// 函数
+// analyzeSession 按广度优先顺序统计方向类型分布，并找出子节点最多的热点与未展开的叶子思维。
+func analyzeSession(session *models.Session) *sessionReflection {
+	reflection := &sessionReflection{
+		meta:       session.GetMetadata(),
+		typeCounts: make(map[models.DirectionType]int, len(reflectionDirectionTypes)),
+	}
+
+	var branches []*models.Thought
+	queue := []*models.Thought{session.RootThought}
+	for len(queue) > 0 {
+		thought := queue[0]
+		queue = queue[1:]
+		if thought == nil {
+			continue
+		}
+		if !thought.IsRoot() {
+			reflection.typeCounts[thought.Direction.Type]++
+			if len(thought.Children) == 0 && len(reflection.leaves) < maxReflectionLeaves {
+				reflection.leaves = append(reflection.leaves, thought)
+			}
+		}
+		if len(thought.Children) > 0 {
+			branches = append(branches, thought)
+		}
+		queue = append(queue, thought.Children...)
+	}
+
+	for _, dirType := range reflectionDirectionTypes {
+		if reflection.typeCounts[dirType] == 0 {
+			reflection.unexplored = append(reflection.unexplored, dirType)
+		}
+	}
+
+	sort.SliceStable(branches, func(i, j int) bool {
+		return len(branches[i].Children) > len(branches[j].Children)
+	})
+	if len(branches) > maxReflectionHotSpots {
+		branches = branches[:maxReflectionHotSpots]
+	}
+	reflection.hotSpots = branches
+	return reflection
+}
+
+func buildReflectionContext(session *models.Session, reflection *sessionReflection) []models.ContextEntry {
+	session.EnsureContextEntries()
+	entries := make([]models.ContextEntry, 0, len(session.ContextEntries)+len(reflection.hotSpots)+len(reflection.leaves)+4)
+	for _, entry := range session.ContextEntries {
+		if entry.Kind == models.ContextGoal || entry.Kind == models.ContextBackground {
+			entries = append(entries, entry)
+		}
+	}
+
+	meta := reflection.meta
+	entries = append(entries, models.NewContextEntry(models.ContextNote, fmt.Sprintf(
+		"session stats: %d thoughts (%d generated, %d user), max depth %d",
+		meta.TotalThoughts, meta.LLMThoughts, meta.UserThoughts, meta.MaxDepth,
+	)))
+
+	distribution := make([]string, 0, len(reflectionDirectionTypes))
+	for _, dirType := range reflectionDirectionTypes {
+		distribution = append(distribution, fmt.Sprintf("%s=%d", dirType, reflection.typeCounts[dirType]))
+	}
+	entries = append(entries, models.NewContextEntry(models.ContextNote, "direction distribution: "+strings.Join(distribution, ", ")))
+	if len(reflection.unexplored) > 0 {
+		entries = append(entries, models.NewContextEntry(models.ContextNote, "unexplored direction types: "+joinDirectionTypes(reflection.unexplored)))
+	}
+
+	for _, thought := range reflection.hotSpots {
+		entries = append(entries, models.NewContextEntry(models.ContextNote, fmt.Sprintf(
+			"hot spot: %s (%d children)", truncate(thought.Content, 80), len(thought.Children),
+		)))
+	}
+	for _, thought := range reflection.leaves {
+		entries = append(entries, models.NewContextEntry(models.ContextNote, fmt.Sprintf(
+			"leaf without elaboration: %s (depth %d)", truncate(thought.Content, 80), thought.Depth,
+		)))
+	}
+	return entries
+}
+
+func parseReflectionReport(content string) (*ReflectionReport, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end <= start {
+		return nil, errors.New("reflection response does not contain a JSON object")
+	}
+
+	var report ReflectionReport
+	if err := json.Unmarshal([]byte(content[start:end+1]), &report); err != nil {
+		return nil, fmt.Errorf("parse llm reflection: %w", err)
+	}
+	report.Strengths = cleanReflectionItems(report.Strengths)
+	report.Gaps = cleanReflectionItems(report.Gaps)
+	report.UnansweredQuestions = cleanReflectionItems(report.UnansweredQuestions)
+	report.SuggestedNextSteps = cleanReflectionItems(report.SuggestedNextSteps)
+	if len(report.Gaps) == 0 && len(report.SuggestedNextSteps) == 0 {
+		return nil, errors.New("reflection has no gaps or next steps")
+	}
+	return &report, nil
+}
+
+// cleanReflectionItems 去除空项并限制条数，结果始终非 nil 以便序列化为空数组。
+func cleanReflectionItems(items []string) []string {
+	cleaned := make([]string, 0, len(items))
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			cleaned = append(cleaned, item)
+		}
+		if len(cleaned) == maxReflectionItems {
+			break
+		}
+	}
+	return cleaned
+}
+
+func fallbackReflection(session *models.Session, reflection *sessionReflection) *ReflectionReport {
+	report := &ReflectionReport{
+		Strengths:           []string{},
+		Gaps:                []string{},
+		UnansweredQuestions: []string{},
+		SuggestedNextSteps:  []string{},
+	}
+	root := session.RootThought.Content
+
+	for _, dirType := range reflectionDirectionTypes {
+		if count := reflection.typeCounts[dirType]; count > 0 {
+			report.Strengths = append(report.Strengths, fmt.Sprintf("%d %s thoughts already explore %s.", count, dirType, root))
+		}
+	}
+	for _, thought := range reflection.hotSpots {
+		if thought.IsRoot() {
+			continue
+		}
+		report.Strengths = append(report.Strengths, fmt.Sprintf("%q is well developed with %d follow-ups.", truncate(thought.Content, 80), len(thought.Children)))
+	}
+
+	for _, dirType := range reflection.unexplored {
+		report.Gaps = append(report.Gaps, fmt.Sprintf("No %s direction has been explored yet.", dirType))
+		report.SuggestedNextSteps = append(report.SuggestedNextSteps, fmt.Sprintf("Expand %s with a %s direction.", root, dirType))
+	}
+	for _, thought := range reflection.leaves {
+		content := truncate(thought.Content, 80)
+		report.UnansweredQuestions = append(report.UnansweredQuestions, fmt.Sprintf("What follows from %q?", content))
+		if len(report.SuggestedNextSteps) < maxReflectionItems {
+			report.SuggestedNextSteps = append(report.SuggestedNextSteps, fmt.Sprintf("Elaborate %q with a deeper thought.", content))
+		}
+	}
+	if reflection.meta.TotalThoughts <= 1 {
+		report.Gaps = append(report.Gaps, "The session has no thoughts beyond the root concept.")
+	}
+	return report
+}
+
+func joinDirectionTypes(types []models.DirectionType) string {
+	names := make([]string, len(types))
+	for i, dirType := range types {
+		names[i] = string(dirType)
+	}
+	return strings.Join(names, ", ")
+}
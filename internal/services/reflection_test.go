@@ -0,0 +1,84 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+)
+
+func reflectionSession() *models.Session {
+	session := models.NewSession("user", "Batteries")
+	chemistry := models.NewThought("Chemistry", session.ID, models.Direction{Type: models.Broad, Title: "Chemistry"})
+	session.RootThought.AddChild(chemistry)
+	chemistry.AddChild(models.NewThought("Solid-state", session.ID, models.Direction{Type: models.Deep, Title: "Solid-state"}))
+	chemistry.AddChild(models.NewThought("Sodium-ion", session.ID, models.Direction{Type: models.Deep, Title: "Sodium-ion"}))
+	return session
+}
+
+func TestReflectOnSessionFallsBackToStructure(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	session := reflectionSession()
+	if err := manager.store.Save(session); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	expander := NewThoughtExpander(NewScriptedLLM(), manager)
+
+	report, err := expander.ReflectOnSession(session.ID)
+	if err != nil {
+		t.Fatalf("ReflectOnSession failed: %v", err)
+	}
+	gaps := strings.Join(report.Gaps, " ")
+	if !strings.Contains(gaps, "lateral") || !strings.Contains(gaps, "critical") || strings.Contains(gaps, "deep") {
+		t.Fatalf("expected unexplored direction types as gaps, got %v", report.Gaps)
+	}
+	questions := strings.Join(report.UnansweredQuestions, " ")
+	if len(report.UnansweredQuestions) != 2 || !strings.Contains(questions, "Solid-state") || !strings.Contains(questions, "Sodium-ion") {
+		t.Fatalf("expected a question per unelaborated leaf, got %v", report.UnansweredQuestions)
+	}
+	if len(report.Strengths) == 0 || len(report.SuggestedNextSteps) == 0 {
+		t.Fatalf("expected strengths and next steps, got %+v", report)
+	}
+
+	if _, err := expander.ReflectOnSession("missing"); err == nil {
+		t.Fatalf("expected missing session to fail")
+	}
+}
+
+func TestReflectOnSessionUsesLLMReport(t *testing.T) {
+	var prompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		prompt = payload.Messages[len(payload.Messages)-1].Content
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{
+				"content": `{"strengths":["Chemistry is well covered."],"gaps":["No critical view on safety."," "],"unanswered_questions":["Is sodium-ion cheaper?"],"suggested_next_steps":["Add a critical direction on safety."]}`,
+			}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	llm := NewLLMOrchestrator("key", server.URL, "model")
+	report, err := llm.ReflectOnSession(reflectionSession())
+	if err != nil {
+		t.Fatalf("ReflectOnSession failed: %v", err)
+	}
+	if len(report.Gaps) != 1 || report.Gaps[0] != "No critical view on safety." || report.UnansweredQuestions[0] != "Is sodium-ion cheaper?" {
+		t.Fatalf("expected LLM report, got %+v", report)
+	}
+	for _, expected := range []string{"direction distribution: broad=1, deep=2, lateral=0, critical=0", "unexplored direction types: lateral, critical", "hot spot: Chemistry (2 children)", "leaf without elaboration: Solid-state"} {
+		if !strings.Contains(prompt, expected) {
+			t.Fatalf("expected prompt to include %q:\n%s", expected, prompt)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+//LLM Response Cache(LLM响应缓存)
+
+package services
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const (
+	DefaultResponseCacheSize = 256
+	DefaultResponseCacheTTL  = 10 * time.Minute
+)
+
+// 结构体
+// ResponseCacheStats 汇总响应缓存的命中情况。
+type ResponseCacheStats struct {
+	Enabled  bool          `json:"enabled"`
+	Entries  int           `json:"entries"`
+	Capacity int           `json:"capacity"`
+	TTL      time.Duration `json:"ttl"`
+	Hits     uint64        `json:"hits"`
+	Misses   uint64        `json:"misses"`
+}
+
+// LLMStats 是编排器对外暴露的运行统计。
+type LLMStats struct {
+	Circuit     utils.CircuitStatus     `json:"circuit"`
+	Cache       ResponseCacheStats      `json:"cache"`
+	RateLimit   utils.TokenBucketStatus `json:"rate_limit"`
+	Concurrency utils.SemaphoreStatus   `json:"concurrency"`
+}
+
+// responseCache 是按最近使用淘汰、带过期时间的 LLM 响应缓存。
+type responseCache struct {
+	mutex    sync.Mutex
+	capacity int
+	ttl      time.Duration
+	order    *list.List
+	entries  map[string]*list.Element
+	hits     uint64
+	misses   uint64
+	now      func() time.Time
+}
+
+type responseCacheEntry struct {
+	key       string
+	response  LLMResponse
+	expiresAt time.Time
+}
+
+// 函数
+func newResponseCache(capacity int, ttl time.Duration) *responseCache {
+	return &responseCache{
+		capacity: capacity,
+		ttl:      ttl,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+// responseCacheKey 以模型、完整提示词、温度与最大令牌数的 SHA-256 作为缓存键。
+func responseCacheKey(model string, call *llmCall) string {
+	hash := sha256.New()
+	for _, part := range []string{
+		model,
+		call.systemPrompt,
+		call.userContent,
+		strconv.FormatFloat(call.temperature, 'g', -1, 64),
+		strconv.Itoa(call.maxTokens),
+	} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	if call.tool != nil {
+		hash.Write([]byte("tool:" + call.tool.Name))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (c *responseCache) get(key string) (*LLMResponse, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[key]
+	if ok {
+		entry := element.Value.(*responseCacheEntry)
+		if c.now().Before(entry.expiresAt) {
+			c.order.MoveToFront(element)
+			c.hits++
+			resp := entry.response
+			return &resp, true
+		}
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+	c.misses++
+	return nil, false
+}
+
+func (c *responseCache) put(key string, resp *LLMResponse) {
+	if resp == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	expiresAt := c.now().Add(c.ttl)
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*responseCacheEntry)
+		entry.response = *resp
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&responseCacheEntry{key: key, response: *resp, expiresAt: expiresAt})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*responseCacheEntry).key)
+	}
+}
+
+func (c *responseCache) stats() ResponseCacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return ResponseCacheStats{
+		Enabled:  true,
+		Entries:  c.order.Len(),
+		Capacity: c.capacity,
+		TTL:      c.ttl,
+		Hits:     c.hits,
+		Misses:   c.misses,
+	}
+}
+
+// 方法
+// SetResponseCache 启用容量为 size、有效期为 ttl 的响应缓存；size 非正时关闭缓存，ttl 非正时使用默认值。
+func (llm *LLMOrchestrator) SetResponseCache(size int, ttl time.Duration) {
+	if llm == nil {
+		return
+	}
+	if size <= 0 {
+		llm.cache = nil
+		return
+	}
+	if ttl <= 0 {
+		ttl = DefaultResponseCacheTTL
+	}
+	llm.cache = newResponseCache(size, ttl)
+}
+
+// WithoutCache 返回一个编排器副本，其调用既不读取也不写入响应缓存。
+func (llm *LLMOrchestrator) WithoutCache() *LLMOrchestrator {
+	if llm == nil {
+		return nil
+	}
+	clone := *llm
+	clone.noCache = true
+	return &clone
+}
+
+// Stats 返回熔断器状态、响应缓存的命中统计与客户端限流的饱和度。
+func (llm *LLMOrchestrator) Stats() LLMStats {
+	stats := LLMStats{Circuit: llm.CircuitStatus(), RateLimit: llm.RateLimitStatus(), Concurrency: llm.ConcurrencyStatus()}
+	if llm != nil && llm.cache != nil {
+		stats.Cache = llm.cache.stats()
+	}
+	return stats
+}
+
+// cachedResponse 查找可复用的响应；命中时返回标记为 Cached 的副本。
+func (llm *LLMOrchestrator) cachedResponse(req *LLMRequest, call *llmCall) (string, *LLMResponse) {
+	if llm.cache == nil || llm.noCache || req.NoCache {
+		return "", nil
+	}
+
+	key := responseCacheKey(llm.reportedModel(), call)
+	resp, ok := llm.cache.get(key)
+	if !ok {
+		return key, nil
+	}
+	resp.Cached = true
+	resp.Attempts = 0
+	return key, resp
+}
+
+// storeResponse 缓存成功的响应；key 为空表示本次调用不使用缓存。
+// 响应由备用模型给出时以该模型重新计算键，主模型的后续请求不会命中备用模型的回答。
+func (llm *LLMOrchestrator) storeResponse(key string, servedBy *LLMOrchestrator, call *llmCall, resp *LLMResponse) {
+	if key == "" || llm.cache == nil {
+		return
+	}
+	if servedBy != nil && servedBy != llm {
+		key = responseCacheKey(servedBy.reportedModel(), call)
+	}
+	llm.cache.put(key, resp)
+}
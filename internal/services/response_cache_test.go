@@ -0,0 +1,120 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"WideMindsMCP/internal/storage"
+)
+
+func newCountingChatServer(t *testing.T, requests *int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"content": "ok"}}},
+			"usage":   map[string]int{"prompt_tokens": 6, "completion_tokens": 4, "total_tokens": 10},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCallLLMServesIdenticalRequestsFromCache(t *testing.T) {
+	var requests int32
+	server := newCountingChatServer(t, &requests)
+
+	orchestrator := NewLLMOrchestrator("key", server.URL, "model")
+	orchestrator.SetResponseCache(8, time.Minute)
+	orchestrator.SetBudgetStore(storage.NewInMemoryBudgetStore(), 0)
+	alice := orchestrator.ForUser("alice")
+
+	first, err := alice.CallLLM(&LLMRequest{Prompt: "hello", Temperature: float64Ptr(0.5), MaxTokens: 64})
+	if err != nil {
+		t.Fatalf("first call failed: %v", err)
+	}
+	second, err := alice.CallLLM(&LLMRequest{Prompt: "hello", Temperature: float64Ptr(0.5), MaxTokens: 64})
+	if err != nil {
+		t.Fatalf("second call failed: %v", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Fatalf("expected the second call to skip the network, got %d requests", got)
+	}
+	if first.Cached || !second.Cached || second.Content != first.Content || second.Attempts != 0 {
+		t.Fatalf("expected only the second response to be cached, got %+v then %+v", first, second)
+	}
+
+	budget, err := orchestrator.GetBudget("alice")
+	if err != nil {
+		t.Fatalf("GetBudget failed: %v", err)
+	}
+	if budget.UsedTokens != 10 {
+		t.Fatalf("expected cached response not to be charged, used %d tokens", budget.UsedTokens)
+	}
+
+	if _, err := alice.CallLLM(&LLMRequest{Prompt: "hello", Temperature: float64Ptr(0.9), MaxTokens: 64}); err != nil {
+		t.Fatalf("call with different temperature failed: %v", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Fatalf("expected a different temperature to miss the cache, got %d requests", got)
+	}
+
+	stats := orchestrator.Stats().Cache
+	if !stats.Enabled || stats.Hits != 1 || stats.Misses != 2 || stats.Entries != 2 {
+		t.Fatalf("unexpected cache stats %+v", stats)
+	}
+}
+
+func TestCallLLMNoCacheBypassesCache(t *testing.T) {
+	var requests int32
+	server := newCountingChatServer(t, &requests)
+
+	orchestrator := NewLLMOrchestrator("key", server.URL, "model")
+	orchestrator.SetResponseCache(8, time.Minute)
+
+	if _, err := orchestrator.CallLLM(&LLMRequest{Prompt: "hello"}); err != nil {
+		t.Fatalf("first call failed: %v", err)
+	}
+	resp, err := orchestrator.CallLLM(&LLMRequest{Prompt: "hello", NoCache: true})
+	if err != nil {
+		t.Fatalf("no_cache call failed: %v", err)
+	}
+	if resp.Cached {
+		t.Fatalf("expected no_cache response to come from the network")
+	}
+	if _, err := orchestrator.WithoutCache().CallLLM(&LLMRequest{Prompt: "hello"}); err != nil {
+		t.Fatalf("WithoutCache call failed: %v", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 3 {
+		t.Fatalf("expected every bypassing call to hit the network, got %d requests", got)
+	}
+}
+
+func TestResponseCacheEvictsLeastRecentlyUsedAndExpired(t *testing.T) {
+	now := time.Now()
+	cache := newResponseCache(2, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.put("a", &LLMResponse{Content: "a"})
+	cache.put("b", &LLMResponse{Content: "b"})
+	if _, ok := cache.get("a"); !ok {
+		t.Fatalf("expected a to be cached")
+	}
+	cache.put("c", &LLMResponse{Content: "c"})
+	if _, ok := cache.get("b"); ok {
+		t.Fatalf("expected least recently used entry b to be evicted")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := cache.get("a"); ok {
+		t.Fatalf("expected expired entry to miss")
+	}
+	if stats := cache.stats(); stats.Entries != 1 || stats.Hits != 1 || stats.Misses != 2 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
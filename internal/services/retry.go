@@ -0,0 +1,196 @@
+//LLM Retry Policy(LLM 调用重试与熔断)
+
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const (
+	DefaultLLMMaxAttempts = 3
+	defaultRetryBackoff   = 500 * time.Millisecond
+	maxRetryBackoff       = 8 * time.Second
+	maxLLMResponseBytes   = 2 * 1024 * 1024
+)
+
+// 结构体
+// llmHTTPError 表示上游返回的错误状态码
+type llmHTTPError struct {
+	status     int
+	body       string
+	retryAfter time.Duration
+}
+
+func (e *llmHTTPError) Error() string {
+	return fmt.Sprintf("llm http %d: %s", e.status, e.body)
+}
+
+// 方法
+// SetCircuitBreaker 替换 CallLLM 使用的熔断器；传入 nil 表示关闭熔断。
+func (llm *LLMOrchestrator) SetCircuitBreaker(breaker *utils.CircuitBreaker) {
+	if llm == nil {
+		return
+	}
+	llm.breaker = breaker
+}
+
+// CircuitStatus 返回 LLM 熔断器的当前状态。
+func (llm *LLMOrchestrator) CircuitStatus() utils.CircuitStatus {
+	if llm == nil {
+		return utils.CircuitStatus{State: utils.CircuitClosed}
+	}
+	return llm.breaker.Status()
+}
+
+// SetRetryPolicy 配置 CallLLM 的最大尝试次数与初始退避时间，非正值保留默认设置。
+func (llm *LLMOrchestrator) SetRetryPolicy(maxAttempts int, backoff time.Duration) {
+	if llm == nil {
+		return
+	}
+	if maxAttempts > 0 {
+		llm.maxAttempts = maxAttempts
+	}
+	if backoff > 0 {
+		llm.retryBackoff = backoff
+	}
+}
+
+// postWithRetry 发送请求，并对 429、5xx 与网络错误按指数退避（含抖动）重试。
+// 存在 Retry-After 时优先使用；下一次等待会超出 ctx 截止时间时停止重试。
+func (llm *LLMOrchestrator) postWithRetry(ctx context.Context, endpoint string, body []byte) ([]byte, int, error) {
+	maxAttempts := llm.maxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultLLMMaxAttempts
+	}
+
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		raw, err := llm.postOnce(ctx, endpoint, body)
+		if err == nil {
+			return raw, attempt, nil
+		}
+		lastErr = err
+
+		if attempt >= maxAttempts || !isRetryableLLMError(ctx, err) {
+			return nil, attempt, lastErr
+		}
+
+		delay := llm.backoffDelay(attempt)
+		var httpErr *llmHTTPError
+		if errors.As(err, &httpErr) && httpErr.retryAfter > 0 {
+			delay = httpErr.retryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return nil, attempt, lastErr
+		}
+
+		utils.Warn("retrying llm request",
+			utils.KV("attempt", attempt),
+			utils.KV("delay", delay.String()),
+			utils.KV("error", err),
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, attempt, lastErr
+		case <-timer.C:
+		}
+	}
+}
+
+func (llm *LLMOrchestrator) postOnce(ctx context.Context, endpoint string, body []byte) ([]byte, error) {
+	reqHTTP, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("new http request: %w", err)
+	}
+	reqHTTP.Header.Set("Content-Type", "application/json")
+	llm.setAuthHeader(reqHTTP)
+
+	resp, err := llm.httpClient.Do(reqHTTP)
+	if err != nil {
+		return nil, fmt.Errorf("llm request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxLLMResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("read llm response: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, &llmHTTPError{
+			status:     resp.StatusCode,
+			body:       truncate(string(raw), 512),
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+	return raw, nil
+}
+
+// backoffDelay 返回第 attempt 次失败后的等待时间：base*2^(attempt-1)，上限 maxRetryBackoff，并在 [d/2, d] 内抖动。
+func (llm *LLMOrchestrator) backoffDelay(attempt int) time.Duration {
+	delay := llm.retryBackoff
+	if delay <= 0 {
+		delay = defaultRetryBackoff
+	}
+	for i := 1; i < attempt && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryBackoff {
+		delay = maxRetryBackoff
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// 函数
+func isRetryableLLMError(ctx context.Context, err error) bool {
+	return ctx.Err() == nil && isUpstreamFailure(err)
+}
+
+// isUpstreamFailure 判断错误是否说明上游不可用（网络错误、超时、429 或 5xx），用于熔断计数。
+// 调用方主动取消不计入。
+func isUpstreamFailure(err error) bool {
+	if isCanceled(err) {
+		return false
+	}
+	var httpErr *llmHTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.status == http.StatusTooManyRequests || httpErr.status >= 500
+	}
+	return err != nil
+}
+
+// parseRetryAfter 解析以秒数或 HTTP 日期表示的 Retry-After 头。
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if delay := at.Sub(now); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/utils"
+)
+
+func newFlakyServer(t *testing.T, failures int32, status int, header http.Header) (*httptest.Server, *int32) {
+	t.Helper()
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= failures {
+			for key, values := range header {
+				for _, value := range values {
+					w.Header().Add(key, value)
+				}
+			}
+			http.Error(w, "upstream unavailable", status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{
+				{"index": 0, "message": map[string]string{"role": "assistant", "content": "ok"}},
+			},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestCallLLMRetriesTransientFailures(t *testing.T) {
+	server, requests := newFlakyServer(t, 2, http.StatusBadGateway, nil)
+	orchestrator := NewLLMOrchestrator("key", server.URL, "model")
+	orchestrator.SetRetryPolicy(3, time.Millisecond)
+
+	resp, err := orchestrator.CallLLM(&LLMRequest{Prompt: "hello"})
+	if err != nil {
+		t.Fatalf("CallLLM returned error: %v", err)
+	}
+	if got := atomic.LoadInt32(requests); got != 3 {
+		t.Fatalf("expected exactly 3 requests, got %d", got)
+	}
+	if resp.Content != "ok" || resp.Attempts != 3 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+}
+
+func TestCallLLMDoesNotRetryClientErrors(t *testing.T) {
+	server, requests := newFlakyServer(t, 5, http.StatusBadRequest, nil)
+	orchestrator := NewLLMOrchestrator("key", server.URL, "model")
+	orchestrator.SetRetryPolicy(3, time.Millisecond)
+
+	if _, err := orchestrator.CallLLM(&LLMRequest{Prompt: "hello"}); err == nil {
+		t.Fatalf("expected client error to be returned")
+	}
+	if got := atomic.LoadInt32(requests); got != 1 {
+		t.Fatalf("expected a single request for a 4xx response, got %d", got)
+	}
+}
+
+func TestCallLLMGivesUpWhenRetryAfterExceedsDeadline(t *testing.T) {
+	server, requests := newFlakyServer(t, 5, http.StatusTooManyRequests, http.Header{"Retry-After": []string{"120"}})
+	orchestrator := NewLLMOrchestrator("key", server.URL, "model")
+	orchestrator.SetRetryPolicy(3, time.Millisecond)
+
+	started := time.Now()
+	if _, err := orchestrator.CallLLM(&LLMRequest{Prompt: "hello"}); err == nil {
+		t.Fatalf("expected rate limit error to be returned")
+	}
+	if got := atomic.LoadInt32(requests); got != 1 {
+		t.Fatalf("expected no retry beyond the deadline, got %d requests", got)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Fatalf("expected call to return promptly, took %v", elapsed)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cases := map[string]time.Duration{
+		"":                              0,
+		"3":                             3 * time.Second,
+		"-1":                            0,
+		"Mon, 01 Jan 2024 12:00:10 GMT": 10 * time.Second,
+		"Mon, 01 Jan 2024 11:59:00 GMT": 0,
+		"soon":                          0,
+	}
+	for value, want := range cases {
+		if got := parseRetryAfter(value, now); got != want {
+			t.Fatalf("parseRetryAfter(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestBackoffDelayGrowsWithJitter(t *testing.T) {
+	orchestrator := NewLLMOrchestrator("", "", "")
+	orchestrator.SetRetryPolicy(5, 100*time.Millisecond)
+
+	for attempt, max := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond, 10: maxRetryBackoff} {
+		delay := orchestrator.backoffDelay(attempt)
+		if delay < max/2 || delay > max {
+			t.Fatalf("attempt %d: delay %v outside [%v, %v]", attempt, delay, max/2, max)
+		}
+	}
+}
+
+func TestCallLLMCircuitBreakerFailsFast(t *testing.T) {
+	server, requests := newFlakyServer(t, 3, http.StatusServiceUnavailable, nil)
+	orchestrator := NewLLMOrchestrator("key", server.URL, "model")
+	orchestrator.SetRetryPolicy(1, time.Millisecond)
+	orchestrator.SetCircuitBreaker(utils.NewCircuitBreaker(2, 50*time.Millisecond))
+
+	for i := 0; i < 2; i++ {
+		if _, err := orchestrator.CallLLM(&LLMRequest{Prompt: "hello"}); err == nil || errors.Is(err, appErrors.ErrCircuitOpen) {
+			t.Fatalf("call %d: expected upstream error, got %v", i+1, err)
+		}
+	}
+	if status := orchestrator.CircuitStatus(); status.State != utils.CircuitOpen || status.CircuitOpenTotal != 1 {
+		t.Fatalf("expected breaker to open after 2 failures, got %+v", status)
+	}
+
+	if _, err := orchestrator.CallLLM(&LLMRequest{Prompt: "hello"}); !errors.Is(err, appErrors.ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen while open, got %v", err)
+	}
+	if got := atomic.LoadInt32(requests); got != 2 {
+		t.Fatalf("expected no HTTP call while open, got %d requests", got)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, err := orchestrator.CallLLM(&LLMRequest{Prompt: "hello"}); err == nil {
+		t.Fatalf("expected failing half-open probe to return an error")
+	}
+	if status := orchestrator.CircuitStatus(); status.State != utils.CircuitOpen || status.CircuitOpenTotal != 2 {
+		t.Fatalf("expected failed probe to reopen the breaker, got %+v", status)
+	}
+	var exposition bytes.Buffer
+	if err := orchestrator.MetricsRegistry().WritePrometheus(&exposition); err != nil || !strings.Contains(exposition.String(), "circuit_open_total 2\n") {
+		t.Fatalf("expected circuit_open_total 2 in the exposition (%v):\n%s", err, exposition.String())
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, err := orchestrator.CallLLM(&LLMRequest{Prompt: "hello"}); err != nil {
+		t.Fatalf("expected successful probe, got %v", err)
+	}
+	if status := orchestrator.CircuitStatus(); status.State != utils.CircuitClosed || status.Failures != 0 {
+		t.Fatalf("expected successful probe to close the breaker, got %+v", status)
+	}
+}
@@ -0,0 +1,281 @@
+//Response Schema Validation(LLM 输出结构校验)
+
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+// maxSchemaLogRunes 限制校验失败时写入日志的原始回复长度
+const maxSchemaLogRunes = 2000
+
+// directionsValidationSchema 是方向输出的校验规则：每项必须有标题与 summary 或 description，
+// type 限定为四种方向，relevance 位于 0-1。仅使用 validateSchema 支持的关键字。
+var directionsValidationSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		directionsResponseKey: map[string]any{
+			"type":     "array",
+			"minItems": 1,
+			"items": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"type":                map[string]any{"type": "string", "enum": []string{"broad", "deep", "lateral", "critical"}},
+					"title":               map[string]any{"type": "string", "minLength": 1},
+					"summary":             map[string]any{"type": "string"},
+					"description":         map[string]any{"type": "string"},
+					"keywords":            map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+					"key_questions":       map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+					"recommended_actions": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+					"direction_rationale": map[string]any{"type": "string"},
+					"relevance":           map[string]any{"type": "number", "minimum": 0, "maximum": 1},
+				},
+				"required": []string{"type", "title"},
+				"anyOf": []map[string]any{
+					{"required": []string{"summary"}},
+					{"required": []string{"description"}},
+				},
+			},
+		},
+	},
+	"required": []string{directionsResponseKey},
+}
+
+// 方法
+// SetSchemaValidation 设置是否在返回方向前按结构校验模型输出（默认关闭）。
+// 开启后校验失败会记录原始回复、计数并以纠正提示重试一次。
+func (llm *LLMOrchestrator) SetSchemaValidation(enabled bool) {
+	if llm == nil {
+		return
+	}
+	llm.schemaValidation = enabled
+}
+
+// ValidateResponse 按方向输出结构校验模型回复；数组、单个对象与 {"directions": [...]} 均可接受。
+func (llm *LLMOrchestrator) ValidateResponse(content string) error {
+	value, err := extractDirectionsValue(content)
+	if err != nil {
+		return err
+	}
+	return validateSchema(directionsValidationSchema, value, "$")
+}
+
+// validateDirections 在开启结构校验时检查已解析的回复；校验失败则请求模型按结构重新输出一次，
+// 重试结果仍无效时保留原解析结果。
+func (llm *LLMOrchestrator) validateDirections(directions []models.Direction, resp *LLMResponse) ([]models.Direction, *LLMResponse, error) {
+	if !llm.schemaValidation {
+		return directions, resp, nil
+	}
+	payload := resp.directionsPayload()
+	schemaErr := llm.ValidateResponse(payload)
+	if schemaErr == nil {
+		return directions, resp, nil
+	}
+	llm.metrics.observeSchemaFailure()
+	utils.Warn("LLM directions response failed schema validation",
+		utils.KV("error", schemaErr),
+		utils.KV("response", truncate(payload, maxSchemaLogRunes)),
+	)
+
+	retry, err := llm.withoutStream().CallLLM(&LLMRequest{
+		Prompt:         buildSchemaCorrectionPrompt(payload, schemaErr),
+		MaxTokens:      repairMaxTokens,
+		ResponseFormat: directionsResponseFormat,
+	})
+	if err != nil {
+		return directions, resp, err
+	}
+	if err := llm.ValidateResponse(retry.Content); err != nil {
+		llm.metrics.observeSchemaFailure()
+		return directions, resp, fmt.Errorf("corrected response: %w", err)
+	}
+	corrected, err := llm.parseDirectionsFromContent(retry.Content)
+	if err != nil {
+		return directions, resp, err
+	}
+	utils.Info("corrected LLM directions response", utils.KV("directions", len(corrected)))
+	return corrected, retry, nil
+}
+
+// 函数
+func buildSchemaCorrectionPrompt(invalid string, schemaErr error) string {
+	return fmt.Sprintf(
+		"Your previous response didn't match the required schema (%v).\n"+
+			"Please return only a JSON object with a %q array. Each item must have type (one of broad, deep, lateral, critical), "+
+			"a non-empty title, a summary, keywords, and relevance between 0 and 1. "+
+			"Do not add commentary or code fences, and keep the original content.\n\n"+
+			"Previous response:\n%s",
+		schemaErr, directionsResponseKey, truncate(invalid, maxRepairOutputRunes),
+	)
+}
+
+// extractDirectionsValue 从回复中取出 JSON 值，并统一包装为 {"directions": [...]}。
+func extractDirectionsValue(content string) (any, error) {
+	text, _ := stripCodeFences(content)
+	if text == "" {
+		return nil, errors.New("llm response empty")
+	}
+	candidates := append([]string{text}, balancedSegments(text, '[', ']')...)
+	candidates = append(candidates, balancedSegments(text, '{', '}')...)
+	for _, candidate := range candidates {
+		var value any
+		if err := json.Unmarshal([]byte(candidate), &value); err != nil {
+			continue
+		}
+		switch v := value.(type) {
+		case []any:
+			return map[string]any{directionsResponseKey: v}, nil
+		case map[string]any:
+			if _, ok := v[directionsResponseKey]; ok {
+				return v, nil
+			}
+			return map[string]any{directionsResponseKey: []any{v}}, nil
+		}
+	}
+	return nil, errors.New("llm response contains no JSON object or array")
+}
+
+// validateSchema 按 JSON Schema 的子集（type、enum、required、properties、items、minItems、
+// minLength、minimum、maximum、anyOf）校验 value，path 用于错误信息中的位置。
+func validateSchema(schema map[string]any, value any, path string) error {
+	if typ, ok := schema["type"].(string); ok && !matchesSchemaType(typ, value) {
+		return fmt.Errorf("%s: expected %s, got %s", path, typ, jsonTypeName(value))
+	}
+	if enum, ok := schema["enum"].([]string); ok {
+		if s, _ := value.(string); !slices.Contains(enum, s) {
+			return fmt.Errorf("%s: %v is not one of %s", path, value, strings.Join(enum, ", "))
+		}
+	}
+	if n, ok := value.(float64); ok {
+		if minimum, ok := schemaNumber(schema["minimum"]); ok && n < minimum {
+			return fmt.Errorf("%s: %v is less than %v", path, n, minimum)
+		}
+		if maximum, ok := schemaNumber(schema["maximum"]); ok && n > maximum {
+			return fmt.Errorf("%s: %v is greater than %v", path, n, maximum)
+		}
+	}
+	if s, ok := value.(string); ok {
+		if minLength, ok := schemaNumber(schema["minLength"]); ok && float64(len(strings.TrimSpace(s))) < minLength {
+			return fmt.Errorf("%s: must not be empty", path)
+		}
+	}
+	if items, ok := value.([]any); ok {
+		if minItems, ok := schemaNumber(schema["minItems"]); ok && float64(len(items)) < minItems {
+			return fmt.Errorf("%s: expected at least %v items", path, minItems)
+		}
+		if itemSchema, ok := schema["items"].(map[string]any); ok {
+			for i, item := range items {
+				if err := validateSchema(itemSchema, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if object, ok := value.(map[string]any); ok {
+		if err := validateObject(schema, object, path); err != nil {
+			return err
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]map[string]any); ok && len(anyOf) > 0 {
+		var errs []error
+		for _, option := range anyOf {
+			err := validateSchema(option, value, path)
+			if err == nil {
+				errs = nil
+				break
+			}
+			errs = append(errs, err)
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("%s: matches none of the alternatives: %w", path, errors.Join(errs...))
+		}
+	}
+	return nil
+}
+
+func validateObject(schema map[string]any, object map[string]any, path string) error {
+	if required, ok := schema["required"].([]string); ok {
+		for _, key := range required {
+			if _, ok := object[key]; !ok {
+				return fmt.Errorf("%s: missing required field %q", path, key)
+			}
+		}
+	}
+	properties, _ := schema["properties"].(map[string]any)
+	for key, propertySchema := range properties {
+		child, ok := object[key]
+		if !ok {
+			continue
+		}
+		if propertySchema, ok := propertySchema.(map[string]any); ok {
+			if err := validateSchema(propertySchema, child, path+"."+key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func matchesSchemaType(typ string, value any) bool {
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	default:
+		return true
+	}
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func schemaNumber(value any) (float64, bool) {
+	switch n := value.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestValidateResponseRejectsMalformedDirections(t *testing.T) {
+	llm := NewLLMOrchestrator("", "", "")
+	cases := map[string]string{
+		"unknown type":      `{"directions":[{"type":"sideways","title":"Chemistry","summary":"Cell chemistry"}]}`,
+		"missing title":     `[{"type":"deep","summary":"Cell chemistry"}]`,
+		"blank title":       `[{"type":"deep","title":"  ","summary":"Cell chemistry"}]`,
+		"no description":    `[{"type":"deep","title":"Chemistry"}]`,
+		"relevance too big": `[{"type":"deep","title":"Chemistry","summary":"Cell chemistry","relevance":5}]`,
+		"keywords not list": `[{"type":"deep","title":"Chemistry","summary":"Cell chemistry","keywords":"battery"}]`,
+		"empty directions":  `{"directions":[]}`,
+		"not json":          `I could not think of any directions.`,
+	}
+	for name, content := range cases {
+		if err := llm.ValidateResponse(content); err == nil {
+			t.Fatalf("%s: expected validation error for %s", name, content)
+		}
+	}
+
+	valid := "```json\n" + `{"directions":[{"type":"deep","title":"Chemistry","description":"Cell chemistry","relevance":0.8,"keywords":["anode"]}]}` + "\n```"
+	if err := llm.ValidateResponse(valid); err != nil {
+		t.Fatalf("expected valid response to pass, got %v", err)
+	}
+}
+
+func TestGenerateThoughtDirectionsRetriesWithCorrectivePrompt(t *testing.T) {
+	malformed := `{"directions":[{"type":"sideways","title":"Chemistry","summary":"Cell chemistry","relevance":5}]}`
+	corrected := `{"directions":[{"type":"deep","title":"Electrolytes","summary":"Solid-state electrolytes","relevance":0.9}]}`
+	var prompts []string
+	server, calls := newScriptedBackend(t, http.StatusOK, []string{malformed, corrected}, &prompts)
+	llm := newRepairTestLLM(t, server.URL)
+	llm.SetSchemaValidation(true)
+
+	directions, err := llm.GenerateThoughtDirections("Batteries", nil)
+	if err != nil {
+		t.Fatalf("GenerateThoughtDirections failed: %v", err)
+	}
+	if atomic.LoadInt32(calls) != 2 {
+		t.Fatalf("expected one corrective retry, got %d calls", *calls)
+	}
+	if len(directions) != 1 || directions[0].Title != "Electrolytes" || directions[0].Provenance == nil || directions[0].Provenance.Model == localFallbackModel {
+		t.Fatalf("expected corrected directions, got %+v", directions)
+	}
+	if len(prompts) != 2 || !strings.HasPrefix(prompts[1], "Your previous response didn't match the required schema") || !strings.Contains(prompts[1], malformed) {
+		t.Fatalf("expected corrective prompt with the invalid output, got %q", prompts)
+	}
+	if got := llm.Metrics().SchemaValidationFailures; got != 1 {
+		t.Fatalf("expected one schema validation failure, got %d", got)
+	}
+}
+
+func TestGenerateThoughtDirectionsKeepsParsedDirectionsWhenCorrectionFails(t *testing.T) {
+	malformed := `[{"type":"sideways","title":"Chemistry","summary":"Cell chemistry"}]`
+	server, calls := newScriptedBackend(t, http.StatusOK, []string{malformed}, nil)
+	llm := newRepairTestLLM(t, server.URL)
+	llm.SetSchemaValidation(true)
+
+	directions, err := llm.GenerateThoughtDirections("Batteries", nil)
+	if err != nil {
+		t.Fatalf("GenerateThoughtDirections failed: %v", err)
+	}
+	if atomic.LoadInt32(calls) != 2 {
+		t.Fatalf("expected exactly one retry, got %d calls", *calls)
+	}
+	if len(directions) != 1 || directions[0].Title != "Chemistry" || directions[0].Provenance.Model == localFallbackModel {
+		t.Fatalf("expected the originally parsed directions, got %+v", directions)
+	}
+	if got := llm.Metrics().SchemaValidationFailures; got != 2 {
+		t.Fatalf("expected failures for the original and corrected responses, got %d", got)
+	}
+	var out strings.Builder
+	if err := llm.MetricsRegistry().WritePrometheus(&out); err != nil || !strings.Contains(out.String(), "llm_schema_validation_failures_total 2") {
+		t.Fatalf("expected the failure counter in the Prometheus export, got %q (%v)", out.String(), err)
+	}
+}
+
+func TestSchemaValidationDisabledByDefault(t *testing.T) {
+	malformed := `[{"type":"sideways","title":"Chemistry","summary":"Cell chemistry"}]`
+	server, calls := newScriptedBackend(t, http.StatusOK, []string{malformed}, nil)
+	llm := newRepairTestLLM(t, server.URL)
+
+	if _, err := llm.GenerateThoughtDirections("Batteries", nil); err != nil {
+		t.Fatalf("GenerateThoughtDirections failed: %v", err)
+	}
+	if atomic.LoadInt32(calls) != 1 || llm.Metrics().SchemaValidationFailures != 0 {
+		t.Fatalf("expected no validation without SetSchemaValidation, got %d calls", *calls)
+	}
+}
@@ -0,0 +1,125 @@
+//Scripted LLM(脚本化 LLM 测试替身)
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"WideMindsMCP/internal/models"
+)
+
+// 结构体
+// ScriptedLLM 是 DirectionGenerator 的测试替身：按入队顺序返回预设结果，不发起任何网络请求。
+// 队列为空时返回错误，便于发现多余的调用。
+type ScriptedLLM struct {
+	mutex      sync.Mutex
+	directions []scriptedDirections
+	thoughts   []scriptedThoughts
+	responses  []scriptedResponse
+	healthErr  error
+	calls      []string
+}
+
+type scriptedDirections struct {
+	directions []models.Direction
+	err        error
+}
+
+type scriptedThoughts struct {
+	thoughts []*models.Thought
+	err      error
+}
+
+type scriptedResponse struct {
+	response *LLMResponse
+	err      error
+}
+
+// 函数
+func NewScriptedLLM() *ScriptedLLM {
+	return &ScriptedLLM{}
+}
+
+// 方法
+// QueueDirections 追加一次 GenerateThoughtDirections 的结果。
+func (s *ScriptedLLM) QueueDirections(directions []models.Direction, err error) *ScriptedLLM {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.directions = append(s.directions, scriptedDirections{directions: directions, err: err})
+	return s
+}
+
+// QueueThoughts 追加一次 ExploreDirection 的结果。
+func (s *ScriptedLLM) QueueThoughts(thoughts []*models.Thought, err error) *ScriptedLLM {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.thoughts = append(s.thoughts, scriptedThoughts{thoughts: thoughts, err: err})
+	return s
+}
+
+// QueueResponse 追加一次 CallLLM 的结果。
+func (s *ScriptedLLM) QueueResponse(response *LLMResponse, err error) *ScriptedLLM {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.responses = append(s.responses, scriptedResponse{response: response, err: err})
+	return s
+}
+
+// SetHealthError 设置 HealthCheck 的返回值。
+func (s *ScriptedLLM) SetHealthError(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.healthErr = err
+}
+
+// Calls 返回按顺序记录的方法调用名。
+func (s *ScriptedLLM) Calls() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.calls...)
+}
+
+func (s *ScriptedLLM) GenerateThoughtDirections(concept string, context []models.ContextEntry) ([]models.Direction, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.calls = append(s.calls, "GenerateThoughtDirections")
+	if len(s.directions) == 0 {
+		return nil, fmt.Errorf("scripted llm: no queued directions for %q", concept)
+	}
+	next := s.directions[0]
+	s.directions = s.directions[1:]
+	return next.directions, next.err
+}
+
+func (s *ScriptedLLM) ExploreDirection(direction models.Direction, depth int, context []models.ContextEntry) ([]*models.Thought, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.calls = append(s.calls, "ExploreDirection")
+	if len(s.thoughts) == 0 {
+		return nil, fmt.Errorf("scripted llm: no queued thoughts for %q", direction.Title)
+	}
+	next := s.thoughts[0]
+	s.thoughts = s.thoughts[1:]
+	return next.thoughts, next.err
+}
+
+func (s *ScriptedLLM) CallLLM(req *LLMRequest) (*LLMResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.calls = append(s.calls, "CallLLM")
+	if len(s.responses) == 0 {
+		return nil, fmt.Errorf("scripted llm: no queued response")
+	}
+	next := s.responses[0]
+	s.responses = s.responses[1:]
+	return next.response, next.err
+}
+
+func (s *ScriptedLLM) HealthCheck(ctx context.Context) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.calls = append(s.calls, "HealthCheck")
+	return s.healthErr
+}
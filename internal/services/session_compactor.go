@@ -0,0 +1,233 @@
+//Session Compaction(闲置会话归档)
+
+package services
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const (
+	DefaultCompactionInterval = time.Hour
+	DefaultCompactionMaxIdle  = 30 * 24 * time.Hour
+
+	archiveTempSuffix     = ".tmp"
+	snapshotArchiveSuffix = ".snapshots.json.gz"
+)
+
+// 结构体
+// SessionCompactor 将长期未使用的文件会话压缩归档到 archiveDir/{年}/{月}/{会话ID}.json.gz 并删除原文件，
+// 会话的命名快照一并归档到同目录的 {会话ID}.snapshots.json.gz。
+// 每个会话先写临时文件再原子改名，归档完成后才删除原文件，中途重启时下一轮会重新归档同一会话。
+type SessionCompactor struct {
+	store      *storage.FileSessionStore
+	archiveDir string
+	// onCompacted 在会话归档并删除后调用，用于清理会话管理器中的缓存
+	onCompacted func(sessionID string)
+	compacted   *utils.Counter
+}
+
+// 函数
+// NewSessionCompactor 创建归档任务；registry 非空时注册 compacted_sessions_total 计数器。
+func NewSessionCompactor(store *storage.FileSessionStore, archiveDir string, registry *utils.MetricsRegistry) *SessionCompactor {
+	compactor := &SessionCompactor{store: store, archiveDir: archiveDir}
+	if registry != nil {
+		compactor.compacted = registry.NewCounter("compacted_sessions_total", "Idle sessions archived to compressed files.", "")
+	}
+	return compactor
+}
+
+// 方法
+// StartCompactor 在后台按 checkInterval 归档闲置超过 maxIdleAge 的会话，ctx 取消时停止；
+// 会话存储不是文件存储时返回错误。
+func (sm *SessionManager) StartCompactor(ctx context.Context, archiveDir string, checkInterval, maxIdleAge time.Duration, registry *utils.MetricsRegistry) (*SessionCompactor, error) {
+	store, ok := sm.store.(*storage.FileSessionStore)
+	if !ok {
+		return nil, errors.New("session compaction requires the file session store")
+	}
+	if strings.TrimSpace(archiveDir) == "" {
+		return nil, errors.New("session archive directory is required")
+	}
+	compactor := NewSessionCompactor(store, archiveDir, registry)
+	compactor.onCompacted = sm.forgetSession
+	go compactor.Run(ctx, checkInterval, maxIdleAge)
+	return compactor, nil
+}
+
+// forgetSession 丢弃会话的缓存与撤销历史，不触碰存储。
+func (sm *SessionManager) forgetSession(sessionID string) {
+	sm.mutex.Lock()
+	delete(sm.cache, sessionID)
+	sm.mutex.Unlock()
+
+	sm.historyMutex.Lock()
+	delete(sm.history, sessionID)
+	sm.historyMutex.Unlock()
+}
+
+// Run 立即并按 checkInterval 周期执行归档，直到 ctx 取消；非正参数使用默认值。
+func (c *SessionCompactor) Run(ctx context.Context, checkInterval, maxIdleAge time.Duration) {
+	if checkInterval <= 0 {
+		checkInterval = DefaultCompactionInterval
+	}
+	if maxIdleAge <= 0 {
+		maxIdleAge = DefaultCompactionMaxIdle
+	}
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		if compacted, err := c.Compact(ctx, time.Now().UTC().Add(-maxIdleAge)); err != nil {
+			utils.Warn("session compaction failed", utils.KV("compacted", compacted), utils.KV("error", err))
+		} else if compacted > 0 {
+			utils.Info("archived idle sessions", utils.KV("compacted", compacted), utils.KV("archive_dir", c.archiveDir))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Compact 归档 cutoff 之前最后更新的全部会话，返回归档数。单个会话失败时记录警告并继续。
+func (c *SessionCompactor) Compact(ctx context.Context, cutoff time.Time) (int, error) {
+	if c == nil || c.store == nil {
+		return 0, errors.New("session compactor is not initialized")
+	}
+	if err := c.removeStaleTempFiles(); err != nil {
+		return 0, err
+	}
+
+	sessions, err := c.store.GetExpiredSessions(cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	compacted := 0
+	for _, session := range sessions {
+		if err := ctx.Err(); err != nil {
+			return compacted, err
+		}
+		if session == nil {
+			continue
+		}
+		if err := c.archive(session); err != nil {
+			utils.Warn("failed to archive session", utils.KV("session_id", session.ID), utils.KV("error", err))
+			continue
+		}
+		deleted, err := c.store.DeleteIfIdle(session.ID, cutoff)
+		if err != nil {
+			utils.Warn("failed to delete archived session", utils.KV("session_id", session.ID), utils.KV("error", err))
+			continue
+		}
+		if !deleted {
+			// 归档期间会话被更新：保留原文件并删除刚写入的归档，下次闲置时按新的更新时间重新归档
+			c.removeArchive(session)
+			continue
+		}
+		if c.onCompacted != nil {
+			c.onCompacted(session.ID)
+		}
+		c.compacted.Inc("")
+		compacted++
+	}
+	return compacted, nil
+}
+
+// ArchivePath 返回会话归档文件的路径，年月取自会话最后更新时间。
+func (c *SessionCompactor) ArchivePath(session *models.Session) string {
+	updatedAt := session.UpdatedAt.UTC()
+	return filepath.Join(c.archiveDir, fmt.Sprintf("%04d", updatedAt.Year()), fmt.Sprintf("%02d", int(updatedAt.Month())), session.ID+".json.gz")
+}
+
+// SnapshotArchivePath 返回会话命名快照归档文件的路径，与会话归档位于同一目录。
+func (c *SessionCompactor) SnapshotArchivePath(session *models.Session) string {
+	return strings.TrimSuffix(c.ArchivePath(session), ".json.gz") + snapshotArchiveSuffix
+}
+
+// archive 先归档命名快照再归档会话，两者都完成后才允许删除原文件。
+func (c *SessionCompactor) archive(session *models.Session) error {
+	snapshots, err := c.store.ListSnapshots(session.ID)
+	if err != nil {
+		return err
+	}
+	if len(snapshots) > 0 {
+		if err := writeArchive(c.SnapshotArchivePath(session), func(w io.Writer) error {
+			return json.NewEncoder(w).Encode(snapshots)
+		}); err != nil {
+			return err
+		}
+	}
+	return writeArchive(c.ArchivePath(session), session.WriteJSON)
+}
+
+// removeArchive 删除会话的归档文件，用于丢弃归档期间已过时的归档。
+func (c *SessionCompactor) removeArchive(session *models.Session) {
+	for _, path := range []string{c.ArchivePath(session), c.SnapshotArchivePath(session)} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			utils.Warn("failed to remove outdated archive", utils.KV("path", path), utils.KV("error", err))
+		}
+	}
+}
+
+// removeStaleTempFiles 删除上次中断时遗留的未完成归档。
+func (c *SessionCompactor) removeStaleTempFiles() error {
+	err := filepath.WalkDir(c.archiveDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), archiveTempSuffix) {
+			return os.Remove(path)
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// 函数
+// writeArchive 将 write 的输出 gzip 压缩后写入临时文件，同步后原子改名为 path。
+func writeArchive(path string, write func(io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tempPath := path + archiveTempSuffix
+	file, err := os.OpenFile(tempPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	writer := gzip.NewWriter(file)
+	writeErr := write(writer)
+	if closeErr := writer.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr == nil {
+		writeErr = file.Sync()
+	}
+	if closeErr := file.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr != nil {
+		_ = os.Remove(tempPath)
+		return writeErr
+	}
+	return os.Rename(tempPath, path)
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+	"WideMindsMCP/internal/utils"
+)
+
+func newCompactorFixture(t *testing.T) (*SessionManager, *storage.FileSessionStore, *SessionCompactor, *utils.MetricsRegistry) {
+	t.Helper()
+	store := storage.NewFileSessionStore(filepath.Join(t.TempDir(), "sessions")).(*storage.FileSessionStore)
+	manager := NewSessionManager(store)
+	registry := utils.NewMetricsRegistry()
+	compactor := NewSessionCompactor(store, filepath.Join(t.TempDir(), "archive"), registry)
+	compactor.onCompacted = manager.forgetSession
+	return manager, store, compactor, registry
+}
+
+func ageSession(t *testing.T, store storage.SessionStore, session *models.Session, updatedAt time.Time) {
+	t.Helper()
+	session.UpdatedAt = updatedAt
+	if err := store.Update(session); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+}
+
+func readArchive(t *testing.T, path string, value any) {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("expected the archive file: %v", err)
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("expected a gzip archive: %v", err)
+	}
+	if err := json.NewDecoder(reader).Decode(value); err != nil {
+		t.Fatalf("decode archive %s: %v", path, err)
+	}
+}
+
+func TestCompactArchivesIdleSessions(t *testing.T) {
+	manager, store, compactor, registry := newCompactorFixture(t)
+	idle, err := manager.CreateSession("user", "Old idea")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	active, err := manager.CreateSession("user", "Fresh idea")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	if _, err := manager.CreateSnapshot(idle.ID, "draft"); err != nil {
+		t.Fatalf("CreateSnapshot failed: %v", err)
+	}
+	ageSession(t, store, idle, time.Date(2026, time.March, 3, 0, 0, 0, 0, time.UTC))
+
+	compacted, err := compactor.Compact(context.Background(), time.Now().UTC().Add(-DefaultCompactionMaxIdle))
+	if err != nil || compacted != 1 {
+		t.Fatalf("expected one archived session, got %d (%v)", compacted, err)
+	}
+
+	path := compactor.ArchivePath(idle)
+	if !strings.HasSuffix(path, filepath.Join("2026", "03", idle.ID+".json.gz")) {
+		t.Fatalf("unexpected archive path %s", path)
+	}
+	var archived models.Session
+	if readArchive(t, path, &archived); archived.ID != idle.ID {
+		t.Fatalf("expected the archived session, got %+v", archived)
+	}
+
+	var snapshots []*models.SessionSnapshot
+	readArchive(t, compactor.SnapshotArchivePath(idle), &snapshots)
+	if len(snapshots) != 1 || snapshots[0].Name != "draft" {
+		t.Fatalf("expected the named snapshot to be archived, got %+v", snapshots)
+	}
+
+	if _, err := manager.GetSession(idle.ID); !errors.Is(err, appErrors.ErrSessionNotFound) {
+		t.Fatalf("expected the archived session to be gone, got %v", err)
+	}
+	sessions, err := store.GetByUserID("user")
+	if err != nil || len(sessions) != 1 || sessions[0].ID != active.ID {
+		t.Fatalf("expected only the active session in the index, got %v (%v)", sessions, err)
+	}
+
+	var metrics strings.Builder
+	if err := registry.WritePrometheus(&metrics); err != nil || !strings.Contains(metrics.String(), "compacted_sessions_total 1") {
+		t.Fatalf("expected the compaction counter, got %q (%v)", metrics.String(), err)
+	}
+}
+
+func TestCompactResumesAfterInterruptedRun(t *testing.T) {
+	manager, store, compactor, _ := newCompactorFixture(t)
+	session, err := manager.CreateSession("user", "Old idea")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	ageSession(t, store, session, time.Now().UTC().Add(-60*24*time.Hour))
+
+	// 模拟上次运行在写归档与删除原文件之间中断：留下半截临时文件和已完成的旧归档
+	path := compactor.ArchivePath(session)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	if err := os.WriteFile(path+archiveTempSuffix, []byte("partial"), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if err := compactor.archive(session); err != nil {
+		t.Fatalf("archive failed: %v", err)
+	}
+
+	compacted, err := compactor.Compact(context.Background(), time.Now().UTC().Add(-DefaultCompactionMaxIdle))
+	if err != nil || compacted != 1 {
+		t.Fatalf("expected the interrupted session to be compacted, got %d (%v)", compacted, err)
+	}
+	if _, err := os.Stat(path + archiveTempSuffix); !os.IsNotExist(err) {
+		t.Fatalf("expected the stale temp file to be removed, got %v", err)
+	}
+	if _, err := store.Get(session.ID); !errors.Is(err, appErrors.ErrSessionNotFound) {
+		t.Fatalf("expected the original file to be deleted, got %v", err)
+	}
+}
+
+func TestStartCompactorRequiresFileStore(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	if _, err := manager.StartCompactor(context.Background(), t.TempDir(), time.Hour, time.Hour, nil); err == nil {
+		t.Fatal("expected an error for the in-memory store")
+	}
+}
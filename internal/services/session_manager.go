@@ -5,33 +5,113 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	appErrors "WideMindsMCP/internal/errors"
 	"WideMindsMCP/internal/models"
 	"WideMindsMCP/internal/storage"
+	"WideMindsMCP/internal/utils"
 )
 
+// 常量
+const DefaultMaxThoughtDepth = 12
+
+const maxDepthReachedMessage = "maximum depth reached; consider pruning or branching higher"
+
+const maxUndo = 10
+
 // 结构体
 type SessionManager struct {
-	store storage.SessionStore
-	cache map[string]*models.Session
-	mutex sync.RWMutex
+	store           storage.SessionStore
+	cache           map[string]*models.Session
+	mutex           sync.RWMutex
+	maxThoughtDepth int
+	history         map[string]*sessionHistory
+	historyMutex    sync.Mutex
+	// reactionMutex 串行化表情反馈计数的更新
+	reactionMutex sync.Mutex
+	embedder      Embedder
+	// similarityThreshold 为探索方向时的相似度告警阈值，0 表示关闭
+	similarityThreshold float64
+	// thoughtEmbeddings 缓存相似度告警与查重使用的思维向量
+	thoughtEmbeddings *thoughtEmbeddingCache
+}
+
+// SessionSnapshot 记录一次变更前的会话状态。
+type SessionSnapshot struct {
+	Before *models.Session
+	Action string
+	At     time.Time
+}
+
+type sessionHistory struct {
+	undo []SessionSnapshot
+	redo []SessionSnapshot
 }
 
 // 函数
 func NewSessionManager(store storage.SessionStore) *SessionManager {
 	return &SessionManager{
-		store: store,
-		cache: make(map[string]*models.Session),
+		store:             store,
+		cache:             make(map[string]*models.Session),
+		maxThoughtDepth:   DefaultMaxThoughtDepth,
+		history:           make(map[string]*sessionHistory),
+		thoughtEmbeddings: newThoughtEmbeddingCache(thoughtEmbeddingCacheSize),
 	}
 }
 
 // 方法
+// SetMaxThoughtDepth 设置思维树允许的最大深度，非正值恢复默认值。
+func (sm *SessionManager) SetMaxThoughtDepth(depth int) {
+	if depth <= 0 {
+		depth = DefaultMaxThoughtDepth
+	}
+	sm.mutex.Lock()
+	sm.maxThoughtDepth = depth
+	sm.mutex.Unlock()
+}
+
+func (sm *SessionManager) MaxThoughtDepth() int {
+	if sm == nil {
+		return DefaultMaxThoughtDepth
+	}
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	return sm.maxThoughtDepth
+}
+
+// checkAttachDepth 确认将 thought 子树挂到 parent 下不会超过最大深度。
+func (sm *SessionManager) checkAttachDepth(parent, thought *models.Thought) error {
+	if parent == nil || thought == nil {
+		return nil
+	}
+	if parent.Depth+1+subtreeHeight(thought) > sm.MaxThoughtDepth() {
+		return utils.ValidationError(maxDepthReachedMessage)
+	}
+	return nil
+}
+
+func subtreeHeight(thought *models.Thought) int {
+	if thought == nil {
+		return 0
+	}
+	height := 0
+	for _, child := range thought.Children {
+		if h := subtreeHeight(child) + 1; h > height {
+			height = h
+		}
+	}
+	return height
+}
+
 func (sm *SessionManager) CreateSession(userID, initialConcept string) (*models.Session, error) {
+	initialConcept = strings.TrimSpace(utils.SanitizeHTML(initialConcept))
 	if initialConcept == "" {
 		return nil, appErrors.ErrInvalidRequest
 	}
@@ -48,6 +128,44 @@ func (sm *SessionManager) CreateSession(userID, initialConcept string) (*models.
 	return session, nil
 }
 
+// ImportSession 净化并保存由外部文档构建的会话，并校验树深度限制。
+func (sm *SessionManager) ImportSession(userID string, session *models.Session) (*models.Session, error) {
+	if session == nil || session.RootThought == nil {
+		return nil, appErrors.ErrInvalidRequest
+	}
+	if session.GetMetadata().MaxDepth > sm.MaxThoughtDepth() {
+		return nil, utils.ValidationError(maxDepthReachedMessage)
+	}
+	sanitizeImportedTree(session.RootThought)
+	if session.RootThought.Content == "" {
+		return nil, appErrors.ErrInvalidRequest
+	}
+	session.NormalizeTree()
+
+	session.UserID = userID
+	if err := sm.store.Save(session); err != nil {
+		return nil, err
+	}
+
+	sm.mutex.Lock()
+	sm.cache[session.ID] = session
+	sm.mutex.Unlock()
+
+	return session, nil
+}
+
+// sanitizeImportedTree 按当前净化模式清理导入的思维内容与方向文字，导入文档与其他用户输入一样不可信。
+func sanitizeImportedTree(root *models.Thought) {
+	for _, thought := range subtreeThoughts(root, nil) {
+		thought.Content = strings.TrimSpace(utils.SanitizeHTML(thought.Content))
+		thought.Direction.Title = strings.TrimSpace(utils.SanitizeHTML(thought.Direction.Title))
+		thought.Direction.Description = strings.TrimSpace(utils.SanitizeHTML(thought.Direction.Description))
+		for i, keyword := range thought.Direction.Keywords {
+			thought.Direction.Keywords[i] = strings.TrimSpace(utils.SanitizeHTML(keyword))
+		}
+	}
+}
+
 func (sm *SessionManager) GetSession(sessionID string) (*models.Session, error) {
 	if sessionID == "" {
 		return nil, appErrors.ErrInvalidRequest
@@ -75,6 +193,29 @@ func (sm *SessionManager) GetSession(sessionID string) (*models.Session, error)
 	return session, nil
 }
 
+// DiffSessions 比较两个会话的思维树
+func (sm *SessionManager) DiffSessions(sessionID, otherID string) (*models.SessionDiff, error) {
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	other, err := sm.GetSession(otherID)
+	if err != nil {
+		return nil, err
+	}
+	return models.DiffSessions(session, other), nil
+}
+
+// AssessReadiness 评估会话是否已探索充分、可以开始总结，不调用 LLM。
+func (sm *SessionManager) AssessReadiness(sessionID string) (*models.ReadinessReport, error) {
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	report := session.ReadinessScore()
+	return &report, nil
+}
+
 func (sm *SessionManager) UpdateSession(session *models.Session) error {
 	if session == nil {
 		return appErrors.ErrInvalidRequest
@@ -105,9 +246,100 @@ func (sm *SessionManager) DeleteSession(sessionID string) error {
 	delete(sm.cache, sessionID)
 	sm.mutex.Unlock()
 
+	sm.historyMutex.Lock()
+	delete(sm.history, sessionID)
+	sm.historyMutex.Unlock()
+
 	return nil
 }
 
+// Undo 撤销会话最近一次变更，恢复变更前的快照。
+func (sm *SessionManager) Undo(sessionID string) (*models.Session, error) {
+	return sm.restoreSnapshot(sessionID, false)
+}
+
+// Redo 重新应用最近一次被撤销的变更。
+func (sm *SessionManager) Redo(sessionID string) (*models.Session, error) {
+	return sm.restoreSnapshot(sessionID, true)
+}
+
+func (sm *SessionManager) restoreSnapshot(sessionID string, redo bool) (*models.Session, error) {
+	current, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	sm.historyMutex.Lock()
+	defer sm.historyMutex.Unlock()
+
+	history := sm.history[sessionID]
+	if history == nil {
+		history = &sessionHistory{}
+		sm.history[sessionID] = history
+	}
+	from, to := &history.undo, &history.redo
+	if redo {
+		from, to = &history.redo, &history.undo
+	}
+	if len(*from) == 0 {
+		if redo {
+			return nil, utils.ValidationError("nothing to redo")
+		}
+		return nil, utils.ValidationError("nothing to undo")
+	}
+
+	snapshot := (*from)[len(*from)-1]
+	restored := snapshot.Before.Clone()
+	if restored == nil {
+		return nil, errors.New("session snapshot could not be restored")
+	}
+	restored.UpdatedAt = time.Now().UTC()
+	// 已发生的 LLM 用量不随撤销/重做回退
+	restored.Usage = current.Usage
+	if err := sm.store.Update(restored); err != nil {
+		return nil, err
+	}
+
+	*from = (*from)[:len(*from)-1]
+	*to = appendBounded(*to, newSessionSnapshot(current, snapshot.Action))
+
+	sm.mutex.Lock()
+	sm.cache[restored.ID] = restored
+	sm.mutex.Unlock()
+
+	return restored, nil
+}
+
+// recordSnapshot 在变更成功后压入撤销栈，并清空重做栈。
+func (sm *SessionManager) recordSnapshot(snapshot SessionSnapshot) {
+	if snapshot.Before == nil {
+		return
+	}
+
+	sm.historyMutex.Lock()
+	defer sm.historyMutex.Unlock()
+
+	history := sm.history[snapshot.Before.ID]
+	if history == nil {
+		history = &sessionHistory{}
+		sm.history[snapshot.Before.ID] = history
+	}
+	history.undo = appendBounded(history.undo, snapshot)
+	history.redo = nil
+}
+
+func newSessionSnapshot(session *models.Session, action string) SessionSnapshot {
+	return SessionSnapshot{Before: session.Clone(), Action: action, At: time.Now().UTC()}
+}
+
+func appendBounded(stack []SessionSnapshot, snapshot SessionSnapshot) []SessionSnapshot {
+	stack = append(stack, snapshot)
+	if len(stack) > maxUndo {
+		stack = append([]SessionSnapshot(nil), stack[len(stack)-maxUndo:]...)
+	}
+	return stack
+}
+
 func (sm *SessionManager) AddThoughtToSession(sessionID string, thought *models.Thought) error {
 	if thought == nil {
 		return appErrors.ErrInvalidRequest
@@ -119,6 +351,7 @@ func (sm *SessionManager) AddThoughtToSession(sessionID string, thought *models.
 	}
 
 	thought.SessionID = session.ID
+	snapshot := newSessionSnapshot(session, "add_thought")
 
 	if session.RootThought == nil {
 		session.RootThought = thought
@@ -132,13 +365,21 @@ func (sm *SessionManager) AddThoughtToSession(sessionID string, thought *models.
 		}
 
 		if parent != nil {
+			if err := sm.checkAttachDepth(parent, thought); err != nil {
+				return err
+			}
 			parent.AddChild(thought)
 		} else {
 			session.RootThought = thought
 		}
 	}
+	session.RecordActivity(models.ActivityThoughtAdded, thought.Content)
 
-	return sm.UpdateSession(session)
+	if err := sm.UpdateSession(session); err != nil {
+		return err
+	}
+	sm.recordSnapshot(snapshot)
+	return nil
 }
 
 func (sm *SessionManager) UpdateThought(sessionID, thoughtID string, update *models.ThoughtUpdate) (*models.Thought, error) {
@@ -151,6 +392,7 @@ func (sm *SessionManager) UpdateThought(sessionID, thoughtID string, update *mod
 		return nil, err
 	}
 
+	snapshot := newSessionSnapshot(session, "update_thought")
 	thought, err := session.ApplyThoughtUpdate(thoughtID, update)
 	if err != nil {
 		return nil, err
@@ -163,19 +405,354 @@ func (sm *SessionManager) UpdateThought(sessionID, thoughtID string, update *mod
 	sm.mutex.Lock()
 	sm.cache[session.ID] = session
 	sm.mutex.Unlock()
+	sm.recordSnapshot(snapshot)
 
 	return thought, nil
 }
 
+// TagSession 以 tags 替换会话标签（可撤销），标签经过去重与长度校验。
+func (sm *SessionManager) TagSession(sessionID string, tags []string) (*models.Session, error) {
+	normalized, err := utils.NormalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := newSessionSnapshot(session, "tag_session")
+	session.Tags = normalized
+	if err := sm.UpdateSession(session); err != nil {
+		return nil, err
+	}
+	sm.recordSnapshot(snapshot)
+
+	return session, nil
+}
+
+// RestructureSession 按规格重建会话思维树（可撤销），结果超过最大深度时拒绝。
+func (sm *SessionManager) RestructureSession(sessionID string, spec *models.StructureSpec) (*models.Session, error) {
+	if spec == nil {
+		return nil, appErrors.ErrInvalidRequest
+	}
+
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	restructured := session.Clone()
+	if restructured == nil {
+		return nil, errors.New("session could not be copied")
+	}
+	if err := restructured.Restructure(spec); err != nil {
+		return nil, err
+	}
+	if subtreeHeight(restructured.RootThought) > sm.MaxThoughtDepth() {
+		return nil, utils.ValidationError(maxDepthReachedMessage)
+	}
+
+	snapshot := newSessionSnapshot(session, "auto_structure")
+	if err := sm.store.Update(restructured); err != nil {
+		return nil, err
+	}
+
+	sm.mutex.Lock()
+	sm.cache[restructured.ID] = restructured
+	sm.mutex.Unlock()
+	sm.recordSnapshot(snapshot)
+
+	return restructured, nil
+}
+
+// SetRootThought 以给定内容与方向新建根思维替换原根，原根的子思维改挂到新根下（可撤销）。
+// 未提供方向时使用默认根方向。
+func (sm *SessionManager) SetRootThought(sessionID, content string, direction *models.Direction) (*models.Session, error) {
+	content = strings.TrimSpace(utils.SanitizeHTML(content))
+	if err := utils.ValidateThoughtContent(content); err != nil {
+		return nil, err
+	}
+	rootDirection := models.RootDirection()
+	if direction != nil {
+		if err := utils.ValidateDirection(direction); err != nil {
+			return nil, err
+		}
+		rootDirection = *direction
+	}
+
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	root := models.NewThought(content, session.ID, rootDirection)
+
+	updated := session.Clone()
+	if updated == nil {
+		return nil, errors.New("session could not be copied")
+	}
+	if err := updated.SetRootThought(root); err != nil {
+		return nil, err
+	}
+	if subtreeHeight(updated.RootThought) > sm.MaxThoughtDepth() {
+		return nil, utils.ValidationError(maxDepthReachedMessage)
+	}
+
+	snapshot := newSessionSnapshot(session, "set_root")
+	if err := sm.store.Update(updated); err != nil {
+		return nil, err
+	}
+
+	sm.mutex.Lock()
+	sm.cache[updated.ID] = updated
+	sm.mutex.Unlock()
+	sm.recordSnapshot(snapshot)
+
+	return updated, nil
+}
+
+// CompactSession 删除相关度低于 minRelevance 的叶子思维（可撤销），返回删除数量。
+func (sm *SessionManager) CompactSession(sessionID string, minRelevance float64) (int, error) {
+	if minRelevance < 0 || minRelevance > 1 {
+		return 0, utils.ValidationError("min_relevance must be between 0 and 1")
+	}
+
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return 0, err
+	}
+
+	snapshot := newSessionSnapshot(session, "compact")
+	removed := session.Compact(minRelevance)
+	if removed == 0 {
+		return 0, nil
+	}
+	session.RecordActivity(models.ActivityThoughtDeleted, fmt.Sprintf("compacted %d thoughts below relevance %.2f", removed, minRelevance))
+	if err := sm.store.Update(session); err != nil {
+		return 0, err
+	}
+
+	sm.mutex.Lock()
+	sm.cache[session.ID] = session
+	sm.mutex.Unlock()
+	sm.recordSnapshot(snapshot)
+
+	return removed, nil
+}
+
+// FindThoughtByExternalID 按外部系统ID查找会话中的思维
+func (sm *SessionManager) FindThoughtByExternalID(sessionID, externalID string) (*models.Thought, error) {
+	if err := utils.ValidateExternalID(externalID); err != nil {
+		return nil, err
+	}
+
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	thought := session.FindThoughtByExternalID(externalID)
+	if thought == nil {
+		return nil, fmt.Errorf("%w: external id %s", appErrors.ErrThoughtNotFound, strings.TrimSpace(externalID))
+	}
+	return thought, nil
+}
+
+// SplitThought 将一个思维拆分为多个部分：原思维保留第一部分，其余成为其子思维。
+// manual 模式使用调用方提供的 parts，sentence/paragraph 模式自动切分原内容。
+func (sm *SessionManager) SplitThought(sessionID, thoughtID string, mode models.SplitMode, parts []string) (*models.Session, error) {
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	target, _ := session.FindThought(thoughtID)
+	if target == nil {
+		return nil, fmt.Errorf("%w: %s", appErrors.ErrThoughtNotFound, thoughtID)
+	}
+
+	switch mode {
+	case models.SplitManual:
+		cleaned := make([]string, 0, len(parts))
+		for _, part := range parts {
+			if trimmed := strings.TrimSpace(utils.SanitizeHTML(part)); trimmed != "" {
+				cleaned = append(cleaned, trimmed)
+			}
+		}
+		parts = cleaned
+	case models.SplitBySentence, models.SplitByParagraph:
+		parts = models.SplitThoughtContent(target.Content, mode)
+	default:
+		return nil, utils.ValidationError("split_by must be sentence, paragraph, or manual")
+	}
+
+	if len(parts) < 2 {
+		return nil, utils.ValidationError("splitting must produce at least 2 parts")
+	}
+	for _, part := range parts {
+		if err := utils.ValidateConcept(part); err != nil {
+			return nil, err
+		}
+	}
+	if target.Depth+1 > sm.MaxThoughtDepth() {
+		return nil, utils.ValidationError(maxDepthReachedMessage)
+	}
+
+	snapshot := newSessionSnapshot(session, "split_thought")
+	if _, err := session.SplitThought(thoughtID, parts); err != nil {
+		return nil, err
+	}
+	session.RecordActivity(models.ActivityThoughtAdded, fmt.Sprintf("split %q into %d thoughts", parts[0], len(parts)))
+
+	if err := sm.store.Update(session); err != nil {
+		return nil, err
+	}
+
+	sm.mutex.Lock()
+	sm.cache[session.ID] = session
+	sm.mutex.Unlock()
+	sm.recordSnapshot(snapshot)
+
+	return session, nil
+}
+
+// MergeThoughts 合并两个兄弟思维，保留 thoughtID 并移除 siblingID。
+func (sm *SessionManager) MergeThoughts(sessionID, thoughtID, siblingID, separator string, force bool) (*models.Session, error) {
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	target, _ := session.FindThought(thoughtID)
+	sibling, _ := session.FindThought(siblingID)
+	detail := ""
+	if target != nil && sibling != nil {
+		detail = fmt.Sprintf("merged %q into %q", sibling.Content, target.Content)
+		if separator == "" {
+			separator = models.DefaultMergeSeparator
+		}
+		if utf8.RuneCountInString(target.Content+separator+sibling.Content) > utils.MaxThoughtContentLength {
+			return nil, utils.ValidationError("merged content is too long")
+		}
+	}
+
+	snapshot := newSessionSnapshot(session, "merge_thoughts")
+	if _, err := session.MergeThoughts(thoughtID, siblingID, separator, force); err != nil {
+		return nil, err
+	}
+	session.RecordActivity(models.ActivityThoughtDeleted, detail)
+
+	if err := sm.store.Update(session); err != nil {
+		return nil, err
+	}
+
+	sm.mutex.Lock()
+	sm.cache[session.ID] = session
+	sm.mutex.Unlock()
+	sm.recordSnapshot(snapshot)
+
+	return session, nil
+}
+
 func (sm *SessionManager) DeleteThought(sessionID, thoughtID string) (*models.Session, error) {
 	session, err := sm.GetSession(sessionID)
 	if err != nil {
 		return nil, err
 	}
 
+	snapshot := newSessionSnapshot(session, "delete_thought")
+	deleted := ""
+	if target, _ := session.FindThought(thoughtID); target != nil {
+		deleted = target.Content
+	}
 	if err := session.RemoveThought(thoughtID); err != nil {
 		return nil, err
 	}
+	session.RecordActivity(models.ActivityThoughtDeleted, deleted)
+
+	if err := sm.store.Update(session); err != nil {
+		return nil, err
+	}
+
+	sm.mutex.Lock()
+	sm.cache[session.ID] = session
+	sm.mutex.Unlock()
+	sm.recordSnapshot(snapshot)
+
+	return session, nil
+}
+
+// AddContextEntry 向会话追加一条上下文（可撤销）。
+func (sm *SessionManager) AddContextEntry(sessionID string, entry models.ContextEntry) (*models.Session, error) {
+	entry.Value = strings.TrimSpace(entry.Value)
+	if entry.Value == "" {
+		return nil, utils.ValidationError("context value is required")
+	}
+
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := newSessionSnapshot(session, "update_context")
+	session.AddContextEntry(entry)
+	session.RecordActivity(models.ActivityContextUpdated, session.ContextEntries[len(session.ContextEntries)-1].String())
+
+	if err := sm.store.Update(session); err != nil {
+		return nil, err
+	}
+
+	sm.mutex.Lock()
+	sm.cache[session.ID] = session
+	sm.mutex.Unlock()
+	sm.recordSnapshot(snapshot)
+
+	return session, nil
+}
+
+// RemoveContextEntry 删除会话中指定位置的上下文（可撤销）。
+func (sm *SessionManager) RemoveContextEntry(sessionID string, index int) (*models.Session, error) {
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := newSessionSnapshot(session, "update_context")
+	session.EnsureContextEntries()
+	removed := ""
+	if index >= 0 && index < len(session.ContextEntries) {
+		removed = session.ContextEntries[index].String()
+	}
+	if err := session.RemoveContextAt(index); err != nil {
+		return nil, err
+	}
+	session.RecordActivity(models.ActivityContextUpdated, "removed "+removed)
+
+	if err := sm.store.Update(session); err != nil {
+		return nil, err
+	}
+
+	sm.mutex.Lock()
+	sm.cache[session.ID] = session
+	sm.mutex.Unlock()
+	sm.recordSnapshot(snapshot)
+
+	return session, nil
+}
+
+// UpdateContextEntry 替换会话中指定位置的上下文（可撤销）。
+func (sm *SessionManager) UpdateContextEntry(sessionID string, index int, entry models.ContextEntry) (*models.Session, error) {
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := newSessionSnapshot(session, "update_context")
+	if err := session.ReplaceContextAt(index, entry); err != nil {
+		return nil, err
+	}
+	session.RecordActivity(models.ActivityContextUpdated, session.ContextEntries[index].String())
 
 	if err := sm.store.Update(session); err != nil {
 		return nil, err
@@ -184,10 +761,53 @@ func (sm *SessionManager) DeleteThought(sessionID, thoughtID string) (*models.Se
 	sm.mutex.Lock()
 	sm.cache[session.ID] = session
 	sm.mutex.Unlock()
+	sm.recordSnapshot(snapshot)
 
 	return session, nil
 }
 
+// AddSessionUsage 将 LLM 用量累加到会话并持久化（不记入撤销历史）。
+func (sm *SessionManager) AddSessionUsage(sessionID string, usage models.SessionUsage) error {
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	session.Usage.Merge(usage)
+	if err := sm.store.Update(session); err != nil {
+		return err
+	}
+
+	sm.mutex.Lock()
+	sm.cache[session.ID] = session
+	sm.mutex.Unlock()
+
+	return nil
+}
+
+// UserUsage 汇总用户所有会话的 LLM 用量与费用。
+func (sm *SessionManager) UserUsage(userID string) (models.SessionUsage, error) {
+	sessions, err := sm.ListSessions(userID)
+	if err != nil {
+		return models.SessionUsage{}, err
+	}
+
+	var total models.SessionUsage
+	for _, session := range sessions {
+		total.Merge(session.Usage)
+	}
+	return total, nil
+}
+
+// SessionActivity 返回会话的活动日志，按时间从新到旧排列。
+func (sm *SessionManager) SessionActivity(sessionID string) ([]models.ActivityEntry, error) {
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return session.RecentActivity(), nil
+}
+
 func (sm *SessionManager) ListSessions(userID string) ([]*models.Session, error) {
 	id := strings.TrimSpace(userID)
 	if id == "" {
@@ -228,6 +848,55 @@ func (sm *SessionManager) ListSessions(userID string) ([]*models.Session, error)
 	return filtered, nil
 }
 
+// SearchSessions 按概念子串与会话标签（均不区分大小写，同时给出时需都满足）查找用户会话，
+// 两者都为空时等同于 ListSessions。结果按更新时间倒序。
+func (sm *SessionManager) SearchSessions(userID, concept, tag string) ([]*models.Session, error) {
+	id := strings.TrimSpace(userID)
+	if id == "" {
+		return nil, appErrors.ErrInvalidRequest
+	}
+	concept = strings.TrimSpace(concept)
+	tag = strings.TrimSpace(tag)
+	if concept == "" && tag == "" {
+		return sm.ListSessions(id)
+	}
+
+	var sessions []*models.Session
+	var err error
+	if concept != "" {
+		sessions, err = sm.store.SearchByConcept(id, concept)
+	} else {
+		sessions, err = sm.store.GetByUserID(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]*models.Session, 0, len(sessions))
+	for _, session := range sessions {
+		if session == nil {
+			continue
+		}
+		if tag != "" && !slices.ContainsFunc(session.Tags, func(candidate string) bool {
+			return strings.EqualFold(strings.TrimSpace(candidate), tag)
+		}) {
+			continue
+		}
+		filtered = append(filtered, session)
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return filtered[i].UpdatedAt.After(filtered[j].UpdatedAt)
+	})
+
+	sm.mutex.Lock()
+	for _, session := range filtered {
+		sm.cache[session.ID] = session
+	}
+	sm.mutex.Unlock()
+
+	return filtered, nil
+}
+
 func (sm *SessionManager) GetActiveSessionsByUser(userID string) ([]*models.Session, error) {
 	sessions, err := sm.ListSessions(userID)
 	if err != nil {
@@ -243,6 +912,22 @@ func (sm *SessionManager) GetActiveSessionsByUser(userID string) ([]*models.Sess
 	return active, nil
 }
 
+// UserTimeline 汇总用户所有会话的活动时间线
+func (sm *SessionManager) UserTimeline(userID string, bucket time.Duration) ([]models.TimelineBucket, error) {
+	sessions, err := sm.ListSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	timelines := make([][]models.TimelineBucket, 0, len(sessions))
+	for _, session := range sessions {
+		if session != nil {
+			timelines = append(timelines, session.Timeline(bucket))
+		}
+	}
+	return models.MergeTimelines(bucket, timelines...), nil
+}
+
 func (sm *SessionManager) CleanupExpiredSessions() error {
 	threshold := time.Now().Add(-24 * time.Hour)
 	sessions, err := sm.store.GetExpiredSessions(threshold)
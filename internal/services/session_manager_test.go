@@ -1,9 +1,12 @@
 package services_test
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
+	appErrors "WideMindsMCP/internal/errors"
 	"WideMindsMCP/internal/models"
 	"WideMindsMCP/internal/services"
 	"WideMindsMCP/internal/storage"
@@ -89,3 +92,335 @@ func TestSessionManagerListSessions(t *testing.T) {
 		t.Fatalf("expected first session second, got %s", sessions[1].ID)
 	}
 }
+
+func TestSessionManagerEnforcesMaxThoughtDepth(t *testing.T) {
+	manager := services.NewSessionManager(storage.NewInMemorySessionStore())
+	manager.SetMaxThoughtDepth(3)
+
+	session, err := manager.CreateSession("depth-user", "root")
+	if err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+
+	parentID := session.RootThought.ID
+	var shallowID string
+	for depth := 1; depth <= 3; depth++ {
+		thought := models.NewThought("level", session.ID, models.Direction{Type: models.Deep, Title: "chain"})
+		id := parentID
+		thought.ParentID = &id
+		if err := manager.AddThoughtToSession(session.ID, thought); err != nil {
+			t.Fatalf("attach at depth %d failed: %v", depth, err)
+		}
+		if thought.Depth != depth {
+			t.Fatalf("expected depth %d, got %d", depth, thought.Depth)
+		}
+		if depth == 1 {
+			shallowID = thought.ID
+		}
+		parentID = thought.ID
+	}
+
+	tooDeep := models.NewThought("too deep", session.ID, models.Direction{Type: models.Deep, Title: "chain"})
+	tooDeep.ParentID = &parentID
+	err = manager.AddThoughtToSession(session.ID, tooDeep)
+	if !errors.Is(err, appErrors.ErrInvalidRequest) {
+		t.Fatalf("expected validation error past the depth limit, got %v", err)
+	}
+
+	branch := models.NewThought("branch", session.ID, models.Direction{Type: models.Lateral, Title: "branch"})
+	branch.ParentID = &shallowID
+	if err := manager.AddThoughtToSession(session.ID, branch); err != nil {
+		t.Fatalf("attach to shallower parent failed: %v", err)
+	}
+
+	expander := services.NewThoughtExpander(services.NewLLMOrchestrator("", "", ""), manager)
+	if _, err := expander.DeepDive(session.ID, "", models.Direction{Type: models.Deep, Title: "dive"}, 4); !errors.Is(err, appErrors.ErrInvalidRequest) {
+		t.Fatalf("expected deep dive beyond the limit to fail, got %v", err)
+	}
+}
+
+func TestSessionManagerUndoRedo(t *testing.T) {
+	manager := services.NewSessionManager(storage.NewInMemorySessionStore())
+
+	session, err := manager.CreateSession("undo-user", "root")
+	if err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	if _, err := manager.Undo(session.ID); !errors.Is(err, appErrors.ErrInvalidRequest) {
+		t.Fatalf("expected undo on fresh session to fail, got %v", err)
+	}
+
+	thought := models.NewThought("child", session.ID, models.Direction{Type: models.Broad, Title: "child"})
+	if err := manager.AddThoughtToSession(session.ID, thought); err != nil {
+		t.Fatalf("add thought failed: %v", err)
+	}
+	if _, err := manager.DeleteThought(session.ID, thought.ID); err != nil {
+		t.Fatalf("delete thought failed: %v", err)
+	}
+
+	restored, err := manager.Undo(session.ID)
+	if err != nil {
+		t.Fatalf("undo failed: %v", err)
+	}
+	if _, ok := restored.GetThoughtTree()[thought.ID]; !ok {
+		t.Fatalf("expected undo to restore deleted thought")
+	}
+
+	restored, err = manager.Undo(session.ID)
+	if err != nil {
+		t.Fatalf("second undo failed: %v", err)
+	}
+	if got := restored.GetMetadata().TotalThoughts; got != 1 {
+		t.Fatalf("expected only the root after undoing add, got %d thoughts", got)
+	}
+
+	redone, err := manager.Redo(session.ID)
+	if err != nil {
+		t.Fatalf("redo failed: %v", err)
+	}
+	if _, ok := redone.GetThoughtTree()[thought.ID]; !ok {
+		t.Fatalf("expected redo to re-apply added thought")
+	}
+
+	fetched, err := manager.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("get session failed: %v", err)
+	}
+	if fetched != redone {
+		t.Fatalf("expected cache to hold the restored session")
+	}
+}
+
+func TestSessionManagerUndoIsBounded(t *testing.T) {
+	manager := services.NewSessionManager(storage.NewInMemorySessionStore())
+
+	session, err := manager.CreateSession("undo-user", "root")
+	if err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	for i := 0; i < 12; i++ {
+		thought := models.NewThought("child", session.ID, models.Direction{Type: models.Broad, Title: "child"})
+		if err := manager.AddThoughtToSession(session.ID, thought); err != nil {
+			t.Fatalf("add thought failed: %v", err)
+		}
+	}
+
+	undone := 0
+	for {
+		if _, err := manager.Undo(session.ID); err != nil {
+			break
+		}
+		undone++
+	}
+	if undone != 10 {
+		t.Fatalf("expected 10 undo steps, got %d", undone)
+	}
+}
+
+func TestSessionManagerSplitThought(t *testing.T) {
+	manager := services.NewSessionManager(storage.NewInMemorySessionStore())
+	session, err := manager.CreateSession("split-user", "root")
+	if err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+
+	direction := models.Direction{Type: models.Deep, Title: "Energy"}
+	thought := models.NewThought("Solar is cheap. Wind is variable! Storage closes the gap?", session.ID, direction)
+	if err := manager.AddThoughtToSession(session.ID, thought); err != nil {
+		t.Fatalf("add thought failed: %v", err)
+	}
+
+	updated, err := manager.SplitThought(session.ID, thought.ID, models.SplitBySentence, nil)
+	if err != nil {
+		t.Fatalf("split failed: %v", err)
+	}
+
+	split, _ := updated.FindThought(thought.ID)
+	if split.Content != "Solar is cheap." {
+		t.Fatalf("expected original to keep the first sentence, got %q", split.Content)
+	}
+	if len(split.Children) != 2 {
+		t.Fatalf("expected 2 new children, got %d", len(split.Children))
+	}
+	for i, want := range []string{"Wind is variable!", "Storage closes the gap?"} {
+		child := split.Children[i]
+		if child.Content != want || child.Direction.Title != "Energy" || child.Direction.Type != models.Deep {
+			t.Fatalf("unexpected child %d: %+v", i, child)
+		}
+	}
+
+	if _, err := manager.SplitThought(session.ID, thought.ID, models.SplitManual, []string{"only one"}); !errors.Is(err, appErrors.ErrInvalidRequest) {
+		t.Fatalf("expected single-part split to fail, got %v", err)
+	}
+	if _, err := manager.SplitThought(session.ID, thought.ID, models.SplitManual, []string{"ok", strings.Repeat("x", 201)}); !errors.Is(err, appErrors.ErrInvalidRequest) {
+		t.Fatalf("expected oversized part to fail validation, got %v", err)
+	}
+}
+
+func TestSessionManagerUserTimeline(t *testing.T) {
+	manager := services.NewSessionManager(storage.NewInMemorySessionStore())
+	for _, concept := range []string{"first", "second"} {
+		if _, err := manager.CreateSession("timeline-user", concept); err != nil {
+			t.Fatalf("create session failed: %v", err)
+		}
+	}
+	if _, err := manager.CreateSession("someone-else", "other"); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+
+	timeline, err := manager.UserTimeline("timeline-user", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("user timeline failed: %v", err)
+	}
+	total := 0
+	for _, bucket := range timeline {
+		total += bucket.Count
+	}
+	if total != 2 {
+		t.Fatalf("expected 2 root thoughts across the user's sessions, got %d", total)
+	}
+}
+
+func TestSessionManagerExternalIDs(t *testing.T) {
+	manager := services.NewSessionManager(storage.NewInMemorySessionStore())
+	session, err := manager.CreateSession("external-user", "root")
+	if err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+
+	externalID := "PROJ-42"
+	externalURL := "https://tracker.example.com/browse/PROJ-42"
+	update := &models.ThoughtUpdate{ExternalID: &externalID, ExternalSystemURL: &externalURL}
+	if _, err := manager.UpdateThought(session.ID, session.RootThought.ID, update); err != nil {
+		t.Fatalf("update external reference failed: %v", err)
+	}
+
+	found, err := manager.FindThoughtByExternalID(session.ID, "PROJ-42")
+	if err != nil {
+		t.Fatalf("find by external id failed: %v", err)
+	}
+	if found.ID != session.RootThought.ID || found.ExternalSystemURL != externalURL {
+		t.Fatalf("unexpected thought %+v", found)
+	}
+
+	if _, err := manager.FindThoughtByExternalID(session.ID, "PROJ-404"); !errors.Is(err, appErrors.ErrThoughtNotFound) {
+		t.Fatalf("expected missing external id to return ErrThoughtNotFound, got %v", err)
+	}
+	if _, err := manager.FindThoughtByExternalID(session.ID, strings.Repeat("x", 129)); !errors.Is(err, appErrors.ErrInvalidRequest) {
+		t.Fatalf("expected oversized external id to fail validation, got %v", err)
+	}
+}
+
+func TestSessionManagerRecordsActivity(t *testing.T) {
+	manager := services.NewSessionManager(storage.NewInMemorySessionStore())
+	session, err := manager.CreateSession("activity-user", "Energy")
+	if err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+
+	thought := models.NewThought("Solar", session.ID, models.Direction{Type: models.Broad, Title: "Solar"})
+	if err := manager.AddThoughtToSession(session.ID, thought); err != nil {
+		t.Fatalf("add thought failed: %v", err)
+	}
+	if _, err := manager.AddContextEntry(session.ID, models.NewContextEntry(models.ContextGoal, "cut emissions")); err != nil {
+		t.Fatalf("add context failed: %v", err)
+	}
+	if _, err := manager.DeleteThought(session.ID, thought.ID); err != nil {
+		t.Fatalf("delete thought failed: %v", err)
+	}
+
+	activity, err := manager.SessionActivity(session.ID)
+	if err != nil {
+		t.Fatalf("session activity failed: %v", err)
+	}
+	expected := []models.ActivityAction{models.ActivityThoughtDeleted, models.ActivityContextUpdated, models.ActivityThoughtAdded}
+	if len(activity) != len(expected) {
+		t.Fatalf("expected %d activity entries, got %+v", len(expected), activity)
+	}
+	for i, action := range expected {
+		if activity[i].Action != action {
+			t.Fatalf("entry %d: expected %s, got %s", i, action, activity[i].Action)
+		}
+	}
+	if activity[0].Detail != "Solar" || activity[0].Timestamp.Before(activity[2].Timestamp) {
+		t.Fatalf("unexpected newest entry %+v", activity[0])
+	}
+
+	stored, err := manager.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("get session failed: %v", err)
+	}
+	doc, err := stored.Export(models.ExportJSON, models.DefaultExportOptions())
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	restored, err := models.ParseSessionJSON(doc.Data)
+	if err != nil {
+		t.Fatalf("parse exported session failed: %v", err)
+	}
+	if len(restored.ActivityLog) != len(expected) || restored.ActivityLog[0].Action != models.ActivityThoughtAdded {
+		t.Fatalf("expected activity log to survive export, got %+v", restored.ActivityLog)
+	}
+}
+
+func TestSearchSessionsCombinesConceptAndTag(t *testing.T) {
+	manager := services.NewSessionManager(storage.NewInMemorySessionStore())
+	for _, item := range []struct {
+		concept string
+		tags    []string
+	}{
+		{"Solar storage", []string{"energy"}},
+		{"Storage lockers", []string{"retail"}},
+		{"Wind farms", []string{"energy"}},
+	} {
+		session, err := manager.CreateSession("user", item.concept)
+		if err != nil {
+			t.Fatalf("CreateSession failed: %v", err)
+		}
+		session.Tags = item.tags
+		if err := manager.UpdateSession(session); err != nil {
+			t.Fatalf("UpdateSession failed: %v", err)
+		}
+	}
+
+	sessions, err := manager.SearchSessions("user", "stor", "")
+	if err != nil || len(sessions) != 2 {
+		t.Fatalf("expected two concept matches, got %d (%v)", len(sessions), err)
+	}
+	sessions, err = manager.SearchSessions("user", "stor", "Energy")
+	if err != nil || len(sessions) != 1 || sessions[0].RootThought.Content != "Solar storage" {
+		t.Fatalf("expected only the energy storage session, got %d (%v)", len(sessions), err)
+	}
+	sessions, err = manager.SearchSessions("user", "", "energy")
+	if err != nil || len(sessions) != 2 || sessions[0].UpdatedAt.Before(sessions[1].UpdatedAt) {
+		t.Fatalf("expected two energy sessions newest first, got %d (%v)", len(sessions), err)
+	}
+}
+
+func TestImportSessionSanitizesTree(t *testing.T) {
+	manager := services.NewSessionManager(storage.NewInMemorySessionStore())
+	imported := models.NewSession("", "<script>alert(1)</script>Energy")
+	child := models.NewThought(`<img src=x onerror=alert(1)>Solar`, imported.ID, models.Direction{
+		Type:        models.Deep,
+		Title:       "<b>Panels</b>",
+		Description: `<a href="javascript:alert(1)">cells</a>`,
+		Keywords:    []string{"<i>pv</i>"},
+	})
+	imported.RootThought.AddChild(child)
+
+	session, err := manager.ImportSession("alice", imported)
+	if err != nil {
+		t.Fatalf("ImportSession failed: %v", err)
+	}
+	for _, thought := range session.GetThoughtTree() {
+		for _, text := range append([]string{thought.Content, thought.Direction.Title, thought.Direction.Description}, thought.Direction.Keywords...) {
+			if strings.ContainsAny(text, "<>") {
+				t.Fatalf("expected imported text to be sanitized, got %q", text)
+			}
+		}
+	}
+	solar := session.GetThoughtTree()[child.ID]
+	if solar == nil || solar.Content != "Solar" || strings.Join(solar.GetPath(), "/") != session.RootThought.Content+"/Solar" {
+		t.Fatalf("expected sanitized content and path, got %+v", solar)
+	}
+}
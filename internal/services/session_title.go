@@ -0,0 +1,185 @@
+//Session Title Suggestion(会话标题建议)
+
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const (
+	SessionTitleCacheTTL  = 10 * time.Minute
+	sessionTitleCacheSize = 128
+	maxTitleThoughts      = 8
+	titleMaxTokens        = 32
+	titleTemperature      = 0.7
+)
+
+// 结构体
+type SessionTitle struct {
+	SuggestedTitle string `json:"suggested_title"`
+}
+
+// 方法
+// SuggestSessionTitle 请求 LLM 根据概念与主要思维给出简洁的会话标题，结果缓存 SessionTitleCacheTTL；
+// 未配置后端、调用失败或标题未通过概念校验时回退为首字母大写的原概念。
+func (llm *LLMOrchestrator) SuggestSessionTitle(concept string, thoughts []string) (string, error) {
+	concept = strings.TrimSpace(concept)
+	if err := utils.ValidateConcept(concept); err != nil {
+		return "", err
+	}
+	fallback := fallbackSessionTitle(concept)
+	if !llm.hasRemoteBackend() {
+		return fallback, nil
+	}
+
+	key := sessionTitleCacheKey(concept, thoughts)
+	if llm.titles != nil {
+		if cached, ok := llm.titles.get(key); ok {
+			return cached.Content, nil
+		}
+	}
+
+	context := make([]models.ContextEntry, 0, len(thoughts))
+	for _, thought := range thoughts {
+		context = append(context, models.NewContextEntry(models.ContextNote, thought))
+	}
+	resp, err := llm.CallLLM(&LLMRequest{
+		Prompt:      llm.BuildPrompt(concept, context, "title"),
+		Temperature: float64Ptr(titleTemperature),
+		MaxTokens:   titleMaxTokens,
+	})
+	if errors.Is(err, appErrors.ErrBudgetExceeded) || isCanceled(err) {
+		return "", err
+	} else if err != nil {
+		utils.Warn("LLM call failed while suggesting a session title", utils.KV("error", err))
+		return fallback, nil
+	}
+
+	title := cleanSessionTitle(resp.Content)
+	if err := utils.ValidateConcept(title); err != nil {
+		utils.Warn("LLM suggested an invalid session title", utils.KV("title", truncate(resp.Content, 200)), utils.KV("error", err))
+		return fallback, nil
+	}
+	if llm.titles != nil {
+		llm.titles.put(key, &LLMResponse{Content: title})
+	}
+	return title, nil
+}
+
+// GenerateSessionTitle 为会话建议标题；apply 为 true 时通过 RenameSession 写入（可撤销）。
+func (te *ThoughtExpander) GenerateSessionTitle(sessionID string, apply bool) (*SessionTitle, error) {
+	if te == nil || te.generator == nil {
+		return nil, errors.New("thought expander is not initialized")
+	}
+	if sessionID == "" {
+		return nil, appErrors.ErrInvalidRequest
+	}
+
+	session, err := te.sessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.RootThought == nil {
+		return nil, errors.New("session has no thoughts")
+	}
+	generator, flushUsage := te.trackUsage(generatorForUser(te.generator, session.UserID), session.ID)
+	title, err := suggestSessionTitle(generator, session.RootThought.Content, collectTitleThoughts(session))
+	flushUsage()
+	if err != nil {
+		return nil, err
+	}
+	if apply {
+		if _, err := te.sessionManager.RenameSession(sessionID, title); err != nil {
+			return nil, err
+		}
+	}
+	return &SessionTitle{SuggestedTitle: title}, nil
+}
+
+// RenameSession 将根思维内容改为 title 并更新各节点路径（可撤销）。
+func (sm *SessionManager) RenameSession(sessionID, title string) (*models.Session, error) {
+	title = strings.TrimSpace(utils.SanitizeHTML(title))
+	if err := utils.ValidateConcept(title); err != nil {
+		return nil, err
+	}
+
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.RootThought == nil {
+		return nil, errors.New("session has no thoughts")
+	}
+
+	snapshot := newSessionSnapshot(session, "rename_session")
+	session.RootThought.Content = title
+	session.NormalizeTree()
+	if err := sm.UpdateSession(session); err != nil {
+		return nil, err
+	}
+	sm.recordSnapshot(snapshot)
+
+	return session, nil
+}
+
+// 函数
+// collectTitleThoughts 按广度优先取最多 maxTitleThoughts 个非根思维内容。
+func collectTitleThoughts(session *models.Session) []string {
+	thoughts := make([]string, 0, maxTitleThoughts)
+	queue := []*models.Thought{session.RootThought}
+	for len(queue) > 0 && len(thoughts) < maxTitleThoughts {
+		thought := queue[0]
+		queue = queue[1:]
+		if thought == nil {
+			continue
+		}
+		if content := strings.TrimSpace(thought.Content); !thought.IsRoot() && content != "" {
+			thoughts = append(thoughts, content)
+		}
+		queue = append(queue, thought.Children...)
+	}
+	return thoughts
+}
+
+func sessionTitleCacheKey(concept string, thoughts []string) string {
+	hash := sha256.New()
+	hash.Write([]byte(concept))
+	for _, thought := range thoughts {
+		hash.Write([]byte{0})
+		hash.Write([]byte(thought))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// cleanSessionTitle 取回复的第一行，去掉 "Title:" 前缀、引号与结尾标点。
+func cleanSessionTitle(content string) string {
+	text, _ := stripCodeFences(content)
+	if line, _, ok := strings.Cut(text, "\n"); ok {
+		text = line
+	}
+	text = strings.TrimSpace(text)
+	if prefix, rest, ok := strings.Cut(text, ":"); ok && strings.EqualFold(strings.TrimSpace(prefix), "title") {
+		text = rest
+	}
+	text = strings.Trim(strings.TrimSpace(text), "\"'“”‘’`*")
+	return strings.TrimRight(strings.TrimSpace(text), ".。!！")
+}
+
+func fallbackSessionTitle(concept string) string {
+	first, size := utf8.DecodeRuneInString(concept)
+	if first == utf8.RuneError {
+		return concept
+	}
+	return string(unicode.ToUpper(first)) + concept[size:]
+}
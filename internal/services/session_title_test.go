@@ -0,0 +1,77 @@
+package services
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+)
+
+func TestSuggestSessionTitleCachesLLMResult(t *testing.T) {
+	var prompts []string
+	server, calls := newScriptedBackend(t, http.StatusOK, []string{`Title: "Storing the Sun"`}, &prompts)
+	llm := newRepairTestLLM(t, server.URL)
+	llm.SetResponseCache(0, 0)
+
+	for i := 0; i < 2; i++ {
+		title, err := llm.SuggestSessionTitle("solar", []string{"Home batteries", "Grid tariffs"})
+		if err != nil {
+			t.Fatalf("SuggestSessionTitle failed: %v", err)
+		}
+		if title != "Storing the Sun" {
+			t.Fatalf("expected the cleaned LLM title, got %q", title)
+		}
+	}
+	if atomic.LoadInt32(calls) != 1 {
+		t.Fatalf("expected the second suggestion to come from the cache, got %d calls", *calls)
+	}
+	if !strings.Contains(prompts[0], "Home batteries") {
+		t.Fatalf("expected the thoughts in the prompt, got %q", prompts[0])
+	}
+}
+
+func TestSuggestSessionTitleFallsBackOnInvalidTitle(t *testing.T) {
+	server, _ := newScriptedBackend(t, http.StatusOK, []string{strings.Repeat("very long title ", 20)}, nil)
+	llm := newRepairTestLLM(t, server.URL)
+
+	title, err := llm.SuggestSessionTitle("solar", nil)
+	if err != nil || title != "Solar" {
+		t.Fatalf("expected the capitalized concept, got %q (%v)", title, err)
+	}
+}
+
+func TestGenerateSessionTitleAppliesRename(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	expander := NewThoughtExpander(NewLLMOrchestrator("", "", ""), manager)
+	session, err := manager.CreateSession("user", "énergie")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	child := models.NewThought("Batteries", session.ID, models.Direction{Type: models.Deep, Title: "Storage"})
+	if err := manager.AddThoughtToSession(session.ID, child); err != nil {
+		t.Fatalf("AddThoughtToSession failed: %v", err)
+	}
+
+	suggestion, err := expander.GenerateSessionTitle(session.ID, false)
+	if err != nil || suggestion.SuggestedTitle != "Énergie" {
+		t.Fatalf("expected the capitalized concept, got %+v (%v)", suggestion, err)
+	}
+	if stored, _ := manager.GetSession(session.ID); stored.RootThought.Content != "énergie" {
+		t.Fatalf("expected no rename without apply, got %q", stored.RootThought.Content)
+	}
+
+	if _, err := expander.GenerateSessionTitle(session.ID, true); err != nil {
+		t.Fatalf("GenerateSessionTitle failed: %v", err)
+	}
+	stored, err := manager.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	renamed, _ := stored.FindThought(child.ID)
+	if stored.RootThought.Content != "Énergie" || renamed.GetPath()[0] != "Énergie" {
+		t.Fatalf("expected the root and child path renamed, got %q / %q", stored.RootThought.Content, renamed.GetPath())
+	}
+}
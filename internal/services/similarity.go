@@ -0,0 +1,163 @@
+//Session Similarity(相似会话检索)
+
+package services
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const (
+	DefaultSimilarSessions = 5
+	MaxSimilarSessions     = 50
+
+	// maxLazyEmbeddingsPerSearch 限制单次检索中为缺少向量的会话补算的数量，避免一次请求触发过多上游调用。
+	maxLazyEmbeddingsPerSearch = 20
+)
+
+// 方法
+// SetEmbedder 设置相似会话检索使用的向量服务；为 nil 时 FindSimilarSessions 返回 ErrEmbeddingsUnavailable。
+func (sm *SessionManager) SetEmbedder(embedder Embedder) {
+	sm.mutex.Lock()
+	sm.embedder = embedder
+	sm.mutex.Unlock()
+}
+
+// FindSimilarSessions 按向量余弦相似度返回同一用户下与目标会话最相似的 topN 个会话。
+// 向量在首次使用时生成并随会话持久化；无法获得向量的会话会被跳过。
+func (sm *SessionManager) FindSimilarSessions(sessionID string, topN int) ([]*models.Session, error) {
+	if topN <= 0 {
+		topN = DefaultSimilarSessions
+	}
+	if topN > MaxSimilarSessions {
+		return nil, utils.ValidationError("top is too large")
+	}
+
+	sm.mutex.RLock()
+	embedder := sm.embedder
+	sm.mutex.RUnlock()
+	if embedder == nil {
+		return nil, appErrors.ErrEmbeddingsUnavailable
+	}
+
+	target, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	targetEmbedding, err := sm.ensureEmbedding(embedder, target)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := sm.ListSessions(target.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	type scoredSession struct {
+		session *models.Session
+		score   float64
+	}
+	scored := make([]scoredSession, 0, len(candidates))
+	lazyBudget := maxLazyEmbeddingsPerSearch
+	skipped := 0
+	for _, candidate := range candidates {
+		if candidate.ID == target.ID {
+			continue
+		}
+		embedding := cachedEmbedding(embedder, candidate)
+		if embedding == nil && lazyBudget > 0 {
+			lazyBudget--
+			if embedding, err = sm.ensureEmbedding(embedder, candidate); err != nil {
+				utils.Warn("failed to embed session", utils.KV("session_id", candidate.ID), utils.KV("error", err))
+			}
+		}
+		if len(embedding) == 0 || len(embedding) != len(targetEmbedding) {
+			skipped++
+			continue
+		}
+		scored = append(scored, scoredSession{session: candidate, score: cosineSimilarity(targetEmbedding, embedding)})
+	}
+	if skipped > 0 {
+		utils.Info("similar session search skipped sessions without embeddings",
+			utils.KV("session_id", target.ID),
+			utils.KV("skipped", skipped),
+		)
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
+	})
+	if len(scored) > topN {
+		scored = scored[:topN]
+	}
+
+	similar := make([]*models.Session, 0, len(scored))
+	for _, item := range scored {
+		similar = append(similar, item.session)
+	}
+	return similar, nil
+}
+
+// ensureEmbedding 返回会话的有效向量，缺失或内容已变化时重新生成并持久化（不记入撤销历史）。
+func (sm *SessionManager) ensureEmbedding(embedder Embedder, session *models.Session) ([]float64, error) {
+	if embedding := cachedEmbedding(embedder, session); embedding != nil {
+		return embedding, nil
+	}
+
+	text := session.EmbeddingText()
+	if strings.TrimSpace(text) == "" {
+		return nil, utils.ValidationError("session has no content to embed")
+	}
+	embedding, err := embedder.GetEmbedding(text)
+	if err != nil {
+		return nil, err
+	}
+
+	session.Embedding = embedding
+	session.EmbeddingKey = session.EmbeddingFingerprint(embedder.EmbeddingModel())
+	if err := sm.store.Update(session); err != nil {
+		return nil, err
+	}
+
+	sm.mutex.Lock()
+	sm.cache[session.ID] = session
+	sm.mutex.Unlock()
+
+	return embedding, nil
+}
+
+// 函数
+// cachedEmbedding 返回与当前模型及会话内容匹配的已缓存向量，否则返回 nil。
+func cachedEmbedding(embedder Embedder, session *models.Session) []float64 {
+	if session == nil || len(session.Embedding) == 0 {
+		return nil
+	}
+	if session.EmbeddingKey != session.EmbeddingFingerprint(embedder.EmbeddingModel()) {
+		return nil
+	}
+	return session.Embedding
+}
+
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+)
+
+// keywordEmbedder 按关键词出现与否生成向量，含 "broken" 的文本返回错误。
+type keywordEmbedder struct {
+	calls int
+}
+
+func (e *keywordEmbedder) GetEmbedding(text string) ([]float64, error) {
+	e.calls++
+	if strings.Contains(text, "broken") {
+		return nil, errors.New("embedding failed")
+	}
+	vector := make([]float64, 0, 3)
+	for _, keyword := range []string{"solar", "wind", "cooking"} {
+		if strings.Contains(strings.ToLower(text), keyword) {
+			vector = append(vector, 1)
+		} else {
+			vector = append(vector, 0)
+		}
+	}
+	return vector, nil
+}
+
+func (e *keywordEmbedder) EmbeddingModel() string {
+	return "keywords"
+}
+
+func TestFindSimilarSessionsRanksByCosineSimilarity(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	embedder := &keywordEmbedder{}
+	manager.SetEmbedder(embedder)
+
+	create := func(userID, concept string) *models.Session {
+		t.Helper()
+		session, err := manager.CreateSession(userID, concept)
+		if err != nil {
+			t.Fatalf("CreateSession failed: %v", err)
+		}
+		return session
+	}
+	target := create("user", "Solar and wind energy")
+	solar := create("user", "Solar panels")
+	cooking := create("user", "Cooking pasta")
+	create("user", "broken session")
+	create("other", "Solar and wind energy")
+
+	similar, err := manager.FindSimilarSessions(target.ID, 5)
+	if err != nil {
+		t.Fatalf("FindSimilarSessions failed: %v", err)
+	}
+	if len(similar) != 2 || similar[0].ID != solar.ID || similar[1].ID != cooking.ID {
+		t.Fatalf("expected solar then cooking, got %+v", similar)
+	}
+
+	stored, err := manager.GetSession(solar.ID)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if len(stored.Embedding) != 3 || stored.EmbeddingKey == "" {
+		t.Fatalf("expected embedding to be cached on the session, got %+v", stored.Embedding)
+	}
+
+	calls := embedder.calls
+	if _, err := manager.FindSimilarSessions(target.ID, 1); err != nil {
+		t.Fatalf("second search failed: %v", err)
+	}
+	if embedder.calls != calls+1 {
+		t.Fatalf("expected only the failing session to be re-embedded, got %d new calls", embedder.calls-calls)
+	}
+
+	if err := manager.AddThoughtToSession(cooking.ID, models.NewThought("Wind-dried tomatoes", cooking.ID, models.Direction{Type: models.Broad, Title: "Ingredients"})); err != nil {
+		t.Fatalf("AddThoughtToSession failed: %v", err)
+	}
+	similar, err = manager.FindSimilarSessions(target.ID, 1)
+	if err != nil {
+		t.Fatalf("search after edit failed: %v", err)
+	}
+	if len(similar) != 1 || similar[0].ID != solar.ID {
+		t.Fatalf("expected top result to stay solar, got %+v", similar)
+	}
+	if edited, _ := manager.GetSession(cooking.ID); len(edited.Embedding) != 3 || edited.Embedding[1] != 1 {
+		t.Fatalf("expected edited session to be re-embedded, got %v", edited.Embedding)
+	}
+
+	if _, err := manager.FindSimilarSessions(target.ID, MaxSimilarSessions+1); !errors.Is(err, appErrors.ErrInvalidRequest) {
+		t.Fatalf("expected oversized top to be rejected, got %v", err)
+	}
+	if _, err := NewSessionManager(storage.NewInMemorySessionStore()).FindSimilarSessions(target.ID, 5); !errors.Is(err, appErrors.ErrEmbeddingsUnavailable) {
+		t.Fatalf("expected ErrEmbeddingsUnavailable without an embedder, got %v", err)
+	}
+}
+
+func TestGetEmbeddingCallsEmbeddingsEndpoint(t *testing.T) {
+	var path, model string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		var payload struct {
+			Model string `json:"model"`
+			Input string `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		model = payload.Model
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"data":  []map[string]any{{"embedding": []float64{0.1, 0.2, 0.3}}},
+			"usage": map[string]int{"total_tokens": 3},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	llm := NewLLMOrchestrator("key", server.URL, "model")
+	llm.SetEmbeddingModel("embed-small")
+	embedding, err := llm.GetEmbedding("solar energy")
+	if err != nil {
+		t.Fatalf("GetEmbedding failed: %v", err)
+	}
+	if len(embedding) != 3 || path != "/v1/embeddings" || model != "embed-small" {
+		t.Fatalf("unexpected embedding call: path=%s model=%s embedding=%v", path, model, embedding)
+	}
+
+	if _, err := NewLLMOrchestrator("", "", "").GetEmbedding("solar"); !errors.Is(err, appErrors.ErrEmbeddingsUnavailable) {
+		t.Fatalf("expected ErrEmbeddingsUnavailable without a backend, got %v", err)
+	}
+}
@@ -0,0 +1,278 @@
+//LLM Streaming(流式补全)
+
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 常量
+const (
+	defaultStreamTimeout = 2 * time.Minute
+	maxStreamLineBytes   = 1024 * 1024
+)
+
+// 结构体
+type streamHook struct {
+	ctx     context.Context
+	onDelta func(string)
+}
+
+type streamChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		Text string `json:"text"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// 方法
+// WithStream 返回一个编排器副本，其 CallLLM 改为流式调用：增量内容交给 onDelta，上游请求随 ctx 取消。
+func (llm *LLMOrchestrator) WithStream(ctx context.Context, onDelta func(string)) *LLMOrchestrator {
+	if llm == nil {
+		return nil
+	}
+	clone := *llm
+	clone.stream = &streamHook{ctx: ctx, onDelta: onDelta}
+	return &clone
+}
+
+// WithContext 返回一个编排器副本，其 CallLLM 的上游请求随 ctx 取消（仍受超时设置约束）。
+func (llm *LLMOrchestrator) WithContext(ctx context.Context) *LLMOrchestrator {
+	if llm == nil {
+		return nil
+	}
+	clone := *llm
+	clone.ctx = ctx
+	return &clone
+}
+
+// requestContext 返回 CallLLM 使用的父 context，未绑定时为 Background。
+func (llm *LLMOrchestrator) requestContext() context.Context {
+	if llm == nil || llm.ctx == nil {
+		return context.Background()
+	}
+	return llm.ctx
+}
+
+func (llm *LLMOrchestrator) withoutStream() *LLMOrchestrator {
+	if llm == nil || llm.stream == nil {
+		return llm
+	}
+	clone := *llm
+	clone.stream = nil
+	return &clone
+}
+
+// CallLLMStream 以 SSE 方式请求 chat completions，逐段回调 onDelta，结束后返回拼接完整的响应与用量。
+// 流式调用不做重试：已输出的增量无法撤回。
+func (llm *LLMOrchestrator) CallLLMStream(ctx context.Context, req *LLMRequest, onDelta func(string)) (*LLMResponse, error) {
+	call, remote, err := llm.prepareCall(req)
+	if err != nil {
+		return nil, err
+	}
+	if !remote || llm.isOllama() {
+		// Ollama 原生接口不走 SSE：整段请求后一次性交付
+		var resp *LLMResponse
+		if remote {
+			resp, err = llm.withoutStream().CallLLM(req)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			resp = llm.localLLMResponse(call.prompt, call.maxTokens)
+		}
+		if onDelta != nil && resp.Content != "" {
+			onDelta(resp.Content)
+		}
+		return resp, nil
+	}
+
+	cacheKey, cached := llm.cachedResponse(req, call)
+	if cached != nil {
+		if onDelta != nil {
+			onDelta(cached.Content)
+		}
+		return cached, nil
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultStreamTimeout)
+		defer cancel()
+	}
+
+	if err := llm.waitForRateLimit(ctx); err != nil {
+		return nil, err
+	}
+	release, err := llm.acquireCallSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	started := time.Now()
+	resp, err := llm.streamRemote(ctx, call, onDelta)
+	release()
+	llm.recordCall(call, resp, err, started, true)
+	if err != nil {
+		return nil, err
+	}
+
+	llm.chargeUsage(call, resp.Usage)
+	llm.reportUsage(call, resp)
+	llm.storeResponse(cacheKey, llm, call, resp)
+	return resp, nil
+}
+
+// streamRemote 经熔断器向上游发起一次流式请求。
+func (llm *LLMOrchestrator) streamRemote(ctx context.Context, call *llmCall, onDelta func(string)) (*LLMResponse, error) {
+	body, err := llm.buildPayload(call, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := llm.breaker.Allow(); err != nil {
+		return nil, err
+	}
+	resp, err := llm.streamOnce(ctx, body, onDelta)
+	if err != nil {
+		if isUpstreamFailure(err) {
+			llm.breaker.RecordFailure()
+		} else {
+			llm.breaker.RecordSuccess()
+		}
+		return nil, err
+	}
+	llm.breaker.RecordSuccess()
+	return resp, nil
+}
+
+func (llm *LLMOrchestrator) streamOnce(ctx context.Context, body []byte, onDelta func(string)) (*LLMResponse, error) {
+	// 提前返回（流中出错）时取消上游请求
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	reqHTTP, err := http.NewRequestWithContext(ctx, http.MethodPost, llm.endpoint(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("new http request: %w", err)
+	}
+	reqHTTP.Header.Set("Content-Type", "application/json")
+	reqHTTP.Header.Set("Accept", "text/event-stream")
+	llm.setAuthHeader(reqHTTP)
+
+	// 客户端超时会截断长时间的流，改由 ctx 控制整体时长
+	client := *llm.httpClient
+	client.Timeout = 0
+	resp, err := client.Do(reqHTTP)
+	if err != nil {
+		return nil, fmt.Errorf("llm request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxLLMResponseBytes))
+		return nil, &llmHTTPError{status: resp.StatusCode, body: truncate(string(raw), 512)}
+	}
+
+	var (
+		content strings.Builder
+		usage   TokenUsage
+		model   string
+	)
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineBytes)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk streamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, &llmParseError{err: fmt.Errorf("decode llm stream chunk: %w", err)}
+		}
+		if chunk.Error != nil {
+			return nil, fmt.Errorf("llm stream error: %s", chunk.Error.Message)
+		}
+		if chunk.Model != "" {
+			model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			usage = TokenUsage{
+				PromptTokens:     chunk.Usage.PromptTokens,
+				CompletionTokens: chunk.Usage.CompletionTokens,
+				TotalTokens:      chunk.Usage.TotalTokens,
+			}
+		}
+		for _, choice := range chunk.Choices {
+			delta := choice.Delta.Content
+			if delta == "" {
+				delta = choice.Text
+			}
+			if delta == "" {
+				continue
+			}
+			content.WriteString(delta)
+			if onDelta != nil {
+				onDelta(delta)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, fmt.Errorf("read llm stream: %w", err)
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+
+	final := strings.TrimSpace(content.String())
+	if final == "" {
+		return nil, errors.New("llm response empty")
+	}
+	if model == "" {
+		model = llm.reportedModel()
+	}
+
+	return &LLMResponse{
+		Content:   final,
+		Usage:     usage,
+		Model:     model,
+		Timestamp: time.Now().UTC(),
+		Attempts:  1,
+	}, nil
+}
+
+// 函数
+// isCanceled 判断错误是否由调用方取消（例如客户端断开）导致。
+func isCanceled(err error) bool {
+	return errors.Is(err, context.Canceled)
+}
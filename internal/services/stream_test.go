@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeStreamChunk(w http.ResponseWriter, data string) {
+	fmt.Fprintf(w, "data: %s\n\n", data)
+	w.(http.Flusher).Flush()
+}
+
+func TestCallLLMStreamDeliversDeltasIncrementally(t *testing.T) {
+	firstReceived := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		writeStreamChunk(w, `{"model":"stream-model","choices":[{"delta":{"content":"Hel"}}]}`)
+		select {
+		case <-firstReceived:
+		case <-time.After(2 * time.Second):
+			return
+		}
+		writeStreamChunk(w, `{"choices":[{"delta":{"content":"lo"}}]}`)
+		writeStreamChunk(w, `{"choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`)
+		writeStreamChunk(w, "[DONE]")
+	}))
+	t.Cleanup(server.Close)
+
+	orchestrator := NewLLMOrchestrator("key", server.URL, "model")
+	var deltas []string
+	resp, err := orchestrator.CallLLMStream(context.Background(), &LLMRequest{Prompt: "hi"}, func(delta string) {
+		deltas = append(deltas, delta)
+		if len(deltas) == 1 {
+			close(firstReceived)
+		}
+	})
+	if err != nil {
+		t.Fatalf("CallLLMStream returned error: %v", err)
+	}
+	if strings.Join(deltas, "|") != "Hel|lo" {
+		t.Fatalf("unexpected deltas %q", deltas)
+	}
+	if resp.Content != strings.Join(deltas, "") || resp.Model != "stream-model" || resp.Usage.TotalTokens != 5 {
+		t.Fatalf("unexpected final response %+v", resp)
+	}
+}
+
+func TestCallLLMStreamMidStreamError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeStreamChunk(w, `{"choices":[{"delta":{"content":"partial"}}]}`)
+		writeStreamChunk(w, `{"error":{"message":"overloaded"}}`)
+	}))
+	t.Cleanup(server.Close)
+
+	orchestrator := NewLLMOrchestrator("key", server.URL, "model")
+	if _, err := orchestrator.CallLLMStream(context.Background(), &LLMRequest{Prompt: "hi"}, nil); err == nil || !strings.Contains(err.Error(), "overloaded") {
+		t.Fatalf("expected mid-stream error, got %v", err)
+	}
+}
+
+func TestCallLLMStreamCancelsUpstreamOnDisconnect(t *testing.T) {
+	upstreamCancelled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeStreamChunk(w, `{"choices":[{"delta":{"content":"first"}}]}`)
+		select {
+		case <-r.Context().Done():
+			close(upstreamCancelled)
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	orchestrator := NewLLMOrchestrator("key", server.URL, "model")
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := orchestrator.CallLLMStream(ctx, &LLMRequest{Prompt: "hi"}, func(string) { cancel() })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	select {
+	case <-upstreamCancelled:
+	case <-time.After(time.Second):
+		t.Fatalf("expected upstream request to be cancelled")
+	}
+	if status := orchestrator.CircuitStatus(); status.Failures != 0 {
+		t.Fatalf("expected client disconnect not to count as an upstream failure, got %+v", status)
+	}
+}
@@ -0,0 +1,109 @@
+//Auto Structuring(自动重组)
+
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const maxStructureThoughts = 150
+
+var structureGroupOrder = []models.DirectionType{models.Broad, models.Deep, models.Lateral, models.Critical, ""}
+
+var structureGroupTitles = map[models.DirectionType]string{
+	models.Broad:    "Broad",
+	models.Deep:     "Deep",
+	models.Lateral:  "Lateral",
+	models.Critical: "Critical",
+	"":              "Other",
+}
+
+// 方法
+// ProposeStructure 请求 LLM 将会话中的思维按层级分组，返回重组规格；失败时回退为按方向类型分组。
+func (llm *LLMOrchestrator) ProposeStructure(session *models.Session) (*models.StructureSpec, error) {
+	if session == nil || session.RootThought == nil {
+		return nil, errors.New("session has no thoughts")
+	}
+
+	views := session.FlattenThoughts(models.FlattenOptions{Order: models.TraversalDFS})
+	if len(views) > maxStructureThoughts {
+		return nil, utils.ValidationError(fmt.Sprintf("session has too many thoughts to auto-structure (max %d)", maxStructureThoughts))
+	}
+
+	entries := make([]models.ContextEntry, 0, len(views)+1)
+	for _, thought := range views {
+		entries = append(entries, models.NewContextEntry(models.ContextNote, fmt.Sprintf("id=%s | %s", thought.ID, truncate(thought.Content, 160))))
+	}
+	entries = append(entries, models.NewContextEntry(models.ContextGoal, "reference every listed id exactly once"))
+	prompt := llm.BuildPrompt(session.RootThought.Content, entries, "structure")
+
+	if llm.hasRemoteBackend() {
+		resp, err := llm.CallLLM(&LLMRequest{
+			Prompt:      prompt,
+			Temperature: float64Ptr(0.3),
+			MaxTokens:   2048,
+		})
+		if errors.Is(err, appErrors.ErrBudgetExceeded) {
+			return nil, err
+		} else if err != nil {
+			utils.Warn("LLM call failed while structuring session", utils.KV("error", err))
+		} else if resp != nil {
+			if spec, parseErr := parseStructureSpec(resp.Content); parseErr != nil {
+				utils.Warn("failed to parse LLM structure response", utils.KV("error", parseErr))
+			} else {
+				return spec, nil
+			}
+		}
+	}
+
+	return structureByDirection(session), nil
+}
+
+// 函数
+func parseStructureSpec(content string) (*models.StructureSpec, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end <= start {
+		return nil, errors.New("structure response does not contain a JSON object")
+	}
+
+	var spec models.StructureSpec
+	if err := json.Unmarshal([]byte(content[start:end+1]), &spec); err != nil {
+		return nil, err
+	}
+	if len(spec.Children) == 0 {
+		return nil, errors.New("structure response has no children")
+	}
+	return &spec, nil
+}
+
+// structureByDirection 保留原根，将其余思维按方向类型分组平铺。
+func structureByDirection(session *models.Session) *models.StructureSpec {
+	groups := map[models.DirectionType][]models.StructureNode{}
+	for _, view := range session.FlattenThoughts(models.FlattenOptions{Order: models.TraversalDFS}) {
+		if view.ID == session.RootThought.ID {
+			continue
+		}
+		directionType := view.Direction.Type
+		if _, known := structureGroupTitles[directionType]; !known {
+			directionType = ""
+		}
+		groups[directionType] = append(groups[directionType], models.StructureNode{ID: view.ID})
+	}
+
+	spec := &models.StructureSpec{RootID: session.RootThought.ID}
+	for _, directionType := range structureGroupOrder {
+		if nodes := groups[directionType]; len(nodes) > 0 {
+			spec.Children = append(spec.Children, models.StructureNode{Group: structureGroupTitles[directionType], Children: nodes})
+		}
+	}
+	return spec
+}
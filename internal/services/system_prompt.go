@@ -0,0 +1,69 @@
+//System Prompt Overrides(系统提示词覆盖)
+
+package services
+
+import (
+	"strings"
+)
+
+// 常量
+const (
+	DefaultSystemPrompt = "You are an assistant that returns valid JSON matching the user's instructions."
+
+	additionalConstraintsHeading = "## Additional constraints\n"
+)
+
+// 方法
+// SetSystemPromptPrefix 设置部署级的系统消息固定前缀，请求中的覆盖无法移除该前缀。
+func (llm *LLMOrchestrator) SetSystemPromptPrefix(prefix string) {
+	if llm == nil {
+		return
+	}
+	llm.systemPromptPrefix = strings.TrimSpace(prefix)
+}
+
+// WithPromptOverrides 返回一个编排器副本：systemPrompt 非空时替换默认系统消息，
+// extra 作为附加约束写入生成的提示词。
+func (llm *LLMOrchestrator) WithPromptOverrides(systemPrompt string, extra []string) *LLMOrchestrator {
+	if llm == nil {
+		return nil
+	}
+	clone := *llm
+	if trimmed := strings.TrimSpace(systemPrompt); trimmed != "" {
+		clone.systemPrompt = trimmed
+	}
+	if len(extra) > 0 {
+		clone.extraInstructions = append(append([]string(nil), llm.extraInstructions...), extra...)
+	}
+	return &clone
+}
+
+// systemMessage 依次使用请求覆盖、编排器覆盖与默认系统消息，并在前面拼接固定前缀。
+func (llm *LLMOrchestrator) systemMessage(override string) string {
+	message := strings.TrimSpace(override)
+	if message == "" {
+		message = llm.systemPrompt
+	}
+	if message == "" {
+		message = DefaultSystemPrompt
+	}
+	if llm.systemPromptPrefix == "" {
+		return message
+	}
+	return llm.systemPromptPrefix + "\n\n" + message
+}
+
+// 函数
+// appendExtraInstructions 将附加指令作为约束小节追加到提示词末尾。
+func appendExtraInstructions(prompt string, extra []string) string {
+	var builder strings.Builder
+	for _, instruction := range extra {
+		if trimmed := strings.TrimSpace(instruction); trimmed != "" {
+			builder.WriteString("- " + trimmed + "\n")
+		}
+	}
+	if builder.Len() == 0 {
+		return prompt
+	}
+	return prompt + "\n\n" + additionalConstraintsHeading + strings.TrimSpace(builder.String())
+}
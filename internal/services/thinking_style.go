@@ -0,0 +1,51 @@
+//Thinking Style(思考风格)
+
+package services
+
+import (
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 方法
+// WithTemperature 返回一个编排器副本，其未指定温度的调用使用 temperature；非正值恢复默认温度。
+func (llm *LLMOrchestrator) WithTemperature(temperature float64) *LLMOrchestrator {
+	if llm == nil {
+		return nil
+	}
+	clone := *llm
+	clone.styleTemperature = temperature
+	return &clone
+}
+
+// SetDefaultThinkingStyle 设置请求与会话画像均未指定风格时使用的思考风格，空值表示沿用 LLM 默认温度。
+func (te *ThoughtExpander) SetDefaultThinkingStyle(style models.ThinkingStyle) error {
+	if te == nil {
+		return nil
+	}
+	if style != "" && !style.IsValid() {
+		return utils.ValidationError("thinking_style must be one of focused, balanced, creative")
+	}
+	te.thinkingStyle = style
+	return nil
+}
+
+// resolveThinkingStyle 按请求、会话画像、扩展器默认值的顺序确定思考风格。
+func (te *ThoughtExpander) resolveThinkingStyle(req *ExpansionRequest) (models.ThinkingStyle, error) {
+	if req.ThinkingStyle != "" {
+		if !req.ThinkingStyle.IsValid() {
+			return "", utils.ValidationError("thinking_style must be one of focused, balanced, creative")
+		}
+		return req.ThinkingStyle, nil
+	}
+	if req.SessionID != "" && te.sessionManager != nil {
+		session, err := te.sessionManager.GetSession(req.SessionID)
+		if err != nil {
+			return "", err
+		}
+		if profile := models.NewUserProfile(session); profile != nil && profile.ThinkingStyle != "" {
+			return profile.ThinkingStyle, nil
+		}
+	}
+	return te.thinkingStyle, nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+)
+
+// newTemperatureServer 记录每次聊天请求的 temperature。
+func newTemperatureServer(t *testing.T) (*httptest.Server, func() []float64) {
+	t.Helper()
+	var (
+		mutex        sync.Mutex
+		temperatures []float64
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Temperature float64 `json:"temperature"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		mutex.Lock()
+		temperatures = append(temperatures, payload.Temperature)
+		mutex.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"content": "ok"}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []float64 {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]float64(nil), temperatures...)
+	}
+}
+
+func expandTemperatures(t *testing.T, expander *ThoughtExpander, req *ExpansionRequest, recorded func() []float64) []float64 {
+	t.Helper()
+	before := len(recorded())
+	if _, err := expander.Expand(req); err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	temperatures := recorded()[before:]
+	if len(temperatures) == 0 {
+		t.Fatalf("expected LLM calls during expansion")
+	}
+	return temperatures
+}
+
+func TestExpandAppliesThinkingStyleTemperature(t *testing.T) {
+	server, recorded := newTemperatureServer(t)
+	llm := NewLLMOrchestrator("key", server.URL, "model")
+	// 固定语言以跳过使用独立温度的语言检测调用
+	if err := llm.SetForceResponseLanguage("en"); err != nil {
+		t.Fatalf("SetForceResponseLanguage failed: %v", err)
+	}
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	expander := NewThoughtExpander(llm, manager)
+
+	session, err := manager.CreateSession("user", "Batteries")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	if _, err := manager.AddContextEntry(session.ID, models.NewContextEntry(models.ContextPreference, "thinking style: focused")); err != nil {
+		t.Fatalf("AddContextEntry failed: %v", err)
+	}
+	if err := expander.SetDefaultThinkingStyle(models.ThinkingBalanced); err != nil {
+		t.Fatalf("SetDefaultThinkingStyle failed: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		req      *ExpansionRequest
+		expected float64
+	}{
+		{"request", &ExpansionRequest{Concept: "Batteries", MaxDirections: 2, ThinkingStyle: models.ThinkingCreative, SessionID: session.ID}, 1.1},
+		{"session profile", &ExpansionRequest{Concept: "Batteries", MaxDirections: 2, SessionID: session.ID}, 0.3},
+		{"expander default", &ExpansionRequest{Concept: "Batteries", MaxDirections: 2}, 0.7},
+	}
+	for _, tc := range cases {
+		for _, temperature := range expandTemperatures(t, expander, tc.req, recorded) {
+			if temperature != tc.expected {
+				t.Fatalf("%s: expected temperature %.1f, got %.1f", tc.name, tc.expected, temperature)
+			}
+		}
+	}
+}
+
+func TestExpandRejectsUnknownThinkingStyle(t *testing.T) {
+	expander := NewThoughtExpander(NewScriptedLLM(), NewSessionManager(storage.NewInMemorySessionStore()))
+	if _, err := expander.Expand(&ExpansionRequest{Concept: "Batteries", ThinkingStyle: "chaotic"}); !errors.Is(err, appErrors.ErrInvalidRequest) {
+		t.Fatalf("expected invalid request, got %v", err)
+	}
+	if err := expander.SetDefaultThinkingStyle("chaotic"); !errors.Is(err, appErrors.ErrInvalidRequest) {
+		t.Fatalf("expected invalid default style to be rejected, got %v", err)
+	}
+}
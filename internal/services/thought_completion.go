@@ -0,0 +1,146 @@
+//Thought Completion(思维补全)
+
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const (
+	completionMaxTokens   = 256
+	completionTemperature = 0.5
+)
+
+// 结构体
+// ThoughtCompletion 是补全结果；Applied 为 true 时 Content 已写入思维。
+type ThoughtCompletion struct {
+	ThoughtID string `json:"thought_id"`
+	Content   string `json:"content"`
+	Applied   bool   `json:"applied"`
+}
+
+// 方法
+// CompleteThought 以从根到父节点的路径为上下文请求 "completion" 提示词，返回已写内容加续写后的完整思维；
+// 未配置后端、调用失败或续写为空时原样返回已写内容，超出预算时返回错误。
+func (llm *LLMOrchestrator) CompleteThought(path []string, partial string) (string, error) {
+	partial = strings.TrimSpace(partial)
+	if partial == "" {
+		return "", utils.ValidationError("partial_content is required")
+	}
+	if !llm.hasRemoteBackend() {
+		return partial, nil
+	}
+
+	concept := partial
+	if len(path) > 0 && strings.TrimSpace(path[0]) != "" {
+		concept = strings.TrimSpace(path[0])
+	}
+	resp, err := llm.CallLLM(&LLMRequest{
+		Prompt:      llm.BuildPrompt(concept, buildCompletionContext(path, partial), "completion"),
+		Temperature: float64Ptr(completionTemperature),
+		MaxTokens:   completionMaxTokens,
+	})
+	if errors.Is(err, appErrors.ErrBudgetExceeded) || isCanceled(err) {
+		return "", err
+	} else if err != nil {
+		utils.Warn("LLM call failed while completing a thought", utils.KV("error", err))
+		return partial, nil
+	}
+	return joinCompletion(partial, resp.Content), nil
+}
+
+// CompleteThought 为会话中的思维补全 partial；apply 为 true 时校验补全内容后写入思维（可撤销）。
+func (te *ThoughtExpander) CompleteThought(sessionID, thoughtID, partial string, apply bool) (*ThoughtCompletion, error) {
+	if te == nil || te.generator == nil {
+		return nil, errors.New("thought expander is not initialized")
+	}
+	if sessionID == "" || thoughtID == "" {
+		return nil, appErrors.ErrInvalidRequest
+	}
+	partial = strings.TrimSpace(partial)
+	if partial == "" {
+		return nil, utils.ValidationError("partial_content is required")
+	}
+	if err := utils.ValidateThoughtContent(partial); err != nil {
+		return nil, err
+	}
+
+	session, err := te.sessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	thought, _ := session.FindThought(thoughtID)
+	if thought == nil {
+		return nil, fmt.Errorf("%w: %s", appErrors.ErrThoughtNotFound, thoughtID)
+	}
+	path := thought.GetPath()
+	if len(path) > 0 {
+		// 最后一项是思维当前的内容，由 partial 取代
+		path = path[:len(path)-1]
+	}
+
+	generator, flushUsage := te.trackUsage(generatorForUser(te.generator, session.UserID), session.ID)
+	content, err := completeThought(generator, path, partial)
+	flushUsage()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ThoughtCompletion{ThoughtID: thoughtID, Content: content}
+	if !apply {
+		return result, nil
+	}
+	update := &models.ThoughtUpdate{Content: &content}
+	if err := utils.ValidateThoughtUpdate(update); err != nil {
+		return nil, err
+	}
+	if _, err := te.sessionManager.UpdateThought(sessionID, thoughtID, update); err != nil {
+		return nil, err
+	}
+	result.Content = *update.Content
+	result.Applied = true
+	return result, nil
+}
+
+// 函数
+func buildCompletionContext(path []string, partial string) []models.ContextEntry {
+	entries := make([]models.ContextEntry, 0, 2)
+	if len(path) > 0 {
+		entries = append(entries, models.NewContextEntry(models.ContextHistory, strings.Join(path, " > ")))
+	}
+	entries = append(entries, models.NewContextEntry(models.ContextNote, "partial thought: "+partial))
+	return entries
+}
+
+// joinCompletion 将续写接在已写内容之后；模型重复了已写内容时直接使用其输出。
+// 续写以标点开头或两侧为中日韩文字时不插入空格。
+func joinCompletion(partial, continuation string) string {
+	continuation = strings.Trim(strings.TrimSpace(continuation), "\"'`“”")
+	continuation = strings.TrimSpace(continuation)
+	if continuation == "" {
+		return partial
+	}
+	if strings.HasPrefix(strings.ToLower(continuation), strings.ToLower(partial)) {
+		return continuation
+	}
+
+	last, _ := utf8.DecodeLastRuneInString(partial)
+	first, _ := utf8.DecodeRuneInString(continuation)
+	if unicode.IsPunct(first) || isCJK(last) || isCJK(first) {
+		return partial + continuation
+	}
+	return partial + " " + continuation
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+)
+
+func newCompletionSession(t *testing.T, manager *SessionManager) (*models.Session, *models.Thought) {
+	t.Helper()
+	session, err := manager.CreateSession("user", "Battery storage")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	thought := models.NewThought("Recycling draft", session.ID, models.Direction{Type: models.Deep, Title: "Recycling"})
+	if err := manager.AddThoughtToSession(session.ID, thought); err != nil {
+		t.Fatalf("AddThoughtToSession failed: %v", err)
+	}
+	return session, thought
+}
+
+func TestCompleteThoughtReturnsCompletionWithoutSaving(t *testing.T) {
+	var prompts []string
+	server, _ := newScriptedBackend(t, http.StatusOK, []string{`"recovers lithium at a fraction of the mining cost."`}, &prompts)
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	session, thought := newCompletionSession(t, manager)
+	expander := NewThoughtExpander(newRepairTestLLM(t, server.URL), manager)
+
+	completion, err := expander.CompleteThought(session.ID, thought.ID, "Closed-loop recycling", false)
+	if err != nil {
+		t.Fatalf("CompleteThought failed: %v", err)
+	}
+	if completion.Content != "Closed-loop recycling recovers lithium at a fraction of the mining cost." || completion.Applied {
+		t.Fatalf("unexpected completion %+v", completion)
+	}
+	if len(prompts) != 1 || !strings.Contains(prompts[0], "partial thought: Closed-loop recycling") || !strings.Contains(prompts[0], "Battery storage") {
+		t.Fatalf("expected prompt to include the partial content and path, got %q", prompts)
+	}
+	if strings.Contains(prompts[0], "Recycling draft") {
+		t.Fatalf("expected the thought's current content to be replaced by the partial content, got %q", prompts[0])
+	}
+
+	stored, _ := manager.GetSession(session.ID)
+	if found, _ := stored.FindThought(thought.ID); found.Content != "Recycling draft" {
+		t.Fatalf("expected thought to stay unchanged, got %q", found.Content)
+	}
+}
+
+func TestCompleteThoughtAppliesValidatedContent(t *testing.T) {
+	server, _ := newScriptedBackend(t, http.StatusOK, []string{"回收锂的成本更低。", strings.Repeat("long ", 100)}, nil)
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	session, thought := newCompletionSession(t, manager)
+	expander := NewThoughtExpander(newRepairTestLLM(t, server.URL), manager)
+
+	completion, err := expander.CompleteThought(session.ID, thought.ID, "闭环回收", true)
+	if err != nil {
+		t.Fatalf("CompleteThought failed: %v", err)
+	}
+	if completion.Content != "闭环回收回收锂的成本更低。" || !completion.Applied {
+		t.Fatalf("unexpected completion %+v", completion)
+	}
+	stored, _ := manager.GetSession(session.ID)
+	if found, _ := stored.FindThought(thought.ID); found.Content != completion.Content {
+		t.Fatalf("expected completion to be saved, got %q", found.Content)
+	}
+
+	if _, err := expander.CompleteThought(session.ID, thought.ID, "Recycling", true); err == nil {
+		t.Fatal("expected an overlong completion to fail validation")
+	}
+}
+
+func TestCompleteThoughtValidatesInput(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	session, thought := newCompletionSession(t, manager)
+	expander := NewThoughtExpander(NewLLMOrchestrator("", "", ""), manager)
+
+	if _, err := expander.CompleteThought(session.ID, thought.ID, "  ", false); err == nil {
+		t.Fatal("expected an error for empty partial content")
+	}
+	if _, err := expander.CompleteThought(session.ID, "missing", "Recycling", false); !errors.Is(err, appErrors.ErrThoughtNotFound) {
+		t.Fatalf("expected ErrThoughtNotFound, got %v", err)
+	}
+	completion, err := expander.CompleteThought(session.ID, thought.ID, "Recycling", false)
+	if err != nil || completion.Content != "Recycling" {
+		t.Fatalf("expected partial content to be returned without a backend, got %+v (%v)", completion, err)
+	}
+}
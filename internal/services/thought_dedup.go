@@ -0,0 +1,133 @@
+//Thought Deduplication(新思维查重)
+
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 枚举类型
+// ThoughtDedupPolicy 决定探索生成的思维与目标子树中已有节点近似重复时的处理方式。
+type ThoughtDedupPolicy string
+
+// 常量
+const (
+	// ThoughtDedupAnnotate 照常插入新节点，并在其注解中记录 duplicate_of。
+	ThoughtDedupAnnotate ThoughtDedupPolicy = "annotate"
+	// ThoughtDedupSkip 不插入新节点，直接返回已有节点（注解中带 duplicate_of）。
+	ThoughtDedupSkip ThoughtDedupPolicy = "skip"
+
+	// DefaultThoughtDedupThreshold 为判定近似重复的相似度（向量余弦相似度或词集合 Jaccard 系数）
+	DefaultThoughtDedupThreshold = 0.9
+)
+
+// 方法
+// SetThoughtDedup 设置新思维查重的相似度阈值（0-1）与处理策略，阈值为 0 表示关闭查重，空策略视为 annotate。
+func (te *ThoughtExpander) SetThoughtDedup(threshold float64, policy ThoughtDedupPolicy) {
+	if te == nil || threshold < 0 {
+		return
+	}
+	if policy == "" {
+		policy = ThoughtDedupAnnotate
+	}
+	te.thoughtDedupThreshold = threshold
+	te.thoughtDedupPolicy = policy
+}
+
+// findDuplicateThought 在以 root 为根的子树中查找与新思维内容最相似且达到阈值的节点。
+// 配置了向量服务时比较最新的 maxSimilarityComparisons 个节点的余弦相似度，
+// 未配置、请求失败或没有节点完成向量比较时退回到全部节点的词集合 Jaccard 系数。
+func (te *ThoughtExpander) findDuplicateThought(root, thought *models.Thought) (*models.Thought, float64) {
+	threshold := te.thoughtDedupThreshold
+	if threshold <= 0 || root == nil || thought == nil || strings.TrimSpace(thought.Content) == "" {
+		return nil, 0
+	}
+
+	candidates := make([]*models.Thought, 0)
+	queue := []*models.Thought{root}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == nil {
+			continue
+		}
+		if strings.TrimSpace(current.Content) != "" {
+			candidates = append(candidates, current)
+		}
+		queue = append(queue, current.Children...)
+	}
+	if len(candidates) == 0 {
+		return nil, 0
+	}
+
+	var (
+		closest    *models.Thought
+		similarity float64
+	)
+	if matched, score, ok := te.closestByEmbedding(candidates, thought.Content); ok {
+		closest, similarity = matched, score
+	} else {
+		closest, similarity = closestByTokens(candidates, thought.Content)
+	}
+	if closest == nil || similarity < threshold {
+		return nil, 0
+	}
+	return closest, similarity
+}
+
+// closestByEmbedding 返回向量最相近的节点，与相似度告警共用 nearestThought 与其向量缓存；
+// 未配置向量服务、新内容的向量请求失败或没有任何节点完成比较时 ok 为 false。
+func (te *ThoughtExpander) closestByEmbedding(candidates []*models.Thought, content string) (*models.Thought, float64, bool) {
+	te.sessionManager.mutex.RLock()
+	embedder := te.sessionManager.embedder
+	te.sessionManager.mutex.RUnlock()
+	if embedder == nil {
+		return nil, 0, false
+	}
+
+	match, ok, err := te.sessionManager.nearestThought(embedder, candidates, content)
+	if err != nil {
+		utils.Debug("embedding unavailable for thought dedup; using token overlap", utils.KV("error", err))
+		return nil, 0, false
+	}
+	if !ok {
+		utils.Debug("no thought embeddings to compare for dedup; using token overlap")
+		return nil, 0, false
+	}
+	return match.thought, match.similarity, true
+}
+
+// markDuplicate 在节点注解中记录近似重复的节点及相似度。
+func markDuplicate(thought, duplicateOf *models.Thought, similarity float64) {
+	thought.SetAnnotation("duplicate_of", duplicateOf.ID)
+	thought.SetAnnotation("duplicate_similarity", fmt.Sprintf("%.2f", similarity))
+}
+
+// 函数
+// ParseThoughtDedupPolicy 解析配置中的查重策略，空值视为 annotate。
+func ParseThoughtDedupPolicy(value string) (ThoughtDedupPolicy, error) {
+	switch policy := ThoughtDedupPolicy(strings.ToLower(strings.TrimSpace(value))); policy {
+	case "", ThoughtDedupAnnotate:
+		return ThoughtDedupAnnotate, nil
+	case ThoughtDedupSkip:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown thought dedup policy %q", value)
+	}
+}
+
+func closestByTokens(candidates []*models.Thought, content string) (*models.Thought, float64) {
+	target := titleTokens(content)
+	var closest *models.Thought
+	best := 0.0
+	for _, candidate := range candidates {
+		if score := jaccard(target, titleTokens(candidate.Content)); score > best {
+			closest, best = candidate, score
+		}
+	}
+	return closest, best
+}
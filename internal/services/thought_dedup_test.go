@@ -0,0 +1,148 @@
+package services
+
+import (
+	"testing"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+)
+
+func newRepeatingExpander(t *testing.T, policy ThoughtDedupPolicy) (*ThoughtExpander, *SessionManager, *models.Session) {
+	t.Helper()
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	llm := NewScriptedLLM()
+	for i := 0; i < 2; i++ {
+		llm.QueueThoughts([]*models.Thought{models.NewThought("Rooftop solar panels cut grid demand", "", models.Direction{Type: models.Deep, Title: "Rooftop"})}, nil)
+	}
+	expander := NewThoughtExpander(llm, manager)
+	expander.SetThoughtDedup(DefaultThoughtDedupThreshold, policy)
+
+	session, err := manager.CreateSession("user", "Energy transition")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	return expander, manager, session
+}
+
+func TestExploreDirectionAnnotatesDuplicateThought(t *testing.T) {
+	expander, manager, session := newRepeatingExpander(t, ThoughtDedupAnnotate)
+	direction := models.Direction{Type: models.Deep, Title: "Rooftop"}
+
+	first, err := expander.ExploreDirection(direction, session.ID, "")
+	if err != nil {
+		t.Fatalf("ExploreDirection failed: %v", err)
+	}
+	if _, ok := first.Annotations["duplicate_of"]; ok {
+		t.Fatalf("expected first thought not to be flagged, got %+v", first.Annotations)
+	}
+
+	second, err := expander.ExploreDirection(direction, session.ID, "")
+	if err != nil {
+		t.Fatalf("ExploreDirection failed: %v", err)
+	}
+	if second.ID == first.ID || second.Annotations["duplicate_of"] != first.ID || second.Annotations["duplicate_similarity"] != "1.00" {
+		t.Fatalf("expected new thought annotated as duplicate of %s, got %s %+v", first.ID, second.ID, second.Annotations)
+	}
+
+	stored, err := manager.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if len(stored.RootThought.Children) != 2 {
+		t.Fatalf("expected both thoughts to be inserted, got %d", len(stored.RootThought.Children))
+	}
+}
+
+func TestExploreDirectionSkipsDuplicateThought(t *testing.T) {
+	expander, manager, session := newRepeatingExpander(t, ThoughtDedupSkip)
+	direction := models.Direction{Type: models.Deep, Title: "Rooftop"}
+
+	first, err := expander.ExploreDirection(direction, session.ID, "")
+	if err != nil {
+		t.Fatalf("ExploreDirection failed: %v", err)
+	}
+	second, err := expander.ExploreDirection(direction, session.ID, "")
+	if err != nil {
+		t.Fatalf("ExploreDirection failed: %v", err)
+	}
+	if second.ID != first.ID || second.Annotations["duplicate_of"] != first.ID {
+		t.Fatalf("expected existing thought %s flagged as duplicate, got %s %+v", first.ID, second.ID, second.Annotations)
+	}
+
+	stored, err := manager.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if len(stored.RootThought.Children) != 1 {
+		t.Fatalf("expected duplicate to be skipped, got %d children", len(stored.RootThought.Children))
+	}
+	if _, ok := stored.RootThought.Children[0].Annotations["duplicate_of"]; ok {
+		t.Fatalf("expected stored thought to stay unflagged, got %+v", stored.RootThought.Children[0].Annotations)
+	}
+}
+
+func TestExploreDirectionDedupUsesEmbeddings(t *testing.T) {
+	expander, manager, session := newRepeatingExpander(t, ThoughtDedupSkip)
+	manager.SetEmbedder(&keywordEmbedder{})
+	direction := models.Direction{Type: models.Deep, Title: "Rooftop"}
+
+	first, err := expander.ExploreDirection(direction, session.ID, "")
+	if err != nil {
+		t.Fatalf("ExploreDirection failed: %v", err)
+	}
+	second, err := expander.ExploreDirection(direction, session.ID, "")
+	if err != nil {
+		t.Fatalf("ExploreDirection failed: %v", err)
+	}
+	if first.ID == session.RootThought.ID || second.ID != first.ID {
+		t.Fatalf("expected embedding match to return the explored thought, got %+v", second)
+	}
+}
+
+func TestThoughtDedupDisabled(t *testing.T) {
+	expander, manager, session := newRepeatingExpander(t, ThoughtDedupSkip)
+	expander.SetThoughtDedup(0, ThoughtDedupSkip)
+	direction := models.Direction{Type: models.Deep, Title: "Rooftop"}
+
+	for i := 0; i < 2; i++ {
+		if _, err := expander.ExploreDirection(direction, session.ID, ""); err != nil {
+			t.Fatalf("ExploreDirection failed: %v", err)
+		}
+	}
+	stored, err := manager.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if len(stored.RootThought.Children) != 2 {
+		t.Fatalf("expected dedup to be disabled, got %d children", len(stored.RootThought.Children))
+	}
+	if _, err := ParseThoughtDedupPolicy("merge"); err == nil {
+		t.Fatal("expected unknown policy to be rejected")
+	}
+}
+
+func TestClosestByEmbeddingSharesCacheAndRequiresAComparison(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	embedder := &keywordEmbedder{}
+	manager.SetEmbedder(embedder)
+	expander := NewThoughtExpander(NewScriptedLLM(), manager)
+
+	broken := []*models.Thought{models.NewThought("broken solar panels", "", models.Direction{})}
+	if _, _, ok := expander.closestByEmbedding(broken, "Solar panels"); ok {
+		t.Fatal("expected ok=false when no candidate embedding succeeds")
+	}
+
+	solar := models.NewThought("Solar farms", "", models.Direction{})
+	wind := models.NewThought("Wind farms", "", models.Direction{})
+	if _, ok, err := manager.nearestThought(embedder, []*models.Thought{solar, wind}, "Solar roofs"); !ok || err != nil {
+		t.Fatalf("expected the similarity pass to compare candidates, got ok=%v (%v)", ok, err)
+	}
+	calls := embedder.calls
+	closest, similarity, ok := expander.closestByEmbedding([]*models.Thought{solar, wind}, "Solar roofs")
+	if !ok || closest != solar || similarity != 1 {
+		t.Fatalf("expected the solar thought, got %+v %.2f (%v)", closest, similarity, ok)
+	}
+	if embedder.calls != calls {
+		t.Fatalf("expected dedup to reuse cached embeddings, got %d new calls", embedder.calls-calls)
+	}
+}
@@ -0,0 +1,127 @@
+//Thought Embedding Cache(思维向量缓存)
+
+package services
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+
+	"WideMindsMCP/internal/models"
+)
+
+// 常量
+// thoughtEmbeddingCacheSize 是缓存的思维向量条数上限
+const thoughtEmbeddingCacheSize = 1024
+
+// 结构体
+// thoughtEmbeddingCache 按向量模型与文本缓存向量，按最近使用淘汰；内容不变的节点只请求一次向量服务。
+type thoughtEmbeddingCache struct {
+	mutex    sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type thoughtEmbeddingEntry struct {
+	key       string
+	embedding []float64
+}
+
+// thoughtMatch 是一次向量比较中与目标文本最相近的节点及其余弦相似度。
+type thoughtMatch struct {
+	thought    *models.Thought
+	similarity float64
+}
+
+// 函数
+func newThoughtEmbeddingCache(capacity int) *thoughtEmbeddingCache {
+	return &thoughtEmbeddingCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+func thoughtEmbeddingKey(model, text string) string {
+	hash := sha256.Sum256([]byte(model + "\x00" + strings.TrimSpace(text)))
+	return hex.EncodeToString(hash[:])
+}
+
+// recentThoughts 返回 candidates 中最新创建的至多 maxSimilarityComparisons 个节点，不修改原切片。
+func recentThoughts(candidates []*models.Thought) []*models.Thought {
+	recent := append([]*models.Thought(nil), candidates...)
+	sort.Slice(recent, func(i, j int) bool {
+		return recent[i].CreatedAt.After(recent[j].CreatedAt)
+	})
+	if len(recent) > maxSimilarityComparisons {
+		recent = recent[:maxSimilarityComparisons]
+	}
+	return recent
+}
+
+// 方法
+func (c *thoughtEmbeddingCache) get(key string) ([]float64, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*thoughtEmbeddingEntry).embedding, true
+}
+
+func (c *thoughtEmbeddingCache) put(key string, embedding []float64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*thoughtEmbeddingEntry).embedding = embedding
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&thoughtEmbeddingEntry{key: key, embedding: embedding})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*thoughtEmbeddingEntry).key)
+	}
+}
+
+// thoughtEmbedding 返回文本的向量，命中缓存时不请求向量服务；请求失败的结果不缓存。
+func (sm *SessionManager) thoughtEmbedding(embedder Embedder, text string) ([]float64, error) {
+	key := thoughtEmbeddingKey(embedder.EmbeddingModel(), text)
+	if embedding, ok := sm.thoughtEmbeddings.get(key); ok {
+		return embedding, nil
+	}
+	embedding, err := embedder.GetEmbedding(text)
+	if err != nil {
+		return nil, err
+	}
+	sm.thoughtEmbeddings.put(key, embedding)
+	return embedding, nil
+}
+
+// nearestThought 比较 text 与 candidates 中最新的 maxSimilarityComparisons 个节点的向量，返回余弦相似度最高的节点。
+// 目标文本的向量请求失败时返回错误；没有任何节点完成比较时 ok 为 false。
+func (sm *SessionManager) nearestThought(embedder Embedder, candidates []*models.Thought, text string) (thoughtMatch, bool, error) {
+	target, err := sm.thoughtEmbedding(embedder, text)
+	if err != nil {
+		return thoughtMatch{}, false, err
+	}
+
+	match := thoughtMatch{similarity: -1}
+	for _, candidate := range recentThoughts(candidates) {
+		embedding, err := sm.thoughtEmbedding(embedder, candidate.Content)
+		if err != nil || len(embedding) != len(target) {
+			continue
+		}
+		if score := cosineSimilarity(target, embedding); score > match.similarity {
+			match = thoughtMatch{thought: candidate, similarity: score}
+		}
+	}
+	return match, match.thought != nil, nil
+}
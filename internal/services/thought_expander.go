@@ -3,55 +3,251 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	appErrors "WideMindsMCP/internal/errors"
 	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
 )
 
 // 结构体
 type ThoughtExpander struct {
-	llmOrchestrator *LLMOrchestrator
-	sessionManager  *SessionManager
+	generator      DirectionGenerator
+	sessionManager *SessionManager
+	concurrency    int
+	thinkingStyle  models.ThinkingStyle
+	dedupThreshold float64
+
+	thoughtDedupThreshold float64
+	thoughtDedupPolicy    ThoughtDedupPolicy
+	// nodeBudget 限制单个会话的节点总数，0 表示不限
+	nodeBudget int
+
+	pathHintLimit    int
+	pathHintStrategy PathHintStrategy
 }
 
 type ExpansionRequest struct {
-	Concept       string               `json:"concept"`
-	Context       []string             `json:"context"`
-	ExpansionType models.DirectionType `json:"expansionType"`
-	MaxDirections int                  `json:"maxDirections"`
+	Concept       string                `json:"concept"`
+	Context       []models.ContextEntry `json:"context"`
+	ExpansionType models.DirectionType  `json:"expansionType"`
+	MaxDirections int                   `json:"maxDirections"`
+	UserID        string                `json:"userId,omitempty"`
+	// MaxTotalThoughts 限制本次扩展生成的预览思维数，<=0 时仅受部署的节点预算约束。
+	MaxTotalThoughts int `json:"maxTotalThoughts,omitempty"`
+	// ConcurrencyLimit 限制同时生成的预览数，<=0 时使用扩展器的默认并发数，最大 MaxExpansionConcurrency。
+	ConcurrencyLimit int `json:"concurrencyLimit,omitempty"`
+	// NoCache 跳过 LLM 响应缓存。
+	NoCache bool `json:"noCache,omitempty"`
+	// ThinkingStyle 调整生成温度；为空时依次使用 SessionID 对应会话画像中的风格与扩展器默认风格。
+	ThinkingStyle models.ThinkingStyle `json:"thinkingStyle,omitempty"`
+	SessionID     string               `json:"sessionId,omitempty"`
+	// MaxExamples 限制提示词中附带的示例数，nil 时使用部署默认值，0 表示不附带示例。
+	MaxExamples *int `json:"maxExamples,omitempty"`
+	// Language 是生成内容的 BCP-47 语言标签，为空时使用部署配置或自动检测。
+	Language string `json:"language,omitempty"`
+	// SystemPrompt 替换默认系统消息，部署配置的固定前缀始终保留。
+	SystemPrompt string `json:"systemPrompt,omitempty"`
+	// ExtraInstructions 作为附加约束追加到提示词中。
+	ExtraInstructions []string `json:"extraInstructions,omitempty"`
+	// Persist 为 true 时将预览思维挂到 SessionID 对应会话的 ParentThoughtID（为空时为根节点）下并保存。
+	Persist         bool   `json:"persist,omitempty"`
+	ParentThoughtID string `json:"parentThoughtId,omitempty"`
+	// ThoughtID 指定 SessionID 会话中要再次扩散的节点：以其内容为概念，结果挂到该节点下，忽略 Concept。
+	ThoughtID string `json:"thoughtId,omitempty"`
+	// MinRelevance 在排序去重后丢弃相关度低于该值（0-1）的方向。
+	MinRelevance float64 `json:"minRelevance,omitempty"`
+	// FallbackIfEmpty 为 true 时，ExpansionType、已探索方向或 MinRelevance 过滤掉全部方向后改用未经该过滤的方向；
+	// 默认返回空结果并在 EmptyReason 中说明原因。
+	FallbackIfEmpty bool `json:"fallbackIfEmpty,omitempty"`
+	// ForceIncludeExisting 为 true 时保留与目标节点子树中已有方向同名的方向；默认将其过滤。
+	ForceIncludeExisting bool `json:"forceIncludeExisting,omitempty"`
+	// IncludeSummary 为 true 时额外请求 LLM 生成 Summary；默认使用基于方向标题与类型的模板摘要。
+	IncludeSummary bool `json:"includeSummary,omitempty"`
+	// PathHints 覆盖扩散 ThoughtID 节点时上下文中历史路径的条数与选择策略。
+	PathHints PathHintOptions `json:"pathHints,omitempty"`
 }
 
 type ExpansionResult struct {
 	Directions []models.Direction `json:"directions"`
 	Thoughts   []*models.Thought  `json:"thoughts"`
+	// Truncated 表示结果因 MaxTotalThoughts 或节点预算被截断
+	Truncated bool `json:"truncated"`
+	// PersistedThoughtIDs 是 Persist 时写入会话的思维 ID，顺序与 Thoughts 相同
+	PersistedThoughtIDs []string `json:"persistedThoughtIds,omitempty"`
+	// EmptyReason 说明方向被过滤为空的原因
+	EmptyReason string `json:"emptyReason,omitempty"`
+	// Summary 是面向终端用户的一段话结果概述
+	Summary string `json:"summary"`
 }
 
+// ExpansionDelta 是流式扩展过程中的一段增量输出
+type ExpansionDelta struct {
+	Stage     string `json:"stage"`
+	Direction string `json:"direction,omitempty"`
+	Delta     string `json:"delta"`
+}
+
+// 常量
+const (
+	DefaultExpansionConcurrency = 3
+	MaxExpansionConcurrency     = 5
+
+	minRelevanceRangeMessage = "min_relevance must be between 0 and 1"
+)
+
 // 函数
-func NewThoughtExpander(llm *LLMOrchestrator, sm *SessionManager) *ThoughtExpander {
+func NewThoughtExpander(generator DirectionGenerator, sm *SessionManager) *ThoughtExpander {
 	return &ThoughtExpander{
-		llmOrchestrator: llm,
-		sessionManager:  sm,
+		generator:      generator,
+		sessionManager: sm,
+		dedupThreshold: DefaultDirectionDedupThreshold,
+
+		thoughtDedupThreshold: DefaultThoughtDedupThreshold,
+		thoughtDedupPolicy:    ThoughtDedupAnnotate,
+
+		pathHintLimit:    DefaultPathHintLimit,
+		pathHintStrategy: PathHintDeepest,
 	}
 }
 
 // 方法
-func (te *ThoughtExpander) Expand(req *ExpansionRequest) (*ExpansionResult, error) {
+// SetExpansionConcurrency 设置请求未指定 ConcurrencyLimit 时的预览并发数，非正值恢复默认值，超过上限时取上限。
+func (te *ThoughtExpander) SetExpansionConcurrency(limit int) {
 	if te == nil {
+		return
+	}
+	if limit > MaxExpansionConcurrency {
+		limit = MaxExpansionConcurrency
+	}
+	te.concurrency = limit
+}
+
+// Generator 返回扩展所使用的 LLM 实现。
+func (te *ThoughtExpander) Generator() DirectionGenerator {
+	if te == nil {
+		return nil
+	}
+	return te.generator
+}
+
+func (te *ThoughtExpander) Expand(req *ExpansionRequest) (*ExpansionResult, error) {
+	return te.ExpandContext(context.Background(), req)
+}
+
+// ExpandContext 与 Expand 相同，ctx 取消或超时时中止所有进行中的上游请求。
+func (te *ThoughtExpander) ExpandContext(ctx context.Context, req *ExpansionRequest) (*ExpansionResult, error) {
+	return te.expand(ctx, req, nil)
+}
+
+// ExpandStream 与 Expand 相同，但模型输出以增量形式回调 onDelta；ctx 取消时中止上游请求。
+// 预览并行生成时 onDelta 的调用会被串行化。
+func (te *ThoughtExpander) ExpandStream(ctx context.Context, req *ExpansionRequest, onDelta func(ExpansionDelta)) (*ExpansionResult, error) {
+	var deltaMutex sync.Mutex
+	result, err := te.expand(ctx, req, func(stageCtx context.Context, generator DirectionGenerator, stage, direction string) DirectionGenerator {
+		return generatorWithStream(generator, stageCtx, func(delta string) {
+			if onDelta != nil {
+				deltaMutex.Lock()
+				defer deltaMutex.Unlock()
+				onDelta(ExpansionDelta{Stage: stage, Direction: direction, Delta: delta})
+			}
+		})
+	})
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	return result, err
+}
+
+// expand 执行扩展；bind 非空时为每个阶段绑定流式输出。
+func (te *ThoughtExpander) expand(ctx context.Context, req *ExpansionRequest, bind func(ctx context.Context, generator DirectionGenerator, stage, direction string) DirectionGenerator) (*ExpansionResult, error) {
+	if te == nil || te.generator == nil {
 		return nil, errors.New("thought expander is not initialized")
 	}
 	if req == nil {
 		return nil, appErrors.ErrInvalidRequest
 	}
+	if req.ThoughtID != "" {
+		nodeReq, err := te.thoughtExpansionRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		req = nodeReq
+	}
 	if req.Concept == "" {
 		return nil, appErrors.ErrInvalidRequest
 	}
 
-	directions, err := te.GenerateDirections(req.Concept, req.Context)
+	if req.MinRelevance < 0 || req.MinRelevance > 1 {
+		return nil, utils.ValidationError(minRelevanceRangeMessage)
+	}
+	style, err := te.resolveThinkingStyle(req)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		parent   *models.Thought
+		explored []string
+	)
+	if req.Persist {
+		if _, parent, err = te.persistTarget(req); err != nil {
+			return nil, err
+		}
+		explored = exploredDirectionTitles(parent)
+	}
+	capacity, err := te.expansionCapacity(req)
+	if err != nil {
+		return nil, err
+	}
+	if capacity == 0 {
+		result := emptyExpansionResult(req.Concept, "")
+		result.Truncated = true
+		return result, nil
+	}
+
+	llm := generatorForUser(te.generator, req.UserID)
+	if req.NoCache {
+		llm = generatorWithoutCache(llm)
+	}
+	if temperature, ok := style.Temperature(); ok {
+		llm = generatorWithTemperature(llm, temperature)
+	}
+	if req.Language != "" {
+		language, ok := NormalizeLanguageTag(req.Language)
+		if !ok {
+			return nil, utils.ValidationError("language must be a BCP-47 tag such as en or zh-CN")
+		}
+		llm = generatorWithLanguage(llm, language)
+	}
+	if req.MaxExamples != nil {
+		if *req.MaxExamples < 0 || *req.MaxExamples > MaxPromptExampleLimit {
+			return nil, utils.ValidationError(fmt.Sprintf("max_examples must be between 0 and %d", MaxPromptExampleLimit))
+		}
+		llm = generatorWithExampleLimit(llm, *req.MaxExamples)
+	}
+	if req.SystemPrompt != "" || len(req.ExtraInstructions) > 0 {
+		if err := utils.ValidateSystemPrompt(req.SystemPrompt); err != nil {
+			return nil, err
+		}
+		instructions, err := utils.NormalizeInstructions(req.ExtraInstructions)
+		if err != nil {
+			return nil, err
+		}
+		llm = generatorWithPromptOverrides(llm, req.SystemPrompt, instructions)
+	}
+	stageLLM := func(ctx context.Context, stage, direction string) DirectionGenerator {
+		if bind == nil {
+			return generatorWithContext(llm, ctx)
+		}
+		return bind(ctx, llm, stage, direction)
+	}
+
+	directions, err := stageLLM(ctx, "directions", "").GenerateThoughtDirections(req.Concept, exploredHintContext(req.Context, explored))
 	if err != nil {
 		return nil, err
 	}
@@ -63,52 +259,272 @@ func (te *ThoughtExpander) Expand(req *ExpansionRequest) (*ExpansionResult, erro
 		}
 		filtered = append(filtered, dir)
 	}
-	if len(filtered) == 0 {
+	if len(filtered) == 0 && len(directions) > 0 {
+		if !req.FallbackIfEmpty {
+			return emptyExpansionResult(req.Concept, fmt.Sprintf("no %s directions were generated", req.ExpansionType)), nil
+		}
 		filtered = directions
 	}
+	if !req.ForceIncludeExisting {
+		if fresh := skipExploredDirections(filtered, explored); len(fresh) > 0 || len(filtered) == 0 {
+			filtered = fresh
+		} else if !req.FallbackIfEmpty {
+			return emptyExpansionResult(req.Concept, "all generated directions were already explored"), nil
+		}
+	}
+	if parent != nil {
+		filtered = downweightRepresentedTypes(filtered, parent.Children)
+	}
+	filtered = rankDirections(filtered, te.dedupThreshold)
+	if relevant := filterByRelevance(filtered, req.MinRelevance); len(relevant) > 0 || len(filtered) == 0 {
+		filtered = relevant
+	} else if !req.FallbackIfEmpty {
+		return emptyExpansionResult(req.Concept, fmt.Sprintf("no directions reached the minimum relevance %.2f", req.MinRelevance)), nil
+	}
 
-	if req.MaxDirections > 0 && len(filtered) > req.MaxDirections {
-		filtered = filtered[:req.MaxDirections]
+	maxDirections := req.MaxDirections
+	if maxDirections <= 0 {
+		maxDirections = DefaultMaxDirections
+	}
+	if len(filtered) > maxDirections {
+		filtered = filtered[:maxDirections]
+	}
+	truncated := false
+	if capacity > 0 && len(filtered) > capacity {
+		filtered = filtered[:capacity]
+		truncated = true
 	}
 
-	previewThoughts := make([]*models.Thought, 0, len(filtered))
-	for _, dir := range filtered {
-		previewCtx := buildExplorationInput(req.Context, dir)
-		thoughts, err := te.llmOrchestrator.ExploreDirection(dir, 1, previewCtx)
+	limit := req.ConcurrencyLimit
+	if limit <= 0 {
+		limit = te.concurrency
+	}
+	previewThoughts, err := generatePreviews(ctx, filtered, limit, func(ctx context.Context, dir models.Direction) ([]*models.Thought, error) {
+		return stageLLM(ctx, "preview", dir.Title).ExploreDirection(dir, 1, buildExplorationInput(req.Context, dir))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ExpansionResult{
+		Directions: filtered,
+		Thoughts:   previewThoughts,
+		Truncated:  truncated,
+		Summary:    templateExpansionSummary(req.Concept, filtered),
+	}
+	if req.IncludeSummary {
+		summary, err := summarizeExpansion(stageLLM(ctx, "summary", ""), req.Concept, filtered)
 		if err != nil {
 			return nil, err
 		}
-		if len(thoughts) > 0 {
-			previewThoughts = append(previewThoughts, thoughts[0])
+		result.Summary = summary
+	}
+	if req.Persist {
+		if err := te.persistExpansion(req, result); err != nil {
+			return nil, err
 		}
 	}
+	return result, nil
+}
 
-	return &ExpansionResult{
-		Directions: filtered,
-		Thoughts:   previewThoughts,
-	}, nil
+func emptyExpansionResult(concept, reason string) *ExpansionResult {
+	summary := templateExpansionSummary(concept, nil)
+	if reason != "" {
+		summary = fmt.Sprintf("%s Reason: %s.", summary, reason)
+	}
+	return &ExpansionResult{Directions: []models.Direction{}, Thoughts: []*models.Thought{}, EmptyReason: reason, Summary: summary}
 }
 
-func (te *ThoughtExpander) DeepDive(direction models.Direction, depth int) ([]*models.Thought, error) {
-	if te == nil {
+// filterByRelevance 保留相关度不低于 minRelevance 的方向，minRelevance 为 0 时原样返回。
+func filterByRelevance(directions []models.Direction, minRelevance float64) []models.Direction {
+	if minRelevance <= 0 {
+		return directions
+	}
+	kept := make([]models.Direction, 0, len(directions))
+	for _, dir := range directions {
+		if dir.Relevance >= minRelevance {
+			kept = append(kept, dir)
+		}
+	}
+	return kept
+}
+
+// generatePreviews 以最多 limit 个并发为每个方向生成预览思维，结果保持方向顺序。
+// 单个方向生成失败时记录警告并跳过其预览；ctx 取消或超出令牌预算时取消其余生成并返回该错误。
+func generatePreviews(ctx context.Context, directions []models.Direction, limit int, explore func(ctx context.Context, dir models.Direction) ([]*models.Thought, error)) ([]*models.Thought, error) {
+	if limit <= 0 {
+		limit = DefaultExpansionConcurrency
+	}
+	if limit > MaxExpansionConcurrency {
+		limit = MaxExpansionConcurrency
+	}
+	if len(directions) > limit {
+		utils.Warn("preview generation limited by concurrency limit",
+			utils.KV("directions", len(directions)),
+			utils.KV("concurrency_limit", limit),
+		)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		failOnce sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		failOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	results := make([][]*models.Thought, len(directions))
+	semaphore := make(chan struct{}, limit)
+	for i, dir := range directions {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if err := ctx.Err(); err != nil {
+			fail(err)
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, dir models.Direction) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			thoughts, err := explore(ctx, dir)
+			if err != nil {
+				if ctx.Err() != nil || errors.Is(err, appErrors.ErrBudgetExceeded) {
+					fail(err)
+					return
+				}
+				utils.Warn("skipping direction preview after generation failed",
+					utils.KV("direction", dir.Title),
+					utils.KV("error", err),
+				)
+				return
+			}
+			results[i] = thoughts
+		}(i, dir)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	previews := make([]*models.Thought, 0, len(directions))
+	for _, thoughts := range results {
+		if len(thoughts) > 0 {
+			previews = append(previews, thoughts[0])
+		}
+	}
+	return previews, nil
+}
+
+// DeepDive 沿方向逐层深入 depth 层，每层以上一层内容为历史，结果链挂到 parentThoughtID
+// （为空时为根节点）下并保存，返回链的第一层。
+func (te *ThoughtExpander) DeepDive(sessionID, parentThoughtID string, direction models.Direction, depth int) (*models.Thought, error) {
+	if te == nil || te.generator == nil {
 		return nil, errors.New("thought expander is not initialized")
 	}
+	if sessionID == "" {
+		return nil, appErrors.ErrInvalidRequest
+	}
 	if depth <= 0 {
 		depth = 1
 	}
 
-	return te.llmOrchestrator.ExploreDirection(direction, depth, nil)
+	session, err := te.sessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	parent := session.RootThought
+	if parentThoughtID != "" {
+		parent = session.GetThoughtTree()[parentThoughtID]
+		if parent == nil {
+			return nil, fmt.Errorf("%w: %s", appErrors.ErrThoughtNotFound, parentThoughtID)
+		}
+	}
+	deepest := depth
+	if parent != nil {
+		deepest += parent.Depth
+	}
+	if deepest > te.sessionManager.MaxThoughtDepth() {
+		return nil, utils.ValidationError(maxDepthReachedMessage)
+	}
+	if remaining := te.remainingNodes(session); remaining == 0 {
+		return nil, utils.ValidationError(nodeBudgetExhaustedMessage)
+	} else if remaining > 0 && depth > remaining {
+		depth = remaining
+	}
+
+	hints, err := te.pathHints(PathHintOptions{}, parent)
+	if err != nil {
+		return nil, err
+	}
+	explorationCtx := buildSessionExplorationContext(session, direction, hints)
+	generator, flushUsage := te.trackUsage(generatorForUser(te.generator, session.UserID), session.ID)
+	defer flushUsage()
+	chain, err := generator.ExploreDirection(direction, depth, explorationCtx)
+	if err != nil {
+		return nil, err
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("no thoughts generated for direction")
+	}
+
+	snapshot := newSessionSnapshot(session, "deep_dive")
+	anchor := parent
+	for _, thought := range chain {
+		thought.SessionID = session.ID
+		thought.Children = nil
+		if anchor == nil {
+			session.RootThought = thought
+		} else {
+			anchor.AddChild(thought)
+		}
+		anchor = thought
+	}
+	session.RecordActivity(models.ActivityDirectionExplored, direction.Title)
+
+	session.UpdatedAt = time.Now().UTC()
+	if err := te.sessionManager.UpdateSession(session); err != nil {
+		return nil, err
+	}
+	te.sessionManager.recordSnapshot(snapshot)
+
+	return chain[0], nil
 }
 
-func (te *ThoughtExpander) GenerateDirections(concept string, context []string) ([]models.Direction, error) {
-	if te == nil {
+// AutoStructure 让 LLM 将会话中的思维重新分组为层级结构并保存。
+func (te *ThoughtExpander) AutoStructure(sessionID string) (*models.Session, error) {
+	if te == nil || te.generator == nil {
 		return nil, errors.New("thought expander is not initialized")
 	}
-	return te.llmOrchestrator.GenerateThoughtDirections(concept, context)
+	if sessionID == "" {
+		return nil, appErrors.ErrInvalidRequest
+	}
+
+	session, err := te.sessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	generator, flushUsage := te.trackUsage(generatorForUser(te.generator, session.UserID), session.ID)
+	defer flushUsage()
+	spec, err := proposeStructure(generator, session)
+	if err != nil {
+		return nil, err
+	}
+	return te.sessionManager.RestructureSession(sessionID, spec)
 }
 
-func (te *ThoughtExpander) ExploreDirection(direction models.Direction, sessionID string) (*models.Thought, error) {
-	if te == nil {
+// SuggestNextActions 为会话给出下一步可执行的探索建议。
+func (te *ThoughtExpander) SuggestNextActions(sessionID string, maxSuggestions int) ([]NextAction, error) {
+	if te == nil || te.generator == nil {
 		return nil, errors.New("thought expander is not initialized")
 	}
 	if sessionID == "" {
@@ -119,9 +535,107 @@ func (te *ThoughtExpander) ExploreDirection(direction models.Direction, sessionI
 	if err != nil {
 		return nil, err
 	}
+	generator, flushUsage := te.trackUsage(generatorForUser(te.generator, session.UserID), session.ID)
+	defer flushUsage()
+	return suggestNextActions(generator, session, maxSuggestions)
+}
 
-	explorationCtx := buildSessionExplorationContext(session, direction)
-	thoughts, err := te.llmOrchestrator.ExploreDirection(direction, 1, explorationCtx)
+// ReflectOnSession 让 LLM 评估会话的优势与缺口，例如尚未探索的方向类型和未展开的叶子思维。
+func (te *ThoughtExpander) ReflectOnSession(sessionID string) (*ReflectionReport, error) {
+	if te == nil || te.generator == nil {
+		return nil, errors.New("thought expander is not initialized")
+	}
+	if sessionID == "" {
+		return nil, appErrors.ErrInvalidRequest
+	}
+
+	session, err := te.sessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	generator, flushUsage := te.trackUsage(generatorForUser(te.generator, session.UserID), session.ID)
+	defer flushUsage()
+	return reflectOnSession(generator, session)
+}
+
+// RecommendDirection 结合用户画像与会话历史从候选方向中推荐最先探索的一个，并给出理由。
+// profile 为 nil 时从会话上下文中提取。
+func (te *ThoughtExpander) RecommendDirection(directions []models.Direction, profile *models.UserProfile, session *models.Session) (*models.Direction, string, error) {
+	if te == nil || te.generator == nil {
+		return nil, "", errors.New("thought expander is not initialized")
+	}
+	if len(directions) == 0 {
+		return nil, "", utils.ValidationError("directions are required")
+	}
+	if len(directions) > MaxRecommendDirections {
+		return nil, "", utils.ValidationError(fmt.Sprintf("at most %d directions can be compared", MaxRecommendDirections))
+	}
+	for i := range directions {
+		if err := utils.ValidateDirection(&directions[i]); err != nil {
+			return nil, "", err
+		}
+	}
+	if profile == nil {
+		profile = models.NewUserProfile(session)
+	}
+
+	userID := ""
+	if profile != nil {
+		userID = profile.UserID
+	}
+	generator := generatorForUser(te.generator, userID)
+	if session != nil {
+		var flushUsage func()
+		generator, flushUsage = te.trackUsage(generator, session.ID)
+		defer flushUsage()
+	}
+	return recommendDirection(generator, directions, profile, session)
+}
+
+func (te *ThoughtExpander) GenerateDirections(concept string, context []models.ContextEntry) ([]models.Direction, error) {
+	if te == nil || te.generator == nil {
+		return nil, errors.New("thought expander is not initialized")
+	}
+	return te.generator.GenerateThoughtDirections(concept, context)
+}
+
+// ExploreDirection 沿方向生成一个思维并挂到 parentThoughtID 指定的节点下；为空时挂到根节点。
+func (te *ThoughtExpander) ExploreDirection(direction models.Direction, sessionID, parentThoughtID string) (*models.Thought, error) {
+	return te.ExploreDirectionWithHints(direction, sessionID, parentThoughtID, PathHintOptions{})
+}
+
+// ExploreDirectionWithHints 与 ExploreDirection 相同，hints 覆盖上下文中历史路径的条数与选择策略。
+func (te *ThoughtExpander) ExploreDirectionWithHints(direction models.Direction, sessionID, parentThoughtID string, hintOpts PathHintOptions) (*models.Thought, error) {
+	if te == nil || te.generator == nil {
+		return nil, errors.New("thought expander is not initialized")
+	}
+	if sessionID == "" {
+		return nil, appErrors.ErrInvalidRequest
+	}
+
+	session, err := te.sessionManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if te.remainingNodes(session) == 0 {
+		return nil, utils.ValidationError(nodeBudgetExhaustedMessage)
+	}
+	parent := session.RootThought
+	if parentThoughtID != "" {
+		parent, _ = session.FindThought(parentThoughtID)
+		if parent == nil {
+			return nil, fmt.Errorf("%w: %s", appErrors.ErrThoughtNotFound, parentThoughtID)
+		}
+	}
+
+	hints, err := te.pathHints(hintOpts, parent)
+	if err != nil {
+		return nil, err
+	}
+	explorationCtx := buildSessionExplorationContext(session, direction, hints)
+	generator, flushUsage := te.trackUsage(generatorForUser(te.generator, session.UserID), session.ID)
+	defer flushUsage()
+	thoughts, err := generator.ExploreDirection(direction, 1, explorationCtx)
 	if err != nil {
 		return nil, err
 	}
@@ -132,43 +646,77 @@ func (te *ThoughtExpander) ExploreDirection(direction models.Direction, sessionI
 	thought := thoughts[0]
 	thought.SessionID = session.ID
 
-	parent := session.RootThought
-	if thought.ParentID != nil {
-		tree := session.GetThoughtTree()
-		if existing, ok := tree[*thought.ParentID]; ok {
-			parent = existing
+	if duplicate, similarity := te.findDuplicateThought(parent, thought); duplicate != nil {
+		utils.Info("explored thought duplicates an existing thought",
+			utils.KV("session_id", session.ID),
+			utils.KV("duplicate_of", duplicate.ID),
+			utils.KV("similarity", similarity),
+			utils.KV("policy", te.thoughtDedupPolicy),
+		)
+		if te.thoughtDedupPolicy == ThoughtDedupSkip {
+			// 返回已有节点的副本，duplicate_of 只出现在响应中，不写回会话
+			existing := *duplicate
+			existing.Annotations = make(map[string]string, len(duplicate.Annotations)+2)
+			for key, value := range duplicate.Annotations {
+				existing.Annotations[key] = value
+			}
+			markDuplicate(&existing, duplicate, similarity)
+			return &existing, nil
 		}
+		markDuplicate(thought, duplicate, similarity)
 	}
 
+	te.sessionManager.annotateSimilarDirection(session, direction, thought)
+	snapshot := newSessionSnapshot(session, "explore_direction")
+
 	if parent == nil {
 		session.RootThought = thought
 	} else {
+		if err := te.sessionManager.checkAttachDepth(parent, thought); err != nil {
+			return nil, err
+		}
 		parent.AddChild(thought)
 	}
+	session.RecordActivity(models.ActivityDirectionExplored, direction.Title)
 
 	session.UpdatedAt = time.Now().UTC()
 	if err := te.sessionManager.UpdateSession(session); err != nil {
 		return nil, err
 	}
+	te.sessionManager.recordSnapshot(snapshot)
 
 	return thought, nil
 }
 
-func buildExplorationInput(base []string, direction models.Direction) []string {
-	entries := make([]string, 0, len(base)+4)
+// trackUsage 为会话相关的操作绑定用量统计；返回的 flush 将本次操作累计的用量与费用写入会话。
+func (te *ThoughtExpander) trackUsage(generator DirectionGenerator, sessionID string) (DirectionGenerator, func()) {
+	collector := &usageCollector{}
+	return generatorWithUsage(generator, collector.record), func() {
+		usage := collector.total()
+		if usage.Calls == 0 {
+			return
+		}
+		if err := te.sessionManager.AddSessionUsage(sessionID, usage); err != nil {
+			utils.Warn("failed to record session llm usage", utils.KV("session_id", sessionID), utils.KV("error", err))
+		}
+	}
+}
+
+func buildExplorationInput(base []models.ContextEntry, direction models.Direction) []models.ContextEntry {
+	entries := make([]models.ContextEntry, 0, len(base)+4)
 	for _, item := range base {
-		trimmed := strings.TrimSpace(item)
-		if trimmed != "" {
-			entries = append(entries, trimmed)
+		item.Value = strings.TrimSpace(item.Value)
+		if item.Value != "" {
+			entries = append(entries, item)
 		}
 	}
 
 	if title := strings.TrimSpace(direction.Title); title != "" {
-		entries = append(entries, fmt.Sprintf("goal: deepen %s", title))
+		entries = append(entries, models.NewContextEntry(models.ContextGoal, fmt.Sprintf("deepen %s", title)))
 	}
 
 	if desc := strings.TrimSpace(direction.Description); desc != "" {
-		entries = append(entries, fmt.Sprintf("background: %s", desc))
+		entries = append(entries, models.NewContextEntry(models.ContextBackground, desc))
 	}
 
 	if len(direction.Keywords) > 0 {
@@ -180,82 +728,35 @@ func buildExplorationInput(base []string, direction models.Direction) []string {
 			}
 		}
 		if len(keywords) > 0 {
-			entries = append(entries, fmt.Sprintf("keywords: %s", strings.Join(keywords, ", ")))
+			entries = append(entries, models.NewContextEntry(models.ContextNote, fmt.Sprintf("keywords: %s", strings.Join(keywords, ", "))))
 		}
 	}
 
 	return entries
 }
 
-func buildSessionExplorationContext(session *models.Session, direction models.Direction) []string {
+// buildSessionExplorationContext 以会话上下文、根节点与按 hints 选出的历史路径为背景构建探索输入。
+func buildSessionExplorationContext(session *models.Session, direction models.Direction, hints pathHintSelection) []models.ContextEntry {
 	if session == nil {
 		return buildExplorationInput(nil, direction)
 	}
 
-	base := make([]string, 0, len(session.Context)+6)
-	for _, entry := range session.Context {
-		trimmed := strings.TrimSpace(entry)
-		if trimmed != "" {
-			base = append(base, trimmed)
+	session.EnsureContextEntries()
+	base := make([]models.ContextEntry, 0, len(session.ContextEntries)+6)
+	for _, entry := range session.ContextEntries {
+		entry.Value = strings.TrimSpace(entry.Value)
+		if entry.Value != "" {
+			base = append(base, entry)
 		}
 	}
 
 	if session.RootThought != nil {
 		rootContent := strings.TrimSpace(session.RootThought.Content)
 		if rootContent != "" {
-			base = append(base, fmt.Sprintf("history: root -> %s", rootContent))
+			base = append(base, models.NewContextEntry(models.ContextHistory, fmt.Sprintf("root -> %s", rootContent)))
 		}
-		base = append(base, collectThoughtPathHints(session.RootThought, 4)...)
+		base = append(base, collectThoughtPathHints(session.RootThought, hints)...)
 	}
 
 	return buildExplorationInput(base, direction)
 }
-
-func collectThoughtPathHints(root *models.Thought, limit int) []string {
-	if root == nil || limit <= 0 {
-		return nil
-	}
-
-	queue := []*models.Thought{root}
-	nodes := make([]*models.Thought, 0, 16)
-	for len(queue) > 0 {
-		current := queue[0]
-		queue = queue[1:]
-		if current == nil {
-			continue
-		}
-		nodes = append(nodes, current)
-		for _, child := range current.Children {
-			if child != nil {
-				queue = append(queue, child)
-			}
-		}
-	}
-
-	sort.Slice(nodes, func(i, j int) bool {
-		if nodes[i].Depth == nodes[j].Depth {
-			return strings.Compare(nodes[i].Content, nodes[j].Content) < 0
-		}
-		return nodes[i].Depth > nodes[j].Depth
-	})
-
-	hints := make([]string, 0, limit)
-	seen := map[string]struct{}{}
-	for _, node := range nodes {
-		if len(hints) >= limit {
-			break
-		}
-		path := node.GetPath()
-		if len(path) == 0 {
-			continue
-		}
-		joined := strings.Join(path, " -> ")
-		if _, ok := seen[joined]; ok {
-			continue
-		}
-		seen[joined] = struct{}{}
-		hints = append(hints, fmt.Sprintf("history: %s", joined))
-	}
-
-	return hints
-}
@@ -1,10 +1,20 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	appErrors "WideMindsMCP/internal/errors"
 	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
 )
 
 func TestBuildSessionExplorationContext(t *testing.T) {
@@ -22,7 +32,7 @@ func TestBuildSessionExplorationContext(t *testing.T) {
 
 	targetDirection := models.Direction{Title: "Energy Storage", Description: "Focus on battery lifecycles", Keywords: []string{"batteries", "supply chain"}}
 
-	ctx := buildSessionExplorationContext(session, targetDirection)
+	ctx := models.ContextStrings(buildSessionExplorationContext(session, targetDirection, pathHintSelection{Limit: DefaultPathHintLimit, Strategy: PathHintDeepest}))
 
 	assertContains(t, ctx, "background: robotics")
 	assertContains(t, ctx, "history: root -> AI strategy")
@@ -39,7 +49,11 @@ func TestExploreDirectionIncludesContextSummary(t *testing.T) {
 		Description: "Explore interconnected impacts",
 	}
 
-	context := []string{"background: robotics", "history: AI strategy -> Ethical risks", "preference: concise"}
+	context := []models.ContextEntry{
+		models.NewContextEntry(models.ContextBackground, "robotics"),
+		models.NewContextEntry(models.ContextHistory, "AI strategy -> Ethical risks"),
+		models.NewContextEntry(models.ContextPreference, "concise"),
+	}
 
 	thoughts, err := orchestrator.ExploreDirection(direction, 2, context)
 	if err != nil {
@@ -76,3 +90,409 @@ func assertContains(t *testing.T, list []string, expected string) {
 func containsSubstring(haystack, needle string) bool {
 	return strings.Contains(haystack, needle)
 }
+
+func TestThoughtProvenanceByCreationPath(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	expander := NewThoughtExpander(NewLLMOrchestrator("", "", ""), manager)
+
+	session, err := manager.CreateSession("user", "Energy")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	manual := models.NewThought("Hand written note", session.ID, models.Direction{Type: models.Broad, Title: "Notes"})
+	if err := manager.AddThoughtToSession(session.ID, manual); err != nil {
+		t.Fatalf("AddThoughtToSession failed: %v", err)
+	}
+	if manual.Provenance == nil || manual.Provenance.Source != models.SourceUser {
+		t.Fatalf("expected manual thought to have user source, got %+v", manual.Provenance)
+	}
+
+	generated, err := expander.ExploreDirection(models.Direction{Type: models.Deep, Title: "Storage"}, session.ID, "")
+	if err != nil {
+		t.Fatalf("ExploreDirection failed: %v", err)
+	}
+	if !generated.IsGenerated() {
+		t.Fatalf("expected explored thought to have llm source, got %+v", generated.Provenance)
+	}
+
+	stored, err := manager.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	meta := stored.GetMetadata()
+	if meta.LLMThoughts != 1 || meta.UserThoughts != 2 {
+		t.Fatalf("expected 1 llm and 2 user thoughts, got %d/%d", meta.LLMThoughts, meta.UserThoughts)
+	}
+}
+
+func TestAutoStructureGroupsByDirectionWithoutBackend(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	expander := NewThoughtExpander(NewLLMOrchestrator("", "", ""), manager)
+
+	session, err := manager.CreateSession("user", "Energy")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	for _, direction := range []models.Direction{
+		{Type: models.Critical, Title: "Risks"},
+		{Type: models.Broad, Title: "Markets"},
+		{Type: models.Critical, Title: "Costs"},
+	} {
+		thought := models.NewThought(direction.Title, session.ID, direction)
+		if err := manager.AddThoughtToSession(session.ID, thought); err != nil {
+			t.Fatalf("AddThoughtToSession failed: %v", err)
+		}
+	}
+
+	structured, err := expander.AutoStructure(session.ID)
+	if err != nil {
+		t.Fatalf("AutoStructure failed: %v", err)
+	}
+	if structured.RootThought.ID != session.RootThought.ID {
+		t.Fatalf("expected fallback structure to keep the root")
+	}
+	groups := structured.RootThought.Children
+	if len(groups) != 2 || groups[0].Content != "Broad" || groups[1].Content != "Critical" || len(groups[1].Children) != 2 {
+		t.Fatalf("unexpected groups %+v", groups)
+	}
+
+	restored, err := manager.Undo(session.ID)
+	if err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if len(restored.RootThought.Children) != 3 {
+		t.Fatalf("expected undo to restore the flat tree, got %d children", len(restored.RootThought.Children))
+	}
+}
+
+func scriptedDirectionSet() []models.Direction {
+	return []models.Direction{
+		{Type: models.Broad, Title: "Markets"},
+		{Type: models.Deep, Title: "Chemistry"},
+		{Type: models.Deep, Title: "Manufacturing"},
+		{Type: models.Deep, Title: "Recycling"},
+	}
+}
+
+func TestExpandWithScriptedLLMFiltersAndTrims(t *testing.T) {
+	scripted := NewScriptedLLM().QueueDirections(scriptedDirectionSet(), nil)
+	for _, title := range []string{"Chemistry", "Manufacturing"} {
+		scripted.QueueThoughts([]*models.Thought{models.NewThought(title+" preview", "", models.Direction{Type: models.Deep, Title: title})}, nil)
+	}
+	expander := NewThoughtExpander(scripted, NewSessionManager(storage.NewInMemorySessionStore()))
+
+	result, err := expander.Expand(&ExpansionRequest{Concept: "Batteries", ExpansionType: models.Deep, MaxDirections: 2, ConcurrencyLimit: 1})
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if len(result.Directions) != 2 || result.Directions[0].Title != "Chemistry" || result.Directions[1].Title != "Manufacturing" {
+		t.Fatalf("expected two deep directions, got %+v", result.Directions)
+	}
+	if len(result.Thoughts) != 2 || result.Thoughts[1].Content != "Manufacturing preview" {
+		t.Fatalf("unexpected previews %+v", result.Thoughts)
+	}
+	if got := strings.Join(scripted.Calls(), ","); got != "GenerateThoughtDirections,ExploreDirection,ExploreDirection" {
+		t.Fatalf("unexpected call sequence %s", got)
+	}
+}
+
+func TestExpandWithScriptedLLMKeepsAllWhenFilterMatchesNothing(t *testing.T) {
+	scripted := NewScriptedLLM().QueueDirections(scriptedDirectionSet()[:2], nil)
+	scripted.QueueThoughts(nil, nil).QueueThoughts(nil, nil)
+	expander := NewThoughtExpander(scripted, NewSessionManager(storage.NewInMemorySessionStore()))
+
+	result, err := expander.Expand(&ExpansionRequest{Concept: "Batteries", ExpansionType: models.Critical, FallbackIfEmpty: true})
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if len(result.Directions) != 2 || len(result.Thoughts) != 0 {
+		t.Fatalf("expected unfiltered directions without previews, got %+v", result)
+	}
+}
+
+func TestExpandWithScriptedLLMPropagatesErrors(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	generateErr := errors.New("directions unavailable")
+	expander := NewThoughtExpander(NewScriptedLLM().QueueDirections(nil, generateErr), manager)
+	if _, err := expander.Expand(&ExpansionRequest{Concept: "Batteries"}); !errors.Is(err, generateErr) {
+		t.Fatalf("expected direction error, got %v", err)
+	}
+
+	budgetErr := fmt.Errorf("preview: %w", appErrors.ErrBudgetExceeded)
+	scripted := NewScriptedLLM().QueueDirections(scriptedDirectionSet(), nil).QueueThoughts(nil, budgetErr)
+	expander = NewThoughtExpander(scripted, manager)
+	if _, err := expander.Expand(&ExpansionRequest{Concept: "Batteries", ConcurrencyLimit: 1}); !errors.Is(err, appErrors.ErrBudgetExceeded) {
+		t.Fatalf("expected budget error, got %v", err)
+	}
+	if got := len(scripted.Calls()); got != 2 {
+		t.Fatalf("expected expansion to stop after exceeding the budget, got %d calls", got)
+	}
+}
+
+func TestExpandSkipsFailedPreviews(t *testing.T) {
+	scripted := NewScriptedLLM().QueueDirections(scriptedDirectionSet(), nil)
+	scripted.QueueThoughts([]*models.Thought{models.NewThought("Markets preview", "", models.Direction{Title: "Markets"})}, nil)
+	scripted.QueueThoughts(nil, errors.New("preview failed"))
+	scripted.QueueThoughts([]*models.Thought{models.NewThought("Manufacturing preview", "", models.Direction{Title: "Manufacturing"})}, nil)
+	scripted.QueueThoughts([]*models.Thought{models.NewThought("Recycling preview", "", models.Direction{Title: "Recycling"})}, nil)
+	expander := NewThoughtExpander(scripted, NewSessionManager(storage.NewInMemorySessionStore()))
+
+	result, err := expander.Expand(&ExpansionRequest{Concept: "Batteries", ConcurrencyLimit: 1})
+	if err != nil {
+		t.Fatalf("expected a failed preview to be skipped, got %v", err)
+	}
+	if len(result.Directions) != 4 || len(result.Thoughts) != 3 {
+		t.Fatalf("expected 4 directions and 3 previews, got %d and %d", len(result.Directions), len(result.Thoughts))
+	}
+	for i, title := range []string{"Markets", "Manufacturing", "Recycling"} {
+		if result.Thoughts[i].Content != title+" preview" {
+			t.Fatalf("preview %d: expected %q, got %q", i, title+" preview", result.Thoughts[i].Content)
+		}
+	}
+}
+
+// slowPreviewGenerator 为每个方向返回同名预览，并记录同时进行的预览数峰值。
+type slowPreviewGenerator struct {
+	delay  time.Duration
+	active int32
+	peak   int32
+}
+
+func (g *slowPreviewGenerator) GenerateThoughtDirections(concept string, context []models.ContextEntry) ([]models.Direction, error) {
+	return scriptedDirectionSet(), nil
+}
+
+func (g *slowPreviewGenerator) ExploreDirection(direction models.Direction, depth int, context []models.ContextEntry) ([]*models.Thought, error) {
+	active := atomic.AddInt32(&g.active, 1)
+	defer atomic.AddInt32(&g.active, -1)
+	for {
+		peak := atomic.LoadInt32(&g.peak)
+		if active <= peak || atomic.CompareAndSwapInt32(&g.peak, peak, active) {
+			break
+		}
+	}
+	time.Sleep(g.delay)
+	return []*models.Thought{models.NewThought(direction.Title+" preview", "", direction)}, nil
+}
+
+func (g *slowPreviewGenerator) CallLLM(req *LLMRequest) (*LLMResponse, error) {
+	return nil, errors.New("not scripted")
+}
+
+func (g *slowPreviewGenerator) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+func TestExpandGeneratesPreviewsInParallelInOrder(t *testing.T) {
+	generator := &slowPreviewGenerator{delay: 20 * time.Millisecond}
+	expander := NewThoughtExpander(generator, NewSessionManager(storage.NewInMemorySessionStore()))
+
+	result, err := expander.Expand(&ExpansionRequest{Concept: "Batteries", ConcurrencyLimit: 3})
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if len(result.Thoughts) != len(result.Directions) {
+		t.Fatalf("expected one preview per direction, got %d", len(result.Thoughts))
+	}
+	for i, thought := range result.Thoughts {
+		if thought.Content != result.Directions[i].Title+" preview" {
+			t.Fatalf("preview %d out of order: %q", i, thought.Content)
+		}
+	}
+	if peak := atomic.LoadInt32(&generator.peak); peak < 2 || peak > 3 {
+		t.Fatalf("expected 2-3 concurrent previews, got %d", peak)
+	}
+}
+
+func TestExpandContextCancelsPreviews(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) > 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{
+				"content": `[{"type":"deep","title":"Chemistry","summary":"Cells"},{"type":"broad","title":"Markets","summary":"Demand"}]`,
+			}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	llm := NewLLMOrchestrator("key", server.URL, "model")
+	llm.SetRetryPolicy(1, 0)
+	_ = llm.SetForceResponseLanguage("en")
+	expander := NewThoughtExpander(llm, NewSessionManager(storage.NewInMemorySessionStore()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	if _, err := expander.ExpandContext(ctx, &ExpansionRequest{Concept: "Batteries"}); err == nil {
+		t.Fatalf("expected expansion to fail once the context expires")
+	}
+	if elapsed := time.Since(started); elapsed > 2*time.Second {
+		t.Fatalf("expected previews to stop with the context, took %v", elapsed)
+	}
+}
+
+func benchmarkExpandPreviews(b *testing.B, limit int) {
+	generator := &slowPreviewGenerator{delay: 2 * time.Millisecond}
+	expander := NewThoughtExpander(generator, NewSessionManager(storage.NewInMemorySessionStore()))
+	req := &ExpansionRequest{Concept: "Batteries", MaxDirections: 4, ConcurrencyLimit: limit}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := expander.Expand(req); err != nil {
+			b.Fatalf("Expand failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkExpandPreviewsSequential(b *testing.B) {
+	benchmarkExpandPreviews(b, 1)
+}
+
+func BenchmarkExpandPreviewsParallel(b *testing.B) {
+	benchmarkExpandPreviews(b, 4)
+}
+
+func TestDeepDiveBuildsPersistedChain(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	expander := NewThoughtExpander(NewLLMOrchestrator("", "", ""), manager)
+	session, err := manager.CreateSession("user", "Energy")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	anchor := models.NewThought("Grid storage", session.ID, models.Direction{Type: models.Broad, Title: "Grid"})
+	if err := manager.AddThoughtToSession(session.ID, anchor); err != nil {
+		t.Fatalf("AddThoughtToSession failed: %v", err)
+	}
+
+	root, err := expander.DeepDive(session.ID, anchor.ID, models.Direction{Type: models.Deep, Title: "Flow batteries"}, 3)
+	if err != nil {
+		t.Fatalf("DeepDive failed: %v", err)
+	}
+
+	stored, err := manager.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	tree := stored.GetThoughtTree()
+	parent := tree[anchor.ID]
+	current := tree[root.ID]
+	for level := 0; level < 3; level++ {
+		if current == nil {
+			t.Fatalf("level %d missing from the stored session", level)
+		}
+		if current.ParentID == nil || *current.ParentID != parent.ID {
+			t.Fatalf("level %d: expected parent %s, got %v", level, parent.ID, current.ParentID)
+		}
+		if current.Depth != anchor.Depth+level+1 {
+			t.Fatalf("level %d: expected depth %d, got %d", level, anchor.Depth+level+1, current.Depth)
+		}
+		wantPath := append(append([]string{}, parent.Path...), current.Content)
+		if strings.Join(current.Path, "/") != strings.Join(wantPath, "/") {
+			t.Fatalf("level %d: expected path %v, got %v", level, wantPath, current.Path)
+		}
+		if level < 2 && len(current.Children) != 1 {
+			t.Fatalf("level %d: expected a single child, got %d", level, len(current.Children))
+		}
+		parent = current
+		if len(current.Children) > 0 {
+			current = current.Children[0]
+		} else {
+			current = nil
+		}
+	}
+	if len(parent.Children) != 0 {
+		t.Fatalf("expected the chain to end at depth 3, got %d more children", len(parent.Children))
+	}
+
+	if _, err := expander.DeepDive(session.ID, "missing", models.Direction{Type: models.Deep, Title: "dive"}, 1); !errors.Is(err, appErrors.ErrThoughtNotFound) {
+		t.Fatalf("expected ErrThoughtNotFound for an unknown parent, got %v", err)
+	}
+}
+
+func TestDeepDivePromptsIncludePreviousLevel(t *testing.T) {
+	var prompts []string
+	server, calls := newScriptedBackend(t, http.StatusOK, []string{
+		`{"hypothesis":"Vanadium electrolytes dominate cost."}`,
+		`{"hypothesis":"Electrolyte leasing shifts the cost to operators."}`,
+		`{"hypothesis":"Leasing needs standard recovery contracts."}`,
+	}, &prompts)
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	expander := NewThoughtExpander(newRepairTestLLM(t, server.URL), manager)
+	session, err := manager.CreateSession("user", "Energy")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	root, err := expander.DeepDive(session.ID, "", models.Direction{Type: models.Deep, Title: "Flow batteries"}, 3)
+	if err != nil {
+		t.Fatalf("DeepDive failed: %v", err)
+	}
+	if atomic.LoadInt32(calls) != 3 || len(prompts) != 3 {
+		t.Fatalf("expected one call per level, got %d", *calls)
+	}
+	if strings.Contains(prompts[0], "previous level:") ||
+		!strings.Contains(prompts[1], "previous level: Vanadium electrolytes dominate cost.") ||
+		!strings.Contains(prompts[2], "previous level: Electrolyte leasing shifts the cost to operators.") {
+		t.Fatalf("expected each prompt to carry the previous level, got %q", prompts)
+	}
+	if root.Content != "Vanadium electrolytes dominate cost." || *root.ParentID != session.RootThought.ID ||
+		root.Children[0].Children[0].Content != "Leasing needs standard recovery contracts." {
+		t.Fatalf("unexpected chain %+v", root)
+	}
+}
+
+func TestExploreDirectionAttachesUnderParent(t *testing.T) {
+	manager := NewSessionManager(storage.NewInMemorySessionStore())
+	expander := NewThoughtExpander(NewLLMOrchestrator("", "", ""), manager)
+	session, err := manager.CreateSession("user", "Energy")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	grid := models.NewThought("Grid storage", session.ID, models.Direction{Type: models.Broad, Title: "Grid"})
+	if err := manager.AddThoughtToSession(session.ID, grid); err != nil {
+		t.Fatalf("AddThoughtToSession failed: %v", err)
+	}
+	flow := models.NewThought("Flow batteries", session.ID, models.Direction{Type: models.Deep, Title: "Flow"})
+	flow.ParentID = &grid.ID
+	if err := manager.AddThoughtToSession(session.ID, flow); err != nil {
+		t.Fatalf("AddThoughtToSession failed: %v", err)
+	}
+	if flow.Depth != 2 {
+		t.Fatalf("expected the anchor at depth 2, got %d", flow.Depth)
+	}
+
+	thought, err := expander.ExploreDirection(models.Direction{Type: models.Deep, Title: "Vanadium"}, session.ID, flow.ID)
+	if err != nil {
+		t.Fatalf("ExploreDirection failed: %v", err)
+	}
+	if thought.Depth != 3 || thought.ParentID == nil || *thought.ParentID != flow.ID {
+		t.Fatalf("expected a depth-3 child of the anchor, got depth %d parent %v", thought.Depth, thought.ParentID)
+	}
+	path := thought.GetPath()
+	if len(path) != 4 || path[0] != "Energy" || path[1] != "Grid storage" || path[2] != "Flow batteries" || path[3] != thought.Content {
+		t.Fatalf("expected the path to include the ancestors, got %q", path)
+	}
+
+	stored, err := manager.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if _, parent := stored.FindThought(thought.ID); parent == nil || parent.ID != flow.ID {
+		t.Fatalf("expected the stored thought under the anchor, got parent %+v", parent)
+	}
+	if len(stored.RootThought.Children) != 1 {
+		t.Fatalf("expected nothing new under the root, got %d children", len(stored.RootThought.Children))
+	}
+
+	if _, err := expander.ExploreDirection(models.Direction{Type: models.Deep, Title: "Vanadium"}, session.ID, "missing"); !errors.Is(err, appErrors.ErrThoughtNotFound) {
+		t.Fatalf("expected ErrThoughtNotFound for an unknown parent, got %v", err)
+	}
+}
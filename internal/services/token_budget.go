@@ -0,0 +1,233 @@
+//Token Budget(令牌预算与上下文裁剪)
+
+package services
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const (
+	defaultCharsPerToken = 4
+	// minCompletionTokens 是裁剪 MaxTokens 后仍需保留的最少生成令牌数
+	minCompletionTokens = 16
+	// defaultCompletionTokens 是请求未指定 MaxTokens 时的生成令牌上限
+	defaultCompletionTokens = 2048
+
+	promptTruncationMarker = "\n\n[... prompt truncated to fit the token budget ...]\n\n"
+)
+
+// 接口
+// TokenEstimator 估算文本的令牌数；默认按字符数粗略估算，可替换为真实分词器。
+type TokenEstimator interface {
+	EstimateTokens(text string) int
+}
+
+// 结构体
+// HeuristicTokenEstimator 以 CharsPerToken 个字符约等于一个令牌估算。
+type HeuristicTokenEstimator struct {
+	CharsPerToken int
+}
+
+// 函数
+func NewHeuristicTokenEstimator() *HeuristicTokenEstimator {
+	return &HeuristicTokenEstimator{CharsPerToken: defaultCharsPerToken}
+}
+
+// 方法
+func (e *HeuristicTokenEstimator) EstimateTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	perToken := defaultCharsPerToken
+	if e != nil && e.CharsPerToken > 0 {
+		perToken = e.CharsPerToken
+	}
+	return utf8.RuneCountInString(text)/perToken + 1
+}
+
+// SetTokenEstimator 替换令牌估算器；nil 恢复默认启发式估算。
+func (llm *LLMOrchestrator) SetTokenEstimator(estimator TokenEstimator) {
+	if llm == nil {
+		return
+	}
+	if estimator == nil {
+		estimator = NewHeuristicTokenEstimator()
+	}
+	llm.estimator = estimator
+}
+
+// SetContextBudget 设置提示词（含上下文）的令牌上限，0 表示不限制。
+func (llm *LLMOrchestrator) SetContextBudget(tokens int) {
+	if llm == nil || tokens < 0 {
+		return
+	}
+	llm.contextBudget = tokens
+}
+
+// SetModelContextWindows 配置各模型的上下文窗口（提示词 + 生成），用于裁剪 MaxTokens。
+func (llm *LLMOrchestrator) SetModelContextWindows(windows map[string]int) {
+	if llm == nil {
+		return
+	}
+	cloned := make(map[string]int, len(windows))
+	for model, tokens := range windows {
+		if model = strings.TrimSpace(model); model != "" && tokens > 0 {
+			cloned[model] = tokens
+		}
+	}
+	llm.contextWindows = cloned
+}
+
+// EstimateTokens 用当前估算器估算提示词的令牌数。
+func (llm *LLMOrchestrator) EstimateTokens(prompt string) int {
+	return llm.estimateTokens(prompt)
+}
+
+// PromptTokenBudget 返回留给提示词的令牌数：max_tokens 扣除生成预留（completionTokens <= 0 时按默认预留），
+// 并受上下文预算限制；返回 0 表示不限制。
+func (llm *LLMOrchestrator) PromptTokenBudget(completionTokens int) int {
+	if llm == nil {
+		return 0
+	}
+	if completionTokens <= 0 {
+		completionTokens = llm.completionTokens(0)
+	}
+	budget := 0
+	if llm.maxTokens > completionTokens {
+		budget = llm.maxTokens - completionTokens
+	}
+	if llm.contextBudget > 0 && (budget == 0 || llm.contextBudget < budget) {
+		budget = llm.contextBudget
+	}
+	return budget
+}
+
+// completionTokens 将请求的生成令牌数限制在 max_tokens 内，未指定时取默认值。
+func (llm *LLMOrchestrator) completionTokens(requested int) int {
+	if requested <= 0 {
+		return min(llm.maxTokens, defaultCompletionTokens)
+	}
+	return min(requested, llm.maxTokens)
+}
+
+// truncatePrompt 从中间裁剪提示词直到不超过 budget：开头的角色与任务、结尾的输出格式要求得以保留。
+func (llm *LLMOrchestrator) truncatePrompt(prompt string, budget int) string {
+	runes := []rune(prompt)
+	keep := len(runes)
+	for keep > 0 {
+		keep = keep * 9 / 10
+		head := keep * 2 / 3
+		truncated := string(runes[:head]) + promptTruncationMarker + string(runes[len(runes)-(keep-head):])
+		if llm.estimateTokens(truncated) <= budget {
+			return truncated
+		}
+	}
+	return string(runes[:min(len(runes), budget)])
+}
+
+func (llm *LLMOrchestrator) estimateTokens(text string) int {
+	if llm == nil || llm.estimator == nil {
+		return NewHeuristicTokenEstimator().EstimateTokens(text)
+	}
+	return llm.estimator.EstimateTokens(text)
+}
+
+// contextWindow 返回当前模型的上下文窗口，未配置时为 0。
+func (llm *LLMOrchestrator) contextWindow() int {
+	if llm == nil || len(llm.contextWindows) == 0 {
+		return 0
+	}
+	if window, ok := llm.contextWindows[llm.model]; ok {
+		return window
+	}
+	if llm.azureDeployment != "" {
+		return llm.contextWindows[llm.azureDeployment]
+	}
+	return 0
+}
+
+// fitSegmentsToBudget 在提示词超出预算（见 PromptTokenBudget）时先丢弃最早的历史记录，再从末尾裁剪背景信息；
+// 任务与输出格式等模板部分始终保留。render 根据当前片段生成完整提示词。
+func (llm *LLMOrchestrator) fitSegmentsToBudget(segments promptContextSegments, render func(promptContextSegments) string) string {
+	prompt := render(segments)
+	budget := llm.PromptTokenBudget(0)
+	if budget <= 0 {
+		return prompt
+	}
+
+	droppedHistory, droppedBackground := 0, 0
+	for llm.estimateTokens(prompt) > budget {
+		switch {
+		case len(segments.history) > 0:
+			segments.history = segments.history[1:]
+			droppedHistory++
+		case len(segments.background) > 0:
+			segments.background = segments.background[:len(segments.background)-1]
+			droppedBackground++
+		default:
+			utils.Warn("prompt exceeds context budget after trimming",
+				utils.KV("budget_tokens", budget),
+				utils.KV("estimated_tokens", llm.estimateTokens(prompt)),
+			)
+			return prompt
+		}
+		prompt = render(segments)
+	}
+
+	if droppedHistory > 0 || droppedBackground > 0 {
+		utils.Info("trimmed prompt context to fit token budget",
+			utils.KV("budget_tokens", budget),
+			utils.KV("dropped_history", droppedHistory),
+			utils.KV("dropped_background", droppedBackground),
+		)
+	}
+	return prompt
+}
+
+// fitRequestContext 丢弃最早的请求上下文条目，直到提示词与上下文合计不超过预算。
+func (llm *LLMOrchestrator) fitRequestContext(prompt string, context []string) []string {
+	if llm == nil || llm.contextBudget <= 0 || len(context) == 0 {
+		return context
+	}
+
+	total := llm.estimateTokens(prompt)
+	for _, entry := range context {
+		total += llm.estimateTokens(entry)
+	}
+
+	dropped := 0
+	for total > llm.contextBudget && dropped < len(context) {
+		total -= llm.estimateTokens(context[dropped])
+		dropped++
+	}
+	if dropped == 0 {
+		return context
+	}
+
+	utils.Info("dropped request context entries to fit token budget",
+		utils.KV("budget_tokens", llm.contextBudget),
+		utils.KV("dropped_context", dropped),
+	)
+	return context[dropped:]
+}
+
+// clampCompletionTokens 使提示词与生成令牌之和不超过模型上下文窗口。
+func (llm *LLMOrchestrator) clampCompletionTokens(promptTokens, maxTokens int) (int, error) {
+	window := llm.contextWindow()
+	if window <= 0 {
+		return maxTokens, nil
+	}
+	available := window - promptTokens
+	if available < minCompletionTokens {
+		return 0, utils.ValidationError(fmt.Sprintf("prompt of ~%d tokens exceeds the %d-token context window of model %s", promptTokens, window, llm.model))
+	}
+	if maxTokens > available {
+		return available, nil
+	}
+	return maxTokens, nil
+}
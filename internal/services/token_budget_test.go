@@ -0,0 +1,134 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+)
+
+func TestBuildPromptFitsContextBudget(t *testing.T) {
+	llm := NewLLMOrchestrator("", "", "")
+	base := llm.estimateTokens(llm.BuildPrompt("Batteries", nil, "directions"))
+	budget := base + 300
+	llm.SetContextBudget(budget)
+
+	var context []models.ContextEntry
+	for i := 0; i < 100; i++ {
+		context = append(context, models.NewContextEntry(models.ContextHistory, fmt.Sprintf("history step %03d %s", i, strings.Repeat("h", 120))))
+	}
+	for i := 0; i < 4; i++ {
+		context = append(context, models.NewContextEntry(models.ContextBackground, fmt.Sprintf("background %02d %s", i, strings.Repeat("b", 120))))
+	}
+
+	prompt := llm.BuildPrompt("Batteries", context, "directions")
+	if tokens := llm.estimateTokens(prompt); tokens > budget {
+		t.Fatalf("expected prompt within %d tokens, got %d", budget, tokens)
+	}
+	for _, section := range []string{"## Mission", "## Output format"} {
+		if !strings.Contains(prompt, section) {
+			t.Fatalf("expected prompt to keep %q section", section)
+		}
+	}
+	if !strings.Contains(prompt, "history step 099") {
+		t.Fatalf("expected newest history entry to be kept:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "history step 000") {
+		t.Fatalf("expected oldest history entry to be dropped")
+	}
+	if !strings.Contains(prompt, "background 03") {
+		t.Fatalf("expected background to be kept while history can still be dropped")
+	}
+
+	llm.SetContextBudget(base + 60)
+	prompt = llm.BuildPrompt("Batteries", context, "directions")
+	if strings.Contains(prompt, "history step") || strings.Contains(prompt, "background 03") {
+		t.Fatalf("expected history and then trailing background to be dropped:\n%s", prompt)
+	}
+	if !strings.Contains(prompt, "## Mission") || !strings.Contains(prompt, "## Output format") {
+		t.Fatalf("expected mission and output format to survive trimming")
+	}
+}
+
+func TestBuildPromptWithoutBudgetKeepsContext(t *testing.T) {
+	llm := NewLLMOrchestrator("", "", "")
+	context := []models.ContextEntry{
+		models.NewContextEntry(models.ContextHistory, "first step"),
+		models.NewContextEntry(models.ContextHistory, "second step"),
+	}
+	prompt := llm.BuildPrompt("Batteries", context, "directions")
+	if !strings.Contains(prompt, "first step") || !strings.Contains(prompt, "second step") {
+		t.Fatalf("expected full history without a budget:\n%s", prompt)
+	}
+}
+
+func TestPrepareCallClampsMaxTokensToContextWindow(t *testing.T) {
+	llm := NewLLMOrchestrator("key", "https://example.com", "small-model")
+	llm.SetTokenEstimator(&HeuristicTokenEstimator{CharsPerToken: 1})
+	llm.SetModelContextWindows(map[string]int{"small-model": 600})
+
+	prompt := strings.Repeat("p", 399)
+	call, remote, err := llm.prepareCall(&LLMRequest{Prompt: prompt, MaxTokens: 1000})
+	if err != nil || !remote {
+		t.Fatalf("prepareCall failed: remote=%v err=%v", remote, err)
+	}
+	if call.estimatedTokens != 400 || call.maxTokens != 200 {
+		t.Fatalf("expected 400 prompt tokens and 200 completion tokens, got %d and %d", call.estimatedTokens, call.maxTokens)
+	}
+
+	_, _, err = llm.prepareCall(&LLMRequest{Prompt: strings.Repeat("p", 700)})
+	if !errors.Is(err, appErrors.ErrInvalidRequest) {
+		t.Fatalf("expected oversized prompt to be rejected, got %v", err)
+	}
+}
+
+func TestPrepareCallDropsOldestRequestContext(t *testing.T) {
+	llm := NewLLMOrchestrator("key", "https://example.com", "model")
+	llm.SetTokenEstimator(&HeuristicTokenEstimator{CharsPerToken: 1})
+	llm.SetContextBudget(40)
+
+	call, _, err := llm.prepareCall(&LLMRequest{
+		Prompt:  strings.Repeat("p", 9),
+		Context: []string{strings.Repeat("a", 19), strings.Repeat("b", 19)},
+	})
+	if err != nil {
+		t.Fatalf("prepareCall failed: %v", err)
+	}
+	if strings.Contains(call.userContent, "aaa") || !strings.Contains(call.userContent, "bbb") {
+		t.Fatalf("expected oldest context entry to be dropped, got %q", call.userContent)
+	}
+}
+
+func TestPrepareCallTruncatesPromptOverInputBudget(t *testing.T) {
+	llm := NewLLMOrchestratorWithOptions(LLMOptions{APIKey: "key", BaseURL: "https://example.com", Model: "model", MaxTokens: 300})
+	llm.SetTokenEstimator(&HeuristicTokenEstimator{CharsPerToken: 1})
+
+	if budget := llm.PromptTokenBudget(100); budget != 200 {
+		t.Fatalf("expected max_tokens minus completion tokens, got %d", budget)
+	}
+	llm.SetContextBudget(150)
+	if budget := llm.PromptTokenBudget(100); budget != 150 {
+		t.Fatalf("expected the smaller context budget to win, got %d", budget)
+	}
+	llm.SetContextBudget(0)
+
+	prompt := "## Mission\n" + strings.Repeat("m", 400) + "\n## Output format\nJSON only."
+	call, _, err := llm.prepareCall(&LLMRequest{Prompt: prompt, MaxTokens: 100})
+	if err != nil {
+		t.Fatalf("prepareCall failed: %v", err)
+	}
+	if tokens := llm.EstimateTokens(call.prompt); tokens > 200 {
+		t.Fatalf("expected prompt within 200 tokens, got %d", tokens)
+	}
+	if !strings.HasPrefix(call.prompt, "## Mission") || !strings.HasSuffix(call.prompt, "JSON only.") || !strings.Contains(call.prompt, "truncated") {
+		t.Fatalf("expected the middle of the prompt to be truncated, got %q", call.prompt)
+	}
+
+	short, _, err := llm.prepareCall(&LLMRequest{Prompt: "short prompt", MaxTokens: 100})
+	if err != nil || short.prompt != "short prompt" {
+		t.Fatalf("expected prompts within budget to be untouched, got %+v (%v)", short, err)
+	}
+}
@@ -0,0 +1,140 @@
+//Tool Calling(函数调用获取结构化输出)
+
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"WideMindsMCP/internal/models"
+)
+
+// 常量
+const proposeDirectionsTool = "propose_directions"
+
+// 结构体
+// ToolSpec 描述请求强制调用的函数；仅在开启函数调用时随 chat completions 请求发送。
+type ToolSpec struct {
+	Name        string
+	Description string
+	Parameters  map[string]any
+}
+
+// ToolCall 是模型返回的一次函数调用，Arguments 为 JSON 文本。
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string
+}
+
+// directionsTool 让模型以函数参数返回方向，字段与 models.Direction 对应。
+var directionsTool = &ToolSpec{
+	Name:        proposeDirectionsTool,
+	Description: "Propose thinking directions that extend the given concept.",
+	Parameters: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			directionsResponseKey: map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"type":        map[string]any{"type": "string", "enum": []string{"broad", "deep", "lateral", "critical"}},
+						"title":       map[string]any{"type": "string"},
+						"description": map[string]any{"type": "string"},
+						"keywords":    map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+						"relevance":   map[string]any{"type": "number", "minimum": 0, "maximum": 1},
+					},
+					"required": []string{"type", "title", "description"},
+				},
+			},
+		},
+		"required": []string{directionsResponseKey},
+	},
+}
+
+// 方法
+// SetToolCalling 设置方向生成是否通过强制函数调用（tools/tool_choice）获取结构化结果（默认关闭）。
+// 仅 chat completions 兼容接口生效；Ollama 与未返回函数调用的回复沿用提示词解析。
+func (llm *LLMOrchestrator) SetToolCalling(enabled bool) {
+	if llm == nil {
+		return
+	}
+	llm.toolCalling = enabled
+}
+
+func (llm *LLMOrchestrator) toolCallingEnabled() bool {
+	return llm != nil && llm.toolCalling && !llm.isOllama()
+}
+
+// toolCall 返回名为 name 的首个函数调用。
+func (r *LLMResponse) toolCall(name string) (ToolCall, bool) {
+	if r == nil {
+		return ToolCall{}, false
+	}
+	for _, call := range r.ToolCalls {
+		if call.Name == name {
+			return call, true
+		}
+	}
+	return ToolCall{}, false
+}
+
+// directionsPayload 返回承载方向的文本：优先使用 propose_directions 的参数，否则为回复正文。
+func (r *LLMResponse) directionsPayload() string {
+	if call, ok := r.toolCall(proposeDirectionsTool); ok {
+		return call.Arguments
+	}
+	return r.Content
+}
+
+// directionsFromResponse 直接解析 propose_directions 的参数；回复不含该函数调用时按正文解析。
+func (llm *LLMOrchestrator) directionsFromResponse(resp *LLMResponse) ([]models.Direction, error) {
+	call, ok := resp.toolCall(proposeDirectionsTool)
+	if !ok {
+		return llm.parseDirectionsFromContent(resp.Content)
+	}
+	return parseDirectionsToolArguments(call.Arguments)
+}
+
+// 函数
+func parseDirectionsToolArguments(arguments string) ([]models.Direction, error) {
+	var parsed struct {
+		Directions []rawDirection `json:"directions"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &parsed); err != nil {
+		return nil, fmt.Errorf("parse %s arguments: %w", proposeDirectionsTool, err)
+	}
+	if len(parsed.Directions) == 0 {
+		return nil, fmt.Errorf("parse %s arguments: %w", proposeDirectionsTool, errors.New("no directions"))
+	}
+	return convertRawDirections(parsed.Directions)
+}
+
+// toolsPayload 返回强制调用 tool 的 tools 与 tool_choice 字段。
+func toolsPayload(tool *ToolSpec) ([]map[string]any, map[string]any) {
+	tools := []map[string]any{{
+		"type": "function",
+		"function": map[string]any{
+			"name":        tool.Name,
+			"description": tool.Description,
+			"parameters":  tool.Parameters,
+		},
+	}}
+	choice := map[string]any{
+		"type":     "function",
+		"function": map[string]string{"name": tool.Name},
+	}
+	return tools, choice
+}
+
+// toolCallArguments 兼容以字符串或 JSON 对象返回的函数参数。
+func toolCallArguments(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return strings.TrimSpace(text)
+	}
+	return strings.TrimSpace(string(raw))
+}
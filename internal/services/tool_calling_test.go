@@ -0,0 +1,106 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"WideMindsMCP/internal/models"
+)
+
+// newRecordedBackend 返回固定的原始响应体，并记录每次请求的请求体。
+func newRecordedBackend(t *testing.T, body []byte, requests *[]map[string]any) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		var payload map[string]any
+		_ = json.Unmarshal(raw, &payload)
+		*requests = append(*requests, payload)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGenerateThoughtDirectionsParsesToolCall(t *testing.T) {
+	fixture, err := os.ReadFile(filepath.Join("testdata", "tool_call_directions.json"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	var requests []map[string]any
+	server := newRecordedBackend(t, fixture, &requests)
+	llm := newRepairTestLLM(t, server.URL)
+	if err := llm.SetJSONMode(JSONModeJSONObject); err != nil {
+		t.Fatalf("SetJSONMode failed: %v", err)
+	}
+	llm.SetToolCalling(true)
+
+	directions, err := llm.GenerateThoughtDirections("Batteries", nil)
+	if err != nil {
+		t.Fatalf("GenerateThoughtDirections failed: %v", err)
+	}
+	if len(directions) != 2 {
+		t.Fatalf("expected two directions from the tool call, got %+v", directions)
+	}
+	first := directions[0]
+	if first.Type != models.Deep || first.Title != "Electrolyte chemistry" || first.Description != "Compare solid and liquid electrolytes" || first.Relevance != 0.9 || len(first.Keywords) != 2 {
+		t.Fatalf("unexpected first direction %+v", first)
+	}
+	if directions[1].Type != models.Critical || first.Provenance == nil || first.Provenance.Model != "gpt-4o-mini" || first.Provenance.TokenUsage.TotalTokens != 168 {
+		t.Fatalf("unexpected directions %+v", directions)
+	}
+
+	if len(requests) != 1 {
+		t.Fatalf("expected a single request, got %d", len(requests))
+	}
+	request := requests[0]
+	if _, ok := request["response_format"]; ok {
+		t.Fatalf("expected tools to replace response_format, got %v", request["response_format"])
+	}
+	tools, _ := request["tools"].([]any)
+	choice, _ := request["tool_choice"].(map[string]any)
+	if len(tools) != 1 || choice == nil || choice["function"].(map[string]any)["name"] != proposeDirectionsTool {
+		t.Fatalf("expected a forced propose_directions call, got tools=%v tool_choice=%v", request["tools"], request["tool_choice"])
+	}
+}
+
+func TestGenerateThoughtDirectionsFallsBackWithoutToolCall(t *testing.T) {
+	content := `{"directions":[{"type":"lateral","title":"Grid storage","description":"Stationary batteries for the grid"}]}`
+	body, _ := json.Marshal(map[string]any{
+		"choices": []map[string]any{{"message": map[string]string{"role": "assistant", "content": content}}},
+	})
+	var requests []map[string]any
+	server := newRecordedBackend(t, body, &requests)
+	llm := newRepairTestLLM(t, server.URL)
+	llm.SetToolCalling(true)
+
+	directions, err := llm.GenerateThoughtDirections("Batteries", nil)
+	if err != nil {
+		t.Fatalf("GenerateThoughtDirections failed: %v", err)
+	}
+	if len(requests) != 1 || len(directions) != 1 || directions[0].Title != "Grid storage" || directions[0].Provenance.Model == localFallbackModel {
+		t.Fatalf("expected directions parsed from the message content, got %+v after %d requests", directions, len(requests))
+	}
+}
+
+func TestToolCallingDisabledByDefault(t *testing.T) {
+	content := `[{"type":"deep","title":"Chemistry","description":"Cell chemistry"}]`
+	body, _ := json.Marshal(map[string]any{
+		"choices": []map[string]any{{"message": map[string]string{"content": content}}},
+	})
+	var requests []map[string]any
+	server := newRecordedBackend(t, body, &requests)
+	llm := newRepairTestLLM(t, server.URL)
+
+	if _, err := llm.GenerateThoughtDirections("Batteries", nil); err != nil {
+		t.Fatalf("GenerateThoughtDirections failed: %v", err)
+	}
+	if _, ok := requests[0]["tools"]; ok {
+		t.Fatalf("expected no tools without SetToolCalling, got %v", requests[0]["tools"])
+	}
+}
@@ -0,0 +1,131 @@
+//LLM HTTP Transport(LLM 出站连接配置)
+
+package services
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const (
+	// DefaultLLMMaxIdleConnsPerHost 覆盖并行预览与批量请求的并发量，避免频繁重建到网关的连接
+	DefaultLLMMaxIdleConnsPerHost = 16
+	MaxLLMMaxIdleConnsPerHost     = 1024
+)
+
+// 结构体
+// LLMTransportOptions 描述访问 LLM 网关的代理与 TLS 设置；零值使用环境代理与系统根证书。
+type LLMTransportOptions struct {
+	// ProxyURL 为出站代理地址（http、https 或 socks5），为空时沿用 HTTP_PROXY 等环境变量。
+	ProxyURL string
+	// CACertFile 为额外信任的 PEM 格式 CA 证书，追加到系统根证书之后。
+	CACertFile string
+	// ClientCertFile 与 ClientKeyFile 用于 mTLS，必须同时设置。
+	ClientCertFile     string
+	ClientKeyFile      string
+	InsecureSkipVerify bool
+	// MaxIdleConnsPerHost 非正值时使用 DefaultLLMMaxIdleConnsPerHost。
+	MaxIdleConnsPerHost int
+}
+
+// 函数
+// NewLLMTransport 按选项构建 HTTP 传输层；证书文件无法读取或解析时返回错误。
+func NewLLMTransport(opts LLMTransportOptions) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	idle := opts.MaxIdleConnsPerHost
+	if idle <= 0 {
+		idle = DefaultLLMMaxIdleConnsPerHost
+	}
+	if idle > MaxLLMMaxIdleConnsPerHost {
+		return nil, fmt.Errorf("max idle connections per host must be at most %d", MaxLLMMaxIdleConnsPerHost)
+	}
+	transport.MaxIdleConnsPerHost = idle
+	if transport.MaxIdleConns < idle {
+		transport.MaxIdleConns = idle
+	}
+
+	if proxy := strings.TrimSpace(opts.ProxyURL); proxy != "" {
+		parsed, err := url.Parse(proxy)
+		if err != nil || parsed.Host == "" {
+			return nil, fmt.Errorf("proxy url %q is not a valid absolute URL", proxy)
+		}
+		switch parsed.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("proxy url %q must use http, https or socks5", proxy)
+		}
+		transport.Proxy = http.ProxyURL(parsed)
+	}
+
+	tlsConfig, err := buildLLMTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	return transport, nil
+}
+
+// buildLLMTLSConfig 在未设置任何 TLS 选项时返回 nil，沿用默认配置。
+func buildLLMTLSConfig(opts LLMTransportOptions) (*tls.Config, error) {
+	certFile, keyFile := strings.TrimSpace(opts.ClientCertFile), strings.TrimSpace(opts.ClientKeyFile)
+	caFile := strings.TrimSpace(opts.CACertFile)
+	if caFile == "" && certFile == "" && keyFile == "" && !opts.InsecureSkipVerify {
+		return nil, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA certificate file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA certificate file %s contains no PEM certificates", caFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("client certificate and key files must be set together")
+	}
+	if certFile != "" {
+		pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{pair}
+	}
+
+	config.InsecureSkipVerify = opts.InsecureSkipVerify
+	return config, nil
+}
+
+// newLLMHTTPClient 构建编排器使用的客户端；传输层配置无效时记录错误并退回默认连接池配置。
+func newLLMHTTPClient(opts LLMTransportOptions, timeout time.Duration) *http.Client {
+	transport, err := NewLLMTransport(opts)
+	if err != nil {
+		utils.Error("invalid LLM transport options; using defaults", utils.KV("error", err))
+		transport, _ = NewLLMTransport(LLMTransportOptions{})
+	}
+	if opts.InsecureSkipVerify {
+		utils.Warn("TLS certificate verification for the LLM backend is disabled")
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewLLMTransportUsesConfiguredProxy(t *testing.T) {
+	transport, err := NewLLMTransport(LLMTransportOptions{ProxyURL: "http://proxy.internal:3128"})
+	if err != nil {
+		t.Fatalf("NewLLMTransport failed: %v", err)
+	}
+	if transport.MaxIdleConnsPerHost != DefaultLLMMaxIdleConnsPerHost {
+		t.Fatalf("expected default idle pool size, got %d", transport.MaxIdleConnsPerHost)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "https://llm.example.com/v1/chat/completions", nil)
+	proxy, err := transport.Proxy(req)
+	if err != nil || proxy == nil || proxy.String() != "http://proxy.internal:3128" {
+		t.Fatalf("expected configured proxy, got %v (%v)", proxy, err)
+	}
+
+	for _, bad := range []string{"proxy.internal:3128", "ftp://proxy.internal"} {
+		if _, err := NewLLMTransport(LLMTransportOptions{ProxyURL: bad}); err == nil {
+			t.Fatalf("expected proxy url %q to be rejected", bad)
+		}
+	}
+}
+
+func TestNewLLMTransportTrustsCustomCA(t *testing.T) {
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer backend.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	block := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: backend.Certificate().Raw})
+	if err := os.WriteFile(caFile, block, 0o600); err != nil {
+		t.Fatalf("write CA file: %v", err)
+	}
+
+	transport, err := NewLLMTransport(LLMTransportOptions{CACertFile: caFile})
+	if err != nil {
+		t.Fatalf("NewLLMTransport failed: %v", err)
+	}
+	if transport.TLSClientConfig == nil || transport.TLSClientConfig.MinVersion != tls.VersionTLS12 {
+		t.Fatalf("expected a TLS config with the custom CA, got %+v", transport.TLSClientConfig)
+	}
+	resp, err := (&http.Client{Transport: transport}).Get(backend.URL)
+	if err != nil {
+		t.Fatalf("expected request to a server signed by the custom CA to succeed: %v", err)
+	}
+	resp.Body.Close()
+
+	garbage := filepath.Join(dir, "garbage.pem")
+	_ = os.WriteFile(garbage, []byte("not a certificate"), 0o600)
+	cases := map[string]LLMTransportOptions{
+		"no certificates":  {CACertFile: garbage},
+		"missing file":     {CACertFile: filepath.Join(dir, "missing.pem")},
+		"cert without key": {ClientCertFile: caFile},
+		"unreadable pair":  {ClientCertFile: caFile, ClientKeyFile: garbage},
+	}
+	for name, opts := range cases {
+		if _, err := NewLLMTransport(opts); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		} else if name == "no certificates" && !strings.Contains(err.Error(), "no PEM certificates") {
+			t.Fatalf("expected a clear message, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+//Session Word Cloud(会话词云)
+
+package services
+
+import (
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+// 常量
+const (
+	DefaultWordCloudSize = 30
+	MaxWordCloudSize     = 200
+)
+
+// 方法
+// WordCloud 返回会话中出现次数最多的 top 个词（使用默认英文停用词）；top <= 0 时取 DefaultWordCloudSize。
+func (sm *SessionManager) WordCloud(sessionID string, top int) ([]models.WordCount, error) {
+	if top <= 0 {
+		top = DefaultWordCloudSize
+	}
+	if top > MaxWordCloudSize {
+		return nil, utils.ValidationError("top is too large")
+	}
+
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return models.TopWords(session.WordCloud(utils.DefaultStopWords()), top), nil
+}
@@ -0,0 +1,68 @@
+//Store LLM Token Budgets(存储令牌预算)
+
+package storage
+
+import (
+	"errors"
+	"sync"
+
+	"WideMindsMCP/internal/models"
+)
+
+// 接口
+type BudgetStore interface {
+	// Get 返回用户预算；不存在时返回 nil, nil。
+	Get(userID string) (*models.LLMBudget, error)
+	Save(budget *models.LLMBudget) error
+	// AddUsage 原子地累加用量，并返回更新后的预算。
+	AddUsage(userID string, tokens int) (*models.LLMBudget, error)
+}
+
+// 结构体
+type InMemoryBudgetStore struct {
+	budgets map[string]*models.LLMBudget
+	mutex   sync.RWMutex
+}
+
+// 函数
+func NewInMemoryBudgetStore() BudgetStore {
+	return &InMemoryBudgetStore{budgets: make(map[string]*models.LLMBudget)}
+}
+
+// InMemoryBudgetStore方法
+func (store *InMemoryBudgetStore) Get(userID string) (*models.LLMBudget, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	budget, ok := store.budgets[userID]
+	if !ok {
+		return nil, nil
+	}
+	clone := *budget
+	return &clone, nil
+}
+
+func (store *InMemoryBudgetStore) Save(budget *models.LLMBudget) error {
+	if budget == nil || budget.UserID == "" {
+		return errors.New("budget must have a user id")
+	}
+
+	clone := *budget
+	store.mutex.Lock()
+	store.budgets[budget.UserID] = &clone
+	store.mutex.Unlock()
+	return nil
+}
+
+func (store *InMemoryBudgetStore) AddUsage(userID string, tokens int) (*models.LLMBudget, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	budget, ok := store.budgets[userID]
+	if !ok {
+		return nil, errors.New("budget not found")
+	}
+	budget.UsedTokens += tokens
+	clone := *budget
+	return &clone, nil
+}
@@ -0,0 +1,117 @@
+//In-Memory Store State Snapshots(内存存储整体快照)
+
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"WideMindsMCP/internal/models"
+)
+
+// 接口
+// SnapshottableStore 是可整体导出并恢复全部状态的会话存储，主要供测试在用例之间重置数据。
+type SnapshottableStore interface {
+	SessionStore
+	// Snapshot 将全部会话及其命名快照序列化为 JSON；序列化失败时返回 nil。
+	Snapshot() []byte
+	// Restore 用 Snapshot 的结果替换存储中的全部内容，数据无效时保持原状态不变。
+	Restore(data []byte) error
+}
+
+var _ SnapshottableStore = (*InMemorySessionStore)(nil)
+
+// 结构体
+// memoryStoreState 是 InMemorySessionStore 快照的 JSON 格式，会话按 ID 排序
+type memoryStoreState struct {
+	Sessions  []json.RawMessage            `json:"sessions"`
+	Snapshots map[string][]json.RawMessage `json:"snapshots,omitempty"`
+}
+
+// InMemorySessionStore方法
+func (store *InMemorySessionStore) Snapshot() []byte {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	ids := make([]string, 0, len(store.sessions))
+	for id := range store.sessions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	state := memoryStoreState{Sessions: make([]json.RawMessage, 0, len(ids))}
+	for _, id := range ids {
+		data, err := encodeSession(store.sessions[id])
+		if err != nil {
+			return nil
+		}
+		state.Sessions = append(state.Sessions, data)
+	}
+	for sessionID, named := range store.snapshots {
+		snapshots := make([]*models.SessionSnapshot, 0, len(named))
+		for _, snapshot := range named {
+			snapshots = append(snapshots, snapshot)
+		}
+		sortSnapshots(snapshots)
+		for _, snapshot := range snapshots {
+			data, err := json.Marshal(snapshot)
+			if err != nil {
+				return nil
+			}
+			if state.Snapshots == nil {
+				state.Snapshots = make(map[string][]json.RawMessage)
+			}
+			state.Snapshots[sessionID] = append(state.Snapshots[sessionID], data)
+		}
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func (store *InMemorySessionStore) Restore(data []byte) error {
+	if len(data) == 0 {
+		return errors.New("store snapshot is empty")
+	}
+	var state memoryStoreState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("decode store snapshot: %w", err)
+	}
+
+	sessions := make(map[string]*models.Session, len(state.Sessions))
+	for _, raw := range state.Sessions {
+		session, err := decodeSession(raw)
+		if err != nil {
+			return fmt.Errorf("decode store snapshot session: %w", err)
+		}
+		if session.ID == "" {
+			return errors.New("store snapshot contains a session without id")
+		}
+		if _, exists := sessions[session.ID]; exists {
+			return fmt.Errorf("store snapshot contains session %s twice", session.ID)
+		}
+		sessions[session.ID] = session
+	}
+	snapshots := make(map[string]map[string]*models.SessionSnapshot, len(state.Snapshots))
+	for sessionID, named := range state.Snapshots {
+		snapshots[sessionID] = make(map[string]*models.SessionSnapshot, len(named))
+		for _, raw := range named {
+			snapshot, err := decodeSnapshot(raw)
+			if err != nil {
+				return fmt.Errorf("decode store snapshot of session %s: %w", sessionID, err)
+			}
+			snapshots[sessionID][snapshot.Name] = snapshot
+		}
+	}
+
+	store.mutex.Lock()
+	store.sessions = sessions
+	store.snapshots = snapshots
+	store.mutex.Unlock()
+	return nil
+}
@@ -0,0 +1,122 @@
+package storage_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/storage"
+)
+
+func seedSessions(b testing.TB, store storage.SessionStore, count int) []*models.Session {
+	b.Helper()
+	sessions := make([]*models.Session, 0, count)
+	for i := 0; i < count; i++ {
+		session := models.NewSession("user", fmt.Sprintf("Concept %d", i))
+		child := models.NewThought(fmt.Sprintf("Child %d", i), session.ID, models.Direction{Type: models.Deep, Title: "Deeper"})
+		session.RootThought.AddChild(child)
+		if err := store.Save(session); err != nil {
+			b.Fatalf("save failed: %v", err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+func TestInMemorySessionStoreSnapshotRestore(t *testing.T) {
+	store := storage.NewInMemorySessionStore().(storage.SnapshottableStore)
+	sessions := seedSessions(t, store, 2)
+	named := &models.SessionSnapshot{Name: "before", CreatedAt: time.Now().UTC(), Data: sessions[0]}
+	if err := store.(storage.SnapshotStore).SaveSnapshot(sessions[0].ID, named); err != nil {
+		t.Fatalf("save snapshot failed: %v", err)
+	}
+	data := store.Snapshot()
+
+	// 快照之后的修改在恢复后全部撤销
+	if err := store.Delete(sessions[1].ID); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	extra := models.NewSession("user", "Added later")
+	if err := store.Save(extra); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if err := store.Restore(data); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+
+	restored, err := store.Get(sessions[1].ID)
+	if err != nil || len(restored.RootThought.Children) != 1 || restored.RootThought.Children[0].ParentID == nil {
+		t.Fatalf("expected the restored thought tree, got %+v (%v)", restored, err)
+	}
+	if _, err := store.Get(extra.ID); !errors.Is(err, appErrors.ErrSessionNotFound) {
+		t.Fatalf("expected the later session to be dropped, got %v", err)
+	}
+	if _, err := store.(storage.SnapshotStore).GetSnapshot(sessions[0].ID, "before"); err != nil {
+		t.Fatalf("expected the named snapshot to be restored: %v", err)
+	}
+
+	if err := store.Restore([]byte("{broken")); err == nil {
+		t.Fatal("expected an error for an invalid snapshot")
+	}
+	if all, _ := store.GetByUserID("user"); len(all) != 2 {
+		t.Fatalf("expected a failed restore to keep the state, got %d sessions", len(all))
+	}
+}
+
+func TestInMemorySessionStoreSnapshotConcurrentAccess(t *testing.T) {
+	store := storage.NewInMemorySessionStore().(storage.SnapshottableStore)
+	seedSessions(t, store, 3)
+	data := store.Snapshot()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				_ = store.Restore(data)
+				return
+			}
+			_ = store.Save(models.NewSession("user", "Concurrent"))
+			_ = store.Snapshot()
+		}(i)
+	}
+	wg.Wait()
+
+	if err := store.Restore(data); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	if all, _ := store.GetByUserID("user"); len(all) != 3 {
+		t.Fatalf("expected the snapshot state, got %d sessions", len(all))
+	}
+}
+
+func BenchmarkInMemorySessionStoreSnapshotRestore(b *testing.B) {
+	store := storage.NewInMemorySessionStore().(storage.SnapshottableStore)
+	seedSessions(b, store, 50)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := store.Restore(store.Snapshot()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInMemorySessionStoreFreshSeed(b *testing.B) {
+	sessions := seedSessions(b, storage.NewInMemorySessionStore(), 50)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		store := storage.NewInMemorySessionStore()
+		for _, session := range sessions {
+			if err := store.Save(session); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -3,6 +3,7 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -27,13 +28,16 @@ type SessionStore interface {
 	Delete(sessionID string) error
 	GetByUserID(userID string) ([]*models.Session, error)
 	GetExpiredSessions(before time.Time) ([]*models.Session, error)
+	// SearchByConcept 返回用户根思维内容包含 query（不区分大小写）的会话，按更新时间倒序。
+	SearchByConcept(userID, query string) ([]*models.Session, error)
 	Ping(ctx context.Context) error
 }
 
 // 结构体
 type InMemorySessionStore struct {
-	sessions map[string]*models.Session
-	mutex    sync.RWMutex
+	sessions  map[string]*models.Session
+	snapshots map[string]map[string]*models.SessionSnapshot
+	mutex     sync.RWMutex
 }
 
 type FileSessionStore struct {
@@ -46,12 +50,14 @@ type FileSessionStore struct {
 
 type sessionMetadata struct {
 	UpdatedAt time.Time
+	Concept   string
 }
 
 // 函数
 func NewInMemorySessionStore() SessionStore {
 	return &InMemorySessionStore{
-		sessions: make(map[string]*models.Session),
+		sessions:  make(map[string]*models.Session),
+		snapshots: make(map[string]map[string]*models.SessionSnapshot),
 	}
 }
 
@@ -89,6 +95,7 @@ type indexSnapshot struct {
 
 type indexRecord struct {
 	UpdatedAt string `json:"updated_at"`
+	Concept   string `json:"concept,omitempty"`
 }
 
 func (store *FileSessionStore) initializeIndex() error {
@@ -142,7 +149,11 @@ func (store *FileSessionStore) loadIndexLocked() error {
 			}
 			return err
 		}
-		sessionIndex[id] = sessionMetadata{UpdatedAt: ts}
+		if record.Concept == "" {
+			// 旧版索引没有记录概念，需要重建
+			return errors.New("index record missing concept")
+		}
+		sessionIndex[id] = sessionMetadata{UpdatedAt: ts, Concept: record.Concept}
 		validSessions[id] = struct{}{}
 	}
 
@@ -170,6 +181,10 @@ func (store *FileSessionStore) rebuildIndexLocked() error {
 			return err
 		}
 		if d.IsDir() {
+			// 命名快照不是会话文件，不参与索引
+			if filepath.Clean(path) == filepath.Clean(store.snapshotRoot()) {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 		if store.indexPath != "" && filepath.Clean(path) == filepath.Clean(store.indexPath) {
@@ -205,7 +220,7 @@ func (store *FileSessionStore) persistIndexLocked() error {
 	}
 
 	for id, meta := range store.sessionIndex {
-		snapshot.Sessions[id] = indexRecord{UpdatedAt: meta.UpdatedAt.Format(time.RFC3339)}
+		snapshot.Sessions[id] = indexRecord{UpdatedAt: meta.UpdatedAt.Format(time.RFC3339), Concept: meta.Concept}
 	}
 
 	for userID, ids := range store.userIndex {
@@ -299,6 +314,7 @@ func (store *InMemorySessionStore) Delete(sessionID string) error {
 	defer store.mutex.Unlock()
 
 	delete(store.sessions, sessionID)
+	delete(store.snapshots, sessionID)
 	return nil
 }
 
@@ -315,6 +331,20 @@ func (store *InMemorySessionStore) GetByUserID(userID string) ([]*models.Session
 	return results, nil
 }
 
+func (store *InMemorySessionStore) SearchByConcept(userID, query string) ([]*models.Session, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	results := make([]*models.Session, 0)
+	for _, session := range store.sessions {
+		if session != nil && session.UserID == userID && conceptMatches(sessionConcept(session), query) {
+			results = append(results, cloneSession(session))
+		}
+	}
+	sortByUpdatedAtDesc(results)
+	return results, nil
+}
+
 func (store *InMemorySessionStore) GetExpiredSessions(before time.Time) ([]*models.Session, error) {
 	store.mutex.RLock()
 	defer store.mutex.RUnlock()
@@ -387,10 +417,32 @@ func (store *FileSessionStore) Delete(sessionID string) error {
 	store.mutex.Lock()
 	defer store.mutex.Unlock()
 
+	return store.deleteLocked(sessionID)
+}
+
+// DeleteIfIdle 仅在会话自 before 起未被更新时删除，返回是否已删除；
+// 归档后用它删除原文件，避免删掉归档期间被更新的会话。
+func (store *FileSessionStore) DeleteIfIdle(sessionID string, before time.Time) (bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if meta, ok := store.sessionIndex[sessionID]; ok && !meta.UpdatedAt.IsZero() && !meta.UpdatedAt.Before(before) {
+		return false, nil
+	}
+	if err := store.deleteLocked(sessionID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (store *FileSessionStore) deleteLocked(sessionID string) error {
 	path := store.sessionPath(sessionID)
 	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return err
 	}
+	if err := os.RemoveAll(store.snapshotDir(sessionID)); err != nil {
+		return err
+	}
 	store.removeFromIndexLocked(sessionID)
 	return store.persistIndexLocked()
 }
@@ -414,6 +466,33 @@ func (store *FileSessionStore) GetByUserID(userID string) ([]*models.Session, er
 	return sessions, nil
 }
 
+// SearchByConcept 先在索引中按概念筛选，只读取命中的会话文件。
+func (store *FileSessionStore) SearchByConcept(userID, query string) ([]*models.Session, error) {
+	store.mutex.RLock()
+	ids := store.lookupUserUnlocked(userID)
+	matched := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if conceptMatches(store.sessionIndex[id].Concept, query) {
+			matched = append(matched, id)
+		}
+	}
+	store.mutex.RUnlock()
+
+	sessions := make([]*models.Session, 0, len(matched))
+	for _, id := range matched {
+		session, err := store.Get(id)
+		if err != nil {
+			if errors.Is(err, appErrors.ErrSessionNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	sortByUpdatedAtDesc(sessions)
+	return sessions, nil
+}
+
 func (store *FileSessionStore) GetExpiredSessions(before time.Time) ([]*models.Session, error) {
 	store.mutex.RLock()
 	if store.sessionIndex == nil {
@@ -454,25 +533,40 @@ func (store *FileSessionStore) sessionPath(sessionID string) string {
 }
 
 func writeSessionFile(path string, session *models.Session) error {
-	payload, err := json.MarshalIndent(session, "", "  ")
+	tempPath := path + ".tmp"
+	file, err := os.OpenFile(tempPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
 	if err != nil {
 		return err
 	}
-
-	tempPath := path + ".tmp"
-	if err := os.WriteFile(tempPath, payload, 0o644); err != nil {
+	if err := session.WriteStoredJSON(file); err != nil {
+		_ = file.Close()
+		_ = os.Remove(tempPath)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(tempPath)
 		return err
 	}
 	return os.Rename(tempPath, path)
 }
 
 func decodeSession(data []byte) (*models.Session, error) {
-	var session models.Session
-	if err := json.Unmarshal(data, &session); err != nil {
+	session, err := models.DecodeStoredSession(data)
+	if err != nil {
 		return nil, err
 	}
 	normalizeThoughtTree(session.RootThought, nil, nil)
-	return &session, nil
+	session.EnsureContextEntries()
+	return session, nil
+}
+
+// encodeSession 以存储格式编码会话，保留 API 格式中省略的向量。
+func encodeSession(session *models.Session) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := session.WriteStoredJSON(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
 func cloneSession(session *models.Session) *models.Session {
@@ -480,7 +574,7 @@ func cloneSession(session *models.Session) *models.Session {
 		return nil
 	}
 
-	payload, err := json.Marshal(session)
+	payload, err := encodeSession(session)
 	if err != nil {
 		return nil
 	}
@@ -549,8 +643,9 @@ func (store *FileSessionStore) indexSessionLocked(session *models.Session) {
 		}
 	}
 
+	meta := sessionMetadata{UpdatedAt: safeUpdatedAt(session), Concept: sessionConcept(session)}
 	if session.UserID == "" {
-		store.sessionIndex[session.ID] = sessionMetadata{UpdatedAt: safeUpdatedAt(session)}
+		store.sessionIndex[session.ID] = meta
 		return
 	}
 
@@ -560,7 +655,24 @@ func (store *FileSessionStore) indexSessionLocked(session *models.Session) {
 		store.userIndex[session.UserID] = ids
 	}
 	ids[session.ID] = struct{}{}
-	store.sessionIndex[session.ID] = sessionMetadata{UpdatedAt: safeUpdatedAt(session)}
+	store.sessionIndex[session.ID] = meta
+}
+
+func sessionConcept(session *models.Session) string {
+	if session == nil || session.RootThought == nil {
+		return ""
+	}
+	return strings.TrimSpace(session.RootThought.Content)
+}
+
+func conceptMatches(concept, query string) bool {
+	return strings.Contains(strings.ToLower(concept), strings.ToLower(strings.TrimSpace(query)))
+}
+
+func sortByUpdatedAtDesc(sessions []*models.Session) {
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt)
+	})
 }
 
 func (store *FileSessionStore) removeFromIndexLocked(sessionID string) {
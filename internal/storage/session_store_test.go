@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -80,6 +81,78 @@ func TestFileSessionStoreIndexPersistence(t *testing.T) {
 	}
 }
 
+func TestFileSessionStoreRestoresThoughtTree(t *testing.T) {
+	dataDir := t.TempDir()
+	store := storage.NewFileSessionStore(dataDir)
+	session := models.NewSession("tree-user", "Root")
+	child := models.NewThought("Child", session.ID, models.Direction{Type: models.Deep, Title: "Child"})
+	grandchild := models.NewThought("Grandchild", session.ID, models.Direction{Type: models.Lateral, Title: "Grandchild"})
+	session.RootThought.AddChild(child)
+	child.AddChild(grandchild)
+
+	if err := store.Save(session); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	loaded, err := storage.NewFileSessionStore(dataDir).Get(session.ID)
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if loaded.RootThought == nil || len(loaded.RootThought.Children) != 1 {
+		t.Fatalf("expected root with one child after reload")
+	}
+	loadedChild := loaded.RootThought.Children[0]
+	if len(loadedChild.Children) != 1 {
+		t.Fatalf("expected grandchild after reload")
+	}
+	loadedGrandchild := loadedChild.Children[0]
+	if loadedGrandchild.ID != grandchild.ID || loadedGrandchild.ParentID == nil || *loadedGrandchild.ParentID != child.ID {
+		t.Fatalf("expected grandchild to reference its parent, got %+v", loadedGrandchild)
+	}
+	if loadedGrandchild.Depth != 2 || len(loadedGrandchild.Path) != 3 || loadedGrandchild.Path[2] != "Grandchild" {
+		t.Fatalf("expected depth and path to be restored, got %d %v", loadedGrandchild.Depth, loadedGrandchild.Path)
+	}
+	if loaded.RootThought.ParentID != nil {
+		t.Fatalf("expected root to have no parent")
+	}
+}
+
+func TestSessionStoresPersistEmbeddingOutsideAPIJSON(t *testing.T) {
+	session := models.NewSession("embed-user", "Solar")
+	session.Embedding = []float64{0.25, 0.75}
+	session.EmbeddingKey = "model:hash"
+
+	api, err := json.Marshal(session)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if strings.Contains(string(api), "embedding") {
+		t.Fatalf("expected API JSON to omit the embedding, got %s", api)
+	}
+
+	dataDir := t.TempDir()
+	for name, store := range map[string]storage.SessionStore{
+		"memory": storage.NewInMemorySessionStore(),
+		"file":   storage.NewFileSessionStore(dataDir),
+	} {
+		if err := store.Save(session); err != nil {
+			t.Fatalf("%s: save failed: %v", name, err)
+		}
+		loaded, err := store.Get(session.ID)
+		if err != nil {
+			t.Fatalf("%s: get failed: %v", name, err)
+		}
+		if len(loaded.Embedding) != 2 || loaded.Embedding[1] != 0.75 || loaded.EmbeddingKey != "model:hash" {
+			t.Fatalf("%s: expected embedding to be persisted, got %v %q", name, loaded.Embedding, loaded.EmbeddingKey)
+		}
+	}
+
+	reloaded, err := storage.NewFileSessionStore(dataDir).Get(session.ID)
+	if err != nil || reloaded.EmbeddingKey != "model:hash" {
+		t.Fatalf("expected embedding to survive a reload, got %+v (%v)", reloaded, err)
+	}
+}
+
 func TestFileSessionStoreIndexCorruptionRecovery(t *testing.T) {
 	dataDir := t.TempDir()
 	store := storage.NewFileSessionStore(dataDir)
@@ -146,3 +219,142 @@ func TestFileSessionStoreGetExpiredSessions(t *testing.T) {
 		t.Fatalf("expected expired session %s, got %s", oldSession.ID, expired[0].ID)
 	}
 }
+
+func TestFileSessionStoreMigratesLegacyContext(t *testing.T) {
+	dataDir := t.TempDir()
+	legacy := map[string]interface{}{
+		"id":        "legacy-session",
+		"userId":    "legacy-user",
+		"context":   []string{"初始概念", "goal: ship v2", "preference: concise", "audience: engineers"},
+		"createdAt": time.Now().UTC(),
+		"updatedAt": time.Now().UTC(),
+		"isActive":  true,
+	}
+	data, err := json.Marshal(legacy)
+	if err != nil {
+		t.Fatalf("marshal legacy session: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dataDir, "legacy-session.json"), data, 0o644); err != nil {
+		t.Fatalf("write legacy session: %v", err)
+	}
+
+	store := storage.NewFileSessionStore(dataDir)
+	loaded, err := store.Get("legacy-session")
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+
+	expected := []models.ContextEntry{
+		{Kind: models.ContextNote, Value: "初始概念"},
+		{Kind: models.ContextGoal, Value: "ship v2"},
+		{Kind: models.ContextPreference, Value: "concise"},
+		{Kind: models.ContextNote, Value: "audience: engineers"},
+	}
+	if len(loaded.ContextEntries) != len(expected) {
+		t.Fatalf("expected %d context entries, got %+v", len(expected), loaded.ContextEntries)
+	}
+	for i, entry := range expected {
+		if loaded.ContextEntries[i] != entry {
+			t.Fatalf("entry %d: expected %+v, got %+v", i, entry, loaded.ContextEntries[i])
+		}
+	}
+	if len(loaded.Context) != len(expected) {
+		t.Fatalf("expected legacy context to be preserved, got %v", loaded.Context)
+	}
+}
+
+func TestFileSessionStoreSnapshotsStayOutOfIndex(t *testing.T) {
+	dataDir := t.TempDir()
+	store := storage.NewFileSessionStore(dataDir)
+	session := models.NewSession("snapshot-user", "思维导图")
+	if err := store.Save(session); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	snapshots := store.(storage.SnapshotStore)
+	snapshot := &models.SessionSnapshot{Name: "v1", CreatedAt: time.Now().UTC(), Data: session.Clone()}
+	if err := snapshots.SaveSnapshot(session.ID, snapshot); err != nil {
+		t.Fatalf("save snapshot failed: %v", err)
+	}
+
+	// 删除索引后重建，快照文件不能被当作会话载入
+	if err := os.Remove(filepath.Join(dataDir, "index.json")); err != nil {
+		t.Fatalf("remove index failed: %v", err)
+	}
+	reloaded := storage.NewFileSessionStore(dataDir)
+	sessions, err := reloaded.GetByUserID("snapshot-user")
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("expected exactly one session after rebuild, got %d (%v)", len(sessions), err)
+	}
+
+	loaded, err := reloaded.(storage.SnapshotStore).GetSnapshot(session.ID, "v1")
+	if err != nil || loaded.Data == nil || loaded.Data.ID != session.ID {
+		t.Fatalf("expected snapshot to persist, got %+v (%v)", loaded, err)
+	}
+
+	if err := reloaded.Delete(session.ID); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "snapshots", session.ID)); !os.IsNotExist(err) {
+		t.Fatalf("expected snapshots to be removed with the session, got %v", err)
+	}
+}
+
+func TestSessionStoresSearchByConcept(t *testing.T) {
+	stores := map[string]func() storage.SessionStore{
+		"memory": storage.NewInMemorySessionStore,
+		"file":   func() storage.SessionStore { return storage.NewFileSessionStore(t.TempDir()) },
+	}
+	for name, newStore := range stores {
+		store := newStore()
+		base := time.Now().UTC()
+		for i, concept := range []string{"Solar Energy Storage", "Grid storage pricing", "Urban gardening"} {
+			session := models.NewSession("searcher", concept)
+			session.UpdatedAt = base.Add(time.Duration(i) * time.Minute)
+			if err := store.Save(session); err != nil {
+				t.Fatalf("%s: save failed: %v", name, err)
+			}
+		}
+		if err := store.Save(models.NewSession("someone-else", "Storage heaters")); err != nil {
+			t.Fatalf("%s: save failed: %v", name, err)
+		}
+
+		sessions, err := store.SearchByConcept("searcher", "STORAGE")
+		if err != nil {
+			t.Fatalf("%s: search failed: %v", name, err)
+		}
+		if len(sessions) != 2 || sessions[0].RootThought.Content != "Grid storage pricing" || sessions[1].RootThought.Content != "Solar Energy Storage" {
+			t.Fatalf("%s: expected the two storage sessions, newest first, got %d", name, len(sessions))
+		}
+		if sessions, err := store.SearchByConcept("searcher", "nuclear"); err != nil || len(sessions) != 0 {
+			t.Fatalf("%s: expected no matches, got %d (%v)", name, len(sessions), err)
+		}
+	}
+}
+
+func TestFileSessionStoreRebuildsIndexWithoutConcepts(t *testing.T) {
+	dataDir := t.TempDir()
+	store := storage.NewFileSessionStore(dataDir)
+	session := models.NewSession("legacy-user", "Tidal power")
+	if err := store.Save(session); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	legacy := map[string]interface{}{
+		"users":    map[string][]string{"legacy-user": {session.ID}},
+		"sessions": map[string]interface{}{session.ID: map[string]string{"updated_at": session.UpdatedAt.Format(time.RFC3339)}},
+	}
+	payload, err := json.Marshal(legacy)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dataDir, "index.json"), payload, 0o644); err != nil {
+		t.Fatalf("write index failed: %v", err)
+	}
+
+	store = storage.NewFileSessionStore(dataDir)
+	sessions, err := store.SearchByConcept("legacy-user", "tidal")
+	if err != nil || len(sessions) != 1 || sessions[0].ID != session.ID {
+		t.Fatalf("expected the rebuilt index to find the session, got %d (%v)", len(sessions), err)
+	}
+}
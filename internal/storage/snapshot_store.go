@@ -0,0 +1,205 @@
+//Store Named Snapshots(存储命名快照)
+
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	appErrors "WideMindsMCP/internal/errors"
+	"WideMindsMCP/internal/models"
+)
+
+// 常量
+// snapshotDirName 是 FileSessionStore 数据目录下存放命名快照的子目录，按会话 ID 分目录保存
+const snapshotDirName = "snapshots"
+
+// 接口
+// SnapshotStore 保存会话的命名快照；内置的两种会话存储都实现了该接口。
+type SnapshotStore interface {
+	// SaveSnapshot 保存快照，同名快照会被覆盖。
+	SaveSnapshot(sessionID string, snapshot *models.SessionSnapshot) error
+	GetSnapshot(sessionID, name string) (*models.SessionSnapshot, error)
+	// ListSnapshots 按创建时间从早到晚返回会话的全部快照。
+	ListSnapshots(sessionID string) ([]*models.SessionSnapshot, error)
+	DeleteSnapshot(sessionID, name string) error
+}
+
+// InMemorySessionStore方法
+func (store *InMemorySessionStore) SaveSnapshot(sessionID string, snapshot *models.SessionSnapshot) error {
+	if snapshot == nil {
+		return errors.New("snapshot is nil")
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.snapshots[sessionID] == nil {
+		store.snapshots[sessionID] = make(map[string]*models.SessionSnapshot)
+	}
+	store.snapshots[sessionID][snapshot.Name] = cloneSnapshot(snapshot)
+	return nil
+}
+
+func (store *InMemorySessionStore) GetSnapshot(sessionID, name string) (*models.SessionSnapshot, error) {
+	store.mutex.RLock()
+	snapshot, ok := store.snapshots[sessionID][name]
+	store.mutex.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", appErrors.ErrSnapshotNotFound, name)
+	}
+	return cloneSnapshot(snapshot), nil
+}
+
+func (store *InMemorySessionStore) ListSnapshots(sessionID string) ([]*models.SessionSnapshot, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	results := make([]*models.SessionSnapshot, 0, len(store.snapshots[sessionID]))
+	for _, snapshot := range store.snapshots[sessionID] {
+		results = append(results, cloneSnapshot(snapshot))
+	}
+	sortSnapshots(results)
+	return results, nil
+}
+
+func (store *InMemorySessionStore) DeleteSnapshot(sessionID, name string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if _, ok := store.snapshots[sessionID][name]; !ok {
+		return fmt.Errorf("%w: %s", appErrors.ErrSnapshotNotFound, name)
+	}
+	delete(store.snapshots[sessionID], name)
+	return nil
+}
+
+// FileSessionStore方法
+func (store *FileSessionStore) SaveSnapshot(sessionID string, snapshot *models.SessionSnapshot) error {
+	if snapshot == nil {
+		return errors.New("snapshot is nil")
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	dir := store.snapshotDir(sessionID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, snapshot.Name+".json")
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, path)
+}
+
+func (store *FileSessionStore) GetSnapshot(sessionID, name string) (*models.SessionSnapshot, error) {
+	store.mutex.RLock()
+	path := filepath.Join(store.snapshotDir(sessionID), name+".json")
+	store.mutex.RUnlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", appErrors.ErrSnapshotNotFound, name)
+		}
+		return nil, err
+	}
+	return decodeSnapshot(data)
+}
+
+func (store *FileSessionStore) ListSnapshots(sessionID string) ([]*models.SessionSnapshot, error) {
+	store.mutex.RLock()
+	dir := store.snapshotDir(sessionID)
+	store.mutex.RUnlock()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []*models.SessionSnapshot{}, nil
+		}
+		return nil, err
+	}
+
+	results := make([]*models.SessionSnapshot, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		snapshot, err := decodeSnapshot(data)
+		if err != nil {
+			return nil, fmt.Errorf("decode snapshot %s: %w", entry.Name(), err)
+		}
+		results = append(results, snapshot)
+	}
+	sortSnapshots(results)
+	return results, nil
+}
+
+func (store *FileSessionStore) DeleteSnapshot(sessionID, name string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	path := filepath.Join(store.snapshotDir(sessionID), name+".json")
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%w: %s", appErrors.ErrSnapshotNotFound, name)
+		}
+		return err
+	}
+	return nil
+}
+
+func (store *FileSessionStore) snapshotRoot() string {
+	return filepath.Join(store.dataDir, snapshotDirName)
+}
+
+func (store *FileSessionStore) snapshotDir(sessionID string) string {
+	return filepath.Join(store.snapshotRoot(), sessionID)
+}
+
+// 函数
+func decodeSnapshot(data []byte) (*models.SessionSnapshot, error) {
+	var snapshot models.SessionSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+	if snapshot.Data != nil {
+		normalizeThoughtTree(snapshot.Data.RootThought, nil, nil)
+		snapshot.Data.EnsureContextEntries()
+	}
+	return &snapshot, nil
+}
+
+func cloneSnapshot(snapshot *models.SessionSnapshot) *models.SessionSnapshot {
+	clone := *snapshot
+	clone.Data = cloneSession(snapshot.Data)
+	return &clone
+}
+
+func sortSnapshots(snapshots []*models.SessionSnapshot) {
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		if snapshots[i].CreatedAt.Equal(snapshots[j].CreatedAt) {
+			return snapshots[i].Name < snapshots[j].Name
+		}
+		return snapshots[i].CreatedAt.Before(snapshots[j].CreatedAt)
+	})
+}
@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"sync"
+	"time"
+
+	appErrors "WideMindsMCP/internal/errors"
+)
+
+// CircuitState 表示熔断器当前状态。
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"
+	CircuitOpen     CircuitState = "open"
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+const (
+	DefaultCircuitFailureThreshold = 5
+	DefaultCircuitRecoveryTimeout  = 30 * time.Second
+)
+
+// CircuitStatus 是熔断器状态的只读快照。
+type CircuitStatus struct {
+	State            CircuitState `json:"state"`
+	Failures         int          `json:"failures"`
+	FailureThreshold int          `json:"failureThreshold"`
+	OpenedAt         *time.Time   `json:"openedAt,omitempty"`
+	CircuitOpenTotal int64        `json:"circuitOpenTotal"`
+}
+
+// CircuitBreaker 在连续失败达到阈值后打开，恢复时间过后以半开状态放行一次探测请求。
+type CircuitBreaker struct {
+	threshold int
+	recovery  time.Duration
+	mu        sync.Mutex
+	state     CircuitState
+	failures  int
+	openedAt  time.Time
+	probing   bool
+	openTotal int64
+	now       func() time.Time
+}
+
+// NewCircuitBreaker 创建熔断器。threshold 或 recovery 非正时使用默认值。
+func NewCircuitBreaker(threshold int, recovery time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = DefaultCircuitFailureThreshold
+	}
+	if recovery <= 0 {
+		recovery = DefaultCircuitRecoveryTimeout
+	}
+	return &CircuitBreaker{
+		threshold: threshold,
+		recovery:  recovery,
+		state:     CircuitClosed,
+		now:       time.Now,
+	}
+}
+
+// Allow 判断是否可以发起调用；熔断打开或半开探测进行中时返回 ErrCircuitOpen。
+func (cb *CircuitBreaker) Allow() error {
+	if cb == nil {
+		return nil
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case CircuitOpen:
+		if cb.now().Sub(cb.openedAt) < cb.recovery {
+			return appErrors.ErrCircuitOpen
+		}
+		cb.state = CircuitHalfOpen
+		cb.probing = true
+		return nil
+	case CircuitHalfOpen:
+		if cb.probing {
+			return appErrors.ErrCircuitOpen
+		}
+		cb.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// RecordSuccess 记录一次成功调用并关闭熔断器。
+func (cb *CircuitBreaker) RecordSuccess() {
+	if cb == nil {
+		return
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.state = CircuitClosed
+	cb.failures = 0
+	cb.probing = false
+}
+
+// RecordFailure 记录一次失败；半开探测失败或连续失败达到阈值时打开熔断器。
+func (cb *CircuitBreaker) RecordFailure() {
+	if cb == nil {
+		return
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures++
+	if cb.state == CircuitHalfOpen || (cb.state == CircuitClosed && cb.failures >= cb.threshold) {
+		cb.state = CircuitOpen
+		cb.openedAt = cb.now()
+		cb.openTotal++
+	}
+	cb.probing = false
+}
+
+// Status 返回熔断器当前状态。
+func (cb *CircuitBreaker) Status() CircuitStatus {
+	if cb == nil {
+		return CircuitStatus{State: CircuitClosed}
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	status := CircuitStatus{
+		State:            cb.state,
+		Failures:         cb.failures,
+		FailureThreshold: cb.threshold,
+		CircuitOpenTotal: cb.openTotal,
+	}
+	if cb.state != CircuitClosed {
+		openedAt := cb.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
@@ -0,0 +1,193 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// AuthMode 决定 API 访问令牌的校验方式。
+type AuthMode string
+
+const (
+	// AuthModeToken 将请求令牌与配置的静态 api_token 比对。
+	AuthModeToken AuthMode = "token"
+	// AuthModeJWT 校验 HS256 签名的 JWT，并以 sub 声明作为用户标识。
+	AuthModeJWT AuthMode = "jwt"
+
+	// MinJWTSecretLength 为 HS256 密钥的最小字节数（256 位）。
+	MinJWTSecretLength = 32
+
+	// AdminTokenHeader 是管理接口携带管理令牌的请求头。
+	AdminTokenHeader = "X-Admin-Token"
+)
+
+// ErrInvalidJWT 表示令牌不是格式正确、签名有效且在有效期内的 JWT。
+var ErrInvalidJWT = errors.New("invalid jwt")
+
+// rateLimitJWTSecret 非空时 ClientKey 以签名有效的 JWT 的 sub 作为限流 key
+var rateLimitJWTSecret atomic.Pointer[[]byte]
+
+// AuthConfig 描述 HTTP 与 MCP 服务共用的鉴权设置。
+type AuthConfig struct {
+	Mode      AuthMode
+	Token     string
+	JWTSecret string
+	// AdminToken 是管理接口所需的独立令牌，为空时管理接口不可用。
+	AdminToken string
+}
+
+type jwtClaims struct {
+	Subject   string   `json:"sub"`
+	ExpiresAt *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+}
+
+// ParseAuthMode 解析配置中的鉴权方式，空值视为 token。
+func ParseAuthMode(value string) (AuthMode, error) {
+	switch mode := AuthMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case "", AuthModeToken:
+		return AuthModeToken, nil
+	case AuthModeJWT:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown auth mode %q", value)
+	}
+}
+
+// Enabled 判断是否需要鉴权：token 模式需配置 api_token，jwt 模式需配置 jwt_secret。
+func (c AuthConfig) Enabled() bool {
+	if c.Mode == AuthModeJWT {
+		return c.JWTSecret != ""
+	}
+	return c.Token != ""
+}
+
+// Authorize 校验请求令牌；未启用鉴权时始终通过。
+func (c AuthConfig) Authorize(token string) bool {
+	if !c.Enabled() {
+		return true
+	}
+	if c.Mode == AuthModeJWT {
+		_, err := VerifyJWT(token, []byte(c.JWTSecret), time.Now())
+		return err == nil
+	}
+	return token == c.Token
+}
+
+// AuthorizeAdmin 校验管理令牌；未配置 admin_token 时始终拒绝。
+func (c AuthConfig) AuthorizeAdmin(token string) bool {
+	if c.AdminToken == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(c.AdminToken)) == 1
+}
+
+// UserID 返回 jwt 模式下已校验令牌的 sub；其他模式或校验失败时返回空字符串。
+func (c AuthConfig) UserID(token string) string {
+	if c.Mode != AuthModeJWT || c.JWTSecret == "" {
+		return ""
+	}
+	userID, err := VerifyJWT(token, []byte(c.JWTSecret), time.Now())
+	if err != nil {
+		return ""
+	}
+	return userID
+}
+
+// SetRateLimitByUserID 设置后 ClientKey 以用 secret 校验通过的 JWT 中的 sub 作为限流 key，空值关闭。
+func SetRateLimitByUserID(secret string) {
+	if secret == "" {
+		rateLimitJWTSecret.Store(nil)
+		return
+	}
+	key := []byte(secret)
+	rateLimitJWTSecret.Store(&key)
+}
+
+// ParseJWTUserID 解码 JWT 载荷并返回 sub 声明，不校验签名（签名由鉴权中间件校验）。
+func ParseJWTUserID(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("%w: expected three segments", ErrInvalidJWT)
+	}
+	claims, err := decodeJWTClaims(parts[1])
+	if err != nil {
+		return "", err
+	}
+	return claims.Subject, nil
+}
+
+// VerifyJWT 校验 HS256 签名与 exp、nbf 声明，返回 sub；不接受其他签名算法。
+func VerifyJWT(token string, secret []byte, now time.Time) (string, error) {
+	if len(secret) == 0 {
+		return "", fmt.Errorf("%w: no secret configured", ErrInvalidJWT)
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("%w: expected three segments", ErrInvalidJWT)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return "", err
+	}
+	if header.Alg != "HS256" {
+		return "", fmt.Errorf("%w: unsupported alg %q", ErrInvalidJWT, header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("%w: malformed signature", ErrInvalidJWT)
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return "", fmt.Errorf("%w: signature mismatch", ErrInvalidJWT)
+	}
+
+	claims, err := decodeJWTClaims(parts[1])
+	if err != nil {
+		return "", err
+	}
+	unix := float64(now.Unix())
+	if claims.ExpiresAt != nil && unix >= *claims.ExpiresAt {
+		return "", fmt.Errorf("%w: token expired", ErrInvalidJWT)
+	}
+	if claims.NotBefore != nil && unix < *claims.NotBefore {
+		return "", fmt.Errorf("%w: token not yet valid", ErrInvalidJWT)
+	}
+	return claims.Subject, nil
+}
+
+// decodeJWTClaims 解码载荷并要求 sub 为非空字符串。
+func decodeJWTClaims(segment string) (*jwtClaims, error) {
+	var claims jwtClaims
+	if err := decodeJWTSegment(segment, &claims); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(claims.Subject) == "" {
+		return nil, fmt.Errorf("%w: missing sub claim", ErrInvalidJWT)
+	}
+	return &claims, nil
+}
+
+func decodeJWTSegment(segment string, target interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("%w: malformed base64 segment", ErrInvalidJWT)
+	}
+	if err := json.Unmarshal(raw, target); err != nil {
+		return fmt.Errorf("%w: malformed json segment", ErrInvalidJWT)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package utils_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"WideMindsMCP/internal/utils"
+)
+
+const testJWTSecret = "0123456789abcdef0123456789abcdef"
+
+func signTestJWT(t *testing.T, alg, secret string, claims map[string]any) string {
+	t.Helper()
+	encode := func(value any) string {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			t.Fatalf("marshal jwt segment: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(raw)
+	}
+	signingInput := encode(map[string]string{"alg": alg, "typ": "JWT"}) + "." + encode(claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestParseJWTUserID(t *testing.T) {
+	token := signTestJWT(t, "HS256", "any-secret", map[string]any{"sub": "alice"})
+	if userID, err := utils.ParseJWTUserID(token); err != nil || userID != "alice" {
+		t.Fatalf("expected alice, got %q (%v)", userID, err)
+	}
+
+	for _, invalid := range []string{"static-token", "a.b.c", signTestJWT(t, "HS256", "any-secret", map[string]any{"name": "no sub"})} {
+		if _, err := utils.ParseJWTUserID(invalid); !errors.Is(err, utils.ErrInvalidJWT) {
+			t.Fatalf("expected ErrInvalidJWT for %q, got %v", invalid, err)
+		}
+	}
+}
+
+func TestVerifyJWT(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	valid := signTestJWT(t, "HS256", testJWTSecret, map[string]any{"sub": "alice", "exp": now.Unix() + 60, "nbf": now.Unix() - 60})
+	if userID, err := utils.VerifyJWT(valid, []byte(testJWTSecret), now); err != nil || userID != "alice" {
+		t.Fatalf("expected valid token for alice, got %q (%v)", userID, err)
+	}
+
+	cases := map[string]string{
+		"wrong secret": signTestJWT(t, "HS256", "another-secret-another-secret-xx", map[string]any{"sub": "alice"}),
+		"alg none":     signTestJWT(t, "none", testJWTSecret, map[string]any{"sub": "alice"}),
+		"expired":      signTestJWT(t, "HS256", testJWTSecret, map[string]any{"sub": "alice", "exp": now.Unix()}),
+		"not yet":      signTestJWT(t, "HS256", testJWTSecret, map[string]any{"sub": "alice", "nbf": now.Unix() + 60}),
+		"tampered":     valid[:len(valid)-2] + "xx",
+		"static token": testJWTSecret,
+	}
+	for name, token := range cases {
+		if _, err := utils.VerifyJWT(token, []byte(testJWTSecret), now); !errors.Is(err, utils.ErrInvalidJWT) {
+			t.Fatalf("%s: expected ErrInvalidJWT, got %v", name, err)
+		}
+	}
+}
+
+func TestAuthConfigAuthorize(t *testing.T) {
+	jwtToken := signTestJWT(t, "HS256", testJWTSecret, map[string]any{"sub": "alice"})
+
+	tokenMode := utils.AuthConfig{Mode: utils.AuthModeToken, Token: "static"}
+	if !tokenMode.Authorize("static") || tokenMode.Authorize(jwtToken) {
+		t.Fatal("token mode must accept only the static token")
+	}
+	jwtMode := utils.AuthConfig{Mode: utils.AuthModeJWT, Token: "static", JWTSecret: testJWTSecret}
+	if !jwtMode.Authorize(jwtToken) || jwtMode.Authorize("static") {
+		t.Fatal("jwt mode must accept only signed tokens")
+	}
+	if disabled := (utils.AuthConfig{Mode: utils.AuthModeJWT}); disabled.Enabled() || !disabled.Authorize("") {
+		t.Fatal("jwt mode without a secret must not require auth")
+	}
+	if jwtMode.UserID(jwtToken) != "alice" || jwtMode.UserID("static") != "" || tokenMode.UserID(jwtToken) != "" {
+		t.Fatal("UserID must return the sub of verified tokens in jwt mode only")
+	}
+	if jwtMode.AuthorizeAdmin("") || (utils.AuthConfig{AdminToken: "admin"}).AuthorizeAdmin("static") || !(utils.AuthConfig{AdminToken: "admin"}).AuthorizeAdmin("admin") {
+		t.Fatal("AuthorizeAdmin must accept only the configured admin token")
+	}
+	if _, err := utils.ParseAuthMode("oauth"); err == nil {
+		t.Fatal("expected unknown auth mode to be rejected")
+	}
+}
+
+func TestClientKeyByUserID(t *testing.T) {
+	t.Cleanup(func() { utils.SetRateLimitByUserID("") })
+	req := httptest.NewRequest("GET", "/api/sessions", nil)
+	req.RemoteAddr = "203.0.113.7:5555"
+	jwtToken := signTestJWT(t, "HS256", testJWTSecret, map[string]any{"sub": "alice"})
+
+	utils.SetRateLimitByUserID("")
+	if key := utils.ClientKey(req, jwtToken); key != jwtToken {
+		t.Fatalf("expected raw token key, got %q", key)
+	}
+	if key := utils.ClientKey(req, ""); key != "203.0.113.7" {
+		t.Fatalf("expected IP key without a token, got %q", key)
+	}
+
+	utils.SetRateLimitByUserID(testJWTSecret)
+	if key := utils.ClientKey(req, jwtToken); key != "user:alice" {
+		t.Fatalf("expected user key, got %q", key)
+	}
+	forged := signTestJWT(t, "HS256", "another-secret-another-secret-xx", map[string]any{"sub": "bob"})
+	if key := utils.ClientKey(req, forged); key != "203.0.113.7" {
+		t.Fatalf("expected IP fallback for a forged JWT, got %q", key)
+	}
+	if key := utils.ClientKey(req, "static-token"); key != "203.0.113.7" {
+		t.Fatalf("expected IP fallback for a non-JWT token, got %q", key)
+	}
+}
@@ -0,0 +1,211 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	appErrors "WideMindsMCP/internal/errors"
+)
+
+// DefaultLocale 是未匹配到可用语言时使用的语言，其消息即源码中的英文原文。
+const DefaultLocale = "en"
+
+var localeTagPattern = regexp.MustCompile(`^[A-Za-z]{2,8}(-[A-Za-z0-9]{1,8})*$`)
+
+// localizedSentinels 是会被翻译的哨兵错误，按检查顺序排列。
+var localizedSentinels = []error{
+	appErrors.ErrSessionNotFound,
+	appErrors.ErrThoughtNotFound,
+	appErrors.ErrSnapshotNotFound,
+	appErrors.ErrToolNotFound,
+	appErrors.ErrBudgetExceeded,
+	appErrors.ErrForbidden,
+	appErrors.ErrCircuitOpen,
+	appErrors.ErrEmbeddingsUnavailable,
+	appErrors.ErrLLMUnauthorized,
+	appErrors.ErrLLMUnreachable,
+	appErrors.ErrLLMRateLimited,
+	appErrors.ErrLLMBusy,
+	appErrors.ErrInvalidRequest,
+}
+
+// LocaleBundle 保存各语言的消息翻译，键为英文原文；缺失的翻译回退为原文。
+type LocaleBundle struct {
+	locales  []string
+	messages map[string]map[string]string
+}
+
+type localeFile struct {
+	Messages map[string]string `yaml:"messages"`
+}
+
+// NewLocaleBundle 使用内存中的翻译创建语言包，DefaultLocale 始终可用。
+func NewLocaleBundle(messages map[string]map[string]string) *LocaleBundle {
+	bundle := &LocaleBundle{
+		locales:  []string{DefaultLocale},
+		messages: map[string]map[string]string{DefaultLocale: {}},
+	}
+	for locale, catalog := range messages {
+		if _, ok := bundle.messages[locale]; !ok {
+			bundle.locales = append(bundle.locales, locale)
+		}
+		bundle.messages[locale] = catalog
+	}
+	sort.Strings(bundle.locales[1:])
+	return bundle
+}
+
+// LoadLocaleBundle 从 dir/{locale}.yaml 加载 locales 中的每种语言；DefaultLocale 的文件可以缺失。
+func LoadLocaleBundle(dir string, locales []string) (*LocaleBundle, error) {
+	messages := make(map[string]map[string]string, len(locales))
+	for _, locale := range locales {
+		if err := ValidateLocale(locale); err != nil {
+			return nil, err
+		}
+		var file localeFile
+		path := filepath.Join(dir, locale+".yaml")
+		if err := LoadYAML(path, &file); err != nil {
+			if locale == DefaultLocale && errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("load locale %s: %w", locale, err)
+		}
+		if file.Messages == nil {
+			file.Messages = map[string]string{}
+		}
+		messages[locale] = file.Messages
+	}
+	return NewLocaleBundle(messages), nil
+}
+
+// ValidateLocale 校验语言标签格式（如 en、zh-Hans），防止被用作路径。
+func ValidateLocale(locale string) error {
+	if !localeTagPattern.MatchString(locale) {
+		return fmt.Errorf("invalid locale: %q", locale)
+	}
+	return nil
+}
+
+// Locales 返回可用的语言列表。
+func (b *LocaleBundle) Locales() []string {
+	if b == nil {
+		return []string{DefaultLocale}
+	}
+	return append([]string(nil), b.locales...)
+}
+
+// Match 按 Accept-Language 的权重选出最合适的可用语言：先精确匹配，再逐级去掉子标签，最后按主语言匹配；均失败时返回 DefaultLocale。
+func (b *LocaleBundle) Match(acceptLanguage string) string {
+	if b == nil {
+		return DefaultLocale
+	}
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		if tag == "*" {
+			return DefaultLocale
+		}
+		for candidate := tag; candidate != ""; candidate = trimLocaleSubtag(candidate) {
+			if locale, ok := b.lookup(candidate); ok {
+				return locale
+			}
+		}
+		primary := strings.ToLower(strings.SplitN(tag, "-", 2)[0])
+		for _, locale := range b.locales {
+			if strings.ToLower(strings.SplitN(locale, "-", 2)[0]) == primary {
+				return locale
+			}
+		}
+	}
+	return DefaultLocale
+}
+
+// Translate 返回 message 在 locale 中的翻译，不存在时原样返回。
+func (b *LocaleBundle) Translate(locale, message string) string {
+	if b == nil {
+		return message
+	}
+	if translated := b.messages[locale][message]; translated != "" {
+		return translated
+	}
+	return message
+}
+
+// LocalizeError 返回错误在 locale 中的描述：校验错误翻译前缀与消息，哨兵错误翻译其对应文本，其余部分保持原样。
+func (b *LocaleBundle) LocalizeError(locale string, err error) string {
+	if err == nil {
+		return ""
+	}
+	message := err.Error()
+	if b == nil || locale == DefaultLocale {
+		return message
+	}
+
+	var validationErr *validationError
+	if errors.As(err, &validationErr) {
+		localized := b.Translate(locale, appErrors.ErrInvalidRequest.Error()) + ": " + b.Translate(locale, validationErr.message)
+		return strings.Replace(message, validationErr.Error(), localized, 1)
+	}
+	for _, sentinel := range localizedSentinels {
+		if errors.Is(err, sentinel) {
+			return strings.Replace(message, sentinel.Error(), b.Translate(locale, sentinel.Error()), 1)
+		}
+	}
+	return message
+}
+
+func (b *LocaleBundle) lookup(tag string) (string, bool) {
+	for _, locale := range b.locales {
+		if strings.EqualFold(locale, tag) {
+			return locale, true
+		}
+	}
+	return "", false
+}
+
+// parseAcceptLanguage 返回按 q 值降序排列的语言标签，q=0 的标签被忽略。
+func parseAcceptLanguage(header string) []string {
+	type weightedTag struct {
+		tag    string
+		weight float64
+	}
+	var tags []weightedTag
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" {
+			continue
+		}
+		weight := 1.0
+		for _, param := range fields[1:] {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					weight = parsed
+				}
+			}
+		}
+		if weight <= 0 {
+			continue
+		}
+		tags = append(tags, weightedTag{tag: tag, weight: weight})
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].weight > tags[j].weight })
+
+	ordered := make([]string, len(tags))
+	for i, tag := range tags {
+		ordered[i] = tag.tag
+	}
+	return ordered
+}
+
+func trimLocaleSubtag(tag string) string {
+	index := strings.LastIndex(tag, "-")
+	if index < 0 {
+		return ""
+	}
+	return tag[:index]
+}
@@ -0,0 +1,259 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLatencyBuckets 是请求耗时直方图的默认上界（秒）。
+var DefaultLatencyBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// MetricsRegistry 保存一组计数器、直方图与即时值，并以 Prometheus 文本格式导出。
+type MetricsRegistry struct {
+	mu      sync.Mutex
+	names   map[string]struct{}
+	metrics []prometheusWriter
+}
+
+type prometheusWriter interface {
+	writePrometheus(w io.Writer) error
+}
+
+// Counter 是只增计数器，可按一个标签维度分别计数；标签名为空时只有一个序列。
+type Counter struct {
+	name   string
+	help   string
+	label  string
+	mu     sync.Mutex
+	values map[string]uint64
+}
+
+// Histogram 按固定上界统计观测值的分布。
+type Histogram struct {
+	name    string
+	help    string
+	buckets []float64
+	mu      sync.Mutex
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+// HistogramBucket 是累计到 UpperBound（含）为止的观测次数。
+type HistogramBucket struct {
+	UpperBound float64 `json:"le"`
+	Count      uint64  `json:"count"`
+}
+
+// HistogramSnapshot 是直方图的只读快照，Buckets 为累计计数，不含 +Inf。
+type HistogramSnapshot struct {
+	Buckets []HistogramBucket `json:"buckets"`
+	Sum     float64           `json:"sum"`
+	Count   uint64            `json:"count"`
+}
+
+// funcMetric 是在导出时调用 fn 取值的指标，kind 为 counter 或 gauge。
+type funcMetric struct {
+	name string
+	help string
+	kind string
+	fn   func() float64
+}
+
+// NewMetricsRegistry 创建空的指标注册表。
+func NewMetricsRegistry() *MetricsRegistry {
+	return &MetricsRegistry{names: map[string]struct{}{}}
+}
+
+// NewCounter 注册计数器；label 为标签名，可为空。重复注册同名指标会 panic。
+func (r *MetricsRegistry) NewCounter(name, help, label string) *Counter {
+	counter := &Counter{name: name, help: help, label: label, values: map[string]uint64{}}
+	r.register(name, counter)
+	return counter
+}
+
+// NewHistogram 注册直方图；buckets 为升序上界，为空时使用 DefaultLatencyBuckets。
+func (r *MetricsRegistry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	bounds := append([]float64(nil), buckets...)
+	sort.Float64s(bounds)
+	histogram := &Histogram{name: name, help: help, buckets: bounds, counts: make([]uint64, len(bounds))}
+	r.register(name, histogram)
+	return histogram
+}
+
+// NewGaugeFunc 注册在导出时调用 fn 取值的即时值。
+func (r *MetricsRegistry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(name, &funcMetric{name: name, help: help, kind: "gauge", fn: fn})
+}
+
+// NewCounterFunc 注册在导出时调用 fn 取值的计数器，fn 须只增不减，适用于由其他组件自行累计的次数。
+func (r *MetricsRegistry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(name, &funcMetric{name: name, help: help, kind: "counter", fn: fn})
+}
+
+// WritePrometheus 按注册顺序以 Prometheus 文本格式（0.0.4）写出全部指标。
+func (r *MetricsRegistry) WritePrometheus(w io.Writer) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	metrics := append([]prometheusWriter(nil), r.metrics...)
+	r.mu.Unlock()
+
+	for _, metric := range metrics {
+		if err := metric.writePrometheus(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *MetricsRegistry) register(name string, metric prometheusWriter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.names[name]; ok {
+		panic(fmt.Sprintf("metric %s already registered", name))
+	}
+	r.names[name] = struct{}{}
+	r.metrics = append(r.metrics, metric)
+}
+
+// Inc 将标签值 labelValue 对应的序列加一。
+func (c *Counter) Inc(labelValue string) {
+	c.Add(labelValue, 1)
+}
+
+// Add 将标签值 labelValue 对应的序列增加 delta。
+func (c *Counter) Add(labelValue string, delta uint64) {
+	if c == nil || delta == 0 {
+		return
+	}
+	c.mu.Lock()
+	c.values[labelValue] += delta
+	c.mu.Unlock()
+}
+
+// Value 返回标签值 labelValue 对应序列的当前值。
+func (c *Counter) Value(labelValue string) uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[labelValue]
+}
+
+// Values 返回各标签值的当前计数副本。
+func (c *Counter) Values() map[string]uint64 {
+	values := map[string]uint64{}
+	if c == nil {
+		return values
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, value := range c.values {
+		values[key] = value
+	}
+	return values
+}
+
+func (c *Counter) writePrometheus(w io.Writer) error {
+	values := c.Values()
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var builder strings.Builder
+	writeMetricHeader(&builder, c.name, c.help, "counter")
+	for _, key := range keys {
+		builder.WriteString(c.name)
+		if c.label != "" {
+			builder.WriteString(formatLabels(c.label, key))
+		}
+		builder.WriteString(" " + strconv.FormatUint(values[key], 10) + "\n")
+	}
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+// Observe 记录一个观测值。
+func (h *Histogram) Observe(value float64) {
+	if h == nil || math.IsNaN(value) {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if index := sort.SearchFloat64s(h.buckets, value); index < len(h.buckets) {
+		h.counts[index]++
+	}
+	h.sum += value
+	h.count++
+}
+
+// Snapshot 返回直方图当前的累计分布。
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	if h == nil {
+		return HistogramSnapshot{}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	snapshot := HistogramSnapshot{
+		Buckets: make([]HistogramBucket, len(h.buckets)),
+		Sum:     h.sum,
+		Count:   h.count,
+	}
+	var cumulative uint64
+	for i, bound := range h.buckets {
+		cumulative += h.counts[i]
+		snapshot.Buckets[i] = HistogramBucket{UpperBound: bound, Count: cumulative}
+	}
+	return snapshot
+}
+
+func (h *Histogram) writePrometheus(w io.Writer) error {
+	snapshot := h.Snapshot()
+	var builder strings.Builder
+	writeMetricHeader(&builder, h.name, h.help, "histogram")
+	for _, bucket := range snapshot.Buckets {
+		builder.WriteString(h.name + "_bucket" + formatLabels("le", formatFloat(bucket.UpperBound)))
+		builder.WriteString(" " + strconv.FormatUint(bucket.Count, 10) + "\n")
+	}
+	builder.WriteString(h.name + "_bucket" + formatLabels("le", "+Inf") + " " + strconv.FormatUint(snapshot.Count, 10) + "\n")
+	builder.WriteString(h.name + "_sum " + formatFloat(snapshot.Sum) + "\n")
+	builder.WriteString(h.name + "_count " + strconv.FormatUint(snapshot.Count, 10) + "\n")
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+func (m *funcMetric) writePrometheus(w io.Writer) error {
+	var builder strings.Builder
+	writeMetricHeader(&builder, m.name, m.help, m.kind)
+	builder.WriteString(m.name + " " + formatFloat(m.fn()) + "\n")
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+func writeMetricHeader(builder *strings.Builder, name, help, kind string) {
+	if help != "" {
+		builder.WriteString("# HELP " + name + " " + strings.ReplaceAll(help, "\n", " ") + "\n")
+	}
+	builder.WriteString("# TYPE " + name + " " + kind + "\n")
+}
+
+func formatLabels(name, value string) string {
+	return "{" + name + "=" + strconv.Quote(value) + "}"
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
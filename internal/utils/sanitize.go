@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// SanitizationMode 决定 SanitizeHTML 对用户输入中 HTML 标签的处理方式。
+type SanitizationMode string
+
+const (
+	// SanitizeStrict 移除全部标签，只保留文本。
+	SanitizeStrict SanitizationMode = "strict"
+	// SanitizePermissive 保留不带属性的 <b>、<i>、<em>，移除其余标签。
+	SanitizePermissive SanitizationMode = "permissive"
+)
+
+// 内容整体丢弃而非保留文本的元素；其中的 title、textarea 等会被解析为原始文本，
+// 若保留其内容会把未解析的标签原样带出。
+var droppedContentElements = map[atom.Atom]struct{}{
+	atom.Script:    {},
+	atom.Style:     {},
+	atom.Iframe:    {},
+	atom.Noscript:  {},
+	atom.Noembed:   {},
+	atom.Noframes:  {},
+	atom.Template:  {},
+	atom.Title:     {},
+	atom.Textarea:  {},
+	atom.Xmp:       {},
+	atom.Plaintext: {},
+}
+
+var permissiveElements = map[atom.Atom]struct{}{
+	atom.B:  {},
+	atom.I:  {},
+	atom.Em: {},
+}
+
+var currentSanitizationMode atomic.Value
+
+// ParseSanitizationMode 解析配置中的净化模式，空值视为 strict。
+func ParseSanitizationMode(value string) (SanitizationMode, error) {
+	switch mode := SanitizationMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case "", SanitizeStrict:
+		return SanitizeStrict, nil
+	case SanitizePermissive:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown sanitization mode %q", value)
+	}
+}
+
+// SetSanitizationMode 设置全局净化模式。
+func SetSanitizationMode(mode SanitizationMode) {
+	currentSanitizationMode.Store(mode)
+}
+
+// CurrentSanitizationMode 返回全局净化模式，未设置时为 strict。
+func CurrentSanitizationMode() SanitizationMode {
+	if mode, ok := currentSanitizationMode.Load().(SanitizationMode); ok {
+		return mode
+	}
+	return SanitizeStrict
+}
+
+// SanitizeHTML 按全局净化模式移除输入中的 HTML 标签，保留文本节点。
+// 文本按原样保留（包括实体与不构成标签的 "<"），<script>、<style> 等元素的内容整体丢弃。
+func SanitizeHTML(input string) string {
+	return SanitizeHTMLWithMode(input, CurrentSanitizationMode())
+}
+
+// SanitizeHTMLWithMode 与 SanitizeHTML 相同，但使用指定的净化模式。
+func SanitizeHTMLWithMode(input string, mode SanitizationMode) string {
+	if !strings.Contains(input, "<") {
+		return input
+	}
+
+	var builder strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(input))
+	dropDepth := 0
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			break
+		}
+
+		switch tokenType {
+		case html.TextToken:
+			if dropDepth == 0 {
+				builder.Write(tokenizer.Raw())
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			element := atom.Lookup(name)
+			if _, ok := droppedContentElements[element]; ok {
+				if tokenType == html.StartTagToken {
+					dropDepth++
+				}
+				continue
+			}
+			if dropDepth == 0 && tokenType == html.StartTagToken && allowsElement(mode, element) {
+				builder.WriteString("<" + element.String() + ">")
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			element := atom.Lookup(name)
+			if _, ok := droppedContentElements[element]; ok {
+				if dropDepth > 0 {
+					dropDepth--
+				}
+				continue
+			}
+			if dropDepth == 0 && allowsElement(mode, element) {
+				builder.WriteString("</" + element.String() + ">")
+			}
+		}
+	}
+	return builder.String()
+}
+
+func allowsElement(mode SanitizationMode, element atom.Atom) bool {
+	if mode != SanitizePermissive {
+		return false
+	}
+	_, ok := permissiveElements[element]
+	return ok
+}
@@ -0,0 +1,62 @@
+package utils_test
+
+import (
+	"testing"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/utils"
+)
+
+func TestSanitizeHTMLStrict(t *testing.T) {
+	cases := map[string]string{
+		"<script>alert(1)</script>":                "",
+		"Solar <script>alert(1)</script>storage":   "Solar storage",
+		"<b>Bold</b> and <a href=\"x\">link</a>":   "Bold and link",
+		"<img src=x onerror=alert(1)>caption":      "caption",
+		"<title><script>alert(1)</script></title>": "",
+		"Plain text stays the same":                "Plain text stays the same",
+		"a < b && c > d":                           "a < b && c > d",
+		"Tom &amp; Jerry <!-- note -->":            "Tom &amp; Jerry ",
+		"数据科学 <em>基础</em>":                         "数据科学 基础",
+	}
+	for input, want := range cases {
+		if got := utils.SanitizeHTMLWithMode(input, utils.SanitizeStrict); got != want {
+			t.Errorf("SanitizeHTMLWithMode(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestSanitizeHTMLPermissiveKeepsBasicFormatting(t *testing.T) {
+	input := `<b class="x">Bold</b> <i>italic</i> <em onclick="alert(1)">em</em> <u>u</u><script>alert(1)</script>`
+	want := "<b>Bold</b> <i>italic</i> <em>em</em> u"
+	if got := utils.SanitizeHTMLWithMode(input, utils.SanitizePermissive); got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestValidationSanitizesUserText(t *testing.T) {
+	direction := &models.Direction{
+		Type:        models.Deep,
+		Title:       "<script>alert(1)</script>Chemistry",
+		Description: "<p>Cell chemistry</p>",
+		Keywords:    []string{"<img src=x onerror=alert(1)>anode", "<script>alert(1)</script>"},
+	}
+	if err := utils.ValidateDirection(direction); err != nil {
+		t.Fatalf("ValidateDirection failed: %v", err)
+	}
+	if direction.Title != "Chemistry" || direction.Description != "Cell chemistry" {
+		t.Fatalf("expected sanitized direction, got %+v", direction)
+	}
+	if len(direction.Keywords) != 1 || direction.Keywords[0] != "anode" {
+		t.Fatalf("expected sanitized keywords without markup-only entries, got %q", direction.Keywords)
+	}
+
+	if err := utils.ValidateDirection(&models.Direction{Type: models.Deep, Title: "<script>alert(1)</script>"}); err == nil {
+		t.Fatalf("expected a title that is only markup to be rejected")
+	}
+
+	context, err := utils.NormalizeContext([]string{"<b>background</b>", "<script>x</script>"})
+	if err != nil || len(context) != 1 || context[0] != "background" {
+		t.Fatalf("expected sanitized context, got %q (%v)", context, err)
+	}
+}
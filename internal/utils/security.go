@@ -4,6 +4,7 @@ import (
 	"net"
 	"net/http"
 	"strings"
+	"time"
 )
 
 // ExtractBearerToken 从 Authorization 头部解析 Bearer token。
@@ -37,8 +38,14 @@ func ResolveRequestToken(r *http.Request) string {
 }
 
 // ClientKey 根据请求推导限流 key，优先使用 token 其次使用 IP。
+// 开启 SetRateLimitByUserID 时以签名有效的 JWT 的 sub 作为 key，校验失败时按 IP 限流，
+// 避免伪造的 sub 占用他人的配额或每次换 sub 绕过限流。
 func ClientKey(r *http.Request, token string) string {
-	if token != "" {
+	if secret := rateLimitJWTSecret.Load(); token != "" && secret != nil {
+		if userID, err := VerifyJWT(token, *secret, time.Now()); err == nil {
+			return "user:" + userID
+		}
+	} else if token != "" {
 		return token
 	}
 	if r == nil {
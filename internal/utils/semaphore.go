@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// SemaphoreStatus 是并发信号量的只读快照。
+type SemaphoreStatus struct {
+	Enabled  bool  `json:"enabled"`
+	Limit    int   `json:"limit"`
+	InFlight int   `json:"in_flight"`
+	Waiting  int   `json:"waiting"`
+	Rejected int64 `json:"rejected_total"`
+}
+
+// Semaphore 限制同时进行的操作数量；Acquire 在没有空位时排队等待。
+type Semaphore struct {
+	slots    chan struct{}
+	waiting  atomic.Int64
+	rejected atomic.Int64
+}
+
+// NewSemaphore 创建最多允许 limit 个并发持有者的信号量；limit 非正时返回 nil 表示不限制。
+func NewSemaphore(limit int) *Semaphore {
+	if limit <= 0 {
+		return nil
+	}
+	return &Semaphore{slots: make(chan struct{}, limit)}
+}
+
+// Acquire 取得一个空位，没有空位时等待到有空位或 ctx 结束；ctx 结束时返回 ctx.Err()。
+// 成功后必须调用 Release 归还。
+func (s *Semaphore) Acquire(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	s.waiting.Add(1)
+	defer s.waiting.Add(-1)
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		s.rejected.Add(1)
+		return ctx.Err()
+	}
+}
+
+// Release 归还 Acquire 取得的空位。
+func (s *Semaphore) Release() {
+	if s == nil {
+		return
+	}
+	<-s.slots
+}
+
+// Status 返回信号量当前状态。
+func (s *Semaphore) Status() SemaphoreStatus {
+	if s == nil {
+		return SemaphoreStatus{}
+	}
+	return SemaphoreStatus{
+		Enabled:  true,
+		Limit:    cap(s.slots),
+		InFlight: len(s.slots),
+		Waiting:  int(s.waiting.Load()),
+		Rejected: s.rejected.Load(),
+	}
+}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// TokenBucketStatus 是令牌桶的只读快照；Saturation 为已消耗的突发容量比例（0-1），有请求排队时为 1。
+type TokenBucketStatus struct {
+	Enabled        bool    `json:"enabled"`
+	RPM            int     `json:"rpm"`
+	Burst          int     `json:"burst"`
+	Available      float64 `json:"available"`
+	Waiting        int     `json:"waiting"`
+	Saturation     float64 `json:"saturation"`
+	ThrottledTotal int64   `json:"throttled_total"`
+}
+
+// TokenBucket 以每分钟 rpm 个的速率补充令牌，最多积累 burst 个；Wait 在令牌不足时排队等待。
+type TokenBucket struct {
+	rpm       int
+	burst     int
+	mu        sync.Mutex
+	tokens    float64
+	last      time.Time
+	waiting   int
+	throttled int64
+	now       func() time.Time
+}
+
+// NewTokenBucket 创建令牌桶，初始为满。rpm 非正时返回 nil 表示不限流，burst 非正时等于 rpm。
+func NewTokenBucket(rpm, burst int) *TokenBucket {
+	if rpm <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = rpm
+	}
+	return &TokenBucket{
+		rpm:    rpm,
+		burst:  burst,
+		tokens: float64(burst),
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// Wait 取得一个令牌；需要排队时阻塞到轮到本次请求或 ctx 结束。
+// 若 ctx 的截止时间早于可取得令牌的时间则立即返回 context.DeadlineExceeded，不占用令牌。
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	b.refillLocked()
+	b.tokens--
+	if b.tokens >= 0 {
+		b.mu.Unlock()
+		return nil
+	}
+	delay := time.Duration(-b.tokens / b.ratePerSecond() * float64(time.Second))
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(b.now().Add(delay)) {
+		b.tokens++
+		b.mu.Unlock()
+		return context.DeadlineExceeded
+	}
+	b.waiting++
+	b.throttled++
+	b.mu.Unlock()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		b.mu.Lock()
+		b.waiting--
+		b.mu.Unlock()
+		return nil
+	case <-ctx.Done():
+		// 归还预留的令牌，后续请求无需为已放弃的请求等待
+		b.mu.Lock()
+		b.waiting--
+		b.tokens++
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Status 返回令牌桶当前状态。
+func (b *TokenBucket) Status() TokenBucketStatus {
+	if b == nil {
+		return TokenBucketStatus{}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refillLocked()
+
+	available := math.Max(0, b.tokens)
+	saturation := 1 - available/float64(b.burst)
+	if b.waiting > 0 {
+		saturation = 1
+	}
+	return TokenBucketStatus{
+		Enabled:        true,
+		RPM:            b.rpm,
+		Burst:          b.burst,
+		Available:      math.Round(available*100) / 100,
+		Waiting:        b.waiting,
+		Saturation:     math.Round(saturation*100) / 100,
+		ThrottledTotal: b.throttled,
+	}
+}
+
+func (b *TokenBucket) ratePerSecond() float64 {
+	return float64(b.rpm) / 60
+}
+
+// refillLocked 按距上次补充的时间补充令牌；排队中的请求以负数令牌表示。
+func (b *TokenBucket) refillLocked() {
+	now := b.now()
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(float64(b.burst), b.tokens+elapsed.Seconds()*b.ratePerSecond())
+	}
+	b.last = now
+}
@@ -1,7 +1,7 @@
 package utils
 
 import (
-	"fmt"
+	"net/url"
 	"strings"
 	"unicode/utf8"
 
@@ -20,6 +20,17 @@ const (
 	MaxKeywordLength        = 50
 	MaxDirectionKeywords    = 16
 	MaxThoughtContentLength = 400
+	MaxThoughtTags          = 10
+	MaxTagLength            = 32
+	MaxExternalIDLength     = 128
+	MaxExternalURLLength    = 128
+	MaxSystemPromptLength   = 2000
+	MaxExtraInstructions    = 10
+	MaxInstructionLength    = 500
+	MaxSnapshotNameLength   = 64
+	MaxReactionTypes        = 10
+	// MaxEmojiRunes bounds ZWJ sequences such as family or profession emoji.
+	MaxEmojiRunes = 10
 )
 
 var allowedDirectionTypes = map[models.DirectionType]struct{}{
@@ -29,9 +40,22 @@ var allowedDirectionTypes = map[models.DirectionType]struct{}{
 	models.Critical: {},
 }
 
+// validationError keeps the raw message so it can be localized.
+type validationError struct {
+	message string
+}
+
+func (e *validationError) Error() string {
+	return appErrors.ErrInvalidRequest.Error() + ": " + e.message
+}
+
+func (e *validationError) Unwrap() error {
+	return appErrors.ErrInvalidRequest
+}
+
 // ValidationError wraps a message with ErrInvalidRequest for consistent reporting.
 func ValidationError(message string) error {
-	return fmt.Errorf("%w: %s", appErrors.ErrInvalidRequest, message)
+	return &validationError{message: message}
 }
 
 // ParseDirectionType normalizes the input direction type and ensures it is supported.
@@ -46,6 +70,18 @@ func ParseDirectionType(value string) (models.DirectionType, error) {
 	return normalized, nil
 }
 
+// ParseThinkingStyle normalizes an optional thinking style; empty input yields an empty style.
+func ParseThinkingStyle(value string) (models.ThinkingStyle, error) {
+	style := models.ThinkingStyle(strings.ToLower(strings.TrimSpace(value)))
+	if style == "" {
+		return "", nil
+	}
+	if !style.IsValid() {
+		return "", ValidationError("thinking_style must be one of focused, balanced, creative")
+	}
+	return style, nil
+}
+
 // IsAllowedDirectionType reports whether the given type is supported.
 func IsAllowedDirectionType(value models.DirectionType) bool {
 	_, ok := allowedDirectionTypes[models.DirectionType(strings.ToLower(strings.TrimSpace(string(value))))]
@@ -91,14 +127,14 @@ func ValidateSessionID(sessionID string) error {
 	return nil
 }
 
-// NormalizeContext trims entries, removes empties, and enforces maximum counts/lengths.
+// NormalizeContext sanitizes and trims entries, removes empties, and enforces maximum counts/lengths.
 func NormalizeContext(items []string) ([]string, error) {
 	if len(items) > MaxContextItems {
 		return nil, ValidationError("context has too many entries")
 	}
 	normalized := make([]string, 0, len(items))
 	for _, item := range items {
-		trimmed := strings.TrimSpace(item)
+		trimmed := strings.TrimSpace(SanitizeHTML(item))
 		if trimmed == "" {
 			continue
 		}
@@ -110,11 +146,38 @@ func NormalizeContext(items []string) ([]string, error) {
 	return normalized, nil
 }
 
-// NormalizeKeywords enforces keyword limits and returns a cleaned slice.
+// NormalizeContextEntries applies the NormalizeContext limits to structured context entries.
+func NormalizeContextEntries(entries []models.ContextEntry) ([]models.ContextEntry, error) {
+	if len(entries) > MaxContextItems {
+		return nil, ValidationError("context has too many entries")
+	}
+	normalized := make([]models.ContextEntry, 0, len(entries))
+	for _, entry := range entries {
+		value := strings.TrimSpace(SanitizeHTML(entry.Value))
+		if value == "" {
+			continue
+		}
+		if utf8.RuneCountInString(value) > MaxContextItemLength {
+			return nil, ValidationError("context item is too long")
+		}
+		kind := models.ContextNote
+		if entry.Kind != "" {
+			parsed, ok := models.ParseContextKind(string(entry.Kind))
+			if !ok {
+				return nil, ValidationError("context entry has an unknown kind")
+			}
+			kind = parsed
+		}
+		normalized = append(normalized, models.NewContextEntry(kind, value))
+	}
+	return normalized, nil
+}
+
+// NormalizeKeywords sanitizes keywords, enforces keyword limits, and returns a cleaned slice.
 func NormalizeKeywords(items []string) ([]string, error) {
 	cleaned := make([]string, 0, len(items))
 	for _, item := range items {
-		trimmed := strings.TrimSpace(item)
+		trimmed := strings.TrimSpace(SanitizeHTML(item))
 		if trimmed == "" {
 			continue
 		}
@@ -129,7 +192,34 @@ func NormalizeKeywords(items []string) ([]string, error) {
 	return cleaned, nil
 }
 
-// ValidateDirection normalizes and validates the provided direction.
+// ValidateSystemPrompt ensures a system prompt override stays within limits; empty means no override.
+func ValidateSystemPrompt(prompt string) error {
+	if utf8.RuneCountInString(strings.TrimSpace(prompt)) > MaxSystemPromptLength {
+		return ValidationError("system_prompt is too long")
+	}
+	return nil
+}
+
+// NormalizeInstructions enforces extra instruction limits and returns a cleaned slice.
+func NormalizeInstructions(items []string) ([]string, error) {
+	cleaned := make([]string, 0, len(items))
+	for _, item := range items {
+		trimmed := strings.TrimSpace(item)
+		if trimmed == "" {
+			continue
+		}
+		if utf8.RuneCountInString(trimmed) > MaxInstructionLength {
+			return nil, ValidationError("extra_instructions contains an entry that is too long")
+		}
+		cleaned = append(cleaned, trimmed)
+		if len(cleaned) > MaxExtraInstructions {
+			return nil, ValidationError("extra_instructions has too many entries")
+		}
+	}
+	return cleaned, nil
+}
+
+// ValidateDirection normalizes (including HTML sanitization of title and description) and validates the provided direction.
 func ValidateDirection(direction *models.Direction) error {
 	if direction == nil {
 		return ValidationError("direction is required")
@@ -140,7 +230,7 @@ func ValidateDirection(direction *models.Direction) error {
 	}
 	direction.Type = parsedType
 
-	direction.Title = strings.TrimSpace(direction.Title)
+	direction.Title = strings.TrimSpace(SanitizeHTML(direction.Title))
 	if direction.Title == "" {
 		return ValidationError("direction.title is required")
 	}
@@ -148,7 +238,7 @@ func ValidateDirection(direction *models.Direction) error {
 		return ValidationError("direction.title is too long")
 	}
 
-	direction.Description = strings.TrimSpace(direction.Description)
+	direction.Description = strings.TrimSpace(SanitizeHTML(direction.Description))
 	if utf8.RuneCountInString(direction.Description) > MaxDirectionDescLength {
 		return ValidationError("direction.description is too long")
 	}
@@ -166,22 +256,32 @@ func ValidateDirection(direction *models.Direction) error {
 	return nil
 }
 
+// ValidateThoughtContent ensures thought content is present and within limits.
+func ValidateThoughtContent(content string) error {
+	trimmed := strings.TrimSpace(content)
+	if trimmed == "" {
+		return ValidationError("content must not be empty")
+	}
+	if utf8.RuneCountInString(trimmed) > MaxThoughtContentLength {
+		return ValidationError("content is too long")
+	}
+	return nil
+}
+
 func ValidateThoughtUpdate(update *models.ThoughtUpdate) error {
 	if update == nil {
 		return ValidationError("update payload is required")
 	}
 
-	if update.Content == nil && update.Direction == nil {
+	if update.Content == nil && update.Direction == nil && update.Tags == nil && update.Confidence == nil &&
+		update.ExternalID == nil && update.ExternalSystemURL == nil {
 		return ValidationError("at least one field must be provided")
 	}
 
 	if update.Content != nil {
-		trimmed := strings.TrimSpace(*update.Content)
-		if trimmed == "" {
-			return ValidationError("content must not be empty")
-		}
-		if utf8.RuneCountInString(trimmed) > MaxThoughtContentLength {
-			return ValidationError("content is too long")
+		trimmed := strings.TrimSpace(SanitizeHTML(*update.Content))
+		if err := ValidateThoughtContent(trimmed); err != nil {
+			return err
 		}
 		*update.Content = trimmed
 	}
@@ -192,5 +292,181 @@ func ValidateThoughtUpdate(update *models.ThoughtUpdate) error {
 		}
 	}
 
+	if update.Tags != nil {
+		tags, err := NormalizeTags(*update.Tags)
+		if err != nil {
+			return err
+		}
+		*update.Tags = tags
+	}
+
+	if update.Confidence != nil && (*update.Confidence < 0 || *update.Confidence > 1) {
+		return ValidationError("confidence must be between 0 and 1")
+	}
+
+	if update.ExternalID != nil {
+		trimmed := strings.TrimSpace(*update.ExternalID)
+		if trimmed != "" {
+			if err := ValidateExternalID(trimmed); err != nil {
+				return err
+			}
+		}
+		*update.ExternalID = trimmed
+	}
+
+	if update.ExternalSystemURL != nil {
+		trimmed := strings.TrimSpace(*update.ExternalSystemURL)
+		if trimmed != "" {
+			if err := ValidateExternalSystemURL(trimmed); err != nil {
+				return err
+			}
+		}
+		*update.ExternalSystemURL = trimmed
+	}
+
+	return nil
+}
+
+// ValidateExternalID ensures an external system identifier is present and bounded.
+func ValidateExternalID(externalID string) error {
+	trimmed := strings.TrimSpace(externalID)
+	if trimmed == "" {
+		return ValidationError("external_id is required")
+	}
+	if utf8.RuneCountInString(trimmed) > MaxExternalIDLength {
+		return ValidationError("external_id is too long")
+	}
+	return nil
+}
+
+// ValidateExternalSystemURL ensures the link to an external system is an absolute http(s) URL.
+func ValidateExternalSystemURL(raw string) error {
+	trimmed := strings.TrimSpace(raw)
+	if utf8.RuneCountInString(trimmed) > MaxExternalURLLength {
+		return ValidationError("external_system_url is too long")
+	}
+	parsed, err := url.Parse(trimmed)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return ValidationError("external_system_url must be a valid http or https URL")
+	}
+	return nil
+}
+
+// NormalizeTags trims, de-duplicates, and enforces tag limits.
+func NormalizeTags(items []string) ([]string, error) {
+	seen := make(map[string]struct{}, len(items))
+	cleaned := make([]string, 0, len(items))
+	for _, item := range items {
+		trimmed := strings.TrimSpace(item)
+		if trimmed == "" {
+			continue
+		}
+		if utf8.RuneCountInString(trimmed) > MaxTagLength {
+			return nil, ValidationError("tag is too long")
+		}
+		if _, ok := seen[trimmed]; ok {
+			continue
+		}
+		seen[trimmed] = struct{}{}
+		cleaned = append(cleaned, trimmed)
+		if len(cleaned) > MaxThoughtTags {
+			return nil, ValidationError("too many tags")
+		}
+	}
+	return cleaned, nil
+}
+
+// ValidateEmoji ensures the value is a single emoji, including flags, keycaps,
+// skin-tone modifiers and ZWJ sequences.
+func ValidateEmoji(emoji string) error {
+	if emoji == "" {
+		return ValidationError("emoji is required")
+	}
+	runes := []rune(emoji)
+	if len(runes) > MaxEmojiRunes {
+		return ValidationError("emoji must be a single Unicode emoji")
+	}
+	if len(runes) >= 2 && isKeycapBase(runes[0]) && runes[len(runes)-1] == 0x20E3 {
+		return nil
+	}
+	if !isEmojiRune(runes[0]) {
+		return ValidationError("emoji must be a single Unicode emoji")
+	}
+	for i := 1; i < len(runes); i++ {
+		r := runes[i]
+		if isEmojiRune(r) || isEmojiModifier(r) {
+			continue
+		}
+		if r == 0x200D && i+1 < len(runes) && isEmojiRune(runes[i+1]) {
+			continue
+		}
+		return ValidationError("emoji must be a single Unicode emoji")
+	}
+	return nil
+}
+
+// ValidateReactions ensures a thought's reaction counts stay within limits.
+func ValidateReactions(reactions map[string]int) error {
+	if len(reactions) > MaxReactionTypes {
+		return ValidationError("too many reaction types")
+	}
+	for emoji, count := range reactions {
+		if err := ValidateEmoji(emoji); err != nil {
+			return err
+		}
+		if count < 0 {
+			return ValidationError("reaction count must not be negative")
+		}
+	}
+	return nil
+}
+
+func isEmojiRune(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF, // pictographs, emoticons, transport, regional indicators
+		r >= 0x2600 && r <= 0x27BF, // misc symbols and dingbats
+		r >= 0x2300 && r <= 0x23FF,
+		r >= 0x2B00 && r <= 0x2BFF,
+		r >= 0x2190 && r <= 0x21FF,
+		r >= 0x25A0 && r <= 0x25FF,
+		r == 0x00A9, r == 0x00AE, r == 0x203C, r == 0x2049, r == 0x2122, r == 0x2139,
+		r == 0x24C2, r == 0x2934, r == 0x2935, r == 0x3030, r == 0x303D, r == 0x3297, r == 0x3299:
+		return true
+	}
+	return false
+}
+
+// isEmojiModifier reports variation selectors, keycap marks and tag characters
+// that may follow an emoji base.
+func isEmojiModifier(r rune) bool {
+	return r == 0xFE0F || r == 0xFE0E || r == 0x20E3 || (r >= 0xE0020 && r <= 0xE007F)
+}
+
+func isKeycapBase(r rune) bool {
+	return (r >= '0' && r <= '9') || r == '#' || r == '*'
+}
+
+// ValidateSnapshotName ensures a snapshot name is a short slug that is safe to use as a file name.
+func ValidateSnapshotName(name string) error {
+	if name == "" {
+		return ValidationError("snapshot name is required")
+	}
+	if len(name) > MaxSnapshotNameLength {
+		return ValidationError("snapshot name is too long")
+	}
+	for i, r := range name {
+		isAlnum := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+		if !isAlnum && (i == 0 || (r != '-' && r != '_')) {
+			return ValidationError("snapshot name may only contain letters, digits, '-' and '_'")
+		}
+	}
+	return nil
+}
+
+// ValidateSVGDimensions ensures a requested SVG size is positive and within models.MaxSVGDimension.
+func ValidateSVGDimensions(width, height int) error {
+	if width <= 0 || height <= 0 || width > models.MaxSVGDimension || height > models.MaxSVGDimension {
+		return ValidationError("width and height must be between 1 and 10000")
+	}
 	return nil
 }
@@ -0,0 +1,110 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"WideMindsMCP/internal/models"
+	"WideMindsMCP/internal/services"
+)
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// doJSON 发送 JSON 请求，校验状态码并在 out 非 nil 时解码响应体。
+func doJSON(t *testing.T, method, url string, body any, wantStatus int, out any) {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("marshal request: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		t.Fatalf("build request: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("%s %s: expected %d, got %d: %s", method, url, wantStatus, resp.StatusCode, raw)
+	}
+	if out != nil {
+		if err := json.Unmarshal(raw, out); err != nil {
+			t.Fatalf("%s %s: decode response: %v\n%s", method, url, err, raw)
+		}
+	}
+}
+
+func TestSessionLifecycleOverHTTP(t *testing.T) {
+	resetStores(t)
+	var session models.Session
+	doJSON(t, http.MethodPost, baseURL+"/api/sessions", map[string]string{
+		"user_id": "integration-user",
+		"concept": "Renewable energy",
+	}, http.StatusOK, &session)
+	if session.ID == "" || session.RootThought == nil || session.RootThought.Content != "Renewable energy" {
+		t.Fatalf("unexpected session %+v", session)
+	}
+	sessionURL := baseURL + "/api/sessions/" + session.ID
+
+	var expansion services.ExpansionResult
+	doJSON(t, http.MethodPost, baseURL+"/api/expand", map[string]any{
+		"concept":    "Renewable energy",
+		"user_id":    "integration-user",
+		"session_id": session.ID,
+	}, http.StatusOK, &expansion)
+	if len(expansion.Directions) == 0 || expansion.Directions[0].Title != fixtureDirectionTitle {
+		t.Fatalf("expected fixture directions from the mock LLM, got %+v", expansion.Directions)
+	}
+
+	var explored models.Thought
+	doJSON(t, http.MethodPost, sessionURL, map[string]any{
+		"direction": expansion.Directions[0],
+	}, http.StatusOK, &explored)
+	if explored.ID == "" || explored.Content == "" || explored.Direction.Title != fixtureDirectionTitle {
+		t.Fatalf("unexpected explored thought %+v", explored)
+	}
+	thoughtURL := sessionURL + "/thoughts/" + explored.ID
+
+	var updated models.Thought
+	doJSON(t, http.MethodPatch, thoughtURL, map[string]any{
+		"content": "Storage is the bottleneck",
+	}, http.StatusOK, &updated)
+	if updated.ID != explored.ID || updated.Content != "Storage is the bottleneck" {
+		t.Fatalf("expected updated content, got %+v", updated)
+	}
+
+	var afterDelete models.Session
+	doJSON(t, http.MethodDelete, thoughtURL, nil, http.StatusOK, &afterDelete)
+	if _, ok := afterDelete.GetThoughtTree()[explored.ID]; ok {
+		t.Fatalf("expected thought %s to be deleted", explored.ID)
+	}
+
+	doJSON(t, http.MethodDelete, sessionURL, nil, http.StatusNoContent, nil)
+	doJSON(t, http.MethodGet, sessionURL, nil, http.StatusNotFound, nil)
+}
+
+func TestRateLimitRejectsRequestsOverLimit(t *testing.T) {
+	resetStores(t)
+	url := limitedBaseURL + "/api/sessions?user_id=rate-limited-user"
+	for i := 0; i < limitedRatePerMinute; i++ {
+		doJSON(t, http.MethodGet, url, nil, http.StatusOK, nil)
+	}
+	doJSON(t, http.MethodGet, url, nil, http.StatusTooManyRequests, nil)
+}
@@ -0,0 +1,124 @@
+//go:build integration
+
+// Package integration 在测试进程内挂载完整的 Web 路由，通过 HTTP 验证完整的请求链路。
+// 运行方式：go test -tags=integration ./test/integration/...
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"WideMindsMCP/internal/app"
+	"WideMindsMCP/internal/storage"
+)
+
+// 常量
+const (
+	fixtureDirectionTitle = "Grid storage"
+	limitedRatePerMinute  = 2
+)
+
+// fixtureResponse 同时满足方向生成（directions）与方向探索（hypothesis 等）的解析格式
+var fixtureResponse = map[string]any{
+	"directions": []map[string]any{
+		{"type": "deep", "title": fixtureDirectionTitle, "description": "How batteries balance intermittent supply", "keywords": []string{"batteries"}, "relevance": 0.9},
+		{"type": "critical", "title": "Land use", "description": "Trade-offs of large solar farms", "keywords": []string{"land"}, "relevance": 0.6},
+	},
+	"hypothesis":       "Storage capacity decides how much renewable supply the grid can absorb.",
+	"key_concepts":     []string{"batteries", "peak shaving"},
+	"validation_steps": []string{"Compare curtailment before and after storage deployments"},
+}
+
+var (
+	// baseURL 指向使用常规限流配置的服务
+	baseURL string
+	// limitedBaseURL 指向每分钟只允许 limitedRatePerMinute 次请求的服务
+	limitedBaseURL string
+	// servers 记录每个服务的存储与启动时的快照，用例结束后据此还原
+	servers []*testServer
+)
+
+// 结构体
+type testServer struct {
+	store    storage.SnapshottableStore
+	baseline []byte
+	http     *httptest.Server
+}
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	llm := httptest.NewServer(http.HandlerFunc(handleMockLLM))
+	defer llm.Close()
+
+	server, err := startServer(llm.URL, 1000)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "start server:", err)
+		return 1
+	}
+	defer server.http.Close()
+
+	limited, err := startServer(llm.URL, limitedRatePerMinute)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "start rate limited server:", err)
+		return 1
+	}
+	defer limited.http.Close()
+
+	servers = []*testServer{server, limited}
+	baseURL, limitedBaseURL = server.http.URL, limited.http.URL
+	return m.Run()
+}
+
+// handleMockLLM 模拟 OpenAI 兼容接口：向量请求返回固定向量，其余请求返回 fixtureResponse。
+func handleMockLLM(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if strings.HasSuffix(r.URL.Path, "/embeddings") {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"data": []map[string]any{{"embedding": []float64{1, 0, 0}}},
+		})
+		return
+	}
+
+	content, _ := json.Marshal(fixtureResponse)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"choices": []map[string]any{{"message": map[string]string{"role": "assistant", "content": string(content)}}},
+		"usage":   map[string]int{"prompt_tokens": 100, "completion_tokens": 50, "total_tokens": 150},
+	})
+}
+
+// startServer 用内存存储组装服务并挂载到 httptest，记录空存储的快照作为还原基线。
+func startServer(llmURL string, ratePerMinute int) (*testServer, error) {
+	cfg := app.DefaultConfig()
+	cfg.LLMProvider = "openai"
+	cfg.LLMAPIKey = "integration-key"
+	cfg.LLMBaseURL = llmURL
+	cfg.LLMModel = "integration-model"
+	cfg.HTTPRateLimitPerMinute = ratePerMinute
+
+	store := storage.NewInMemorySessionStore().(storage.SnapshottableStore)
+	handler, err := app.NewHandler(cfg, store)
+	if err != nil {
+		return nil, err
+	}
+	return &testServer{store: store, baseline: store.Snapshot(), http: httptest.NewServer(handler)}, nil
+}
+
+// resetStores 在用例结束后把每个服务的存储还原到启动时的快照，使用例之间互不影响。
+func resetStores(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		for _, server := range servers {
+			if err := server.store.Restore(server.baseline); err != nil {
+				t.Errorf("restore store: %v", err)
+			}
+		}
+	})
+}